@@ -0,0 +1,196 @@
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// TradingDaysPerYear 加密货币全年无休，按365天年化
+	TradingDaysPerYear = 365.0
+	// minDailyReturns 计算波动率/夏普所需的最少日收益率个数
+	minDailyReturns = 2
+)
+
+// EquityPoint 账户净值数据点
+type EquityPoint struct {
+	Time   time.Time
+	Equity float64
+}
+
+// RiskMetrics 基于净值曲线计算的风险调整指标
+// 样本不足时对应指标为 nil，并在 Notes 中说明原因
+type RiskMetrics struct {
+	Start                time.Time `json:"start"`
+	End                  time.Time `json:"end"`
+	Points               int       `json:"points"`                // 参与计算的净值点数
+	Days                 int       `json:"days"`                  // 按日重采样后的天数
+	StartEquity          float64   `json:"start_equity"`          // 起始净值
+	EndEquity            float64   `json:"end_equity"`            // 结束净值
+	TotalReturnPct       float64   `json:"total_return_pct"`      // 区间总收益率(%)
+	AnnualizedReturnPct  *float64  `json:"annualized_return_pct"` // 年化收益率(%)
+	VolatilityPct        *float64  `json:"volatility_pct"`        // 年化波动率(%)，日收益率标准差 × √365
+	SharpeRatio          *float64  `json:"sharpe_ratio"`          // 夏普比率（无风险利率按0计）
+	MaxDrawdownPct       float64   `json:"max_drawdown_pct"`      // 最大回撤(%)
+	MaxDrawdownPeak      time.Time `json:"max_drawdown_peak"`     // 最大回撤开始（前高）时间
+	MaxDrawdownHours     float64   `json:"max_drawdown_hours"`    // 最大回撤持续时长（前高到收复，未收复则到最后一个点）
+	MaxDrawdownRecovered bool      `json:"max_drawdown_recovered"`
+	CalmarRatio          *float64  `json:"calmar_ratio"` // 年化收益率 / 最大回撤
+	Partial              bool      `json:"partial"`      // 是否因样本不足只返回部分指标
+	Notes                []string  `json:"notes"`
+}
+
+// ParsePeriod 解析统计区间，支持 Nd（天）、Nh（小时）以及 all（全部，返回0）
+func ParsePeriod(s string) (time.Duration, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "all" {
+		return 0, nil
+	}
+	if len(s) < 2 {
+		return 0, fmt.Errorf("统计区间格式无效: %q（示例: 7d、30d、12h、all）", s)
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("统计区间格式无效: %q（示例: 7d、30d、12h、all）", s)
+	}
+	switch s[len(s)-1] {
+	case 'd':
+		return time.Duration(n) * 24 * time.Hour, nil
+	case 'h':
+		return time.Duration(n) * time.Hour, nil
+	}
+	return 0, fmt.Errorf("统计区间格式无效: %q（示例: 7d、30d、12h、all）", s)
+}
+
+// ComputeRiskMetrics 计算净值曲线的风险指标
+// 最大回撤使用全部净值点；收益率/波动率/夏普按UTC自然日收盘净值重采样后计算
+func ComputeRiskMetrics(points []EquityPoint) (*RiskMetrics, error) {
+	valid := make([]EquityPoint, 0, len(points))
+	for _, p := range points {
+		if p.Equity > 0 && !math.IsNaN(p.Equity) && !math.IsInf(p.Equity, 0) {
+			valid = append(valid, p)
+		}
+	}
+	if len(valid) < 2 {
+		return nil, fmt.Errorf("净值数据点不足（%d个），至少需要2个", len(valid))
+	}
+	sort.SliceStable(valid, func(i, j int) bool { return valid[i].Time.Before(valid[j].Time) })
+
+	first, last := valid[0], valid[len(valid)-1]
+	m := &RiskMetrics{
+		Start:          first.Time,
+		End:            last.Time,
+		Points:         len(valid),
+		StartEquity:    first.Equity,
+		EndEquity:      last.Equity,
+		TotalReturnPct: (last.Equity/first.Equity - 1) * 100,
+		Notes:          []string{},
+	}
+
+	computeDrawdown(valid, m)
+
+	// 年化收益率：区间不足1天时年化结果没有意义
+	elapsedDays := last.Time.Sub(first.Time).Hours() / 24
+	if elapsedDays >= 1 {
+		annualized := (math.Pow(last.Equity/first.Equity, TradingDaysPerYear/elapsedDays) - 1) * 100
+		m.AnnualizedReturnPct = &annualized
+		if m.MaxDrawdownPct > 0 {
+			calmar := annualized / m.MaxDrawdownPct
+			m.CalmarRatio = &calmar
+		} else {
+			m.Notes = append(m.Notes, "区间内无回撤，Calmar比率无法计算")
+		}
+	} else {
+		m.Partial = true
+		m.Notes = append(m.Notes, "数据区间不足1天，未计算年化收益率和Calmar比率")
+	}
+
+	returns := dailyReturns(valid)
+	m.Days = len(returns) + 1
+	if len(returns) >= minDailyReturns {
+		mean, stdev := meanStdev(returns)
+		volatility := stdev * math.Sqrt(TradingDaysPerYear) * 100
+		m.VolatilityPct = &volatility
+		if stdev > 0 {
+			sharpe := mean / stdev * math.Sqrt(TradingDaysPerYear)
+			m.SharpeRatio = &sharpe
+		} else {
+			m.Notes = append(m.Notes, "日收益率无波动，夏普比率无法计算")
+		}
+	} else {
+		m.Partial = true
+		m.Notes = append(m.Notes, fmt.Sprintf("日收益率样本不足（%d个，至少需要%d个），未计算波动率和夏普比率", len(returns), minDailyReturns))
+	}
+
+	return m, nil
+}
+
+// computeDrawdown 计算最大回撤及其持续时长（从前高到收复前高，未收复则到最后一个点）
+func computeDrawdown(points []EquityPoint, m *RiskMetrics) {
+	peak := points[0]
+	var maxDD float64
+	var maxPeak EquityPoint
+	for _, p := range points {
+		if p.Equity >= peak.Equity {
+			peak = p
+			continue
+		}
+		if dd := (peak.Equity - p.Equity) / peak.Equity * 100; dd > maxDD {
+			maxDD, maxPeak = dd, peak
+		}
+	}
+	if maxDD == 0 {
+		return
+	}
+
+	m.MaxDrawdownPct = maxDD
+	m.MaxDrawdownPeak = maxPeak.Time
+	end := points[len(points)-1].Time
+	for _, p := range points {
+		if p.Time.After(maxPeak.Time) && p.Equity >= maxPeak.Equity {
+			end = p.Time
+			m.MaxDrawdownRecovered = true
+			break
+		}
+	}
+	m.MaxDrawdownHours = end.Sub(maxPeak.Time).Hours()
+}
+
+// dailyReturns 按UTC自然日取每日最后一个净值，计算相邻两日的收益率
+func dailyReturns(points []EquityPoint) []float64 {
+	var closes []float64
+	var lastDay string
+	for _, p := range points {
+		day := p.Time.UTC().Format("2006-01-02")
+		if day == lastDay {
+			closes[len(closes)-1] = p.Equity
+			continue
+		}
+		closes = append(closes, p.Equity)
+		lastDay = day
+	}
+
+	returns := make([]float64, 0, len(closes))
+	for i := 1; i < len(closes); i++ {
+		returns = append(returns, closes[i]/closes[i-1]-1)
+	}
+	return returns
+}
+
+// meanStdev 均值与样本标准差（n-1）
+func meanStdev(values []float64) (float64, float64) {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	var sq float64
+	for _, v := range values {
+		sq += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(sq / float64(len(values)-1))
+}
@@ -0,0 +1,120 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var day0 = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func curve(hours []float64, equities []float64) []EquityPoint {
+	points := make([]EquityPoint, len(equities))
+	for i := range equities {
+		points[i] = EquityPoint{Time: day0.Add(time.Duration(hours[i] * float64(time.Hour))), Equity: equities[i]}
+	}
+	return points
+}
+
+func near(got, want, tol float64) bool {
+	return math.Abs(got-want) <= tol*math.Max(1, math.Abs(want))
+}
+
+func TestComputeRiskMetrics(t *testing.T) {
+	// 每日收盘净值 100 → 110 → 99 → 108.9，日收益率 +10%、-10%、+10%
+	// 日内的中间点只影响最大回撤，收益率按每日最后一个净值计算
+	points := curve(
+		[]float64{0, 24, 30, 47, 48, 72},
+		[]float64{100, 110, 104, 110, 99, 108.9},
+	)
+	m, err := ComputeRiskMetrics(points)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if m.Points != 6 || m.Days != 4 {
+		t.Errorf("Points/Days = %d/%d, want 6/4", m.Points, m.Days)
+	}
+	if !near(m.TotalReturnPct, 8.9, 1e-9) {
+		t.Errorf("TotalReturnPct = %v, want 8.9", m.TotalReturnPct)
+	}
+	if m.AnnualizedReturnPct == nil || !near(*m.AnnualizedReturnPct, (math.Pow(1.089, 365.0/3)-1)*100, 1e-9) {
+		t.Errorf("AnnualizedReturnPct = %v", m.AnnualizedReturnPct)
+	}
+	if m.VolatilityPct == nil || !near(*m.VolatilityPct, 220.60522810365728, 1e-9) {
+		t.Errorf("VolatilityPct = %v, want 220.605", m.VolatilityPct)
+	}
+	if m.SharpeRatio == nil || !near(*m.SharpeRatio, 5.515130702591431, 1e-9) {
+		t.Errorf("SharpeRatio = %v, want 5.515", m.SharpeRatio)
+	}
+	if !near(m.MaxDrawdownPct, 10, 1e-9) {
+		t.Errorf("MaxDrawdownPct = %v, want 10", m.MaxDrawdownPct)
+	}
+	// 前高为第二次到达110（47h），之后未收复，持续到最后一个点（72h）
+	if !m.MaxDrawdownPeak.Equal(day0.Add(47*time.Hour)) || m.MaxDrawdownRecovered || m.MaxDrawdownHours != 25 {
+		t.Errorf("drawdown peak=%v recovered=%v hours=%v, want 47h/false/25", m.MaxDrawdownPeak, m.MaxDrawdownRecovered, m.MaxDrawdownHours)
+	}
+	if m.CalmarRatio == nil || !near(*m.CalmarRatio, *m.AnnualizedReturnPct/10, 1e-9) {
+		t.Errorf("CalmarRatio = %v", m.CalmarRatio)
+	}
+	if m.Partial {
+		t.Errorf("完整样本不应标记为 partial: %v", m.Notes)
+	}
+}
+
+func TestComputeRiskMetricsRecoveredDrawdown(t *testing.T) {
+	points := curve([]float64{0, 6, 12, 18}, []float64{100, 80, 90, 120})
+	m, err := ComputeRiskMetrics(points)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !near(m.MaxDrawdownPct, 20, 1e-9) || !m.MaxDrawdownRecovered || m.MaxDrawdownHours != 18 {
+		t.Errorf("drawdown = %v%% recovered=%v hours=%v, want 20/true/18", m.MaxDrawdownPct, m.MaxDrawdownRecovered, m.MaxDrawdownHours)
+	}
+}
+
+func TestComputeRiskMetricsShortHistory(t *testing.T) {
+	if _, err := ComputeRiskMetrics(curve([]float64{0}, []float64{100})); err == nil {
+		t.Error("单个数据点应返回错误")
+	}
+	if _, err := ComputeRiskMetrics(curve([]float64{0, 1}, []float64{100, 0})); err == nil {
+		t.Error("无效净值应被忽略")
+	}
+
+	// 不足1天：只返回总收益率和最大回撤
+	m, err := ComputeRiskMetrics(curve([]float64{0, 3, 6}, []float64{100, 95, 102}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !m.Partial || len(m.Notes) == 0 {
+		t.Errorf("短样本应标记 partial 并附说明, got partial=%v notes=%v", m.Partial, m.Notes)
+	}
+	if m.AnnualizedReturnPct != nil || m.VolatilityPct != nil || m.SharpeRatio != nil || m.CalmarRatio != nil {
+		t.Errorf("短样本不应返回年化/波动率/夏普/Calmar")
+	}
+	if !near(m.TotalReturnPct, 2, 1e-9) || !near(m.MaxDrawdownPct, 5, 1e-9) {
+		t.Errorf("TotalReturnPct/MaxDrawdownPct = %v/%v, want 2/5", m.TotalReturnPct, m.MaxDrawdownPct)
+	}
+}
+
+func TestParsePeriod(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"30d", 30 * 24 * time.Hour, false},
+		{"12h", 12 * time.Hour, false},
+		{"ALL", 0, false},
+		{"0d", 0, true},
+		{"30", 0, true},
+		{"1w", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePeriod(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParsePeriod(%q) = %v, %v; want %v, err=%v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package api
+
+import "nofx/mcp"
+
+// aiModelProvider 查找用户AI模型配置对应的提供商（用于按提供商校验采样参数），未找到时按自定义API处理
+func (s *Server) aiModelProvider(userID, aiModelID string) mcp.Provider {
+	models, err := s.database.GetAIModels(userID)
+	if err != nil {
+		return mcp.ProviderCustom
+	}
+	for _, model := range models {
+		if model.ID == aiModelID || model.Provider == aiModelID {
+			return mcp.Provider(model.Provider)
+		}
+	}
+	return mcp.ProviderCustom
+}
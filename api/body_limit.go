@@ -0,0 +1,41 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bodyLimitMiddleware 限制请求体大小，防止超大请求体（如巨型 custom_prompt、trader_ids）耗尽内存。
+// Content-Length 超过上限时直接返回 413；未声明长度（分块传输）的请求按上限读取，超过时同样返回 413，
+// 处理器不会解析到截断的请求体。maxBytes<=0 表示不限制
+func bodyLimitMiddleware(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if maxBytes <= 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > maxBytes {
+			respondError(c, http.StatusRequestEntityTooLarge, ErrCodeRequestTooLarge, maxBytes)
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				respondError(c, http.StatusRequestEntityTooLarge, ErrCodeRequestTooLarge, maxBytes)
+			} else {
+				respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err)
+			}
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Next()
+	}
+}
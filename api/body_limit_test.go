@@ -0,0 +1,69 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newRouter := func(limit int64) *gin.Engine {
+		router := gin.New()
+		router.Use(bodyLimitMiddleware(limit))
+		router.POST("/api/traders", func(c *gin.Context) {
+			var req struct {
+				CustomPrompt string   `json:"custom_prompt"`
+				TraderIDs    []string `json:"trader_ids" binding:"max=3"`
+			}
+			if err := c.ShouldBindJSON(&req); err != nil {
+				respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err)
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"prompt_len": len(req.CustomPrompt)})
+		})
+		return router
+	}
+	prompt := func(n int) string {
+		return `{"custom_prompt":"` + strings.Repeat("x", n) + `"}`
+	}
+
+	tests := []struct {
+		name       string
+		limit      int64
+		body       string
+		chunked    bool // 不声明 Content-Length（分块传输）
+		wantStatus int
+	}{
+		{name: "未超过上限正常解析", limit: 1024, body: prompt(100), wantStatus: http.StatusOK},
+		{name: "Content-Length超过上限返回413", limit: 1024, body: prompt(4096), wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "分块传输超过上限返回413", limit: 1024, body: prompt(4096), chunked: true, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "分块传输未超过上限正常解析", limit: 1024, body: prompt(100), chunked: true, wantStatus: http.StatusOK},
+		{name: "上限为0时不限制", limit: 0, body: prompt(4096), wantStatus: http.StatusOK},
+		{name: "数组长度超过上限返回400", limit: 1024, body: `{"trader_ids":["a","b","c","d"]}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader = strings.NewReader(tt.body)
+			if tt.chunked {
+				body = io.MultiReader(body) // 隐藏长度，httptest 不会设置 Content-Length
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api/traders", body)
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			newRouter(tt.limit).ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusRequestEntityTooLarge && !strings.Contains(w.Body.String(), string(ErrCodeRequestTooLarge)) {
+				t.Errorf("413 响应缺少错误码: %s", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"fmt"
+
+	"nofx/market"
+)
+
+// fetchMarkPrice 获取币种当前价格（可替换，便于测试）
+var fetchMarkPrice = func(symbol string) (float64, error) {
+	data, err := market.Get(symbol)
+	if err != nil {
+		return 0, err
+	}
+	return data.CurrentPrice, nil
+}
+
+// closeQuantityForNotional 按平仓金额（USDT）和当前价格换算平仓数量，超过持仓数量时按全部持仓平仓
+func closeQuantityForNotional(symbol string, notionalUSD, positionQty float64) (float64, error) {
+	price, err := fetchMarkPrice(symbol)
+	if err != nil {
+		return 0, fmt.Errorf("获取 %s 当前价格失败: %w", symbol, err)
+	}
+	if price <= 0 {
+		return 0, fmt.Errorf("%s 当前价格无效: %v", symbol, price)
+	}
+	quantity := notionalUSD / price
+	if quantity > positionQty {
+		quantity = positionQty
+	}
+	return quantity, nil
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestCloseQuantityForNotional(t *testing.T) {
+	origFetch := fetchMarkPrice
+	defer func() { fetchMarkPrice = origFetch }()
+	fetchMarkPrice = func(symbol string) (float64, error) {
+		if symbol != "BTCUSDT" {
+			return 0, errors.New("unknown symbol")
+		}
+		return 50000, nil
+	}
+
+	tests := []struct {
+		name        string
+		notional    float64
+		positionQty float64
+		want        float64
+	}{
+		{"按金额换算数量", 500, 0.1, 0.01},
+		{"金额超过持仓时全部平仓", 10000, 0.1, 0.1},
+		{"金额恰好等于持仓价值", 5000, 0.1, 0.1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := closeQuantityForNotional("BTCUSDT", tt.notional, tt.positionQty)
+			if err != nil {
+				t.Fatalf("返回错误: %v", err)
+			}
+			if math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("平仓数量 = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := closeQuantityForNotional("ETHUSDT", 500, 1); err == nil {
+		t.Errorf("获取价格失败时应返回错误")
+	}
+}
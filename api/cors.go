@@ -0,0 +1,79 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// corsPolicy 跨域访问策略
+type corsPolicy struct {
+	allowAll bool            // 配置了 "*"：允许任意来源，但不允许携带凭证（CORS 规范禁止通配符+凭证）
+	origins  map[string]bool // 允许的来源（已规范化）
+}
+
+// newCORSPolicy 解析逗号分隔的来源列表
+func newCORSPolicy(allowedOrigins string) *corsPolicy {
+	p := &corsPolicy{origins: make(map[string]bool)}
+	for _, origin := range strings.Split(allowedOrigins, ",") {
+		origin = normalizeOrigin(origin)
+		if origin == "" {
+			continue
+		}
+		if origin == "*" {
+			p.allowAll = true
+			continue
+		}
+		p.origins[origin] = true
+	}
+	return p
+}
+
+// normalizeOrigin 去掉首尾空白和末尾斜杠，并统一为小写
+func normalizeOrigin(origin string) string {
+	return strings.ToLower(strings.TrimRight(strings.TrimSpace(origin), "/"))
+}
+
+// corsMiddleware CORS中间件：只回显白名单内的来源并允许携带凭证；
+// 配置 "*" 时返回通配符且不允许凭证；无 Origin 的请求（服务端调用）不处理跨域头
+func corsMiddleware(allowedOrigins string) gin.HandlerFunc {
+	policy := newCORSPolicy(allowedOrigins)
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		header.Add("Vary", "Origin")
+		allowed := true
+		switch {
+		case policy.origins[normalizeOrigin(origin)]:
+			header.Set("Access-Control-Allow-Origin", origin)
+			header.Set("Access-Control-Allow-Credentials", "true")
+		case policy.allowAll:
+			header.Set("Access-Control-Allow-Origin", "*")
+		default:
+			allowed = false
+		}
+
+		if allowed {
+			header.Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			header.Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, Accept-Language")
+			header.Set("Access-Control-Max-Age", "86400")
+		}
+
+		if c.Request.Method == "OPTIONS" {
+			if !allowed {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.AbortWithStatus(http.StatusOK)
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newRouter := func(allowed string) *gin.Engine {
+		router := gin.New()
+		router.Use(corsMiddleware(allowed))
+		router.GET("/api/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+		return router
+	}
+
+	tests := []struct {
+		name            string
+		allowed         string
+		method          string
+		origin          string
+		wantStatus      int
+		wantOrigin      string
+		wantCredentials string
+	}{
+		{name: "白名单来源回显并允许凭证", allowed: "https://app.example.com, http://localhost:3000", method: "GET", origin: "https://app.example.com", wantStatus: http.StatusOK, wantOrigin: "https://app.example.com", wantCredentials: "true"},
+		{name: "不在白名单的来源不返回ACAO", allowed: "https://app.example.com", method: "GET", origin: "https://evil.example.com", wantStatus: http.StatusOK},
+		{name: "不在白名单的预检请求被拒绝", allowed: "https://app.example.com", method: "OPTIONS", origin: "https://evil.example.com", wantStatus: http.StatusForbidden},
+		{name: "白名单预检请求", allowed: "https://app.example.com/", method: "OPTIONS", origin: "https://APP.example.com", wantStatus: http.StatusOK, wantOrigin: "https://APP.example.com", wantCredentials: "true"},
+		{name: "通配符不允许凭证", allowed: "*", method: "GET", origin: "https://any.example.com", wantStatus: http.StatusOK, wantOrigin: "*"},
+		{name: "无Origin的服务端请求直接放行", allowed: "https://app.example.com", method: "GET", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/health", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			w := httptest.NewRecorder()
+			newRouter(tt.allowed).ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials"); got != tt.wantCredentials {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want %q", got, tt.wantCredentials)
+			}
+		})
+	}
+}
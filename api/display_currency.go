@@ -0,0 +1,125 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 展示币种：交易与存储始终使用 USDT，仅在账户/净值接口中按当前汇率附加换算后的数值，保证各客户端展示一致
+const (
+	defaultDisplayCurrency = "USDT"
+	displayRateCacheTTL    = 60 * time.Second // 换算汇率缓存时间
+)
+
+// cryptoDisplayCurrencies 按交易所 {币种}USDT 实时价格换算的加密货币展示币种
+var cryptoDisplayCurrencies = map[string]bool{"BTC": true, "ETH": true}
+
+// accountDisplayFields 账户信息中需要换算的金额字段（百分比、数量类字段不换算）
+var accountDisplayFields = []string{
+	"total_equity", "wallet_balance", "unrealized_profit", "available_balance",
+	"total_pnl", "total_unrealized_pnl", "initial_balance", "daily_pnl", "margin_used",
+}
+
+// displayRateEntry 展示汇率缓存项
+type displayRateEntry struct {
+	rate      float64
+	fetchedAt time.Time
+}
+
+// normalizeDisplayCurrency 统一展示币种格式（大写，空值为 USDT）
+func normalizeDisplayCurrency(currency string) string {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return defaultDisplayCurrency
+	}
+	return currency
+}
+
+// validDisplayCurrency 展示币种是否受支持：USDT、BTC/ETH 或已配置汇率的法币
+func validDisplayCurrency(currency string, fxRates map[string]float64) bool {
+	if currency == defaultDisplayCurrency || cryptoDisplayCurrencies[currency] {
+		return true
+	}
+	_, ok := fxRates[currency]
+	return ok
+}
+
+// usdtToDisplayRate 计算 1 USDT 折合多少展示币种：加密货币取 {币种}USDT 价格的倒数，法币使用配置的汇率
+func usdtToDisplayRate(currency string, fxRates map[string]float64, price func(symbol string) (float64, error)) (float64, error) {
+	if currency == defaultDisplayCurrency {
+		return 1, nil
+	}
+	if cryptoDisplayCurrencies[currency] {
+		p, err := price(currency + "USDT")
+		if err != nil {
+			return 0, fmt.Errorf("获取 %sUSDT 价格失败: %w", currency, err)
+		}
+		if p <= 0 {
+			return 0, fmt.Errorf("%sUSDT 价格无效: %v", currency, p)
+		}
+		return 1 / p, nil
+	}
+	if rate, ok := fxRates[currency]; ok {
+		return rate, nil
+	}
+	return 0, fmt.Errorf("不支持的展示币种: %s", currency)
+}
+
+// convertAccountForDisplay 按汇率换算账户信息中的金额字段，返回附加在响应中的 display 对象
+func convertAccountForDisplay(account map[string]interface{}, currency string, rate float64) gin.H {
+	display := gin.H{"currency": currency, "rate": rate}
+	for _, key := range accountDisplayFields {
+		if value, ok := account[key].(float64); ok {
+			display[key] = value * rate
+		}
+	}
+	return display
+}
+
+// userDisplayRate 获取用户的展示币种及 1 USDT 的换算汇率（带缓存）
+// 展示币种为 USDT 或汇率获取失败时返回 ok=false，调用方只返回原始 USDT 数值
+func (s *Server) userDisplayRate(userID string) (currency string, rate float64, ok bool) {
+	currency = normalizeDisplayCurrency(s.database.GetUserDisplayCurrency(userID))
+	if currency == defaultDisplayCurrency {
+		return currency, 1, false
+	}
+
+	if cached, found := s.displayRates.Load(currency); found {
+		entry := cached.(*displayRateEntry)
+		if time.Since(entry.fetchedAt) < displayRateCacheTTL {
+			return currency, entry.rate, true
+		}
+	}
+	rate, err := usdtToDisplayRate(currency, s.database.GetDisplayFXRates(), fetchMarkPrice)
+	if err != nil {
+		return currency, 0, false
+	}
+	s.displayRates.Store(currency, &displayRateEntry{rate: rate, fetchedAt: time.Now()})
+	return currency, rate, true
+}
+
+// handleUpdateUserAccount 更新用户账户设置（目前支持展示币种 display_currency）
+func (s *Server) handleUpdateUserAccount(c *gin.Context) {
+	var req struct {
+		DisplayCurrency string `json:"display_currency"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err)
+		return
+	}
+
+	currency := normalizeDisplayCurrency(req.DisplayCurrency)
+	if !validDisplayCurrency(currency, s.database.GetDisplayFXRates()) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidDisplayCurrency, currency)
+		return
+	}
+	if err := s.database.UpdateUserDisplayCurrency(c.GetString("user_id"), currency); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"display_currency": currency})
+}
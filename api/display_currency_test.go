@@ -0,0 +1,84 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestUSDTToDisplayRate(t *testing.T) {
+	price := func(symbol string) (float64, error) {
+		switch symbol {
+		case "BTCUSDT":
+			return 50000, nil
+		case "ETHUSDT":
+			return 0, errors.New("no data")
+		}
+		return 0, errors.New("unexpected symbol " + symbol)
+	}
+	fxRates := map[string]float64{"EUR": 0.92}
+
+	tests := []struct {
+		name     string
+		currency string
+		wantRate float64
+		wantErr  bool
+	}{
+		{"USDT 不换算", "USDT", 1, false},
+		{"BTC 按实时价格取倒数", "BTC", 1.0 / 50000, false},
+		{"法币按配置汇率", "EUR", 0.92, false},
+		{"价格获取失败", "ETH", 0, true},
+		{"未配置的币种", "JPY", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate, err := usdtToDisplayRate(tt.currency, fxRates, price)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("usdtToDisplayRate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if math.Abs(rate-tt.wantRate) > 1e-15 {
+				t.Errorf("usdtToDisplayRate() = %v, want %v", rate, tt.wantRate)
+			}
+		})
+	}
+}
+
+func TestConvertAccountForDisplayBTC(t *testing.T) {
+	rate, err := usdtToDisplayRate("BTC", nil, func(string) (float64, error) { return 50000, nil })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	account := map[string]interface{}{
+		"total_equity":   10000.0,
+		"total_pnl":      -500.0,
+		"total_pnl_pct":  -5.0,
+		"position_count": 2,
+	}
+
+	display := convertAccountForDisplay(account, "BTC", rate)
+
+	if got := display["total_equity"].(float64); math.Abs(got-0.2) > 1e-12 {
+		t.Errorf("total_equity = %v, want 0.2 BTC", got)
+	}
+	if got := display["total_pnl"].(float64); math.Abs(got+0.01) > 1e-12 {
+		t.Errorf("total_pnl = %v, want -0.01 BTC", got)
+	}
+	if _, ok := display["total_pnl_pct"]; ok {
+		t.Error("percentage fields should not be converted")
+	}
+	if account["total_equity"] != 10000.0 {
+		t.Error("raw USDT values must stay unchanged")
+	}
+}
+
+func TestValidDisplayCurrency(t *testing.T) {
+	fxRates := map[string]float64{"CNY": 7.2}
+	for currency, want := range map[string]bool{"USDT": true, "BTC": true, "ETH": true, "CNY": true, "EUR": false} {
+		if got := validDisplayCurrency(currency, fxRates); got != want {
+			t.Errorf("validDisplayCurrency(%q) = %v, want %v", currency, got, want)
+		}
+	}
+	if got := normalizeDisplayCurrency(" btc "); got != "BTC" {
+		t.Errorf("normalizeDisplayCurrency() = %q, want BTC", got)
+	}
+}
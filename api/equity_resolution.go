@@ -0,0 +1,50 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"nofx/logger"
+)
+
+// 收益曲线降采样粒度（resolution 参数）
+const (
+	equityResolutionRaw    = "raw"    // 原始数据点（默认，兼容旧前端）
+	equityResolutionHourly = "hourly" // 每小时一个点
+	equityResolutionDaily  = "daily"  // 每天一个点（UTC）
+)
+
+// parseEquityResolution 解析 resolution 参数为分桶时长，raw/空字符串返回0（不降采样）
+func parseEquityResolution(resolution string) (time.Duration, error) {
+	switch strings.ToLower(strings.TrimSpace(resolution)) {
+	case "", equityResolutionRaw:
+		return 0, nil
+	case equityResolutionHourly:
+		return time.Hour, nil
+	case equityResolutionDaily:
+		return 24 * time.Hour, nil
+	default:
+		return 0, fmt.Errorf("不支持的 resolution: %s（可选 raw/hourly/daily）", resolution)
+	}
+}
+
+// downsampleEquityRecords 按时间分桶降采样，每个桶保留最后一条记录（即该时段的收盘净值）
+// records 需按时间正序排列；bucket<=0 时原样返回
+func downsampleEquityRecords(records []*logger.DecisionRecord, bucket time.Duration) []*logger.DecisionRecord {
+	if bucket <= 0 || len(records) == 0 {
+		return records
+	}
+	sampled := make([]*logger.DecisionRecord, 0)
+	var lastKey time.Time
+	for _, record := range records {
+		key := record.Timestamp.UTC().Truncate(bucket)
+		if len(sampled) > 0 && key.Equal(lastKey) {
+			sampled[len(sampled)-1] = record
+			continue
+		}
+		sampled = append(sampled, record)
+		lastKey = key
+	}
+	return sampled
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"nofx/logger"
+)
+
+func TestParseEquityResolution(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"raw", 0, false},
+		{"Hourly", time.Hour, false},
+		{"daily", 24 * time.Hour, false},
+		{"weekly", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseEquityResolution(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseEquityResolution(%q) = %v, %v; want %v, wantErr %v", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestDownsampleEquityRecords(t *testing.T) {
+	// 3天、每3分钟一条的密集序列
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	var records []*logger.DecisionRecord
+	for i := 0; i < 3*24*20; i++ {
+		records = append(records, &logger.DecisionRecord{
+			Timestamp:    start.Add(time.Duration(i) * 3 * time.Minute),
+			CycleNumber:  i + 1,
+			AccountState: logger.AccountSnapshot{TotalBalance: 1000 + float64(i)},
+		})
+	}
+
+	t.Run("daily 每天一个点，取当天最后一条", func(t *testing.T) {
+		daily := downsampleEquityRecords(records, 24*time.Hour)
+		if len(daily) != 3 {
+			t.Fatalf("len = %d, want 3", len(daily))
+		}
+		for day, r := range daily {
+			wantCycle := (day + 1) * 24 * 20
+			if r.CycleNumber != wantCycle {
+				t.Errorf("day %d cycle = %d, want %d", day, r.CycleNumber, wantCycle)
+			}
+			if r.Timestamp.Day() != start.Day()+day {
+				t.Errorf("day %d timestamp = %v", day, r.Timestamp)
+			}
+		}
+	})
+
+	t.Run("hourly 每小时一个点", func(t *testing.T) {
+		if got := len(downsampleEquityRecords(records, time.Hour)); got != 72 {
+			t.Errorf("len = %d, want 72", got)
+		}
+	})
+
+	t.Run("raw 原样返回", func(t *testing.T) {
+		if got := len(downsampleEquityRecords(records, 0)); got != len(records) {
+			t.Errorf("len = %d, want %d", got, len(records))
+		}
+	})
+}
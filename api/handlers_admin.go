@@ -0,0 +1,195 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"nofx/auth"
+	"nofx/config"
+	"nofx/trader"
+)
+
+// adminStatsCacheTTL 管理员统计中数据库聚合部分的缓存时长
+const adminStatsCacheTTL = 30 * time.Second
+
+// processStartTime 进程启动时间（用于计算运行时长）
+var processStartTime = time.Now()
+
+// adminStatsCache 数据库聚合统计缓存，避免每次请求都全表扫描
+type adminStatsCache struct {
+	mu        sync.Mutex
+	stats     *config.SystemStats
+	fetchedAt time.Time
+}
+
+// adminMiddleware 管理员权限中间件（需在 authMiddleware 之后使用）
+func (s *Server) adminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("user_id")
+
+		// 管理员模式下登录的内置 admin 账号
+		if auth.IsAdminMode() && userID == "admin" {
+			c.Next()
+			return
+		}
+
+		user, err := s.database.GetUserByID(userID)
+		if err != nil {
+			respondError(c, http.StatusUnauthorized, ErrCodeUserNotFound)
+			c.Abort()
+			return
+		}
+		if user.Role != "admin" {
+			respondError(c, http.StatusForbidden, ErrCodeAdminRequired)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// getSystemStats 获取数据库聚合统计（带短TTL缓存），返回统计和数据生成时间
+func (s *Server) getSystemStats() (*config.SystemStats, time.Time, error) {
+	s.adminStats.mu.Lock()
+	defer s.adminStats.mu.Unlock()
+
+	if s.adminStats.stats != nil && time.Since(s.adminStats.fetchedAt) < adminStatsCacheTTL {
+		return s.adminStats.stats, s.adminStats.fetchedAt, nil
+	}
+
+	stats, err := s.database.GetSystemStats()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	s.adminStats.stats = stats
+	s.adminStats.fetchedAt = time.Now()
+	return stats, s.adminStats.fetchedAt, nil
+}
+
+// handleAdminStats 系统级统计：用户、交易员分布、当日AI调用、数据库连接池和进程资源
+func (s *Server) handleAdminStats(c *gin.Context) {
+	stats, statsAt, err := s.getSystemStats()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	loaded, running, aiCallsToday := s.traderManager.GetRuntimeStats()
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	dbStats := s.database.DBStats()
+
+	c.JSON(http.StatusOK, gin.H{
+		"users": gin.H{
+			"total": stats.TotalUsers,
+		},
+		"traders": gin.H{
+			"total":             stats.TotalTraders,
+			"running":           stats.RunningTraders,
+			"stopped":           stats.StoppedTraders,
+			"by_exchange":       stats.TradersByExchange,
+			"by_ai_provider":    stats.TradersByAIProvider,
+			"loaded_in_memory":  loaded,
+			"running_in_memory": running,
+		},
+		"ai_calls_today": aiCallsToday,
+		"database": gin.H{
+			"open_connections": dbStats.OpenConnections,
+			"in_use":           dbStats.InUse,
+			"idle":             dbStats.Idle,
+			"wait_count":       dbStats.WaitCount,
+			"wait_duration_ms": dbStats.WaitDuration.Milliseconds(),
+		},
+		"process": gin.H{
+			"start_time":     processStartTime.Format(time.RFC3339),
+			"uptime_seconds": int64(time.Since(processStartTime).Seconds()),
+			"goroutines":     runtime.NumGoroutine(),
+			"heap_alloc_mb":  float64(mem.HeapAlloc) / 1024 / 1024,
+			"sys_mb":         float64(mem.Sys) / 1024 / 1024,
+			"num_gc":         mem.NumGC,
+		},
+		"stats_cached_at": statsAt.Format(time.RFC3339),
+	})
+}
+
+// handleRotateEncryptionKey 轮换数据加密密钥并重新加密所有已存储的密钥（无法解密的字段跳过并在响应中列出）
+func (s *Server) handleRotateEncryptionKey(c *gin.Context) {
+	log.Printf("🔑 管理员 %s 发起数据加密密钥轮换", c.GetString("user_id"))
+
+	version, reencrypted, skipped, err := s.database.RotateEncryptionKey()
+	if err != nil {
+		log.Printf("❌ 数据加密密钥轮换失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"key_version":        version,
+		"reencrypted_fields": reencrypted,
+		"skipped_fields":     skipped,
+		"rotated_at":         time.Now().Format(time.RFC3339),
+	})
+}
+
+// maintenanceBanner 维护模式提示（503 风格，随系统配置返回给前端展示）
+func maintenanceBanner(c *gin.Context) gin.H {
+	lang := c.GetString(langContextKey)
+	if lang == "" {
+		lang = parseAcceptLanguage(c.GetHeader("Accept-Language"))
+	}
+	return gin.H{
+		"status":  http.StatusServiceUnavailable,
+		"code":    ErrCodeMaintenanceMode,
+		"message": localizeError(lang, ErrCodeMaintenanceMode),
+	}
+}
+
+// handleSetMaintenanceMode 开启/关闭平台维护模式（持久化到系统配置并立即对所有交易员生效）
+func (s *Server) handleSetMaintenanceMode(c *gin.Context) {
+	var req struct {
+		Enabled *bool `json:"enabled" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err)
+		return
+	}
+
+	if err := s.database.SetMaintenanceMode(*req.Enabled); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	trader.SetMaintenanceMode(*req.Enabled)
+	log.Printf("🛠 管理员 %s 将平台维护模式设置为 %v", c.GetString("user_id"), *req.Enabled)
+
+	c.JSON(http.StatusOK, gin.H{"maintenance_mode": *req.Enabled})
+}
+
+// handleGetTokenBlacklist 查看登出token黑名单当前有效条目数
+func (s *Server) handleGetTokenBlacklist(c *gin.Context) {
+	pruneInterval := s.database.GetTokenBlacklistPruneInterval()
+	if pruneInterval <= 0 {
+		pruneInterval = auth.DefaultBlacklistPruneInterval
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"size":                   auth.BlacklistSize(),
+		"prune_interval_minutes": int(pruneInterval.Minutes()),
+	})
+}
+
+// handleClearTokenBlacklist 清空登出token黑名单（已登出但未过期的token将重新生效）
+func (s *Server) handleClearTokenBlacklist(c *gin.Context) {
+	cleared, err := auth.ClearBlacklist()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("🧹 管理员 %s 清空了token黑名单（%d 条）", c.GetString("user_id"), cleared)
+
+	c.JSON(http.StatusOK, gin.H{"cleared": cleared})
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"nofx/config"
+)
+
+// leverageSetter 运行中交易员的杠杆热更新接口（*trader.AutoTrader 实现）
+type leverageSetter interface {
+	SetLeverageConfig(btcEthLeverage, altcoinLeverage int)
+}
+
+// bulkLeverageResult 批量调整杠杆时单个交易员的处理结果
+type bulkLeverageResult struct {
+	TraderID        string `json:"trader_id"`
+	TraderName      string `json:"trader_name,omitempty"`
+	Success         bool   `json:"success"`
+	Running         bool   `json:"running"` // 是否已对运行中的交易员立即生效
+	BTCETHLeverage  int    `json:"btc_eth_leverage,omitempty"`
+	AltcoinLeverage int    `json:"altcoin_leverage,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+// applyBulkLeverage 对用户名下的交易员批量调整杠杆（0表示保持原值）：先持久化，运行中的交易员再热更新
+// traderIDs 为空时应用到全部交易员；不属于该用户的交易员返回失败结果，不做修改
+func applyBulkLeverage(owned []*config.TraderRecord, traderIDs []string, btcEthLeverage, altcoinLeverage int,
+	save func(id string, btcEthLeverage, altcoinLeverage int) error, running func(id string) leverageSetter) []bulkLeverageResult {
+	byID := make(map[string]*config.TraderRecord, len(owned))
+	for _, record := range owned {
+		byID[record.ID] = record
+	}
+	if len(traderIDs) == 0 {
+		for _, record := range owned {
+			traderIDs = append(traderIDs, record.ID)
+		}
+	}
+
+	results := make([]bulkLeverageResult, 0, len(traderIDs))
+	for _, id := range traderIDs {
+		record, ok := byID[id]
+		if !ok {
+			results = append(results, bulkLeverageResult{TraderID: id, Error: "交易员不存在或无权操作"})
+			continue
+		}
+
+		result := bulkLeverageResult{
+			TraderID:        id,
+			TraderName:      record.Name,
+			BTCETHLeverage:  record.BTCETHLeverage,
+			AltcoinLeverage: record.AltcoinLeverage,
+		}
+		if btcEthLeverage > 0 {
+			result.BTCETHLeverage = btcEthLeverage
+		}
+		if altcoinLeverage > 0 {
+			result.AltcoinLeverage = altcoinLeverage
+		}
+		if err := save(id, result.BTCETHLeverage, result.AltcoinLeverage); err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+		if at := running(id); at != nil {
+			at.SetLeverageConfig(result.BTCETHLeverage, result.AltcoinLeverage)
+			result.Running = true
+		}
+		result.Success = true
+		results = append(results, result)
+	}
+	return results
+}
+
+// handleBulkUpdateLeverage 批量调整当前用户交易员的杠杆（未指定 trader_ids 时应用到全部交易员），运行中的交易员立即生效
+func (s *Server) handleBulkUpdateLeverage(c *gin.Context) {
+	userID := c.GetString("user_id")
+	var req struct {
+		BTCETHLeverage  int      `json:"btc_eth_leverage"`
+		AltcoinLeverage int      `json:"altcoin_leverage"`
+		TraderIDs       []string `json:"trader_ids" binding:"max=200"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err)
+		return
+	}
+	if req.BTCETHLeverage == 0 && req.AltcoinLeverage == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "btc_eth_leverage 和 altcoin_leverage 至少需要提供一个"})
+		return
+	}
+	if !s.validateLeverageCeilings(c, req.BTCETHLeverage, req.AltcoinLeverage) {
+		return
+	}
+
+	owned, err := s.database.GetTradersByOwnerUserID(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	results := applyBulkLeverage(owned, req.TraderIDs, req.BTCETHLeverage, req.AltcoinLeverage,
+		s.database.UpdateTraderLeverage,
+		func(id string) leverageSetter {
+			if at, err := s.traderManager.GetTrader(id); err == nil && at != nil {
+				return at
+			}
+			return nil
+		})
+
+	updated := 0
+	for _, r := range results {
+		if r.Success {
+			updated++
+		}
+	}
+	log.Printf("✓ 用户 %s 批量调整杠杆: BTC/ETH=%d 山寨币=%d，成功 %d/%d", userID, req.BTCETHLeverage, req.AltcoinLeverage, updated, len(results))
+	c.JSON(http.StatusOK, gin.H{"updated": updated, "results": results})
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"errors"
+	"testing"
+
+	"nofx/config"
+)
+
+type fakeLeverageSetter struct {
+	btcEth, altcoin int
+}
+
+func (f *fakeLeverageSetter) SetLeverageConfig(btcEthLeverage, altcoinLeverage int) {
+	f.btcEth, f.altcoin = btcEthLeverage, altcoinLeverage
+}
+
+func TestApplyBulkLeverage(t *testing.T) {
+	owned := []*config.TraderRecord{
+		{ID: "running", Name: "运行中", BTCETHLeverage: 10, AltcoinLeverage: 5},
+		{ID: "stopped", Name: "已停止", BTCETHLeverage: 20, AltcoinLeverage: 8},
+	}
+	type saved struct{ btcEth, altcoin int }
+
+	t.Run("未指定交易员时应用到全部：运行中立即生效，已停止的持久化", func(t *testing.T) {
+		persisted := map[string]saved{}
+		live := &fakeLeverageSetter{}
+		results := applyBulkLeverage(owned, nil, 3, 0,
+			func(id string, btcEth, altcoin int) error {
+				persisted[id] = saved{btcEth, altcoin}
+				return nil
+			},
+			func(id string) leverageSetter {
+				if id == "running" {
+					return live
+				}
+				return nil
+			})
+
+		if len(results) != 2 || !results[0].Success || !results[1].Success {
+			t.Fatalf("results = %+v, want 2 successes", results)
+		}
+		if *live != (fakeLeverageSetter{btcEth: 3, altcoin: 5}) || !results[0].Running {
+			t.Errorf("running trader leverage = %+v, want btc/eth 3 and altcoin unchanged 5", *live)
+		}
+		if persisted["stopped"] != (saved{3, 8}) || results[1].Running {
+			t.Errorf("stopped trader persisted = %+v, want {3 8} and not running", persisted["stopped"])
+		}
+		if persisted["running"] != (saved{3, 5}) {
+			t.Errorf("running trader persisted = %+v, want {3 5}", persisted["running"])
+		}
+	})
+
+	t.Run("不属于当前用户的交易员不做修改", func(t *testing.T) {
+		calls := 0
+		results := applyBulkLeverage(owned, []string{"stopped", "other"}, 2, 2,
+			func(string, int, int) error { calls++; return nil },
+			func(string) leverageSetter { return nil })
+
+		if len(results) != 2 || !results[0].Success || results[1].Success || results[1].Error == "" {
+			t.Fatalf("results = %+v, want stopped ok and other rejected", results)
+		}
+		if calls != 1 {
+			t.Errorf("save calls = %d, want 1", calls)
+		}
+	})
+
+	t.Run("持久化失败时不热更新", func(t *testing.T) {
+		live := &fakeLeverageSetter{}
+		results := applyBulkLeverage(owned, []string{"running"}, 2, 2,
+			func(string, int, int) error { return errors.New("db down") },
+			func(string) leverageSetter { return live })
+
+		if results[0].Success || results[0].Running || live.btcEth != 0 {
+			t.Errorf("result = %+v live = %+v, want failure without live update", results[0], *live)
+		}
+	})
+}
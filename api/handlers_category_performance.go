@@ -0,0 +1,183 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"nofx/config"
+)
+
+// categoryPerformanceTimeout 分类表现各交易员账户数据的共享获取超时
+const categoryPerformanceTimeout = 10 * time.Second
+
+// CategoryTraderPerformance 分类中单个交易员的账户表现
+type CategoryTraderPerformance struct {
+	TraderID       string  `json:"trader_id"`
+	TraderName     string  `json:"trader_name"`
+	TotalEquity    float64 `json:"total_equity"`
+	InitialBalance float64 `json:"initial_balance"`
+	TotalPnL       float64 `json:"total_pnl"`
+	TotalPnLPct    float64 `json:"total_pnl_pct"`
+}
+
+// CategoryPerformance 分类内所有交易员的聚合表现（部分交易员获取失败时只聚合成功的部分，失败原因见 Errors）
+type CategoryPerformance struct {
+	CategoryID       int                         `json:"category_id"`
+	Category         string                      `json:"category"`
+	TraderCount      int                         `json:"trader_count"`       // 分类中的交易员数
+	ReportedCount    int                         `json:"reported_count"`     // 成功获取数据的交易员数
+	CombinedEquity   float64                     `json:"combined_equity"`    // 合计净值
+	CombinedPnL      float64                     `json:"combined_pnl"`       // 合计盈亏
+	CombinedPnLPct   float64                     `json:"combined_pnl_pct"`   // 合计盈亏 / 合计初始余额
+	AverageReturnPct float64                     `json:"average_return_pct"` // 各交易员收益率的平均值
+	BestPerformer    *CategoryTraderPerformance  `json:"best_performer"`     // 收益率最高的交易员
+	WorstPerformer   *CategoryTraderPerformance  `json:"worst_performer"`    // 收益率最低的交易员
+	Traders          []CategoryTraderPerformance `json:"traders"`            // 按收益率从高到低
+	Errors           map[string]string           `json:"errors,omitempty"`   // trader_id -> 获取失败原因
+	FetchedAt        time.Time                   `json:"fetched_at"`
+}
+
+// aggregateCategoryPerformance 聚合分类内交易员的表现（traders 会按收益率从高到低排序）
+func aggregateCategoryPerformance(traders []CategoryTraderPerformance) CategoryPerformance {
+	sort.SliceStable(traders, func(i, j int) bool { return traders[i].TotalPnLPct > traders[j].TotalPnLPct })
+	perf := CategoryPerformance{Traders: traders, ReportedCount: len(traders)}
+	if len(traders) == 0 {
+		perf.Traders = []CategoryTraderPerformance{}
+		return perf
+	}
+
+	combinedInitial, returnSum := 0.0, 0.0
+	for _, t := range traders {
+		perf.CombinedEquity += t.TotalEquity
+		perf.CombinedPnL += t.TotalPnL
+		combinedInitial += t.InitialBalance
+		returnSum += t.TotalPnLPct
+	}
+	if combinedInitial > 0 {
+		perf.CombinedPnLPct = perf.CombinedPnL / combinedInitial * 100
+	}
+	perf.AverageReturnPct = returnSum / float64(len(traders))
+	best, worst := traders[0], traders[len(traders)-1]
+	perf.BestPerformer, perf.WorstPerformer = &best, &worst
+	return perf
+}
+
+// fetchCategoryTraders 并行获取分类内各交易员的表现（共享超时），返回成功的结果和失败原因
+func fetchCategoryTraders(traderIDs []string, fetch func(traderID string) (CategoryTraderPerformance, error), timeout time.Duration) ([]CategoryTraderPerformance, map[string]string) {
+	type fetchResult struct {
+		traderID string
+		perf     CategoryTraderPerformance
+		err      error
+	}
+	results := make(chan fetchResult, len(traderIDs))
+	for _, id := range traderIDs {
+		go func(traderID string) {
+			perf, err := fetch(traderID)
+			results <- fetchResult{traderID: traderID, perf: perf, err: err}
+		}(id)
+	}
+
+	traders := make([]CategoryTraderPerformance, 0, len(traderIDs))
+	errs := make(map[string]string)
+	done := make(map[string]bool, len(traderIDs))
+	deadline := time.After(timeout)
+	for pending := len(traderIDs); pending > 0; pending-- {
+		select {
+		case r := <-results:
+			done[r.traderID] = true
+			if r.err != nil {
+				errs[r.traderID] = r.err.Error()
+			} else {
+				traders = append(traders, r.perf)
+			}
+		case <-deadline:
+			for _, id := range traderIDs {
+				if !done[id] {
+					errs[id] = fmt.Sprintf("获取超时（%v）", timeout)
+				}
+			}
+			pending = 0
+		}
+	}
+	return traders, errs
+}
+
+// canViewCategory 分类所有者、管理员、以及管理该分类的小组组长可查看分类表现
+func (s *Server) canViewCategory(user *config.User, category *config.Category) bool {
+	if user.Role == "admin" || category.OwnerUserID == user.ID {
+		return true
+	}
+	if user.Role != "group_leader" {
+		return false
+	}
+	categories, _ := s.database.GetGroupLeaderCategories(user.ID)
+	for _, name := range categories {
+		if name == category.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// handleGetCategoryPerformance 分类聚合表现：合计净值/盈亏、最佳/最差交易员、平均收益率，
+// 各交易员账户数据并行获取，部分失败时返回其余数据并在 errors 中说明
+func (s *Server) handleGetCategoryPerformance(c *gin.Context) {
+	categoryID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err)
+		return
+	}
+	user, err := s.database.GetUserByID(c.GetString("user_id"))
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, ErrCodeUserNotFound)
+		return
+	}
+	category, err := s.database.GetCategoryByID(categoryID)
+	if err != nil || category == nil {
+		respondError(c, http.StatusNotFound, ErrCodeCategoryNotFound)
+		return
+	}
+	if !s.canViewCategory(user, category) {
+		respondError(c, http.StatusForbidden, ErrCodeCategoryAccessDenied)
+		return
+	}
+
+	records, err := s.database.GetTradersByCategories([]string{category.Name})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeGetTradersFailed, err)
+		return
+	}
+	traderIDs := make([]string, 0, len(records))
+	for _, record := range records {
+		traderIDs = append(traderIDs, record.ID)
+	}
+
+	traders, errs := fetchCategoryTraders(traderIDs, func(traderID string) (CategoryTraderPerformance, error) {
+		autoTrader, err := s.traderManager.GetTrader(traderID)
+		if err != nil {
+			return CategoryTraderPerformance{}, err
+		}
+		account, err := autoTrader.GetAccountInfo()
+		if err != nil {
+			return CategoryTraderPerformance{}, err
+		}
+		perf := CategoryTraderPerformance{TraderID: traderID, TraderName: autoTrader.GetName()}
+		perf.TotalEquity, _ = account["total_equity"].(float64)
+		perf.InitialBalance, _ = account["initial_balance"].(float64)
+		perf.TotalPnL, _ = account["total_pnl"].(float64)
+		perf.TotalPnLPct, _ = account["total_pnl_pct"].(float64)
+		return perf, nil
+	}, categoryPerformanceTimeout)
+
+	perf := aggregateCategoryPerformance(traders)
+	perf.CategoryID, perf.Category, perf.TraderCount = category.ID, category.Name, len(traderIDs)
+	perf.FetchedAt = time.Now()
+	if len(errs) > 0 {
+		perf.Errors = errs
+	}
+	c.JSON(http.StatusOK, perf)
+}
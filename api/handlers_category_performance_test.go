@@ -0,0 +1,82 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCategoryPerformanceAggregation(t *testing.T) {
+	accounts := map[string]CategoryTraderPerformance{
+		"t1": {TraderID: "t1", TraderName: "Alpha", TotalEquity: 1200, InitialBalance: 1000, TotalPnL: 200, TotalPnLPct: 20},
+		"t2": {TraderID: "t2", TraderName: "Beta", TotalEquity: 2850, InitialBalance: 3000, TotalPnL: -150, TotalPnLPct: -5},
+	}
+	fetch := func(traderID string) (CategoryTraderPerformance, error) {
+		if perf, ok := accounts[traderID]; ok {
+			return perf, nil
+		}
+		return CategoryTraderPerformance{}, errors.New("交易员未加载")
+	}
+
+	t.Run("两个交易员聚合", func(t *testing.T) {
+		traders, errs := fetchCategoryTraders([]string{"t2", "t1"}, fetch, time.Second)
+		if len(errs) != 0 {
+			t.Fatalf("unexpected errors: %v", errs)
+		}
+		perf := aggregateCategoryPerformance(traders)
+
+		if perf.ReportedCount != 2 {
+			t.Errorf("ReportedCount = %d, want 2", perf.ReportedCount)
+		}
+		if perf.CombinedEquity != 4050 {
+			t.Errorf("CombinedEquity = %v, want 4050", perf.CombinedEquity)
+		}
+		if perf.CombinedPnL != 50 {
+			t.Errorf("CombinedPnL = %v, want 50", perf.CombinedPnL)
+		}
+		if math.Abs(perf.CombinedPnLPct-1.25) > 1e-9 {
+			t.Errorf("CombinedPnLPct = %v, want 1.25", perf.CombinedPnLPct)
+		}
+		if perf.AverageReturnPct != 7.5 {
+			t.Errorf("AverageReturnPct = %v, want 7.5", perf.AverageReturnPct)
+		}
+		if perf.BestPerformer == nil || perf.BestPerformer.TraderID != "t1" {
+			t.Errorf("BestPerformer = %+v, want t1", perf.BestPerformer)
+		}
+		if perf.WorstPerformer == nil || perf.WorstPerformer.TraderID != "t2" {
+			t.Errorf("WorstPerformer = %+v, want t2", perf.WorstPerformer)
+		}
+	})
+
+	t.Run("部分交易员失败时返回其余数据", func(t *testing.T) {
+		traders, errs := fetchCategoryTraders([]string{"t1", "missing"}, fetch, time.Second)
+		perf := aggregateCategoryPerformance(traders)
+		if perf.ReportedCount != 1 || perf.CombinedEquity != 1200 {
+			t.Errorf("perf = %+v, want only t1 aggregated", perf)
+		}
+		if errs["missing"] == "" || len(errs) != 1 {
+			t.Errorf("errs = %v, want error for missing", errs)
+		}
+	})
+
+	t.Run("超时的交易员记录在errors中", func(t *testing.T) {
+		slow := func(traderID string) (CategoryTraderPerformance, error) {
+			if traderID == "slow" {
+				time.Sleep(200 * time.Millisecond)
+			}
+			return fetch("t1")
+		}
+		traders, errs := fetchCategoryTraders([]string{"t1", "slow"}, slow, 50*time.Millisecond)
+		if len(traders) != 1 || errs["slow"] == "" {
+			t.Errorf("traders = %d, errs = %v, want slow timed out", len(traders), errs)
+		}
+	})
+
+	t.Run("没有交易员", func(t *testing.T) {
+		perf := aggregateCategoryPerformance(nil)
+		if perf.BestPerformer != nil || perf.Traders == nil {
+			t.Errorf("perf = %+v, want empty traders and no best performer", perf)
+		}
+	})
+}
@@ -0,0 +1,371 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"nofx/auth"
+	"nofx/config"
+)
+
+// categoryStructureVersion 分类结构导出格式版本
+const categoryStructureVersion = 1
+
+// CategoryStructure 分类 + 账号结构（用于在不同部署间导出/导入，不包含任何密码）
+type CategoryStructure struct {
+	Version      int                     `json:"version"`
+	ExportedAt   time.Time               `json:"exported_at"`
+	Categories   []CategoryStructureItem `json:"categories" binding:"max=500"`
+	GroupLeaders []GroupLeaderStructure  `json:"group_leaders" binding:"max=500"`
+}
+
+// CategoryStructureItem 单个分类及其交易员、交易员账号
+type CategoryStructureItem struct {
+	Name           string                   `json:"name"`
+	Description    string                   `json:"description"`
+	TraderIDs      []string                 `json:"trader_ids"`
+	TraderAccounts []TraderAccountStructure `json:"trader_accounts"`
+}
+
+// TraderAccountStructure 交易员账号（trader_account 角色）
+type TraderAccountStructure struct {
+	TraderID string `json:"trader_id"`
+	Email    string `json:"email"`
+}
+
+// GroupLeaderStructure 小组组长及其可观测的分类
+type GroupLeaderStructure struct {
+	Email      string   `json:"email"`
+	Categories []string `json:"categories"`
+}
+
+// CategoryImportItem 导入结果中的单项
+type CategoryImportItem struct {
+	Type   string `json:"type"`             // category / trader_category / trader_account / group_leader
+	Name   string `json:"name"`             // 分类名称 / 交易员ID / 原邮箱
+	Reason string `json:"reason,omitempty"` // 跳过原因
+
+	// 新建账号时返回（密码仅此一次）
+	UserID   string `json:"user_id,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// CategoryImportResult 导入结果：已创建与已跳过的项
+type CategoryImportResult struct {
+	Created []CategoryImportItem `json:"created"`
+	Skipped []CategoryImportItem `json:"skipped"`
+}
+
+func (r *CategoryImportResult) created(item CategoryImportItem) {
+	r.Created = append(r.Created, item)
+}
+
+func (r *CategoryImportResult) skipped(typ, name, reason string) {
+	r.Skipped = append(r.Skipped, CategoryImportItem{Type: typ, Name: name, Reason: reason})
+}
+
+// validateCategoryStructure 校验导入文件自身的一致性（版本、分类名称唯一、交易员只属于一个分类、
+// 交易员账号引用的交易员必须在所属分类中）；交易员是否存在在导入时逐项检查
+func validateCategoryStructure(st *CategoryStructure) error {
+	if st.Version != categoryStructureVersion {
+		return fmt.Errorf("不支持的导出格式版本: %d", st.Version)
+	}
+	names := make(map[string]bool)
+	traderCategory := make(map[string]string)
+	for _, cat := range st.Categories {
+		name := strings.TrimSpace(cat.Name)
+		if name == "" {
+			return fmt.Errorf("分类名称不能为空")
+		}
+		if names[name] {
+			return fmt.Errorf("分类名称重复: %s", name)
+		}
+		names[name] = true
+
+		for _, tid := range cat.TraderIDs {
+			if prev, ok := traderCategory[tid]; ok {
+				return fmt.Errorf("交易员 %s 同时属于分类 %s 和 %s", tid, prev, name)
+			}
+			traderCategory[tid] = name
+		}
+		for _, acc := range cat.TraderAccounts {
+			if traderCategory[acc.TraderID] != name {
+				return fmt.Errorf("交易员账号引用的交易员 %s 不在分类 %s 中", acc.TraderID, name)
+			}
+		}
+	}
+	for _, leader := range st.GroupLeaders {
+		if len(leader.Categories) == 0 {
+			return fmt.Errorf("小组组长 %s 未关联任何分类", leader.Email)
+		}
+	}
+	return nil
+}
+
+// requireCategoryManager 只有 admin/user 角色可以管理分类结构
+func (s *Server) requireCategoryManager(c *gin.Context) (*config.User, bool) {
+	user, err := s.database.GetUserByID(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "用户不存在"})
+		return nil, false
+	}
+	if user.Role != "admin" && user.Role != "user" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "权限不足"})
+		return nil, false
+	}
+	return user, true
+}
+
+// handleExportCategories 导出当前用户的分类结构：分类、交易员分类归属、交易员账号、小组组长（不含密码）
+func (s *Server) handleExportCategories(c *gin.Context) {
+	user, ok := s.requireCategoryManager(c)
+	if !ok {
+		return
+	}
+
+	categories, err := s.database.GetCategoriesByOwner(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取分类失败"})
+		return
+	}
+
+	export := CategoryStructure{
+		Version:      categoryStructureVersion,
+		ExportedAt:   time.Now().UTC(),
+		Categories:   []CategoryStructureItem{},
+		GroupLeaders: []GroupLeaderStructure{},
+	}
+	owned := make(map[string]bool)
+	for _, cat := range categories {
+		owned[cat.Name] = true
+
+		item := CategoryStructureItem{Name: cat.Name, Description: cat.Description, TraderIDs: []string{}, TraderAccounts: []TraderAccountStructure{}}
+		traders, _ := s.database.GetTradersByCategories([]string{cat.Name})
+		sort.Slice(traders, func(i, j int) bool { return traders[i].ID < traders[j].ID })
+		for _, t := range traders {
+			// 分类名称只在同一所有者下唯一，排除其他用户同名分类下的交易员
+			if t.OwnerUserID != user.ID {
+				continue
+			}
+			item.TraderIDs = append(item.TraderIDs, t.ID)
+			if t.TraderAccountID == "" {
+				continue
+			}
+			if account, err := s.database.GetUserByID(t.TraderAccountID); err == nil && account != nil {
+				item.TraderAccounts = append(item.TraderAccounts, TraderAccountStructure{TraderID: t.ID, Email: account.Email})
+			}
+		}
+		export.Categories = append(export.Categories, item)
+	}
+
+	leaderIDs, _ := s.database.GetGroupLeaderIDsByOwner(user.ID)
+	for _, id := range leaderIDs {
+		leader, err := s.database.GetUserByID(id)
+		if err != nil || leader == nil || leader.Role != "group_leader" {
+			continue
+		}
+		leaderCategories, _ := s.database.GetGroupLeaderCategories(id)
+		var cats []string
+		for _, cat := range leaderCategories {
+			if owned[cat] {
+				cats = append(cats, cat)
+			}
+		}
+		if len(cats) == 0 {
+			continue
+		}
+		sort.Strings(cats)
+		export.GroupLeaders = append(export.GroupLeaders, GroupLeaderStructure{Email: leader.Email, Categories: cats})
+	}
+	sort.Slice(export.GroupLeaders, func(i, j int) bool { return export.GroupLeaders[i].Email < export.GroupLeaders[j].Email })
+
+	c.JSON(http.StatusOK, export)
+}
+
+// handleImportCategories 按导出的结构重建分类、交易员分类归属、交易员账号和小组组长。
+// 所有新建的分类和小组组长归当前用户所有；只能为当前用户自己的交易员设置分类；
+// 新账号使用随机临时密码（仅在响应中返回一次），邮箱已被占用时生成随机邮箱。已存在的项跳过，可重复导入
+func (s *Server) handleImportCategories(c *gin.Context) {
+	user, ok := s.requireCategoryManager(c)
+	if !ok {
+		return
+	}
+
+	var req CategoryStructure
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return
+	}
+	if err := validateCategoryStructure(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := &CategoryImportResult{Created: []CategoryImportItem{}, Skipped: []CategoryImportItem{}}
+	ownedNames, _ := s.database.GetUserCategories(user.ID)
+	owned := make(map[string]bool)
+	for _, name := range ownedNames {
+		owned[name] = true
+	}
+
+	// 1. 分类
+	for _, cat := range req.Categories {
+		name := strings.TrimSpace(cat.Name)
+		if owned[name] {
+			result.skipped("category", name, "分类已存在")
+			continue
+		}
+		if _, err := s.database.CreateCategory(user.ID, name, cat.Description); err != nil {
+			result.skipped("category", name, "创建分类失败: "+err.Error())
+			continue
+		}
+		owned[name] = true
+		result.created(CategoryImportItem{Type: "category", Name: name})
+	}
+
+	// 2. 交易员分类归属（交易员必须存在且属于当前用户）
+	traders := make(map[string]*config.TraderRecord)
+	for _, cat := range req.Categories {
+		name := strings.TrimSpace(cat.Name)
+		for _, tid := range cat.TraderIDs {
+			if !owned[name] {
+				result.skipped("trader_category", tid, "分类不存在")
+				continue
+			}
+			t, err := s.database.GetTraderByID(tid)
+			if err != nil || t == nil {
+				result.skipped("trader_category", tid, "交易员不存在")
+				continue
+			}
+			if user.Role != "admin" && t.OwnerUserID != user.ID {
+				result.skipped("trader_category", tid, "交易员不属于当前用户")
+				continue
+			}
+			traders[tid] = t
+			if t.Category == name {
+				result.skipped("trader_category", tid, "交易员已在该分类中")
+				continue
+			}
+			if err := s.database.UpdateTraderCategory(tid, name); err != nil {
+				result.skipped("trader_category", tid, "更新交易员分类失败: "+err.Error())
+				continue
+			}
+			t.Category = name
+			result.created(CategoryImportItem{Type: "trader_category", Name: tid})
+		}
+	}
+
+	// 3. 交易员账号
+	for _, cat := range req.Categories {
+		for _, acc := range cat.TraderAccounts {
+			t, ok := traders[acc.TraderID]
+			if !ok {
+				result.skipped("trader_account", acc.TraderID, "交易员不存在或不属于当前用户")
+				continue
+			}
+			if t.TraderAccountID != "" {
+				if existing, _ := s.database.GetUserByID(t.TraderAccountID); existing != nil {
+					result.skipped("trader_account", acc.TraderID, "交易员已有账号")
+					continue
+				}
+			}
+			item, err := s.createImportedAccount(acc.Email, config.User{Role: "trader_account", TraderID: t.ID, Category: t.Category})
+			if err != nil {
+				result.skipped("trader_account", acc.TraderID, err.Error())
+				continue
+			}
+			if err := s.database.UpdateTraderAccountID(t.ID, item.UserID); err != nil {
+				s.database.DeleteUser(item.UserID)
+				result.skipped("trader_account", acc.TraderID, "关联交易员账号失败: "+err.Error())
+				continue
+			}
+			item.Type, item.Name = "trader_account", acc.TraderID
+			result.created(item)
+		}
+	}
+
+	// 4. 小组组长（已存在同邮箱且由当前用户创建的小组组长时跳过）
+	existingLeaders := make(map[string]bool)
+	leaderIDs, _ := s.database.GetGroupLeaderIDsByOwner(user.ID)
+	for _, id := range leaderIDs {
+		if leader, err := s.database.GetUserByID(id); err == nil && leader != nil {
+			existingLeaders[leader.Email] = true
+		}
+	}
+	for _, leader := range req.GroupLeaders {
+		if existingLeaders[leader.Email] {
+			result.skipped("group_leader", leader.Email, "小组组长已存在")
+			continue
+		}
+		var cats []string
+		for _, cat := range leader.Categories {
+			if owned[cat] {
+				cats = append(cats, cat)
+			}
+		}
+		if len(cats) == 0 {
+			result.skipped("group_leader", leader.Email, "关联的分类均不存在")
+			continue
+		}
+
+		item, err := s.createImportedAccount(leader.Email, config.User{Role: "group_leader"})
+		if err != nil {
+			result.skipped("group_leader", leader.Email, err.Error())
+			continue
+		}
+		linked := true
+		for _, cat := range cats {
+			if err := s.database.InsertGroupLeaderCategory(item.UserID, cat, user.ID); err != nil {
+				linked = false
+				break
+			}
+		}
+		if !linked {
+			s.database.DeleteUser(item.UserID)
+			result.skipped("group_leader", leader.Email, "关联分类失败")
+			continue
+		}
+		item.Type, item.Name = "group_leader", leader.Email
+		result.created(item)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// createImportedAccount 创建导入的账号（随机临时密码，跳过OTP）；邮箱为空或已被占用时生成随机邮箱
+func (s *Server) createImportedAccount(email string, newUser config.User) (CategoryImportItem, error) {
+	if existing, _ := s.database.GetUserByEmail(email); email == "" || existing != nil {
+		email = ""
+		for i := 0; i < 10; i++ {
+			candidate := generateRandomEmail()
+			if existing, _ := s.database.GetUserByEmail(candidate); existing == nil {
+				email = candidate
+				break
+			}
+		}
+		if email == "" {
+			return CategoryImportItem{}, fmt.Errorf("无法生成唯一邮箱")
+		}
+	}
+
+	password := generateRandomPassword(12)
+	passwordHash, err := auth.HashPassword(password)
+	if err != nil {
+		return CategoryImportItem{}, fmt.Errorf("密码处理失败")
+	}
+
+	newUser.ID = uuid.New().String()
+	newUser.Email = email
+	newUser.PasswordHash = passwordHash
+	newUser.OTPVerified = true
+	if err := s.database.CreateUser(&newUser); err != nil {
+		return CategoryImportItem{}, fmt.Errorf("创建账号失败: %w", err)
+	}
+	return CategoryImportItem{UserID: newUser.ID, Email: email, Password: password}, nil
+}
@@ -0,0 +1,42 @@
+package api
+
+import "testing"
+
+func TestValidateCategoryStructure(t *testing.T) {
+	valid := func() *CategoryStructure {
+		return &CategoryStructure{
+			Version: categoryStructureVersion,
+			Categories: []CategoryStructureItem{
+				{Name: "A", TraderIDs: []string{"t1", "t2"}, TraderAccounts: []TraderAccountStructure{{TraderID: "t1", Email: "a@x.com"}}},
+				{Name: "B", TraderIDs: []string{"t3"}},
+			},
+			GroupLeaders: []GroupLeaderStructure{{Email: "leader@x.com", Categories: []string{"A", "B"}}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(st *CategoryStructure)
+		wantErr bool
+	}{
+		{"合法结构", func(st *CategoryStructure) {}, false},
+		{"版本不支持", func(st *CategoryStructure) { st.Version = 99 }, true},
+		{"分类名称为空", func(st *CategoryStructure) { st.Categories[1].Name = "  " }, true},
+		{"分类名称重复", func(st *CategoryStructure) { st.Categories[1].Name = "A" }, true},
+		{"交易员属于多个分类", func(st *CategoryStructure) { st.Categories[1].TraderIDs = []string{"t1"} }, true},
+		{"交易员账号引用分类外的交易员", func(st *CategoryStructure) {
+			st.Categories[1].TraderAccounts = []TraderAccountStructure{{TraderID: "t1"}}
+		}, true},
+		{"小组组长未关联分类", func(st *CategoryStructure) { st.GroupLeaders[0].Categories = nil }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := valid()
+			tt.mutate(st)
+			err := validateCategoryStructure(st)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateCategoryStructure() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
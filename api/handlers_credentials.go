@@ -0,0 +1,88 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"nofx/config"
+)
+
+// undecryptableCredentials 返回交易员使用的AI模型/交易所中无法解密的凭证字段（如 "exchange.secret_key"）
+func (s *Server) undecryptableCredentials(userID, aiModelID, exchangeID string) []string {
+	var fields []string
+	if models, err := s.database.GetAIModels(userID); err == nil {
+		var model *config.AIModelConfig
+		for _, m := range models {
+			if m.ID == aiModelID || (model == nil && m.Provider == aiModelID) {
+				model = m
+			}
+		}
+		if model != nil {
+			for _, f := range model.UndecryptableFields {
+				fields = append(fields, "model."+f)
+			}
+		}
+	}
+	if exchanges, err := s.database.GetExchanges(userID); err == nil {
+		for _, e := range exchanges {
+			if e.ID == exchangeID {
+				for _, f := range e.UndecryptableFields {
+					fields = append(fields, "exchange."+f)
+				}
+				break
+			}
+		}
+	}
+	return fields
+}
+
+// handleClearModelCredential 清除AI模型已保存的凭证，用户可随后重新输入
+func (s *Server) handleClearModelCredential(c *gin.Context) {
+	userID := c.GetString("user_id")
+	modelID := c.Param("id")
+	field := c.Param("field")
+
+	err := s.database.ClearAIModelCredential(userID, modelID, field)
+	if errors.Is(err, config.ErrUnknownCredentialField) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidCredentialField, field)
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(c, http.StatusNotFound, ErrCodeModelNotFound, modelID)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("🧹 用户 %s 清除了AI模型 %s 的 %s", userID, modelID, field)
+	c.JSON(http.StatusOK, gin.H{"message": "凭证已清除，请重新输入"})
+}
+
+// handleClearExchangeCredential 清除交易所已保存的单个凭证字段，其他字段保持不变
+func (s *Server) handleClearExchangeCredential(c *gin.Context) {
+	userID := c.GetString("user_id")
+	exchangeID := c.Param("id")
+	field := c.Param("field")
+
+	err := s.database.ClearExchangeCredential(userID, exchangeID, field)
+	if errors.Is(err, config.ErrUnknownCredentialField) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidCredentialField, field)
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(c, http.StatusNotFound, ErrCodeExchangeNotFound, exchangeID)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("🧹 用户 %s 清除了交易所 %s 的 %s", userID, exchangeID, field)
+	c.JSON(http.StatusOK, gin.H{"message": "凭证已清除，请重新输入"})
+}
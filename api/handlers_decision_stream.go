@@ -0,0 +1,101 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 决策记录 SSE 推送参数
+const (
+	decisionStreamDefaultBackfill = 10               // 连接建立时默认回放的最近记录数
+	decisionStreamMaxBackfill     = 100              // 回放记录数上限
+	decisionStreamBuffer          = 16               // 每个连接的推送缓冲（消费过慢时丢弃新记录）
+	decisionStreamHeartbeat       = 15 * time.Second // 心跳注释行间隔，防止代理关闭空闲连接
+)
+
+// parseDecisionStreamBackfill 解析回放记录数（?backfill=N，缺省为默认值，0 表示不回放）
+func parseDecisionStreamBackfill(raw string) (int, error) {
+	if raw == "" {
+		return decisionStreamDefaultBackfill, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 || n > decisionStreamMaxBackfill {
+		return 0, fmt.Errorf("backfill 参数不合法: %s（0-%d）", raw, decisionStreamMaxBackfill)
+	}
+	return n, nil
+}
+
+// handleDecisionStream 以 SSE 实时推送交易员新写入的决策记录（含AI思维链和执行动作）
+// 连接建立时先回放最近 N 条记录（event: decision），之后每写入一条推送一条；空闲时定期发送心跳注释行
+func (s *Server) handleDecisionStream(c *gin.Context) {
+	traderID := c.Param("id")
+	if _, ok := s.authorizeTraderOwner(c, traderID); !ok {
+		return
+	}
+	backfill, err := parseDecisionStreamBackfill(c.Query("backfill"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil || at == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员未加载，请先启动交易员"})
+		return
+	}
+	decisionLogger := at.GetDecisionLogger()
+
+	// 先订阅再回放，避免回放期间写入的记录丢失（按记录时间去重）
+	records, unsubscribe := decisionLogger.Subscribe(decisionStreamBuffer)
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 关闭 nginx 缓冲，保证实时推送
+	c.Status(http.StatusOK)
+
+	var lastBackfilled time.Time
+	if backfill > 0 {
+		history, err := decisionLogger.GetLatestRecords(backfill)
+		if err != nil {
+			log.Printf("⚠️ 回放交易员 %s 的决策记录失败: %v", traderID, err)
+		}
+		for _, record := range history {
+			c.SSEvent("decision", record)
+			if record.Timestamp.After(lastBackfilled) {
+				lastBackfilled = record.Timestamp
+			}
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(decisionStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case record, ok := <-records:
+			if !ok {
+				return
+			}
+			if !record.Timestamp.After(lastBackfilled) {
+				continue
+			}
+			c.SSEvent("decision", record)
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
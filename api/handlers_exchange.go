@@ -0,0 +1,114 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"nofx/config"
+	"nofx/trader"
+)
+
+// resolveExchangeProvider 返回交易所配置对应的平台类型（provider 为空时从 ID 推断，兼容旧数据）
+func resolveExchangeProvider(exchange *config.ExchangeConfig) string {
+	if exchange.Provider != "" {
+		return exchange.Provider
+	}
+	for _, provider := range []string{"binance", "hyperliquid", "aster", "bitget"} {
+		if strings.HasPrefix(exchange.ID, provider) {
+			return provider
+		}
+	}
+	return exchange.ID
+}
+
+// validateBackupExchange 校验交易员的备用交易所配置：须存在、与主配置不同且属于同一交易所（backupID 为空表示不启用）
+func validateBackupExchange(exchanges []*config.ExchangeConfig, primaryID, backupID string) error {
+	if backupID == "" {
+		return nil
+	}
+	if backupID == primaryID {
+		return fmt.Errorf("备用交易所不能与主交易所相同")
+	}
+	var primary, backup *config.ExchangeConfig
+	for _, exchange := range exchanges {
+		switch exchange.ID {
+		case primaryID:
+			primary = exchange
+		case backupID:
+			backup = exchange
+		}
+	}
+	if backup == nil {
+		return fmt.Errorf("交易所配置不存在: %s", backupID)
+	}
+	if primary != nil && resolveExchangeProvider(primary) != resolveExchangeProvider(backup) {
+		return fmt.Errorf("备用交易所(%s)与主交易所(%s)不是同一交易所", resolveExchangeProvider(backup), resolveExchangeProvider(primary))
+	}
+	return nil
+}
+
+// handleGetLeverageBrackets 查询交易所某币种的杠杆分层（用于前端限制杠杆滑块）
+// GET /api/exchanges/:id/leverage-brackets?symbol=BTCUSDT
+func (s *Server) handleGetLeverageBrackets(c *gin.Context) {
+	userID := c.GetString("user_id")
+	exchangeID := c.Param("id")
+	symbol := strings.ToUpper(strings.TrimSpace(c.Query("symbol")))
+	if !strings.HasSuffix(symbol, "USDT") {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidSymbol, symbol)
+		return
+	}
+
+	exchanges, err := s.database.GetExchanges(userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeExchangeConfigFailed, err)
+		return
+	}
+	var exchangeCfg *config.ExchangeConfig
+	for _, exchange := range exchanges {
+		if exchange.ID == exchangeID {
+			exchangeCfg = exchange
+			break
+		}
+	}
+	if exchangeCfg == nil {
+		respondError(c, http.StatusNotFound, ErrCodeExchangeNotFound, exchangeID)
+		return
+	}
+
+	var t trader.Trader
+	provider := resolveExchangeProvider(exchangeCfg)
+	switch provider {
+	case "binance":
+		t = trader.NewFuturesTrader(exchangeCfg.APIKey, exchangeCfg.SecretKey, userID)
+	case "bitget":
+		t = trader.NewBitgetTrader(exchangeCfg.APIKey, exchangeCfg.SecretKey, exchangeCfg.Passphrase, exchangeCfg.Testnet)
+	default:
+		// 其他交易所暂不支持查询杠杆分层
+		c.JSON(http.StatusOK, gin.H{
+			"exchange_id":  exchangeID,
+			"symbol":       symbol,
+			"supported":    false,
+			"max_leverage": 0,
+			"brackets":     []trader.LeverageBracket{},
+		})
+		return
+	}
+
+	brackets, err := t.GetLeverageBrackets(symbol)
+	if err != nil {
+		log.Printf("⚠️ 查询 %s %s 杠杆分层失败: %v", provider, symbol, err)
+		respondError(c, http.StatusBadGateway, ErrCodeLeverageBracketsFailed, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"exchange_id":  exchangeID,
+		"symbol":       symbol,
+		"supported":    true,
+		"max_leverage": trader.MaxLeverageForNotional(brackets, 0),
+		"brackets":     brackets,
+	})
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"nofx/market"
+)
+
+const (
+	// klinesCacheTTL K线缓存时长（图表展示不需要秒级实时）
+	klinesCacheTTL = 30 * time.Second
+	// klinesDefaultLimit / klinesMaxLimit 返回K线数量的默认值与上限
+	klinesDefaultLimit = 200
+	klinesMaxLimit     = 500
+	// klinesRSIPeriod 与决策引擎一致的 RSI 周期
+	klinesRSIPeriod = 14
+)
+
+// klineIntervals 支持的K线周期（与币安合约一致）
+var klineIntervals = map[string]bool{
+	"1m": true, "3m": true, "5m": true, "15m": true, "30m": true,
+	"1h": true, "2h": true, "4h": true, "6h": true, "8h": true, "12h": true,
+	"1d": true, "3d": true, "1w": true, "1M": true,
+}
+
+// klinesCacheEntry K线缓存项
+type klinesCacheEntry struct {
+	klines    []market.Kline
+	fetchedAt time.Time
+}
+
+// fetchKlines 获取K线（可替换，便于测试）
+var fetchKlines = func(symbol, interval string, limit int) ([]market.Kline, error) {
+	return market.NewAPIClient().GetKlines(symbol, interval, limit)
+}
+
+// klineIndicatorSeries 按收盘价计算与K线逐根对齐的指标序列，数据不足的位置为 nil。
+// 每个位置都用截至该K线的收盘价调用 market.CalculateRSI/CalculateMACD，保证与决策引擎看到的数值一致
+func klineIndicatorSeries(closes []float64, indicators []string) gin.H {
+	result := gin.H{}
+	for _, indicator := range indicators {
+		switch indicator {
+		case "rsi":
+			rsi := make([]*float64, len(closes))
+			for i := klinesRSIPeriod; i < len(closes); i++ {
+				v := market.CalculateRSI(closes[:i+1], klinesRSIPeriod)
+				rsi[i] = &v
+			}
+			result["rsi"] = rsi
+		case "macd":
+			macdLine := make([]*float64, len(closes))
+			signalLine := make([]*float64, len(closes))
+			histogram := make([]*float64, len(closes))
+			// MACD 从第 27 根开始有值，信号线还需再累计 9 根
+			for i := 26 + 9 - 1; i < len(closes); i++ {
+				m, s, h := market.CalculateMACD(closes[:i+1])
+				macdLine[i], signalLine[i], histogram[i] = &m, &s, &h
+			}
+			result["macd"] = gin.H{"macd": macdLine, "signal": signalLine, "histogram": histogram}
+		}
+	}
+	return result
+}
+
+// parseIndicators 解析 indicators 参数（逗号分隔），返回不支持的指标名
+func parseIndicators(raw string) ([]string, string) {
+	var indicators []string
+	seen := map[string]bool{}
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		if name != "rsi" && name != "macd" {
+			return nil, name
+		}
+		seen[name] = true
+		indicators = append(indicators, name)
+	}
+	return indicators, ""
+}
+
+// handleGetMarketKlines 获取K线及计算好的指标，用于前端绘制与AI决策一致的图表
+// GET /api/market/klines?symbol=BTCUSDT&interval=1h&limit=200&indicators=rsi,macd
+func (s *Server) handleGetMarketKlines(c *gin.Context) {
+	symbol := market.Normalize(strings.TrimSpace(c.Query("symbol")))
+	if symbol == "USDT" || strings.IndexFunc(symbol, func(r rune) bool {
+		return (r < 'A' || r > 'Z') && (r < '0' || r > '9')
+	}) >= 0 {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidSymbol, c.Query("symbol"))
+		return
+	}
+
+	interval := c.DefaultQuery("interval", "1h")
+	if !klineIntervals[interval] {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidKlineInterval, interval)
+		return
+	}
+
+	limit := klinesDefaultLimit
+	if raw := c.Query("limit"); raw != "" {
+		if v, err := strconv.Atoi(raw); err == nil {
+			limit = v
+		}
+	}
+	if limit < 1 {
+		limit = 1
+	}
+	if limit > klinesMaxLimit {
+		limit = klinesMaxLimit
+	}
+
+	indicators, unsupported := parseIndicators(c.Query("indicators"))
+	if unsupported != "" {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidIndicator, unsupported)
+		return
+	}
+
+	cacheKey := fmt.Sprintf("%s|%s|%d", symbol, interval, limit)
+	var entry *klinesCacheEntry
+	if cached, ok := s.klines.Load(cacheKey); ok && time.Since(cached.(*klinesCacheEntry).fetchedAt) < klinesCacheTTL {
+		entry = cached.(*klinesCacheEntry)
+	} else {
+		klines, err := fetchKlines(symbol, interval, limit)
+		if err != nil {
+			respondError(c, http.StatusBadGateway, ErrCodeKlinesFetchFailed, err)
+			return
+		}
+		entry = &klinesCacheEntry{klines: klines, fetchedAt: time.Now()}
+		s.klines.Store(cacheKey, entry)
+		// 清理过期缓存，避免不同参数组合无限累积
+		s.klines.Range(func(key, value interface{}) bool {
+			if time.Since(value.(*klinesCacheEntry).fetchedAt) >= klinesCacheTTL {
+				s.klines.Delete(key)
+			}
+			return true
+		})
+	}
+
+	candles := make([]gin.H, 0, len(entry.klines))
+	closes := make([]float64, 0, len(entry.klines))
+	for _, k := range entry.klines {
+		candles = append(candles, gin.H{
+			"open_time":  k.OpenTime,
+			"open":       k.Open,
+			"high":       k.High,
+			"low":        k.Low,
+			"close":      k.Close,
+			"volume":     k.Volume,
+			"close_time": k.CloseTime,
+		})
+		closes = append(closes, k.Close)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"symbol":     symbol,
+		"interval":   interval,
+		"klines":     candles,
+		"indicators": klineIndicatorSeries(closes, indicators),
+		"fetched_at": entry.fetchedAt,
+	})
+}
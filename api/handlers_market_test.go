@@ -0,0 +1,117 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"nofx/market"
+)
+
+func TestHandleGetMarketKlines(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var fetchCalls, lastLimit int
+	origFetch := fetchKlines
+	fetchKlines = func(symbol, interval string, limit int) ([]market.Kline, error) {
+		fetchCalls++
+		lastLimit = limit
+		klines := make([]market.Kline, 60)
+		for i := range klines {
+			price := 100 + float64(i%7) - float64(i%3)
+			klines[i] = market.Kline{OpenTime: int64(i), Open: price, High: price + 1, Low: price - 1, Close: price, Volume: 10}
+		}
+		return klines, nil
+	}
+	defer func() { fetchKlines = origFetch }()
+
+	s := &Server{klinesLimiter: newIPRateLimiter(3, time.Minute)}
+	router := gin.New()
+	router.GET("/api/market/klines", s.klinesLimiter.Middleware(), s.handleGetMarketKlines)
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/api/market/klines?"+query, nil))
+		return w
+	}
+
+	t.Run("返回K线及与决策引擎一致的指标", func(t *testing.T) {
+		w := get("symbol=btc&interval=1h&limit=5000&indicators=rsi,macd")
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+		}
+		if lastLimit != klinesMaxLimit {
+			t.Errorf("limit 应被限制为 %d，实际 %d", klinesMaxLimit, lastLimit)
+		}
+
+		var resp struct {
+			Symbol     string `json:"symbol"`
+			Klines     []gin.H
+			Indicators struct {
+				RSI  []*float64 `json:"rsi"`
+				MACD struct {
+					Histogram []*float64 `json:"histogram"`
+				} `json:"macd"`
+			} `json:"indicators"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("解析响应失败: %v", err)
+		}
+		if resp.Symbol != "BTCUSDT" || len(resp.Klines) != 60 || len(resp.Indicators.RSI) != 60 {
+			t.Fatalf("响应内容不完整: symbol=%s klines=%d rsi=%d", resp.Symbol, len(resp.Klines), len(resp.Indicators.RSI))
+		}
+		if resp.Indicators.RSI[klinesRSIPeriod-1] != nil || resp.Indicators.MACD.Histogram[30] != nil {
+			t.Errorf("数据不足的位置应为 null")
+		}
+
+		closes := make([]float64, 60)
+		for i := range closes {
+			closes[i] = 100 + float64(i%7) - float64(i%3)
+		}
+		wantRSI := market.CalculateRSI(closes, klinesRSIPeriod)
+		_, _, wantHist := market.CalculateMACD(closes)
+		if got := *resp.Indicators.RSI[59]; got != wantRSI {
+			t.Errorf("最新RSI = %v, want %v", got, wantRSI)
+		}
+		if got := *resp.Indicators.MACD.Histogram[59]; got != wantHist {
+			t.Errorf("最新MACD柱 = %v, want %v", got, wantHist)
+		}
+	})
+
+	t.Run("相同参数命中缓存", func(t *testing.T) {
+		before := fetchCalls
+		if w := get("symbol=BTCUSDT&interval=1h&limit=500"); w.Code != http.StatusOK {
+			t.Fatalf("status = %d", w.Code)
+		}
+		if fetchCalls != before {
+			t.Errorf("缓存有效期内不应重复请求交易所")
+		}
+	})
+
+	t.Run("非法周期", func(t *testing.T) {
+		if w := get("symbol=BTCUSDT&interval=7m"); w.Code != http.StatusBadRequest {
+			t.Errorf("非法周期应返回 400，实际 %d", w.Code)
+		}
+	})
+
+	t.Run("超过限流返回429", func(t *testing.T) {
+		w := get("symbol=BTCUSDT")
+		if w.Code != http.StatusTooManyRequests {
+			t.Fatalf("第4次请求应被限流，实际 %d", w.Code)
+		}
+		if w.Header().Get("Retry-After") == "" {
+			t.Errorf("限流响应应包含 Retry-After")
+		}
+	})
+}
+
+func TestParseIndicators(t *testing.T) {
+	if got, bad := parseIndicators(" RSI,macd,rsi,"); bad != "" || len(got) != 2 {
+		t.Errorf("parseIndicators = %v, %q", got, bad)
+	}
+	if _, bad := parseIndicators("rsi,bollinger"); bad != "bollinger" {
+		t.Errorf("应返回不支持的指标，实际 %q", bad)
+	}
+}
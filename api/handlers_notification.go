@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"nofx/logger"
+)
+
+// notificationTestMessage 通知渠道测试消息
+const notificationTestMessage = "🔔 NOFX 通知渠道测试消息"
+
+// notificationTestResult 单个通知渠道的测试结果（不包含 bot token、签名密钥等敏感信息）
+type notificationTestResult struct {
+	Channel   string `json:"channel"` // "telegram" / "webhook"
+	WebhookID int64  `json:"webhook_id,omitempty"`
+	URL       string `json:"url,omitempty"`
+	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latency_ms"`
+}
+
+// timeNotificationSend 执行一次推送并记录结果与耗时
+func timeNotificationSend(result notificationTestResult, send func() error) notificationTestResult {
+	start := time.Now()
+	err := send()
+	result.LatencyMs = time.Since(start).Milliseconds()
+	result.Success = err == nil
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// handleTestNotifications 向当前用户已配置的全部通知渠道同步发送测试消息，返回各渠道的投递结果
+func (s *Server) handleTestNotifications(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if ok, retryAfter := s.notifyLimiter.allow(userID); !ok {
+		c.Header("Retry-After", formatRetryAfter(retryAfter))
+		respondError(c, http.StatusTooManyRequests, ErrCodeRateLimited)
+		return
+	}
+
+	webhooks, err := s.database.GetUserWebhooks(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	results := []notificationTestResult{}
+	if logger.TelegramEnabled() {
+		results = append(results, timeNotificationSend(notificationTestResult{Channel: "telegram"}, func() error {
+			return logger.SendTelegramTest(notificationTestMessage)
+		}))
+	}
+	for _, w := range webhooks {
+		if !w.Enabled {
+			continue
+		}
+		webhook := w
+		event := logger.Event{
+			Type:    logger.EventTest,
+			Message: notificationTestMessage,
+			Data:    map[string]interface{}{"webhook_id": webhook.ID},
+		}
+		results = append(results, timeNotificationSend(notificationTestResult{Channel: "webhook", WebhookID: webhook.ID, URL: webhook.URL}, func() error {
+			return logger.SendWebhook(webhookTestClient, webhook, event)
+		}))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
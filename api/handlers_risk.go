@@ -0,0 +1,81 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"nofx/analytics"
+)
+
+// riskMetricsMaxRecords 计算风险指标时最多读取的决策记录数（每3分钟一个周期约20天）
+const riskMetricsMaxRecords = 10000
+
+// handleGetTraderRiskMetrics 交易员风险调整指标（年化收益、波动率、夏普、最大回撤、Calmar），需为所有者或管理员
+func (s *Server) handleGetTraderRiskMetrics(c *gin.Context) {
+	traderID := c.Param("id")
+	if _, ok := s.authorizeTraderOwner(c, traderID); !ok {
+		return
+	}
+	s.respondRiskMetrics(c, traderID)
+}
+
+// handleGetPublicTraderRiskMetrics 公开交易员的风险指标（无需认证，仅限公开展示的交易员）
+func (s *Server) handleGetPublicTraderRiskMetrics(c *gin.Context) {
+	traderID := c.Param("id")
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil || !at.IsPublic() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
+		return
+	}
+	s.respondRiskMetrics(c, traderID)
+}
+
+// respondRiskMetrics 按 period 参数（默认30d）从决策日志中的净值曲线计算风险指标
+// 样本不足时返回 metrics=null 并在 note 中说明，而不是报错
+func (s *Server) respondRiskMetrics(c *gin.Context, traderID string) {
+	period := c.DefaultQuery("period", "30d")
+	window, err := analytics.ParsePeriod(period)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	records, err := s.traderManager.DecisionLogger(traderID).GetLatestRecords(riskMetricsMaxRecords)
+	if err != nil {
+		log.Printf("⚠️ 读取交易员 %s 的决策日志失败: %v", traderID, err)
+	}
+
+	var since time.Time
+	if window > 0 {
+		since = time.Now().Add(-window)
+	}
+	points := make([]analytics.EquityPoint, 0, len(records))
+	for _, record := range records {
+		if record.Timestamp.Before(since) {
+			continue
+		}
+		// TotalBalance字段实际存储的是TotalEquity
+		points = append(points, analytics.EquityPoint{Time: record.Timestamp, Equity: record.AccountState.TotalBalance})
+	}
+
+	metrics, err := analytics.ComputeRiskMetrics(points)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"trader_id": traderID,
+			"period":    period,
+			"metrics":   nil,
+			"partial":   true,
+			"note":      err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id": traderID,
+		"period":    period,
+		"metrics":   metrics,
+		"partial":   metrics.Partial,
+	})
+}
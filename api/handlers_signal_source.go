@@ -0,0 +1,153 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"nofx/config"
+	"nofx/pool"
+)
+
+// signalSourceRequest 命名信号源的创建/更新参数
+type signalSourceRequest struct {
+	Name    string   `json:"name"`
+	URL     string   `json:"url"`
+	Type    string   `json:"type"`
+	Weight  *float64 `json:"weight"`
+	Enabled *bool    `json:"enabled"`
+}
+
+// applyTo 校验参数并写入信号源，返回不合法的字段说明
+func (req *signalSourceRequest) applyTo(item *config.UserSignalSourceItem) (string, bool) {
+	if req.Name != "" {
+		item.Name = strings.TrimSpace(req.Name)
+	}
+	if req.URL != "" {
+		item.URL = strings.TrimSpace(req.URL)
+	}
+	if req.Type != "" {
+		item.Type = req.Type
+	}
+	if req.Weight != nil {
+		item.Weight = *req.Weight
+	}
+	if req.Enabled != nil {
+		item.Enabled = *req.Enabled
+	}
+
+	if item.Name == "" {
+		return "name", false
+	}
+	if u, err := url.Parse(item.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "url", false
+	}
+	if item.Type != pool.SignalSourceTypeCoinPool && item.Type != pool.SignalSourceTypeOITop {
+		return "type", false
+	}
+	if item.Weight <= 0 {
+		return "weight", false
+	}
+	return "", true
+}
+
+// handleListSignalSources 获取当前用户的命名信号源列表
+func (s *Server) handleListSignalSources(c *gin.Context) {
+	items, err := s.database.GetUserSignalSourceItems(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if items == nil {
+		items = []*config.UserSignalSourceItem{}
+	}
+	c.JSON(http.StatusOK, gin.H{"sources": items})
+}
+
+// handleCreateSignalSource 新增命名信号源（type 默认 coin_pool，weight 默认 1）
+func (s *Server) handleCreateSignalSource(c *gin.Context) {
+	var req signalSourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err)
+		return
+	}
+
+	item := &config.UserSignalSourceItem{
+		UserID:    c.GetString("user_id"),
+		Type:      pool.SignalSourceTypeCoinPool,
+		Weight:    1,
+		Enabled:   true,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if field, ok := req.applyTo(item); !ok {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidSignalSource, field)
+		return
+	}
+	if err := s.database.CreateUserSignalSourceItem(item); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeSignalSourceSaveFailed, err)
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+// getSignalSourceParam 解析路径中的信号源ID并读取当前用户的配置
+func (s *Server) getSignalSourceParam(c *gin.Context) (*config.UserSignalSourceItem, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusNotFound, ErrCodeSignalSourceNotFound)
+		return nil, false
+	}
+	item, err := s.database.GetUserSignalSourceItem(c.GetString("user_id"), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(c, http.StatusNotFound, ErrCodeSignalSourceNotFound)
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return item, true
+}
+
+// handleUpdateSignalSource 更新命名信号源（未提供的字段保持不变）
+func (s *Server) handleUpdateSignalSource(c *gin.Context) {
+	item, ok := s.getSignalSourceParam(c)
+	if !ok {
+		return
+	}
+
+	var req signalSourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err)
+		return
+	}
+	if field, ok := req.applyTo(item); !ok {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidSignalSource, field)
+		return
+	}
+	if err := s.database.UpdateUserSignalSourceItem(item); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeSignalSourceSaveFailed, err)
+		return
+	}
+	item.UpdatedAt = time.Now()
+	c.JSON(http.StatusOK, item)
+}
+
+// handleDeleteSignalSource 删除命名信号源
+func (s *Server) handleDeleteSignalSource(c *gin.Context) {
+	item, ok := s.getSignalSourceParam(c)
+	if !ok {
+		return
+	}
+	if err := s.database.DeleteUserSignalSourceItem(item.UserID, item.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "信号源已删除"})
+}
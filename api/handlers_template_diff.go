@@ -0,0 +1,56 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"nofx/config"
+	"nofx/decision"
+
+	"github.com/gin-gonic/gin"
+)
+
+// traderTemplateSettings 交易员当前设置中与模板基准可比较的部分
+func traderTemplateSettings(record *config.TraderRecord) decision.TemplateDefaults {
+	return decision.TemplateDefaults{
+		CustomPrompt:        record.CustomPrompt,
+		OverrideBasePrompt:  record.OverrideBasePrompt,
+		ScanIntervalMinutes: record.ScanIntervalMinutes,
+		BTCETHLeverage:      record.BTCETHLeverage,
+		AltcoinLeverage:     record.AltcoinLeverage,
+		IsCrossMargin:       record.IsCrossMargin,
+		MinConfidence:       record.MinConfidence,
+		DefaultStopLossPct:  record.DefaultStopLossPct,
+	}
+}
+
+// traderTemplateDiffResponse 交易员相对提示词模板基准设置的差异
+func traderTemplateDiffResponse(record *config.TraderRecord, template *decision.PromptTemplate) gin.H {
+	differences := decision.DiffTemplateDefaults(template.Defaults, traderTemplateSettings(record))
+	return gin.H{
+		"trader_id":              record.ID,
+		"system_prompt_template": template.Name,
+		"modified":               len(differences) > 0,
+		"differences":            differences,
+	}
+}
+
+// handleGetTraderTemplateDiff 对比交易员当前的提示词/设置与所用系统提示词模板的基准设置（只读）
+func (s *Server) handleGetTraderTemplateDiff(c *gin.Context) {
+	record, ok := s.authorizeTraderAccess(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	templateName := record.SystemPromptTemplate
+	if templateName == "" {
+		templateName = defaultSystemPromptTemplate
+	}
+	template, err := decision.GetPromptTemplate(templateName)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("模板不存在: %s", templateName)})
+		return
+	}
+
+	c.JSON(http.StatusOK, traderTemplateDiffResponse(record, template))
+}
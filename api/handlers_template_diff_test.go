@@ -0,0 +1,79 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"nofx/config"
+	"nofx/decision"
+)
+
+func TestTraderTemplateDiff(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"steady.txt":           "稳健策略 {{SYMBOL}}",
+		"steady.defaults.json": `{"btc_eth_leverage": 3, "altcoin_leverage": 2}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pm := decision.NewPromptManager()
+	if err := pm.LoadTemplates(dir); err != nil {
+		t.Fatal(err)
+	}
+	template, err := pm.GetTemplate("steady")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 与模板基准完全一致的交易员
+	unmodified := func() *config.TraderRecord {
+		return &config.TraderRecord{
+			ID:                   "t1",
+			SystemPromptTemplate: "steady",
+			ScanIntervalMinutes:  5,
+			BTCETHLeverage:       3,
+			AltcoinLeverage:      2,
+			IsCrossMargin:        true,
+		}
+	}
+
+	t.Run("未修改的交易员没有差异", func(t *testing.T) {
+		resp := traderTemplateDiffResponse(unmodified(), template)
+		if resp["modified"] != false {
+			t.Errorf("modified = %v, want false", resp["modified"])
+		}
+		if diffs := resp["differences"].([]decision.TemplateSettingDiff); len(diffs) != 0 {
+			t.Errorf("differences = %+v, want none", diffs)
+		}
+	})
+
+	t.Run("覆盖自定义prompt后差异中包含该项", func(t *testing.T) {
+		record := unmodified()
+		record.CustomPrompt = "只做BTC，止损2%"
+		record.OverrideBasePrompt = true
+		record.BTCETHLeverage = 10
+
+		resp := traderTemplateDiffResponse(record, template)
+		if resp["modified"] != true {
+			t.Errorf("modified = %v, want true", resp["modified"])
+		}
+		want := []decision.TemplateSettingDiff{
+			{Field: "btc_eth_leverage", Template: 3, Current: 10},
+			{Field: "custom_prompt", Template: "", Current: "只做BTC，止损2%"},
+			{Field: "override_base_prompt", Template: false, Current: true},
+		}
+		diffs := resp["differences"].([]decision.TemplateSettingDiff)
+		if len(diffs) != len(want) {
+			t.Fatalf("differences = %+v, want %+v", diffs, want)
+		}
+		for i := range want {
+			if diffs[i] != want[i] {
+				t.Errorf("differences[%d] = %+v, want %+v", i, diffs[i], want[i])
+			}
+		}
+	})
+}
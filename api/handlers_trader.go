@@ -0,0 +1,1003 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"nofx/config"
+	"nofx/logger"
+	"nofx/pool"
+	"nofx/signal"
+	"nofx/trader"
+)
+
+// authorizeTraderAccess 校验当前用户是否有权访问指定交易员（失败时直接写入响应）
+// 允许：管理员、创建者、本体 user_id、绑定的 trader_account 用户
+func (s *Server) authorizeTraderAccess(c *gin.Context, traderID string) (*config.TraderRecord, bool) {
+	userID := c.GetString("user_id")
+	if traderID == "" {
+		respondError(c, http.StatusBadRequest, ErrCodeTraderIDRequired)
+		return nil, false
+	}
+
+	trader, err := s.database.GetTraderByID(traderID)
+	if err != nil || trader == nil {
+		respondError(c, http.StatusNotFound, ErrCodeTraderNotFound)
+		return nil, false
+	}
+
+	user, err := s.database.GetUserByID(userID)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, ErrCodeUserNotFound)
+		return nil, false
+	}
+
+	role := user.Role
+	if role == "" {
+		role = "user"
+	}
+
+	if role != "admin" {
+		ownerID := trader.OwnerUserID
+		if ownerID == "" {
+			ownerID = trader.UserID
+		}
+		if userID != ownerID && userID != trader.UserID && userID != trader.TraderAccountID {
+			respondError(c, http.StatusForbidden, ErrCodeTraderAccessDenied)
+			return nil, false
+		}
+	}
+
+	return trader, true
+}
+
+// authorizeTraderOwner 校验当前用户是否为交易员所有者或管理员（失败时直接写入响应）
+func (s *Server) authorizeTraderOwner(c *gin.Context, traderID string) (*config.TraderRecord, bool) {
+	userID := c.GetString("user_id")
+
+	user, err := s.database.GetUserByID(userID)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, ErrCodeUserNotFound)
+		return nil, false
+	}
+
+	role := user.Role
+	if role == "" {
+		role = "user" // 默认是普通用户
+	}
+
+	trader, err := s.database.GetTraderByID(traderID)
+	if err != nil || trader == nil {
+		respondError(c, http.StatusNotFound, ErrCodeTraderNotFound)
+		return nil, false
+	}
+
+	// 权限检查：如果不是admin，验证交易员是否属于当前用户
+	if role != "admin" && trader.OwnerUserID != userID {
+		respondError(c, http.StatusForbidden, ErrCodeTraderNotOwned)
+		return nil, false
+	}
+
+	return trader, true
+}
+
+// handleGetLastSnapshot 获取交易员最近一次持久化的账户/持仓快照
+// 直接读取决策日志，不访问交易所，也不要求交易员已加载到内存
+func (s *Server) handleGetLastSnapshot(c *gin.Context) {
+	traderID := c.Param("id")
+	traderRecord, ok := s.authorizeTraderAccess(c, traderID)
+	if !ok {
+		return
+	}
+
+	record, err := s.traderManager.DecisionLogger(traderID).GetLatestSnapshot()
+	if err != nil {
+		log.Printf("⚠️ 读取交易员 %s 的快照失败: %v", traderID, err)
+	}
+	if record == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"trader_id":    traderID,
+			"is_running":   traderRecord.IsRunning,
+			"has_snapshot": false,
+		})
+		return
+	}
+
+	positions := record.Positions
+	if positions == nil {
+		positions = []logger.PositionSnapshot{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id":       traderID,
+		"is_running":      traderRecord.IsRunning,
+		"has_snapshot":    true,
+		"timestamp":       record.Timestamp,
+		"age_seconds":     int64(time.Since(record.Timestamp).Seconds()),
+		"cycle_number":    record.CycleNumber,
+		"initial_balance": traderRecord.InitialBalance,
+		"account_state":   record.AccountState,
+		"positions":       positions,
+	})
+}
+
+// handleRunCycle 手动触发一次决策周期，并同步返回决策与执行结果
+func (s *Server) handleRunCycle(c *gin.Context) {
+	traderID := c.Param("id")
+	if _, ok := s.authorizeTraderOwner(c, traderID); !ok {
+		return
+	}
+
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil || at == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员未加载，请先启动交易员"})
+		return
+	}
+	if running, _ := at.GetStatus()["is_running"].(bool); !running {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "交易员未运行，请先启动交易员"})
+		return
+	}
+
+	record, err := at.RunOnce()
+	if record == nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, trader.ErrManualCycleTooFrequent) {
+			status = http.StatusTooManyRequests
+		} else if errors.Is(err, trader.ErrCycleInProgress) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"trader_id":     traderID,
+		"cycle_number":  record.CycleNumber,
+		"timestamp":     record.Timestamp,
+		"success":       record.Success,
+		"error_message": record.ErrorMessage,
+		"decisions":     record.Decisions,
+		"execution_log": record.ExecutionLog,
+		"cot_trace":     record.CoTTrace,
+	}
+	if err != nil {
+		response["error"] = err.Error()
+	}
+
+	log.Printf("✓ 手动决策周期完成 [%s]: 成功=%v, 决策数=%d", at.GetName(), record.Success, len(record.Decisions))
+	c.JSON(http.StatusOK, response)
+}
+
+// handleReduceExposure 将交易员所有持仓按同一比例（0-100）减仓，返回每个持仓的执行结果
+func (s *Server) handleReduceExposure(c *gin.Context) {
+	traderID := c.Param("id")
+	if _, ok := s.authorizeTraderOwner(c, traderID); !ok {
+		return
+	}
+
+	var req struct {
+		Percent *float64 `json:"percent"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Percent == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "percent 参数不能为空（0-100）"})
+		return
+	}
+	percent := math.Max(0, math.Min(100, *req.Percent))
+	if percent == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "percent 必须大于0"})
+		return
+	}
+
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil || at == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员未加载，请先启动交易员"})
+		return
+	}
+
+	results, err := at.ReduceAllPositions(percent)
+	if err != nil && results == nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, trader.ErrCycleInProgress) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": fmt.Sprintf("减仓失败: %v", err)})
+		return
+	}
+	if results == nil {
+		results = []trader.ReduceResult{}
+	}
+
+	log.Printf("✓ 交易员 %s 整体减仓 %.1f%%：处理持仓 %d 个", traderID, percent, len(results))
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id": traderID,
+		"percent":   percent,
+		"results":   results,
+	})
+}
+
+// handleGetOrphanPositions 列出没有被管理的孤儿持仓：信号模式下没有活跃策略，自主模式下近期没有AI决策涉及
+func (s *Server) handleGetOrphanPositions(c *gin.Context) {
+	traderID := c.Param("id")
+	if _, ok := s.authorizeTraderOwner(c, traderID); !ok {
+		return
+	}
+
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil || at == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员未加载，请先启动交易员"})
+		return
+	}
+
+	orphans, err := at.FindOrphanPositions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("检查孤儿持仓失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id": traderID,
+		"orphans":   orphans,
+	})
+}
+
+// handleCloseOrphanPositions 平掉所有孤儿持仓，返回每个持仓的执行结果
+func (s *Server) handleCloseOrphanPositions(c *gin.Context) {
+	traderID := c.Param("id")
+	if _, ok := s.authorizeTraderOwner(c, traderID); !ok {
+		return
+	}
+
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil || at == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员未加载，请先启动交易员"})
+		return
+	}
+
+	results, err := at.CloseOrphanPositions()
+	if err != nil && results == nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, trader.ErrCycleInProgress) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": fmt.Sprintf("平仓失败: %v", err)})
+		return
+	}
+	if results == nil {
+		results = []trader.ReduceResult{}
+	}
+
+	log.Printf("✓ 交易员 %s 平掉孤儿持仓：处理持仓 %d 个", traderID, len(results))
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id": traderID,
+		"results":   results,
+	})
+}
+
+// handleReprotectPosition 手动重设单个持仓的止损/止盈单（不等待决策周期）
+// stop_loss/take_profit 省略或为0时使用最近记录的价位；返回重设后的保护单ID
+func (s *Server) handleReprotectPosition(c *gin.Context) {
+	traderID := c.Param("id")
+	if _, ok := s.authorizeTraderOwner(c, traderID); !ok {
+		return
+	}
+
+	var req struct {
+		Symbol     string  `json:"symbol"`
+		StopLoss   float64 `json:"stop_loss"`
+		TakeProfit float64 `json:"take_profit"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Symbol == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "symbol 参数不能为空"})
+		return
+	}
+	if req.StopLoss < 0 || req.TakeProfit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "stop_loss 和 take_profit 不能为负数"})
+		return
+	}
+
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil || at == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员未加载，请先启动交易员"})
+		return
+	}
+
+	result, err := at.ReprotectPosition(req.Symbol, req.StopLoss, req.TakeProfit)
+	if err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, trader.ErrPositionNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, trader.ErrCycleInProgress):
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": fmt.Sprintf("重设保护单失败: %v", err)})
+		return
+	}
+
+	log.Printf("✓ 交易员 %s 手动重设 %s %s 保护单：止损=%.4f 止盈=%.4f", traderID, result.Symbol, result.Side, result.StopLoss, result.TakeProfit)
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id": traderID,
+		"success":   len(result.Errors) == 0,
+		"result":    result,
+	})
+}
+
+// handleSweepDust 手动清理交易员的粉尘持仓（平仓后残留的、低于最小下单量的持仓）：先尝试平仓，失败时按已平仓处理
+func (s *Server) handleSweepDust(c *gin.Context) {
+	traderID := c.Param("id")
+	if _, ok := s.authorizeTraderOwner(c, traderID); !ok {
+		return
+	}
+
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil || at == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员未加载，请先启动交易员"})
+		return
+	}
+
+	results, err := at.SweepDust()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("🧹 交易员 %s 手动清理粉尘持仓: %d 个", traderID, len(results))
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id": traderID,
+		"swept":     results,
+	})
+}
+
+// handleGetPendingActions 获取等待人工审批的首笔交易（approval_required_first_trade 开启时）
+func (s *Server) handleGetPendingActions(c *gin.Context) {
+	traderID := c.Param("id")
+	if _, ok := s.authorizeTraderOwner(c, traderID); !ok {
+		return
+	}
+
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil || at == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员未加载，请先启动交易员"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id":       traderID,
+		"pending_actions": at.PendingActions(),
+	})
+}
+
+// handleGetMonitorState 获取交易员内存中的回撤监控状态（持仓峰值收益、首次出现时间、风控暂停时间），只读
+func (s *Server) handleGetMonitorState(c *gin.Context) {
+	traderID := c.Param("id")
+	if _, ok := s.authorizeTraderOwner(c, traderID); !ok {
+		return
+	}
+
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil || at == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员未加载，请先启动交易员"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id":     traderID,
+		"monitor_state": at.GetMonitorState(),
+	})
+}
+
+// handleGetStatisticsBreakdown 按币种/方向/动作拆分的统计（?by=symbol,side，按顺序嵌套），
+// 盈亏以决策日志配对出的已平仓交易为准，没有已平仓交易的分组只统计成功执行的动作数
+func (s *Server) handleGetStatisticsBreakdown(c *gin.Context) {
+	traderID := c.Param("id")
+	if _, ok := s.authorizeTraderOwner(c, traderID); !ok {
+		return
+	}
+
+	by := c.DefaultQuery("by", logger.BreakdownBySymbol)
+	var dimensions []string
+	for _, dim := range strings.Split(by, ",") {
+		dim = strings.ToLower(strings.TrimSpace(dim))
+		if dim == "" {
+			continue
+		}
+		if !logger.ValidBreakdownDimension(dim) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("不支持的统计维度: %s（可选 symbol / side / action）", dim)})
+			return
+		}
+		dimensions = append(dimensions, dim)
+	}
+
+	breakdown, err := s.traderManager.DecisionLogger(traderID).GetStatisticsBreakdown(dimensions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取统计信息失败: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id": traderID,
+		"breakdown": breakdown,
+	})
+}
+
+// handleApprovePendingAction 审批通过待审批的首笔交易并立即执行，之后的交易自动执行
+func (s *Server) handleApprovePendingAction(c *gin.Context) {
+	traderID := c.Param("id")
+	if _, ok := s.authorizeTraderOwner(c, traderID); !ok {
+		return
+	}
+
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil || at == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员未加载，请先启动交易员"})
+		return
+	}
+
+	result, err := at.ApprovePendingAction(c.Param("action_id"))
+	if result == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("审批失败: %v", err)})
+		return
+	}
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, trader.ErrCycleInProgress) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": fmt.Sprintf("审批通过但执行失败: %v", err), "result": result})
+		return
+	}
+
+	log.Printf("✓ 交易员 %s 首笔交易审批通过并执行: %s %s", traderID, result.Symbol, result.Action)
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id": traderID,
+		"success":   true,
+		"result":    result,
+	})
+}
+
+// handleRejectPendingAction 拒绝待审批的首笔交易（记录到决策日志，下一笔开仓仍需审批）
+func (s *Server) handleRejectPendingAction(c *gin.Context) {
+	traderID := c.Param("id")
+	if _, ok := s.authorizeTraderOwner(c, traderID); !ok {
+		return
+	}
+
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil || at == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员未加载，请先启动交易员"})
+		return
+	}
+
+	actionID := c.Param("action_id")
+	if err := at.RejectPendingAction(actionID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("拒绝失败: %v", err)})
+		return
+	}
+
+	log.Printf("✓ 交易员 %s 拒绝首笔交易: %s", traderID, actionID)
+	c.JSON(http.StatusOK, gin.H{"trader_id": traderID, "success": true})
+}
+
+// handleGetMarginMode 查询交易所中指定币种的实际仓位模式，并与交易员配置对比
+func (s *Server) handleGetMarginMode(c *gin.Context) {
+	traderID := c.Param("id")
+	if _, ok := s.authorizeTraderAccess(c, traderID); !ok {
+		return
+	}
+	symbol := c.Query("symbol")
+	if symbol == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "symbol 参数不能为空"})
+		return
+	}
+
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil || at == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员未加载，请先启动交易员"})
+		return
+	}
+
+	status, err := at.CheckMarginMode(symbol)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("查询仓位模式失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"trader_id": traderID, "margin_mode": status})
+}
+
+// handleReconcileMarginMode 按交易员配置修改交易所中指定币种的仓位模式（已有持仓时交易所不允许修改）
+func (s *Server) handleReconcileMarginMode(c *gin.Context) {
+	traderID := c.Param("id")
+	if _, ok := s.authorizeTraderOwner(c, traderID); !ok {
+		return
+	}
+
+	var req struct {
+		Symbol string `json:"symbol"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Symbol == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "symbol 参数不能为空"})
+		return
+	}
+
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil || at == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员未加载，请先启动交易员"})
+		return
+	}
+
+	status, err := at.ReconcileMarginMode(req.Symbol)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("调整仓位模式失败: %v", err)})
+		return
+	}
+
+	log.Printf("✓ 交易员 %s 校准 %s 仓位模式：一致=%v 已调整=%v", traderID, status.Symbol, status.Matches, status.Reconciled)
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id":   traderID,
+		"success":     status.Matches,
+		"margin_mode": status,
+	})
+}
+
+// handleSignalPreview 预览信号模式对给定策略的执行计划（入场、补仓、止盈止损），只对账和调用AI，不下单
+func (s *Server) handleSignalPreview(c *gin.Context) {
+	traderID := c.Param("id")
+	if _, ok := s.authorizeTraderOwner(c, traderID); !ok {
+		return
+	}
+
+	var strat signal.SignalDecision
+	if err := c.ShouldBindJSON(&strat); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err)
+		return
+	}
+	strat.Symbol = strings.ToUpper(strings.TrimSpace(strat.Symbol))
+
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil || at == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员未加载，请先启动交易员"})
+		return
+	}
+
+	preview, err := at.PreviewSignalStrategy(&strat)
+	if err != nil {
+		if preview == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "preview": preview})
+		return
+	}
+
+	log.Printf("🔍 交易员 %s 预览信号 %s 执行计划：差异=%v 计划动作 %d 个", traderID, strat.Symbol, preview.DiffDetected, len(preview.Actions))
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id": traderID,
+		"preview":   preview,
+	})
+}
+
+// handleGetBalanceHistory 获取交易员在交易所的资金流水（充值/提现、已实现盈亏、资金费、手续费）
+// start_time/end_time 为毫秒时间戳，缺省时由交易所实现决定（默认最近7天）
+func (s *Server) handleGetBalanceHistory(c *gin.Context) {
+	traderID := c.Param("id")
+	if _, ok := s.authorizeTraderAccess(c, traderID); !ok {
+		return
+	}
+
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil || at == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员未加载，请先启动交易员"})
+		return
+	}
+
+	startTime, err := strconv.ParseInt(c.DefaultQuery("start_time", "0"), 10, 64)
+	if err != nil || startTime < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start_time 参数格式错误（毫秒时间戳）"})
+		return
+	}
+	endTime, err := strconv.ParseInt(c.DefaultQuery("end_time", "0"), 10, 64)
+	if err != nil || endTime < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_time 参数格式错误（毫秒时间戳）"})
+		return
+	}
+
+	history, err := at.GetTrader().GetBalanceHistory(startTime, endTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取资金流水失败: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id": traderID,
+		"history":   history,
+	})
+}
+
+// baselinePeriod 周期净值基准及该周期的盈亏（当前周期按实时净值计算，交易员未加载时不返回）
+type baselinePeriod struct {
+	*config.TraderBaseline
+	Current bool     `json:"current"`
+	PnL     *float64 `json:"pnl,omitempty"`
+	PnLPct  *float64 `json:"pnl_pct,omitempty"`
+}
+
+// handleGetTraderBaselines 获取交易员的周期净值基准历史（baseline_reset_policy 按周/月滚动记录），
+// 同时返回以初始余额为基准的全部时间盈亏和初始余额手动调整记录；实时净值只在交易员已加载时可用
+func (s *Server) handleGetTraderBaselines(c *gin.Context) {
+	traderID := c.Param("id")
+	traderRecord, ok := s.authorizeTraderAccess(c, traderID)
+	if !ok {
+		return
+	}
+
+	baselines, err := s.database.GetTraderBaselines(traderID, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取周期净值基准失败: %v", err)})
+		return
+	}
+	audits, err := s.database.GetBaselineAudits(traderID, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取初始余额调整记录失败: %v", err)})
+		return
+	}
+
+	var totalEquity *float64
+	if at, err := s.traderManager.GetTrader(traderID); err == nil && at != nil {
+		if info, err := at.GetAccountInfo(); err == nil {
+			if equity, ok := info["total_equity"].(float64); ok {
+				totalEquity = &equity
+			}
+		}
+	}
+
+	periods := make([]baselinePeriod, 0, len(baselines))
+	for _, b := range baselines {
+		period := baselinePeriod{TraderBaseline: b, Current: b.EndEquity == nil}
+		endEquity := b.EndEquity
+		if period.Current {
+			endEquity = totalEquity
+		}
+		if endEquity != nil && b.Baseline > 0 {
+			pnl := *endEquity - b.Baseline
+			pnlPct := pnl / b.Baseline * 100
+			period.PnL, period.PnLPct = &pnl, &pnlPct
+		}
+		periods = append(periods, period)
+	}
+
+	allTime := gin.H{"initial_balance": traderRecord.InitialBalance}
+	if totalEquity != nil && traderRecord.InitialBalance > 0 {
+		totalPnL := *totalEquity - traderRecord.InitialBalance
+		allTime["total_equity"] = *totalEquity
+		allTime["total_pnl"] = totalPnL
+		allTime["total_pnl_pct"] = totalPnL / traderRecord.InitialBalance * 100
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id":             traderID,
+		"baseline_reset_policy": traderRecord.BaselineResetPolicy,
+		"all_time":              allTime,
+		"periods":               periods,
+		"manual_adjustments":    audits,
+	})
+}
+
+// handleSetTraderBaseline 手动调整交易员初始余额（全部时间盈亏基准）：baseline 直接设置新值，或 delta 按已知的
+// 充值（正数）/提现（负数）增量调整，reason 必填。数据库连同审计记录（调整前的值、原因）一起更新，交易员已加载时立即生效。
+// 与 sync-balance 用当前余额覆盖不同，这里不会把已有盈亏清零
+func (s *Server) handleSetTraderBaseline(c *gin.Context) {
+	traderID := c.Param("id")
+	traderRecord, ok := s.authorizeTraderOwner(c, traderID)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Baseline *float64 `json:"baseline"`
+		Delta    *float64 `json:"delta"`
+		Reason   string   `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err)
+		return
+	}
+
+	previous := traderRecord.InitialBalance
+	newBalance, mode, err := resolveBaselineAdjustment(previous, req.Baseline, req.Delta, req.Reason)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidBaselineAdjust, err)
+		return
+	}
+	maxInitialBalance, _ := s.database.GetInitialBalanceLimits()
+	switch checkInitialBalance(newBalance, maxInitialBalance) {
+	case ErrCodeInvalidInitialBalance:
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidInitialBalance)
+		return
+	case ErrCodeInitialBalanceTooHigh:
+		respondError(c, http.StatusBadRequest, ErrCodeInitialBalanceTooHigh, maxInitialBalance)
+		return
+	}
+
+	audit := &config.BaselineAudit{
+		TraderID:        traderID,
+		UserID:          c.GetString("user_id"),
+		Mode:            mode,
+		PreviousBalance: previous,
+		NewBalance:      newBalance,
+		Delta:           newBalance - previous,
+		Reason:          strings.TrimSpace(req.Reason),
+	}
+	if err := s.database.SetTraderInitialBalanceWithAudit(audit); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeSetBaselineFailed, err)
+		return
+	}
+
+	// 交易员已加载时同步内存中的初始余额（delta 方式视为资金变动，同时调整周期基准和回撤峰值）
+	if at, err := s.traderManager.GetTrader(traderID); err == nil && at != nil {
+		at.SetInitialBalanceBaseline(newBalance, mode == config.BaselineAuditModeDelta)
+	}
+
+	log.Printf("🧾 交易员 %s 初始余额手动调整（%s）: %.2f → %.2f USDT，原因: %s", traderID, mode, previous, newBalance, audit.Reason)
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id":        traderID,
+		"previous_balance": previous,
+		"initial_balance":  newBalance,
+		"audit":            audit,
+	})
+}
+
+// handleSetAnalysisOnly 运行时切换交易员的仅分析模式（只记录AI决策，不执行下单）
+func (s *Server) handleSetAnalysisOnly(c *gin.Context) {
+	traderID := c.Param("id")
+	if _, ok := s.authorizeTraderOwner(c, traderID); !ok {
+		return
+	}
+
+	var req struct {
+		AnalysisOnly *bool `json:"analysis_only"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.AnalysisOnly == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "analysis_only 参数不能为空"})
+		return
+	}
+
+	if err := s.database.UpdateTraderAnalysisOnly(traderID, *req.AnalysisOnly); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新仅分析模式失败: %v", err)})
+		return
+	}
+
+	// 交易员已加载时立即生效，下一个决策周期起按新模式运行
+	if at, err := s.traderManager.GetTrader(traderID); err == nil && at != nil {
+		at.SetAnalysisOnly(*req.AnalysisOnly)
+	}
+
+	log.Printf("✓ 交易员 %s 仅分析模式: %v", traderID, *req.AnalysisOnly)
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id":     traderID,
+		"analysis_only": *req.AnalysisOnly,
+	})
+}
+
+// handlePurgeDecisions 手动清理交易员指定时间之前的决策日志和策略决策历史
+// before 支持 2006-01-02 或 RFC3339 格式；始终保留最近 logger.MinRetainedDecisionRecords 条记录
+func (s *Server) handlePurgeDecisions(c *gin.Context) {
+	traderID := c.Param("id")
+	if _, ok := s.authorizeTraderOwner(c, traderID); !ok {
+		return
+	}
+
+	beforeStr := c.Query("before")
+	if beforeStr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "before 参数不能为空（格式：2006-01-02 或 RFC3339）"})
+		return
+	}
+	before, err := time.ParseInLocation("2006-01-02", beforeStr, time.Local)
+	if err != nil {
+		before, err = time.Parse(time.RFC3339, beforeStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "before 参数格式错误（格式：2006-01-02 或 RFC3339）"})
+			return
+		}
+	}
+
+	files, rows, err := s.traderManager.PruneTraderDecisionLogs(s.database, traderID, before)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("清理决策记录失败: %v", err)})
+		return
+	}
+
+	log.Printf("🗑️ 手动清理交易员 %s 的决策记录（%s 之前）：日志文件 %d 个，策略决策历史 %d 条", traderID, before.Format(time.RFC3339), files, rows)
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id":                traderID,
+		"before":                   before,
+		"deleted_log_files":        files,
+		"deleted_strategy_history": rows,
+		"min_retained":             logger.MinRetainedDecisionRecords,
+	})
+}
+
+// bookFetchTimeout 订单簿快照各子查询的共享超时
+const bookFetchTimeout = 10 * time.Second
+
+// handleGetTraderBook 一次性返回持仓、当前委托和账户概要（并行获取，共享超时），
+// 避免前端分开请求导致"有持仓但看不到止损单"的不一致；某项失败时返回其余数据并在 errors 中说明。
+// 交易所不支持查询委托时不视为失败：orders 为空且 orders_supported=false
+func (s *Server) handleGetTraderBook(c *gin.Context) {
+	// 路径中的交易员ID作为 trader_id 参与权限检查
+	query := c.Request.URL.Query()
+	query.Set("trader_id", c.Param("id"))
+	c.Request.URL.RawQuery = query.Encode()
+
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	autoTrader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	type bookResult struct {
+		key  string
+		data interface{}
+		err  error
+	}
+	results := make(chan bookResult, 3)
+	fetch := func(key string, fn func() (interface{}, error)) {
+		go func() {
+			data, err := fn()
+			results <- bookResult{key: key, data: data, err: err}
+		}()
+	}
+	fetch("positions", func() (interface{}, error) { return autoTrader.GetPositions() })
+	fetch("orders", func() (interface{}, error) { return autoTrader.GetTrader().GetOpenOrders("") })
+	fetch("account", func() (interface{}, error) { return autoTrader.GetAccountInfo() })
+
+	fetchedAt := time.Now()
+	data := make(map[string]interface{}, 3)
+	errs := make(map[string]string)
+	ordersSupported := true
+	timeout := time.After(bookFetchTimeout)
+	for pending := 3; pending > 0; pending-- {
+		select {
+		case r := <-results:
+			if r.key == "orders" && errors.Is(r.err, trader.ErrOpenOrdersNotSupported) {
+				ordersSupported = false
+				data[r.key] = []map[string]interface{}{}
+			} else if r.err != nil {
+				errs[r.key] = r.err.Error()
+			} else {
+				data[r.key] = r.data
+			}
+		case <-timeout:
+			for _, key := range []string{"positions", "orders", "account"} {
+				if _, ok := data[key]; !ok && errs[key] == "" {
+					errs[key] = fmt.Sprintf("查询超时（%v）", bookFetchTimeout)
+				}
+			}
+			pending = 0
+		}
+	}
+
+	positions, _ := data["positions"].([]map[string]interface{})
+	if positions == nil {
+		positions = []map[string]interface{}{}
+	}
+	orders, _ := data["orders"].([]map[string]interface{})
+	if orders == nil {
+		orders = []map[string]interface{}{}
+	}
+	// 持仓杠杆用于补全委托单的杠杆和名义价值
+	leverageMap := make(map[string]float64)
+	for _, pos := range positions {
+		if sym, ok := pos["symbol"].(string); ok {
+			if lev, ok := pos["leverage"].(int); ok && lev > 0 {
+				leverageMap[sym] = float64(lev)
+			}
+		}
+	}
+	enrichOrders(autoTrader, orders, "", leverageMap)
+
+	resp := gin.H{
+		"trader_id":        traderID,
+		"fetched_at":       fetchedAt,
+		"positions":        positions,
+		"orders":           orders,
+		"account":          data["account"],
+		"orders_supported": ordersSupported,
+	}
+	if len(errs) > 0 {
+		log.Printf("⚠️ 获取交易员 %s 订单簿快照部分失败: %v", traderID, errs)
+		resp["errors"] = errs
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// candidatesCacheTTL 候选币种缓存时长（币种池不会分钟级变化）
+const candidatesCacheTTL = 2 * time.Minute
+
+// candidatesCacheEntry 候选币种缓存项
+type candidatesCacheEntry struct {
+	candidates []gin.H
+	fetchedAt  time.Time
+}
+
+// handleGetTraderCandidates 返回交易员当前的候选币种及来源标签（default/custom/ai500/oi_top）和评分
+// 只读取币种池，不调用AI、不下单；交易员未加载时先从数据库加载配置
+func (s *Server) handleGetTraderCandidates(c *gin.Context) {
+	traderID := c.Param("id")
+	traderRecord, ok := s.authorizeTraderOwner(c, traderID)
+	if !ok {
+		return
+	}
+
+	if cached, ok := s.candidates.Load(traderID); ok {
+		entry := cached.(*candidatesCacheEntry)
+		if time.Since(entry.fetchedAt) < candidatesCacheTTL {
+			c.JSON(http.StatusOK, gin.H{"trader_id": traderID, "candidates": entry.candidates, "fetched_at": entry.fetchedAt, "cached": true})
+			return
+		}
+	}
+
+	autoTrader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		if loadErr := s.traderManager.LoadUserTraders(s.database, traderRecord.UserID); loadErr != nil {
+			log.Printf("⚠️ 加载用户 %s 的交易员失败: %v", traderRecord.UserID, loadErr)
+		}
+		if autoTrader, err = s.traderManager.GetTrader(traderID); err != nil {
+			respondError(c, http.StatusNotFound, ErrCodeTraderNotFound)
+			return
+		}
+	}
+
+	coins, err := autoTrader.GetCandidateCoins()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取候选币种失败: %v", err)})
+		return
+	}
+
+	// AI500评分和OI Top排名（读取币种池缓存，失败时省略）
+	ai500Scores := make(map[string]float64)
+	if coinPool, err := pool.GetCoinPool(); err == nil {
+		for _, coin := range coinPool {
+			ai500Scores[coin.Pair] = coin.Score
+		}
+	}
+	oiTop := make(map[string]pool.OIPosition)
+	if positions, err := pool.GetOITopPositions(); err == nil {
+		for _, pos := range positions {
+			oiTop[pos.Symbol] = pos
+		}
+	}
+
+	candidates := make([]gin.H, 0, len(coins))
+	for _, coin := range coins {
+		item := gin.H{"symbol": coin.Symbol, "sources": coin.Sources}
+		for _, source := range coin.Sources {
+			switch source {
+			case "ai500":
+				if score, ok := ai500Scores[coin.Symbol]; ok {
+					item["ai500_score"] = score
+				}
+			case "oi_top":
+				if pos, ok := oiTop[coin.Symbol]; ok {
+					item["oi_rank"] = pos.Rank
+					item["oi_delta_percent"] = pos.OIDeltaPercent
+				}
+			}
+		}
+		candidates = append(candidates, item)
+	}
+
+	entry := &candidatesCacheEntry{candidates: candidates, fetchedAt: time.Now()}
+	s.candidates.Store(traderID, entry)
+	c.JSON(http.StatusOK, gin.H{"trader_id": traderID, "candidates": candidates, "fetched_at": entry.fetchedAt, "cached": false})
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"nofx/config"
+	"nofx/trader"
+
+	"github.com/gin-gonic/gin"
+)
+
+// invalidSymbol 返回逗号分隔币种列表中第一个格式错误的币种（必须以USDT结尾），全部合法时返回空字符串
+func invalidSymbol(symbols string) string {
+	for _, symbol := range trader.ParseSymbolList(symbols) {
+		if !strings.HasSuffix(strings.ToUpper(symbol), "USDT") {
+			return symbol
+		}
+	}
+	return ""
+}
+
+// traderSymbolsResponse 交易员币种配置及生效语义
+func traderSymbolsResponse(record *config.TraderRecord) gin.H {
+	candidates := trader.ParseSymbolList(record.CandidateSymbols)
+	allowlist := trader.ParseSymbolList(record.TradingSymbols)
+
+	universe := "default" // 默认币种 / 币种池
+	switch {
+	case len(candidates) > 0:
+		universe = "candidate_symbols"
+	case len(allowlist) > 0:
+		universe = "trading_symbols"
+	}
+	return gin.H{
+		"trader_id":         record.ID,
+		"candidate_symbols": candidates,
+		"trading_symbols":   allowlist,
+		"universe_source":   universe,           // AI选币的候选池来源
+		"allowlist_enabled": len(allowlist) > 0, // 开仓是否受 trading_symbols 限制
+	}
+}
+
+// handleGetTraderSymbols 获取交易员的候选币种池（candidate_symbols）和开仓白名单（trading_symbols）
+func (s *Server) handleGetTraderSymbols(c *gin.Context) {
+	traderRecord, ok := s.authorizeTraderOwner(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, traderSymbolsResponse(traderRecord))
+}
+
+// handleUpdateTraderSymbols 设置交易员的候选币种池和开仓白名单（未传的字段保持不变，传空字符串表示清空）
+// candidate_symbols 决定AI从哪些币种中选择；trading_symbols 非空时为开仓白名单，未配置 candidate_symbols 时同时作为候选池
+func (s *Server) handleUpdateTraderSymbols(c *gin.Context) {
+	traderID := c.Param("id")
+	traderRecord, ok := s.authorizeTraderOwner(c, traderID)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		CandidateSymbols *string `json:"candidate_symbols"`
+		TradingSymbols   *string `json:"trading_symbols"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.CandidateSymbols != nil {
+		traderRecord.CandidateSymbols = *req.CandidateSymbols
+	}
+	if req.TradingSymbols != nil {
+		traderRecord.TradingSymbols = *req.TradingSymbols
+	}
+	for _, symbols := range []string{traderRecord.CandidateSymbols, traderRecord.TradingSymbols} {
+		if symbol := invalidSymbol(symbols); symbol != "" {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidSymbol, symbol)
+			return
+		}
+	}
+
+	if err := s.database.UpdateTraderSymbols(traderID, traderRecord.CandidateSymbols, traderRecord.TradingSymbols); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新交易员币种配置失败: %v", err)})
+		return
+	}
+	s.candidates.Delete(traderID)
+
+	// 交易员已加载时立即生效，下一个决策周期起使用新的候选池和白名单
+	if at, err := s.traderManager.GetTrader(traderID); err == nil && at != nil {
+		at.SetSymbolUniverse(trader.ParseSymbolList(traderRecord.CandidateSymbols), trader.ParseSymbolList(traderRecord.TradingSymbols))
+	}
+
+	log.Printf("✓ 交易员 %s 币种配置已更新: candidate_symbols=%q trading_symbols=%q", traderID, traderRecord.CandidateSymbols, traderRecord.TradingSymbols)
+	c.JSON(http.StatusOK, traderSymbolsResponse(traderRecord))
+}
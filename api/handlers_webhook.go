@@ -0,0 +1,172 @@
+package api
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"nofx/config"
+	"nofx/logger"
+)
+
+// webhookTestClient 测试推送使用的HTTP客户端（拨号时拒绝本机/内网地址）
+var webhookTestClient = logger.NewWebhookHTTPClient(10 * time.Second)
+
+// webhookResponse Webhook列表项（不返回密钥本身）
+func webhookResponse(w *config.UserWebhook) gin.H {
+	events := w.Events
+	if events == nil {
+		events = []string{}
+	}
+	return gin.H{
+		"id":         w.ID,
+		"url":        w.URL,
+		"events":     events,
+		"enabled":    w.Enabled,
+		"has_secret": w.Secret != "",
+		"created_at": w.CreatedAt,
+	}
+}
+
+// validateWebhookEvents 校验订阅的事件类型
+func validateWebhookEvents(events []string) (string, bool) {
+	for _, e := range events {
+		supported := false
+		for _, t := range logger.EventTypes {
+			if e == t {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			return e, false
+		}
+	}
+	return "", true
+}
+
+// handleListWebhooks 获取当前用户的Webhook配置
+func (s *Server) handleListWebhooks(c *gin.Context) {
+	userID := c.GetString("user_id")
+	webhooks, err := s.database.GetUserWebhooks(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := make([]gin.H, 0, len(webhooks))
+	for _, w := range webhooks {
+		result = append(result, webhookResponse(w))
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": result, "event_types": logger.EventTypes})
+}
+
+// handleCreateWebhook 创建Webhook，未提供密钥时自动生成（仅在创建时返回一次）
+func (s *Server) handleCreateWebhook(c *gin.Context) {
+	userID := c.GetString("user_id")
+	var req struct {
+		URL    string   `json:"url" binding:"required"`
+		Secret string   `json:"secret"`
+		Events []string `json:"events" binding:"max=50"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err)
+		return
+	}
+
+	if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidWebhookURL)
+		return
+	}
+	if err := logger.ValidateWebhookURL(c.Request.Context(), req.URL); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeWebhookURLBlocked, err)
+		return
+	}
+	if event, ok := validateWebhookEvents(req.Events); !ok {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidWebhookEvent, event)
+		return
+	}
+
+	if req.Secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrCodeWebhookSaveFailed, err)
+			return
+		}
+		req.Secret = hex.EncodeToString(buf)
+	}
+
+	webhook := &config.UserWebhook{
+		UserID:    userID,
+		URL:       req.URL,
+		Secret:    req.Secret,
+		Events:    req.Events,
+		Enabled:   true,
+		CreatedAt: time.Now(),
+	}
+	if err := s.database.CreateUserWebhook(webhook); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeWebhookSaveFailed, err)
+		return
+	}
+
+	resp := webhookResponse(webhook)
+	resp["secret"] = webhook.Secret
+	c.JSON(http.StatusOK, resp)
+}
+
+// getWebhookParam 解析路径中的Webhook ID并读取当前用户的配置
+func (s *Server) getWebhookParam(c *gin.Context) (*config.UserWebhook, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusNotFound, ErrCodeWebhookNotFound)
+		return nil, false
+	}
+	webhook, err := s.database.GetUserWebhook(c.GetString("user_id"), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(c, http.StatusNotFound, ErrCodeWebhookNotFound)
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return webhook, true
+}
+
+// handleDeleteWebhook 删除Webhook
+func (s *Server) handleDeleteWebhook(c *gin.Context) {
+	webhook, ok := s.getWebhookParam(c)
+	if !ok {
+		return
+	}
+	if err := s.database.DeleteUserWebhook(webhook.UserID, webhook.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook已删除"})
+}
+
+// handleTestWebhook 同步发送一条测试事件，返回投递结果
+func (s *Server) handleTestWebhook(c *gin.Context) {
+	webhook, ok := s.getWebhookParam(c)
+	if !ok {
+		return
+	}
+
+	event := logger.Event{
+		Type:    logger.EventTest,
+		Message: "🔔 NOFX Webhook 测试消息",
+		Data:    map[string]interface{}{"webhook_id": webhook.ID},
+	}
+	if err := logger.SendWebhook(webhookTestClient, webhook, event); err != nil {
+		respondError(c, http.StatusBadGateway, ErrCodeWebhookTestFailed, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "测试推送成功"})
+}
@@ -0,0 +1,306 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorCode API错误码（前端可据此做精确判断，message 按 Accept-Language 本地化）
+type ErrorCode string
+
+const (
+	// 通用
+	ErrCodeInvalidRequest  ErrorCode = "INVALID_REQUEST"
+	ErrCodeRequestTooLarge ErrorCode = "REQUEST_BODY_TOO_LARGE"
+
+	// 认证相关
+	ErrCodeMissingAuthHeader      ErrorCode = "AUTH_MISSING_HEADER"
+	ErrCodeInvalidAuthFormat      ErrorCode = "AUTH_INVALID_FORMAT"
+	ErrCodeTokenRevoked           ErrorCode = "AUTH_TOKEN_REVOKED"
+	ErrCodeInvalidToken           ErrorCode = "AUTH_INVALID_TOKEN"
+	ErrCodeTokenGenerateFailed    ErrorCode = "AUTH_TOKEN_GENERATE_FAILED"
+	ErrCodeAdminModeOnly          ErrorCode = "AUTH_ADMIN_MODE_ONLY"
+	ErrCodeMissingPassword        ErrorCode = "AUTH_MISSING_PASSWORD"
+	ErrCodeWrongPassword          ErrorCode = "AUTH_WRONG_PASSWORD"
+	ErrCodeInvalidCredentials     ErrorCode = "AUTH_INVALID_CREDENTIALS"
+	ErrCodeRegistrationAdminMode  ErrorCode = "AUTH_REGISTRATION_ADMIN_MODE"
+	ErrCodeRegistrationClosed     ErrorCode = "AUTH_REGISTRATION_CLOSED"
+	ErrCodeBetaCodeRequired       ErrorCode = "AUTH_BETA_CODE_REQUIRED"
+	ErrCodeBetaCodeVerifyFailed   ErrorCode = "AUTH_BETA_CODE_VERIFY_FAILED"
+	ErrCodeBetaCodeInvalid        ErrorCode = "AUTH_BETA_CODE_INVALID"
+	ErrCodeEmailRegistered        ErrorCode = "AUTH_EMAIL_REGISTERED"
+	ErrCodePasswordProcessFailed  ErrorCode = "AUTH_PASSWORD_PROCESS_FAILED"
+	ErrCodeOTPSecretFailed        ErrorCode = "AUTH_OTP_SECRET_FAILED"
+	ErrCodeInvalidOTP             ErrorCode = "AUTH_INVALID_OTP"
+	ErrCodeCreateUserFailed       ErrorCode = "AUTH_CREATE_USER_FAILED"
+	ErrCodeUpdateUserStatusFailed ErrorCode = "AUTH_UPDATE_USER_STATUS_FAILED"
+	ErrCodeUserNotFound           ErrorCode = "USER_NOT_FOUND"
+	ErrCodeInvalidDisplayCurrency ErrorCode = "USER_INVALID_DISPLAY_CURRENCY"
+	ErrCodeAdminRequired          ErrorCode = "AUTH_ADMIN_REQUIRED"
+
+	// 交易员增删改查
+	ErrCodeTraderIDRequired       ErrorCode = "TRADER_ID_REQUIRED"
+	ErrCodeTraderNotFound         ErrorCode = "TRADER_NOT_FOUND"
+	ErrCodeTraderNotOwned         ErrorCode = "TRADER_NOT_OWNED"
+	ErrCodeTraderAccessDenied     ErrorCode = "TRADER_ACCESS_DENIED"
+	ErrCodeInvalidBTCETHLeverage  ErrorCode = "TRADER_INVALID_BTC_ETH_LEVERAGE"
+	ErrCodeInvalidAltcoinLeverage ErrorCode = "TRADER_INVALID_ALTCOIN_LEVERAGE"
+	ErrCodeInvalidStopLossPct     ErrorCode = "TRADER_INVALID_STOP_LOSS_PCT"
+	ErrCodeInvalidWarmupMinutes   ErrorCode = "TRADER_INVALID_WARMUP_MINUTES"
+	ErrCodeInvalidMinConfidence   ErrorCode = "TRADER_INVALID_MIN_CONFIDENCE"
+	ErrCodeInvalidSignalSizing    ErrorCode = "TRADER_INVALID_SIGNAL_SIZING"
+	ErrCodeInvalidEquityBracket   ErrorCode = "TRADER_INVALID_EQUITY_BRACKET"
+	ErrCodeInvalidTradingSchedule ErrorCode = "TRADER_INVALID_TRADING_SCHEDULE"
+	ErrCodeInvalidMarketCondition ErrorCode = "TRADER_INVALID_MARKET_CONDITION"
+	ErrCodeInvalidMaxOpenOrders   ErrorCode = "TRADER_INVALID_MAX_OPEN_ORDERS"
+	ErrCodeInvalidTrailingStop    ErrorCode = "TRADER_INVALID_TRAILING_STOP"
+	ErrCodeInvalidMaxActions      ErrorCode = "TRADER_INVALID_MAX_ACTIONS"
+	ErrCodeInvalidAIInterval      ErrorCode = "TRADER_INVALID_AI_INTERVAL"
+	ErrCodeInvalidSymbolExposure  ErrorCode = "TRADER_INVALID_SYMBOL_EXPOSURE"
+	ErrCodeInvalidRecentTrades    ErrorCode = "TRADER_INVALID_RECENT_TRADES"
+	ErrCodeInvalidInitialBalance  ErrorCode = "TRADER_INVALID_INITIAL_BALANCE"
+	ErrCodeInitialBalanceTooHigh  ErrorCode = "TRADER_INITIAL_BALANCE_TOO_HIGH"
+	ErrCodeInitialBalanceMismatch ErrorCode = "TRADER_INITIAL_BALANCE_MISMATCH"
+	ErrCodeInvalidBaselineAdjust  ErrorCode = "TRADER_INVALID_BASELINE_ADJUST"
+	ErrCodeSetBaselineFailed      ErrorCode = "TRADER_SET_BASELINE_FAILED"
+	ErrCodeInvalidSizingBase      ErrorCode = "TRADER_INVALID_SIZING_BASE"
+	ErrCodeInvalidAISampling      ErrorCode = "TRADER_INVALID_AI_SAMPLING"
+	ErrCodeInvalidAIFailurePolicy ErrorCode = "TRADER_INVALID_AI_FAILURE_POLICY"
+	ErrCodeInvalidBaselinePolicy  ErrorCode = "TRADER_INVALID_BASELINE_RESET_POLICY"
+	ErrCodeInvalidDrawdownStop    ErrorCode = "TRADER_INVALID_DRAWDOWN_STOP"
+	ErrCodeInvalidStopApproach    ErrorCode = "TRADER_INVALID_STOP_APPROACH_ALERT"
+	ErrCodeInvalidMinHolding      ErrorCode = "TRADER_INVALID_MIN_HOLDING"
+	ErrCodeInvalidStrategySource  ErrorCode = "TRADER_INVALID_STRATEGY_SOURCE"
+	ErrCodeInvalidIndicators      ErrorCode = "TRADER_INVALID_CONTEXT_INDICATORS"
+	ErrCodeInvalidTP1ClosePct     ErrorCode = "TRADER_INVALID_TP1_CLOSE_PCT"
+	ErrCodeInvalidRebalance       ErrorCode = "TRADER_INVALID_REBALANCE"
+	ErrCodeInvalidAIAnomaly       ErrorCode = "TRADER_INVALID_AI_ANOMALY"
+	ErrCodeInvalidSLTPDefaults    ErrorCode = "TRADER_INVALID_SLTP_DEFAULTS"
+	ErrCodeInvalidAuditInterval   ErrorCode = "TRADER_INVALID_AUDIT_INTERVAL"
+	ErrCodeInvalidMinAvailable    ErrorCode = "TRADER_INVALID_MIN_AVAILABLE"
+	ErrCodeInvalidSymbol          ErrorCode = "TRADER_INVALID_SYMBOL"
+	ErrCodeExchangeConfigFailed   ErrorCode = "TRADER_EXCHANGE_CONFIG_FAILED"
+	ErrCodeExchangeNotFound       ErrorCode = "TRADER_EXCHANGE_NOT_FOUND"
+	ErrCodeExchangeDisabled       ErrorCode = "TRADER_EXCHANGE_DISABLED"
+	ErrCodeInvalidBackupExchange  ErrorCode = "TRADER_INVALID_BACKUP_EXCHANGE"
+	ErrCodeCategoryNotFound       ErrorCode = "TRADER_CATEGORY_NOT_FOUND"
+	ErrCodeCategoryNotOwned       ErrorCode = "TRADER_CATEGORY_NOT_OWNED"
+	ErrCodeCategoryAccessDenied   ErrorCode = "TRADER_CATEGORY_ACCESS_DENIED"
+	ErrCodeGetTradersFailed       ErrorCode = "TRADER_LIST_FAILED"
+	ErrCodeCreateTraderFailed     ErrorCode = "TRADER_CREATE_FAILED"
+	ErrCodeUpdateTraderFailed     ErrorCode = "TRADER_UPDATE_FAILED"
+	ErrCodeDeleteTraderFailed     ErrorCode = "TRADER_DELETE_FAILED"
+	ErrCodeGetTraderConfigFailed  ErrorCode = "TRADER_GET_CONFIG_FAILED"
+	ErrCodeTraderQuotaExceeded    ErrorCode = "TRADER_QUOTA_EXCEEDED"
+
+	// 交易所
+	ErrCodeLeverageBracketsFailed ErrorCode = "EXCHANGE_LEVERAGE_BRACKETS_FAILED"
+
+	// Webhook
+	ErrCodeInvalidWebhookURL   ErrorCode = "WEBHOOK_INVALID_URL"
+	ErrCodeInvalidWebhookEvent ErrorCode = "WEBHOOK_INVALID_EVENT"
+	ErrCodeWebhookNotFound     ErrorCode = "WEBHOOK_NOT_FOUND"
+	ErrCodeWebhookSaveFailed   ErrorCode = "WEBHOOK_SAVE_FAILED"
+	ErrCodeWebhookTestFailed   ErrorCode = "WEBHOOK_TEST_FAILED"
+	ErrCodeWebhookURLBlocked   ErrorCode = "WEBHOOK_URL_BLOCKED"
+
+	// 信号源
+	ErrCodeInvalidSignalSource    ErrorCode = "SIGNAL_SOURCE_INVALID"
+	ErrCodeSignalSourceNotFound   ErrorCode = "SIGNAL_SOURCE_NOT_FOUND"
+	ErrCodeSignalSourceSaveFailed ErrorCode = "SIGNAL_SOURCE_SAVE_FAILED"
+
+	// 凭证加解密
+	ErrCodePayloadDecryptFailed    ErrorCode = "CREDENTIAL_PAYLOAD_DECRYPT_FAILED"
+	ErrCodeCredentialUndecryptable ErrorCode = "CREDENTIAL_UNDECRYPTABLE"
+	ErrCodeInvalidCredentialField  ErrorCode = "CREDENTIAL_INVALID_FIELD"
+	ErrCodeModelNotFound           ErrorCode = "CREDENTIAL_MODEL_NOT_FOUND"
+	ErrCodeInvalidCustomAuth       ErrorCode = "CREDENTIAL_INVALID_CUSTOM_AUTH"
+
+	// 行情
+	ErrCodeInvalidKlineInterval ErrorCode = "MARKET_INVALID_INTERVAL"
+	ErrCodeInvalidIndicator     ErrorCode = "MARKET_INVALID_INDICATOR"
+	ErrCodeKlinesFetchFailed    ErrorCode = "MARKET_KLINES_FETCH_FAILED"
+	ErrCodeRateLimited          ErrorCode = "RATE_LIMITED"
+
+	// 系统
+	ErrCodeMaintenanceMode ErrorCode = "SYSTEM_MAINTENANCE"
+)
+
+// defaultLanguage 未指定或不支持 Accept-Language 时使用的语言
+const defaultLanguage = "zh"
+
+// langContextKey gin.Context 中保存请求语言的键
+const langContextKey = "lang"
+
+// errorMessages 错误码 → 语言 → 文案（带 %v/%s 的文案由调用方传入参数格式化）
+// 新增语言只需在此补充对应条目，缺失时回退到默认语言
+var errorMessages = map[ErrorCode]map[string]string{
+	ErrCodeInvalidRequest:  {"zh": "请求参数无效: %v", "en": "Invalid request: %v"},
+	ErrCodeRequestTooLarge: {"zh": "请求体过大，最多允许 %d 字节", "en": "Request body too large, at most %d bytes allowed"},
+
+	ErrCodeMissingAuthHeader:      {"zh": "缺少Authorization头", "en": "Missing Authorization header"},
+	ErrCodeInvalidAuthFormat:      {"zh": "无效的Authorization格式", "en": "Invalid Authorization header format"},
+	ErrCodeTokenRevoked:           {"zh": "token已失效，请重新登录", "en": "Token has expired, please log in again"},
+	ErrCodeInvalidToken:           {"zh": "无效的token", "en": "Invalid token"},
+	ErrCodeTokenGenerateFailed:    {"zh": "生成token失败", "en": "Failed to generate token"},
+	ErrCodeAdminModeOnly:          {"zh": "仅管理员模式可用", "en": "Only available in admin mode"},
+	ErrCodeMissingPassword:        {"zh": "缺少密码", "en": "Password is required"},
+	ErrCodeWrongPassword:          {"zh": "密码错误", "en": "Incorrect password"},
+	ErrCodeInvalidCredentials:     {"zh": "邮箱或密码错误", "en": "Incorrect email or password"},
+	ErrCodeRegistrationAdminMode:  {"zh": "管理员模式下禁用注册", "en": "Registration is disabled in admin mode"},
+	ErrCodeRegistrationClosed:     {"zh": "注册已关闭", "en": "Registration is closed"},
+	ErrCodeBetaCodeRequired:       {"zh": "内测期间，注册需要提供内测码", "en": "A beta code is required to register during the beta"},
+	ErrCodeBetaCodeVerifyFailed:   {"zh": "验证内测码失败", "en": "Failed to verify beta code"},
+	ErrCodeBetaCodeInvalid:        {"zh": "内测码无效或已被使用", "en": "Beta code is invalid or already used"},
+	ErrCodeEmailRegistered:        {"zh": "邮箱已被注册", "en": "Email is already registered"},
+	ErrCodePasswordProcessFailed:  {"zh": "密码处理失败", "en": "Failed to process password"},
+	ErrCodeOTPSecretFailed:        {"zh": "OTP密钥生成失败", "en": "Failed to generate OTP secret"},
+	ErrCodeInvalidOTP:             {"zh": "OTP验证码错误", "en": "Incorrect OTP code"},
+	ErrCodeCreateUserFailed:       {"zh": "创建用户失败: %v", "en": "Failed to create user: %v"},
+	ErrCodeUpdateUserStatusFailed: {"zh": "更新用户状态失败", "en": "Failed to update user status"},
+	ErrCodeUserNotFound:           {"zh": "用户不存在", "en": "User not found"},
+	ErrCodeInvalidDisplayCurrency: {"zh": "不支持的展示币种: %s", "en": "Unsupported display currency: %s"},
+	ErrCodeAdminRequired:          {"zh": "仅管理员可访问", "en": "Admin access required"},
+
+	ErrCodeTraderIDRequired:       {"zh": "交易员ID不能为空", "en": "Trader ID is required"},
+	ErrCodeTraderNotFound:         {"zh": "交易员不存在", "en": "Trader not found"},
+	ErrCodeTraderNotOwned:         {"zh": "只能操作自己的交易员", "en": "You can only manage your own traders"},
+	ErrCodeTraderAccessDenied:     {"zh": "无权访问该交易员", "en": "You do not have access to this trader"},
+	ErrCodeInvalidBTCETHLeverage:  {"zh": "BTC/ETH杠杆必须在1-%d之间（0表示使用默认值或保持原值）", "en": "BTC/ETH leverage must be between 1 and %d (or 0 to use default / keep existing)."},
+	ErrCodeInvalidAltcoinLeverage: {"zh": "山寨币杠杆必须在1-%d之间（0表示使用默认值或保持原值）", "en": "Altcoin leverage must be between 1 and %d (or 0 to use default / keep existing)."},
+	ErrCodeInvalidStopLossPct:     {"zh": "default_stop_loss_pct 必须在0-100之间", "en": "default_stop_loss_pct must be between 0 and 100."},
+	ErrCodeInvalidWarmupMinutes:   {"zh": "warmup_minutes 不能为负数", "en": "warmup_minutes must not be negative."},
+	ErrCodeInvalidMinConfidence:   {"zh": "min_confidence 必须在0-100之间", "en": "min_confidence must be between 0 and 100."},
+	ErrCodeInvalidSignalSizing:    {"zh": "信号模式底仓与默认补仓比例必须大于0，且合计不能超过100%", "en": "Signal base position and default add percentages must be positive and sum to at most 100%."},
+	ErrCodeInvalidEquityBracket:   {"zh": "账户净值止盈止损阈值无效：不能为负，止损百分比需小于100，止损需低于止盈", "en": "Invalid equity take-profit/stop-loss: values must be non-negative, stop-loss percent below 100, and stop-loss below take-profit."},
+	ErrCodeInvalidTradingSchedule: {"zh": "交易时段配置无效: %v", "en": "Invalid trading schedule: %v"},
+	ErrCodeInvalidMarketCondition: {"zh": "skip_if_btc_move_pct 和 skip_if_funding_above 不能为负数", "en": "skip_if_btc_move_pct and skip_if_funding_above must not be negative."},
+	ErrCodeInvalidMaxOpenOrders:   {"zh": "max_open_orders 不能为负数", "en": "max_open_orders must not be negative."},
+	ErrCodeInvalidTrailingStop:    {"zh": "保本/跟踪止损阈值不能为负数，trail_lock_fraction 需在 0 到 1 之间（不含1）", "en": "Break-even and trailing stop thresholds must not be negative, and trail_lock_fraction must be in [0, 1)."},
+	ErrCodeInvalidMaxActions:      {"zh": "max_actions_per_cycle 不能为负数", "en": "max_actions_per_cycle must not be negative."},
+	ErrCodeInvalidAIInterval:      {"zh": "min_seconds_between_ai_calls 不能为负数", "en": "min_seconds_between_ai_calls must not be negative."},
+	ErrCodeInvalidSymbolExposure:  {"zh": "max_per_symbol_exposure_pct 不能为负数", "en": "max_per_symbol_exposure_pct must not be negative."},
+	ErrCodeInvalidRecentTrades:    {"zh": "recent_trades_count 必须在 1 到 %d 之间", "en": "recent_trades_count must be between 1 and %d."},
+	ErrCodeInvalidInitialBalance:  {"zh": "初始余额必须大于0", "en": "Initial balance must be greater than 0."},
+	ErrCodeInitialBalanceTooHigh:  {"zh": "初始余额不能超过 %.2f USDT", "en": "Initial balance must not exceed %.2f USDT."},
+	ErrCodeInvalidBaselineAdjust:  {"zh": "初始余额调整参数不合法: %v", "en": "Invalid baseline adjustment: %v"},
+	ErrCodeSetBaselineFailed:      {"zh": "调整初始余额失败: %v", "en": "Failed to set the initial balance baseline: %v"},
+	ErrCodeInvalidSizingBase:      {"zh": "sizing_base 不合法: %s（可选 fixed / equity）", "en": "Invalid sizing_base: %s (expected fixed or equity)."},
+	ErrCodeInvalidAISampling:      {"zh": "AI采样参数不合法: %v", "en": "Invalid AI sampling parameters: %v"},
+	ErrCodeInvalidAIFailurePolicy: {"zh": "on_ai_failure 不合法: %s（可选 hold / flatten / pause）", "en": "Invalid on_ai_failure: %s (expected hold, flatten or pause)."},
+	ErrCodeInvalidBaselinePolicy:  {"zh": "baseline_reset_policy 不合法: %s（可选 none / weekly / monthly）", "en": "Invalid baseline_reset_policy: %s (expected none, weekly or monthly)."},
+	ErrCodeInvalidDrawdownStop:    {"zh": "max_drawdown_stop_pct 必须在 0 到 100 之间（0 表示使用系统最大回撤）", "en": "max_drawdown_stop_pct must be between 0 and 100 (0 uses the system max drawdown)."},
+	ErrCodeInvalidStopApproach:    {"zh": "stop_approach_alert_pct 必须在 0 到 100 之间（0 表示关闭）", "en": "stop_approach_alert_pct must be between 0 and 100 (0 disables the alert)."},
+	ErrCodeInvalidStrategySource:  {"zh": "无效的策略来源: %s，必须是发件人邮箱（user@domain）或域名（@domain）", "en": "Invalid strategy source: %s, must be a sender address (user@domain) or a domain (@domain)."},
+	ErrCodeInvalidIndicators:      {"zh": "多周期指标配置不合法: %v", "en": "Invalid context_indicators / context_timeframes: %v"},
+	ErrCodeInvalidTP1ClosePct:     {"zh": "tp1_close_pct 必须在 0 到 100 之间（0 表示关闭）", "en": "tp1_close_pct must be between 0 and 100 (0 disables the scale-out)."},
+	ErrCodeInvalidRebalance:       {"zh": "目标权重再平衡配置不合法: %v", "en": "Invalid rebalance_targets / rebalance_tolerance_pct / rebalance_interval_minutes: %v"},
+	ErrCodeInvalidAIAnomaly:       {"zh": "AI输出异常检测配置不合法: %v", "en": "Invalid ai_anomaly_idle_cycles / ai_anomaly_repeat_cycles: %v"},
+	ErrCodeInvalidSLTPDefaults:    {"zh": "默认止损/止盈配置不合法: %v", "en": "Invalid protective_defaults: %v"},
+	ErrCodeInvalidAuditInterval:   {"zh": "仓位对账间隔不合法: %v", "en": "Invalid position_audit_minutes: %v"},
+	ErrCodeInvalidMinAvailable:    {"zh": "可用余额下限不合法: %v", "en": "Invalid min_available_balance: %v"},
+	ErrCodeInvalidMinHolding:      {"zh": "min_holding_minutes 必须在 0 到 10080（7天）之间（0 表示不限制）", "en": "min_holding_minutes must be between 0 and 10080 (7 days); 0 disables the limit."},
+	ErrCodeInitialBalanceMismatch: {"zh": "初始余额 %.2f USDT 与交易所当前余额 %.2f USDT 相差超过 %.0f%%，请确认后提交（confirm_initial_balance=true）", "en": "Initial balance %.2f USDT differs from the exchange balance %.2f USDT by more than %.0f%%. Please confirm and resubmit with confirm_initial_balance=true."},
+	ErrCodeInvalidSymbol:          {"zh": "无效的币种格式: %s，必须以USDT结尾", "en": "Invalid symbol format: %s, must end with USDT"},
+	ErrCodeExchangeConfigFailed:   {"zh": "获取交易所配置失败: %v", "en": "Failed to get exchange config: %v"},
+	ErrCodeExchangeNotFound:       {"zh": "交易所配置不存在: %s", "en": "Exchange config not found: %s"},
+	ErrCodeExchangeDisabled:       {"zh": "交易所未启用", "en": "Exchange is not enabled"},
+	ErrCodeInvalidBackupExchange:  {"zh": "备用交易所配置无效: %v", "en": "Invalid backup exchange: %v"},
+	ErrCodeCategoryNotFound:       {"zh": "分类不存在", "en": "Category not found"},
+	ErrCodeCategoryNotOwned:       {"zh": "只能使用自己的分类", "en": "You can only use your own categories"},
+	ErrCodeCategoryAccessDenied:   {"zh": "无权查看该分类", "en": "You do not have access to this category"},
+	ErrCodeGetTradersFailed:       {"zh": "获取交易员列表失败: %v", "en": "Failed to list traders: %v"},
+	ErrCodeCreateTraderFailed:     {"zh": "创建交易员失败: %v", "en": "Failed to create trader: %v"},
+	ErrCodeUpdateTraderFailed:     {"zh": "更新交易员失败: %v", "en": "Failed to update trader: %v"},
+	ErrCodeDeleteTraderFailed:     {"zh": "删除交易员失败: %v", "en": "Failed to delete trader: %v"},
+	ErrCodeGetTraderConfigFailed:  {"zh": "获取交易员配置失败: %v", "en": "Failed to get trader config: %v"},
+	ErrCodeTraderQuotaExceeded:    {"zh": "交易员数量已达上限（%d/%d），请删除不用的交易员后再创建", "en": "Trader limit reached (%d/%d), delete unused traders before creating a new one"},
+
+	ErrCodeLeverageBracketsFailed: {"zh": "查询杠杆分层失败: %v", "en": "Failed to query leverage brackets: %v"},
+
+	ErrCodeInvalidWebhookURL:   {"zh": "Webhook地址必须是 http(s) URL", "en": "Webhook URL must be an http(s) URL"},
+	ErrCodeInvalidWebhookEvent: {"zh": "不支持的事件类型: %s", "en": "Unsupported event type: %s"},
+	ErrCodeWebhookNotFound:     {"zh": "Webhook不存在", "en": "Webhook not found"},
+	ErrCodeWebhookSaveFailed:   {"zh": "保存Webhook失败: %v", "en": "Failed to save webhook: %v"},
+	ErrCodeWebhookTestFailed:   {"zh": "测试推送失败: %v", "en": "Test delivery failed: %v"},
+	ErrCodeWebhookURLBlocked:   {"zh": "Webhook地址不可用（不能指向本机或内网地址）: %v", "en": "Webhook URL is not allowed (must not point to a local or private address): %v"},
+
+	ErrCodeInvalidSignalSource:    {"zh": "无效的信号源配置: %s", "en": "Invalid signal source: %s"},
+	ErrCodeSignalSourceNotFound:   {"zh": "信号源不存在", "en": "Signal source not found"},
+	ErrCodeSignalSourceSaveFailed: {"zh": "保存信号源失败: %v", "en": "Failed to save signal source: %v"},
+
+	ErrCodePayloadDecryptFailed:    {"zh": "请求数据解密失败（服务端密钥可能已更新），请刷新页面后重新提交", "en": "Failed to decrypt the request (the server key may have changed); refresh the page and submit again"},
+	ErrCodeCredentialUndecryptable: {"zh": "已保存的凭证无法解密（可能因加密密钥变更）: %s，请重新输入这些凭证", "en": "Saved credentials can no longer be decrypted (the encryption key may have changed): %s. Please re-enter them"},
+	ErrCodeInvalidCredentialField:  {"zh": "不支持清除的凭证字段: %s", "en": "Unsupported credential field: %s"},
+	ErrCodeModelNotFound:           {"zh": "AI模型配置不存在: %s", "en": "AI model config not found: %s"},
+	ErrCodeInvalidCustomAuth:       {"zh": "AI模型 %s 的自定义认证配置不合法: %v", "en": "Invalid custom auth config for AI model %s: %v"},
+
+	ErrCodeInvalidKlineInterval: {"zh": "不支持的K线周期: %s", "en": "Unsupported kline interval: %s"},
+	ErrCodeInvalidIndicator:     {"zh": "不支持的指标: %s（可选 rsi, macd）", "en": "Unsupported indicator: %s (supported: rsi, macd)"},
+	ErrCodeKlinesFetchFailed:    {"zh": "获取K线失败: %v", "en": "Failed to fetch klines: %v"},
+	ErrCodeRateLimited:          {"zh": "请求过于频繁，请稍后再试", "en": "Too many requests, please try again later"},
+
+	ErrCodeMaintenanceMode: {"zh": "平台维护中，暂停开新仓（平仓和持仓保护照常执行）", "en": "The platform is under maintenance: new positions are paused (closes and position protection still run)"},
+}
+
+// parseAcceptLanguage 从 Accept-Language 头中选出第一个支持的语言（如 "en-US,en;q=0.9" → "en"）
+func parseAcceptLanguage(header string) string {
+	for _, part := range strings.Split(header, ",") {
+		tag := strings.TrimSpace(part)
+		if i := strings.Index(tag, ";"); i >= 0 {
+			if strings.TrimSpace(tag[i+1:]) == "q=0" {
+				continue
+			}
+			tag = tag[:i]
+		}
+		if i := strings.IndexAny(tag, "-_"); i >= 0 {
+			tag = tag[:i]
+		}
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == defaultLanguage || isSupportedLanguage(tag) {
+			return tag
+		}
+	}
+	return defaultLanguage
+}
+
+// isSupportedLanguage 判断错误文案表中是否存在该语言
+func isSupportedLanguage(lang string) bool {
+	_, ok := errorMessages[ErrCodeInvalidRequest][lang]
+	return ok
+}
+
+// i18nMiddleware 解析请求语言并写入上下文，供 respondError 本地化错误信息
+func i18nMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(langContextKey, parseAcceptLanguage(c.GetHeader("Accept-Language")))
+		c.Next()
+	}
+}
+
+// localizeError 按语言返回错误码对应的文案，args 用于格式化带占位符的文案
+func localizeError(lang string, code ErrorCode, args ...interface{}) string {
+	messages, ok := errorMessages[code]
+	if !ok {
+		return string(code)
+	}
+	msg, ok := messages[lang]
+	if !ok {
+		msg = messages[defaultLanguage]
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// respondError 以当前请求的语言返回错误信息，响应体同时包含错误码
+func respondError(c *gin.Context, status int, code ErrorCode, args ...interface{}) {
+	lang := c.GetString(langContextKey)
+	if lang == "" {
+		lang = parseAcceptLanguage(c.GetHeader("Accept-Language"))
+	}
+	c.JSON(status, gin.H{"error": localizeError(lang, code, args...), "code": code})
+}
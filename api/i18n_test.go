@@ -0,0 +1,118 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		expected string
+	}{
+		{name: "未设置时使用默认语言", header: "", expected: "zh"},
+		{name: "英文", header: "en", expected: "en"},
+		{name: "带地区和权重", header: "en-US,en;q=0.9,zh-CN;q=0.8", expected: "en"},
+		{name: "中文优先", header: "zh-CN,zh;q=0.9,en;q=0.8", expected: "zh"},
+		{name: "跳过不支持的语言", header: "fr-FR,en;q=0.5", expected: "en"},
+		{name: "全部不支持时使用默认语言", header: "fr,de", expected: "zh"},
+		{name: "q=0 表示不接受", header: "en;q=0,zh;q=0.5", expected: "zh"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseAcceptLanguage(tt.header); got != tt.expected {
+				t.Errorf("parseAcceptLanguage(%q) = %q, want %q", tt.header, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRespondErrorLocalized(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(i18nMiddleware())
+	router.GET("/trader", func(c *gin.Context) {
+		respondError(c, http.StatusNotFound, ErrCodeTraderNotFound)
+	})
+	router.GET("/trader-failed", func(c *gin.Context) {
+		respondError(c, http.StatusInternalServerError, ErrCodeCreateTraderFailed, "db locked")
+	})
+
+	tests := []struct {
+		name           string
+		path           string
+		acceptLanguage string
+		expectedStatus int
+		expectedError  string
+		expectedCode   ErrorCode
+	}{
+		{
+			name:           "默认返回中文",
+			path:           "/trader",
+			expectedStatus: http.StatusNotFound,
+			expectedError:  "交易员不存在",
+			expectedCode:   ErrCodeTraderNotFound,
+		},
+		{
+			name:           "Accept-Language: en 返回英文",
+			path:           "/trader",
+			acceptLanguage: "en",
+			expectedStatus: http.StatusNotFound,
+			expectedError:  "Trader not found",
+			expectedCode:   ErrCodeTraderNotFound,
+		},
+		{
+			name:           "带参数的错误信息",
+			path:           "/trader-failed",
+			acceptLanguage: "en-US,en;q=0.9",
+			expectedStatus: http.StatusInternalServerError,
+			expectedError:  "Failed to create trader: db locked",
+			expectedCode:   ErrCodeCreateTraderFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("状态码 = %d, want %d", w.Code, tt.expectedStatus)
+			}
+			var body struct {
+				Error string    `json:"error"`
+				Code  ErrorCode `json:"code"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("解析响应失败: %v", err)
+			}
+			if body.Error != tt.expectedError {
+				t.Errorf("error = %q, want %q", body.Error, tt.expectedError)
+			}
+			if body.Code != tt.expectedCode {
+				t.Errorf("code = %q, want %q", body.Code, tt.expectedCode)
+			}
+		})
+	}
+}
+
+// TestErrorMessagesComplete 确保每个错误码都有中英文文案
+func TestErrorMessagesComplete(t *testing.T) {
+	for code, messages := range errorMessages {
+		for _, lang := range []string{"zh", "en"} {
+			if messages[lang] == "" {
+				t.Errorf("错误码 %s 缺少 %s 文案", code, lang)
+			}
+		}
+	}
+}
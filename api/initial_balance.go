@@ -0,0 +1,84 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"nofx/config"
+	"nofx/trader"
+)
+
+// checkInitialBalance 校验初始余额为正数且不超过系统上限（maxBalance<=0 表示不限制）
+// 返回错误码，合法时为空
+func checkInitialBalance(balance, maxBalance float64) ErrorCode {
+	if balance <= 0 || math.IsNaN(balance) || math.IsInf(balance, 0) {
+		return ErrCodeInvalidInitialBalance
+	}
+	if maxBalance > 0 && balance > maxBalance {
+		return ErrCodeInitialBalanceTooHigh
+	}
+	return ""
+}
+
+// initialBalanceDeviates 判断填写的初始余额与交易所实际余额的偏差是否超过允许的百分比
+// （实际余额未知或 maxDeviationPct<=0 时不检查）
+func initialBalanceDeviates(entered, detected, maxDeviationPct float64) bool {
+	if detected <= 0 || maxDeviationPct <= 0 {
+		return false
+	}
+	return math.Abs(entered-detected)/detected*100 > maxDeviationPct
+}
+
+// resolveBaselineAdjustment 根据手动调整请求计算新的初始余额：baseline（直接设置）和 delta（已知充值/提现的增量）
+// 必须且只能提供一个，reason 不能为空。返回新值和调整方式（config.BaselineAuditModeSet / BaselineAuditModeDelta），
+// 新值的范围由调用方用 checkInitialBalance 校验
+func resolveBaselineAdjustment(current float64, baseline, delta *float64, reason string) (float64, string, error) {
+	if strings.TrimSpace(reason) == "" {
+		return 0, "", fmt.Errorf("reason 不能为空")
+	}
+	switch {
+	case baseline != nil && delta != nil:
+		return 0, "", fmt.Errorf("baseline 和 delta 只能提供一个")
+	case baseline != nil:
+		return *baseline, config.BaselineAuditModeSet, nil
+	case delta != nil:
+		if *delta == 0 || math.IsNaN(*delta) || math.IsInf(*delta, 0) {
+			return 0, "", fmt.Errorf("delta 必须是非零数值")
+		}
+		return current + *delta, config.BaselineAuditModeDelta, nil
+	}
+	return 0, "", fmt.Errorf("必须提供 baseline 或 delta")
+}
+
+// fetchExchangeBalance 查询交易所账户当前可用余额
+func fetchExchangeBalance(exchangeCfg *config.ExchangeConfig, userID string) (float64, error) {
+	var t trader.Trader
+	var err error
+	switch resolveExchangeProvider(exchangeCfg) {
+	case "binance":
+		t = trader.NewFuturesTrader(exchangeCfg.APIKey, exchangeCfg.SecretKey, userID)
+	case "bitget":
+		t = trader.NewBitgetTrader(exchangeCfg.APIKey, exchangeCfg.SecretKey, exchangeCfg.Passphrase, exchangeCfg.Testnet)
+	case "hyperliquid":
+		t, err = trader.NewHyperliquidTrader(exchangeCfg.APIKey, exchangeCfg.HyperliquidWalletAddr, exchangeCfg.Testnet)
+	case "aster":
+		t, err = trader.NewAsterTrader(exchangeCfg.AsterUser, exchangeCfg.AsterSigner, exchangeCfg.AsterPrivateKey)
+	default:
+		return 0, fmt.Errorf("不支持的交易所类型: %s", exchangeCfg.ID)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("连接交易所失败: %w", err)
+	}
+
+	balanceInfo, err := t.GetBalance()
+	if err != nil {
+		return 0, fmt.Errorf("查询余额失败: %w", err)
+	}
+	for _, key := range []string{"available_balance", "availableBalance", "balance"} {
+		if v, ok := balanceInfo[key].(float64); ok && v > 0 {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("无法获取可用余额")
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"math"
+	"testing"
+
+	"nofx/config"
+)
+
+func TestCheckInitialBalance(t *testing.T) {
+	tests := []struct {
+		name       string
+		balance    float64
+		maxBalance float64
+		wantCode   ErrorCode
+	}{
+		{name: "正常余额", balance: 1000, maxBalance: 10000000},
+		{name: "等于上限允许", balance: 10000000, maxBalance: 10000000},
+		{name: "0拒绝", balance: 0, maxBalance: 10000000, wantCode: ErrCodeInvalidInitialBalance},
+		{name: "负数拒绝", balance: -100, maxBalance: 10000000, wantCode: ErrCodeInvalidInitialBalance},
+		{name: "NaN拒绝", balance: math.NaN(), maxBalance: 10000000, wantCode: ErrCodeInvalidInitialBalance},
+		{name: "超过上限拒绝", balance: 10000001, maxBalance: 10000000, wantCode: ErrCodeInitialBalanceTooHigh},
+		{name: "上限为0表示不限制", balance: 1e12, maxBalance: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := checkInitialBalance(tt.balance, tt.maxBalance); code != tt.wantCode {
+				t.Errorf("checkInitialBalance(%v, %v) = %q, want %q", tt.balance, tt.maxBalance, code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestResolveBaselineAdjustment(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name     string
+		baseline *float64
+		delta    *float64
+		reason   string
+		want     float64
+		wantMode string
+		wantErr  bool
+	}{
+		{name: "直接设置", baseline: f(1500), reason: "纠正初始余额", want: 1500, wantMode: config.BaselineAuditModeSet},
+		{name: "充值增量", delta: f(500), reason: "充值", want: 1500, wantMode: config.BaselineAuditModeDelta},
+		{name: "提现增量", delta: f(-300), reason: "提现", want: 700, wantMode: config.BaselineAuditModeDelta},
+		{name: "缺少原因", baseline: f(1500), reason: "  ", wantErr: true},
+		{name: "同时提供两者", baseline: f(1500), delta: f(500), reason: "x", wantErr: true},
+		{name: "都未提供", reason: "x", wantErr: true},
+		{name: "增量为0", delta: f(0), reason: "x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, mode, err := resolveBaselineAdjustment(1000, tt.baseline, tt.delta, tt.reason)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveBaselineAdjustment() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (got != tt.want || mode != tt.wantMode) {
+				t.Errorf("resolveBaselineAdjustment() = (%v, %q), want (%v, %q)", got, mode, tt.want, tt.wantMode)
+			}
+		})
+	}
+}
+
+func TestInitialBalanceDeviates(t *testing.T) {
+	tests := []struct {
+		name         string
+		entered      float64
+		detected     float64
+		tolerancePct float64
+		want         bool
+	}{
+		{name: "与实际余额一致", entered: 1000, detected: 1000, tolerancePct: 50},
+		{name: "只使用部分资金（偏差内）", entered: 600, detected: 1000, tolerancePct: 50},
+		{name: "多填一个0需要确认", entered: 10000, detected: 1000, tolerancePct: 50, want: true},
+		{name: "远小于实际余额需要确认", entered: 100, detected: 1000, tolerancePct: 50, want: true},
+		{name: "实际余额未知时不检查", entered: 10000, detected: 0, tolerancePct: 50},
+		{name: "容差为0时不检查", entered: 10000, detected: 1000, tolerancePct: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := initialBalanceDeviates(tt.entered, tt.detected, tt.tolerancePct); got != tt.want {
+				t.Errorf("initialBalanceDeviates(%v, %v, %v) = %v, want %v", tt.entered, tt.detected, tt.tolerancePct, got, tt.want)
+			}
+		})
+	}
+}
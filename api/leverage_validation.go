@@ -0,0 +1,38 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"nofx/config"
+)
+
+// checkLeverageCeilings 校验杠杆不超过系统上限（0表示使用默认值或保持原值）
+// 返回第一个超限项的错误码及其上限，全部合法时错误码为空
+func checkLeverageCeilings(btcEthLeverage, altcoinLeverage, maxBTCETH, maxAltcoin int) (ErrorCode, int) {
+	if btcEthLeverage < 0 || btcEthLeverage > maxBTCETH {
+		return ErrCodeInvalidBTCETHLeverage, maxBTCETH
+	}
+	if altcoinLeverage < 0 || altcoinLeverage > maxAltcoin {
+		return ErrCodeInvalidAltcoinLeverage, maxAltcoin
+	}
+	return "", 0
+}
+
+// leverageCeilings 从系统配置读取杠杆上限
+func (s *Server) leverageCeilings() (btcEth, altcoin int) {
+	if s.database == nil {
+		return config.DefaultMaxBTCETHLeverage, config.DefaultMaxAltcoinLeverage
+	}
+	return s.database.GetLeverageCeilings()
+}
+
+// validateLeverageCeilings 校验请求中的杠杆，超限时写入错误响应并返回 false
+func (s *Server) validateLeverageCeilings(c *gin.Context, btcEthLeverage, altcoinLeverage int) bool {
+	maxBTCETH, maxAltcoin := s.leverageCeilings()
+	if code, ceiling := checkLeverageCeilings(btcEthLeverage, altcoinLeverage, maxBTCETH, maxAltcoin); code != "" {
+		respondError(c, http.StatusBadRequest, code, ceiling)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,30 @@
+package api
+
+import "testing"
+
+func TestCheckLeverageCeilings(t *testing.T) {
+	tests := []struct {
+		name            string
+		btcEthLeverage  int
+		altcoinLeverage int
+		maxBTCETH       int
+		maxAltcoin      int
+		wantCode        ErrorCode
+		wantCeiling     int
+	}{
+		{name: "0表示使用默认值", btcEthLeverage: 0, altcoinLeverage: 0, maxBTCETH: 10, maxAltcoin: 5},
+		{name: "等于上限允许", btcEthLeverage: 10, altcoinLeverage: 5, maxBTCETH: 10, maxAltcoin: 5},
+		{name: "BTC/ETH超过上限拒绝", btcEthLeverage: 20, altcoinLeverage: 5, maxBTCETH: 10, maxAltcoin: 5, wantCode: ErrCodeInvalidBTCETHLeverage, wantCeiling: 10},
+		{name: "山寨币超过上限拒绝", btcEthLeverage: 5, altcoinLeverage: 8, maxBTCETH: 10, maxAltcoin: 5, wantCode: ErrCodeInvalidAltcoinLeverage, wantCeiling: 5},
+		{name: "负数拒绝", btcEthLeverage: -1, altcoinLeverage: 0, maxBTCETH: 10, maxAltcoin: 5, wantCode: ErrCodeInvalidBTCETHLeverage, wantCeiling: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, ceiling := checkLeverageCeilings(tt.btcEthLeverage, tt.altcoinLeverage, tt.maxBTCETH, tt.maxAltcoin)
+			if code != tt.wantCode || ceiling != tt.wantCeiling {
+				t.Errorf("checkLeverageCeilings() = (%q, %d), want (%q, %d)", code, ceiling, tt.wantCode, tt.wantCeiling)
+			}
+		})
+	}
+}
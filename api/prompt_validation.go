@@ -0,0 +1,42 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// defaultMaxCustomPromptLength 自定义Prompt默认最大长度（字符数）
+const defaultMaxCustomPromptLength = 8000
+
+// SanitizeCustomPrompt 校验并清理用户自定义Prompt
+// 1. 超过 maxLen 个字符时返回错误（maxLen<=0 时使用默认值）
+// 2. 转义 {{ 和 }}，避免与信号执行器模板中的 {{XXX}} 占位符冲突
+func SanitizeCustomPrompt(prompt string, maxLen int) (string, error) {
+	if maxLen <= 0 {
+		maxLen = defaultMaxCustomPromptLength
+	}
+	if n := utf8.RuneCountInString(prompt); n > maxLen {
+		return "", fmt.Errorf("自定义prompt过长: %d 字符，最多允许 %d 字符", n, maxLen)
+	}
+	// 逐次替换直到不再出现，防止 "{{{" 之类的组合在替换后重新拼出占位符
+	for strings.Contains(prompt, "{{") || strings.Contains(prompt, "}}") {
+		prompt = strings.ReplaceAll(prompt, "{{", "{ {")
+		prompt = strings.ReplaceAll(prompt, "}}", "} }")
+	}
+	return prompt, nil
+}
+
+// maxCustomPromptLength 从系统配置读取自定义Prompt最大长度
+func (s *Server) maxCustomPromptLength() int {
+	if s.database == nil {
+		return defaultMaxCustomPromptLength
+	}
+	if v, _ := s.database.GetSystemConfig("max_custom_prompt_length"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultMaxCustomPromptLength
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolvePublicDisplayName(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		traderID string
+		want     string
+	}{
+		{"空名称保持为空（使用交易员名称）", "", "t1", ""},
+		{"自定义名称去除首尾空格", "  Alpha  ", "t1", "Alpha"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolvePublicDisplayName(tt.input, tt.traderID); got != tt.want {
+				t.Errorf("resolvePublicDisplayName(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("auto 按交易员ID生成固定化名", func(t *testing.T) {
+		first := resolvePublicDisplayName("auto", "user1_trader")
+		if !strings.HasPrefix(first, "Trader-") || len(first) != len("Trader-")+6 {
+			t.Fatalf("化名格式不正确: %q", first)
+		}
+		if again := resolvePublicDisplayName(" AUTO ", "user1_trader"); again != first {
+			t.Errorf("同一交易员应生成相同化名: %q vs %q", first, again)
+		}
+		if other := resolvePublicDisplayName("auto", "user2_trader"); other == first {
+			t.Errorf("不同交易员不应生成相同化名: %q", other)
+		}
+		if strings.Contains(first, "user1") {
+			t.Errorf("化名不应包含交易员ID: %q", first)
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ipRateLimiter 按客户端IP的固定窗口限流器，用于保护公开的代理类接口
+type ipRateLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	clients map[string]*rateWindow
+	now     func() time.Time
+}
+
+// rateWindow 单个客户端当前窗口的请求计数
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// newIPRateLimiter 创建限流器：每个IP在 window 内最多 limit 次请求
+func newIPRateLimiter(limit int, window time.Duration) *ipRateLimiter {
+	return &ipRateLimiter{
+		limit:   limit,
+		window:  window,
+		clients: make(map[string]*rateWindow),
+		now:     time.Now,
+	}
+}
+
+// allow 判断该IP本次请求是否放行，超限时返回需要等待的时间
+func (l *ipRateLimiter) allow(ip string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	w, ok := l.clients[ip]
+	if !ok || now.Sub(w.start) >= l.window {
+		// 顺带清理过期窗口，避免 map 无限增长
+		if !ok && len(l.clients) > 0 {
+			for key, cw := range l.clients {
+				if now.Sub(cw.start) >= l.window {
+					delete(l.clients, key)
+				}
+			}
+		}
+		l.clients[ip] = &rateWindow{start: now, count: 1}
+		return true, 0
+	}
+	if w.count >= l.limit {
+		return false, l.window - now.Sub(w.start)
+	}
+	w.count++
+	return true, 0
+}
+
+// Middleware 超限时返回 429 及 Retry-After
+func (l *ipRateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if ok, retryAfter := l.allow(c.ClientIP()); !ok {
+			c.Header("Retry-After", formatRetryAfter(retryAfter))
+			respondError(c, http.StatusTooManyRequests, ErrCodeRateLimited)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// formatRetryAfter 将等待时间向上取整为秒
+func formatRetryAfter(d time.Duration) string {
+	seconds := int((d + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.Itoa(seconds)
+}
@@ -3,7 +3,10 @@ package api
 import (
 	"bufio"
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
@@ -22,9 +25,11 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/google/uuid"
 )
 
@@ -36,6 +41,12 @@ type Server struct {
 	cryptoService *crypto.CryptoService
 	mcpClient     *mcp.Client
 	port          int
+	adminStats    adminStatsCache // 管理员统计缓存
+	candidates    sync.Map        // 交易员候选币种缓存 traderID -> *candidatesCacheEntry
+	klines        sync.Map        // K线缓存 symbol|interval|limit -> *klinesCacheEntry
+	displayRates  sync.Map        // 展示币种换算汇率缓存 currency -> *displayRateEntry
+	klinesLimiter *ipRateLimiter  // 公开K线接口按IP限流
+	notifyLimiter *ipRateLimiter  // 通知测试接口按用户限流
 }
 
 // NewServer 创建API服务器
@@ -45,8 +56,16 @@ func NewServer(traderManager *manager.TraderManager, database *config.Database,
 
 	router := gin.Default()
 
-	// 启用CORS
-	router.Use(corsMiddleware())
+	// 启用CORS（允许的来源来自环境变量 CORS_ALLOWED_ORIGINS 或系统配置 cors_allowed_origins）
+	allowedOrigins := database.GetCORSAllowedOrigins()
+	log.Printf("🌐 CORS 允许的来源: %s", allowedOrigins)
+	router.Use(corsMiddleware(allowedOrigins))
+
+	// 按 Accept-Language 选择错误信息语言
+	router.Use(i18nMiddleware())
+
+	// 限制请求体大小（环境变量 MAX_REQUEST_BODY_BYTES 或系统配置 max_request_body_bytes，默认1MB）
+	router.Use(bodyLimitMiddleware(database.GetMaxRequestBodyBytes()))
 
 	s := &Server{
 		router:        router,
@@ -55,6 +74,8 @@ func NewServer(traderManager *manager.TraderManager, database *config.Database,
 		cryptoService: cryptoService,
 		mcpClient:     mcpClient,
 		port:          port,
+		klinesLimiter: newIPRateLimiter(60, time.Minute),
+		notifyLimiter: newIPRateLimiter(3, time.Minute),
 	}
 
 	// 设置路由
@@ -63,24 +84,6 @@ func NewServer(traderManager *manager.TraderManager, database *config.Database,
 	return s
 }
 
-// corsMiddleware CORS中间件
-func corsMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Max-Age", "86400")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(http.StatusOK)
-			return
-		}
-
-		c.Next()
-	}
-}
-
 // setupRoutes 设置路由
 func (s *Server) setupRoutes() {
 	// API路由组
@@ -111,11 +114,15 @@ func (s *Server) setupRoutes() {
 		// 加密服务（无需认证）
 		api.GET("/crypto/public-key", s.handleGetPublicKey)
 
+		// 行情K线与指标（无需认证，按IP限流）
+		api.GET("/market/klines", s.klinesLimiter.Middleware(), s.handleGetMarketKlines)
+
 		// 系统提示词模板管理（仅在非管理员模式下公开）
 		if !auth.IsAdminMode() {
 			// 系统提示词模板管理（无需认证）
 			api.GET("/prompt-templates", s.handleGetPromptTemplates)
 			api.GET("/prompt-templates/:name", s.handleGetPromptTemplate)
+			api.GET("/prompt-templates/:name/validate", s.handleValidatePromptTemplate)
 
 			// 公开的竞赛数据（无需认证）
 			api.GET("/traders", s.handlePublicTraderList)
@@ -125,6 +132,7 @@ func (s *Server) setupRoutes() {
 			// 批量历史曲线对比：仍然保留为无需认证的公开接口
 			api.POST("/equity-history-batch", s.handleEquityHistoryBatch)
 			api.GET("/traders/:id/public-config", s.handleGetPublicTraderConfig)
+			api.GET("/traders/:id/public-risk-metrics", s.handleGetPublicTraderRiskMetrics)
 		}
 
 		// 需要认证的路由
@@ -138,29 +146,63 @@ func (s *Server) setupRoutes() {
 
 			// AI交易员管理
 			protected.GET("/my-traders", s.handleTraderList)
+			protected.POST("/my-traders/leverage", s.handleBulkUpdateLeverage) // 批量调整交易员杠杆
 			protected.GET("/traders/:id/config", s.handleGetTraderConfig)
 			protected.POST("/traders", s.handleCreateTrader)
 			protected.PUT("/traders/:id", s.handleUpdateTrader)
 			protected.DELETE("/traders/:id", s.handleDeleteTrader)
 			protected.POST("/traders/:id/start", s.handleStartTrader)
 			protected.POST("/traders/:id/stop", s.handleStopTrader)
+			protected.POST("/traders/:id/run-cycle", s.handleRunCycle)             // 手动触发一次决策周期
+			protected.POST("/traders/:id/reduce-exposure", s.handleReduceExposure) // 所有持仓按同一比例减仓
+			protected.POST("/traders/:id/positions/reprotect", s.handleReprotectPosition)
+			protected.POST("/traders/:id/positions/sweep-dust", s.handleSweepDust)   // 清理平仓后残留的粉尘持仓
+			protected.GET("/traders/:id/pending-actions", s.handleGetPendingActions) // 等待人工审批的首笔交易
+			protected.POST("/traders/:id/pending-actions/:action_id/approve", s.handleApprovePendingAction)
+			protected.POST("/traders/:id/pending-actions/:action_id/reject", s.handleRejectPendingAction)
+			protected.GET("/traders/:id/margin-mode", s.handleGetMarginMode)     // 交易所实际仓位模式与配置对比
+			protected.GET("/traders/:id/monitor-state", s.handleGetMonitorState) // 内存中的回撤监控状态（只读）
+			protected.POST("/traders/:id/margin-mode/reconcile", s.handleReconcileMarginMode)
+			protected.GET("/traders/:id/orphan-positions", s.handleGetOrphanPositions)          // 没有活跃策略/近期决策管理的孤儿持仓
+			protected.POST("/traders/:id/orphan-positions/close", s.handleCloseOrphanPositions) // 平掉所有孤儿持仓
+			protected.POST("/traders/:id/signal-preview", s.handleSignalPreview)
+			protected.GET("/traders/:id/statistics/breakdown", s.handleGetStatisticsBreakdown) // 按币种/方向/动作拆分的统计
 			protected.PUT("/traders/:id/prompt", s.handleUpdateTraderPrompt)
+			protected.PUT("/traders/:id/analysis-only", s.handleSetAnalysisOnly) // 运行时切换仅分析模式
 			protected.POST("/traders/:id/sync-balance", s.handleSyncBalance)
+			protected.POST("/traders/:id/set-baseline", s.handleSetTraderBaseline) // 手动设置/增量调整初始余额（记录审计）
 			protected.GET("/traders/:id/current-balance", s.handleGetCurrentBalance)
+			protected.GET("/traders/:id/balance-history", s.handleGetBalanceHistory) // 交易所资金流水（充值/提现/盈亏）
+			protected.GET("/traders/:id/baselines", s.handleGetTraderBaselines)      // 周期净值基准历史（全部时间/分周期盈亏）
 			protected.POST("/traders/:id/create-account", s.handleCreateTraderAccount)
 			protected.PUT("/traders/:id/account/password", s.handleUpdateTraderAccountPassword)
 			protected.GET("/traders/:id/account", s.handleGetTraderAccount)
 			protected.GET("/traders/:id/strategy-status", s.handleGetTraderStrategyStatus)
 			protected.GET("/traders/:id/strategy-statuses", s.handleGetTraderStrategyStatuses) // 新增：获取所有策略状态
 			protected.GET("/traders/:id/strategy-decisions", s.handleGetStrategyDecisions)
+			protected.GET("/traders/:id/last-snapshot", s.handleGetLastSnapshot)  // 最近一次持久化的账户快照（无需加载交易员）
+			protected.GET("/traders/:id/book", s.handleGetTraderBook)             // 持仓+委托+账户的一致快照
+			protected.GET("/traders/:id/candidates", s.handleGetTraderCandidates) // 候选币种及来源（不调用AI）
+			protected.DELETE("/traders/:id/decisions", s.handlePurgeDecisions)    // 手动清理指定时间之前的决策记录
 			protected.DELETE("/traders/:id/account", s.handleDeleteTraderAccount)
+			protected.GET("/traders/:id/decisions/stream", s.handleDecisionStream)
+			protected.GET("/traders/:id/symbols", s.handleGetTraderSymbols)
+			protected.PUT("/traders/:id/symbols", s.handleUpdateTraderSymbols)
+			protected.GET("/traders/:id/template-diff", s.handleGetTraderTemplateDiff)
 			protected.POST("/traders/:id/category", s.handleSetTraderCategory)
 
+			// 风险调整指标（年化收益、波动率、夏普、最大回撤、Calmar），?period=30d
+			protected.GET("/traders/:id/risk-metrics", s.handleGetTraderRiskMetrics)
+
 			// 分类管理
 			protected.GET("/categories", s.handleGetCategories)
 			protected.POST("/categories", s.handleCreateCategory)
 			protected.PUT("/categories/:id", s.handleUpdateCategory)
 			protected.DELETE("/categories/:id", s.handleDeleteCategory)
+			protected.GET("/categories/export", s.handleExportCategories)  // 导出分类+账号结构（不含密码）
+			protected.POST("/categories/import", s.handleImportCategories) // 导入分类+账号结构（新账号返回临时密码）
+			// 分类聚合表现（所有者/管理员/管理该分类的小组组长）
+			protected.GET("/categories/:id/performance", s.handleGetCategoryPerformance)
 
 			// 小组组长管理
 			protected.POST("/group-leaders/create", s.handleCreateGroupLeader)
@@ -177,17 +219,36 @@ func (s *Server) setupRoutes() {
 			// AI模型配置
 			protected.GET("/models", s.handleGetModelConfigs)
 			protected.PUT("/models", s.handleUpdateModelConfigs)
+			protected.DELETE("/models/:id/credentials/:field", s.handleClearModelCredential) // 清除无法解密的凭证后重新输入
 
 			// 交易所配置
 			protected.GET("/exchanges", s.handleGetExchangeConfigs)
 			protected.PUT("/exchanges", s.handleUpdateExchangeConfigs)
+			protected.DELETE("/exchanges/:id/credentials/:field", s.handleClearExchangeCredential)
+			protected.GET("/exchanges/:id/leverage-brackets", s.handleGetLeverageBrackets) // 币种杠杆分层（最大杠杆）
 
 			// 用户信号源配置
 			protected.GET("/user/signal-sources", s.handleGetUserSignalSource)
 			protected.POST("/user/signal-sources", s.handleSaveUserSignalSource)
+			protected.GET("/user/signal-sources/list", s.handleListSignalSources)
+			protected.POST("/user/signal-sources/list", s.handleCreateSignalSource)
+			protected.PUT("/user/signal-sources/:id", s.handleUpdateSignalSource)
+			protected.DELETE("/user/signal-sources/:id", s.handleDeleteSignalSource)
+
+			// Webhook回调
+			protected.GET("/user/webhooks", s.handleListWebhooks)
+			protected.POST("/user/webhooks", s.handleCreateWebhook)
+			protected.DELETE("/user/webhooks/:id", s.handleDeleteWebhook)
+			protected.POST("/user/webhooks/:id/test", s.handleTestWebhook)
+			protected.POST("/user/notifications/test", s.handleTestNotifications)
 
 			// 用户账户信息
 			protected.GET("/user/account", s.handleUserAccount)
+			protected.PUT("/user/account", s.handleUpdateUserAccount) // 更新账户设置（展示币种）
+
+			// 用户的新建交易员默认设置（提示词模板、杠杆等）
+			protected.GET("/user/defaults", s.handleGetUserDefaults)
+			protected.PUT("/user/defaults", s.handleUpdateUserDefaults)
 
 			// 指定trader的数据（使用query参数 ?trader_id=xxx）
 			protected.GET("/status", s.handleStatus)
@@ -209,6 +270,16 @@ func (s *Server) setupRoutes() {
 			protected.GET("/logs", s.handleGetLogs)
 		}
 
+		// 管理员专用路由
+		admin := api.Group("/admin", s.authMiddleware(), s.adminMiddleware())
+		{
+			admin.GET("/stats", s.handleAdminStats)
+			admin.POST("/rotate-encryption-key", s.handleRotateEncryptionKey)
+			admin.POST("/maintenance", s.handleSetMaintenanceMode)
+			admin.GET("/blacklist", s.handleGetTokenBlacklist)
+			admin.POST("/blacklist/clear", s.handleClearTokenBlacklist)
+		}
+
 		// 公开的分析报告 API
 		api.GET("/analysis/report", s.handleGetAnalysisReport)
 		api.GET("/analysis/report/stream", s.handleGetAnalysisReportStream)
@@ -371,13 +442,23 @@ func (s *Server) handleGetSystemConfig(c *gin.Context) {
 	betaModeStr, _ := s.database.GetSystemConfig("beta_mode")
 	betaMode := betaModeStr == "true"
 
-	c.JSON(http.StatusOK, gin.H{
-		"admin_mode":       auth.IsAdminMode(),
-		"beta_mode":        betaMode,
-		"default_coins":    defaultCoins,
-		"btc_eth_leverage": btcEthLeverage,
-		"altcoin_leverage": altcoinLeverage,
-	})
+	maxBTCETHLeverage, maxAltcoinLeverage := s.leverageCeilings()
+
+	resp := gin.H{
+		"admin_mode":           auth.IsAdminMode(),
+		"beta_mode":            betaMode,
+		"default_coins":        defaultCoins,
+		"btc_eth_leverage":     btcEthLeverage,
+		"altcoin_leverage":     altcoinLeverage,
+		"max_btc_eth_leverage": maxBTCETHLeverage,
+		"max_altcoin_leverage": maxAltcoinLeverage,
+		"maintenance_mode":     trader.MaintenanceMode(),
+	}
+	// 维护模式下附带 503 风格的提示横幅，前端据此展示维护通知
+	if trader.MaintenanceMode() {
+		resp["maintenance"] = maintenanceBanner(c)
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 // handleGetServerIP 获取服务器IP地址（用于白名单配置）
@@ -626,14 +707,119 @@ type CreateTraderRequest struct {
 	ScanIntervalMinutes  int     `json:"scan_interval_minutes"`
 	BTCETHLeverage       int     `json:"btc_eth_leverage"`
 	AltcoinLeverage      int     `json:"altcoin_leverage"`
-	TradingSymbols       string  `json:"trading_symbols"`
+	TradingSymbols       string  `json:"trading_symbols"`   // 交易币种（开仓白名单），为空表示不限制
+	CandidateSymbols     string  `json:"candidate_symbols"` // AI选币的候选币种池，为空时沿用 trading_symbols
 	CustomPrompt         string  `json:"custom_prompt"`
 	OverrideBasePrompt   bool    `json:"override_base_prompt"`
 	SystemPromptTemplate string  `json:"system_prompt_template"` // 系统提示词模板名称
 	IsCrossMargin        *bool   `json:"is_cross_margin"`        // 指针类型，nil表示使用默认值true
+
+	// 初始余额与交易所实际余额偏差较大时，需确认后重新提交（true 表示已确认，跳过偏差检查）
+	ConfirmInitialBalance bool `json:"confirm_initial_balance"`
 	UseCoinPool          bool    `json:"use_coin_pool"`
 	UseOITop             bool    `json:"use_oi_top"`
-	Category             string  `json:"category"` // 可选：分类名称（如果提供，必须属于当前用户）
+	Category             string  `json:"category"`              // 可选：分类名称（如果提供，必须属于当前用户）
+	RequireStopLoss      bool    `json:"require_stop_loss"`     // 开仓必须带有效止损
+	DefaultStopLossPct   float64 `json:"default_stop_loss_pct"` // 止损缺失时自动推导的最大亏损百分比（0=不推导）
+
+	// 交易选项
+	ExcludeHeldFromCandidates bool                   `json:"exclude_held_from_candidates"` // 候选币种中剔除已持仓币种
+	AnalysisOnly              bool                   `json:"analysis_only"`                // 仅分析模式（只记录决策不执行）
+	WarmupMinutes             int                    `json:"warmup_minutes"`               // 启动后预热时长（分钟），0=不预热
+	SkipCycleIfBusy           bool                   `json:"skip_cycle_if_busy"`           // 周期执行中时跳过新的触发（默认等待）
+	MaxPositionAgeHours       int                    `json:"max_position_age_hours"`       // 持仓最长持有时间（小时），0=不限制
+	AllowPyramiding           bool                   `json:"allow_pyramiding"`             // 允许对同方向已有持仓加仓（默认关闭）
+	MaxAddsPerPosition        int                    `json:"max_adds_per_position"`        // 单个持仓最多加仓次数，0=默认2次
+	EnforceDailyLossStop      bool                   `json:"enforce_daily_loss_stop"`      // 日亏损硬止损（默认关闭，仅作提示）
+	AllowFlip                 bool                   `json:"allow_flip"`                   // 允许反手动作 flip_long/flip_short（默认关闭）
+	MinConfidence             int                    `json:"min_confidence"`               // 开仓最低信心度（0-100，0表示不限制）
+	SignalBasePositionPct     float64                `json:"signal_base_position_pct"`     // 信号模式底仓百分比（0表示默认20）
+	SignalDefaultAddPct       float64                `json:"signal_default_add_pct"`       // 信号模式默认补仓百分比（0表示默认10）
+	EquityTakeProfit          float64                `json:"equity_take_profit"`           // 账户净值止盈（USDT，0表示关闭）
+	EquityStopLoss            float64                `json:"equity_stop_loss"`             // 账户净值止损（USDT，0表示关闭）
+	EquityTakeProfitPct       float64                `json:"equity_take_profit_pct"`       // 账户净值止盈百分比（0表示关闭）
+	EquityStopLossPct         float64                `json:"equity_stop_loss_pct"`         // 账户净值止损百分比（0表示关闭）
+	AutoReprotect             bool                   `json:"auto_reprotect"`               // 每个决策周期后校验持仓保护单，缺失时自动补设
+	PublicDisplayName         string                 `json:"public_display_name"`          // 公开接口展示名称（空=交易员名称，"auto"=生成化名）
+	PublicVisibility          *bool                  `json:"public_visibility"`            // 是否在公开接口中展示（未传时默认展示）
+	BackupExchangeID          string                 `json:"backup_exchange_id"`           // 备用交易所配置ID（须与主交易所为同一交易所）
+	TradingSchedule           []trader.TradingWindow `json:"trading_schedule"`             // 允许开新仓的时段（UTC），为空表示全天可交易
+	IncludeOrderBookDepth     bool                   `json:"include_orderbook_depth"`      // 决策上下文包含盘口深度（交易所不支持时忽略）
+	SkipIfBTCMovePct          float64                `json:"skip_if_btc_move_pct"`         // BTC 1小时涨跌幅绝对值超过该百分比时跳过周期（0=不启用）
+	SkipIfFundingAbove        float64                `json:"skip_if_funding_above"`        // BTC 资金费率绝对值超过该百分比时跳过周期（0=不启用）
+	MaxOpenOrders             *int                   `json:"max_open_orders"`              // 单个币种最多同时存在的限价挂单数（未传时默认10，0=不限制）
+	BreakevenAtProfitPct      float64                `json:"breakeven_at_profit_pct"`      // 持仓收益率达到该百分比后止损移至开仓价（0=不启用）
+	TrailStopAfterProfitPct   float64                `json:"trail_stop_after_profit_pct"`  // 持仓收益率达到该百分比后跟踪止损（0=不启用）
+	TrailLockFraction         float64                `json:"trail_lock_fraction"`          // 跟踪止损锁定的峰值收益比例（0-1，0=默认0.5）
+	MaxActionsPerCycle        *int                   `json:"max_actions_per_cycle"`        // 每个决策周期最多执行的动作数（未传时默认10，0=不限制）
+
+	// 首笔交易人工审批
+	RequireFirstTradeApproval bool `json:"approval_required_first_trade"` // 首笔开仓需人工审批后执行
+
+	// AI调用频率下限
+	MinSecondsBetweenAICalls int `json:"min_seconds_between_ai_calls"` // 两次AI调用之间的最小间隔（秒，0=不限制）
+
+	// 单币种敞口上限
+	MaxPerSymbolExposurePct float64 `json:"max_per_symbol_exposure_pct"` // 单币种持仓价值占净值的最大百分比（0=不限制）
+
+	// 决策上下文附带最近平仓交易
+	IncludeRecentTrades *bool `json:"include_recent_trades"` // 是否附带最近平仓交易（未传时默认开启）
+	RecentTradesCount   *int  `json:"recent_trades_count"`   // 附带的最近平仓交易笔数（未传时默认5，最多10）
+
+	// 仓位计算基数
+	SizingBase string `json:"sizing_base"` // fixed=初始余额（默认），equity=账户实时净值（复利）
+
+	// AI采样参数（未传时使用提供商默认值）
+	AITemperature *float64 `json:"ai_temperature"`
+	AITopP        *float64 `json:"ai_top_p"`
+	AIMaxTokens   int      `json:"ai_max_tokens"` // 0=默认值
+
+	// AI调用/解析失败时的处理策略
+	OnAIFailure string `json:"on_ai_failure"` // hold=保持现状（默认），flatten=平掉所有持仓，pause=暂停开新仓直到下一次AI决策成功
+
+	// 周期净值基准滚动策略
+	BaselineResetPolicy string `json:"baseline_reset_policy"` // none=不滚动（默认），weekly=每周一，monthly=每月1日（UTC）记录新的周期基准
+
+	// 账户最大回撤硬止损（净值较历史峰值回撤达到阈值时停止开新仓并暂停交易）
+	EnforceMaxDrawdownStop bool    `json:"enforce_max_drawdown_stop"` // 默认关闭，max_drawdown 仅作提示
+	MaxDrawdownStopPct     float64 `json:"max_drawdown_stop_pct"`     // 回撤阈值（百分比），0=使用系统 max_drawdown
+	DrawdownStopFlatten    bool    `json:"drawdown_stop_flatten"`     // 触发时是否平掉所有持仓
+
+	// 止损接近提醒
+	StopApproachAlertPct float64 `json:"stop_approach_alert_pct"` // 标记价格距止损在该百分比以内时推送一次通知（0=关闭，默认）
+
+	// 最短持仓时间
+	MinHoldingMinutes int `json:"min_holding_minutes"` // 持仓未满该分钟数时拒绝AI主动平仓（0=不限制，默认）
+
+	// 信号模式策略来源订阅
+	StrategySources string `json:"strategy_sources"` // 只跟随这些来源的策略（发件人邮箱或 @域名，逗号分隔；为空时跟随所有来源）
+
+	// 多周期指标上下文（自主模式，加入候选币种和持仓的 prompt）
+	ContextIndicators string `json:"context_indicators"` // 指标：rsi / macd / ema_cross / atr，逗号分隔（为空表示不加入，默认）
+	ContextTimeframes string `json:"context_timeframes"` // K线周期：5m / 15m / 1h / 4h / 1d，逗号分隔，最多3个
+
+	// 信号模式第一止盈分批止盈
+	TP1ClosePct      float64 `json:"tp1_close_pct"`      // 到达第一止盈时部分平仓的百分比（0=关闭，默认）
+	TP1BreakevenStop bool    `json:"tp1_breakeven_stop"` // 分批止盈后将剩余仓位止损移到开仓价
+
+	// 目标权重再平衡模式（配置了目标权重时替代AI自主决策）
+	RebalanceTargets         map[string]float64 `json:"rebalance_targets"`          // 币种 -> 目标权重（占仓位计算基数的百分比），合计不超过100
+	RebalanceTolerancePct    float64            `json:"rebalance_tolerance_pct"`    // 偏离目标超过该百分点时调整（0=默认2）
+	RebalanceIntervalMinutes int                `json:"rebalance_interval_minutes"` // 检查间隔分钟数（0=默认60，5~10080）
+
+	UseOCO bool `json:"use_oco"` // 开仓止损/止盈以 OCO 方式联动（一个成交后撤销另一个）
+
+	// AI输出异常检测（连续周期数，0=关闭）
+	AIAnomalyIdleCycles   int  `json:"ai_anomaly_idle_cycles"`   // 有候选币种却连续无开仓的周期数
+	AIAnomalyRepeatCycles int  `json:"ai_anomaly_repeat_cycles"` // 连续输出相同决策的周期数（至少2）
+	AIAnomalyPause        bool `json:"ai_anomaly_pause"`         // 异常期间暂停开新仓
+
+	// 默认止损/止盈（币种或 default -> {"sl": 百分比, "tp": 百分比}），决策缺少保护价位时按开仓价推导
+	ProtectiveDefaults map[string]trader.ProtectiveDefault `json:"protective_defaults"`
+
+	PositionAuditMinutes int `json:"position_audit_minutes"` // 信号模式仓位对账间隔（分钟），0=默认30
+
+	MinAvailableBalance float64 `json:"min_available_balance"` // 可用余额低于该值（或为负）时暂停开新仓，0=仅负数时
 }
 
 type ModelConfig struct {
@@ -661,6 +847,11 @@ type UpdateModelConfigRequest struct {
 		APIKey          string `json:"api_key"`
 		CustomAPIURL    string `json:"custom_api_url"`
 		CustomModelName string `json:"custom_model_name"`
+		MaxPromptTokens int    `json:"max_prompt_tokens"` // Prompt token 预算（0=不限制）
+
+		// 自定义API的附加请求头与认证方式（bearer/raw/api-key/x-api-key/none），请求头不传时保留已保存的值，传空对象表示清除
+		CustomHeaders    map[string]string `json:"custom_headers"`
+		CustomAuthScheme string            `json:"custom_auth_scheme"`
 	} `json:"models"`
 }
 
@@ -684,30 +875,192 @@ type UpdateExchangeConfigRequest struct {
 func (s *Server) handleCreateTrader(c *gin.Context) {
 	userID := c.GetString("user_id")
 	var req CreateTraderRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err)
 		return
 	}
 
-	// Validate leverage range (0 means use system default)
-	if req.BTCETHLeverage < 0 || req.BTCETHLeverage > 125 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "BTC/ETH leverage must be between 1 and 125 (or 0 to use default)."})
+	// 请求中未显式提供的字段使用用户的默认设置（用户未配置的再回退到系统默认值）
+	if defaults, err := s.database.GetUserTraderDefaults(userID); err != nil {
+		log.Printf("⚠️ 读取用户 %s 的默认设置失败，使用系统默认值: %v", userID, err)
+	} else {
+		body, _ := c.Get(gin.BodyBytesKey)
+		raw, _ := body.([]byte)
+		applyUserTraderDefaults(&req, providedJSONFields(raw), defaults)
+	}
+
+	// Validate leverage range (0 means use system default; ceilings come from system config)
+	if !s.validateLeverageCeilings(c, req.BTCETHLeverage, req.AltcoinLeverage) {
+		return
+	}
+	if req.DefaultStopLossPct < 0 || req.DefaultStopLossPct >= 100 {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidStopLossPct)
+		return
+	}
+	if req.WarmupMinutes < 0 {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidWarmupMinutes)
+		return
+	}
+	if req.MinConfidence < 0 || req.MinConfidence > 100 {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidMinConfidence)
+		return
+	}
+	// 未配置时保留0（运行时使用默认值），只按实际生效的比例校验
+	if !trader.ValidSignalSizing(trader.EffectiveSignalSizing(req.SignalBasePositionPct, req.SignalDefaultAddPct)) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidSignalSizing)
+		return
+	}
+	if !trader.ValidEquityBracket(req.EquityTakeProfit, req.EquityStopLoss, req.EquityTakeProfitPct, req.EquityStopLossPct) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidEquityBracket)
+		return
+	}
+	if err := trader.ValidateTradingSchedule(req.TradingSchedule); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidTradingSchedule, err)
+		return
+	}
+	if req.SkipIfBTCMovePct < 0 || req.SkipIfFundingAbove < 0 {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidMarketCondition)
+		return
+	}
+	maxOpenOrders := trader.DefaultMaxOpenOrders
+	if req.MaxOpenOrders != nil {
+		maxOpenOrders = *req.MaxOpenOrders
+	}
+	if maxOpenOrders < 0 {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidMaxOpenOrders)
+		return
+	}
+	if req.BreakevenAtProfitPct < 0 || req.TrailStopAfterProfitPct < 0 || req.TrailLockFraction < 0 || req.TrailLockFraction >= 1 {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidTrailingStop)
+		return
+	}
+	maxActionsPerCycle := trader.DefaultMaxActionsPerCycle
+	if req.MaxActionsPerCycle != nil {
+		maxActionsPerCycle = *req.MaxActionsPerCycle
+	}
+	if maxActionsPerCycle < 0 {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidMaxActions)
 		return
 	}
-	if req.AltcoinLeverage < 0 || req.AltcoinLeverage > 75 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Altcoin leverage must be between 1 and 75 (or 0 to use default)."})
+	if req.MinSecondsBetweenAICalls < 0 {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidAIInterval)
+		return
+	}
+	if req.MaxPerSymbolExposurePct < 0 {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidSymbolExposure)
+		return
+	}
+	includeRecentTrades := true
+	if req.IncludeRecentTrades != nil {
+		includeRecentTrades = *req.IncludeRecentTrades
+	}
+	recentTradesCount := trader.DefaultRecentTradesCount
+	if req.RecentTradesCount != nil {
+		recentTradesCount = *req.RecentTradesCount
+	}
+	if recentTradesCount < 1 || recentTradesCount > trader.MaxRecentTradesCount {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRecentTrades, trader.MaxRecentTradesCount)
+		return
+	}
+	if !trader.ValidSizingBase(req.SizingBase) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidSizingBase, req.SizingBase)
+		return
+	}
+	sizingBase := req.SizingBase
+	if sizingBase == "" {
+		sizingBase = trader.SizingBaseFixed
+	}
+	if !trader.ValidAIFailurePolicy(req.OnAIFailure) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidAIFailurePolicy, req.OnAIFailure)
+		return
+	}
+	onAIFailure := req.OnAIFailure
+	if onAIFailure == "" {
+		onAIFailure = trader.AIFailureHold
+	}
+	if !trader.ValidBaselineResetPolicy(req.BaselineResetPolicy) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidBaselinePolicy, req.BaselineResetPolicy)
+		return
+	}
+	baselineResetPolicy := req.BaselineResetPolicy
+	if baselineResetPolicy == "" {
+		baselineResetPolicy = trader.BaselineResetNone
+	}
+	if !trader.ValidMaxDrawdownStopPct(req.MaxDrawdownStopPct) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidDrawdownStop)
+		return
+	}
+	if !trader.ValidStopApproachAlertPct(req.StopApproachAlertPct) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidStopApproach)
+		return
+	}
+	if !trader.ValidMinHoldingMinutes(req.MinHoldingMinutes) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidMinHolding)
+		return
+	}
+	if source := invalidStrategySource(req.StrategySources); source != "" {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidStrategySource, source)
+		return
+	}
+	if err := trader.ValidateContextIndicators(trader.ParseIndicatorList(req.ContextIndicators), trader.ParseIndicatorList(req.ContextTimeframes)); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidIndicators, err)
+		return
+	}
+	if !trader.ValidTP1ClosePct(req.TP1ClosePct) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidTP1ClosePct)
+		return
+	}
+	if err := trader.ValidateRebalanceConfig(req.RebalanceTargets, req.RebalanceTolerancePct, req.RebalanceIntervalMinutes); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRebalance, err)
+		return
+	}
+	if err := trader.ValidateAIAnomalyConfig(req.AIAnomalyIdleCycles, req.AIAnomalyRepeatCycles); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidAIAnomaly, err)
+		return
+	}
+	if err := trader.ValidateProtectiveDefaults(req.ProtectiveDefaults); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidSLTPDefaults, err)
+		return
+	}
+	if err := trader.ValidatePositionAuditInterval(req.PositionAuditMinutes); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidAuditInterval, err)
+		return
+	}
+	if err := trader.ValidateMinAvailableBalance(req.MinAvailableBalance); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidMinAvailable, err)
+		return
+	}
+	aiSampling := mcp.SamplingParams{Temperature: req.AITemperature, TopP: req.AITopP, MaxTokens: req.AIMaxTokens}
+	if err := mcp.ValidateSamplingParams(s.aiModelProvider(userID, req.AIModelID), aiSampling); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidAISampling, err)
 		return
 	}
 
-	// 校验交易币种格式
-	if req.TradingSymbols != "" {
-		symbols := strings.Split(req.TradingSymbols, ",")
-		for _, symbol := range symbols {
-			symbol = strings.TrimSpace(symbol)
-			if symbol != "" && !strings.HasSuffix(strings.ToUpper(symbol), "USDT") {
-				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("无效的币种格式: %s，必须以USDT结尾", symbol)})
-				return
+	// 校验自定义prompt（长度限制 + 占位符转义）
+	customPrompt, err := SanitizeCustomPrompt(req.CustomPrompt, s.maxCustomPromptLength())
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err)
+		return
+	}
+	req.CustomPrompt = customPrompt
+
+	// 检查交易员数量上限（管理员不受限制）
+	if user, err := s.database.GetUserByID(userID); err == nil && user.Role != "admin" {
+		if count, limit, err := s.database.CheckTraderQuota(userID); err != nil {
+			if limit > 0 {
+				respondError(c, http.StatusForbidden, ErrCodeTraderQuotaExceeded, count, limit)
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			}
+			return
+		}
+	}
+
+	// 校验交易币种/候选币种格式
+	for _, symbols := range []string{req.TradingSymbols, req.CandidateSymbols} {
+		if symbol := invalidSymbol(symbols); symbol != "" {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidSymbol, symbol)
+			return
 		}
 	}
 
@@ -716,7 +1069,7 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 	// 但生成交易员ID时应该使用 provider（如 bitget）
 	exchanges, err := s.database.GetExchanges(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取交易所配置失败: %v", err)})
+		respondError(c, http.StatusInternalServerError, ErrCodeExchangeConfigFailed, err)
 		return
 	}
 
@@ -725,32 +1078,22 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 	for _, exchange := range exchanges {
 		if exchange.ID == req.ExchangeID {
 			exchangeCfg = exchange
-			exchangeProvider = exchange.Provider
-			if exchangeProvider == "" {
-				// 如果 provider 为空，从 ID 推断（兼容旧数据）
-				if strings.HasPrefix(exchange.ID, "binance") {
-					exchangeProvider = "binance"
-				} else if strings.HasPrefix(exchange.ID, "hyperliquid") {
-					exchangeProvider = "hyperliquid"
-				} else if strings.HasPrefix(exchange.ID, "aster") {
-					exchangeProvider = "aster"
-				} else if strings.HasPrefix(exchange.ID, "bitget") {
-					exchangeProvider = "bitget"
-				} else {
-					exchangeProvider = exchange.ID // Fallback
-				}
-			}
+			exchangeProvider = resolveExchangeProvider(exchange)
 			break
 		}
 	}
 
 	if exchangeCfg == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("交易所配置不存在: %s", req.ExchangeID)})
+		respondError(c, http.StatusBadRequest, ErrCodeExchangeNotFound, req.ExchangeID)
 		return
 	}
 
 	if !exchangeCfg.Enabled {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "交易所未启用"})
+		respondError(c, http.StatusBadRequest, ErrCodeExchangeDisabled)
+		return
+	}
+	if err := validateBackupExchange(exchanges, req.ExchangeID, req.BackupExchangeID); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidBackupExchange, err)
 		return
 	}
 
@@ -766,31 +1109,16 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 	}
 
 	// 设置杠杆默认值（从系统配置获取）
-	btcEthLeverage := 5
-	altcoinLeverage := 5
+	btcEthLeverage, altcoinLeverage := s.systemDefaultLeverage()
 	if req.BTCETHLeverage > 0 {
 		btcEthLeverage = req.BTCETHLeverage
-	} else {
-		// 从系统配置获取默认值
-		if btcEthLeverageStr, _ := s.database.GetSystemConfig("btc_eth_leverage"); btcEthLeverageStr != "" {
-			if val, err := strconv.Atoi(btcEthLeverageStr); err == nil && val > 0 {
-				btcEthLeverage = val
-			}
-		}
 	}
 	if req.AltcoinLeverage > 0 {
 		altcoinLeverage = req.AltcoinLeverage
-	} else {
-		// 从系统配置获取默认值
-		if altcoinLeverageStr, _ := s.database.GetSystemConfig("altcoin_leverage"); altcoinLeverageStr != "" {
-			if val, err := strconv.Atoi(altcoinLeverageStr); err == nil && val > 0 {
-				altcoinLeverage = val
-			}
-		}
 	}
 
 	// 设置系统提示词模板默认值
-	systemPromptTemplate := "default"
+	systemPromptTemplate := defaultSystemPromptTemplate
 	if req.SystemPromptTemplate != "" {
 		systemPromptTemplate = req.SystemPromptTemplate
 	}
@@ -798,15 +1126,45 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 	// 设置扫描间隔默认值（移除最小3分钟限制，允许测试用）
 	scanIntervalMinutes := req.ScanIntervalMinutes
 	if scanIntervalMinutes <= 0 {
-		scanIntervalMinutes = 5 // 默认5分钟
+		scanIntervalMinutes = defaultScanIntervalMinutes // 默认5分钟
 	}
 	// 注释掉最小3分钟限制，允许设置1分钟用于测试
 	// if scanIntervalMinutes < 3 {
 	// 	scanIntervalMinutes = 3
 	// }
 
-	// ✅ 直接使用用户输入的初始余额，不进行任何自动查询或覆盖
+	// ✅ 直接使用用户输入的初始余额，不进行自动覆盖；与交易所实际余额偏差较大时要求用户确认
 	actualBalance := req.InitialBalance
+	maxInitialBalance, balanceTolerancePct := config.DefaultMaxInitialBalance, config.DefaultInitialBalanceDeviationPct
+	if s.database != nil {
+		maxInitialBalance, balanceTolerancePct = s.database.GetInitialBalanceLimits()
+	}
+	switch checkInitialBalance(actualBalance, maxInitialBalance) {
+	case ErrCodeInvalidInitialBalance:
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidInitialBalance)
+		return
+	case ErrCodeInitialBalanceTooHigh:
+		respondError(c, http.StatusBadRequest, ErrCodeInitialBalanceTooHigh, maxInitialBalance)
+		return
+	}
+	if !req.ConfirmInitialBalance && balanceTolerancePct > 0 {
+		detected, err := fetchExchangeBalance(exchangeCfg, userID)
+		if err != nil {
+			log.Printf("⚠️ 查询交易所余额失败，跳过初始余额偏差检查: %v", err)
+		} else if initialBalanceDeviates(actualBalance, detected, balanceTolerancePct) {
+			lang := c.GetString(langContextKey)
+			if lang == "" {
+				lang = parseAcceptLanguage(c.GetHeader("Accept-Language"))
+			}
+			c.JSON(http.StatusConflict, gin.H{
+				"error":            localizeError(lang, ErrCodeInitialBalanceMismatch, actualBalance, detected, balanceTolerancePct),
+				"code":             ErrCodeInitialBalanceMismatch,
+				"initial_balance":  actualBalance,
+				"detected_balance": detected,
+			})
+			return
+		}
+	}
 	log.Printf("✓ 使用用户设置的初始余额: %.2f USDT", actualBalance)
 
 	// 设置分类和所有者用户ID
@@ -815,16 +1173,22 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 		// 验证分类是否属于当前用户
 		categoryObj, err := s.database.GetCategoryByName(req.Category)
 		if err != nil || categoryObj == nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "分类不存在"})
+			respondError(c, http.StatusBadRequest, ErrCodeCategoryNotFound)
 			return
 		}
 		if categoryObj.OwnerUserID != userID {
-			c.JSON(http.StatusForbidden, gin.H{"error": "只能使用自己的分类"})
+			respondError(c, http.StatusForbidden, ErrCodeCategoryNotOwned)
 			return
 		}
 		category = req.Category
 	}
 
+	// 公开展示设置（默认展示真实名称，保持原有行为）
+	publicVisibility := true
+	if req.PublicVisibility != nil {
+		publicVisibility = *req.PublicVisibility
+	}
+
 	// 创建交易员配置（数据库实体）
 	trader := &config.TraderRecord{
 		ID:                   traderID,
@@ -838,6 +1202,7 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 		BTCETHLeverage:       btcEthLeverage,
 		AltcoinLeverage:      altcoinLeverage,
 		TradingSymbols:       req.TradingSymbols,
+		CandidateSymbols:     req.CandidateSymbols,
 		UseCoinPool:          req.UseCoinPool,
 		UseOITop:             req.UseOITop,
 		CustomPrompt:         req.CustomPrompt,
@@ -846,12 +1211,76 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 		IsCrossMargin:        isCrossMargin,
 		ScanIntervalMinutes:  scanIntervalMinutes,
 		IsRunning:            false,
+		RequireStopLoss:      req.RequireStopLoss,
+		DefaultStopLossPct:   req.DefaultStopLossPct,
+
+		// 交易选项
+		ExcludeHeldFromCandidates: req.ExcludeHeldFromCandidates,
+		AnalysisOnly:              req.AnalysisOnly,
+		WarmupMinutes:             req.WarmupMinutes,
+		SkipCycleIfBusy:           req.SkipCycleIfBusy,
+		MaxPositionAgeHours:       req.MaxPositionAgeHours,
+		AllowPyramiding:           req.AllowPyramiding,
+		MaxAddsPerPosition:        req.MaxAddsPerPosition,
+		EnforceDailyLossStop:      req.EnforceDailyLossStop,
+		AllowFlip:                 req.AllowFlip,
+		MinConfidence:             req.MinConfidence,
+		SignalBasePositionPct:     req.SignalBasePositionPct,
+		SignalDefaultAddPct:       req.SignalDefaultAddPct,
+		EquityTakeProfit:          req.EquityTakeProfit,
+		EquityStopLoss:            req.EquityStopLoss,
+		EquityTakeProfitPct:       req.EquityTakeProfitPct,
+		EquityStopLossPct:         req.EquityStopLossPct,
+		AutoReprotect:             req.AutoReprotect,
+		PublicDisplayName:         resolvePublicDisplayName(req.PublicDisplayName, traderID),
+		PublicVisibility:          publicVisibility,
+		BackupExchangeID:          req.BackupExchangeID,
+		TradingSchedule:           trader.FormatTradingSchedule(req.TradingSchedule),
+		IncludeOrderBookDepth:     req.IncludeOrderBookDepth,
+		SkipIfBTCMovePct:          req.SkipIfBTCMovePct,
+		SkipIfFundingAbove:        req.SkipIfFundingAbove,
+		MaxOpenOrders:             maxOpenOrders,
+		BreakevenAtProfitPct:      req.BreakevenAtProfitPct,
+		TrailStopAfterProfitPct:   req.TrailStopAfterProfitPct,
+		TrailLockFraction:         req.TrailLockFraction,
+		MaxActionsPerCycle:        maxActionsPerCycle,
+		RequireFirstTradeApproval: req.RequireFirstTradeApproval,
+		MinSecondsBetweenAICalls:  req.MinSecondsBetweenAICalls,
+		MaxPerSymbolExposurePct:   req.MaxPerSymbolExposurePct,
+		IncludeRecentTrades:       includeRecentTrades,
+		RecentTradesCount:         recentTradesCount,
+		SizingBase:                sizingBase,
+		AITemperature:             aiSampling.Temperature,
+		AITopP:                    aiSampling.TopP,
+		AIMaxTokens:               aiSampling.MaxTokens,
+		OnAIFailure:               onAIFailure,
+		BaselineResetPolicy:       baselineResetPolicy,
+		EnforceMaxDrawdownStop:    req.EnforceMaxDrawdownStop,
+		MaxDrawdownStopPct:        req.MaxDrawdownStopPct,
+		DrawdownStopFlatten:       req.DrawdownStopFlatten,
+		StopApproachAlertPct:      req.StopApproachAlertPct,
+		MinHoldingMinutes:         req.MinHoldingMinutes,
+		StrategySources:           strings.Join(signal.ParseSources(req.StrategySources), ","),
+		ContextIndicators:         strings.Join(trader.ParseIndicatorList(req.ContextIndicators), ","),
+		ContextTimeframes:         strings.Join(trader.ParseIndicatorList(req.ContextTimeframes), ","),
+		TP1ClosePct:               req.TP1ClosePct,
+		TP1BreakevenStop:          req.TP1BreakevenStop,
+		RebalanceTargets:          trader.FormatRebalanceTargets(trader.NormalizeRebalanceTargets(req.RebalanceTargets)),
+		RebalanceTolerancePct:     req.RebalanceTolerancePct,
+		RebalanceIntervalMinutes:  req.RebalanceIntervalMinutes,
+		UseOCO:                    req.UseOCO,
+		AIAnomalyIdleCycles:       req.AIAnomalyIdleCycles,
+		AIAnomalyRepeatCycles:     req.AIAnomalyRepeatCycles,
+		AIAnomalyPause:            req.AIAnomalyPause,
+		ProtectiveDefaults:        trader.FormatProtectiveDefaults(trader.NormalizeProtectiveDefaults(req.ProtectiveDefaults)),
+		PositionAuditMinutes:      req.PositionAuditMinutes,
+		MinAvailableBalance:       req.MinAvailableBalance,
 	}
 
 	// 保存到数据库
 	err = s.database.CreateTrader(trader)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("创建交易员失败: %v", err)})
+		respondError(c, http.StatusInternalServerError, ErrCodeCreateTraderFailed, err)
 		return
 	}
 
@@ -874,18 +1303,128 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 
 // UpdateTraderRequest 更新交易员请求
 type UpdateTraderRequest struct {
-	Name                 string  `json:"name" binding:"required"`
-	AIModelID            string  `json:"ai_model_id" binding:"required"`
-	ExchangeID           string  `json:"exchange_id" binding:"required"`
-	InitialBalance       float64 `json:"initial_balance"`
-	ScanIntervalMinutes  int     `json:"scan_interval_minutes"`
-	BTCETHLeverage       int     `json:"btc_eth_leverage"`
-	AltcoinLeverage      int     `json:"altcoin_leverage"`
-	TradingSymbols       string  `json:"trading_symbols"`
-	CustomPrompt         string  `json:"custom_prompt"`
-	OverrideBasePrompt   bool    `json:"override_base_prompt"`
-	SystemPromptTemplate string  `json:"system_prompt_template"` // 系统提示词模板名称
-	IsCrossMargin        *bool   `json:"is_cross_margin"`
+	Name                 string   `json:"name" binding:"required"`
+	AIModelID            string   `json:"ai_model_id" binding:"required"`
+	ExchangeID           string   `json:"exchange_id" binding:"required"`
+	InitialBalance       float64  `json:"initial_balance"`
+	ScanIntervalMinutes  int      `json:"scan_interval_minutes"`
+	BTCETHLeverage       int      `json:"btc_eth_leverage"`
+	AltcoinLeverage      int      `json:"altcoin_leverage"`
+	TradingSymbols       string   `json:"trading_symbols"`
+	CandidateSymbols     *string  `json:"candidate_symbols"` // nil表示保持原值
+	CustomPrompt         string   `json:"custom_prompt"`
+	OverrideBasePrompt   bool     `json:"override_base_prompt"`
+	SystemPromptTemplate string   `json:"system_prompt_template"` // 系统提示词模板名称
+	IsCrossMargin        *bool    `json:"is_cross_margin"`
+	RequireStopLoss      *bool    `json:"require_stop_loss"`     // nil表示保持原值
+	DefaultStopLossPct   *float64 `json:"default_stop_loss_pct"` // nil表示保持原值
+
+	// 交易选项（nil表示保持原值）
+	ExcludeHeldFromCandidates *bool                   `json:"exclude_held_from_candidates"`
+	AnalysisOnly              *bool                   `json:"analysis_only"`
+	WarmupMinutes             *int                    `json:"warmup_minutes"`
+	SkipCycleIfBusy           *bool                   `json:"skip_cycle_if_busy"`
+	MaxPositionAgeHours       *int                    `json:"max_position_age_hours"`
+	AllowPyramiding           *bool                   `json:"allow_pyramiding"`
+	MaxAddsPerPosition        *int                    `json:"max_adds_per_position"`
+	EnforceDailyLossStop      *bool                   `json:"enforce_daily_loss_stop"`
+	AllowFlip                 *bool                   `json:"allow_flip"`
+	MinConfidence             *int                    `json:"min_confidence"`
+	SignalBasePositionPct     *float64                `json:"signal_base_position_pct"`
+	SignalDefaultAddPct       *float64                `json:"signal_default_add_pct"`
+	EquityTakeProfit          *float64                `json:"equity_take_profit"`
+	EquityStopLoss            *float64                `json:"equity_stop_loss"`
+	EquityTakeProfitPct       *float64                `json:"equity_take_profit_pct"`
+	EquityStopLossPct         *float64                `json:"equity_stop_loss_pct"`
+	AutoReprotect             *bool                   `json:"auto_reprotect"`
+	PublicDisplayName         *string                 `json:"public_display_name"`
+	PublicVisibility          *bool                   `json:"public_visibility"`
+	BackupExchangeID          *string                 `json:"backup_exchange_id"`
+	TradingSchedule           *[]trader.TradingWindow `json:"trading_schedule"`
+	IncludeOrderBookDepth     *bool                   `json:"include_orderbook_depth"`
+	SkipIfBTCMovePct          *float64                `json:"skip_if_btc_move_pct"`
+	SkipIfFundingAbove        *float64                `json:"skip_if_funding_above"`
+	MaxOpenOrders             *int                    `json:"max_open_orders"`
+	BreakevenAtProfitPct      *float64                `json:"breakeven_at_profit_pct"`
+	TrailStopAfterProfitPct   *float64                `json:"trail_stop_after_profit_pct"`
+	TrailLockFraction         *float64                `json:"trail_lock_fraction"`
+	MaxActionsPerCycle        *int                    `json:"max_actions_per_cycle"`
+
+	// 首笔交易人工审批
+	RequireFirstTradeApproval *bool `json:"approval_required_first_trade"`
+
+	// AI调用频率下限
+	MinSecondsBetweenAICalls *int `json:"min_seconds_between_ai_calls"`
+
+	// 单币种敞口上限
+	MaxPerSymbolExposurePct *float64 `json:"max_per_symbol_exposure_pct"`
+
+	// 决策上下文附带最近平仓交易
+	IncludeRecentTrades *bool `json:"include_recent_trades"`
+	RecentTradesCount   *int  `json:"recent_trades_count"`
+
+	// 仓位计算基数
+	SizingBase *string `json:"sizing_base"`
+
+	// AI采样参数（未传时保持不变）
+	AITemperature *float64 `json:"ai_temperature"`
+	AITopP        *float64 `json:"ai_top_p"`
+	AIMaxTokens   *int     `json:"ai_max_tokens"`
+
+	// AI调用/解析失败时的处理策略（未传时保持不变）
+	OnAIFailure *string `json:"on_ai_failure"`
+
+	// 周期净值基准滚动策略（未传时保持不变）
+	BaselineResetPolicy *string `json:"baseline_reset_policy"`
+
+	// 账户最大回撤硬止损（未传时保持不变）
+	EnforceMaxDrawdownStop *bool    `json:"enforce_max_drawdown_stop"`
+	MaxDrawdownStopPct     *float64 `json:"max_drawdown_stop_pct"`
+	DrawdownStopFlatten    *bool    `json:"drawdown_stop_flatten"`
+
+	// 止损接近提醒阈值（未传时保持不变）
+	StopApproachAlertPct *float64 `json:"stop_approach_alert_pct"`
+
+	// 最短持仓时间（分钟，未传时保持不变）
+	MinHoldingMinutes *int `json:"min_holding_minutes"`
+
+	// 信号模式策略来源订阅（未传时保持不变，传空字符串表示跟随所有来源）
+	StrategySources *string `json:"strategy_sources"`
+
+	// 多周期指标上下文（未传时保持不变，传空字符串表示不加入）
+	ContextIndicators *string `json:"context_indicators"`
+	ContextTimeframes *string `json:"context_timeframes"`
+
+	// 信号模式第一止盈分批止盈（未传时保持不变）
+	TP1ClosePct      *float64 `json:"tp1_close_pct"`
+	TP1BreakevenStop *bool    `json:"tp1_breakeven_stop"`
+
+	// 目标权重再平衡（未传时保持不变，传空对象表示关闭；启用/关闭需重启交易员）
+	RebalanceTargets         *map[string]float64 `json:"rebalance_targets"`
+	RebalanceTolerancePct    *float64            `json:"rebalance_tolerance_pct"`
+	RebalanceIntervalMinutes *int                `json:"rebalance_interval_minutes"`
+
+	UseOCO *bool `json:"use_oco"`
+
+	AIAnomalyIdleCycles   *int  `json:"ai_anomaly_idle_cycles"`
+	AIAnomalyRepeatCycles *int  `json:"ai_anomaly_repeat_cycles"`
+	AIAnomalyPause        *bool `json:"ai_anomaly_pause"`
+
+	ProtectiveDefaults *map[string]trader.ProtectiveDefault `json:"protective_defaults"`
+
+	PositionAuditMinutes *int `json:"position_audit_minutes"`
+
+	MinAvailableBalance *float64 `json:"min_available_balance"`
+}
+
+// invalidStrategySource 返回逗号分隔策略来源列表中第一个格式错误的订阅项，全部合法时返回空字符串
+func invalidStrategySource(sources string) string {
+	for _, source := range signal.ParseSources(sources) {
+		if !signal.ValidSource(source) {
+			return source
+		}
+	}
+	return ""
 }
 
 // handleUpdateTrader 更新交易员配置
@@ -895,24 +1434,125 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 
 	var req UpdateTraderRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err)
 		return
 	}
 
-	// Validate leverage range (0 means keep existing)
-	if req.BTCETHLeverage < 0 || req.BTCETHLeverage > 125 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "BTC/ETH leverage must be between 1 and 125 (or 0 to keep existing)."})
+	// Validate leverage range (0 means keep existing; ceilings come from system config)
+	if !s.validateLeverageCeilings(c, req.BTCETHLeverage, req.AltcoinLeverage) {
+		return
+	}
+	if req.DefaultStopLossPct != nil && (*req.DefaultStopLossPct < 0 || *req.DefaultStopLossPct >= 100) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidStopLossPct)
+		return
+	}
+	if req.WarmupMinutes != nil && *req.WarmupMinutes < 0 {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidWarmupMinutes)
+		return
+	}
+	if req.MinConfidence != nil && (*req.MinConfidence < 0 || *req.MinConfidence > 100) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidMinConfidence)
+		return
+	}
+	if (req.SkipIfBTCMovePct != nil && *req.SkipIfBTCMovePct < 0) || (req.SkipIfFundingAbove != nil && *req.SkipIfFundingAbove < 0) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidMarketCondition)
+		return
+	}
+	if req.MaxOpenOrders != nil && *req.MaxOpenOrders < 0 {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidMaxOpenOrders)
+		return
+	}
+	if (req.BreakevenAtProfitPct != nil && *req.BreakevenAtProfitPct < 0) || (req.TrailStopAfterProfitPct != nil && *req.TrailStopAfterProfitPct < 0) ||
+		(req.TrailLockFraction != nil && (*req.TrailLockFraction < 0 || *req.TrailLockFraction >= 1)) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidTrailingStop)
+		return
+	}
+	if req.MaxActionsPerCycle != nil && *req.MaxActionsPerCycle < 0 {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidMaxActions)
+		return
+	}
+	if req.MinSecondsBetweenAICalls != nil && *req.MinSecondsBetweenAICalls < 0 {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidAIInterval)
+		return
+	}
+	if req.MaxPerSymbolExposurePct != nil && *req.MaxPerSymbolExposurePct < 0 {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidSymbolExposure)
+		return
+	}
+	if req.RecentTradesCount != nil && (*req.RecentTradesCount < 1 || *req.RecentTradesCount > trader.MaxRecentTradesCount) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRecentTrades, trader.MaxRecentTradesCount)
+		return
+	}
+	if req.SizingBase != nil && !trader.ValidSizingBase(*req.SizingBase) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidSizingBase, *req.SizingBase)
+		return
+	}
+	if req.OnAIFailure != nil && !trader.ValidAIFailurePolicy(*req.OnAIFailure) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidAIFailurePolicy, *req.OnAIFailure)
+		return
+	}
+	if req.BaselineResetPolicy != nil && !trader.ValidBaselineResetPolicy(*req.BaselineResetPolicy) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidBaselinePolicy, *req.BaselineResetPolicy)
+		return
+	}
+	if req.MaxDrawdownStopPct != nil && !trader.ValidMaxDrawdownStopPct(*req.MaxDrawdownStopPct) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidDrawdownStop)
+		return
+	}
+	if req.StopApproachAlertPct != nil && !trader.ValidStopApproachAlertPct(*req.StopApproachAlertPct) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidStopApproach)
+		return
+	}
+	if req.MinHoldingMinutes != nil && !trader.ValidMinHoldingMinutes(*req.MinHoldingMinutes) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidMinHolding)
 		return
 	}
-	if req.AltcoinLeverage < 0 || req.AltcoinLeverage > 75 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Altcoin leverage must be between 1 and 75 (or 0 to keep existing)."})
+	if req.StrategySources != nil {
+		if source := invalidStrategySource(*req.StrategySources); source != "" {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidStrategySource, source)
+			return
+		}
+	}
+	if req.ContextIndicators != nil || req.ContextTimeframes != nil {
+		var indicators, timeframes []string
+		if req.ContextIndicators != nil {
+			indicators = trader.ParseIndicatorList(*req.ContextIndicators)
+		}
+		if req.ContextTimeframes != nil {
+			timeframes = trader.ParseIndicatorList(*req.ContextTimeframes)
+		}
+		if err := trader.ValidateContextIndicators(indicators, timeframes); err != nil {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidIndicators, err)
+			return
+		}
+	}
+	if req.TP1ClosePct != nil && !trader.ValidTP1ClosePct(*req.TP1ClosePct) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidTP1ClosePct)
+		return
+	}
+	if symbol := invalidSymbol(req.TradingSymbols); symbol != "" {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidSymbol, symbol)
 		return
 	}
+	if req.CandidateSymbols != nil {
+		if symbol := invalidSymbol(*req.CandidateSymbols); symbol != "" {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidSymbol, symbol)
+			return
+		}
+	}
+
+	// 校验自定义prompt（长度限制 + 占位符转义）
+	customPrompt, err := SanitizeCustomPrompt(req.CustomPrompt, s.maxCustomPromptLength())
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err)
+		return
+	}
+	req.CustomPrompt = customPrompt
 
 	// 获取用户角色
 	user, err := s.database.GetUserByID(userID)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "用户不存在"})
+		respondError(c, http.StatusUnauthorized, ErrCodeUserNotFound)
 		return
 	}
 
@@ -924,14 +1564,14 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 	// 获取交易员信息
 	existingTrader, err := s.database.GetTraderByID(traderID)
 	if err != nil || existingTrader == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
+		respondError(c, http.StatusNotFound, ErrCodeTraderNotFound)
 		return
 	}
 
 	// 权限检查：如果不是admin，验证交易员是否属于当前用户
 	if role != "admin" {
 		if existingTrader.OwnerUserID != userID {
-			c.JSON(http.StatusForbidden, gin.H{"error": "只能修改自己的交易员"})
+			respondError(c, http.StatusForbidden, ErrCodeTraderNotOwned)
 			return
 		}
 	}
@@ -942,6 +1582,313 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 		isCrossMargin = *req.IsCrossMargin
 	}
 
+	// 开仓止损保护（未传则保持原值）
+	requireStopLoss := existingTrader.RequireStopLoss
+	if req.RequireStopLoss != nil {
+		requireStopLoss = *req.RequireStopLoss
+	}
+	defaultStopLossPct := existingTrader.DefaultStopLossPct
+	if req.DefaultStopLossPct != nil {
+		defaultStopLossPct = *req.DefaultStopLossPct
+	}
+
+	// 交易选项（未传则保持原值）
+	excludeHeldFromCandidates := existingTrader.ExcludeHeldFromCandidates
+	if req.ExcludeHeldFromCandidates != nil {
+		excludeHeldFromCandidates = *req.ExcludeHeldFromCandidates
+	}
+	analysisOnly := existingTrader.AnalysisOnly
+	if req.AnalysisOnly != nil {
+		analysisOnly = *req.AnalysisOnly
+	}
+	warmupMinutes := existingTrader.WarmupMinutes
+	if req.WarmupMinutes != nil {
+		warmupMinutes = *req.WarmupMinutes
+	}
+	skipCycleIfBusy := existingTrader.SkipCycleIfBusy
+	if req.SkipCycleIfBusy != nil {
+		skipCycleIfBusy = *req.SkipCycleIfBusy
+	}
+	maxPositionAgeHours := existingTrader.MaxPositionAgeHours
+	if req.MaxPositionAgeHours != nil {
+		maxPositionAgeHours = *req.MaxPositionAgeHours
+	}
+	allowPyramiding := existingTrader.AllowPyramiding
+	if req.AllowPyramiding != nil {
+		allowPyramiding = *req.AllowPyramiding
+	}
+	maxAddsPerPosition := existingTrader.MaxAddsPerPosition
+	if req.MaxAddsPerPosition != nil {
+		maxAddsPerPosition = *req.MaxAddsPerPosition
+	}
+	enforceDailyLossStop := existingTrader.EnforceDailyLossStop
+	if req.EnforceDailyLossStop != nil {
+		enforceDailyLossStop = *req.EnforceDailyLossStop
+	}
+	allowFlip := existingTrader.AllowFlip
+	if req.AllowFlip != nil {
+		allowFlip = *req.AllowFlip
+	}
+	minConfidence := existingTrader.MinConfidence
+	if req.MinConfidence != nil {
+		minConfidence = *req.MinConfidence
+	}
+	signalBasePositionPct := existingTrader.SignalBasePositionPct
+	if req.SignalBasePositionPct != nil {
+		signalBasePositionPct = *req.SignalBasePositionPct
+	}
+	signalDefaultAddPct := existingTrader.SignalDefaultAddPct
+	if req.SignalDefaultAddPct != nil {
+		signalDefaultAddPct = *req.SignalDefaultAddPct
+	}
+	if !trader.ValidSignalSizing(trader.EffectiveSignalSizing(signalBasePositionPct, signalDefaultAddPct)) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidSignalSizing)
+		return
+	}
+	equityTakeProfit := existingTrader.EquityTakeProfit
+	if req.EquityTakeProfit != nil {
+		equityTakeProfit = *req.EquityTakeProfit
+	}
+	equityStopLoss := existingTrader.EquityStopLoss
+	if req.EquityStopLoss != nil {
+		equityStopLoss = *req.EquityStopLoss
+	}
+	equityTakeProfitPct := existingTrader.EquityTakeProfitPct
+	if req.EquityTakeProfitPct != nil {
+		equityTakeProfitPct = *req.EquityTakeProfitPct
+	}
+	equityStopLossPct := existingTrader.EquityStopLossPct
+	if req.EquityStopLossPct != nil {
+		equityStopLossPct = *req.EquityStopLossPct
+	}
+	if !trader.ValidEquityBracket(equityTakeProfit, equityStopLoss, equityTakeProfitPct, equityStopLossPct) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidEquityBracket)
+		return
+	}
+	autoReprotect := existingTrader.AutoReprotect
+	if req.AutoReprotect != nil {
+		autoReprotect = *req.AutoReprotect
+	}
+	publicDisplayName := existingTrader.PublicDisplayName
+	if req.PublicDisplayName != nil {
+		publicDisplayName = resolvePublicDisplayName(*req.PublicDisplayName, traderID)
+	}
+	publicVisibility := existingTrader.PublicVisibility
+	if req.PublicVisibility != nil {
+		publicVisibility = *req.PublicVisibility
+	}
+	tradingSchedule, _ := trader.ParseTradingSchedule(existingTrader.TradingSchedule)
+	if req.TradingSchedule != nil {
+		if err := trader.ValidateTradingSchedule(*req.TradingSchedule); err != nil {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidTradingSchedule, err)
+			return
+		}
+		tradingSchedule = *req.TradingSchedule
+	}
+	backupExchangeID := existingTrader.BackupExchangeID
+	if req.BackupExchangeID != nil {
+		backupExchangeID = *req.BackupExchangeID
+	}
+	if backupExchangeID != "" {
+		exchanges, err := s.database.GetExchanges(existingTrader.UserID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrCodeExchangeConfigFailed, err)
+			return
+		}
+		if err := validateBackupExchange(exchanges, req.ExchangeID, backupExchangeID); err != nil {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidBackupExchange, err)
+			return
+		}
+	}
+	includeOrderBookDepth := existingTrader.IncludeOrderBookDepth
+	if req.IncludeOrderBookDepth != nil {
+		includeOrderBookDepth = *req.IncludeOrderBookDepth
+	}
+	skipIfBTCMovePct := existingTrader.SkipIfBTCMovePct
+	if req.SkipIfBTCMovePct != nil {
+		skipIfBTCMovePct = *req.SkipIfBTCMovePct
+	}
+	skipIfFundingAbove := existingTrader.SkipIfFundingAbove
+	if req.SkipIfFundingAbove != nil {
+		skipIfFundingAbove = *req.SkipIfFundingAbove
+	}
+	maxOpenOrders := existingTrader.MaxOpenOrders
+	if req.MaxOpenOrders != nil {
+		maxOpenOrders = *req.MaxOpenOrders
+	}
+	breakevenAtProfitPct := existingTrader.BreakevenAtProfitPct
+	if req.BreakevenAtProfitPct != nil {
+		breakevenAtProfitPct = *req.BreakevenAtProfitPct
+	}
+	trailStopAfterProfitPct := existingTrader.TrailStopAfterProfitPct
+	if req.TrailStopAfterProfitPct != nil {
+		trailStopAfterProfitPct = *req.TrailStopAfterProfitPct
+	}
+	trailLockFraction := existingTrader.TrailLockFraction
+	if req.TrailLockFraction != nil {
+		trailLockFraction = *req.TrailLockFraction
+	}
+	maxActionsPerCycle := existingTrader.MaxActionsPerCycle
+	if req.MaxActionsPerCycle != nil {
+		maxActionsPerCycle = *req.MaxActionsPerCycle
+	}
+	requireFirstTradeApproval := existingTrader.RequireFirstTradeApproval
+	if req.RequireFirstTradeApproval != nil {
+		requireFirstTradeApproval = *req.RequireFirstTradeApproval
+	}
+	minSecondsBetweenAICalls := existingTrader.MinSecondsBetweenAICalls
+	if req.MinSecondsBetweenAICalls != nil {
+		minSecondsBetweenAICalls = *req.MinSecondsBetweenAICalls
+	}
+	maxPerSymbolExposurePct := existingTrader.MaxPerSymbolExposurePct
+	if req.MaxPerSymbolExposurePct != nil {
+		maxPerSymbolExposurePct = *req.MaxPerSymbolExposurePct
+	}
+	includeRecentTrades := existingTrader.IncludeRecentTrades
+	if req.IncludeRecentTrades != nil {
+		includeRecentTrades = *req.IncludeRecentTrades
+	}
+	recentTradesCount := existingTrader.RecentTradesCount
+	if req.RecentTradesCount != nil {
+		recentTradesCount = *req.RecentTradesCount
+	}
+	sizingBase := existingTrader.SizingBase
+	if req.SizingBase != nil && *req.SizingBase != "" {
+		sizingBase = *req.SizingBase
+	}
+	onAIFailure := existingTrader.OnAIFailure
+	if req.OnAIFailure != nil && *req.OnAIFailure != "" {
+		onAIFailure = *req.OnAIFailure
+	}
+	baselineResetPolicy := existingTrader.BaselineResetPolicy
+	if req.BaselineResetPolicy != nil && *req.BaselineResetPolicy != "" {
+		baselineResetPolicy = *req.BaselineResetPolicy
+	}
+	enforceMaxDrawdownStop := existingTrader.EnforceMaxDrawdownStop
+	if req.EnforceMaxDrawdownStop != nil {
+		enforceMaxDrawdownStop = *req.EnforceMaxDrawdownStop
+	}
+	maxDrawdownStopPct := existingTrader.MaxDrawdownStopPct
+	if req.MaxDrawdownStopPct != nil {
+		maxDrawdownStopPct = *req.MaxDrawdownStopPct
+	}
+	drawdownStopFlatten := existingTrader.DrawdownStopFlatten
+	if req.DrawdownStopFlatten != nil {
+		drawdownStopFlatten = *req.DrawdownStopFlatten
+	}
+	stopApproachAlertPct := existingTrader.StopApproachAlertPct
+	if req.StopApproachAlertPct != nil {
+		stopApproachAlertPct = *req.StopApproachAlertPct
+	}
+	minHoldingMinutes := existingTrader.MinHoldingMinutes
+	if req.MinHoldingMinutes != nil {
+		minHoldingMinutes = *req.MinHoldingMinutes
+	}
+	strategySources := existingTrader.StrategySources
+	if req.StrategySources != nil {
+		strategySources = strings.Join(signal.ParseSources(*req.StrategySources), ",")
+	}
+	contextIndicators := trader.ParseIndicatorList(existingTrader.ContextIndicators)
+	if req.ContextIndicators != nil {
+		contextIndicators = trader.ParseIndicatorList(*req.ContextIndicators)
+	}
+	contextTimeframes := trader.ParseIndicatorList(existingTrader.ContextTimeframes)
+	if req.ContextTimeframes != nil {
+		contextTimeframes = trader.ParseIndicatorList(*req.ContextTimeframes)
+	}
+	tp1ClosePct := existingTrader.TP1ClosePct
+	if req.TP1ClosePct != nil {
+		tp1ClosePct = *req.TP1ClosePct
+	}
+	tp1BreakevenStop := existingTrader.TP1BreakevenStop
+	if req.TP1BreakevenStop != nil {
+		tp1BreakevenStop = *req.TP1BreakevenStop
+	}
+	rebalanceTargets := trader.ParseRebalanceTargets(existingTrader.RebalanceTargets)
+	if req.RebalanceTargets != nil {
+		rebalanceTargets = trader.NormalizeRebalanceTargets(*req.RebalanceTargets)
+	}
+	rebalanceTargetsJSON := trader.FormatRebalanceTargets(rebalanceTargets)
+	rebalanceTolerancePct := existingTrader.RebalanceTolerancePct
+	if req.RebalanceTolerancePct != nil {
+		rebalanceTolerancePct = *req.RebalanceTolerancePct
+	}
+	rebalanceIntervalMinutes := existingTrader.RebalanceIntervalMinutes
+	if req.RebalanceIntervalMinutes != nil {
+		rebalanceIntervalMinutes = *req.RebalanceIntervalMinutes
+	}
+	if req.RebalanceTargets != nil || req.RebalanceTolerancePct != nil || req.RebalanceIntervalMinutes != nil {
+		// 与已保存的配置合并后整体校验（权重合计、容忍带、间隔）
+		if err := trader.ValidateRebalanceConfig(rebalanceTargets, rebalanceTolerancePct, rebalanceIntervalMinutes); err != nil {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidRebalance, err)
+			return
+		}
+	}
+	useOCO := existingTrader.UseOCO
+	if req.UseOCO != nil {
+		useOCO = *req.UseOCO
+	}
+	aiAnomalyIdleCycles := existingTrader.AIAnomalyIdleCycles
+	if req.AIAnomalyIdleCycles != nil {
+		aiAnomalyIdleCycles = *req.AIAnomalyIdleCycles
+	}
+	aiAnomalyRepeatCycles := existingTrader.AIAnomalyRepeatCycles
+	if req.AIAnomalyRepeatCycles != nil {
+		aiAnomalyRepeatCycles = *req.AIAnomalyRepeatCycles
+	}
+	aiAnomalyPause := existingTrader.AIAnomalyPause
+	if req.AIAnomalyPause != nil {
+		aiAnomalyPause = *req.AIAnomalyPause
+	}
+	if err := trader.ValidateAIAnomalyConfig(aiAnomalyIdleCycles, aiAnomalyRepeatCycles); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidAIAnomaly, err)
+		return
+	}
+	protectiveDefaults := trader.ParseProtectiveDefaults(existingTrader.ProtectiveDefaults)
+	if req.ProtectiveDefaults != nil {
+		if err := trader.ValidateProtectiveDefaults(*req.ProtectiveDefaults); err != nil {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidSLTPDefaults, err)
+			return
+		}
+		protectiveDefaults = trader.NormalizeProtectiveDefaults(*req.ProtectiveDefaults)
+	}
+	protectiveDefaultsJSON := trader.FormatProtectiveDefaults(protectiveDefaults)
+	positionAuditMinutes := existingTrader.PositionAuditMinutes
+	if req.PositionAuditMinutes != nil {
+		if err := trader.ValidatePositionAuditInterval(*req.PositionAuditMinutes); err != nil {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidAuditInterval, err)
+			return
+		}
+		positionAuditMinutes = *req.PositionAuditMinutes
+	}
+	minAvailableBalance := existingTrader.MinAvailableBalance
+	if req.MinAvailableBalance != nil {
+		if err := trader.ValidateMinAvailableBalance(*req.MinAvailableBalance); err != nil {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidMinAvailable, err)
+			return
+		}
+		minAvailableBalance = *req.MinAvailableBalance
+	}
+	candidateSymbols := existingTrader.CandidateSymbols
+	if req.CandidateSymbols != nil {
+		candidateSymbols = *req.CandidateSymbols
+	}
+	candidateCoins, allowedSymbols := trader.ParseSymbolList(candidateSymbols), trader.ParseSymbolList(req.TradingSymbols)
+	aiSampling := mcp.SamplingParams{Temperature: existingTrader.AITemperature, TopP: existingTrader.AITopP, MaxTokens: existingTrader.AIMaxTokens}
+	if req.AITemperature != nil {
+		aiSampling.Temperature = req.AITemperature
+	}
+	if req.AITopP != nil {
+		aiSampling.TopP = req.AITopP
+	}
+	if req.AIMaxTokens != nil {
+		aiSampling.MaxTokens = *req.AIMaxTokens
+	}
+	if err := mcp.ValidateSamplingParams(s.aiModelProvider(userID, req.AIModelID), aiSampling); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidAISampling, err)
+		return
+	}
+
 	// 设置杠杆默认值
 	btcEthLeverage := req.BTCETHLeverage
 	altcoinLeverage := req.AltcoinLeverage
@@ -978,20 +1925,92 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 		BTCETHLeverage:       btcEthLeverage,
 		AltcoinLeverage:      altcoinLeverage,
 		TradingSymbols:       req.TradingSymbols,
+		CandidateSymbols:     candidateSymbols,
 		CustomPrompt:         req.CustomPrompt,
 		OverrideBasePrompt:   req.OverrideBasePrompt,
 		SystemPromptTemplate: systemPromptTemplate, // 🔑 允许更新提示词模板
 		IsCrossMargin:        isCrossMargin,
 		ScanIntervalMinutes:  scanIntervalMinutes,
 		IsRunning:            existingTrader.IsRunning, // 保持原值
+		RequireStopLoss:      requireStopLoss,
+		DefaultStopLossPct:   defaultStopLossPct,
+
+		// 交易选项
+		ExcludeHeldFromCandidates: excludeHeldFromCandidates,
+		AnalysisOnly:              analysisOnly,
+		WarmupMinutes:             warmupMinutes,
+		SkipCycleIfBusy:           skipCycleIfBusy,
+		MaxPositionAgeHours:       maxPositionAgeHours,
+		AllowPyramiding:           allowPyramiding,
+		MaxAddsPerPosition:        maxAddsPerPosition,
+		EnforceDailyLossStop:      enforceDailyLossStop,
+		AllowFlip:                 allowFlip,
+		MinConfidence:             minConfidence,
+		SignalBasePositionPct:     signalBasePositionPct,
+		SignalDefaultAddPct:       signalDefaultAddPct,
+		EquityTakeProfit:          equityTakeProfit,
+		EquityStopLoss:            equityStopLoss,
+		EquityTakeProfitPct:       equityTakeProfitPct,
+		EquityStopLossPct:         equityStopLossPct,
+		AutoReprotect:             autoReprotect,
+		PublicDisplayName:         publicDisplayName,
+		PublicVisibility:          publicVisibility,
+		BackupExchangeID:          backupExchangeID,
+		TradingSchedule:           trader.FormatTradingSchedule(tradingSchedule),
+		IncludeOrderBookDepth:     includeOrderBookDepth,
+		SkipIfBTCMovePct:          skipIfBTCMovePct,
+		SkipIfFundingAbove:        skipIfFundingAbove,
+		MaxOpenOrders:             maxOpenOrders,
+		BreakevenAtProfitPct:      breakevenAtProfitPct,
+		TrailStopAfterProfitPct:   trailStopAfterProfitPct,
+		TrailLockFraction:         trailLockFraction,
+		MaxActionsPerCycle:        maxActionsPerCycle,
+		RequireFirstTradeApproval: requireFirstTradeApproval,
+		MinSecondsBetweenAICalls:  minSecondsBetweenAICalls,
+		MaxPerSymbolExposurePct:   maxPerSymbolExposurePct,
+		IncludeRecentTrades:       includeRecentTrades,
+		RecentTradesCount:         recentTradesCount,
+		SizingBase:                sizingBase,
+		AITemperature:             aiSampling.Temperature,
+		AITopP:                    aiSampling.TopP,
+		AIMaxTokens:               aiSampling.MaxTokens,
+		OnAIFailure:               onAIFailure,
+		BaselineResetPolicy:       baselineResetPolicy,
+		EnforceMaxDrawdownStop:    enforceMaxDrawdownStop,
+		MaxDrawdownStopPct:        maxDrawdownStopPct,
+		DrawdownStopFlatten:       drawdownStopFlatten,
+		StopApproachAlertPct:      stopApproachAlertPct,
+		MinHoldingMinutes:         minHoldingMinutes,
+		StrategySources:           strategySources,
+		ContextIndicators:         strings.Join(contextIndicators, ","),
+		ContextTimeframes:         strings.Join(contextTimeframes, ","),
+		TP1ClosePct:               tp1ClosePct,
+		TP1BreakevenStop:          tp1BreakevenStop,
+		RebalanceTargets:          rebalanceTargetsJSON,
+		RebalanceTolerancePct:     rebalanceTolerancePct,
+		RebalanceIntervalMinutes:  rebalanceIntervalMinutes,
+		UseOCO:                    useOCO,
+		AIAnomalyIdleCycles:       aiAnomalyIdleCycles,
+		AIAnomalyRepeatCycles:     aiAnomalyRepeatCycles,
+		AIAnomalyPause:            aiAnomalyPause,
+		ProtectiveDefaults:        protectiveDefaultsJSON,
+		PositionAuditMinutes:      positionAuditMinutes,
+		MinAvailableBalance:       minAvailableBalance,
 	}
 
 	// 更新数据库
 	err = s.database.UpdateTrader(trader)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新交易员失败: %v", err)})
+		respondError(c, http.StatusInternalServerError, ErrCodeUpdateTraderFailed, err)
 		return
 	}
+	// 从关闭切换为开启首笔交易审批时，重新要求审批下一笔开仓（未运行的交易员下次启动时生效）
+	if requireFirstTradeApproval && !existingTrader.RequireFirstTradeApproval {
+		if err := s.database.SaveTraderFirstTradeApproved(traderID, false); err != nil {
+			log.Printf("⚠️ 重置交易员 %s 首笔交易审批状态失败: %v", traderID, err)
+		}
+	}
+	s.candidates.Delete(traderID)
 
 	// 如果交易员正在运行，更新内存中的配置
 	if existingTrader.IsRunning {
@@ -1052,6 +2071,51 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 				runningTrader.SetOverrideBasePrompt(req.OverrideBasePrompt)
 				runningTrader.SetLeverageConfig(btcEthLeverage, altcoinLeverage)
 				runningTrader.SetCrossMarginMode(isCrossMargin)
+				runningTrader.SetStopLossGuard(requireStopLoss, defaultStopLossPct)
+				runningTrader.SetExcludeHeldFromCandidates(excludeHeldFromCandidates)
+				runningTrader.SetAnalysisOnly(analysisOnly)
+				runningTrader.SetWarmupMinutes(warmupMinutes)
+				runningTrader.SetSkipCycleIfBusy(skipCycleIfBusy)
+				runningTrader.SetMaxPositionAgeHours(maxPositionAgeHours)
+				runningTrader.SetPyramiding(allowPyramiding, maxAddsPerPosition)
+				runningTrader.SetEnforceDailyLossStop(enforceDailyLossStop)
+				runningTrader.SetAllowFlip(allowFlip)
+				runningTrader.SetMinConfidence(minConfidence)
+				runningTrader.SetSignalSizing(signalBasePositionPct, signalDefaultAddPct)
+				// 只有阈值实际变化时才更新（SetEquityBracket 会解除已触发的暂停）
+				if equityTakeProfit != existingTrader.EquityTakeProfit || equityStopLoss != existingTrader.EquityStopLoss ||
+					equityTakeProfitPct != existingTrader.EquityTakeProfitPct || equityStopLossPct != existingTrader.EquityStopLossPct {
+					runningTrader.SetEquityBracket(equityTakeProfit, equityStopLoss, equityTakeProfitPct, equityStopLossPct)
+				}
+				runningTrader.SetAutoReprotect(autoReprotect)
+				runningTrader.SetPublicProfile(publicDisplayName, publicVisibility)
+				runningTrader.SetTradingSchedule(tradingSchedule)
+				runningTrader.SetIncludeOrderBookDepth(includeOrderBookDepth)
+				runningTrader.SetMarketConditionGate(skipIfBTCMovePct, skipIfFundingAbove)
+				runningTrader.SetMaxOpenOrders(maxOpenOrders)
+				runningTrader.SetProfitProtection(breakevenAtProfitPct, trailStopAfterProfitPct, trailLockFraction)
+				runningTrader.SetMaxActionsPerCycle(maxActionsPerCycle)
+				runningTrader.SetRequireFirstTradeApproval(requireFirstTradeApproval)
+				runningTrader.SetMinSecondsBetweenAICalls(minSecondsBetweenAICalls)
+				runningTrader.SetMaxPerSymbolExposurePct(maxPerSymbolExposurePct)
+				runningTrader.SetRecentClosedTrades(includeRecentTrades, recentTradesCount)
+				runningTrader.SetSizingBase(sizingBase)
+				runningTrader.SetAISampling(aiSampling)
+				runningTrader.SetOnAIFailure(onAIFailure)
+				runningTrader.SetBaselineResetPolicy(baselineResetPolicy)
+				runningTrader.SetMaxDrawdownStop(enforceMaxDrawdownStop, maxDrawdownStopPct, drawdownStopFlatten)
+				runningTrader.SetStopApproachAlertPct(stopApproachAlertPct)
+				runningTrader.SetMinHoldingMinutes(minHoldingMinutes)
+				runningTrader.SetStrategySources(signal.ParseSources(strategySources))
+				runningTrader.SetContextIndicators(contextIndicators, contextTimeframes)
+				runningTrader.SetTP1ScaleOut(tp1ClosePct, tp1BreakevenStop)
+				runningTrader.SetRebalanceConfig(rebalanceTargets, rebalanceTolerancePct, rebalanceIntervalMinutes)
+				runningTrader.SetUseOCO(useOCO)
+				runningTrader.SetAIAnomalyConfig(aiAnomalyIdleCycles, aiAnomalyRepeatCycles, aiAnomalyPause)
+				runningTrader.SetProtectiveDefaults(protectiveDefaults)
+				runningTrader.SetPositionAuditInterval(positionAuditMinutes)
+				runningTrader.SetMinAvailableBalance(minAvailableBalance)
+				runningTrader.SetSymbolUniverse(candidateCoins, allowedSymbols)
 				log.Printf("✓ 已更新运行中交易员的系统提示词模板: %s → %s", existingTrader.SystemPromptTemplate, systemPromptTemplate)
 			}
 		}
@@ -1079,7 +2143,7 @@ func (s *Server) handleDeleteTrader(c *gin.Context) {
 	// 获取用户角色
 	user, err := s.database.GetUserByID(userID)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "用户不存在"})
+		respondError(c, http.StatusUnauthorized, ErrCodeUserNotFound)
 		return
 	}
 
@@ -1091,14 +2155,14 @@ func (s *Server) handleDeleteTrader(c *gin.Context) {
 	// 获取交易员信息
 	trader, err := s.database.GetTraderByID(traderID)
 	if err != nil || trader == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
+		respondError(c, http.StatusNotFound, ErrCodeTraderNotFound)
 		return
 	}
 
 	// 权限检查：如果不是admin，验证交易员是否属于当前用户
 	if role != "admin" {
 		if trader.OwnerUserID != userID {
-			c.JSON(http.StatusForbidden, gin.H{"error": "只能删除自己的交易员"})
+			respondError(c, http.StatusForbidden, ErrCodeTraderNotOwned)
 			return
 		}
 	}
@@ -1106,7 +2170,7 @@ func (s *Server) handleDeleteTrader(c *gin.Context) {
 	// 从数据库删除
 	err = s.database.DeleteTrader(userID, traderID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("删除交易员失败: %v", err)})
+		respondError(c, http.StatusInternalServerError, ErrCodeDeleteTraderFailed, err)
 		return
 	}
 
@@ -1171,6 +2235,12 @@ func (s *Server) handleStartTrader(c *gin.Context) {
 		}
 	}
 
+	// 已保存的凭证无法解密时提示用户重新输入，而不是启动后以空密钥反复失败
+	if fields := s.undecryptableCredentials(traderRecord.UserID, traderRecord.AIModelID, traderRecord.ExchangeID); len(fields) > 0 {
+		respondError(c, http.StatusConflict, ErrCodeCredentialUndecryptable, strings.Join(fields, ", "))
+		return
+	}
+
 	trader, err := s.traderManager.GetTrader(traderID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
@@ -1184,6 +2254,9 @@ func (s *Server) handleStartTrader(c *gin.Context) {
 		return
 	}
 
+	// 手动启动视为确认恢复交易，解除已触发的净值止盈止损暂停
+	trader.ResumeEquityBracket()
+
 	// 启动交易员
 	go func() {
 		log.Printf("▶️  启动交易员 %s (%s)", traderID, trader.GetName())
@@ -1302,6 +2375,14 @@ func (s *Server) handleUpdateTraderPrompt(c *gin.Context) {
 		return
 	}
 
+	// 校验自定义prompt（长度限制 + 占位符转义）
+	customPrompt, err := SanitizeCustomPrompt(req.CustomPrompt, s.maxCustomPromptLength())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.CustomPrompt = customPrompt
+
 	// 更新数据库
 	err = s.database.UpdateTraderCustomPrompt(userID, traderID, req.CustomPrompt, req.OverrideBasePrompt)
 	if err != nil {
@@ -1597,8 +2678,8 @@ func (s *Server) handleUpdateModelConfigs(c *gin.Context) {
 
 		plaintext, err := s.cryptoService.DecryptSensitiveData(&encryptedPayload)
 		if err != nil {
-			log.Printf("❌ 解密失败: %v", err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Decryption failed: %v", err)})
+			log.Printf("❌ 解密失败（密文长度=%d，kid=%q，数据密钥版本=v%d）: %v", len(encryptedPayload.Ciphertext), encryptedPayload.KID, s.cryptoService.DataKeyVersion(), err)
+			respondError(c, http.StatusBadRequest, ErrCodePayloadDecryptFailed)
 			return
 		}
 
@@ -1617,9 +2698,21 @@ func (s *Server) handleUpdateModelConfigs(c *gin.Context) {
 		return
 	}
 
+	// 先校验全部模型的自定义认证配置，避免部分模型已更新后才失败
+	for modelID, modelData := range req.Models {
+		if err := mcp.ValidateCustomAuth(modelData.CustomAuthScheme, modelData.CustomHeaders); err != nil {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidCustomAuth, modelID, err)
+			return
+		}
+	}
+
 	// 更新每个模型的配置
 	for modelID, modelData := range req.Models {
-		err := s.database.UpdateAIModel(userID, modelID, modelData.Enabled, modelData.APIKey, modelData.CustomAPIURL, modelData.CustomModelName)
+		authScheme := ""
+		if modelData.CustomAuthScheme != "" {
+			authScheme = mcp.NormalizeAuthScheme(modelData.CustomAuthScheme)
+		}
+		err := s.database.UpdateAIModel(userID, modelID, modelData.Enabled, modelData.APIKey, modelData.CustomAPIURL, modelData.CustomModelName, modelData.MaxPromptTokens, modelData.CustomHeaders, authScheme)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新模型 %s 失败: %v", modelID, err)})
 			return
@@ -1633,7 +2726,7 @@ func (s *Server) handleUpdateModelConfigs(c *gin.Context) {
 		// 这里不返回错误，因为模型配置已经成功更新到数据库
 	}
 
-	log.Printf("✓ AI模型配置已更新: %+v", req.Models)
+	log.Printf("✓ AI模型配置已更新: %d 个模型", len(req.Models))
 	c.JSON(http.StatusOK, gin.H{"message": "模型配置已更新"})
 }
 
@@ -1674,8 +2767,8 @@ func (s *Server) handleUpdateExchangeConfigs(c *gin.Context) {
 
 		plaintext, err := s.cryptoService.DecryptSensitiveData(&encryptedPayload)
 		if err != nil {
-			log.Printf("❌ 解密失败: %v", err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Decryption failed: %v", err)})
+			log.Printf("❌ 解密失败（密文长度=%d，kid=%q，数据密钥版本=v%d）: %v", len(encryptedPayload.Ciphertext), encryptedPayload.KID, s.cryptoService.DataKeyVersion(), err)
+			respondError(c, http.StatusBadRequest, ErrCodePayloadDecryptFailed)
 			return
 		}
 
@@ -1717,12 +2810,19 @@ func (s *Server) handleUpdateExchangeConfigs(c *gin.Context) {
 // handleGetUserSignalSource 获取用户信号源配置
 func (s *Server) handleGetUserSignalSource(c *gin.Context) {
 	userID := c.GetString("user_id")
+	// 附带多信号源列表，兼容旧版单一URL的返回字段
+	sources := []*config.UserSignalSourceItem{}
+	if items, err := s.database.GetUserSignalSourceItems(userID); err == nil && items != nil {
+		sources = items
+	}
+
 	source, err := s.database.GetUserSignalSource(userID)
 	if err != nil {
 		// 如果配置不存在，返回空配置而不是404错误
 		c.JSON(http.StatusOK, gin.H{
 			"coin_pool_url": "",
 			"oi_top_url":    "",
+			"sources":       sources,
 		})
 		return
 	}
@@ -1730,6 +2830,7 @@ func (s *Server) handleGetUserSignalSource(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"coin_pool_url": source.CoinPoolURL,
 		"oi_top_url":    source.OITopURL,
+		"sources":       sources,
 	})
 }
 
@@ -1910,20 +3011,30 @@ func contains(slice []string, item string) bool {
 	return false
 }
 
+// parseRebalanceTargets 目标权重在配置接口中以对象返回（handleGetTraderConfig 中局部变量 trader 遮蔽了包名）
+func parseRebalanceTargets(raw string) map[string]float64 {
+	return trader.ParseRebalanceTargets(raw)
+}
+
+// parseProtectiveDefaults 默认止损/止盈在配置接口中以对象返回（同上，避免局部变量 trader 遮蔽包名）
+func parseProtectiveDefaults(raw string) map[string]trader.ProtectiveDefault {
+	return trader.ParseProtectiveDefaults(raw)
+}
+
 // handleGetTraderConfig 获取交易员详细配置
 func (s *Server) handleGetTraderConfig(c *gin.Context) {
 	userID := c.GetString("user_id")
 	traderID := c.Param("id")
 
 	if traderID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "交易员ID不能为空"})
+		respondError(c, http.StatusBadRequest, ErrCodeTraderIDRequired)
 		return
 	}
 
 	// 获取用户角色
 	user, err := s.database.GetUserByID(userID)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "用户不存在"})
+		respondError(c, http.StatusUnauthorized, ErrCodeUserNotFound)
 		return
 	}
 
@@ -1935,7 +3046,7 @@ func (s *Server) handleGetTraderConfig(c *gin.Context) {
 	// 获取交易员信息
 	trader, err := s.database.GetTraderByID(traderID)
 	if err != nil || trader == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
+		respondError(c, http.StatusNotFound, ErrCodeTraderNotFound)
 		return
 	}
 
@@ -1975,13 +3086,13 @@ func (s *Server) handleGetTraderConfig(c *gin.Context) {
 	}
 
 	if !canAccess {
-		c.JSON(http.StatusForbidden, gin.H{"error": "无权访问该交易员"})
+		respondError(c, http.StatusForbidden, ErrCodeTraderAccessDenied)
 		return
 	}
 
 	traderConfig, _, _, err := s.database.GetTraderConfig(userID, traderID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("获取交易员配置失败: %v", err)})
+		respondError(c, http.StatusNotFound, ErrCodeGetTraderConfigFailed, err)
 		return
 	}
 
@@ -2008,12 +3119,77 @@ func (s *Server) handleGetTraderConfig(c *gin.Context) {
 		"btc_eth_leverage":       traderConfig.BTCETHLeverage,
 		"altcoin_leverage":       traderConfig.AltcoinLeverage,
 		"trading_symbols":        traderConfig.TradingSymbols,
+		"candidate_symbols":      traderConfig.CandidateSymbols,
 		"custom_prompt":          traderConfig.CustomPrompt,
 		"override_base_prompt":   traderConfig.OverrideBasePrompt,
 		"is_cross_margin":        traderConfig.IsCrossMargin,
 		"use_coin_pool":          traderConfig.UseCoinPool,
 		"use_oi_top":             traderConfig.UseOITop,
+		"require_stop_loss":      traderConfig.RequireStopLoss,
+		"default_stop_loss_pct":  traderConfig.DefaultStopLossPct,
 		"is_running":             isRunning,
+
+		// 交易选项
+		"exclude_held_from_candidates":  traderConfig.ExcludeHeldFromCandidates,
+		"analysis_only":                 traderConfig.AnalysisOnly,
+		"warmup_minutes":                traderConfig.WarmupMinutes,
+		"skip_cycle_if_busy":            traderConfig.SkipCycleIfBusy,
+		"max_position_age_hours":        traderConfig.MaxPositionAgeHours,
+		"allow_pyramiding":              traderConfig.AllowPyramiding,
+		"max_adds_per_position":         traderConfig.MaxAddsPerPosition,
+		"enforce_daily_loss_stop":       traderConfig.EnforceDailyLossStop,
+		"allow_flip":                    traderConfig.AllowFlip,
+		"min_confidence":                traderConfig.MinConfidence,
+		"signal_base_position_pct":      traderConfig.SignalBasePositionPct,
+		"signal_default_add_pct":        traderConfig.SignalDefaultAddPct,
+		"equity_take_profit":            traderConfig.EquityTakeProfit,
+		"equity_stop_loss":              traderConfig.EquityStopLoss,
+		"equity_take_profit_pct":        traderConfig.EquityTakeProfitPct,
+		"equity_stop_loss_pct":          traderConfig.EquityStopLossPct,
+		"auto_reprotect":                traderConfig.AutoReprotect,
+		"public_display_name":           traderConfig.PublicDisplayName,
+		"public_visibility":             traderConfig.PublicVisibility,
+		"backup_exchange_id":            traderConfig.BackupExchangeID,
+		"trading_schedule":              tradingScheduleResponse(traderConfig.TradingSchedule),
+		"include_orderbook_depth":       traderConfig.IncludeOrderBookDepth,
+		"skip_if_btc_move_pct":          traderConfig.SkipIfBTCMovePct,
+		"skip_if_funding_above":         traderConfig.SkipIfFundingAbove,
+		"max_open_orders":               traderConfig.MaxOpenOrders,
+		"breakeven_at_profit_pct":       traderConfig.BreakevenAtProfitPct,
+		"trail_stop_after_profit_pct":   traderConfig.TrailStopAfterProfitPct,
+		"trail_lock_fraction":           traderConfig.TrailLockFraction,
+		"max_actions_per_cycle":         traderConfig.MaxActionsPerCycle,
+		"approval_required_first_trade": traderConfig.RequireFirstTradeApproval,
+		"min_seconds_between_ai_calls":  traderConfig.MinSecondsBetweenAICalls,
+		"max_per_symbol_exposure_pct":   traderConfig.MaxPerSymbolExposurePct,
+		"include_recent_trades":         traderConfig.IncludeRecentTrades,
+		"recent_trades_count":           traderConfig.RecentTradesCount,
+		"sizing_base":                   traderConfig.SizingBase,
+		"ai_temperature":                traderConfig.AITemperature,
+		"ai_top_p":                      traderConfig.AITopP,
+		"ai_max_tokens":                 traderConfig.AIMaxTokens,
+		"on_ai_failure":                 traderConfig.OnAIFailure,
+		"baseline_reset_policy":         traderConfig.BaselineResetPolicy,
+		"enforce_max_drawdown_stop":     traderConfig.EnforceMaxDrawdownStop,
+		"max_drawdown_stop_pct":         traderConfig.MaxDrawdownStopPct,
+		"drawdown_stop_flatten":         traderConfig.DrawdownStopFlatten,
+		"stop_approach_alert_pct":       traderConfig.StopApproachAlertPct,
+		"min_holding_minutes":           traderConfig.MinHoldingMinutes,
+		"strategy_sources":              traderConfig.StrategySources,
+		"context_indicators":            traderConfig.ContextIndicators,
+		"context_timeframes":            traderConfig.ContextTimeframes,
+		"tp1_close_pct":                 traderConfig.TP1ClosePct,
+		"tp1_breakeven_stop":            traderConfig.TP1BreakevenStop,
+		"rebalance_targets":             parseRebalanceTargets(traderConfig.RebalanceTargets),
+		"rebalance_tolerance_pct":       traderConfig.RebalanceTolerancePct,
+		"rebalance_interval_minutes":    traderConfig.RebalanceIntervalMinutes,
+		"use_oco":                       traderConfig.UseOCO,
+		"ai_anomaly_idle_cycles":        traderConfig.AIAnomalyIdleCycles,
+		"ai_anomaly_repeat_cycles":      traderConfig.AIAnomalyRepeatCycles,
+		"ai_anomaly_pause":              traderConfig.AIAnomalyPause,
+		"protective_defaults":           parseProtectiveDefaults(traderConfig.ProtectiveDefaults),
+		"position_audit_minutes":        traderConfig.PositionAuditMinutes,
+		"min_available_balance":         traderConfig.MinAvailableBalance,
 	}
 
 	c.JSON(http.StatusOK, result)
@@ -2066,6 +3242,17 @@ func (s *Server) handleUserAccount(c *gin.Context) {
 		response["categories"] = []string{}
 	}
 
+	// 交易员数量与上限（0表示不限制），用于前端控制创建按钮
+	traderCount, _ := s.database.CountTradersByOwner(userID)
+	maxTraders := s.database.GetMaxTradersPerUser()
+	if user.Role == "admin" {
+		maxTraders = 0
+	}
+	response["trader_count"] = traderCount
+	response["max_traders"] = maxTraders
+	response["can_create_trader"] = maxTraders == 0 || traderCount < maxTraders
+	response["display_currency"] = normalizeDisplayCurrency(s.database.GetUserDisplayCurrency(userID))
+
 	c.JSON(http.StatusOK, response)
 }
 
@@ -2099,6 +3286,9 @@ func (s *Server) handleAccount(c *gin.Context) {
 		account["available_balance"],
 		account["total_pnl"],
 		account["total_pnl_pct"])
+	if currency, rate, ok := s.userDisplayRate(c.GetString("user_id")); ok {
+		account["display"] = convertAccountForDisplay(account, currency, rate)
+	}
 	c.JSON(http.StatusOK, account)
 }
 
@@ -2141,21 +3331,24 @@ func (s *Server) handleGetOrders(c *gin.Context) {
 		return
 	}
 
-	trader := autoTrader.GetTrader() // 获取内部的交易器实例
+	exchangeTrader := autoTrader.GetTrader() // 获取内部的交易器实例
 
 	// 获取交易对（如果有指定）
 	symbol := c.Query("symbol") // 可选：只查询某一个交易对的委托
 
-	orders, err := trader.GetOpenOrders(symbol)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取委托单失败: %v", err)})
-			return
-		}
+	orders, err := exchangeTrader.GetOpenOrders(symbol)
+	if errors.Is(err, trader.ErrOpenOrdersNotSupported) {
+		orders, err = []map[string]interface{}{}, nil
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取委托单失败: %v", err)})
+		return
+	}
 
 	// 补充 symbol 字段，并注入 leverage 和 计算 position_value
 	// 1. 获取持仓信息以拿到杠杆倍数
 	var leverageMap = make(map[string]float64)
-	if positions, err := trader.GetPositions(); err == nil {
+	if positions, err := exchangeTrader.GetPositions(); err == nil {
 		for _, pos := range positions {
 			if sym, ok := pos["symbol"].(string); ok {
 				if lev, ok := pos["leverage"].(float64); ok {
@@ -2165,6 +3358,13 @@ func (s *Server) handleGetOrders(c *gin.Context) {
 		}
 	}
 
+	enrichOrders(autoTrader, orders, symbol, leverageMap)
+
+	c.JSON(http.StatusOK, gin.H{"orders": orders})
+}
+
+// enrichOrders 补充委托单的 symbol、leverage（持仓杠杆优先，其次为配置杠杆）和名义价值 position_value
+func enrichOrders(autoTrader *trader.AutoTrader, orders []map[string]interface{}, symbol string, leverageMap map[string]float64) {
 	// 获取用户配置的杠杆，作为 fallback
 	btcEthLev := 5.0
 	altLev := 5.0
@@ -2217,8 +3417,6 @@ func (s *Server) handleGetOrders(c *gin.Context) {
 			order["position_value"] = price * qty
 		}
 	}
-
-	c.JSON(http.StatusOK, gin.H{"orders": orders})
 }
 
 // handleClosePosition 平仓操作
@@ -2230,15 +3428,24 @@ func (s *Server) handleClosePosition(c *gin.Context) {
 	}
 
 	var req struct {
-		Symbol   string  `json:"symbol" binding:"required"`   // 交易对，如 BTCUSDT
-		Side     string  `json:"side" binding:"required"`     // long 或 short
-		Quantity float64 `json:"quantity" binding:"required"` // 平仓数量
+		Symbol      string  `json:"symbol" binding:"required"` // 交易对，如 BTCUSDT
+		Side        string  `json:"side" binding:"required"`   // long 或 short
+		Quantity    float64 `json:"quantity"`                  // 平仓数量
+		NotionalUSD float64 `json:"notional_usd"`              // 平仓金额（USDT），按当前价格换算数量，与 quantity 二选一
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("参数错误: %v", err)})
 		return
 	}
+	if (req.Quantity > 0) == (req.NotionalUSD > 0) || req.Quantity < 0 || req.NotionalUSD < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "quantity 和 notional_usd 必须且只能提供一个正数"})
+		return
+	}
+	if req.Side != "long" && req.Side != "short" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "side 必须是 long 或 short"})
+		return
+	}
 
 	trader, err := s.traderManager.GetTrader(traderID)
 	if err != nil {
@@ -2246,15 +3453,38 @@ func (s *Server) handleClosePosition(c *gin.Context) {
 		return
 	}
 
+	// 按金额平仓：根据当前价格换算数量，超过持仓数量时全部平仓
+	quantity := req.Quantity
+	if req.NotionalUSD > 0 {
+		positions, err := trader.GetPositions()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		positionQty := 0.0
+		for _, pos := range positions {
+			if pos["symbol"] == req.Symbol && pos["side"] == req.Side {
+				positionQty, _ = pos["quantity"].(float64)
+				break
+			}
+		}
+		if positionQty <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("未找到 %s %s 持仓", req.Symbol, req.Side)})
+			return
+		}
+		quantity, err = closeQuantityForNotional(req.Symbol, req.NotionalUSD, positionQty)
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	// 根据持仓方向调用对应的平仓方法
 	var result map[string]interface{}
 	if req.Side == "long" {
-		result, err = trader.CloseLong(req.Symbol, req.Quantity)
-	} else if req.Side == "short" {
-		result, err = trader.CloseShort(req.Symbol, req.Quantity)
+		result, err = trader.CloseLong(req.Symbol, quantity)
 	} else {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "side 必须是 long 或 short"})
-		return
+		result, err = trader.CloseShort(req.Symbol, quantity)
 	}
 
 	if err != nil {
@@ -2276,8 +3506,9 @@ func (s *Server) handleClosePosition(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "平仓成功",
-		"result":  result,
+		"message":  "平仓成功",
+		"quantity": quantity,
+		"result":   result,
 	})
 }
 
@@ -2295,6 +3526,7 @@ func (s *Server) handleDecisions(c *gin.Context) {
 		c.JSON(http.StatusOK, []*config.StrategyDecisionHistory{})
 		return
 	}
+	s.backfillDecisionModel(traderID, records)
 
 	c.JSON(http.StatusOK, records)
 }
@@ -2318,10 +3550,28 @@ func (s *Server) handleLatestDecisions(c *gin.Context) {
 	} else {
 		log.Printf("🔍 查询决策 [trader_id=%s]: 找到 %d 条记录", traderID, len(records))
 	}
+	s.backfillDecisionModel(traderID, records)
 
 	c.JSON(http.StatusOK, records)
 }
 
+// backfillDecisionModel 旧记录未保存实际响应的模型时，用交易员当前配置的模型补齐
+// 仅补齐有AI原始响应的记录（确定性补单、执行事件等不经过AI的记录保持为空）
+func (s *Server) backfillDecisionModel(traderID string, records []*config.StrategyDecisionHistory) {
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		return
+	}
+	provider, model := at.GetAIModel(), at.GetAIModelName()
+	for _, r := range records {
+		if r.AIProvider != "" || r.RawAIResponse == "" {
+			continue
+		}
+		r.AIProvider = provider
+		r.AIModelName = model
+	}
+}
+
 // handleStatistics 统计信息
 func (s *Server) handleStatistics(c *gin.Context) {
 	_, traderID, err := s.getTraderFromQuery(c)
@@ -2377,6 +3627,13 @@ func (s *Server) handleEquityHistory(c *gin.Context) {
 		return
 	}
 
+	// resolution=raw/hourly/daily：长周期曲线在服务端降采样，减少返回数据量
+	bucket, err := parseEquityResolution(c.Query("resolution"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	trader, err := s.traderManager.GetTrader(traderID)
 	if err != nil {
 		log.Printf("❌ handleEquityHistory: 获取交易员失败 - trader_id=%s, error=%v", traderID, err)
@@ -2395,6 +3652,7 @@ func (s *Server) handleEquityHistory(c *gin.Context) {
 	}
 
 	log.Printf("📊 handleEquityHistory: 找到 %d 条历史记录 - trader_id=%s", len(records), traderID)
+	records = downsampleEquityRecords(records, bucket)
 
 	// 构建收益率历史数据点（字段名与前端期望一致）
 	type EquityPoint struct {
@@ -2408,7 +3666,13 @@ func (s *Server) handleEquityHistory(c *gin.Context) {
 		PositionCount    int     `json:"position_count"`    // 持仓数量
 		MarginUsedPct    float64 `json:"margin_used_pct"`   // 保证金使用率
 		CycleNumber      int     `json:"cycle_number"`
+
+		// 按用户展示币种换算的数值（按当前汇率换算，展示币种为USDT时不返回）
+		DisplayCurrency string  `json:"display_currency,omitempty"`
+		DisplayEquity   float64 `json:"display_equity,omitempty"`
+		DisplayPnL      float64 `json:"display_pnl,omitempty"`
 	}
+	displayCurrency, displayRate, hasDisplay := s.userDisplayRate(c.GetString("user_id"))
 
 	// 从AutoTrader获取初始余额（用于计算盈亏百分比）
 	initialBalance := 0.0
@@ -2465,6 +3729,12 @@ func (s *Server) handleEquityHistory(c *gin.Context) {
 			MarginUsedPct:    record.AccountState.MarginUsedPct,
 			CycleNumber:      record.CycleNumber,
 		})
+		if hasDisplay {
+			point := &history[len(history)-1]
+			point.DisplayCurrency = displayCurrency
+			point.DisplayEquity = totalEquity * displayRate
+			point.DisplayPnL = totalPnL * displayRate
+		}
 	}
 
 	log.Printf("✅ handleEquityHistory: 返回 %d 条历史数据点 - trader_id=%s", len(history), traderID)
@@ -2503,7 +3773,7 @@ func (s *Server) authMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "缺少Authorization头"})
+			respondError(c, http.StatusUnauthorized, ErrCodeMissingAuthHeader)
 			c.Abort()
 			return
 		}
@@ -2511,7 +3781,7 @@ func (s *Server) authMiddleware() gin.HandlerFunc {
 		// 检查Bearer token格式
 		tokenParts := strings.Split(authHeader, " ")
 		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Authorization格式"})
+			respondError(c, http.StatusUnauthorized, ErrCodeInvalidAuthFormat)
 			c.Abort()
 			return
 		}
@@ -2520,7 +3790,7 @@ func (s *Server) authMiddleware() gin.HandlerFunc {
 
 		// 黑名单检查
 		if auth.IsTokenBlacklisted(tokenString) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "token已失效，请重新登录"})
+			respondError(c, http.StatusUnauthorized, ErrCodeTokenRevoked)
 			c.Abort()
 			return
 		}
@@ -2528,7 +3798,7 @@ func (s *Server) authMiddleware() gin.HandlerFunc {
 		// 验证JWT token
 		claims, err := auth.ValidateJWT(tokenString)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的token: " + err.Error()})
+			respondError(c, http.StatusUnauthorized, ErrCodeInvalidToken)
 			c.Abort()
 			return
 		}
@@ -2543,7 +3813,7 @@ func (s *Server) authMiddleware() gin.HandlerFunc {
 // handleAdminLogin 管理员登录（密码仅来自环境变量）
 func (s *Server) handleAdminLogin(c *gin.Context) {
 	if !auth.IsAdminMode() {
-		c.JSON(http.StatusForbidden, gin.H{"error": "仅管理员模式可用"})
+		respondError(c, http.StatusForbidden, ErrCodeAdminModeOnly)
 		return
 	}
 
@@ -2554,17 +3824,17 @@ func (s *Server) handleAdminLogin(c *gin.Context) {
 		Password string `json:"password"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Password) == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少密码"})
+		respondError(c, http.StatusBadRequest, ErrCodeMissingPassword)
 		return
 	}
 	if !auth.CheckAdminPassword(req.Password) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "密码错误"})
+		respondError(c, http.StatusUnauthorized, ErrCodeWrongPassword)
 		return
 	}
 
 	token, err := auth.GenerateJWT("admin", "admin@localhost")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成token失败"})
+		respondError(c, http.StatusInternalServerError, ErrCodeTokenGenerateFailed)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"token": token, "user_id": "admin", "email": "admin@localhost"})
@@ -2574,18 +3844,18 @@ func (s *Server) handleAdminLogin(c *gin.Context) {
 func (s *Server) handleLogout(c *gin.Context) {
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "缺少Authorization头"})
+		respondError(c, http.StatusUnauthorized, ErrCodeMissingAuthHeader)
 		return
 	}
 	parts := strings.Split(authHeader, " ")
 	if len(parts) != 2 || parts[0] != "Bearer" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Authorization格式"})
+		respondError(c, http.StatusUnauthorized, ErrCodeInvalidAuthFormat)
 		return
 	}
 	tokenString := parts[1]
 	claims, err := auth.ValidateJWT(tokenString)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的token"})
+		respondError(c, http.StatusUnauthorized, ErrCodeInvalidToken)
 		return
 	}
 	var exp time.Time
@@ -2602,14 +3872,14 @@ func (s *Server) handleLogout(c *gin.Context) {
 func (s *Server) handleRegister(c *gin.Context) {
 	// 管理员模式下禁用注册
 	if auth.IsAdminMode() {
-		c.JSON(http.StatusForbidden, gin.H{"error": "管理员模式下禁用注册"})
+		respondError(c, http.StatusForbidden, ErrCodeRegistrationAdminMode)
 		return
 	}
 
 	// 若未开启注册，返回403
 	allowRegStr, _ := s.database.GetSystemConfig("allow_registration")
 	if allowRegStr == "false" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "注册已关闭"})
+		respondError(c, http.StatusForbidden, ErrCodeRegistrationClosed)
 		return
 	}
 
@@ -2620,7 +3890,7 @@ func (s *Server) handleRegister(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err)
 		return
 	}
 
@@ -2629,18 +3899,18 @@ func (s *Server) handleRegister(c *gin.Context) {
 	if betaModeStr == "true" {
 		// 内测模式下必须提供有效的内测码
 		if req.BetaCode == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "内测期间，注册需要提供内测码"})
+			respondError(c, http.StatusBadRequest, ErrCodeBetaCodeRequired)
 			return
 		}
 
 		// 验证内测码
 		isValid, err := s.database.ValidateBetaCode(req.BetaCode)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "验证内测码失败"})
+			respondError(c, http.StatusInternalServerError, ErrCodeBetaCodeVerifyFailed)
 			return
 		}
 		if !isValid {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "内测码无效或已被使用"})
+			respondError(c, http.StatusBadRequest, ErrCodeBetaCodeInvalid)
 			return
 		}
 	}
@@ -2648,21 +3918,21 @@ func (s *Server) handleRegister(c *gin.Context) {
 	// 检查邮箱是否已存在
 	_, err := s.database.GetUserByEmail(req.Email)
 	if err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "邮箱已被注册"})
+		respondError(c, http.StatusConflict, ErrCodeEmailRegistered)
 		return
 	}
 
 	// 生成密码哈希
 	passwordHash, err := auth.HashPassword(req.Password)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "密码处理失败"})
+		respondError(c, http.StatusInternalServerError, ErrCodePasswordProcessFailed)
 		return
 	}
 
 	// 生成OTP密钥
 	otpSecret, err := auth.GenerateOTPSecret()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "OTP密钥生成失败"})
+		respondError(c, http.StatusInternalServerError, ErrCodeOTPSecretFailed)
 		return
 	}
 
@@ -2679,7 +3949,7 @@ func (s *Server) handleRegister(c *gin.Context) {
 
 	err = s.database.CreateUser(user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建用户失败: " + err.Error()})
+		respondError(c, http.StatusInternalServerError, ErrCodeCreateUserFailed, err)
 		return
 	}
 
@@ -2714,34 +3984,34 @@ func (s *Server) handleCompleteRegistration(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err)
 		return
 	}
 
 	// 获取用户信息
 	user, err := s.database.GetUserByID(req.UserID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		respondError(c, http.StatusNotFound, ErrCodeUserNotFound)
 		return
 	}
 
 	// 验证OTP
 	if !auth.VerifyOTP(user.OTPSecret, req.OTPCode) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "OTP验证码错误"})
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidOTP)
 		return
 	}
 
 	// 更新用户OTP验证状态
 	err = s.database.UpdateUserOTPVerified(req.UserID, true)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新用户状态失败"})
+		respondError(c, http.StatusInternalServerError, ErrCodeUpdateUserStatusFailed)
 		return
 	}
 
 	// 生成JWT token
 	token, err := auth.GenerateJWT(user.ID, user.Email)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成token失败"})
+		respondError(c, http.StatusInternalServerError, ErrCodeTokenGenerateFailed)
 		return
 	}
 
@@ -2767,20 +4037,20 @@ func (s *Server) handleLogin(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err)
 		return
 	}
 
 	// 获取用户信息
 	user, err := s.database.GetUserByEmail(req.Email)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "邮箱或密码错误"})
+		respondError(c, http.StatusUnauthorized, ErrCodeInvalidCredentials)
 		return
 	}
 
 	// 验证密码
 	if !auth.CheckPassword(req.Password, user.PasswordHash) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "邮箱或密码错误"})
+		respondError(c, http.StatusUnauthorized, ErrCodeInvalidCredentials)
 		return
 	}
 
@@ -2824,7 +4094,7 @@ func (s *Server) handleLogin(c *gin.Context) {
 		// 这些账号由普通用户创建，不需要OTP验证
 		token, err := auth.GenerateJWT(user.ID, user.Email)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "生成token失败"})
+			respondError(c, http.StatusInternalServerError, ErrCodeTokenGenerateFailed)
 			return
 		}
 
@@ -2854,27 +4124,27 @@ func (s *Server) handleVerifyOTP(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err)
 		return
 	}
 
 	// 获取用户信息
 	user, err := s.database.GetUserByID(req.UserID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		respondError(c, http.StatusNotFound, ErrCodeUserNotFound)
 		return
 	}
 
 	// 验证OTP
 	if !auth.VerifyOTP(user.OTPSecret, req.OTPCode) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "验证码错误"})
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidOTP)
 		return
 	}
 
 	// 生成JWT token
 	token, err := auth.GenerateJWT(user.ID, user.Email)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成token失败"})
+		respondError(c, http.StatusInternalServerError, ErrCodeTokenGenerateFailed)
 		return
 	}
 
@@ -2987,6 +4257,7 @@ func (s *Server) Start() error {
 	log.Printf("  • GET  /api/status?trader_id=xxx     - 指定trader的系统状态")
 	log.Printf("  • GET  /api/account?trader_id=xxx    - 指定trader的账户信息")
 	log.Printf("  • GET  /api/positions?trader_id=xxx  - 指定trader的持仓列表")
+	log.Printf("  • GET  /api/traders/:id/book         - 持仓、委托和账户的一致快照")
 	log.Printf("  • GET  /api/decisions?trader_id=xxx  - 指定trader的决策日志")
 	log.Printf("  • GET  /api/decisions/latest?trader_id=xxx - 指定trader的最新决策")
 	log.Printf("  • GET  /api/statistics?trader_id=xxx - 指定trader的统计信息")
@@ -3030,6 +4301,24 @@ func (s *Server) handleGetPromptTemplate(c *gin.Context) {
 	})
 }
 
+// handleValidatePromptTemplate 检查模板中无法被替换的占位符，并返回可用的占位符列表
+func (s *Server) handleValidatePromptTemplate(c *gin.Context) {
+	templateName := c.Param("name")
+
+	unresolved, err := decision.ValidateTemplate(templateName)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("模板不存在: %s", templateName)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"name":            templateName,
+		"valid":           len(unresolved) == 0,
+		"unresolved":      unresolved,
+		"known_variables": decision.TemplateVariables,
+	})
+}
+
 // handlePublicTraderList 获取公开的交易员列表（无需认证）
 func (s *Server) handlePublicTraderList(c *gin.Context) {
 	// 从所有用户获取交易员信息
@@ -3105,11 +4394,22 @@ func (s *Server) handleTopTraders(c *gin.Context) {
 // handleEquityHistoryBatch 批量获取多个交易员的收益率历史数据（无需认证，用于表现对比）
 func (s *Server) handleEquityHistoryBatch(c *gin.Context) {
 	var requestBody struct {
-		TraderIDs []string `json:"trader_ids"`
+		TraderIDs  []string `json:"trader_ids"`
+		Resolution string   `json:"resolution"` // raw/hourly/daily，未传时使用 query 参数
 	}
 
 	// 尝试解析POST请求的JSON body
-	if err := c.ShouldBindJSON(&requestBody); err != nil {
+	bindErr := c.ShouldBindJSON(&requestBody)
+	if requestBody.Resolution == "" {
+		requestBody.Resolution = c.Query("resolution")
+	}
+	bucket, err := parseEquityResolution(requestBody.Resolution)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if bindErr != nil {
 		// 如果JSON解析失败，尝试从query参数获取（兼容GET请求）
 		traderIDsParam := c.Query("trader_ids")
 		if traderIDsParam == "" {
@@ -3136,7 +4436,7 @@ func (s *Server) handleEquityHistoryBatch(c *gin.Context) {
 				}
 			}
 
-			result := s.getEquityHistoryForTraders(traderIDs)
+			result := s.getEquityHistoryForTraders(traderIDs, bucket)
 			c.JSON(http.StatusOK, result)
 			return
 		}
@@ -3153,12 +4453,12 @@ func (s *Server) handleEquityHistoryBatch(c *gin.Context) {
 		requestBody.TraderIDs = requestBody.TraderIDs[:20]
 	}
 
-	result := s.getEquityHistoryForTraders(requestBody.TraderIDs)
+	result := s.getEquityHistoryForTraders(requestBody.TraderIDs, bucket)
 	c.JSON(http.StatusOK, result)
 }
 
-// getEquityHistoryForTraders 获取多个交易员的历史数据
-func (s *Server) getEquityHistoryForTraders(traderIDs []string) map[string]interface{} {
+// getEquityHistoryForTraders 获取多个交易员的历史数据（bucket>0 时按时间分桶降采样）
+func (s *Server) getEquityHistoryForTraders(traderIDs []string, bucket time.Duration) map[string]interface{} {
 	result := make(map[string]interface{})
 	histories := make(map[string]interface{})
 	errors := make(map[string]string)
@@ -3168,18 +4468,24 @@ func (s *Server) getEquityHistoryForTraders(traderIDs []string) map[string]inter
 			continue
 		}
 
+		// 未公开展示的交易员按不存在处理，避免通过批量接口泄露
 		trader, err := s.traderManager.GetTrader(traderID)
-		if err != nil {
+		if err != nil || !trader.IsPublic() {
 			errors[traderID] = "交易员不存在"
 			continue
 		}
 
-		// 获取历史数据（用于对比展示，限制数据量）
-		records, err := trader.GetDecisionLogger().GetLatestRecords(500)
+		// 获取历史数据（用于对比展示，限制数据量；降采样时可覆盖更长的时间范围）
+		limit := 500
+		if bucket > 0 {
+			limit = 10000
+		}
+		records, err := trader.GetDecisionLogger().GetLatestRecords(limit)
 		if err != nil {
 			errors[traderID] = fmt.Sprintf("获取历史数据失败: %v", err)
 			continue
 		}
+		records = downsampleEquityRecords(records, bucket)
 
 		// 构建收益率历史数据
 		history := make([]map[string]interface{}, 0, len(records))
@@ -3216,7 +4522,7 @@ func (s *Server) handleGetPublicTraderConfig(c *gin.Context) {
 	}
 
 	trader, err := s.traderManager.GetTrader(traderID)
-	if err != nil {
+	if err != nil || !trader.IsPublic() {
 		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
 		return
 	}
@@ -3227,7 +4533,7 @@ func (s *Server) handleGetPublicTraderConfig(c *gin.Context) {
 	// 只返回公开的配置信息，不包含API密钥等敏感数据
 	result := map[string]interface{}{
 		"trader_id":   trader.GetID(),
-		"trader_name": trader.GetName(),
+		"trader_name": trader.PublicName(),
 		"ai_model":    trader.GetAIModel(),
 		"exchange":    trader.GetExchange(),
 		"is_running":  status["is_running"],
@@ -3238,6 +4544,25 @@ func (s *Server) handleGetPublicTraderConfig(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// tradingScheduleResponse 将存储的交易时段转换为接口返回的数组（未配置或无效时返回空数组）
+func tradingScheduleResponse(raw string) []trader.TradingWindow {
+	windows, err := trader.ParseTradingSchedule(raw)
+	if err != nil || windows == nil {
+		return []trader.TradingWindow{}
+	}
+	return windows
+}
+
+// resolvePublicDisplayName 规范化公开展示名称："auto" 表示按交易员ID生成固定化名
+func resolvePublicDisplayName(name, traderID string) string {
+	name = strings.TrimSpace(name)
+	if strings.EqualFold(name, "auto") {
+		sum := sha256.Sum256([]byte(traderID))
+		return "Trader-" + strings.ToUpper(hex.EncodeToString(sum[:3]))
+	}
+	return name
+}
+
 // generateRandomEmail 生成随机邮箱
 func generateRandomEmail() string {
 	randomStr := uuid.New().String()[:8]
@@ -3494,7 +4819,7 @@ func (s *Server) handleCreateGroupLeader(c *gin.Context) {
 		GenerateRandomPassword bool     `json:"generate_random_password"`
 		Email                  string   `json:"email"`
 		Password               string   `json:"password"`
-		Categories             []string `json:"categories" binding:"required"` // 必填：可以观测的分类列表
+		Categories             []string `json:"categories" binding:"required,max=100"` // 必填：可以观测的分类列表（最多100个）
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -3921,7 +5246,7 @@ func (s *Server) handleUpdateGroupLeaderCategories(c *gin.Context) {
 	}
 
 	var req struct {
-		Categories []string `json:"categories" binding:"required"`
+		Categories []string `json:"categories" binding:"required,max=100"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
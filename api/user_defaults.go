@@ -0,0 +1,243 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"nofx/config"
+	"nofx/trader"
+)
+
+// 新建交易员的系统默认值（用户未配置默认设置、请求也未提供时使用）
+const (
+	defaultSystemPromptTemplate = "default"
+	defaultScanIntervalMinutes  = 5
+	defaultTraderLeverage       = 5
+)
+
+// providedJSONFields 请求体中显式提供的字段（值为 null 视为未提供）
+func providedJSONFields(body []byte) map[string]bool {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return map[string]bool{}
+	}
+	provided := make(map[string]bool, len(raw))
+	for key, value := range raw {
+		provided[key] = string(value) != "null"
+	}
+	return provided
+}
+
+// applyUserTraderDefaults 将用户默认设置填入请求中未显式提供的字段（请求中显式提供的值始终优先）
+func applyUserTraderDefaults(req *CreateTraderRequest, provided map[string]bool, defaults *config.UserTraderDefaults) {
+	if defaults == nil {
+		return
+	}
+	if !provided["system_prompt_template"] && defaults.SystemPromptTemplate != nil {
+		req.SystemPromptTemplate = *defaults.SystemPromptTemplate
+	}
+	if !provided["scan_interval_minutes"] && defaults.ScanIntervalMinutes != nil {
+		req.ScanIntervalMinutes = *defaults.ScanIntervalMinutes
+	}
+	if !provided["btc_eth_leverage"] && defaults.BTCETHLeverage != nil {
+		req.BTCETHLeverage = *defaults.BTCETHLeverage
+	}
+	if !provided["altcoin_leverage"] && defaults.AltcoinLeverage != nil {
+		req.AltcoinLeverage = *defaults.AltcoinLeverage
+	}
+	if !provided["is_cross_margin"] && defaults.IsCrossMargin != nil {
+		v := *defaults.IsCrossMargin
+		req.IsCrossMargin = &v
+	}
+	if !provided["trading_symbols"] && defaults.TradingSymbols != nil {
+		req.TradingSymbols = *defaults.TradingSymbols
+	}
+	if !provided["require_stop_loss"] && defaults.RequireStopLoss != nil {
+		req.RequireStopLoss = *defaults.RequireStopLoss
+	}
+	if !provided["default_stop_loss_pct"] && defaults.DefaultStopLossPct != nil {
+		req.DefaultStopLossPct = *defaults.DefaultStopLossPct
+	}
+	if !provided["min_confidence"] && defaults.MinConfidence != nil {
+		req.MinConfidence = *defaults.MinConfidence
+	}
+	if !provided["enforce_daily_loss_stop"] && defaults.EnforceDailyLossStop != nil {
+		req.EnforceDailyLossStop = *defaults.EnforceDailyLossStop
+	}
+	if !provided["max_open_orders"] && defaults.MaxOpenOrders != nil {
+		v := *defaults.MaxOpenOrders
+		req.MaxOpenOrders = &v
+	}
+	if !provided["max_actions_per_cycle"] && defaults.MaxActionsPerCycle != nil {
+		v := *defaults.MaxActionsPerCycle
+		req.MaxActionsPerCycle = &v
+	}
+	if !provided["sizing_base"] && defaults.SizingBase != nil {
+		req.SizingBase = *defaults.SizingBase
+	}
+	if !provided["on_ai_failure"] && defaults.OnAIFailure != nil {
+		req.OnAIFailure = *defaults.OnAIFailure
+	}
+}
+
+// systemTraderDefaults 新建交易员的系统默认值（杠杆来自系统配置）
+func systemTraderDefaults(btcEthLeverage, altcoinLeverage int) config.UserTraderDefaults {
+	template, scan := defaultSystemPromptTemplate, defaultScanIntervalMinutes
+	cross, requireSL, enforceStop := true, false, false
+	symbols, sizing, onAIFailure := "", trader.SizingBaseFixed, trader.AIFailureHold
+	stopLossPct, minConfidence := 0.0, 0
+	maxOpenOrders, maxActions := trader.DefaultMaxOpenOrders, trader.DefaultMaxActionsPerCycle
+	return config.UserTraderDefaults{
+		SystemPromptTemplate: &template,
+		ScanIntervalMinutes:  &scan,
+		BTCETHLeverage:       &btcEthLeverage,
+		AltcoinLeverage:      &altcoinLeverage,
+		IsCrossMargin:        &cross,
+		TradingSymbols:       &symbols,
+		RequireStopLoss:      &requireSL,
+		DefaultStopLossPct:   &stopLossPct,
+		MinConfidence:        &minConfidence,
+		EnforceDailyLossStop: &enforceStop,
+		MaxOpenOrders:        &maxOpenOrders,
+		MaxActionsPerCycle:   &maxActions,
+		SizingBase:           &sizing,
+		OnAIFailure:          &onAIFailure,
+	}
+}
+
+// resolveTraderDefaults 合并用户默认设置与系统默认值（用户设置优先），用于新建表单预填
+func resolveTraderDefaults(user *config.UserTraderDefaults, system config.UserTraderDefaults) config.UserTraderDefaults {
+	resolved := system
+	if user == nil {
+		return resolved
+	}
+	if user.SystemPromptTemplate != nil {
+		resolved.SystemPromptTemplate = user.SystemPromptTemplate
+	}
+	if user.ScanIntervalMinutes != nil {
+		resolved.ScanIntervalMinutes = user.ScanIntervalMinutes
+	}
+	if user.BTCETHLeverage != nil {
+		resolved.BTCETHLeverage = user.BTCETHLeverage
+	}
+	if user.AltcoinLeverage != nil {
+		resolved.AltcoinLeverage = user.AltcoinLeverage
+	}
+	if user.IsCrossMargin != nil {
+		resolved.IsCrossMargin = user.IsCrossMargin
+	}
+	if user.TradingSymbols != nil {
+		resolved.TradingSymbols = user.TradingSymbols
+	}
+	if user.RequireStopLoss != nil {
+		resolved.RequireStopLoss = user.RequireStopLoss
+	}
+	if user.DefaultStopLossPct != nil {
+		resolved.DefaultStopLossPct = user.DefaultStopLossPct
+	}
+	if user.MinConfidence != nil {
+		resolved.MinConfidence = user.MinConfidence
+	}
+	if user.EnforceDailyLossStop != nil {
+		resolved.EnforceDailyLossStop = user.EnforceDailyLossStop
+	}
+	if user.MaxOpenOrders != nil {
+		resolved.MaxOpenOrders = user.MaxOpenOrders
+	}
+	if user.MaxActionsPerCycle != nil {
+		resolved.MaxActionsPerCycle = user.MaxActionsPerCycle
+	}
+	if user.SizingBase != nil {
+		resolved.SizingBase = user.SizingBase
+	}
+	if user.OnAIFailure != nil {
+		resolved.OnAIFailure = user.OnAIFailure
+	}
+	return resolved
+}
+
+// systemDefaultLeverage 新建交易员的系统默认杠杆（system_config btc_eth_leverage / altcoin_leverage）
+func (s *Server) systemDefaultLeverage() (btcEth, altcoin int) {
+	btcEth, altcoin = defaultTraderLeverage, defaultTraderLeverage
+	if value, _ := s.database.GetSystemConfig("btc_eth_leverage"); value != "" {
+		if v, err := strconv.Atoi(value); err == nil && v > 0 {
+			btcEth = v
+		}
+	}
+	if value, _ := s.database.GetSystemConfig("altcoin_leverage"); value != "" {
+		if v, err := strconv.Atoi(value); err == nil && v > 0 {
+			altcoin = v
+		}
+	}
+	return btcEth, altcoin
+}
+
+// validateUserTraderDefaults 校验用户默认设置（规则与创建交易员一致），不合法时写入错误响应并返回 false
+func (s *Server) validateUserTraderDefaults(c *gin.Context, d *config.UserTraderDefaults) bool {
+	btcEth, altcoin := 0, 0
+	if d.BTCETHLeverage != nil {
+		btcEth = *d.BTCETHLeverage
+	}
+	if d.AltcoinLeverage != nil {
+		altcoin = *d.AltcoinLeverage
+	}
+	if !s.validateLeverageCeilings(c, btcEth, altcoin) {
+		return false
+	}
+	switch {
+	case d.ScanIntervalMinutes != nil && *d.ScanIntervalMinutes < 0:
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "scan_interval_minutes 不能为负数")
+	case d.DefaultStopLossPct != nil && (*d.DefaultStopLossPct < 0 || *d.DefaultStopLossPct >= 100):
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidStopLossPct)
+	case d.MinConfidence != nil && (*d.MinConfidence < 0 || *d.MinConfidence > 100):
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidMinConfidence)
+	case d.MaxOpenOrders != nil && *d.MaxOpenOrders < 0:
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidMaxOpenOrders)
+	case d.MaxActionsPerCycle != nil && *d.MaxActionsPerCycle < 0:
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidMaxActions)
+	case d.SizingBase != nil && !trader.ValidSizingBase(*d.SizingBase):
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidSizingBase, *d.SizingBase)
+	case d.OnAIFailure != nil && !trader.ValidAIFailurePolicy(*d.OnAIFailure):
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidAIFailurePolicy, *d.OnAIFailure)
+	default:
+		return true
+	}
+	return false
+}
+
+// handleGetUserDefaults 获取用户的新建交易员默认设置；resolved 为合并系统默认值后的结果，用于新建表单预填
+func (s *Server) handleGetUserDefaults(c *gin.Context) {
+	defaults, err := s.database.GetUserTraderDefaults(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	btcEth, altcoin := s.systemDefaultLeverage()
+	c.JSON(http.StatusOK, gin.H{
+		"defaults": defaults,
+		"resolved": resolveTraderDefaults(defaults, systemTraderDefaults(btcEth, altcoin)),
+	})
+}
+
+// handleUpdateUserDefaults 保存用户的新建交易员默认设置（整体覆盖，未传的字段回退到系统默认值）
+func (s *Server) handleUpdateUserDefaults(c *gin.Context) {
+	var defaults config.UserTraderDefaults
+	if err := c.ShouldBindJSON(&defaults); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err)
+		return
+	}
+	if !s.validateUserTraderDefaults(c, &defaults) {
+		return
+	}
+	if err := s.database.SaveUserTraderDefaults(c.GetString("user_id"), &defaults); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	btcEth, altcoin := s.systemDefaultLeverage()
+	c.JSON(http.StatusOK, gin.H{
+		"defaults": defaults,
+		"resolved": resolveTraderDefaults(&defaults, systemTraderDefaults(btcEth, altcoin)),
+	})
+}
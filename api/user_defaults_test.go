@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"nofx/config"
+)
+
+func TestApplyUserTraderDefaults(t *testing.T) {
+	template, scan, btcEth, maxOpen, sizing := "my_tpl", 15, 8, 2, "equity"
+	zero := 0
+	user := &config.UserTraderDefaults{
+		SystemPromptTemplate: &template,
+		ScanIntervalMinutes:  &scan,
+		BTCETHLeverage:       &btcEth,
+		MaxOpenOrders:        &maxOpen,
+		SizingBase:           &sizing,
+	}
+
+	tests := []struct {
+		name         string
+		body         string
+		wantTemplate string
+		wantScan     int
+		wantBTCETH   int
+		wantMaxOpen  *int
+	}{
+		{"省略可选字段时使用用户默认值", `{"name":"t1"}`, "my_tpl", 15, 8, &maxOpen},
+		{"显式提供的值优先", `{"name":"t1","system_prompt_template":"aggressive","btc_eth_leverage":3,"max_open_orders":0}`, "aggressive", 15, 3, &zero},
+		{"null 视为未提供", `{"name":"t1","btc_eth_leverage":null,"scan_interval_minutes":null}`, "my_tpl", 15, 8, &maxOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req CreateTraderRequest
+			if err := json.Unmarshal([]byte(tt.body), &req); err != nil {
+				t.Fatalf("解析请求失败: %v", err)
+			}
+			applyUserTraderDefaults(&req, providedJSONFields([]byte(tt.body)), user)
+
+			if req.SystemPromptTemplate != tt.wantTemplate {
+				t.Errorf("system_prompt_template = %q, want %q", req.SystemPromptTemplate, tt.wantTemplate)
+			}
+			if req.ScanIntervalMinutes != tt.wantScan {
+				t.Errorf("scan_interval_minutes = %d, want %d", req.ScanIntervalMinutes, tt.wantScan)
+			}
+			if req.BTCETHLeverage != tt.wantBTCETH {
+				t.Errorf("btc_eth_leverage = %d, want %d", req.BTCETHLeverage, tt.wantBTCETH)
+			}
+			if req.MaxOpenOrders == nil || *req.MaxOpenOrders != *tt.wantMaxOpen {
+				t.Errorf("max_open_orders = %v, want %d", req.MaxOpenOrders, *tt.wantMaxOpen)
+			}
+			// 用户未配置的字段保持请求原值，由创建逻辑回退到系统默认值
+			if req.AltcoinLeverage != 0 || req.IsCrossMargin != nil {
+				t.Errorf("用户未配置的字段不应被填充: altcoin=%d cross=%v", req.AltcoinLeverage, req.IsCrossMargin)
+			}
+		})
+	}
+
+	var req CreateTraderRequest
+	applyUserTraderDefaults(&req, providedJSONFields([]byte(`{}`)), nil)
+	if req.SystemPromptTemplate != "" || req.BTCETHLeverage != 0 {
+		t.Errorf("没有用户默认设置时请求不应被修改: %+v", req)
+	}
+}
+
+func TestResolveTraderDefaults(t *testing.T) {
+	btcEth := 8
+	resolved := resolveTraderDefaults(&config.UserTraderDefaults{BTCETHLeverage: &btcEth}, systemTraderDefaults(5, 3))
+
+	if *resolved.BTCETHLeverage != 8 {
+		t.Errorf("btc_eth_leverage = %d, want 8（用户设置优先）", *resolved.BTCETHLeverage)
+	}
+	if *resolved.AltcoinLeverage != 3 {
+		t.Errorf("altcoin_leverage = %d, want 3（回退到系统默认值）", *resolved.AltcoinLeverage)
+	}
+	if *resolved.SystemPromptTemplate != defaultSystemPromptTemplate || *resolved.ScanIntervalMinutes != defaultScanIntervalMinutes {
+		t.Errorf("提示词模板/扫描间隔应回退到系统默认值: %q / %d", *resolved.SystemPromptTemplate, *resolved.ScanIntervalMinutes)
+	}
+}
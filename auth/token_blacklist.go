@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultBlacklistPruneInterval 黑名单过期条目的默认清理间隔
+const DefaultBlacklistPruneInterval = 10 * time.Minute
+
+// maxBlacklistEntries 黑名单最大容量阈值
+const maxBlacklistEntries = 100_000
+
+// BlacklistStore token黑名单持久化存储（只保存token的SHA-256哈希，重启后恢复已登出的token）
+type BlacklistStore interface {
+	SaveBlacklistedToken(tokenHash string, expiresAt time.Time) error
+	LoadBlacklistedTokens() (map[string]time.Time, error)
+	DeleteExpiredBlacklistedTokens(before time.Time) error
+	ClearBlacklistedTokens() error
+}
+
+// tokenBlacklist 用于登出后的token黑名单（内存 tokenHash -> 过期时间，配置存储后同步持久化）
+var tokenBlacklist = struct {
+	sync.RWMutex
+	items map[string]time.Time
+	store BlacklistStore
+}{items: make(map[string]time.Time)}
+
+// hashToken 计算token哈希（黑名单不保存原始token）
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// SetBlacklistStore 设置黑名单持久化存储，并加载其中尚未过期的条目
+func SetBlacklistStore(store BlacklistStore) error {
+	items, err := store.LoadBlacklistedTokens()
+	if err != nil {
+		return err
+	}
+
+	tokenBlacklist.Lock()
+	defer tokenBlacklist.Unlock()
+	tokenBlacklist.store = store
+	now := time.Now()
+	for tokenHash, exp := range items {
+		if now.Before(exp) {
+			tokenBlacklist.items[tokenHash] = exp
+		}
+	}
+	return nil
+}
+
+// BlacklistToken 将token加入黑名单直到过期
+func BlacklistToken(token string, exp time.Time) {
+	tokenHash := hashToken(token)
+
+	tokenBlacklist.Lock()
+	defer tokenBlacklist.Unlock()
+	tokenBlacklist.items[tokenHash] = exp
+	if tokenBlacklist.store != nil {
+		if err := tokenBlacklist.store.SaveBlacklistedToken(tokenHash, exp); err != nil {
+			log.Printf("auth: persist blacklisted token failed: %v", err)
+		}
+	}
+
+	// 如果超过容量阈值，则进行一次过期清理；若仍超限，记录警告日志
+	if len(tokenBlacklist.items) > maxBlacklistEntries {
+		pruneExpiredLocked(time.Now())
+		if len(tokenBlacklist.items) > maxBlacklistEntries {
+			log.Printf("auth: token blacklist size (%d) exceeds limit (%d) after sweep; consider reducing JWT TTL",
+				len(tokenBlacklist.items), maxBlacklistEntries)
+		}
+	}
+}
+
+// IsTokenBlacklisted 检查token是否在黑名单中（过期自动清理）
+func IsTokenBlacklisted(token string) bool {
+	tokenHash := hashToken(token)
+
+	tokenBlacklist.Lock()
+	defer tokenBlacklist.Unlock()
+	if exp, ok := tokenBlacklist.items[tokenHash]; ok {
+		if time.Now().After(exp) {
+			delete(tokenBlacklist.items, tokenHash)
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// pruneExpiredLocked 删除已过期的条目，返回删除数量（调用方需持有写锁）
+func pruneExpiredLocked(now time.Time) int {
+	removed := 0
+	for tokenHash, exp := range tokenBlacklist.items {
+		if now.After(exp) {
+			delete(tokenBlacklist.items, tokenHash)
+			removed++
+		}
+	}
+	if tokenBlacklist.store != nil {
+		if err := tokenBlacklist.store.DeleteExpiredBlacklistedTokens(now); err != nil {
+			log.Printf("auth: prune persisted token blacklist failed: %v", err)
+		}
+	}
+	return removed
+}
+
+// PruneExpiredTokens 清理黑名单中已过期的条目，返回清理数量
+func PruneExpiredTokens() int {
+	tokenBlacklist.Lock()
+	defer tokenBlacklist.Unlock()
+	return pruneExpiredLocked(time.Now())
+}
+
+// BlacklistSize 黑名单中尚未过期的条目数
+func BlacklistSize() int {
+	tokenBlacklist.RLock()
+	defer tokenBlacklist.RUnlock()
+	now := time.Now()
+	size := 0
+	for _, exp := range tokenBlacklist.items {
+		if !now.After(exp) {
+			size++
+		}
+	}
+	return size
+}
+
+// ClearBlacklist 清空黑名单（含持久化存储），返回清空前的条目数
+func ClearBlacklist() (int, error) {
+	tokenBlacklist.Lock()
+	defer tokenBlacklist.Unlock()
+	if tokenBlacklist.store != nil {
+		if err := tokenBlacklist.store.ClearBlacklistedTokens(); err != nil {
+			return 0, err
+		}
+	}
+	cleared := len(tokenBlacklist.items)
+	tokenBlacklist.items = make(map[string]time.Time)
+	return cleared, nil
+}
+
+// StartBlacklistPruner 启动后台任务，按间隔清理黑名单过期条目（interval<=0 时使用默认间隔）
+func StartBlacklistPruner(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultBlacklistPruneInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if removed := PruneExpiredTokens(); removed > 0 {
+				log.Printf("auth: pruned %d expired blacklisted tokens", removed)
+			}
+		}
+	}()
+}
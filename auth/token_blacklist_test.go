@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// memoryBlacklistStore 内存实现的黑名单存储，模拟重启前后的持久化数据
+type memoryBlacklistStore struct {
+	items map[string]time.Time
+}
+
+func (m *memoryBlacklistStore) SaveBlacklistedToken(tokenHash string, expiresAt time.Time) error {
+	m.items[tokenHash] = expiresAt
+	return nil
+}
+
+func (m *memoryBlacklistStore) LoadBlacklistedTokens() (map[string]time.Time, error) {
+	items := make(map[string]time.Time, len(m.items))
+	for k, v := range m.items {
+		items[k] = v
+	}
+	return items, nil
+}
+
+func (m *memoryBlacklistStore) DeleteExpiredBlacklistedTokens(before time.Time) error {
+	for k, v := range m.items {
+		if before.After(v) {
+			delete(m.items, k)
+		}
+	}
+	return nil
+}
+
+func (m *memoryBlacklistStore) ClearBlacklistedTokens() error {
+	m.items = make(map[string]time.Time)
+	return nil
+}
+
+// resetBlacklist 重置黑名单全局状态（模拟进程重启）
+func resetBlacklist() {
+	tokenBlacklist.Lock()
+	defer tokenBlacklist.Unlock()
+	tokenBlacklist.items = make(map[string]time.Time)
+	tokenBlacklist.store = nil
+}
+
+func TestPruneExpiredTokens(t *testing.T) {
+	resetBlacklist()
+	defer resetBlacklist()
+	store := &memoryBlacklistStore{items: map[string]time.Time{}}
+	if err := SetBlacklistStore(store); err != nil {
+		t.Fatalf("SetBlacklistStore: %v", err)
+	}
+
+	BlacklistToken("expired-token", time.Now().Add(-time.Minute))
+	BlacklistToken("active-token", time.Now().Add(time.Hour))
+
+	if removed := PruneExpiredTokens(); removed != 1 {
+		t.Errorf("PruneExpiredTokens() = %d, want 1", removed)
+	}
+	if size := BlacklistSize(); size != 1 {
+		t.Errorf("BlacklistSize() = %d, want 1", size)
+	}
+	if !IsTokenBlacklisted("active-token") {
+		t.Errorf("未过期的token应保留在黑名单中")
+	}
+	if IsTokenBlacklisted("expired-token") {
+		t.Errorf("过期的token应已被清理")
+	}
+	if len(store.items) != 1 {
+		t.Errorf("持久化存储中应只剩1条，got %d", len(store.items))
+	}
+	if _, ok := store.items["active-token"]; ok {
+		t.Errorf("持久化存储不应保存原始token")
+	}
+}
+
+func TestBlacklistSurvivesRestart(t *testing.T) {
+	resetBlacklist()
+	defer resetBlacklist()
+	store := &memoryBlacklistStore{items: map[string]time.Time{}}
+	if err := SetBlacklistStore(store); err != nil {
+		t.Fatalf("SetBlacklistStore: %v", err)
+	}
+	BlacklistToken("logged-out-token", time.Now().Add(time.Hour))
+
+	// 模拟重启：内存清空后从存储恢复
+	resetBlacklist()
+	if IsTokenBlacklisted("logged-out-token") {
+		t.Fatalf("重置后内存黑名单应为空")
+	}
+	if err := SetBlacklistStore(store); err != nil {
+		t.Fatalf("SetBlacklistStore: %v", err)
+	}
+	if !IsTokenBlacklisted("logged-out-token") {
+		t.Errorf("重启后已登出的token应仍在黑名单中")
+	}
+
+	cleared, err := ClearBlacklist()
+	if err != nil || cleared != 1 {
+		t.Errorf("ClearBlacklist() = (%d, %v), want (1, nil)", cleared, err)
+	}
+	if IsTokenBlacklisted("logged-out-token") || len(store.items) != 0 {
+		t.Errorf("清空后内存与存储中均不应有条目")
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"os"
 	"runtime"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -22,13 +23,14 @@ import (
 // DatabaseInterface 定义了数据库实现需要提供的方法集合
 type DatabaseInterface interface {
 	SetCryptoService(cs *crypto.CryptoService)
+	RotateEncryptionKey() (version int, reencrypted int, skipped []string, err error)
 	CreateUser(user *User) error
 	GetUserByEmail(email string) (*User, error)
 	GetUserByID(userID string) (*User, error)
 	GetAllUsers() ([]string, error)
 	UpdateUserOTPVerified(userID string, verified bool) error
 	GetAIModels(userID string) ([]*AIModelConfig, error)
-	UpdateAIModel(userID, id string, enabled bool, apiKey, customAPIURL, customModelName string) error
+	UpdateAIModel(userID, id string, enabled bool, apiKey, customAPIURL, customModelName string, maxPromptTokens int, customHeaders map[string]string, customAuthScheme string) error
 	GetExchanges(userID string) ([]*ExchangeConfig, error)
 	UpdateExchange(userID, id string, enabled bool, apiKey, secretKey, passphrase string, testnet bool, hyperliquidWalletAddr, asterUser, asterSigner, asterPrivateKey, provider, label string) error
 	CreateAIModel(userID, id, name, provider string, enabled bool, apiKey, customAPIURL string) error
@@ -47,6 +49,11 @@ type DatabaseInterface interface {
 	CreateUserSignalSource(userID, coinPoolURL, oiTopURL string) error
 	GetUserSignalSource(userID string) (*UserSignalSource, error)
 	UpdateUserSignalSource(userID, coinPoolURL, oiTopURL string) error
+	CreateUserSignalSourceItem(item *UserSignalSourceItem) error
+	GetUserSignalSourceItems(userID string) ([]*UserSignalSourceItem, error)
+	GetUserSignalSourceItem(userID string, id int64) (*UserSignalSourceItem, error)
+	UpdateUserSignalSourceItem(item *UserSignalSourceItem) error
+	DeleteUserSignalSourceItem(userID string, id int64) error
 	GetCustomCoins() []string
 	LoadBetaCodesFromFile(filePath string) error
 	ValidateBetaCode(code string) (bool, error)
@@ -64,6 +71,15 @@ type DatabaseInterface interface {
 	UpdateStrategyOrderStatus(id int, status string) error
 	// Execution Log
 	LogExecutionEvent(traderID, strategyID, action, symbol, reason string, success bool, errInfo string) error
+	// Webhook
+	CreateUserWebhook(w *UserWebhook) error
+	GetUserWebhooks(userID string) ([]*UserWebhook, error)
+	GetUserWebhook(userID string, id int64) (*UserWebhook, error)
+	DeleteUserWebhook(userID string, id int64) error
+	RecordWebhookDeadLetter(dl *WebhookDeadLetter) error
+	// Credential reset
+	ClearAIModelCredential(userID, id, field string) error
+	ClearExchangeCredential(userID, id, field string) error
 	Close() error
 }
 
@@ -235,6 +251,21 @@ func (d *Database) createTables(isMySQL bool) error {
 			UNIQUE(user_id)
 		)`,
 
+		// 用户命名信号源表（多信号源模式，按权重合并候选币种）
+		`CREATE TABLE IF NOT EXISTS user_signal_source_items (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			url TEXT NOT NULL,
+			type TEXT NOT NULL DEFAULT 'coin_pool', -- 'coin_pool' or 'oi_top'
+			weight REAL DEFAULT 1,
+			enabled BOOLEAN DEFAULT 1,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_user_signal_source_items_user ON user_signal_source_items(user_id)`,
+
 		// 交易员配置表
 		`CREATE TABLE IF NOT EXISTS traders (
 			id TEXT PRIMARY KEY,
@@ -401,6 +432,70 @@ func (d *Database) createTables(isMySQL bool) error {
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		)`,
 
+		// 用户 Webhook 回调配置（events 逗号分隔，为空表示订阅全部事件）
+		`CREATE TABLE IF NOT EXISTS user_webhooks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id TEXT NOT NULL,
+			url TEXT NOT NULL,
+			secret TEXT DEFAULT '',
+			events TEXT DEFAULT '',
+			enabled BOOLEAN DEFAULT 1,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_user_webhooks_user ON user_webhooks(user_id)`,
+
+		// 登出token黑名单（只保存token哈希，expires_at 为 Unix 秒）
+		`CREATE TABLE IF NOT EXISTS token_blacklist (
+			token_hash TEXT PRIMARY KEY,
+			expires_at INTEGER NOT NULL
+		)`,
+
+		// 用户级新建交易员默认设置（settings 为 JSON，未设置的字段回退到系统默认值）
+		`CREATE TABLE IF NOT EXISTS user_defaults (
+			user_id TEXT PRIMARY KEY,
+			settings TEXT NOT NULL DEFAULT '{}',
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Webhook 死信记录（多次重试仍投递失败）
+		`CREATE TABLE IF NOT EXISTS webhook_dead_letters (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			webhook_id INTEGER NOT NULL,
+			user_id TEXT NOT NULL,
+			event_type TEXT NOT NULL,
+			payload TEXT NOT NULL,
+			attempts INTEGER DEFAULT 0,
+			last_error TEXT DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// 交易员周期净值基准历史（baseline_reset_policy 按周/月滚动，保留每个周期的期初/期末净值）
+		`CREATE TABLE IF NOT EXISTS trader_baselines (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			trader_id TEXT NOT NULL,
+			policy TEXT NOT NULL,
+			baseline REAL NOT NULL,
+			period_start DATETIME NOT NULL,
+			end_equity REAL,
+			period_end DATETIME,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_trader_baselines_trader ON trader_baselines(trader_id, period_start)`,
+
+		// 初始余额（盈亏基准）手动调整审计记录
+		`CREATE TABLE IF NOT EXISTS trader_baseline_audits (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			trader_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			mode TEXT NOT NULL,
+			previous_balance REAL NOT NULL,
+			new_balance REAL NOT NULL,
+			delta REAL NOT NULL,
+			reason TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_trader_baseline_audits_trader ON trader_baseline_audits(trader_id, created_at)`,
+
 		// 触发器：自动更新 updated_at
 		`CREATE TRIGGER IF NOT EXISTS update_users_updated_at
 			AFTER UPDATE ON users
@@ -445,7 +540,7 @@ func (d *Database) createTables(isMySQL bool) error {
 		}
 	}
 
-	// 为现有数据库添加新字段（向后兼容）
+	// 为现有数据库添加新字段（向后兼容，MySQL 对应的增量列见 mysqlAddedColumns）
 	alterQueries := []string{
 		`ALTER TABLE exchanges ADD COLUMN hyperliquid_wallet_addr TEXT DEFAULT ''`,
 		`ALTER TABLE exchanges ADD COLUMN aster_user TEXT DEFAULT ''`,
@@ -467,17 +562,96 @@ func (d *Database) createTables(isMySQL bool) error {
 		`ALTER TABLE traders ADD COLUMN system_prompt_template TEXT DEFAULT 'default'`, // 系统提示词模板名称
 		`ALTER TABLE ai_models ADD COLUMN custom_api_url TEXT DEFAULT ''`,              // 自定义API地址
 		`ALTER TABLE ai_models ADD COLUMN custom_model_name TEXT DEFAULT ''`,           // 自定义模型名称
+		`ALTER TABLE ai_models ADD COLUMN max_prompt_tokens INTEGER DEFAULT 0`,         // Prompt token 预算（0=不限制）
+		`ALTER TABLE ai_models ADD COLUMN custom_headers TEXT DEFAULT ''`,              // 自定义API附加请求头（JSON，加密存储）
+		`ALTER TABLE ai_models ADD COLUMN custom_auth_scheme TEXT DEFAULT ''`,          // 自定义API认证方式（空=bearer）
 		`ALTER TABLE strategy_decision_history ADD COLUMN system_prompt TEXT DEFAULT ''`,
 		`ALTER TABLE strategy_decision_history ADD COLUMN input_prompt TEXT DEFAULT ''`,
 		`ALTER TABLE strategy_decision_history ADD COLUMN raw_ai_response TEXT DEFAULT ''`,
+		`ALTER TABLE strategy_decision_history ADD COLUMN ai_provider TEXT DEFAULT ''`,
+		`ALTER TABLE strategy_decision_history ADD COLUMN ai_model_name TEXT DEFAULT ''`,
+		`ALTER TABLE strategy_decision_history ADD COLUMN was_fallback BOOLEAN DEFAULT 0`,
 		`ALTER TABLE trader_strategy_status ADD COLUMN had_position BOOLEAN DEFAULT 0`,
 		// 多用户观测系统扩展字段
 		`ALTER TABLE users ADD COLUMN role TEXT DEFAULT 'user'`,              // 用户角色: 'admin' | 'user' | 'group_leader' | 'trader_account'
 		`ALTER TABLE users ADD COLUMN trader_id TEXT DEFAULT NULL`,           // 交易员账号关联的交易员ID
 		`ALTER TABLE users ADD COLUMN category TEXT DEFAULT NULL`,            // 交易员账号的分类（冗余字段）
+		`ALTER TABLE users ADD COLUMN display_currency TEXT DEFAULT 'USDT'`,  // 账户/净值展示币种（仅用于展示换算，交易与存储始终为USDT）
 		`ALTER TABLE traders ADD COLUMN category TEXT DEFAULT ''`,            // 交易员分类
 		`ALTER TABLE traders ADD COLUMN trader_account_id TEXT DEFAULT NULL`, // 关联的交易员账号用户ID
 		`ALTER TABLE traders ADD COLUMN owner_user_id TEXT DEFAULT NULL`,     // 创建该交易员的用户ID
+		// 开仓保护配置
+		`ALTER TABLE traders ADD COLUMN require_stop_loss BOOLEAN DEFAULT 0`,  // 开仓必须带有效止损
+		`ALTER TABLE traders ADD COLUMN default_stop_loss_pct REAL DEFAULT 0`, // 自动推导止损的最大亏损百分比
+		// 交易选项
+		`ALTER TABLE traders ADD COLUMN exclude_held_from_candidates BOOLEAN DEFAULT 0`,  // 候选币种中剔除已持仓币种
+		`ALTER TABLE traders ADD COLUMN analysis_only BOOLEAN DEFAULT 0`,                 // 仅分析模式（只记录决策不执行）
+		`ALTER TABLE traders ADD COLUMN warmup_minutes INTEGER DEFAULT 0`,                // 启动后预热时长（分钟，预热期内只记录决策不执行）
+		`ALTER TABLE traders ADD COLUMN skip_cycle_if_busy BOOLEAN DEFAULT 0`,            // 周期执行中时跳过新的触发（默认等待）
+		`ALTER TABLE traders ADD COLUMN max_position_age_hours INTEGER DEFAULT 0`,        // 持仓最长持有时间（小时，0=不限制）
+		`ALTER TABLE traders ADD COLUMN allow_pyramiding BOOLEAN DEFAULT 0`,              // 允许对同方向已有持仓加仓
+		`ALTER TABLE traders ADD COLUMN max_adds_per_position INTEGER DEFAULT 2`,         // 单个持仓最多加仓次数
+		`ALTER TABLE traders ADD COLUMN enforce_daily_loss_stop BOOLEAN DEFAULT 0`,       // 日亏损硬止损（达到最大日亏损时平仓并暂停交易）
+		`ALTER TABLE traders ADD COLUMN allow_flip BOOLEAN DEFAULT 0`,                    // 允许反手动作 flip_long/flip_short（默认关闭）
+		`ALTER TABLE traders ADD COLUMN min_confidence INTEGER DEFAULT 0`,                // 开仓最低信心度（0-100，0表示不限制）
+		`ALTER TABLE traders ADD COLUMN signal_base_position_pct REAL DEFAULT 20`,        // 信号模式底仓占分配资金的百分比（默认20）
+		`ALTER TABLE traders ADD COLUMN signal_default_add_pct REAL DEFAULT 10`,          // 信号模式补仓未指定比例时的默认百分比（默认10）
+		`ALTER TABLE traders ADD COLUMN equity_take_profit REAL DEFAULT 0`,               // 账户净值止盈（USDT绝对值，净值达到即全部平仓并暂停，0表示关闭）
+		`ALTER TABLE traders ADD COLUMN equity_stop_loss REAL DEFAULT 0`,                 // 账户净值止损（USDT绝对值，净值跌至即全部平仓并暂停，0表示关闭）
+		`ALTER TABLE traders ADD COLUMN equity_take_profit_pct REAL DEFAULT 0`,           // 账户净值止盈百分比（相对初始余额，0表示关闭）
+		`ALTER TABLE traders ADD COLUMN equity_stop_loss_pct REAL DEFAULT 0`,             // 账户净值止损百分比（相对初始余额，0表示关闭）
+		`ALTER TABLE traders ADD COLUMN auto_reprotect BOOLEAN DEFAULT 0`,                // 每个决策周期后校验持仓保护单，缺失时按最近决策的止损/止盈补设
+		`ALTER TABLE traders ADD COLUMN public_display_name TEXT DEFAULT ''`,             // 公开接口（排行榜/竞赛）展示的名称，空表示使用交易员名称
+		`ALTER TABLE traders ADD COLUMN public_visibility BOOLEAN DEFAULT 1`,             // 是否在公开接口（排行榜/竞赛/收益对比）中展示
+		`ALTER TABLE traders ADD COLUMN backup_exchange_id TEXT DEFAULT ''`,              // 备用交易所配置ID（同一交易所的另一组API密钥，主密钥持续鉴权/IP失败时切换），空表示不启用
+		`ALTER TABLE traders ADD COLUMN trading_schedule TEXT DEFAULT ''`,                // 允许开新仓的时段（UTC，JSON数组），空表示全天可交易
+		`ALTER TABLE traders ADD COLUMN include_orderbook_depth BOOLEAN DEFAULT 0`,       // 决策上下文包含盘口深度（买一卖一、价差、附近挂单量）
+		`ALTER TABLE traders ADD COLUMN skip_if_btc_move_pct REAL DEFAULT 0`,             // BTC 1小时涨跌幅绝对值超过该百分比时跳过周期（0=不启用）
+		`ALTER TABLE traders ADD COLUMN skip_if_funding_above REAL DEFAULT 0`,            // BTC 资金费率绝对值超过该百分比时跳过周期（0=不启用）
+		`ALTER TABLE traders ADD COLUMN max_open_orders INTEGER DEFAULT 10`,              // 单个币种最多同时存在的限价挂单数（不含止盈止损计划单），0=不限制
+		`ALTER TABLE traders ADD COLUMN breakeven_at_profit_pct REAL DEFAULT 0`,          // 持仓收益率达到该百分比后止损移至开仓价（0=不启用）
+		`ALTER TABLE traders ADD COLUMN trail_stop_after_profit_pct REAL DEFAULT 0`,      // 持仓收益率达到该百分比后按峰值收益跟踪止损（0=不启用）
+		`ALTER TABLE traders ADD COLUMN trail_lock_fraction REAL DEFAULT 0.5`,            // 跟踪止损锁定的峰值收益比例（0-1）
+		`ALTER TABLE traders ADD COLUMN max_actions_per_cycle INTEGER DEFAULT 10`,        // 每个决策周期最多执行的动作数（0=不限制）
+		`ALTER TABLE traders ADD COLUMN approval_required_first_trade BOOLEAN DEFAULT 0`, // 首笔开仓需人工审批后执行
+		`ALTER TABLE traders ADD COLUMN min_seconds_between_ai_calls INTEGER DEFAULT 0`,  // 两次AI调用之间的最小间隔（秒，0=不限制）
+		`ALTER TABLE traders ADD COLUMN max_per_symbol_exposure_pct REAL DEFAULT 0`,      // 单币种持仓价值占净值的最大百分比（0=不限制）
+		`ALTER TABLE traders ADD COLUMN include_recent_trades BOOLEAN DEFAULT 1`,         // 决策上下文附带最近平仓交易
+		`ALTER TABLE traders ADD COLUMN recent_trades_count INTEGER DEFAULT 5`,           // 附带的最近平仓交易笔数（最多10笔）
+		`ALTER TABLE traders ADD COLUMN sizing_base TEXT DEFAULT 'fixed'`,                // 仓位计算基数：fixed=初始余额，equity=账户实时净值
+		`ALTER TABLE traders ADD COLUMN ai_temperature REAL`,                             // AI采样温度（NULL=提供商默认值）
+		`ALTER TABLE traders ADD COLUMN ai_top_p REAL`,                                   // AI核采样概率（NULL=提供商默认值）
+		`ALTER TABLE traders ADD COLUMN ai_max_tokens INTEGER DEFAULT 0`,                 // AI响应最大token数（0=默认值）
+		`ALTER TABLE traders ADD COLUMN on_ai_failure TEXT DEFAULT 'hold'`,               // AI调用/解析失败时的处理策略：hold/flatten/pause
+		`ALTER TABLE traders ADD COLUMN baseline_reset_policy TEXT DEFAULT 'none'`,       // 周期净值基准滚动策略：none/weekly/monthly
+		`ALTER TABLE traders ADD COLUMN enforce_max_drawdown_stop BOOLEAN DEFAULT 0`,     // 账户最大回撤硬止损（净值较历史峰值回撤达到阈值时停止开新仓）
+		`ALTER TABLE traders ADD COLUMN max_drawdown_stop_pct REAL DEFAULT 0`,            // 最大回撤硬止损阈值（百分比，0=使用系统 max_drawdown）
+		`ALTER TABLE traders ADD COLUMN drawdown_stop_flatten BOOLEAN DEFAULT 0`,         // 触发最大回撤硬止损时平掉所有持仓
+		`ALTER TABLE traders ADD COLUMN stop_approach_alert_pct REAL DEFAULT 0`,          // 标记价格距止损在该百分比以内时推送一次提醒（0=关闭）
+		`ALTER TABLE traders ADD COLUMN candidate_symbols TEXT DEFAULT ''`,               // AI选币的候选币种池（逗号分隔，为空时沿用 trading_symbols）
+		`ALTER TABLE traders ADD COLUMN min_holding_minutes INTEGER DEFAULT 0`,           // 最短持仓时间（分钟），未满时拒绝AI主动平仓，0=不限制
+		`ALTER TABLE traders ADD COLUMN strategy_sources TEXT DEFAULT ''`,                // 信号模式订阅的策略来源（发件人邮箱或 @域名，逗号分隔），为空时跟随所有来源
+		`ALTER TABLE traders ADD COLUMN context_indicators TEXT DEFAULT ''`,              // 加入决策上下文的多周期指标（rsi,macd,ema_cross,atr，逗号分隔），为空时不加入
+		`ALTER TABLE traders ADD COLUMN context_timeframes TEXT DEFAULT ''`,              // 多周期指标使用的K线周期（如 1h,4h，逗号分隔），为空时不加入
+		`ALTER TABLE traders ADD COLUMN tp1_close_pct REAL DEFAULT 0`,                    // 信号模式到达第一止盈时部分平仓的百分比，0=关闭
+		`ALTER TABLE traders ADD COLUMN tp1_breakeven_stop BOOLEAN DEFAULT 0`,            // 第一止盈分批止盈后将剩余仓位止损移到开仓价
+		`ALTER TABLE traders ADD COLUMN rebalance_targets TEXT DEFAULT ''`,               // 目标权重再平衡模式的目标权重（JSON: 币种 -> 百分比），为空时不启用
+		`ALTER TABLE traders ADD COLUMN rebalance_tolerance_pct REAL DEFAULT 0`,          // 再平衡容忍带（百分点），0=默认2
+		`ALTER TABLE traders ADD COLUMN rebalance_interval_minutes INTEGER DEFAULT 0`,    // 再平衡检查间隔（分钟），0=默认60
+		`ALTER TABLE traders ADD COLUMN use_oco BOOLEAN DEFAULT 0`,                       // 止损/止盈以 OCO（一个成交撤销另一个）方式联动下单
+		`ALTER TABLE traders ADD COLUMN ai_anomaly_idle_cycles INTEGER DEFAULT 0`,        // 连续 N 个周期有候选币种却没有开仓时告警（0=关闭）
+		`ALTER TABLE traders ADD COLUMN ai_anomaly_repeat_cycles INTEGER DEFAULT 0`,      // 连续 N 个周期输出相同决策时告警（0=关闭）
+		`ALTER TABLE traders ADD COLUMN ai_anomaly_pause BOOLEAN DEFAULT 0`,              // AI输出异常期间暂停开新仓
+		`ALTER TABLE traders ADD COLUMN protective_defaults TEXT DEFAULT ''`,             // 缺少止损/止盈时按币种推导的默认百分比（JSON: 币种/default -> {sl, tp}）
+		`ALTER TABLE traders ADD COLUMN position_audit_minutes INTEGER DEFAULT 0`,        // 信号模式仓位对账间隔（分钟），0=默认30
+		`ALTER TABLE traders ADD COLUMN min_available_balance REAL DEFAULT 0`,            // 可用余额低于该值（或为负）时进入 margin_deficit 状态，0=仅负数时
+		// 运行状态
+		`ALTER TABLE traders ADD COLUMN position_first_seen TEXT`,               // 持仓首次出现时间（JSON: symbol_side -> 毫秒时间戳）
+		`ALTER TABLE traders ADD COLUMN peak_equity REAL DEFAULT 0`,             // 账户净值历史峰值（最大回撤硬止损基准）
+		`ALTER TABLE traders ADD COLUMN drawdown_stop_armed BOOLEAN DEFAULT 1`,  // 最大回撤硬止损是否待命（触发后净值创新高才重新待命）
+		`ALTER TABLE traders ADD COLUMN first_trade_approved BOOLEAN DEFAULT 0`, // 首笔交易已人工审批并执行成功（之后的开仓自动执行）
+		`ALTER TABLE traders ADD COLUMN position_pyramid TEXT`,                  // 持仓加仓状态（JSON: symbol_side -> 加仓次数/整体止损/止盈）
+		`ALTER TABLE traders ADD COLUMN equity_bracket_hit TEXT DEFAULT ''`,     // 已触发的账户净值止盈/止损（take_profit/stop_loss），非空时暂停交易
 	}
 
 	for _, query := range alterQueries {
@@ -560,16 +734,34 @@ func (d *Database) initDefaultData(isMySQL bool) error {
 
 	// 初始化系统配置 - 创建所有字段，设置默认值，后续由config.json同步更新
 	systemConfigs := map[string]string{
-		"beta_mode":            "false",                                                                               // 默认关闭内测模式
-		"api_server_port":      "8080",                                                                                // 默认API端口
-		"use_default_coins":    "true",                                                                                // 默认使用内置币种列表
-		"default_coins":        `["BTCUSDT","ETHUSDT","SOLUSDT","BNBUSDT","XRPUSDT","DOGEUSDT","ADAUSDT","HYPEUSDT"]`, // 默认币种列表（JSON格式）
-		"max_daily_loss":       "10.0",                                                                                // 最大日损失百分比
-		"max_drawdown":         "20.0",                                                                                // 最大回撤百分比
-		"stop_trading_minutes": "60",                                                                                  // 停止交易时间（分钟）
-		"btc_eth_leverage":     "5",                                                                                   // BTC/ETH杠杆倍数
-		"altcoin_leverage":     "5",                                                                                   // 山寨币杠杆倍数
-		"jwt_secret":           "",                                                                                    // JWT密钥，默认为空，由config.json或系统生成
+		"beta_mode":                   "false",                                                                               // 默认关闭内测模式
+		"api_server_port":             "8080",                                                                                // 默认API端口
+		"use_default_coins":           "true",                                                                                // 默认使用内置币种列表
+		"default_coins":               `["BTCUSDT","ETHUSDT","SOLUSDT","BNBUSDT","XRPUSDT","DOGEUSDT","ADAUSDT","HYPEUSDT"]`, // 默认币种列表（JSON格式）
+		"max_daily_loss":              "10.0",                                                                                // 最大日损失百分比
+		"max_drawdown":                "20.0",                                                                                // 最大回撤百分比
+		"stop_trading_minutes":        "60",                                                                                  // 停止交易时间（分钟）
+		"btc_eth_leverage":            "5",                                                                                   // BTC/ETH杠杆倍数
+		"altcoin_leverage":            "5",                                                                                   // 山寨币杠杆倍数
+		"jwt_secret":                  "",                                                                                    // JWT密钥，默认为空，由config.json或系统生成
+		"max_custom_prompt_length":    "8000",                                                                                // 自定义prompt最大长度（字符数）
+		"max_traders_per_user":        "0",                                                                                   // 每个用户最多可创建的交易员数量（0=不限制，管理员不受限）
+		"decision_log_retention_days": "90",                                                                                  // 决策日志保留天数（0=不自动清理，始终保留最近100条）
+		"auto_resume_traders":         "true",                                                                                // 启动时自动恢复重启前处于运行状态的交易员
+		"max_btc_eth_leverage":        "125",                                                                                 // BTC/ETH杠杆上限（创建/更新交易员校验，执行时下调）
+		"max_altcoin_leverage":        "75",                                                                                  // 山寨币杠杆上限（创建/更新交易员校验，执行时下调）
+		"cors_allowed_origins":        DefaultCORSAllowedOrigins,                                                             // 允许跨域访问的来源（逗号分隔，"*" 表示任意来源且不允许携带凭证）
+		"order_rate_limits":           "",                                                                                    // 各交易所下单/撤单限速（如 "binance=10,bitget=5"，次/秒，未配置的交易所使用默认值）
+		"maintenance_mode":            "false",                                                                               // 平台维护模式（开启时所有交易员暂停开新仓，平仓和持仓保护照常执行）
+		"max_initial_balance":         "10000000",                                                                            // 创建交易员时初始余额上限（USDT，0=不限制）
+		"initial_balance_tolerance":   "50",                                                                                  // 初始余额与交易所实际余额偏差超过该百分比时需用户确认（0=不检查）
+		"blacklist_prune_minutes":     "10",                                                                                  // 登出token黑名单过期条目清理间隔（分钟）
+		"display_fx_rates":            "",                                                                                    // 法币展示汇率（1 USDT 折合的数量，如 "EUR=0.92,CNY=7.2"；BTC/ETH 按实时价格换算）
+		"dust_handling":               "close",                                                                               // 平仓后残留粉尘持仓的处理方式（close=再次平仓，mark=直接按已平仓处理，off=不处理）
+		"exchange_backoff_minutes":    "15",                                                                                  // 检测到交易所维护时暂停调用交易所和AI的时长（分钟），之后试探恢复
+		"recv_window_ms":              "50000",                                                                               // 币安/Aster 签名请求的 recvWindow（毫秒，最大60000），本地时钟漂移时放宽可减少时间戳错误
+		"cancel_opposite_orders":      "true",                                                                                // 反手/开反方向仓位前撤销该币种原方向的挂单（false=保留）
+		"max_request_body_bytes":      "1048576",                                                                             // API请求体大小上限（字节，超过返回413，0=不限制）
 	}
 
 	for key, value := range systemConfigs {
@@ -702,8 +894,15 @@ type AIModelConfig struct {
 	APIKey          string    `json:"apiKey"`
 	CustomAPIURL    string    `json:"customApiUrl"`
 	CustomModelName string    `json:"customModelName"`
+	MaxPromptTokens int       `json:"maxPromptTokens"` // Prompt token 预算，超出时自动裁剪低优先级内容（0=不限制）
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
+	// UndecryptableFields 已保存但无法解密的敏感字段（如密钥变更后），需用户重新输入
+	UndecryptableFields []string `json:"undecryptableFields,omitempty"`
+
+	// 自定义API（provider=custom）的附加请求头（加密存储）与认证方式（bearer/raw/api-key/x-api-key/none，空=bearer）
+	CustomHeaders    map[string]string `json:"customHeaders,omitempty"`
+	CustomAuthScheme string            `json:"customAuthScheme,omitempty"`
 }
 
 // ExchangeConfig 交易所配置
@@ -728,6 +927,8 @@ type ExchangeConfig struct {
 	AsterPrivateKey string    `json:"asterPrivateKey"`
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
+	// UndecryptableFields 已保存但无法解密的敏感字段（如密钥变更后），需用户重新输入
+	UndecryptableFields []string `json:"undecryptableFields,omitempty"`
 }
 
 // TraderRecord 交易员配置（数据库实体）
@@ -742,7 +943,8 @@ type TraderRecord struct {
 	IsRunning            bool      `json:"is_running"`
 	BTCETHLeverage       int       `json:"btc_eth_leverage"`       // BTC/ETH杠杆倍数
 	AltcoinLeverage      int       `json:"altcoin_leverage"`       // 山寨币杠杆倍数
-	TradingSymbols       string    `json:"trading_symbols"`        // 交易币种，逗号分隔
+	TradingSymbols       string    `json:"trading_symbols"`        // 交易币种，逗号分隔（显式配置时同时作为开仓白名单）
+	CandidateSymbols     string    `json:"candidate_symbols"`      // AI选币的候选币种池，逗号分隔（为空时沿用 trading_symbols）
 	UseCoinPool          bool      `json:"use_coin_pool"`          // 是否使用COIN POOL信号源
 	UseOITop             bool      `json:"use_oi_top"`             // 是否使用OI TOP信号源
 	CustomPrompt         string    `json:"custom_prompt"`          // 自定义交易策略prompt
@@ -752,8 +954,112 @@ type TraderRecord struct {
 	Category             string    `json:"category"`               // 交易员分类
 	TraderAccountID      string    `json:"trader_account_id"`      // 关联的交易员账号用户ID
 	OwnerUserID          string    `json:"owner_user_id"`          // 创建该交易员的用户ID
+	RequireStopLoss      bool      `json:"require_stop_loss"`      // 开仓必须带有效止损
+	DefaultStopLossPct   float64   `json:"default_stop_loss_pct"`  // 止损缺失时自动推导的最大亏损百分比（0=不推导）
 	CreatedAt            time.Time `json:"created_at"`
 	UpdatedAt            time.Time `json:"updated_at"`
+
+	// 交易选项
+	ExcludeHeldFromCandidates bool    `json:"exclude_held_from_candidates"` // 候选币种中剔除已持仓币种
+	AnalysisOnly              bool    `json:"analysis_only"`                // 仅分析模式（只记录决策不执行）
+	WarmupMinutes             int     `json:"warmup_minutes"`               // 启动后预热时长（分钟，预热期内只记录决策不执行）
+	SkipCycleIfBusy           bool    `json:"skip_cycle_if_busy"`           // 周期执行中时跳过新的触发（默认等待）
+	MaxPositionAgeHours       int     `json:"max_position_age_hours"`       // 持仓最长持有时间（小时，0=不限制）
+	AllowPyramiding           bool    `json:"allow_pyramiding"`             // 允许对同方向已有持仓加仓
+	MaxAddsPerPosition        int     `json:"max_adds_per_position"`        // 单个持仓最多加仓次数
+	EnforceDailyLossStop      bool    `json:"enforce_daily_loss_stop"`      // 日亏损硬止损（达到最大日亏损时平仓并暂停交易）
+	AllowFlip                 bool    `json:"allow_flip"`                   // 允许反手动作 flip_long/flip_short（默认关闭）
+	MinConfidence             int     `json:"min_confidence"`               // 开仓最低信心度（0-100，0表示不限制）
+	SignalBasePositionPct     float64 `json:"signal_base_position_pct"`     // 信号模式底仓占分配资金的百分比（默认20）
+	SignalDefaultAddPct       float64 `json:"signal_default_add_pct"`       // 信号模式补仓未指定比例时的默认百分比（默认10）
+	EquityTakeProfit          float64 `json:"equity_take_profit"`           // 账户净值止盈（USDT绝对值，净值达到即全部平仓并暂停，0表示关闭）
+	EquityStopLoss            float64 `json:"equity_stop_loss"`             // 账户净值止损（USDT绝对值，净值跌至即全部平仓并暂停，0表示关闭）
+	EquityTakeProfitPct       float64 `json:"equity_take_profit_pct"`       // 账户净值止盈百分比（相对初始余额，0表示关闭）
+	EquityStopLossPct         float64 `json:"equity_stop_loss_pct"`         // 账户净值止损百分比（相对初始余额，0表示关闭）
+	AutoReprotect             bool    `json:"auto_reprotect"`               // 每个决策周期后校验持仓保护单，缺失时按最近决策的止损/止盈补设
+	PublicDisplayName         string  `json:"public_display_name"`          // 公开接口（排行榜/竞赛）展示的名称，空表示使用交易员名称
+	PublicVisibility          bool    `json:"public_visibility"`            // 是否在公开接口（排行榜/竞赛/收益对比）中展示
+	BackupExchangeID          string  `json:"backup_exchange_id"`           // 备用交易所配置ID（同一交易所的另一组API密钥，主密钥持续鉴权/IP失败时切换），空表示不启用
+	TradingSchedule           string  `json:"trading_schedule"`             // 允许开新仓的时段（UTC，JSON数组），空表示全天可交易
+	IncludeOrderBookDepth     bool    `json:"include_orderbook_depth"`      // 决策上下文包含盘口深度（买一卖一、价差、附近挂单量）
+	SkipIfBTCMovePct          float64 `json:"skip_if_btc_move_pct"`         // BTC 1小时涨跌幅绝对值超过该百分比时跳过周期（0=不启用）
+	SkipIfFundingAbove        float64 `json:"skip_if_funding_above"`        // BTC 资金费率绝对值超过该百分比时跳过周期（0=不启用）
+	MaxOpenOrders             int     `json:"max_open_orders"`              // 单个币种最多同时存在的限价挂单数（不含止盈止损计划单），0=不限制
+	BreakevenAtProfitPct      float64 `json:"breakeven_at_profit_pct"`      // 持仓收益率达到该百分比后止损移至开仓价（0=不启用）
+	TrailStopAfterProfitPct   float64 `json:"trail_stop_after_profit_pct"`  // 持仓收益率达到该百分比后按峰值收益跟踪止损（0=不启用）
+	TrailLockFraction         float64 `json:"trail_lock_fraction"`          // 跟踪止损锁定的峰值收益比例（0-1）
+	MaxActionsPerCycle        int     `json:"max_actions_per_cycle"`        // 每个决策周期最多执行的动作数（0=不限制）
+
+	// 首笔交易人工审批
+	RequireFirstTradeApproval bool `json:"approval_required_first_trade"` // 首笔开仓需人工审批后执行
+
+	// AI调用频率下限
+	MinSecondsBetweenAICalls int `json:"min_seconds_between_ai_calls"` // 两次AI调用之间的最小间隔（秒，0=不限制）
+
+	// 单币种敞口上限
+	MaxPerSymbolExposurePct float64 `json:"max_per_symbol_exposure_pct"` // 单币种持仓价值占净值的最大百分比（0=不限制）
+
+	// 决策上下文附带最近平仓交易
+	IncludeRecentTrades bool `json:"include_recent_trades"` // 是否附带最近平仓交易（含开仓理由）
+	RecentTradesCount   int  `json:"recent_trades_count"`   // 附带的最近平仓交易笔数（最多10笔）
+
+	// 仓位计算基数
+	SizingBase string `json:"sizing_base"` // fixed=初始余额（默认），equity=账户实时净值（复利）
+
+	// AI采样参数（nil/0 表示使用提供商默认值）
+	AITemperature *float64 `json:"ai_temperature"`
+	AITopP        *float64 `json:"ai_top_p"`
+	AIMaxTokens   int      `json:"ai_max_tokens"`
+
+	// AI调用/解析失败时的处理策略
+	OnAIFailure string `json:"on_ai_failure"` // hold=保持现状（默认），flatten=平掉所有持仓，pause=暂停开新仓直到下一次AI决策成功
+
+	// 周期净值基准：按周/月将当时净值记录为新的周期基准（initial_balance 保持不变，用于全部时间的总收益）
+	BaselineResetPolicy string `json:"baseline_reset_policy"` // none=不滚动（默认），weekly=每周一，monthly=每月1日（UTC）
+
+	// 账户最大回撤硬止损：净值较历史峰值回撤达到阈值时停止开新仓并暂停交易（峰值持久化在 peak_equity）
+	EnforceMaxDrawdownStop bool    `json:"enforce_max_drawdown_stop"`
+	MaxDrawdownStopPct     float64 `json:"max_drawdown_stop_pct"` // 回撤阈值（百分比），0=使用系统 max_drawdown
+	DrawdownStopFlatten    bool    `json:"drawdown_stop_flatten"` // 触发时是否平掉所有持仓
+
+	// 止损接近提醒：标记价格距止损在该百分比以内时推送一次通知（0=关闭）
+	StopApproachAlertPct float64 `json:"stop_approach_alert_pct"`
+
+	// 最短持仓时间：持仓未满该分钟数时拒绝AI主动平仓/部分平仓/反手，止损和紧急平仓不受限制（0=不限制）
+	MinHoldingMinutes int `json:"min_holding_minutes"`
+
+	// 信号模式策略来源订阅：只跟随这些来源的策略（发件人邮箱或 @域名，逗号分隔；为空时跟随所有来源）
+	StrategySources string `json:"strategy_sources"`
+
+	// 多周期指标上下文：加入候选币种/持仓的指标（rsi,macd,ema_cross,atr）和K线周期（如 1h,4h），逗号分隔，任一为空时不加入
+	ContextIndicators string `json:"context_indicators"`
+	ContextTimeframes string `json:"context_timeframes"`
+
+	// 信号模式第一止盈分批止盈：到达 TakeProfits[0] 时部分平仓的百分比（0=关闭），以及是否将剩余仓位止损移到开仓价
+	TP1ClosePct      float64 `json:"tp1_close_pct"`
+	TP1BreakevenStop bool    `json:"tp1_breakeven_stop"`
+
+	// 目标权重再平衡模式：目标权重（JSON: 币种 -> 占仓位计算基数的百分比，为空时不启用）、容忍带（百分点）、检查间隔（分钟）
+	RebalanceTargets         string  `json:"rebalance_targets"`
+	RebalanceTolerancePct    float64 `json:"rebalance_tolerance_pct"`
+	RebalanceIntervalMinutes int     `json:"rebalance_interval_minutes"`
+
+	// 开仓止损/止盈以 OCO（一个成交撤销另一个）方式联动下单
+	UseOCO bool `json:"use_oco"`
+
+	// AI输出异常检测：连续 N 个周期无开仓 / 重复相同决策时告警（0=关闭），可选暂停开新仓
+	AIAnomalyIdleCycles   int  `json:"ai_anomaly_idle_cycles"`
+	AIAnomalyRepeatCycles int  `json:"ai_anomaly_repeat_cycles"`
+	AIAnomalyPause        bool `json:"ai_anomaly_pause"`
+
+	// 默认止损/止盈（JSON: 币种或 default -> {"sl": 百分比, "tp": 百分比}），决策缺少保护价位时按开仓价推导
+	ProtectiveDefaults string `json:"protective_defaults"`
+
+	// 信号模式仓位对账间隔（分钟，0=默认30），对账时关闭仓位已结束的策略并提示孤儿持仓
+	PositionAuditMinutes int `json:"position_audit_minutes"`
+
+	// 可用余额下限（USDT，0=仅在可用余额为负时），低于该值时进入 margin_deficit 状态：暂停开新仓、优先平仓并通知
+	MinAvailableBalance float64 `json:"min_available_balance"`
 }
 
 // StrategyOrder 策略委托单记录
@@ -937,6 +1243,9 @@ func (d *Database) GetAIModels(userID string) ([]*AIModelConfig, error) {
 		       COALESCE(api_key, '') as api_key,
 		       COALESCE(custom_api_url, '') as custom_api_url,
 		       COALESCE(custom_model_name, '') as custom_model_name,
+		       COALESCE(max_prompt_tokens, 0) as max_prompt_tokens,
+		       COALESCE(custom_headers, '') as custom_headers,
+		       COALESCE(custom_auth_scheme, '') as custom_auth_scheme,
 		       created_at, updated_at
 		FROM ai_models WHERE user_id = ? ORDER BY id
 	`, userID)
@@ -949,9 +1258,11 @@ func (d *Database) GetAIModels(userID string) ([]*AIModelConfig, error) {
 	models := make([]*AIModelConfig, 0)
 	for rows.Next() {
 		var model AIModelConfig
+		var customHeaders string
 		err := rows.Scan(
 			&model.ID, &model.UserID, &model.Name, &model.Provider,
-			&model.Enabled, &model.APIKey, &model.CustomAPIURL, &model.CustomModelName,
+			&model.Enabled, &model.APIKey, &model.CustomAPIURL, &model.CustomModelName, &model.MaxPromptTokens,
+			&customHeaders, &model.CustomAuthScheme,
 			&model.CreatedAt, &model.UpdatedAt,
 		)
 		if err != nil {
@@ -959,7 +1270,14 @@ func (d *Database) GetAIModels(userID string) ([]*AIModelConfig, error) {
 		}
 		// 解密API Key（如果为空字符串则跳过解密）
 		if model.APIKey != "" {
-			model.APIKey = d.decryptSensitiveData(model.APIKey)
+			var ok bool
+			if model.APIKey, ok = d.decryptSensitiveField(model.APIKey); !ok {
+				model.UndecryptableFields = append(model.UndecryptableFields, "api_key")
+			}
+		}
+		var ok bool
+		if model.CustomHeaders, ok = d.decodeCustomHeaders(customHeaders); !ok {
+			model.UndecryptableFields = append(model.UndecryptableFields, "custom_headers")
 		}
 		models = append(models, &model)
 	}
@@ -967,8 +1285,36 @@ func (d *Database) GetAIModels(userID string) ([]*AIModelConfig, error) {
 	return models, nil
 }
 
+// updateExistingAIModel 更新已有的AI模型配置
+// 🔒 apiKey 为空时保留已保存的密钥，customHeaders 为 nil 时保留已保存的请求头（空 map 表示清除），用户只修改其他字段时无需重新输入
+func (d *Database) updateExistingAIModel(userID, existingID string, enabled bool, apiKey, customAPIURL, customModelName string, maxPromptTokens int, customHeaders map[string]string, customAuthScheme string) error {
+	setClauses := []string{"enabled = ?", "custom_api_url = ?", "custom_model_name = ?", "max_prompt_tokens = ?", "custom_auth_scheme = ?", fmt.Sprintf("updated_at = %s", d.getTimeFunc())}
+	args := []interface{}{enabled, customAPIURL, customModelName, maxPromptTokens, customAuthScheme}
+	if apiKey != "" {
+		setClauses = append(setClauses, "api_key = ?")
+		args = append(args, d.encryptSensitiveData(apiKey))
+	}
+	if customHeaders != nil {
+		setClauses = append(setClauses, "custom_headers = ?")
+		args = append(args, d.encodeCustomHeaders(customHeaders))
+	}
+	args = append(args, existingID, userID)
+	_, err := d.db.Exec(fmt.Sprintf(`
+		UPDATE ai_models SET %s
+		WHERE id = ? AND user_id = ?
+	`, strings.Join(setClauses, ", ")), args...)
+	return err
+}
+
 // UpdateAIModel 更新AI模型配置，如果不存在则创建用户特定配置
-func (d *Database) UpdateAIModel(userID, id string, enabled bool, apiKey, customAPIURL, customModelName string) error {
+func (d *Database) UpdateAIModel(userID, id string, enabled bool, apiKey, customAPIURL, customModelName string, maxPromptTokens int, customHeaders map[string]string, customAuthScheme string) error {
+	unlock := d.lockSensitiveWrite()
+	defer unlock()
+
+	if maxPromptTokens < 0 {
+		maxPromptTokens = 0
+	}
+
 	// 先尝试精确匹配 ID（新版逻辑，支持多个相同 provider 的模型）
 	var existingID string
 	err := d.db.QueryRow(`
@@ -977,12 +1323,7 @@ func (d *Database) UpdateAIModel(userID, id string, enabled bool, apiKey, custom
 
 	if err == nil {
 		// 找到了现有配置（精确匹配 ID），更新它
-		encryptedAPIKey := d.encryptSensitiveData(apiKey)
-		_, err = d.db.Exec(fmt.Sprintf(`
-			UPDATE ai_models SET enabled = ?, api_key = ?, custom_api_url = ?, custom_model_name = ?, updated_at = %s
-			WHERE id = ? AND user_id = ?
-		`, d.getTimeFunc()), enabled, encryptedAPIKey, customAPIURL, customModelName, existingID, userID)
-		return err
+		return d.updateExistingAIModel(userID, existingID, enabled, apiKey, customAPIURL, customModelName, maxPromptTokens, customHeaders, customAuthScheme)
 	}
 
 	// ID 不存在，尝试兼容旧逻辑：将 id 作为 provider 查找
@@ -994,12 +1335,7 @@ func (d *Database) UpdateAIModel(userID, id string, enabled bool, apiKey, custom
 	if err == nil {
 		// 找到了现有配置（通过 provider 匹配，兼容旧版），更新它
 		log.Printf("✓ 通过 provider 匹配更新模型: %s -> %s（建议前端使用完整ID）", provider, existingID)
-		encryptedAPIKey := d.encryptSensitiveData(apiKey)
-		_, err = d.db.Exec(fmt.Sprintf(`
-			UPDATE ai_models SET enabled = ?, api_key = ?, custom_api_url = ?, custom_model_name = ?, updated_at = %s
-			WHERE id = ? AND user_id = ?
-		`, d.getTimeFunc()), enabled, encryptedAPIKey, customAPIURL, customModelName, existingID, userID)
-		return err
+		return d.updateExistingAIModel(userID, existingID, enabled, apiKey, customAPIURL, customModelName, maxPromptTokens, customHeaders, customAuthScheme)
 	}
 
 	// 没有找到任何现有配置，创建新的
@@ -1045,9 +1381,9 @@ func (d *Database) UpdateAIModel(userID, id string, enabled bool, apiKey, custom
 	encryptedAPIKey := d.encryptSensitiveData(apiKey)
 	timeFunc := d.getTimeFunc()
 	_, err = d.db.Exec(fmt.Sprintf(`
-		INSERT INTO ai_models (id, user_id, name, provider, enabled, api_key, custom_api_url, custom_model_name, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, %s, %s)
-	`, timeFunc, timeFunc), newModelID, userID, name, provider, enabled, encryptedAPIKey, customAPIURL, customModelName)
+		INSERT INTO ai_models (id, user_id, name, provider, enabled, api_key, custom_api_url, custom_model_name, max_prompt_tokens, custom_headers, custom_auth_scheme, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, %s, %s)
+	`, timeFunc, timeFunc), newModelID, userID, name, provider, enabled, encryptedAPIKey, customAPIURL, customModelName, maxPromptTokens, d.encodeCustomHeaders(customHeaders), customAuthScheme)
 
 	return err
 }
@@ -1095,18 +1431,23 @@ func (d *Database) GetExchanges(userID string) ([]*ExchangeConfig, error) {
 			return nil, err
 		}
 
-		// 解密敏感字段（如果为空字符串则跳过解密）
-		if exchange.APIKey != "" {
-			exchange.APIKey = d.decryptSensitiveData(exchange.APIKey)
-		}
-		if exchange.SecretKey != "" {
-			exchange.SecretKey = d.decryptSensitiveData(exchange.SecretKey)
-		}
-		if exchange.AsterPrivateKey != "" {
-			exchange.AsterPrivateKey = d.decryptSensitiveData(exchange.AsterPrivateKey)
-		}
-		if exchange.Passphrase != "" {
-			exchange.Passphrase = d.decryptSensitiveData(exchange.Passphrase)
+		// 解密敏感字段（如果为空字符串则跳过解密），记录无法解密的字段
+		for _, f := range []struct {
+			column string
+			value  *string
+		}{
+			{"api_key", &exchange.APIKey},
+			{"secret_key", &exchange.SecretKey},
+			{"aster_private_key", &exchange.AsterPrivateKey},
+			{"passphrase", &exchange.Passphrase},
+		} {
+			if *f.value == "" {
+				continue
+			}
+			var ok bool
+			if *f.value, ok = d.decryptSensitiveField(*f.value); !ok {
+				exchange.UndecryptableFields = append(exchange.UndecryptableFields, f.column)
+			}
 		}
 
 		// 如果数据库中有provider，使用数据库值，否则推导
@@ -1128,6 +1469,9 @@ func (d *Database) GetExchanges(userID string) ([]*ExchangeConfig, error) {
 // UpdateExchange 更新交易所配置，如果不存在则创建用户特定配置
 // 🔒 安全特性：空值不会覆盖现有的敏感字段（api_key, secret_key, aster_private_key）
 func (d *Database) UpdateExchange(userID, id string, enabled bool, apiKey, secretKey, passphrase string, testnet bool, hyperliquidWalletAddr, asterUser, asterSigner, asterPrivateKey, provider, label string) error {
+	unlock := d.lockSensitiveWrite()
+	defer unlock()
+
 	log.Printf("🔧 UpdateExchange: userID=%s, id=%s, enabled=%v, provider=%s, label=%s", userID, id, enabled, provider, label)
 
 	// 构建动态 UPDATE SET 子句
@@ -1244,6 +1588,9 @@ func (d *Database) UpdateExchange(userID, id string, enabled bool, apiKey, secre
 
 // CreateAIModel 创建AI模型配置
 func (d *Database) CreateAIModel(userID, id, name, provider string, enabled bool, apiKey, customAPIURL string) error {
+	unlock := d.lockSensitiveWrite()
+	defer unlock()
+
 	timeFunc := d.getTimeFunc()
 	encryptedAPIKey := d.encryptSensitiveData(apiKey)
 
@@ -1266,6 +1613,9 @@ func (d *Database) CreateAIModel(userID, id, name, provider string, enabled bool
 
 // CreateExchange 创建交易所配置
 func (d *Database) CreateExchange(userID, id, name, typ string, enabled bool, apiKey, secretKey string, testnet bool, hyperliquidWalletAddr, asterUser, asterSigner, asterPrivateKey string) error {
+	unlock := d.lockSensitiveWrite()
+	defer unlock()
+
 	// 加密敏感字段
 	encryptedAPIKey := d.encryptSensitiveData(apiKey)
 	encryptedSecretKey := d.encryptSensitiveData(secretKey)
@@ -1300,12 +1650,49 @@ func (d *Database) CreateTrader(trader *TraderRecord) error {
 		ownerUserID = trader.UserID // 默认使用user_id作为owner_user_id
 	}
 	_, err := d.db.Exec(`
-		INSERT INTO traders (id, user_id, name, ai_model_id, exchange_id, initial_balance, scan_interval_minutes, is_running, btc_eth_leverage, altcoin_leverage, trading_symbols, use_coin_pool, use_oi_top, custom_prompt, override_base_prompt, system_prompt_template, is_cross_margin, category, owner_user_id)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, trader.ID, trader.UserID, trader.Name, trader.AIModelID, trader.ExchangeID, trader.InitialBalance, trader.ScanIntervalMinutes, trader.IsRunning, trader.BTCETHLeverage, trader.AltcoinLeverage, trader.TradingSymbols, trader.UseCoinPool, trader.UseOITop, trader.CustomPrompt, trader.OverrideBasePrompt, trader.SystemPromptTemplate, trader.IsCrossMargin, category, ownerUserID)
+		INSERT INTO traders (id, user_id, name, ai_model_id, exchange_id, initial_balance, scan_interval_minutes, is_running, btc_eth_leverage, altcoin_leverage, trading_symbols, use_coin_pool, use_oi_top, custom_prompt, override_base_prompt, system_prompt_template, is_cross_margin, category, owner_user_id, require_stop_loss, default_stop_loss_pct, exclude_held_from_candidates, analysis_only, warmup_minutes, skip_cycle_if_busy, max_position_age_hours, allow_pyramiding, max_adds_per_position, enforce_daily_loss_stop, allow_flip, min_confidence, signal_base_position_pct, signal_default_add_pct, equity_take_profit, equity_stop_loss, equity_take_profit_pct, equity_stop_loss_pct, auto_reprotect, public_display_name, public_visibility, backup_exchange_id, trading_schedule, include_orderbook_depth, skip_if_btc_move_pct, skip_if_funding_above, max_open_orders, breakeven_at_profit_pct, trail_stop_after_profit_pct, trail_lock_fraction, max_actions_per_cycle, approval_required_first_trade, min_seconds_between_ai_calls, max_per_symbol_exposure_pct, include_recent_trades, recent_trades_count, sizing_base, ai_temperature, ai_top_p, ai_max_tokens, on_ai_failure, baseline_reset_policy, enforce_max_drawdown_stop, max_drawdown_stop_pct, drawdown_stop_flatten, stop_approach_alert_pct, candidate_symbols, min_holding_minutes, strategy_sources, context_indicators, context_timeframes, tp1_close_pct, tp1_breakeven_stop, rebalance_targets, rebalance_tolerance_pct, rebalance_interval_minutes, use_oco, ai_anomaly_idle_cycles, ai_anomaly_repeat_cycles, ai_anomaly_pause, protective_defaults, position_audit_minutes, min_available_balance)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, trader.ID, trader.UserID, trader.Name, trader.AIModelID, trader.ExchangeID, trader.InitialBalance, trader.ScanIntervalMinutes, trader.IsRunning, trader.BTCETHLeverage, trader.AltcoinLeverage, trader.TradingSymbols, trader.UseCoinPool, trader.UseOITop, trader.CustomPrompt, trader.OverrideBasePrompt, trader.SystemPromptTemplate, trader.IsCrossMargin, category, ownerUserID, trader.RequireStopLoss, trader.DefaultStopLossPct, trader.ExcludeHeldFromCandidates, trader.AnalysisOnly, trader.WarmupMinutes, trader.SkipCycleIfBusy, trader.MaxPositionAgeHours, trader.AllowPyramiding, trader.MaxAddsPerPosition, trader.EnforceDailyLossStop, trader.AllowFlip, trader.MinConfidence, trader.SignalBasePositionPct, trader.SignalDefaultAddPct, trader.EquityTakeProfit, trader.EquityStopLoss, trader.EquityTakeProfitPct, trader.EquityStopLossPct, trader.AutoReprotect, trader.PublicDisplayName, trader.PublicVisibility, trader.BackupExchangeID, trader.TradingSchedule, trader.IncludeOrderBookDepth, trader.SkipIfBTCMovePct, trader.SkipIfFundingAbove, trader.MaxOpenOrders, trader.BreakevenAtProfitPct, trader.TrailStopAfterProfitPct, trader.TrailLockFraction, trader.MaxActionsPerCycle, trader.RequireFirstTradeApproval, trader.MinSecondsBetweenAICalls, trader.MaxPerSymbolExposurePct, trader.IncludeRecentTrades, trader.RecentTradesCount, trader.SizingBase, trader.AITemperature, trader.AITopP, trader.AIMaxTokens, trader.OnAIFailure, trader.BaselineResetPolicy, trader.EnforceMaxDrawdownStop, trader.MaxDrawdownStopPct, trader.DrawdownStopFlatten, trader.StopApproachAlertPct, trader.CandidateSymbols, trader.MinHoldingMinutes, trader.StrategySources, trader.ContextIndicators, trader.ContextTimeframes, trader.TP1ClosePct, trader.TP1BreakevenStop, trader.RebalanceTargets, trader.RebalanceTolerancePct, trader.RebalanceIntervalMinutes, trader.UseOCO, trader.AIAnomalyIdleCycles, trader.AIAnomalyRepeatCycles, trader.AIAnomalyPause, trader.ProtectiveDefaults, trader.PositionAuditMinutes, trader.MinAvailableBalance)
 	return err
 }
 
+// CountTradersByOwner 统计用户拥有的交易员数量（owner_user_id为空时按user_id计算）
+func (d *Database) CountTradersByOwner(userID string) (int, error) {
+	var count int
+	err := d.db.QueryRow(`
+		SELECT COUNT(*) FROM traders
+		WHERE owner_user_id = ? OR ((owner_user_id IS NULL OR owner_user_id = '') AND user_id = ?)
+	`, userID, userID).Scan(&count)
+	return count, err
+}
+
+// GetMaxTradersPerUser 获取每个用户可创建的最大交易员数量（0表示不限制）
+func (d *Database) GetMaxTradersPerUser() int {
+	value, err := d.GetSystemConfig("max_traders_per_user")
+	if err != nil || value == "" {
+		return 0
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 0 {
+		return 0
+	}
+	return limit
+}
+
+// CheckTraderQuota 检查用户是否还能创建新的交易员
+// 返回当前数量和上限；超出上限时返回错误（上限为0表示不限制）
+func (d *Database) CheckTraderQuota(userID string) (count, limit int, err error) {
+	count, err = d.CountTradersByOwner(userID)
+	if err != nil {
+		return 0, 0, err
+	}
+	limit = d.GetMaxTradersPerUser()
+	if limit > 0 && count >= limit {
+		return count, limit, fmt.Errorf("交易员数量已达上限（%d/%d），请删除不用的交易员后再创建", count, limit)
+	}
+	return count, limit, nil
+}
+
 // GetTraders 获取用户的交易员
 func (d *Database) GetTraders(userID string) ([]*TraderRecord, error) {
 	rows, err := d.db.Query(`
@@ -1319,6 +1706,65 @@ func (d *Database) GetTraders(userID string) ([]*TraderRecord, error) {
 		       COALESCE(category, '') as category,
 		       COALESCE(trader_account_id, '') as trader_account_id,
 		       COALESCE(owner_user_id, '') as owner_user_id,
+		       COALESCE(require_stop_loss, 0) as require_stop_loss,
+		       COALESCE(default_stop_loss_pct, 0) as default_stop_loss_pct,
+		       COALESCE(exclude_held_from_candidates, 0) as exclude_held_from_candidates,
+		       COALESCE(analysis_only, 0) as analysis_only,
+		       COALESCE(warmup_minutes, 0) as warmup_minutes,
+		       COALESCE(skip_cycle_if_busy, 0) as skip_cycle_if_busy,
+		       COALESCE(max_position_age_hours, 0) as max_position_age_hours,
+		       COALESCE(allow_pyramiding, 0) as allow_pyramiding,
+		       COALESCE(max_adds_per_position, 2) as max_adds_per_position,
+		       COALESCE(enforce_daily_loss_stop, 0) as enforce_daily_loss_stop,
+		       COALESCE(allow_flip, 0) as allow_flip,
+		       COALESCE(min_confidence, 0) as min_confidence,
+		       COALESCE(signal_base_position_pct, 20) as signal_base_position_pct,
+		       COALESCE(signal_default_add_pct, 10) as signal_default_add_pct,
+		       COALESCE(equity_take_profit, 0) as equity_take_profit,
+		       COALESCE(equity_stop_loss, 0) as equity_stop_loss,
+		       COALESCE(equity_take_profit_pct, 0) as equity_take_profit_pct,
+		       COALESCE(equity_stop_loss_pct, 0) as equity_stop_loss_pct,
+		       COALESCE(auto_reprotect, 0) as auto_reprotect,
+		       COALESCE(public_display_name, '') as public_display_name,
+		       COALESCE(public_visibility, 1) as public_visibility,
+		       COALESCE(backup_exchange_id, '') as backup_exchange_id,
+		       COALESCE(trading_schedule, '') as trading_schedule,
+		       COALESCE(include_orderbook_depth, 0) as include_orderbook_depth,
+		       COALESCE(skip_if_btc_move_pct, 0) as skip_if_btc_move_pct,
+		       COALESCE(skip_if_funding_above, 0) as skip_if_funding_above,
+		       COALESCE(max_open_orders, 10) as max_open_orders,
+		       COALESCE(breakeven_at_profit_pct, 0) as breakeven_at_profit_pct,
+		       COALESCE(trail_stop_after_profit_pct, 0) as trail_stop_after_profit_pct,
+		       COALESCE(trail_lock_fraction, 0.5) as trail_lock_fraction,
+		       COALESCE(max_actions_per_cycle, 10) as max_actions_per_cycle,
+		       COALESCE(approval_required_first_trade, 0) as approval_required_first_trade,
+		       COALESCE(min_seconds_between_ai_calls, 0) as min_seconds_between_ai_calls,
+		       COALESCE(max_per_symbol_exposure_pct, 0) as max_per_symbol_exposure_pct,
+		       COALESCE(include_recent_trades, 1) as include_recent_trades,
+		       COALESCE(recent_trades_count, 5) as recent_trades_count,
+		       COALESCE(sizing_base, 'fixed') as sizing_base,
+		       ai_temperature, ai_top_p, COALESCE(ai_max_tokens, 0) as ai_max_tokens,
+		       COALESCE(on_ai_failure, 'hold') as on_ai_failure,
+		       COALESCE(baseline_reset_policy, 'none') as baseline_reset_policy,
+		       COALESCE(enforce_max_drawdown_stop, 0) as enforce_max_drawdown_stop, COALESCE(max_drawdown_stop_pct, 0) as max_drawdown_stop_pct, COALESCE(drawdown_stop_flatten, 0) as drawdown_stop_flatten,
+		       COALESCE(stop_approach_alert_pct, 0) as stop_approach_alert_pct,
+		       COALESCE(candidate_symbols, '') as candidate_symbols,
+		       COALESCE(min_holding_minutes, 0) as min_holding_minutes,
+		       COALESCE(strategy_sources, '') as strategy_sources,
+		       COALESCE(context_indicators, '') as context_indicators,
+		       COALESCE(context_timeframes, '') as context_timeframes,
+		       COALESCE(tp1_close_pct, 0) as tp1_close_pct,
+		       COALESCE(tp1_breakeven_stop, 0) as tp1_breakeven_stop,
+		       COALESCE(rebalance_targets, '') as rebalance_targets,
+		       COALESCE(rebalance_tolerance_pct, 0) as rebalance_tolerance_pct,
+		       COALESCE(rebalance_interval_minutes, 0) as rebalance_interval_minutes,
+		       COALESCE(use_oco, 0) as use_oco,
+		       COALESCE(ai_anomaly_idle_cycles, 0) as ai_anomaly_idle_cycles,
+		       COALESCE(ai_anomaly_repeat_cycles, 0) as ai_anomaly_repeat_cycles,
+		       COALESCE(ai_anomaly_pause, 0) as ai_anomaly_pause,
+		       COALESCE(protective_defaults, '') as protective_defaults,
+		       COALESCE(position_audit_minutes, 0) as position_audit_minutes,
+		       COALESCE(min_available_balance, 0) as min_available_balance,
 		       created_at, updated_at
 		FROM traders WHERE user_id = ? ORDER BY created_at DESC
 	`, userID)
@@ -1338,6 +1784,65 @@ func (d *Database) GetTraders(userID string) ([]*TraderRecord, error) {
 			&trader.CustomPrompt, &trader.OverrideBasePrompt, &trader.SystemPromptTemplate,
 			&trader.IsCrossMargin,
 			&trader.Category, &trader.TraderAccountID, &trader.OwnerUserID,
+			&trader.RequireStopLoss,
+			&trader.DefaultStopLossPct,
+			&trader.ExcludeHeldFromCandidates,
+			&trader.AnalysisOnly,
+			&trader.WarmupMinutes,
+			&trader.SkipCycleIfBusy,
+			&trader.MaxPositionAgeHours,
+			&trader.AllowPyramiding,
+			&trader.MaxAddsPerPosition,
+			&trader.EnforceDailyLossStop,
+			&trader.AllowFlip,
+			&trader.MinConfidence,
+			&trader.SignalBasePositionPct,
+			&trader.SignalDefaultAddPct,
+			&trader.EquityTakeProfit,
+			&trader.EquityStopLoss,
+			&trader.EquityTakeProfitPct,
+			&trader.EquityStopLossPct,
+			&trader.AutoReprotect,
+			&trader.PublicDisplayName,
+			&trader.PublicVisibility,
+			&trader.BackupExchangeID,
+			&trader.TradingSchedule,
+			&trader.IncludeOrderBookDepth,
+			&trader.SkipIfBTCMovePct,
+			&trader.SkipIfFundingAbove,
+			&trader.MaxOpenOrders,
+			&trader.BreakevenAtProfitPct,
+			&trader.TrailStopAfterProfitPct,
+			&trader.TrailLockFraction,
+			&trader.MaxActionsPerCycle,
+			&trader.RequireFirstTradeApproval,
+			&trader.MinSecondsBetweenAICalls,
+			&trader.MaxPerSymbolExposurePct,
+			&trader.IncludeRecentTrades,
+			&trader.RecentTradesCount,
+			&trader.SizingBase,
+			&trader.AITemperature, &trader.AITopP, &trader.AIMaxTokens,
+			&trader.OnAIFailure,
+			&trader.BaselineResetPolicy,
+			&trader.EnforceMaxDrawdownStop, &trader.MaxDrawdownStopPct, &trader.DrawdownStopFlatten,
+			&trader.StopApproachAlertPct,
+			&trader.CandidateSymbols,
+			&trader.MinHoldingMinutes,
+			&trader.StrategySources,
+			&trader.ContextIndicators,
+			&trader.ContextTimeframes,
+			&trader.TP1ClosePct,
+			&trader.TP1BreakevenStop,
+			&trader.RebalanceTargets,
+			&trader.RebalanceTolerancePct,
+			&trader.RebalanceIntervalMinutes,
+			&trader.UseOCO,
+			&trader.AIAnomalyIdleCycles,
+			&trader.AIAnomalyRepeatCycles,
+			&trader.AIAnomalyPause,
+			&trader.ProtectiveDefaults,
+			&trader.PositionAuditMinutes,
+			&trader.MinAvailableBalance,
 			&trader.CreatedAt, &trader.UpdatedAt,
 		)
 		if err != nil {
@@ -1362,12 +1867,49 @@ func (d *Database) UpdateTrader(trader *TraderRecord) error {
 			name = ?, ai_model_id = ?, exchange_id = ?, initial_balance = ?,
 			scan_interval_minutes = ?, btc_eth_leverage = ?, altcoin_leverage = ?,
 			trading_symbols = ?, custom_prompt = ?, override_base_prompt = ?,
-			system_prompt_template = ?, is_cross_margin = ?, updated_at = %s
+			system_prompt_template = ?, is_cross_margin = ?,
+			require_stop_loss = ?, default_stop_loss_pct = ?,
+			exclude_held_from_candidates = ?, analysis_only = ?, warmup_minutes = ?,
+			skip_cycle_if_busy = ?, max_position_age_hours = ?,
+			allow_pyramiding = ?, max_adds_per_position = ?,
+			enforce_daily_loss_stop = ?, allow_flip = ?, min_confidence = ?,
+			signal_base_position_pct = ?, signal_default_add_pct = ?,
+			equity_take_profit = ?, equity_stop_loss = ?,
+			equity_take_profit_pct = ?, equity_stop_loss_pct = ?,
+			auto_reprotect = ?, public_display_name = ?, public_visibility = ?,
+			backup_exchange_id = ?, trading_schedule = ?,
+			include_orderbook_depth = ?, skip_if_btc_move_pct = ?,
+			skip_if_funding_above = ?, max_open_orders = ?,
+			breakeven_at_profit_pct = ?, trail_stop_after_profit_pct = ?,
+			trail_lock_fraction = ?, max_actions_per_cycle = ?,
+			approval_required_first_trade = ?, min_seconds_between_ai_calls = ?,
+			max_per_symbol_exposure_pct = ?, include_recent_trades = ?,
+			recent_trades_count = ?, sizing_base = ?, ai_temperature = ?, ai_top_p = ?, ai_max_tokens = ?, on_ai_failure = ?, baseline_reset_policy = ?,
+			enforce_max_drawdown_stop = ?, max_drawdown_stop_pct = ?, drawdown_stop_flatten = ?, stop_approach_alert_pct = ?, candidate_symbols = ?, min_holding_minutes = ?, strategy_sources = ?, context_indicators = ?, context_timeframes = ?, tp1_close_pct = ?, tp1_breakeven_stop = ?, rebalance_targets = ?, rebalance_tolerance_pct = ?, rebalance_interval_minutes = ?, use_oco = ?, ai_anomaly_idle_cycles = ?, ai_anomaly_repeat_cycles = ?, ai_anomaly_pause = ?, protective_defaults = ?, position_audit_minutes = ?, min_available_balance = ?, updated_at = %s
 		WHERE id = ? AND user_id = ?
 	`, d.getTimeFunc()), trader.Name, trader.AIModelID, trader.ExchangeID, trader.InitialBalance,
 		trader.ScanIntervalMinutes, trader.BTCETHLeverage, trader.AltcoinLeverage,
 		trader.TradingSymbols, trader.CustomPrompt, trader.OverrideBasePrompt,
-		trader.SystemPromptTemplate, trader.IsCrossMargin, trader.ID, trader.UserID)
+		trader.SystemPromptTemplate, trader.IsCrossMargin,
+		trader.RequireStopLoss, trader.DefaultStopLossPct,
+		trader.ExcludeHeldFromCandidates, trader.AnalysisOnly,
+		trader.WarmupMinutes, trader.SkipCycleIfBusy,
+		trader.MaxPositionAgeHours, trader.AllowPyramiding,
+		trader.MaxAddsPerPosition, trader.EnforceDailyLossStop,
+		trader.AllowFlip, trader.MinConfidence,
+		trader.SignalBasePositionPct, trader.SignalDefaultAddPct,
+		trader.EquityTakeProfit, trader.EquityStopLoss,
+		trader.EquityTakeProfitPct, trader.EquityStopLossPct,
+		trader.AutoReprotect, trader.PublicDisplayName,
+		trader.PublicVisibility, trader.BackupExchangeID,
+		trader.TradingSchedule, trader.IncludeOrderBookDepth,
+		trader.SkipIfBTCMovePct, trader.SkipIfFundingAbove,
+		trader.MaxOpenOrders, trader.BreakevenAtProfitPct,
+		trader.TrailStopAfterProfitPct, trader.TrailLockFraction,
+		trader.MaxActionsPerCycle, trader.RequireFirstTradeApproval,
+		trader.MinSecondsBetweenAICalls, trader.MaxPerSymbolExposurePct,
+		trader.IncludeRecentTrades, trader.RecentTradesCount, trader.SizingBase, trader.AITemperature, trader.AITopP, trader.AIMaxTokens, trader.OnAIFailure, trader.BaselineResetPolicy,
+		trader.EnforceMaxDrawdownStop, trader.MaxDrawdownStopPct, trader.DrawdownStopFlatten, trader.StopApproachAlertPct, trader.CandidateSymbols, trader.MinHoldingMinutes, trader.StrategySources, trader.ContextIndicators, trader.ContextTimeframes, trader.TP1ClosePct, trader.TP1BreakevenStop, trader.RebalanceTargets, trader.RebalanceTolerancePct, trader.RebalanceIntervalMinutes, trader.UseOCO, trader.AIAnomalyIdleCycles, trader.AIAnomalyRepeatCycles, trader.AIAnomalyPause, trader.ProtectiveDefaults, trader.PositionAuditMinutes, trader.MinAvailableBalance, trader.ID, trader.UserID)
 	return err
 }
 
@@ -1377,6 +1919,31 @@ func (d *Database) UpdateTraderCustomPrompt(userID, id string, customPrompt stri
 	return err
 }
 
+// AdjustTraderInitialBalance 按外部充值/提现金额增量调整交易员初始余额
+// 与 UpdateTraderInitialBalance 不同，只叠加资金流水确认的转入/转出净额，不会覆盖用户设置的盈亏基准
+func (d *Database) AdjustTraderInitialBalance(id string, delta float64) error {
+	_, err := d.db.Exec(`UPDATE traders SET initial_balance = initial_balance + ? WHERE id = ?`, delta, id)
+	return err
+}
+
+// UpdateTraderAnalysisOnly 切换交易员仅分析模式
+func (d *Database) UpdateTraderAnalysisOnly(id string, analysisOnly bool) error {
+	_, err := d.db.Exec(`UPDATE traders SET analysis_only = ? WHERE id = ?`, analysisOnly, id)
+	return err
+}
+
+// UpdateTraderSymbols 更新交易员的候选币种池（candidate_symbols）和交易币种白名单（trading_symbols）
+func (d *Database) UpdateTraderSymbols(id, candidateSymbols, tradingSymbols string) error {
+	_, err := d.db.Exec(`UPDATE traders SET candidate_symbols = ?, trading_symbols = ? WHERE id = ?`, candidateSymbols, tradingSymbols, id)
+	return err
+}
+
+// UpdateTraderLeverage 更新交易员杠杆配置
+func (d *Database) UpdateTraderLeverage(id string, btcEthLeverage, altcoinLeverage int) error {
+	_, err := d.db.Exec(`UPDATE traders SET btc_eth_leverage = ?, altcoin_leverage = ? WHERE id = ?`, btcEthLeverage, altcoinLeverage, id)
+	return err
+}
+
 // UpdateTraderInitialBalance 更新交易员初始余额（用于自动同步交易所实际余额）
 func (d *Database) UpdateTraderInitialBalance(userID, id string, newBalance float64) error {
 	// 🚫 严格禁止：为了防止意外覆盖用户设置的初始余额，此函数已被禁用
@@ -1415,6 +1982,7 @@ func (d *Database) GetTraderConfig(userID, traderID string) (*TraderRecord, *AIM
 	var exchange ExchangeConfig
 
 	var exchangeProvider, exchangeLabel string
+	var customHeaders string
 
 	err := d.db.QueryRow(`
 		SELECT
@@ -1428,10 +1996,72 @@ func (d *Database) GetTraderConfig(userID, traderID string) (*TraderRecord, *AIM
 			COALESCE(t.override_base_prompt, 0) as override_base_prompt,
 			COALESCE(t.system_prompt_template, 'default') as system_prompt_template,
 			COALESCE(t.is_cross_margin, 1) as is_cross_margin,
+			COALESCE(t.require_stop_loss, 0) as require_stop_loss,
+			COALESCE(t.default_stop_loss_pct, 0) as default_stop_loss_pct,
+			COALESCE(t.exclude_held_from_candidates, 0) as exclude_held_from_candidates,
+			COALESCE(t.analysis_only, 0) as analysis_only,
+			COALESCE(t.warmup_minutes, 0) as warmup_minutes,
+			COALESCE(t.skip_cycle_if_busy, 0) as skip_cycle_if_busy,
+			COALESCE(t.max_position_age_hours, 0) as max_position_age_hours,
+			COALESCE(t.allow_pyramiding, 0) as allow_pyramiding,
+			COALESCE(t.max_adds_per_position, 2) as max_adds_per_position,
+			COALESCE(t.enforce_daily_loss_stop, 0) as enforce_daily_loss_stop,
+			COALESCE(t.allow_flip, 0) as allow_flip,
+			COALESCE(t.min_confidence, 0) as min_confidence,
+			COALESCE(t.signal_base_position_pct, 20) as signal_base_position_pct,
+			COALESCE(t.signal_default_add_pct, 10) as signal_default_add_pct,
+			COALESCE(t.equity_take_profit, 0) as equity_take_profit,
+			COALESCE(t.equity_stop_loss, 0) as equity_stop_loss,
+			COALESCE(t.equity_take_profit_pct, 0) as equity_take_profit_pct,
+			COALESCE(t.equity_stop_loss_pct, 0) as equity_stop_loss_pct,
+			COALESCE(t.auto_reprotect, 0) as auto_reprotect,
+			COALESCE(t.public_display_name, '') as public_display_name,
+			COALESCE(t.public_visibility, 1) as public_visibility,
+			COALESCE(t.backup_exchange_id, '') as backup_exchange_id,
+			COALESCE(t.trading_schedule, '') as trading_schedule,
+			COALESCE(t.include_orderbook_depth, 0) as include_orderbook_depth,
+			COALESCE(t.skip_if_btc_move_pct, 0) as skip_if_btc_move_pct,
+			COALESCE(t.skip_if_funding_above, 0) as skip_if_funding_above,
+			COALESCE(t.max_open_orders, 10) as max_open_orders,
+			COALESCE(t.breakeven_at_profit_pct, 0) as breakeven_at_profit_pct,
+			COALESCE(t.trail_stop_after_profit_pct, 0) as trail_stop_after_profit_pct,
+			COALESCE(t.trail_lock_fraction, 0.5) as trail_lock_fraction,
+			COALESCE(t.max_actions_per_cycle, 10) as max_actions_per_cycle,
+			COALESCE(t.approval_required_first_trade, 0) as approval_required_first_trade,
+			COALESCE(t.min_seconds_between_ai_calls, 0) as min_seconds_between_ai_calls,
+			COALESCE(t.max_per_symbol_exposure_pct, 0) as max_per_symbol_exposure_pct,
+			COALESCE(t.include_recent_trades, 1) as include_recent_trades,
+			COALESCE(t.recent_trades_count, 5) as recent_trades_count,
+			COALESCE(t.sizing_base, 'fixed') as sizing_base,
+			t.ai_temperature, t.ai_top_p, COALESCE(t.ai_max_tokens, 0) as ai_max_tokens,
+			COALESCE(t.on_ai_failure, 'hold') as on_ai_failure,
+			COALESCE(t.baseline_reset_policy, 'none') as baseline_reset_policy,
+			COALESCE(t.enforce_max_drawdown_stop, 0) as enforce_max_drawdown_stop, COALESCE(t.max_drawdown_stop_pct, 0) as max_drawdown_stop_pct, COALESCE(t.drawdown_stop_flatten, 0) as drawdown_stop_flatten,
+			COALESCE(t.stop_approach_alert_pct, 0) as stop_approach_alert_pct,
+			COALESCE(t.candidate_symbols, '') as candidate_symbols,
+			COALESCE(t.min_holding_minutes, 0) as min_holding_minutes,
+			COALESCE(t.strategy_sources, '') as strategy_sources,
+			COALESCE(t.context_indicators, '') as context_indicators,
+			COALESCE(t.context_timeframes, '') as context_timeframes,
+			COALESCE(t.tp1_close_pct, 0) as tp1_close_pct,
+			COALESCE(t.tp1_breakeven_stop, 0) as tp1_breakeven_stop,
+			COALESCE(t.rebalance_targets, '') as rebalance_targets,
+			COALESCE(t.rebalance_tolerance_pct, 0) as rebalance_tolerance_pct,
+			COALESCE(t.rebalance_interval_minutes, 0) as rebalance_interval_minutes,
+			COALESCE(t.use_oco, 0) as use_oco,
+			COALESCE(t.ai_anomaly_idle_cycles, 0) as ai_anomaly_idle_cycles,
+			COALESCE(t.ai_anomaly_repeat_cycles, 0) as ai_anomaly_repeat_cycles,
+			COALESCE(t.ai_anomaly_pause, 0) as ai_anomaly_pause,
+			COALESCE(t.protective_defaults, '') as protective_defaults,
+			COALESCE(t.position_audit_minutes, 0) as position_audit_minutes,
+			COALESCE(t.min_available_balance, 0) as min_available_balance,
 			t.created_at, t.updated_at,
 			a.id, a.user_id, a.name, a.provider, a.enabled, a.api_key,
 			COALESCE(a.custom_api_url, '') as custom_api_url,
 			COALESCE(a.custom_model_name, '') as custom_model_name,
+			COALESCE(a.max_prompt_tokens, 0) as max_prompt_tokens,
+			COALESCE(a.custom_headers, '') as custom_headers,
+			COALESCE(a.custom_auth_scheme, '') as custom_auth_scheme,
 			a.created_at, a.updated_at,
 			e.id, e.user_id, e.name, e.type, e.enabled, e.api_key, e.secret_key, e.testnet,
 			COALESCE(e.hyperliquid_wallet_addr, '') as hyperliquid_wallet_addr,
@@ -1452,9 +2082,69 @@ func (d *Database) GetTraderConfig(userID, traderID string) (*TraderRecord, *AIM
 		&trader.UseCoinPool, &trader.UseOITop,
 		&trader.CustomPrompt, &trader.OverrideBasePrompt, &trader.SystemPromptTemplate,
 		&trader.IsCrossMargin,
+		&trader.RequireStopLoss,
+		&trader.DefaultStopLossPct,
+		&trader.ExcludeHeldFromCandidates,
+		&trader.AnalysisOnly,
+		&trader.WarmupMinutes,
+		&trader.SkipCycleIfBusy,
+		&trader.MaxPositionAgeHours,
+		&trader.AllowPyramiding,
+		&trader.MaxAddsPerPosition,
+		&trader.EnforceDailyLossStop,
+		&trader.AllowFlip,
+		&trader.MinConfidence,
+		&trader.SignalBasePositionPct,
+		&trader.SignalDefaultAddPct,
+		&trader.EquityTakeProfit,
+		&trader.EquityStopLoss,
+		&trader.EquityTakeProfitPct,
+		&trader.EquityStopLossPct,
+		&trader.AutoReprotect,
+		&trader.PublicDisplayName,
+		&trader.PublicVisibility,
+		&trader.BackupExchangeID,
+		&trader.TradingSchedule,
+		&trader.IncludeOrderBookDepth,
+		&trader.SkipIfBTCMovePct,
+		&trader.SkipIfFundingAbove,
+		&trader.MaxOpenOrders,
+		&trader.BreakevenAtProfitPct,
+		&trader.TrailStopAfterProfitPct,
+		&trader.TrailLockFraction,
+		&trader.MaxActionsPerCycle,
+		&trader.RequireFirstTradeApproval,
+		&trader.MinSecondsBetweenAICalls,
+		&trader.MaxPerSymbolExposurePct,
+		&trader.IncludeRecentTrades,
+		&trader.RecentTradesCount,
+		&trader.SizingBase,
+		&trader.AITemperature, &trader.AITopP, &trader.AIMaxTokens,
+		&trader.OnAIFailure,
+		&trader.BaselineResetPolicy,
+		&trader.EnforceMaxDrawdownStop, &trader.MaxDrawdownStopPct, &trader.DrawdownStopFlatten,
+		&trader.StopApproachAlertPct,
+		&trader.CandidateSymbols,
+		&trader.MinHoldingMinutes,
+		&trader.StrategySources,
+		&trader.ContextIndicators,
+		&trader.ContextTimeframes,
+		&trader.TP1ClosePct,
+		&trader.TP1BreakevenStop,
+		&trader.RebalanceTargets,
+		&trader.RebalanceTolerancePct,
+		&trader.RebalanceIntervalMinutes,
+		&trader.UseOCO,
+		&trader.AIAnomalyIdleCycles,
+		&trader.AIAnomalyRepeatCycles,
+		&trader.AIAnomalyPause,
+		&trader.ProtectiveDefaults,
+		&trader.PositionAuditMinutes,
+		&trader.MinAvailableBalance,
 		&trader.CreatedAt, &trader.UpdatedAt,
 		&aiModel.ID, &aiModel.UserID, &aiModel.Name, &aiModel.Provider, &aiModel.Enabled, &aiModel.APIKey,
-		&aiModel.CustomAPIURL, &aiModel.CustomModelName,
+		&aiModel.CustomAPIURL, &aiModel.CustomModelName, &aiModel.MaxPromptTokens,
+		&customHeaders, &aiModel.CustomAuthScheme,
 		&aiModel.CreatedAt, &aiModel.UpdatedAt,
 		&exchange.ID, &exchange.UserID, &exchange.Name, &exchange.Type, &exchange.Enabled,
 		&exchange.APIKey, &exchange.SecretKey, &exchange.Testnet,
@@ -1469,6 +2159,7 @@ func (d *Database) GetTraderConfig(userID, traderID string) (*TraderRecord, *AIM
 
 	// 解密敏感数据
 	aiModel.APIKey = d.decryptSensitiveData(aiModel.APIKey)
+	aiModel.CustomHeaders, _ = d.decodeCustomHeaders(customHeaders)
 	exchange.APIKey = d.decryptSensitiveData(exchange.APIKey)
 	exchange.SecretKey = d.decryptSensitiveData(exchange.SecretKey)
 	exchange.AsterPrivateKey = d.decryptSensitiveData(exchange.AsterPrivateKey)
@@ -1731,6 +2422,158 @@ func (d *Database) SetCryptoService(cs *crypto.CryptoService) {
 	d.cryptoService = cs
 }
 
+// dataKeyVersionConfigKey 数据加密密钥版本号（system_config）
+const dataKeyVersionConfigKey = "data_key_version"
+
+// secretColumn 需要在密钥轮换时重新加密的字段
+type secretColumn struct {
+	table   string
+	columns []string
+}
+
+// sensitiveColumns 所有加密存储的敏感字段
+var sensitiveColumns = []secretColumn{
+	{table: "ai_models", columns: []string{"api_key", "custom_headers"}},
+	{table: "exchanges", columns: []string{"api_key", "secret_key", "passphrase", "aster_private_key"}},
+	{table: "user_webhooks", columns: []string{"secret"}},
+}
+
+// RotateEncryptionKey 轮换数据加密密钥：生成新密钥，在一个事务内用新密钥重新加密所有已存储的敏感字段
+// 轮换期间独占密钥锁，所有"加密 + 写入"路径（见 lockSensitiveWrite）都会等待轮换结束，不会写入旧密钥密文
+// 本就无法解密的字段（如旧版明文或损坏数据）保持原样并在 skipped 中报告，不影响其余字段的轮换；
+// 读取或写入失败则整体回滚。旧密钥只在事务提交前保留（用于提交失败时恢复），提交成功后即清除
+func (d *Database) RotateEncryptionKey() (int, int, []string, error) {
+	if d.cryptoService == nil || !d.cryptoService.HasDataKey() {
+		return 0, 0, nil, fmt.Errorf("数据加密未启用")
+	}
+
+	unlock := d.cryptoService.LockKeyRotation()
+	defer unlock()
+
+	newKey, err := crypto.NewDataKey()
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("生成新密钥失败: %w", err)
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	defer tx.Rollback()
+
+	reencrypted := 0
+	var skipped []string
+	for _, sc := range sensitiveColumns {
+		count, tableSkipped, err := d.reencryptTable(tx, sc, newKey)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		reencrypted += count
+		skipped = append(skipped, tableSkipped...)
+	}
+
+	oldVersion := d.cryptoService.DataKeyVersion()
+	newVersion := oldVersion + 1
+	if d.isMySQL {
+		_, err = tx.Exec(fmt.Sprintf(`
+			INSERT INTO system_config (`+"`key`"+`, value, updated_at)
+			VALUES (?, ?, %s)
+			ON DUPLICATE KEY UPDATE value = VALUES(value), updated_at = %s
+		`, d.getTimeFunc(), d.getTimeFunc()), dataKeyVersionConfigKey, strconv.Itoa(newVersion))
+	} else {
+		_, err = tx.Exec(fmt.Sprintf(`
+			INSERT OR REPLACE INTO system_config (key, value, updated_at)
+			VALUES (?, ?, %s)
+		`, d.getTimeFunc()), dataKeyVersionConfigKey, strconv.Itoa(newVersion))
+	}
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("更新密钥版本失败: %w", err)
+	}
+
+	// 先持久化新密钥再提交事务：提交失败时旧密钥仍作为 previousKey 可解密原数据
+	if err := d.cryptoService.SwitchDataKey(newKey, newVersion); err != nil {
+		return 0, 0, nil, fmt.Errorf("保存新密钥失败: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("❌ 密钥轮换事务提交失败，恢复旧密钥: %v", err)
+		// 用新旧密钥互换的方式恢复，版本号保持不变
+		if restoreErr := d.cryptoService.RestorePreviousDataKey(oldVersion); restoreErr != nil {
+			log.Printf("❌ 恢复旧密钥失败: %v", restoreErr)
+		}
+		return 0, 0, nil, fmt.Errorf("提交密钥轮换失败: %w", err)
+	}
+	// 所有数据已用新密钥提交，旧密钥不再需要
+	if err := d.cryptoService.ClearPreviousDataKey(); err != nil {
+		log.Printf("⚠️ 清除旧数据密钥失败: %v", err)
+	}
+
+	log.Printf("🔑 数据加密密钥已轮换: v%d -> v%d，重新加密 %d 个字段，跳过 %d 个无法解密的字段", oldVersion, newVersion, reencrypted, len(skipped))
+	return newVersion, reencrypted, skipped, nil
+}
+
+// reencryptTable 在事务内重新加密指定表的敏感字段，返回重新加密的字段数和跳过的字段（表.列#id）
+func (d *Database) reencryptTable(tx *sql.Tx, sc secretColumn, newKey []byte) (int, []string, error) {
+	rows, err := tx.Query(fmt.Sprintf(`SELECT id, user_id, %s FROM %s`, strings.Join(sc.columns, ", "), sc.table))
+	if err != nil {
+		return 0, nil, fmt.Errorf("读取 %s 失败: %w", sc.table, err)
+	}
+
+	type secretRow struct {
+		id, userID string
+		values     []sql.NullString
+	}
+	var records []secretRow
+	for rows.Next() {
+		r := secretRow{values: make([]sql.NullString, len(sc.columns))}
+		dest := []interface{}{&r.id, &r.userID}
+		for i := range r.values {
+			dest = append(dest, &r.values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return 0, nil, fmt.Errorf("读取 %s 失败: %w", sc.table, err)
+		}
+		records = append(records, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, fmt.Errorf("读取 %s 失败: %w", sc.table, err)
+	}
+
+	count := 0
+	var skipped []string
+	for _, r := range records {
+		for i, column := range sc.columns {
+			if !r.values[i].Valid || r.values[i].String == "" {
+				continue
+			}
+			reencrypted, err := d.cryptoService.ReencryptForStorage(r.values[i].String, newKey)
+			if err != nil {
+				// 当前密钥本就无法解密，保持原值，不让个别坏数据阻塞整个轮换
+				field := fmt.Sprintf("%s.%s#%s", sc.table, column, r.id)
+				log.Printf("⚠️ 跳过无法解密的字段 %s: %v", field, err)
+				skipped = append(skipped, field)
+				continue
+			}
+			if _, err := tx.Exec(fmt.Sprintf(`UPDATE %s SET %s = ? WHERE id = ? AND user_id = ?`, sc.table, column),
+				reencrypted, r.id, r.userID); err != nil {
+				return 0, nil, fmt.Errorf("更新 %s.%s (id=%s) 失败: %w", sc.table, column, r.id, err)
+			}
+			count++
+		}
+	}
+	return count, skipped, nil
+}
+
+// lockSensitiveWrite 在加密敏感字段并写入数据库期间持有，防止中途发生密钥轮换导致写入旧密钥密文
+// 未启用加密时为空操作；同一调用链只能获取一次（不可重入）
+func (d *Database) lockSensitiveWrite() func() {
+	if d.cryptoService == nil {
+		return func() {}
+	}
+	return d.cryptoService.LockKeyUse()
+}
+
 // encryptSensitiveData 加密敏感数据用于存储
 func (d *Database) encryptSensitiveData(plaintext string) string {
 	if d.cryptoService == nil || plaintext == "" {
@@ -1748,24 +2591,31 @@ func (d *Database) encryptSensitiveData(plaintext string) string {
 
 // decryptSensitiveData 解密敏感数据
 func (d *Database) decryptSensitiveData(encrypted string) string {
+	decrypted, _ := d.decryptSensitiveField(encrypted)
+	return decrypted
+}
+
+// decryptSensitiveField 解密敏感数据，ok=false 表示存储的密文无法解密（如数据加密密钥已变更）
+func (d *Database) decryptSensitiveField(encrypted string) (string, bool) {
 	if d.cryptoService == nil || encrypted == "" {
-		return encrypted
+		return encrypted, true
 	}
 
 	// 如果不是加密格式，直接返回
 	if !d.cryptoService.IsEncryptedStorageValue(encrypted) {
-		return encrypted
+		return encrypted, true
 	}
 
 	decrypted, err := d.cryptoService.DecryptFromStorage(encrypted)
 	if err != nil {
-		log.Printf("⚠️ 解密失败: %v", err)
+		// 只记录密文长度与密钥版本，便于排查，不输出任何明文/密文内容
+		log.Printf("⚠️ 解密失败（密文长度=%d，当前密钥版本=v%d）: %v", len(encrypted), d.cryptoService.DataKeyVersion(), err)
 		// 🔴 CRITICAL FIX: 解密失败时返回空字符串，不要返回加密文本
 		// 这样可以防止加密格式的文本被当作API密钥使用
-		return ""
+		return "", false
 	}
 
-	return decrypted
+	return decrypted, true
 }
 
 // migrateUserRoles 数据迁移：设置现有用户的role字段
@@ -1811,6 +2661,64 @@ func (d *Database) migrateTradersOwnerUserID() {
 	}
 }
 
+// SystemStats 系统级统计（管理员总览用）
+type SystemStats struct {
+	TotalUsers          int            `json:"total_users"`
+	TotalTraders        int            `json:"total_traders"`
+	RunningTraders      int            `json:"running_traders"`
+	StoppedTraders      int            `json:"stopped_traders"`
+	TradersByExchange   map[string]int `json:"traders_by_exchange"`
+	TradersByAIProvider map[string]int `json:"traders_by_ai_provider"`
+}
+
+// GetSystemStats 统计用户数、交易员数（运行/停止）以及按交易所、AI提供商的分布
+func (d *Database) GetSystemStats() (*SystemStats, error) {
+	stats := &SystemStats{
+		TradersByExchange:   make(map[string]int),
+		TradersByAIProvider: make(map[string]int),
+	}
+
+	if err := d.db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&stats.TotalUsers); err != nil {
+		return nil, fmt.Errorf("统计用户数失败: %w", err)
+	}
+
+	rows, err := d.db.Query(`
+		SELECT t.exchange_id, COALESCE(a.provider, ''), COALESCE(t.is_running, 0)
+		FROM traders t
+		LEFT JOIN ai_models a ON t.ai_model_id = a.id AND t.user_id = a.user_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("统计交易员失败: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var exchangeID, provider string
+		var isRunning bool
+		if err := rows.Scan(&exchangeID, &provider, &isRunning); err != nil {
+			return nil, err
+		}
+		if provider == "" {
+			provider = "unknown"
+		}
+		stats.TotalTraders++
+		if isRunning {
+			stats.RunningTraders++
+		} else {
+			stats.StoppedTraders++
+		}
+		stats.TradersByExchange[exchangeID]++
+		stats.TradersByAIProvider[provider]++
+	}
+
+	return stats, rows.Err()
+}
+
+// DBStats 获取数据库连接池统计
+func (d *Database) DBStats() sql.DBStats {
+	return d.db.Stats()
+}
+
 // GetAllTraders 获取所有交易员（Admin用）
 func (d *Database) GetAllTraders() ([]*TraderRecord, error) {
 	rows, err := d.db.Query(`
@@ -1824,6 +2732,65 @@ func (d *Database) GetAllTraders() ([]*TraderRecord, error) {
 		       COALESCE(category, '') as category,
 		       COALESCE(trader_account_id, '') as trader_account_id,
 		       COALESCE(owner_user_id, '') as owner_user_id,
+		       COALESCE(require_stop_loss, 0) as require_stop_loss,
+		       COALESCE(default_stop_loss_pct, 0) as default_stop_loss_pct,
+		       COALESCE(exclude_held_from_candidates, 0) as exclude_held_from_candidates,
+		       COALESCE(analysis_only, 0) as analysis_only,
+		       COALESCE(warmup_minutes, 0) as warmup_minutes,
+		       COALESCE(skip_cycle_if_busy, 0) as skip_cycle_if_busy,
+		       COALESCE(max_position_age_hours, 0) as max_position_age_hours,
+		       COALESCE(allow_pyramiding, 0) as allow_pyramiding,
+		       COALESCE(max_adds_per_position, 2) as max_adds_per_position,
+		       COALESCE(enforce_daily_loss_stop, 0) as enforce_daily_loss_stop,
+		       COALESCE(allow_flip, 0) as allow_flip,
+		       COALESCE(min_confidence, 0) as min_confidence,
+		       COALESCE(signal_base_position_pct, 20) as signal_base_position_pct,
+		       COALESCE(signal_default_add_pct, 10) as signal_default_add_pct,
+		       COALESCE(equity_take_profit, 0) as equity_take_profit,
+		       COALESCE(equity_stop_loss, 0) as equity_stop_loss,
+		       COALESCE(equity_take_profit_pct, 0) as equity_take_profit_pct,
+		       COALESCE(equity_stop_loss_pct, 0) as equity_stop_loss_pct,
+		       COALESCE(auto_reprotect, 0) as auto_reprotect,
+		       COALESCE(public_display_name, '') as public_display_name,
+		       COALESCE(public_visibility, 1) as public_visibility,
+		       COALESCE(backup_exchange_id, '') as backup_exchange_id,
+		       COALESCE(trading_schedule, '') as trading_schedule,
+		       COALESCE(include_orderbook_depth, 0) as include_orderbook_depth,
+		       COALESCE(skip_if_btc_move_pct, 0) as skip_if_btc_move_pct,
+		       COALESCE(skip_if_funding_above, 0) as skip_if_funding_above,
+		       COALESCE(max_open_orders, 10) as max_open_orders,
+		       COALESCE(breakeven_at_profit_pct, 0) as breakeven_at_profit_pct,
+		       COALESCE(trail_stop_after_profit_pct, 0) as trail_stop_after_profit_pct,
+		       COALESCE(trail_lock_fraction, 0.5) as trail_lock_fraction,
+		       COALESCE(max_actions_per_cycle, 10) as max_actions_per_cycle,
+		       COALESCE(approval_required_first_trade, 0) as approval_required_first_trade,
+		       COALESCE(min_seconds_between_ai_calls, 0) as min_seconds_between_ai_calls,
+		       COALESCE(max_per_symbol_exposure_pct, 0) as max_per_symbol_exposure_pct,
+		       COALESCE(include_recent_trades, 1) as include_recent_trades,
+		       COALESCE(recent_trades_count, 5) as recent_trades_count,
+		       COALESCE(sizing_base, 'fixed') as sizing_base,
+		       ai_temperature, ai_top_p, COALESCE(ai_max_tokens, 0) as ai_max_tokens,
+		       COALESCE(on_ai_failure, 'hold') as on_ai_failure,
+		       COALESCE(baseline_reset_policy, 'none') as baseline_reset_policy,
+		       COALESCE(enforce_max_drawdown_stop, 0) as enforce_max_drawdown_stop, COALESCE(max_drawdown_stop_pct, 0) as max_drawdown_stop_pct, COALESCE(drawdown_stop_flatten, 0) as drawdown_stop_flatten,
+		       COALESCE(stop_approach_alert_pct, 0) as stop_approach_alert_pct,
+		       COALESCE(candidate_symbols, '') as candidate_symbols,
+		       COALESCE(min_holding_minutes, 0) as min_holding_minutes,
+		       COALESCE(strategy_sources, '') as strategy_sources,
+		       COALESCE(context_indicators, '') as context_indicators,
+		       COALESCE(context_timeframes, '') as context_timeframes,
+		       COALESCE(tp1_close_pct, 0) as tp1_close_pct,
+		       COALESCE(tp1_breakeven_stop, 0) as tp1_breakeven_stop,
+		       COALESCE(rebalance_targets, '') as rebalance_targets,
+		       COALESCE(rebalance_tolerance_pct, 0) as rebalance_tolerance_pct,
+		       COALESCE(rebalance_interval_minutes, 0) as rebalance_interval_minutes,
+		       COALESCE(use_oco, 0) as use_oco,
+		       COALESCE(ai_anomaly_idle_cycles, 0) as ai_anomaly_idle_cycles,
+		       COALESCE(ai_anomaly_repeat_cycles, 0) as ai_anomaly_repeat_cycles,
+		       COALESCE(ai_anomaly_pause, 0) as ai_anomaly_pause,
+		       COALESCE(protective_defaults, '') as protective_defaults,
+		       COALESCE(position_audit_minutes, 0) as position_audit_minutes,
+		       COALESCE(min_available_balance, 0) as min_available_balance,
 		       created_at, updated_at
 		FROM traders ORDER BY created_at DESC
 	`)
@@ -1843,6 +2810,65 @@ func (d *Database) GetAllTraders() ([]*TraderRecord, error) {
 			&trader.CustomPrompt, &trader.OverrideBasePrompt, &trader.SystemPromptTemplate,
 			&trader.IsCrossMargin,
 			&trader.Category, &trader.TraderAccountID, &trader.OwnerUserID,
+			&trader.RequireStopLoss,
+			&trader.DefaultStopLossPct,
+			&trader.ExcludeHeldFromCandidates,
+			&trader.AnalysisOnly,
+			&trader.WarmupMinutes,
+			&trader.SkipCycleIfBusy,
+			&trader.MaxPositionAgeHours,
+			&trader.AllowPyramiding,
+			&trader.MaxAddsPerPosition,
+			&trader.EnforceDailyLossStop,
+			&trader.AllowFlip,
+			&trader.MinConfidence,
+			&trader.SignalBasePositionPct,
+			&trader.SignalDefaultAddPct,
+			&trader.EquityTakeProfit,
+			&trader.EquityStopLoss,
+			&trader.EquityTakeProfitPct,
+			&trader.EquityStopLossPct,
+			&trader.AutoReprotect,
+			&trader.PublicDisplayName,
+			&trader.PublicVisibility,
+			&trader.BackupExchangeID,
+			&trader.TradingSchedule,
+			&trader.IncludeOrderBookDepth,
+			&trader.SkipIfBTCMovePct,
+			&trader.SkipIfFundingAbove,
+			&trader.MaxOpenOrders,
+			&trader.BreakevenAtProfitPct,
+			&trader.TrailStopAfterProfitPct,
+			&trader.TrailLockFraction,
+			&trader.MaxActionsPerCycle,
+			&trader.RequireFirstTradeApproval,
+			&trader.MinSecondsBetweenAICalls,
+			&trader.MaxPerSymbolExposurePct,
+			&trader.IncludeRecentTrades,
+			&trader.RecentTradesCount,
+			&trader.SizingBase,
+			&trader.AITemperature, &trader.AITopP, &trader.AIMaxTokens,
+			&trader.OnAIFailure,
+			&trader.BaselineResetPolicy,
+			&trader.EnforceMaxDrawdownStop, &trader.MaxDrawdownStopPct, &trader.DrawdownStopFlatten,
+			&trader.StopApproachAlertPct,
+			&trader.CandidateSymbols,
+			&trader.MinHoldingMinutes,
+			&trader.StrategySources,
+			&trader.ContextIndicators,
+			&trader.ContextTimeframes,
+			&trader.TP1ClosePct,
+			&trader.TP1BreakevenStop,
+			&trader.RebalanceTargets,
+			&trader.RebalanceTolerancePct,
+			&trader.RebalanceIntervalMinutes,
+			&trader.UseOCO,
+			&trader.AIAnomalyIdleCycles,
+			&trader.AIAnomalyRepeatCycles,
+			&trader.AIAnomalyPause,
+			&trader.ProtectiveDefaults,
+			&trader.PositionAuditMinutes,
+			&trader.MinAvailableBalance,
 			&trader.CreatedAt, &trader.UpdatedAt,
 		)
 		if err != nil {
@@ -1867,6 +2893,65 @@ func (d *Database) GetTradersByOwnerUserID(userID string) ([]*TraderRecord, erro
 		       COALESCE(category, '') as category,
 		       COALESCE(trader_account_id, '') as trader_account_id,
 		       COALESCE(owner_user_id, '') as owner_user_id,
+		       COALESCE(require_stop_loss, 0) as require_stop_loss,
+		       COALESCE(default_stop_loss_pct, 0) as default_stop_loss_pct,
+		       COALESCE(exclude_held_from_candidates, 0) as exclude_held_from_candidates,
+		       COALESCE(analysis_only, 0) as analysis_only,
+		       COALESCE(warmup_minutes, 0) as warmup_minutes,
+		       COALESCE(skip_cycle_if_busy, 0) as skip_cycle_if_busy,
+		       COALESCE(max_position_age_hours, 0) as max_position_age_hours,
+		       COALESCE(allow_pyramiding, 0) as allow_pyramiding,
+		       COALESCE(max_adds_per_position, 2) as max_adds_per_position,
+		       COALESCE(enforce_daily_loss_stop, 0) as enforce_daily_loss_stop,
+		       COALESCE(allow_flip, 0) as allow_flip,
+		       COALESCE(min_confidence, 0) as min_confidence,
+		       COALESCE(signal_base_position_pct, 20) as signal_base_position_pct,
+		       COALESCE(signal_default_add_pct, 10) as signal_default_add_pct,
+		       COALESCE(equity_take_profit, 0) as equity_take_profit,
+		       COALESCE(equity_stop_loss, 0) as equity_stop_loss,
+		       COALESCE(equity_take_profit_pct, 0) as equity_take_profit_pct,
+		       COALESCE(equity_stop_loss_pct, 0) as equity_stop_loss_pct,
+		       COALESCE(auto_reprotect, 0) as auto_reprotect,
+		       COALESCE(public_display_name, '') as public_display_name,
+		       COALESCE(public_visibility, 1) as public_visibility,
+		       COALESCE(backup_exchange_id, '') as backup_exchange_id,
+		       COALESCE(trading_schedule, '') as trading_schedule,
+		       COALESCE(include_orderbook_depth, 0) as include_orderbook_depth,
+		       COALESCE(skip_if_btc_move_pct, 0) as skip_if_btc_move_pct,
+		       COALESCE(skip_if_funding_above, 0) as skip_if_funding_above,
+		       COALESCE(max_open_orders, 10) as max_open_orders,
+		       COALESCE(breakeven_at_profit_pct, 0) as breakeven_at_profit_pct,
+		       COALESCE(trail_stop_after_profit_pct, 0) as trail_stop_after_profit_pct,
+		       COALESCE(trail_lock_fraction, 0.5) as trail_lock_fraction,
+		       COALESCE(max_actions_per_cycle, 10) as max_actions_per_cycle,
+		       COALESCE(approval_required_first_trade, 0) as approval_required_first_trade,
+		       COALESCE(min_seconds_between_ai_calls, 0) as min_seconds_between_ai_calls,
+		       COALESCE(max_per_symbol_exposure_pct, 0) as max_per_symbol_exposure_pct,
+		       COALESCE(include_recent_trades, 1) as include_recent_trades,
+		       COALESCE(recent_trades_count, 5) as recent_trades_count,
+		       COALESCE(sizing_base, 'fixed') as sizing_base,
+		       ai_temperature, ai_top_p, COALESCE(ai_max_tokens, 0) as ai_max_tokens,
+		       COALESCE(on_ai_failure, 'hold') as on_ai_failure,
+		       COALESCE(baseline_reset_policy, 'none') as baseline_reset_policy,
+		       COALESCE(enforce_max_drawdown_stop, 0) as enforce_max_drawdown_stop, COALESCE(max_drawdown_stop_pct, 0) as max_drawdown_stop_pct, COALESCE(drawdown_stop_flatten, 0) as drawdown_stop_flatten,
+		       COALESCE(stop_approach_alert_pct, 0) as stop_approach_alert_pct,
+		       COALESCE(candidate_symbols, '') as candidate_symbols,
+		       COALESCE(min_holding_minutes, 0) as min_holding_minutes,
+		       COALESCE(strategy_sources, '') as strategy_sources,
+		       COALESCE(context_indicators, '') as context_indicators,
+		       COALESCE(context_timeframes, '') as context_timeframes,
+		       COALESCE(tp1_close_pct, 0) as tp1_close_pct,
+		       COALESCE(tp1_breakeven_stop, 0) as tp1_breakeven_stop,
+		       COALESCE(rebalance_targets, '') as rebalance_targets,
+		       COALESCE(rebalance_tolerance_pct, 0) as rebalance_tolerance_pct,
+		       COALESCE(rebalance_interval_minutes, 0) as rebalance_interval_minutes,
+		       COALESCE(use_oco, 0) as use_oco,
+		       COALESCE(ai_anomaly_idle_cycles, 0) as ai_anomaly_idle_cycles,
+		       COALESCE(ai_anomaly_repeat_cycles, 0) as ai_anomaly_repeat_cycles,
+		       COALESCE(ai_anomaly_pause, 0) as ai_anomaly_pause,
+		       COALESCE(protective_defaults, '') as protective_defaults,
+		       COALESCE(position_audit_minutes, 0) as position_audit_minutes,
+		       COALESCE(min_available_balance, 0) as min_available_balance,
 		       created_at, updated_at
 		FROM traders WHERE owner_user_id = ? ORDER BY created_at DESC
 	`, userID)
@@ -1886,6 +2971,65 @@ func (d *Database) GetTradersByOwnerUserID(userID string) ([]*TraderRecord, erro
 			&trader.CustomPrompt, &trader.OverrideBasePrompt, &trader.SystemPromptTemplate,
 			&trader.IsCrossMargin,
 			&trader.Category, &trader.TraderAccountID, &trader.OwnerUserID,
+			&trader.RequireStopLoss,
+			&trader.DefaultStopLossPct,
+			&trader.ExcludeHeldFromCandidates,
+			&trader.AnalysisOnly,
+			&trader.WarmupMinutes,
+			&trader.SkipCycleIfBusy,
+			&trader.MaxPositionAgeHours,
+			&trader.AllowPyramiding,
+			&trader.MaxAddsPerPosition,
+			&trader.EnforceDailyLossStop,
+			&trader.AllowFlip,
+			&trader.MinConfidence,
+			&trader.SignalBasePositionPct,
+			&trader.SignalDefaultAddPct,
+			&trader.EquityTakeProfit,
+			&trader.EquityStopLoss,
+			&trader.EquityTakeProfitPct,
+			&trader.EquityStopLossPct,
+			&trader.AutoReprotect,
+			&trader.PublicDisplayName,
+			&trader.PublicVisibility,
+			&trader.BackupExchangeID,
+			&trader.TradingSchedule,
+			&trader.IncludeOrderBookDepth,
+			&trader.SkipIfBTCMovePct,
+			&trader.SkipIfFundingAbove,
+			&trader.MaxOpenOrders,
+			&trader.BreakevenAtProfitPct,
+			&trader.TrailStopAfterProfitPct,
+			&trader.TrailLockFraction,
+			&trader.MaxActionsPerCycle,
+			&trader.RequireFirstTradeApproval,
+			&trader.MinSecondsBetweenAICalls,
+			&trader.MaxPerSymbolExposurePct,
+			&trader.IncludeRecentTrades,
+			&trader.RecentTradesCount,
+			&trader.SizingBase,
+			&trader.AITemperature, &trader.AITopP, &trader.AIMaxTokens,
+			&trader.OnAIFailure,
+			&trader.BaselineResetPolicy,
+			&trader.EnforceMaxDrawdownStop, &trader.MaxDrawdownStopPct, &trader.DrawdownStopFlatten,
+			&trader.StopApproachAlertPct,
+			&trader.CandidateSymbols,
+			&trader.MinHoldingMinutes,
+			&trader.StrategySources,
+			&trader.ContextIndicators,
+			&trader.ContextTimeframes,
+			&trader.TP1ClosePct,
+			&trader.TP1BreakevenStop,
+			&trader.RebalanceTargets,
+			&trader.RebalanceTolerancePct,
+			&trader.RebalanceIntervalMinutes,
+			&trader.UseOCO,
+			&trader.AIAnomalyIdleCycles,
+			&trader.AIAnomalyRepeatCycles,
+			&trader.AIAnomalyPause,
+			&trader.ProtectiveDefaults,
+			&trader.PositionAuditMinutes,
+			&trader.MinAvailableBalance,
 			&trader.CreatedAt, &trader.UpdatedAt,
 		)
 		if err != nil {
@@ -1922,6 +3066,65 @@ func (d *Database) GetTradersByCategories(categories []string) ([]*TraderRecord,
 		       COALESCE(category, '') as category,
 		       COALESCE(trader_account_id, '') as trader_account_id,
 		       COALESCE(owner_user_id, '') as owner_user_id,
+		       COALESCE(require_stop_loss, 0) as require_stop_loss,
+		       COALESCE(default_stop_loss_pct, 0) as default_stop_loss_pct,
+		       COALESCE(exclude_held_from_candidates, 0) as exclude_held_from_candidates,
+		       COALESCE(analysis_only, 0) as analysis_only,
+		       COALESCE(warmup_minutes, 0) as warmup_minutes,
+		       COALESCE(skip_cycle_if_busy, 0) as skip_cycle_if_busy,
+		       COALESCE(max_position_age_hours, 0) as max_position_age_hours,
+		       COALESCE(allow_pyramiding, 0) as allow_pyramiding,
+		       COALESCE(max_adds_per_position, 2) as max_adds_per_position,
+		       COALESCE(enforce_daily_loss_stop, 0) as enforce_daily_loss_stop,
+		       COALESCE(allow_flip, 0) as allow_flip,
+		       COALESCE(min_confidence, 0) as min_confidence,
+		       COALESCE(signal_base_position_pct, 20) as signal_base_position_pct,
+		       COALESCE(signal_default_add_pct, 10) as signal_default_add_pct,
+		       COALESCE(equity_take_profit, 0) as equity_take_profit,
+		       COALESCE(equity_stop_loss, 0) as equity_stop_loss,
+		       COALESCE(equity_take_profit_pct, 0) as equity_take_profit_pct,
+		       COALESCE(equity_stop_loss_pct, 0) as equity_stop_loss_pct,
+		       COALESCE(auto_reprotect, 0) as auto_reprotect,
+		       COALESCE(public_display_name, '') as public_display_name,
+		       COALESCE(public_visibility, 1) as public_visibility,
+		       COALESCE(backup_exchange_id, '') as backup_exchange_id,
+		       COALESCE(trading_schedule, '') as trading_schedule,
+		       COALESCE(include_orderbook_depth, 0) as include_orderbook_depth,
+		       COALESCE(skip_if_btc_move_pct, 0) as skip_if_btc_move_pct,
+		       COALESCE(skip_if_funding_above, 0) as skip_if_funding_above,
+		       COALESCE(max_open_orders, 10) as max_open_orders,
+		       COALESCE(breakeven_at_profit_pct, 0) as breakeven_at_profit_pct,
+		       COALESCE(trail_stop_after_profit_pct, 0) as trail_stop_after_profit_pct,
+		       COALESCE(trail_lock_fraction, 0.5) as trail_lock_fraction,
+		       COALESCE(max_actions_per_cycle, 10) as max_actions_per_cycle,
+		       COALESCE(approval_required_first_trade, 0) as approval_required_first_trade,
+		       COALESCE(min_seconds_between_ai_calls, 0) as min_seconds_between_ai_calls,
+		       COALESCE(max_per_symbol_exposure_pct, 0) as max_per_symbol_exposure_pct,
+		       COALESCE(include_recent_trades, 1) as include_recent_trades,
+		       COALESCE(recent_trades_count, 5) as recent_trades_count,
+		       COALESCE(sizing_base, 'fixed') as sizing_base,
+		       ai_temperature, ai_top_p, COALESCE(ai_max_tokens, 0) as ai_max_tokens,
+		       COALESCE(on_ai_failure, 'hold') as on_ai_failure,
+		       COALESCE(baseline_reset_policy, 'none') as baseline_reset_policy,
+		       COALESCE(enforce_max_drawdown_stop, 0) as enforce_max_drawdown_stop, COALESCE(max_drawdown_stop_pct, 0) as max_drawdown_stop_pct, COALESCE(drawdown_stop_flatten, 0) as drawdown_stop_flatten,
+		       COALESCE(stop_approach_alert_pct, 0) as stop_approach_alert_pct,
+		       COALESCE(candidate_symbols, '') as candidate_symbols,
+		       COALESCE(min_holding_minutes, 0) as min_holding_minutes,
+		       COALESCE(strategy_sources, '') as strategy_sources,
+		       COALESCE(context_indicators, '') as context_indicators,
+		       COALESCE(context_timeframes, '') as context_timeframes,
+		       COALESCE(tp1_close_pct, 0) as tp1_close_pct,
+		       COALESCE(tp1_breakeven_stop, 0) as tp1_breakeven_stop,
+		       COALESCE(rebalance_targets, '') as rebalance_targets,
+		       COALESCE(rebalance_tolerance_pct, 0) as rebalance_tolerance_pct,
+		       COALESCE(rebalance_interval_minutes, 0) as rebalance_interval_minutes,
+		       COALESCE(use_oco, 0) as use_oco,
+		       COALESCE(ai_anomaly_idle_cycles, 0) as ai_anomaly_idle_cycles,
+		       COALESCE(ai_anomaly_repeat_cycles, 0) as ai_anomaly_repeat_cycles,
+		       COALESCE(ai_anomaly_pause, 0) as ai_anomaly_pause,
+		       COALESCE(protective_defaults, '') as protective_defaults,
+		       COALESCE(position_audit_minutes, 0) as position_audit_minutes,
+		       COALESCE(min_available_balance, 0) as min_available_balance,
 		       created_at, updated_at
 		FROM traders WHERE category IN (%s) ORDER BY created_at DESC
 	`, strings.Join(placeholders, ","))
@@ -1943,6 +3146,65 @@ func (d *Database) GetTradersByCategories(categories []string) ([]*TraderRecord,
 			&trader.CustomPrompt, &trader.OverrideBasePrompt, &trader.SystemPromptTemplate,
 			&trader.IsCrossMargin,
 			&trader.Category, &trader.TraderAccountID, &trader.OwnerUserID,
+			&trader.RequireStopLoss,
+			&trader.DefaultStopLossPct,
+			&trader.ExcludeHeldFromCandidates,
+			&trader.AnalysisOnly,
+			&trader.WarmupMinutes,
+			&trader.SkipCycleIfBusy,
+			&trader.MaxPositionAgeHours,
+			&trader.AllowPyramiding,
+			&trader.MaxAddsPerPosition,
+			&trader.EnforceDailyLossStop,
+			&trader.AllowFlip,
+			&trader.MinConfidence,
+			&trader.SignalBasePositionPct,
+			&trader.SignalDefaultAddPct,
+			&trader.EquityTakeProfit,
+			&trader.EquityStopLoss,
+			&trader.EquityTakeProfitPct,
+			&trader.EquityStopLossPct,
+			&trader.AutoReprotect,
+			&trader.PublicDisplayName,
+			&trader.PublicVisibility,
+			&trader.BackupExchangeID,
+			&trader.TradingSchedule,
+			&trader.IncludeOrderBookDepth,
+			&trader.SkipIfBTCMovePct,
+			&trader.SkipIfFundingAbove,
+			&trader.MaxOpenOrders,
+			&trader.BreakevenAtProfitPct,
+			&trader.TrailStopAfterProfitPct,
+			&trader.TrailLockFraction,
+			&trader.MaxActionsPerCycle,
+			&trader.RequireFirstTradeApproval,
+			&trader.MinSecondsBetweenAICalls,
+			&trader.MaxPerSymbolExposurePct,
+			&trader.IncludeRecentTrades,
+			&trader.RecentTradesCount,
+			&trader.SizingBase,
+			&trader.AITemperature, &trader.AITopP, &trader.AIMaxTokens,
+			&trader.OnAIFailure,
+			&trader.BaselineResetPolicy,
+			&trader.EnforceMaxDrawdownStop, &trader.MaxDrawdownStopPct, &trader.DrawdownStopFlatten,
+			&trader.StopApproachAlertPct,
+			&trader.CandidateSymbols,
+			&trader.MinHoldingMinutes,
+			&trader.StrategySources,
+			&trader.ContextIndicators,
+			&trader.ContextTimeframes,
+			&trader.TP1ClosePct,
+			&trader.TP1BreakevenStop,
+			&trader.RebalanceTargets,
+			&trader.RebalanceTolerancePct,
+			&trader.RebalanceIntervalMinutes,
+			&trader.UseOCO,
+			&trader.AIAnomalyIdleCycles,
+			&trader.AIAnomalyRepeatCycles,
+			&trader.AIAnomalyPause,
+			&trader.ProtectiveDefaults,
+			&trader.PositionAuditMinutes,
+			&trader.MinAvailableBalance,
 			&trader.CreatedAt, &trader.UpdatedAt,
 		)
 		if err != nil {
@@ -1967,6 +3229,65 @@ func (d *Database) GetTradersByID(traderID string) ([]*TraderRecord, error) {
 		       COALESCE(category, '') as category,
 		       COALESCE(trader_account_id, '') as trader_account_id,
 		       COALESCE(owner_user_id, '') as owner_user_id,
+		       COALESCE(require_stop_loss, 0) as require_stop_loss,
+		       COALESCE(default_stop_loss_pct, 0) as default_stop_loss_pct,
+		       COALESCE(exclude_held_from_candidates, 0) as exclude_held_from_candidates,
+		       COALESCE(analysis_only, 0) as analysis_only,
+		       COALESCE(warmup_minutes, 0) as warmup_minutes,
+		       COALESCE(skip_cycle_if_busy, 0) as skip_cycle_if_busy,
+		       COALESCE(max_position_age_hours, 0) as max_position_age_hours,
+		       COALESCE(allow_pyramiding, 0) as allow_pyramiding,
+		       COALESCE(max_adds_per_position, 2) as max_adds_per_position,
+		       COALESCE(enforce_daily_loss_stop, 0) as enforce_daily_loss_stop,
+		       COALESCE(allow_flip, 0) as allow_flip,
+		       COALESCE(min_confidence, 0) as min_confidence,
+		       COALESCE(signal_base_position_pct, 20) as signal_base_position_pct,
+		       COALESCE(signal_default_add_pct, 10) as signal_default_add_pct,
+		       COALESCE(equity_take_profit, 0) as equity_take_profit,
+		       COALESCE(equity_stop_loss, 0) as equity_stop_loss,
+		       COALESCE(equity_take_profit_pct, 0) as equity_take_profit_pct,
+		       COALESCE(equity_stop_loss_pct, 0) as equity_stop_loss_pct,
+		       COALESCE(auto_reprotect, 0) as auto_reprotect,
+		       COALESCE(public_display_name, '') as public_display_name,
+		       COALESCE(public_visibility, 1) as public_visibility,
+		       COALESCE(backup_exchange_id, '') as backup_exchange_id,
+		       COALESCE(trading_schedule, '') as trading_schedule,
+		       COALESCE(include_orderbook_depth, 0) as include_orderbook_depth,
+		       COALESCE(skip_if_btc_move_pct, 0) as skip_if_btc_move_pct,
+		       COALESCE(skip_if_funding_above, 0) as skip_if_funding_above,
+		       COALESCE(max_open_orders, 10) as max_open_orders,
+		       COALESCE(breakeven_at_profit_pct, 0) as breakeven_at_profit_pct,
+		       COALESCE(trail_stop_after_profit_pct, 0) as trail_stop_after_profit_pct,
+		       COALESCE(trail_lock_fraction, 0.5) as trail_lock_fraction,
+		       COALESCE(max_actions_per_cycle, 10) as max_actions_per_cycle,
+		       COALESCE(approval_required_first_trade, 0) as approval_required_first_trade,
+		       COALESCE(min_seconds_between_ai_calls, 0) as min_seconds_between_ai_calls,
+		       COALESCE(max_per_symbol_exposure_pct, 0) as max_per_symbol_exposure_pct,
+		       COALESCE(include_recent_trades, 1) as include_recent_trades,
+		       COALESCE(recent_trades_count, 5) as recent_trades_count,
+		       COALESCE(sizing_base, 'fixed') as sizing_base,
+		       ai_temperature, ai_top_p, COALESCE(ai_max_tokens, 0) as ai_max_tokens,
+		       COALESCE(on_ai_failure, 'hold') as on_ai_failure,
+		       COALESCE(baseline_reset_policy, 'none') as baseline_reset_policy,
+		       COALESCE(enforce_max_drawdown_stop, 0) as enforce_max_drawdown_stop, COALESCE(max_drawdown_stop_pct, 0) as max_drawdown_stop_pct, COALESCE(drawdown_stop_flatten, 0) as drawdown_stop_flatten,
+		       COALESCE(stop_approach_alert_pct, 0) as stop_approach_alert_pct,
+		       COALESCE(candidate_symbols, '') as candidate_symbols,
+		       COALESCE(min_holding_minutes, 0) as min_holding_minutes,
+		       COALESCE(strategy_sources, '') as strategy_sources,
+		       COALESCE(context_indicators, '') as context_indicators,
+		       COALESCE(context_timeframes, '') as context_timeframes,
+		       COALESCE(tp1_close_pct, 0) as tp1_close_pct,
+		       COALESCE(tp1_breakeven_stop, 0) as tp1_breakeven_stop,
+		       COALESCE(rebalance_targets, '') as rebalance_targets,
+		       COALESCE(rebalance_tolerance_pct, 0) as rebalance_tolerance_pct,
+		       COALESCE(rebalance_interval_minutes, 0) as rebalance_interval_minutes,
+		       COALESCE(use_oco, 0) as use_oco,
+		       COALESCE(ai_anomaly_idle_cycles, 0) as ai_anomaly_idle_cycles,
+		       COALESCE(ai_anomaly_repeat_cycles, 0) as ai_anomaly_repeat_cycles,
+		       COALESCE(ai_anomaly_pause, 0) as ai_anomaly_pause,
+		       COALESCE(protective_defaults, '') as protective_defaults,
+		       COALESCE(position_audit_minutes, 0) as position_audit_minutes,
+		       COALESCE(min_available_balance, 0) as min_available_balance,
 		       created_at, updated_at
 		FROM traders WHERE id = ? ORDER BY created_at DESC
 	`, traderID)
@@ -1986,6 +3307,65 @@ func (d *Database) GetTradersByID(traderID string) ([]*TraderRecord, error) {
 			&trader.CustomPrompt, &trader.OverrideBasePrompt, &trader.SystemPromptTemplate,
 			&trader.IsCrossMargin,
 			&trader.Category, &trader.TraderAccountID, &trader.OwnerUserID,
+			&trader.RequireStopLoss,
+			&trader.DefaultStopLossPct,
+			&trader.ExcludeHeldFromCandidates,
+			&trader.AnalysisOnly,
+			&trader.WarmupMinutes,
+			&trader.SkipCycleIfBusy,
+			&trader.MaxPositionAgeHours,
+			&trader.AllowPyramiding,
+			&trader.MaxAddsPerPosition,
+			&trader.EnforceDailyLossStop,
+			&trader.AllowFlip,
+			&trader.MinConfidence,
+			&trader.SignalBasePositionPct,
+			&trader.SignalDefaultAddPct,
+			&trader.EquityTakeProfit,
+			&trader.EquityStopLoss,
+			&trader.EquityTakeProfitPct,
+			&trader.EquityStopLossPct,
+			&trader.AutoReprotect,
+			&trader.PublicDisplayName,
+			&trader.PublicVisibility,
+			&trader.BackupExchangeID,
+			&trader.TradingSchedule,
+			&trader.IncludeOrderBookDepth,
+			&trader.SkipIfBTCMovePct,
+			&trader.SkipIfFundingAbove,
+			&trader.MaxOpenOrders,
+			&trader.BreakevenAtProfitPct,
+			&trader.TrailStopAfterProfitPct,
+			&trader.TrailLockFraction,
+			&trader.MaxActionsPerCycle,
+			&trader.RequireFirstTradeApproval,
+			&trader.MinSecondsBetweenAICalls,
+			&trader.MaxPerSymbolExposurePct,
+			&trader.IncludeRecentTrades,
+			&trader.RecentTradesCount,
+			&trader.SizingBase,
+			&trader.AITemperature, &trader.AITopP, &trader.AIMaxTokens,
+			&trader.OnAIFailure,
+			&trader.BaselineResetPolicy,
+			&trader.EnforceMaxDrawdownStop, &trader.MaxDrawdownStopPct, &trader.DrawdownStopFlatten,
+			&trader.StopApproachAlertPct,
+			&trader.CandidateSymbols,
+			&trader.MinHoldingMinutes,
+			&trader.StrategySources,
+			&trader.ContextIndicators,
+			&trader.ContextTimeframes,
+			&trader.TP1ClosePct,
+			&trader.TP1BreakevenStop,
+			&trader.RebalanceTargets,
+			&trader.RebalanceTolerancePct,
+			&trader.RebalanceIntervalMinutes,
+			&trader.UseOCO,
+			&trader.AIAnomalyIdleCycles,
+			&trader.AIAnomalyRepeatCycles,
+			&trader.AIAnomalyPause,
+			&trader.ProtectiveDefaults,
+			&trader.PositionAuditMinutes,
+			&trader.MinAvailableBalance,
 			&trader.CreatedAt, &trader.UpdatedAt,
 		)
 		if err != nil {
@@ -2011,6 +3391,65 @@ func (d *Database) GetTraderByID(traderID string) (*TraderRecord, error) {
 		       COALESCE(category, '') as category,
 		       COALESCE(trader_account_id, '') as trader_account_id,
 		       COALESCE(owner_user_id, '') as owner_user_id,
+		       COALESCE(require_stop_loss, 0) as require_stop_loss,
+		       COALESCE(default_stop_loss_pct, 0) as default_stop_loss_pct,
+		       COALESCE(exclude_held_from_candidates, 0) as exclude_held_from_candidates,
+		       COALESCE(analysis_only, 0) as analysis_only,
+		       COALESCE(warmup_minutes, 0) as warmup_minutes,
+		       COALESCE(skip_cycle_if_busy, 0) as skip_cycle_if_busy,
+		       COALESCE(max_position_age_hours, 0) as max_position_age_hours,
+		       COALESCE(allow_pyramiding, 0) as allow_pyramiding,
+		       COALESCE(max_adds_per_position, 2) as max_adds_per_position,
+		       COALESCE(enforce_daily_loss_stop, 0) as enforce_daily_loss_stop,
+		       COALESCE(allow_flip, 0) as allow_flip,
+		       COALESCE(min_confidence, 0) as min_confidence,
+		       COALESCE(signal_base_position_pct, 20) as signal_base_position_pct,
+		       COALESCE(signal_default_add_pct, 10) as signal_default_add_pct,
+		       COALESCE(equity_take_profit, 0) as equity_take_profit,
+		       COALESCE(equity_stop_loss, 0) as equity_stop_loss,
+		       COALESCE(equity_take_profit_pct, 0) as equity_take_profit_pct,
+		       COALESCE(equity_stop_loss_pct, 0) as equity_stop_loss_pct,
+		       COALESCE(auto_reprotect, 0) as auto_reprotect,
+		       COALESCE(public_display_name, '') as public_display_name,
+		       COALESCE(public_visibility, 1) as public_visibility,
+		       COALESCE(backup_exchange_id, '') as backup_exchange_id,
+		       COALESCE(trading_schedule, '') as trading_schedule,
+		       COALESCE(include_orderbook_depth, 0) as include_orderbook_depth,
+		       COALESCE(skip_if_btc_move_pct, 0) as skip_if_btc_move_pct,
+		       COALESCE(skip_if_funding_above, 0) as skip_if_funding_above,
+		       COALESCE(max_open_orders, 10) as max_open_orders,
+		       COALESCE(breakeven_at_profit_pct, 0) as breakeven_at_profit_pct,
+		       COALESCE(trail_stop_after_profit_pct, 0) as trail_stop_after_profit_pct,
+		       COALESCE(trail_lock_fraction, 0.5) as trail_lock_fraction,
+		       COALESCE(max_actions_per_cycle, 10) as max_actions_per_cycle,
+		       COALESCE(approval_required_first_trade, 0) as approval_required_first_trade,
+		       COALESCE(min_seconds_between_ai_calls, 0) as min_seconds_between_ai_calls,
+		       COALESCE(max_per_symbol_exposure_pct, 0) as max_per_symbol_exposure_pct,
+		       COALESCE(include_recent_trades, 1) as include_recent_trades,
+		       COALESCE(recent_trades_count, 5) as recent_trades_count,
+		       COALESCE(sizing_base, 'fixed') as sizing_base,
+		       ai_temperature, ai_top_p, COALESCE(ai_max_tokens, 0) as ai_max_tokens,
+		       COALESCE(on_ai_failure, 'hold') as on_ai_failure,
+		       COALESCE(baseline_reset_policy, 'none') as baseline_reset_policy,
+		       COALESCE(enforce_max_drawdown_stop, 0) as enforce_max_drawdown_stop, COALESCE(max_drawdown_stop_pct, 0) as max_drawdown_stop_pct, COALESCE(drawdown_stop_flatten, 0) as drawdown_stop_flatten,
+		       COALESCE(stop_approach_alert_pct, 0) as stop_approach_alert_pct,
+		       COALESCE(candidate_symbols, '') as candidate_symbols,
+		       COALESCE(min_holding_minutes, 0) as min_holding_minutes,
+		       COALESCE(strategy_sources, '') as strategy_sources,
+		       COALESCE(context_indicators, '') as context_indicators,
+		       COALESCE(context_timeframes, '') as context_timeframes,
+		       COALESCE(tp1_close_pct, 0) as tp1_close_pct,
+		       COALESCE(tp1_breakeven_stop, 0) as tp1_breakeven_stop,
+		       COALESCE(rebalance_targets, '') as rebalance_targets,
+		       COALESCE(rebalance_tolerance_pct, 0) as rebalance_tolerance_pct,
+		       COALESCE(rebalance_interval_minutes, 0) as rebalance_interval_minutes,
+		       COALESCE(use_oco, 0) as use_oco,
+		       COALESCE(ai_anomaly_idle_cycles, 0) as ai_anomaly_idle_cycles,
+		       COALESCE(ai_anomaly_repeat_cycles, 0) as ai_anomaly_repeat_cycles,
+		       COALESCE(ai_anomaly_pause, 0) as ai_anomaly_pause,
+		       COALESCE(protective_defaults, '') as protective_defaults,
+		       COALESCE(position_audit_minutes, 0) as position_audit_minutes,
+		       COALESCE(min_available_balance, 0) as min_available_balance,
 		       created_at, updated_at
 		FROM traders WHERE id = ?
 	`, traderID).Scan(
@@ -2021,6 +3460,65 @@ func (d *Database) GetTraderByID(traderID string) (*TraderRecord, error) {
 		&trader.CustomPrompt, &trader.OverrideBasePrompt, &trader.SystemPromptTemplate,
 		&trader.IsCrossMargin,
 		&trader.Category, &trader.TraderAccountID, &trader.OwnerUserID,
+		&trader.RequireStopLoss,
+		&trader.DefaultStopLossPct,
+		&trader.ExcludeHeldFromCandidates,
+		&trader.AnalysisOnly,
+		&trader.WarmupMinutes,
+		&trader.SkipCycleIfBusy,
+		&trader.MaxPositionAgeHours,
+		&trader.AllowPyramiding,
+		&trader.MaxAddsPerPosition,
+		&trader.EnforceDailyLossStop,
+		&trader.AllowFlip,
+		&trader.MinConfidence,
+		&trader.SignalBasePositionPct,
+		&trader.SignalDefaultAddPct,
+		&trader.EquityTakeProfit,
+		&trader.EquityStopLoss,
+		&trader.EquityTakeProfitPct,
+		&trader.EquityStopLossPct,
+		&trader.AutoReprotect,
+		&trader.PublicDisplayName,
+		&trader.PublicVisibility,
+		&trader.BackupExchangeID,
+		&trader.TradingSchedule,
+		&trader.IncludeOrderBookDepth,
+		&trader.SkipIfBTCMovePct,
+		&trader.SkipIfFundingAbove,
+		&trader.MaxOpenOrders,
+		&trader.BreakevenAtProfitPct,
+		&trader.TrailStopAfterProfitPct,
+		&trader.TrailLockFraction,
+		&trader.MaxActionsPerCycle,
+		&trader.RequireFirstTradeApproval,
+		&trader.MinSecondsBetweenAICalls,
+		&trader.MaxPerSymbolExposurePct,
+		&trader.IncludeRecentTrades,
+		&trader.RecentTradesCount,
+		&trader.SizingBase,
+		&trader.AITemperature, &trader.AITopP, &trader.AIMaxTokens,
+		&trader.OnAIFailure,
+		&trader.BaselineResetPolicy,
+		&trader.EnforceMaxDrawdownStop, &trader.MaxDrawdownStopPct, &trader.DrawdownStopFlatten,
+		&trader.StopApproachAlertPct,
+		&trader.CandidateSymbols,
+		&trader.MinHoldingMinutes,
+		&trader.StrategySources,
+		&trader.ContextIndicators,
+		&trader.ContextTimeframes,
+		&trader.TP1ClosePct,
+		&trader.TP1BreakevenStop,
+		&trader.RebalanceTargets,
+		&trader.RebalanceTolerancePct,
+		&trader.RebalanceIntervalMinutes,
+		&trader.UseOCO,
+		&trader.AIAnomalyIdleCycles,
+		&trader.AIAnomalyRepeatCycles,
+		&trader.AIAnomalyPause,
+		&trader.ProtectiveDefaults,
+		&trader.PositionAuditMinutes,
+		&trader.MinAvailableBalance,
 		&trader.CreatedAt, &trader.UpdatedAt,
 	)
 	if err != nil {
@@ -2262,6 +3760,65 @@ func (d *Database) GetTraderByAccountID(accountID string) (*TraderRecord, error)
 		       COALESCE(category, '') as category,
 		       COALESCE(trader_account_id, '') as trader_account_id,
 		       COALESCE(owner_user_id, '') as owner_user_id,
+		       COALESCE(require_stop_loss, 0) as require_stop_loss,
+		       COALESCE(default_stop_loss_pct, 0) as default_stop_loss_pct,
+		       COALESCE(exclude_held_from_candidates, 0) as exclude_held_from_candidates,
+		       COALESCE(analysis_only, 0) as analysis_only,
+		       COALESCE(warmup_minutes, 0) as warmup_minutes,
+		       COALESCE(skip_cycle_if_busy, 0) as skip_cycle_if_busy,
+		       COALESCE(max_position_age_hours, 0) as max_position_age_hours,
+		       COALESCE(allow_pyramiding, 0) as allow_pyramiding,
+		       COALESCE(max_adds_per_position, 2) as max_adds_per_position,
+		       COALESCE(enforce_daily_loss_stop, 0) as enforce_daily_loss_stop,
+		       COALESCE(allow_flip, 0) as allow_flip,
+		       COALESCE(min_confidence, 0) as min_confidence,
+		       COALESCE(signal_base_position_pct, 20) as signal_base_position_pct,
+		       COALESCE(signal_default_add_pct, 10) as signal_default_add_pct,
+		       COALESCE(equity_take_profit, 0) as equity_take_profit,
+		       COALESCE(equity_stop_loss, 0) as equity_stop_loss,
+		       COALESCE(equity_take_profit_pct, 0) as equity_take_profit_pct,
+		       COALESCE(equity_stop_loss_pct, 0) as equity_stop_loss_pct,
+		       COALESCE(auto_reprotect, 0) as auto_reprotect,
+		       COALESCE(public_display_name, '') as public_display_name,
+		       COALESCE(public_visibility, 1) as public_visibility,
+		       COALESCE(backup_exchange_id, '') as backup_exchange_id,
+		       COALESCE(trading_schedule, '') as trading_schedule,
+		       COALESCE(include_orderbook_depth, 0) as include_orderbook_depth,
+		       COALESCE(skip_if_btc_move_pct, 0) as skip_if_btc_move_pct,
+		       COALESCE(skip_if_funding_above, 0) as skip_if_funding_above,
+		       COALESCE(max_open_orders, 10) as max_open_orders,
+		       COALESCE(breakeven_at_profit_pct, 0) as breakeven_at_profit_pct,
+		       COALESCE(trail_stop_after_profit_pct, 0) as trail_stop_after_profit_pct,
+		       COALESCE(trail_lock_fraction, 0.5) as trail_lock_fraction,
+		       COALESCE(max_actions_per_cycle, 10) as max_actions_per_cycle,
+		       COALESCE(approval_required_first_trade, 0) as approval_required_first_trade,
+		       COALESCE(min_seconds_between_ai_calls, 0) as min_seconds_between_ai_calls,
+		       COALESCE(max_per_symbol_exposure_pct, 0) as max_per_symbol_exposure_pct,
+		       COALESCE(include_recent_trades, 1) as include_recent_trades,
+		       COALESCE(recent_trades_count, 5) as recent_trades_count,
+		       COALESCE(sizing_base, 'fixed') as sizing_base,
+		       ai_temperature, ai_top_p, COALESCE(ai_max_tokens, 0) as ai_max_tokens,
+		       COALESCE(on_ai_failure, 'hold') as on_ai_failure,
+		       COALESCE(baseline_reset_policy, 'none') as baseline_reset_policy,
+		       COALESCE(enforce_max_drawdown_stop, 0) as enforce_max_drawdown_stop, COALESCE(max_drawdown_stop_pct, 0) as max_drawdown_stop_pct, COALESCE(drawdown_stop_flatten, 0) as drawdown_stop_flatten,
+		       COALESCE(stop_approach_alert_pct, 0) as stop_approach_alert_pct,
+		       COALESCE(candidate_symbols, '') as candidate_symbols,
+		       COALESCE(min_holding_minutes, 0) as min_holding_minutes,
+		       COALESCE(strategy_sources, '') as strategy_sources,
+		       COALESCE(context_indicators, '') as context_indicators,
+		       COALESCE(context_timeframes, '') as context_timeframes,
+		       COALESCE(tp1_close_pct, 0) as tp1_close_pct,
+		       COALESCE(tp1_breakeven_stop, 0) as tp1_breakeven_stop,
+		       COALESCE(rebalance_targets, '') as rebalance_targets,
+		       COALESCE(rebalance_tolerance_pct, 0) as rebalance_tolerance_pct,
+		       COALESCE(rebalance_interval_minutes, 0) as rebalance_interval_minutes,
+		       COALESCE(use_oco, 0) as use_oco,
+		       COALESCE(ai_anomaly_idle_cycles, 0) as ai_anomaly_idle_cycles,
+		       COALESCE(ai_anomaly_repeat_cycles, 0) as ai_anomaly_repeat_cycles,
+		       COALESCE(ai_anomaly_pause, 0) as ai_anomaly_pause,
+		       COALESCE(protective_defaults, '') as protective_defaults,
+		       COALESCE(position_audit_minutes, 0) as position_audit_minutes,
+		       COALESCE(min_available_balance, 0) as min_available_balance,
 		       created_at, updated_at
 		FROM traders WHERE trader_account_id = ?
 	`, accountID).Scan(
@@ -2274,6 +3831,65 @@ func (d *Database) GetTraderByAccountID(accountID string) (*TraderRecord, error)
 		&trader.Category,
 		&trader.TraderAccountID,
 		&trader.OwnerUserID,
+		&trader.RequireStopLoss,
+		&trader.DefaultStopLossPct,
+		&trader.ExcludeHeldFromCandidates,
+		&trader.AnalysisOnly,
+		&trader.WarmupMinutes,
+		&trader.SkipCycleIfBusy,
+		&trader.MaxPositionAgeHours,
+		&trader.AllowPyramiding,
+		&trader.MaxAddsPerPosition,
+		&trader.EnforceDailyLossStop,
+		&trader.AllowFlip,
+		&trader.MinConfidence,
+		&trader.SignalBasePositionPct,
+		&trader.SignalDefaultAddPct,
+		&trader.EquityTakeProfit,
+		&trader.EquityStopLoss,
+		&trader.EquityTakeProfitPct,
+		&trader.EquityStopLossPct,
+		&trader.AutoReprotect,
+		&trader.PublicDisplayName,
+		&trader.PublicVisibility,
+		&trader.BackupExchangeID,
+		&trader.TradingSchedule,
+		&trader.IncludeOrderBookDepth,
+		&trader.SkipIfBTCMovePct,
+		&trader.SkipIfFundingAbove,
+		&trader.MaxOpenOrders,
+		&trader.BreakevenAtProfitPct,
+		&trader.TrailStopAfterProfitPct,
+		&trader.TrailLockFraction,
+		&trader.MaxActionsPerCycle,
+		&trader.RequireFirstTradeApproval,
+		&trader.MinSecondsBetweenAICalls,
+		&trader.MaxPerSymbolExposurePct,
+		&trader.IncludeRecentTrades,
+		&trader.RecentTradesCount,
+		&trader.SizingBase,
+		&trader.AITemperature, &trader.AITopP, &trader.AIMaxTokens,
+		&trader.OnAIFailure,
+		&trader.BaselineResetPolicy,
+		&trader.EnforceMaxDrawdownStop, &trader.MaxDrawdownStopPct, &trader.DrawdownStopFlatten,
+		&trader.StopApproachAlertPct,
+		&trader.CandidateSymbols,
+		&trader.MinHoldingMinutes,
+		&trader.StrategySources,
+		&trader.ContextIndicators,
+		&trader.ContextTimeframes,
+		&trader.TP1ClosePct,
+		&trader.TP1BreakevenStop,
+		&trader.RebalanceTargets,
+		&trader.RebalanceTolerancePct,
+		&trader.RebalanceIntervalMinutes,
+		&trader.UseOCO,
+		&trader.AIAnomalyIdleCycles,
+		&trader.AIAnomalyRepeatCycles,
+		&trader.AIAnomalyPause,
+		&trader.ProtectiveDefaults,
+		&trader.PositionAuditMinutes,
+		&trader.MinAvailableBalance,
 		&trader.CreatedAt, &trader.UpdatedAt,
 	)
 	if err != nil {
@@ -2294,6 +3910,25 @@ func (d *Database) DeleteGroupLeaderCategories(groupLeaderID string) error {
 	return err
 }
 
+// GetGroupLeaderIDsByOwner 获取由指定用户创建的所有小组组长ID
+func (d *Database) GetGroupLeaderIDsByOwner(ownerUserID string) ([]string, error) {
+	rows, err := d.db.Query(`SELECT DISTINCT group_leader_id FROM group_leader_categories WHERE owner_user_id = ?`, ownerUserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
 // TraderStrategyStatus 交易员策略状态
 type TraderStrategyStatus struct {
 	ID          int64     `json:"id"`
@@ -2341,6 +3976,9 @@ type StrategyDecisionHistory struct {
 	RawAIResponse    string    `json:"raw_ai_response"`
 	ExecutionSuccess bool      `json:"execution_success"`
 	ExecutionError   string    `json:"execution_error"`
+	AIProvider       string    `json:"ai_provider"`   // 实际响应的AI提供商
+	AIModelName      string    `json:"ai_model_name"` // 实际响应的AI模型
+	WasFallback      bool      `json:"was_fallback"`  // 是否由备用模型响应
 }
 
 // UpdateTraderStrategyStatus 更新策略状态
@@ -2378,6 +4016,105 @@ func (d *Database) UpdateTraderStrategyStatus(status *TraderStrategyStatus) erro
 	return err
 }
 
+// GetTraderPositionFirstSeen 读取交易员持久化的持仓首次出现时间（symbol_side -> 毫秒时间戳）
+func (d *Database) GetTraderPositionFirstSeen(traderID string) (map[string]int64, error) {
+	var raw sql.NullString
+	err := d.db.QueryRow(`SELECT position_first_seen FROM traders WHERE id = ?`, traderID).Scan(&raw)
+	if err != nil {
+		return nil, err
+	}
+
+	firstSeen := make(map[string]int64)
+	if !raw.Valid || raw.String == "" {
+		return firstSeen, nil
+	}
+	if err := json.Unmarshal([]byte(raw.String), &firstSeen); err != nil {
+		return nil, fmt.Errorf("解析持仓首次出现时间失败: %w", err)
+	}
+	return firstSeen, nil
+}
+
+// SaveTraderPositionFirstSeen 保存交易员的持仓首次出现时间（重启后用于恢复持仓时长）
+func (d *Database) SaveTraderPositionFirstSeen(traderID string, firstSeen map[string]int64) error {
+	data, err := json.Marshal(firstSeen)
+	if err != nil {
+		return err
+	}
+	_, err = d.db.Exec(`UPDATE traders SET position_first_seen = ? WHERE id = ?`, string(data), traderID)
+	return err
+}
+
+// PositionPyramidState 单个持仓的加仓状态（持久化到 traders.position_pyramid）
+type PositionPyramidState struct {
+	Adds       int     `json:"adds"`
+	StopLoss   float64 `json:"stop_loss"`
+	TakeProfit float64 `json:"take_profit"`
+}
+
+// GetTraderPositionPyramid 读取交易员持久化的持仓加仓状态（symbol_side -> 加仓状态）
+func (d *Database) GetTraderPositionPyramid(traderID string) (map[string]PositionPyramidState, error) {
+	var raw sql.NullString
+	err := d.db.QueryRow(`SELECT position_pyramid FROM traders WHERE id = ?`, traderID).Scan(&raw)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[string]PositionPyramidState)
+	if !raw.Valid || raw.String == "" {
+		return states, nil
+	}
+	if err := json.Unmarshal([]byte(raw.String), &states); err != nil {
+		return nil, fmt.Errorf("解析持仓加仓状态失败: %w", err)
+	}
+	return states, nil
+}
+
+// SaveTraderPositionPyramid 保存交易员的持仓加仓状态（重启后加仓次数不被重置）
+func (d *Database) SaveTraderPositionPyramid(traderID string, states map[string]PositionPyramidState) error {
+	data, err := json.Marshal(states)
+	if err != nil {
+		return err
+	}
+	_, err = d.db.Exec(`UPDATE traders SET position_pyramid = ? WHERE id = ?`, string(data), traderID)
+	return err
+}
+
+// GetTraderDrawdownState 读取交易员持久化的净值历史峰值和最大回撤硬止损待命状态
+func (d *Database) GetTraderDrawdownState(traderID string) (peakEquity float64, armed bool, err error) {
+	err = d.db.QueryRow(`SELECT COALESCE(peak_equity, 0), COALESCE(drawdown_stop_armed, 1) FROM traders WHERE id = ?`, traderID).Scan(&peakEquity, &armed)
+	return peakEquity, armed, err
+}
+
+// SaveTraderDrawdownState 保存交易员的净值历史峰值和最大回撤硬止损待命状态（重启后恢复，触发后需净值创新高才重新待命）
+func (d *Database) SaveTraderDrawdownState(traderID string, peakEquity float64, armed bool) error {
+	_, err := d.db.Exec(`UPDATE traders SET peak_equity = ?, drawdown_stop_armed = ? WHERE id = ?`, peakEquity, armed, traderID)
+	return err
+}
+
+// GetTraderFirstTradeApproved 读取交易员首笔交易是否已审批通过并执行成功
+func (d *Database) GetTraderFirstTradeApproved(traderID string) (approved bool, err error) {
+	err = d.db.QueryRow(`SELECT COALESCE(first_trade_approved, 0) FROM traders WHERE id = ?`, traderID).Scan(&approved)
+	return approved, err
+}
+
+// SaveTraderFirstTradeApproved 保存交易员首笔交易审批状态（重启后不再重复要求审批）
+func (d *Database) SaveTraderFirstTradeApproved(traderID string, approved bool) error {
+	_, err := d.db.Exec(`UPDATE traders SET first_trade_approved = ? WHERE id = ?`, approved, traderID)
+	return err
+}
+
+// GetTraderEquityBracketHit 获取交易员已触发的账户净值止盈/止损（空字符串表示未暂停）
+func (d *Database) GetTraderEquityBracketHit(traderID string) (hit string, err error) {
+	err = d.db.QueryRow(`SELECT COALESCE(equity_bracket_hit, '') FROM traders WHERE id = ?`, traderID).Scan(&hit)
+	return hit, err
+}
+
+// SaveTraderEquityBracketHit 保存交易员账户净值止盈/止损暂停状态（重启进程后继续暂停）
+func (d *Database) SaveTraderEquityBracketHit(traderID, hit string) error {
+	_, err := d.db.Exec(`UPDATE traders SET equity_bracket_hit = ? WHERE id = ?`, hit, traderID)
+	return err
+}
+
 // GetTraderStrategyStatuses 获取交易员的所有策略状态
 func (d *Database) GetTraderStrategyStatuses(traderID string) ([]*TraderStrategyStatus, error) {
 	query := `SELECT id, trader_id, strategy_id, symbol, had_position, status, entry_price, quantity, realized_pnl, updated_at FROM trader_strategy_status WHERE trader_id = ?`
@@ -2424,15 +4161,18 @@ func (d *Database) SaveStrategyDecision(history *StrategyDecisionHistory) error
 			current_price, target_price, position_side, position_qty,
 			amount_percent, reason, rsi_1h, rsi_4h, macd_4h,
 			system_prompt, input_prompt, raw_ai_response,
-			execution_success, execution_error
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			execution_success, execution_error,
+			ai_provider, ai_model_name, was_fallback
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
+	// decision_time 统一以 UTC 存储，与清理条件 PruneStrategyDecisionHistory 保持一致
 	_, err := d.db.Exec(query,
-		history.TraderID, history.StrategyID, history.DecisionTime, history.Action, history.Symbol,
+		history.TraderID, history.StrategyID, history.DecisionTime.UTC(), history.Action, history.Symbol,
 		history.CurrentPrice, history.TargetPrice, history.PositionSide, history.PositionQty,
 		history.AmountPercent, history.Reason, history.RSI1H, history.RSI4H, history.MACD4H,
 		history.SystemPrompt, history.InputPrompt, history.RawAIResponse,
 		history.ExecutionSuccess, history.ExecutionError,
+		history.AIProvider, history.AIModelName, history.WasFallback,
 	)
 	return err
 }
@@ -2445,10 +4185,10 @@ func (d *Database) LogExecutionEvent(traderID, strategyID, action, symbol, reaso
 		INSERT INTO strategy_decision_history (
 			trader_id, strategy_id, decision_time, action, symbol,
 			reason, execution_success, execution_error
-		) VALUES (?, ?, CURRENT_TIMESTAMP, ?, ?, ?, ?, ?)
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	_, err := d.db.Exec(query,
-		traderID, strategyID, action, symbol,
+		traderID, strategyID, time.Now().UTC(), action, symbol,
 		reason, success, errInfo,
 	)
 	if err != nil {
@@ -2457,6 +4197,240 @@ func (d *Database) LogExecutionEvent(traderID, strategyID, action, symbol, reaso
 	return err
 }
 
+// PruneStrategyDecisionHistory 删除交易员 before 之前的策略决策历史，始终保留最新的 minKeep 条
+func (d *Database) PruneStrategyDecisionHistory(traderID string, before time.Time, minKeep int) (int64, error) {
+	if minKeep < 0 {
+		minKeep = 0
+	}
+	// 子查询多包一层派生表，兼容 MySQL 不允许在子查询中直接 LIMIT 同一张表的限制
+	result, err := d.db.Exec(`
+		DELETE FROM strategy_decision_history
+		WHERE trader_id = ? AND decision_time < ?
+		AND id NOT IN (
+			SELECT id FROM (
+				SELECT id FROM strategy_decision_history
+				WHERE trader_id = ?
+				ORDER BY decision_time DESC, id DESC
+				LIMIT ?
+			) AS recent
+		)
+	`, traderID, before.UTC(), traderID, minKeep)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// 系统级杠杆上限默认值
+const (
+	DefaultMaxBTCETHLeverage  = 125
+	DefaultMaxAltcoinLeverage = 75
+)
+
+// GetLeverageCeilings 获取系统级杠杆上限（BTC/ETH、山寨币），未配置或非法时使用默认值
+func (d *Database) GetLeverageCeilings() (btcEth, altcoin int) {
+	btcEth, altcoin = DefaultMaxBTCETHLeverage, DefaultMaxAltcoinLeverage
+	if value, err := d.GetSystemConfig("max_btc_eth_leverage"); err == nil {
+		if v, err := strconv.Atoi(value); err == nil && v > 0 {
+			btcEth = v
+		}
+	}
+	if value, err := d.GetSystemConfig("max_altcoin_leverage"); err == nil {
+		if v, err := strconv.Atoi(value); err == nil && v > 0 {
+			altcoin = v
+		}
+	}
+	return btcEth, altcoin
+}
+
+// 初始余额校验默认值
+const (
+	DefaultMaxInitialBalance          = 10000000.0
+	DefaultInitialBalanceDeviationPct = 50.0
+)
+
+// GetInitialBalanceLimits 获取初始余额上限与允许的实际余额偏差百分比，未配置或非法时使用默认值（配置为0表示不限制/不检查）
+func (d *Database) GetInitialBalanceLimits() (maxBalance, deviationPct float64) {
+	maxBalance, deviationPct = DefaultMaxInitialBalance, DefaultInitialBalanceDeviationPct
+	if value, err := d.GetSystemConfig("max_initial_balance"); err == nil {
+		if v, err := strconv.ParseFloat(value, 64); err == nil && v >= 0 {
+			maxBalance = v
+		}
+	}
+	if value, err := d.GetSystemConfig("initial_balance_tolerance"); err == nil {
+		if v, err := strconv.ParseFloat(value, 64); err == nil && v >= 0 {
+			deviationPct = v
+		}
+	}
+	return maxBalance, deviationPct
+}
+
+// GetOrderRateLimits 获取各交易所下单/撤单限速（system_config order_rate_limits，格式 "binance=10,bitget=5"，次/秒）
+// 未配置或格式非法的条目忽略（交易器使用默认限速）
+func (d *Database) GetOrderRateLimits() map[string]float64 {
+	limits := map[string]float64{}
+	value, err := d.GetSystemConfig("order_rate_limits")
+	if err != nil {
+		return limits
+	}
+	for _, item := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(item), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		exchange := strings.ToLower(strings.TrimSpace(parts[0]))
+		rate, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if exchange == "" || err != nil || rate <= 0 {
+			continue
+		}
+		limits[exchange] = rate
+	}
+	return limits
+}
+
+// GetDisplayFXRates 获取法币展示汇率（system_config display_fx_rates，格式 "EUR=0.92,CNY=7.2"，即 1 USDT 折合的数量）
+// 未配置或格式非法的条目忽略
+func (d *Database) GetDisplayFXRates() map[string]float64 {
+	rates := map[string]float64{}
+	value, err := d.GetSystemConfig("display_fx_rates")
+	if err != nil {
+		return rates
+	}
+	for _, item := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(item), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		currency := strings.ToUpper(strings.TrimSpace(parts[0]))
+		rate, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if currency == "" || err != nil || rate <= 0 {
+			continue
+		}
+		rates[currency] = rate
+	}
+	return rates
+}
+
+// GetUserDisplayCurrency 获取用户的展示币种（未设置时为 USDT）
+func (d *Database) GetUserDisplayCurrency(userID string) string {
+	var currency sql.NullString
+	if err := d.db.QueryRow(`SELECT display_currency FROM users WHERE id = ?`, userID).Scan(&currency); err != nil || currency.String == "" {
+		return "USDT"
+	}
+	return currency.String
+}
+
+// UpdateUserDisplayCurrency 更新用户的展示币种
+func (d *Database) UpdateUserDisplayCurrency(userID, currency string) error {
+	_, err := d.db.Exec(fmt.Sprintf(`UPDATE users SET display_currency = ?, updated_at = %s WHERE id = ?`, d.getTimeFunc()), currency, userID)
+	return err
+}
+
+// MaintenanceModeEnabled 平台维护模式是否开启（system_config maintenance_mode，默认关闭）
+func (d *Database) MaintenanceModeEnabled() bool {
+	value, err := d.GetSystemConfig("maintenance_mode")
+	return err == nil && value == "true"
+}
+
+// SetMaintenanceMode 开启/关闭平台维护模式
+func (d *Database) SetMaintenanceMode(enabled bool) error {
+	return d.SetSystemConfig("maintenance_mode", strconv.FormatBool(enabled))
+}
+
+// GetDustHandling 平仓后残留粉尘持仓的处理方式（system_config dust_handling，默认 close）
+func (d *Database) GetDustHandling() string {
+	value, err := d.GetSystemConfig("dust_handling")
+	if err != nil || value == "" {
+		return "close"
+	}
+	return value
+}
+
+// GetExchangeMaintenanceBackoff 检测到交易所维护时的退避时长（system_config exchange_backoff_minutes，未配置或非法时返回0，由调用方使用默认值）
+func (d *Database) GetExchangeMaintenanceBackoff() time.Duration {
+	value, err := d.GetSystemConfig("exchange_backoff_minutes")
+	if err != nil {
+		return 0
+	}
+	minutes, err := strconv.Atoi(value)
+	if err != nil || minutes <= 0 {
+		return 0
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// GetRecvWindowMillis 签名请求的 recvWindow（system_config recv_window_ms，未配置或非法时返回0，由调用方使用默认值）
+func (d *Database) GetRecvWindowMillis() int64 {
+	value, err := d.GetSystemConfig("recv_window_ms")
+	if err != nil {
+		return 0
+	}
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || ms <= 0 {
+		return 0
+	}
+	return ms
+}
+
+// CancelOppositeOrdersEnabled 反手/开反方向仓位前是否撤销原方向挂单（system_config cancel_opposite_orders，默认开启，仅 false 时关闭）
+func (d *Database) CancelOppositeOrdersEnabled() bool {
+	value, err := d.GetSystemConfig("cancel_opposite_orders")
+	return err != nil || value != "false"
+}
+
+// DefaultCORSAllowedOrigins 默认允许跨域访问的来源（本地前端）
+const DefaultCORSAllowedOrigins = "http://localhost:3000,http://127.0.0.1:3000"
+
+// GetCORSAllowedOrigins 获取允许跨域访问的来源列表（逗号分隔），优先使用环境变量 CORS_ALLOWED_ORIGINS
+func (d *Database) GetCORSAllowedOrigins() string {
+	if value := strings.TrimSpace(os.Getenv("CORS_ALLOWED_ORIGINS")); value != "" {
+		return value
+	}
+	value, err := d.GetSystemConfig("cors_allowed_origins")
+	if err != nil || strings.TrimSpace(value) == "" {
+		return DefaultCORSAllowedOrigins
+	}
+	return value
+}
+
+// DefaultMaxRequestBodyBytes 默认API请求体大小上限（1MB）
+const DefaultMaxRequestBodyBytes int64 = 1 << 20
+
+// GetMaxRequestBodyBytes 获取API请求体大小上限（字节，0=不限制），优先使用环境变量 MAX_REQUEST_BODY_BYTES
+func (d *Database) GetMaxRequestBodyBytes() int64 {
+	value := strings.TrimSpace(os.Getenv("MAX_REQUEST_BODY_BYTES"))
+	if value == "" {
+		value, _ = d.GetSystemConfig("max_request_body_bytes")
+	}
+	limit, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || limit < 0 {
+		return DefaultMaxRequestBodyBytes
+	}
+	return limit
+}
+
+// AutoResumeTradersEnabled 启动时是否自动恢复重启前处于运行状态的交易员（默认开启）
+func (d *Database) AutoResumeTradersEnabled() bool {
+	value, err := d.GetSystemConfig("auto_resume_traders")
+	if err != nil || value == "" {
+		return true
+	}
+	return value != "false"
+}
+
+// GetDecisionLogRetentionDays 获取决策日志保留天数（默认90天，0表示不自动清理）
+func (d *Database) GetDecisionLogRetentionDays() int {
+	value, err := d.GetSystemConfig("decision_log_retention_days")
+	if err != nil || value == "" {
+		return 90
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 0 {
+		return 90
+	}
+	return days
+}
+
 // GetStrategyDecisionHistory 获取策略决策历史(按时间倒序,支持分页)
 func (d *Database) GetStrategyDecisionHistory(traderID string, limit int) ([]*StrategyDecisionHistory, error) {
 	if limit <= 0 {
@@ -2468,7 +4442,8 @@ func (d *Database) GetStrategyDecisionHistory(traderID string, limit int) ([]*St
 		       current_price, target_price, position_side, position_qty,
 		       amount_percent, reason, rsi_1h, rsi_4h, macd_4h,
 		       system_prompt, input_prompt, raw_ai_response,
-		       execution_success, execution_error
+		       execution_success, execution_error,
+		       COALESCE(ai_provider, ''), COALESCE(ai_model_name, ''), COALESCE(was_fallback, 0)
 		FROM strategy_decision_history
 		WHERE trader_id = ?
 		ORDER BY decision_time DESC
@@ -2490,6 +4465,7 @@ func (d *Database) GetStrategyDecisionHistory(traderID string, limit int) ([]*St
 			&h.AmountPercent, &h.Reason, &h.RSI1H, &h.RSI4H, &h.MACD4H,
 			&h.SystemPrompt, &h.InputPrompt, &h.RawAIResponse,
 			&h.ExecutionSuccess, &h.ExecutionError,
+			&h.AIProvider, &h.AIModelName, &h.WasFallback,
 		)
 		if err != nil {
 			return nil, err
@@ -2507,7 +4483,8 @@ func (d *Database) GetAllOpenStrategyDecisions(traderID string) ([]*StrategyDeci
 		       current_price, target_price, position_side, position_qty,
 		       amount_percent, reason, rsi_1h, rsi_4h, macd_4h,
 		       system_prompt, input_prompt, raw_ai_response,
-		       execution_success, execution_error
+		       execution_success, execution_error,
+		       COALESCE(ai_provider, ''), COALESCE(ai_model_name, ''), COALESCE(was_fallback, 0)
 		FROM strategy_decision_history
 		WHERE trader_id = ?
 		  AND (UPPER(action) LIKE '%OPEN%' OR UPPER(action) LIKE '%ADD%')
@@ -2529,6 +4506,7 @@ func (d *Database) GetAllOpenStrategyDecisions(traderID string) ([]*StrategyDeci
 			&h.AmountPercent, &h.Reason, &h.RSI1H, &h.RSI4H, &h.MACD4H,
 			&h.SystemPrompt, &h.InputPrompt, &h.RawAIResponse,
 			&h.ExecutionSuccess, &h.ExecutionError,
+			&h.AIProvider, &h.AIModelName, &h.WasFallback,
 		)
 		if err != nil {
 			return nil, err
@@ -2546,7 +4524,8 @@ func (d *Database) GetAllCloseStrategyDecisions(traderID string) ([]*StrategyDec
 		       current_price, target_price, position_side, position_qty,
 		       amount_percent, reason, rsi_1h, rsi_4h, macd_4h,
 		       system_prompt, input_prompt, raw_ai_response,
-		       execution_success, execution_error
+		       execution_success, execution_error,
+		       COALESCE(ai_provider, ''), COALESCE(ai_model_name, ''), COALESCE(was_fallback, 0)
 		FROM strategy_decision_history
 		WHERE trader_id = ?
 		  AND (UPPER(action) LIKE '%CLOSE%' OR UPPER(action) LIKE '%EMERGENCY_CLOSE%')
@@ -2568,6 +4547,7 @@ func (d *Database) GetAllCloseStrategyDecisions(traderID string) ([]*StrategyDec
 			&h.AmountPercent, &h.Reason, &h.RSI1H, &h.RSI4H, &h.MACD4H,
 			&h.SystemPrompt, &h.InputPrompt, &h.RawAIResponse,
 			&h.ExecutionSuccess, &h.ExecutionError,
+			&h.AIProvider, &h.AIModelName, &h.WasFallback,
 		)
 		if err != nil {
 			return nil, err
@@ -2585,7 +4565,8 @@ func (d *Database) GetAllSLTPStrategyDecisions(traderID string) ([]*StrategyDeci
 		       current_price, target_price, position_side, position_qty,
 		       amount_percent, reason, rsi_1h, rsi_4h, macd_4h,
 		       system_prompt, input_prompt, raw_ai_response,
-		       execution_success, execution_error
+		       execution_success, execution_error,
+		       COALESCE(ai_provider, ''), COALESCE(ai_model_name, ''), COALESCE(was_fallback, 0)
 		FROM strategy_decision_history
 		WHERE trader_id = ?
 		  AND (
@@ -2617,6 +4598,7 @@ func (d *Database) GetAllSLTPStrategyDecisions(traderID string) ([]*StrategyDeci
 			&h.AmountPercent, &h.Reason, &h.RSI1H, &h.RSI4H, &h.MACD4H,
 			&h.SystemPrompt, &h.InputPrompt, &h.RawAIResponse,
 			&h.ExecutionSuccess, &h.ExecutionError,
+			&h.AIProvider, &h.AIModelName, &h.WasFallback,
 		)
 		if err != nil {
 			return nil, err
@@ -2635,7 +4617,8 @@ func (d *Database) GetAllOrderStrategyDecisions(traderID string) ([]*StrategyDec
 		       current_price, target_price, position_side, position_qty,
 		       amount_percent, reason, rsi_1h, rsi_4h, macd_4h,
 		       system_prompt, input_prompt, raw_ai_response,
-		       execution_success, execution_error
+		       execution_success, execution_error,
+		       COALESCE(ai_provider, ''), COALESCE(ai_model_name, ''), COALESCE(was_fallback, 0)
 		FROM strategy_decision_history
 		WHERE trader_id = ?
 		  AND (
@@ -2664,6 +4647,7 @@ func (d *Database) GetAllOrderStrategyDecisions(traderID string) ([]*StrategyDec
 			&h.AmountPercent, &h.Reason, &h.RSI1H, &h.RSI4H, &h.MACD4H,
 			&h.SystemPrompt, &h.InputPrompt, &h.RawAIResponse,
 			&h.ExecutionSuccess, &h.ExecutionError,
+			&h.AIProvider, &h.AIModelName, &h.WasFallback,
 		)
 		if err != nil {
 			return nil, err
@@ -2686,7 +4670,8 @@ func (d *Database) GetStrategyDecisionsByStrategyID(strategyID string, limit int
 		       current_price, target_price, position_side, position_qty,
 		       amount_percent, reason, rsi_1h, rsi_4h, macd_4h,
 		       system_prompt, input_prompt, raw_ai_response,
-		       execution_success, execution_error
+		       execution_success, execution_error,
+		       COALESCE(ai_provider, ''), COALESCE(ai_model_name, ''), COALESCE(was_fallback, 0)
 		FROM strategy_decision_history
 		WHERE strategy_id = ?
 		ORDER BY decision_time DESC
@@ -2708,6 +4693,7 @@ func (d *Database) GetStrategyDecisionsByStrategyID(strategyID string, limit int
 			&h.AmountPercent, &h.Reason, &h.RSI1H, &h.RSI4H, &h.MACD4H,
 			&h.SystemPrompt, &h.InputPrompt, &h.RawAIResponse,
 			&h.ExecutionSuccess, &h.ExecutionError,
+			&h.AIProvider, &h.AIModelName, &h.WasFallback,
 		)
 		if err != nil {
 			return nil, err
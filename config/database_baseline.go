@@ -0,0 +1,183 @@
+package config
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// TraderBaseline 交易员的周期净值基准（baseline_reset_policy 每次滚动新增一条，历史记录保留用于分周期盈亏统计）
+type TraderBaseline struct {
+	ID          int64      `json:"id"`
+	TraderID    string     `json:"trader_id"`
+	Policy      string     `json:"policy"`       // 创建该周期时的滚动策略（weekly/monthly）
+	Baseline    float64    `json:"baseline"`     // 周期期初净值（期间的充值/提现会等额调整）
+	PeriodStart time.Time  `json:"period_start"` // 周期开始时间（UTC）
+	EndEquity   *float64   `json:"end_equity"`   // 周期期末净值（当前周期为 nil）
+	PeriodEnd   *time.Time `json:"period_end"`   // 周期结束时间（当前周期为 nil）
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// scanTraderBaseline 扫描一行周期基准记录
+func scanTraderBaseline(scanner interface{ Scan(...interface{}) error }) (*TraderBaseline, error) {
+	var b TraderBaseline
+	var endEquity sql.NullFloat64
+	var periodEnd sql.NullTime
+	if err := scanner.Scan(&b.ID, &b.TraderID, &b.Policy, &b.Baseline, &b.PeriodStart, &endEquity, &periodEnd, &b.CreatedAt); err != nil {
+		return nil, err
+	}
+	if endEquity.Valid {
+		b.EndEquity = &endEquity.Float64
+	}
+	if periodEnd.Valid {
+		b.PeriodEnd = &periodEnd.Time
+	}
+	return &b, nil
+}
+
+// GetLatestTraderBaseline 获取交易员当前（最近一个）周期的净值基准，没有记录时返回 nil
+func (d *Database) GetLatestTraderBaseline(traderID string) (*TraderBaseline, error) {
+	row := d.db.QueryRow(`SELECT id, trader_id, policy, baseline, period_start, end_equity, period_end, created_at
+		FROM trader_baselines WHERE trader_id = ? ORDER BY period_start DESC, id DESC LIMIT 1`, traderID)
+	b, err := scanTraderBaseline(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return b, err
+}
+
+// GetTraderBaselines 获取交易员的周期净值基准历史（按周期开始时间倒序，limit<=0 时不限制）
+func (d *Database) GetTraderBaselines(traderID string, limit int) ([]*TraderBaseline, error) {
+	query := `SELECT id, trader_id, policy, baseline, period_start, end_equity, period_end, created_at
+		FROM trader_baselines WHERE trader_id = ? ORDER BY period_start DESC, id DESC`
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	rows, err := d.db.Query(query, traderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	baselines := []*TraderBaseline{}
+	for rows.Next() {
+		b, err := scanTraderBaseline(rows)
+		if err != nil {
+			return nil, err
+		}
+		baselines = append(baselines, b)
+	}
+	return baselines, rows.Err()
+}
+
+// RollTraderBaseline 滚动到新的周期基准：补记上一周期（prev，可为 nil）的期末净值和结束时间，并新增 next（成功后回填 ID）
+func (d *Database) RollTraderBaseline(prev *TraderBaseline, endEquity float64, next *TraderBaseline) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if prev != nil && prev.ID > 0 {
+		if _, err := tx.Exec(`UPDATE trader_baselines SET end_equity = ?, period_end = ? WHERE id = ?`,
+			endEquity, next.PeriodStart, prev.ID); err != nil {
+			return fmt.Errorf("更新上一周期净值基准失败: %w", err)
+		}
+	}
+	result, err := tx.Exec(`INSERT INTO trader_baselines (trader_id, policy, baseline, period_start) VALUES (?, ?, ?, ?)`,
+		next.TraderID, next.Policy, next.Baseline, next.PeriodStart)
+	if err != nil {
+		return fmt.Errorf("新增周期净值基准失败: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	if id, err := result.LastInsertId(); err == nil {
+		next.ID = id
+	}
+	if prev != nil {
+		prev.EndEquity = &endEquity
+		periodEnd := next.PeriodStart
+		prev.PeriodEnd = &periodEnd
+	}
+	return nil
+}
+
+// AdjustTraderBaseline 按外部充值/提现净额调整周期基准（与 AdjustTraderInitialBalance 一致，已实现盈亏不受影响）
+func (d *Database) AdjustTraderBaseline(id int64, delta float64) error {
+	_, err := d.db.Exec(`UPDATE trader_baselines SET baseline = baseline + ? WHERE id = ?`, delta, id)
+	return err
+}
+
+// 初始余额手动调整方式
+const (
+	BaselineAuditModeSet   = "set"   // 直接设置新的初始余额（纠正错误的盈亏基准）
+	BaselineAuditModeDelta = "delta" // 按已知的充值（正数）/提现（负数）金额增量调整
+)
+
+// BaselineAudit 初始余额（全部时间盈亏基准）手动调整的审计记录
+type BaselineAudit struct {
+	ID              int64     `json:"id"`
+	TraderID        string    `json:"trader_id"`
+	UserID          string    `json:"user_id"` // 执行调整的用户
+	Mode            string    `json:"mode"`    // set / delta
+	PreviousBalance float64   `json:"previous_balance"`
+	NewBalance      float64   `json:"new_balance"`
+	Delta           float64   `json:"delta"` // NewBalance - PreviousBalance
+	Reason          string    `json:"reason"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// SetTraderInitialBalanceWithAudit 手动设置交易员初始余额并写入审计记录（同一事务，成功后回填 ID 和创建时间）。
+// 与被禁用的 UpdateTraderInitialBalance 不同，这里只由用户显式调用，且保留调整前的值和原因
+func (d *Database) SetTraderInitialBalanceWithAudit(audit *BaselineAudit) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE traders SET initial_balance = ? WHERE id = ?`, audit.NewBalance, audit.TraderID); err != nil {
+		return fmt.Errorf("更新初始余额失败: %w", err)
+	}
+
+	createdAt := time.Now().UTC()
+	result, err := tx.Exec(`INSERT INTO trader_baseline_audits (trader_id, user_id, mode, previous_balance, new_balance, delta, reason, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		audit.TraderID, audit.UserID, audit.Mode, audit.PreviousBalance, audit.NewBalance, audit.Delta, audit.Reason, createdAt)
+	if err != nil {
+		return fmt.Errorf("写入初始余额审计记录失败: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	if id, err := result.LastInsertId(); err == nil {
+		audit.ID = id
+	}
+	audit.CreatedAt = createdAt
+	return nil
+}
+
+// GetBaselineAudits 获取交易员初始余额手动调整的审计记录（按时间倒序，limit<=0 时不限制）
+func (d *Database) GetBaselineAudits(traderID string, limit int) ([]*BaselineAudit, error) {
+	query := `SELECT id, trader_id, user_id, mode, previous_balance, new_balance, delta, reason, created_at
+		FROM trader_baseline_audits WHERE trader_id = ? ORDER BY created_at DESC, id DESC`
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	rows, err := d.db.Query(query, traderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	audits := []*BaselineAudit{}
+	for rows.Next() {
+		var a BaselineAudit
+		if err := rows.Scan(&a.ID, &a.TraderID, &a.UserID, &a.Mode, &a.PreviousBalance, &a.NewBalance, &a.Delta, &a.Reason, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		audits = append(audits, &a)
+	}
+	return audits, rows.Err()
+}
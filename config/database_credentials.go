@@ -0,0 +1,97 @@
+package config
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// AIModelCredentialFields AI模型可单独清除的敏感字段（数据库列名）
+var AIModelCredentialFields = []string{"api_key", "custom_headers"}
+
+// ExchangeCredentialFields 交易所可单独清除的敏感字段（数据库列名）
+var ExchangeCredentialFields = []string{"api_key", "secret_key", "passphrase", "aster_private_key"}
+
+// ErrUnknownCredentialField 不支持清除的凭证字段
+var ErrUnknownCredentialField = errors.New("unknown credential field")
+
+// ClearAIModelCredential 清除AI模型已保存的单个敏感字段（用于无法解密后重新输入）
+func (d *Database) ClearAIModelCredential(userID, id, field string) error {
+	if !isCredentialField(AIModelCredentialFields, field) {
+		return ErrUnknownCredentialField
+	}
+
+	// field 已通过白名单校验，可安全拼接列名
+	result, err := d.db.Exec(fmt.Sprintf(`
+		UPDATE ai_models SET %s = '', updated_at = %s
+		WHERE id = ? AND user_id = ?
+	`, field, d.getTimeFunc()), id, userID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ClearExchangeCredential 清除交易所已保存的单个敏感字段，其他字段保持不变
+func (d *Database) ClearExchangeCredential(userID, id, field string) error {
+	if !isCredentialField(ExchangeCredentialFields, field) {
+		return ErrUnknownCredentialField
+	}
+
+	// field 已通过白名单校验，可安全拼接列名
+	result, err := d.db.Exec(fmt.Sprintf(`
+		UPDATE exchanges SET %s = '', updated_at = %s
+		WHERE id = ? AND user_id = ?
+	`, field, d.getTimeFunc()), id, userID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// isCredentialField 字段是否在可清除的凭证字段白名单中
+func isCredentialField(fields []string, field string) bool {
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeCustomHeaders 将自定义API附加请求头序列化为 JSON 并加密存储（为空时存空字符串）
+func (d *Database) encodeCustomHeaders(headers map[string]string) string {
+	if len(headers) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(headers)
+	if err != nil {
+		return ""
+	}
+	return d.encryptSensitiveData(string(data))
+}
+
+// decodeCustomHeaders 解密并解析已保存的自定义API附加请求头，无法解密时返回 false
+func (d *Database) decodeCustomHeaders(stored string) (map[string]string, bool) {
+	if stored == "" {
+		return nil, true
+	}
+	plaintext, ok := d.decryptSensitiveField(stored)
+	if !ok {
+		return nil, false
+	}
+	var headers map[string]string
+	if err := json.Unmarshal([]byte(plaintext), &headers); err != nil {
+		log.Printf("⚠️ 解析自定义请求头失败: %v", err)
+		return nil, true
+	}
+	return headers, true
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+// TestUndecryptableCredentialsAndClear 存储的密文损坏时应标记字段，并可单独清除后重新输入
+func TestUndecryptableCredentialsAndClear(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	if db.cryptoService == nil {
+		t.Skip("加密服务不可用")
+	}
+
+	userID := "test-user-001"
+	if err := db.UpdateExchange(userID, "binance", true, "api-key", "secret-key", "", false, "", "", "", "", "binance", ""); err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	// 模拟密钥变更后无法解密的密文
+	if _, err := db.db.Exec(`UPDATE exchanges SET secret_key = ? WHERE id = ? AND user_id = ?`, "ENC:v1:AAAA:BBBB", "binance", userID); err != nil {
+		t.Fatalf("写入损坏密文失败: %v", err)
+	}
+
+	exchanges, err := db.GetExchanges(userID)
+	if err != nil || len(exchanges) == 0 {
+		t.Fatalf("获取配置失败: %v", err)
+	}
+	if exchanges[0].SecretKey != "" {
+		t.Errorf("无法解密的字段不应返回密文")
+	}
+	if len(exchanges[0].UndecryptableFields) != 1 || exchanges[0].UndecryptableFields[0] != "secret_key" {
+		t.Errorf("应标记 secret_key 无法解密，实际 %v", exchanges[0].UndecryptableFields)
+	}
+
+	if err := db.ClearExchangeCredential(userID, "binance", "secret_key"); err != nil {
+		t.Fatalf("清除凭证失败: %v", err)
+	}
+	exchanges, _ = db.GetExchanges(userID)
+	if len(exchanges[0].UndecryptableFields) != 0 {
+		t.Errorf("清除后不应再有无法解密的字段，实际 %v", exchanges[0].UndecryptableFields)
+	}
+	if exchanges[0].APIKey != "api-key" {
+		t.Errorf("清除单个字段不应影响其他字段，APIKey=%s", exchanges[0].APIKey)
+	}
+
+	if err := db.ClearExchangeCredential(userID, "binance", "label"); !errors.Is(err, ErrUnknownCredentialField) {
+		t.Errorf("不支持的字段应返回 ErrUnknownCredentialField，实际 %v", err)
+	}
+	if err := db.ClearExchangeCredential(userID, "okx", "api_key"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("不存在的交易所应返回 sql.ErrNoRows，实际 %v", err)
+	}
+}
@@ -37,6 +37,11 @@ func NewMySQLDatabase(dsn string) (*Database, error) {
 		return nil, fmt.Errorf("创建MySQL表失败: %w", err)
 	}
 
+	// 补齐建表语句之后新增的列（已有数据库升级时 CREATE TABLE IF NOT EXISTS 不会添加新列）
+	if err := database.migrateMySQLAddedColumns(); err != nil {
+		return nil, fmt.Errorf("迁移MySQL增量列失败: %w", err)
+	}
+
 	// 设置全局实例
 	GlobalDB = database
 
@@ -173,6 +178,21 @@ func (d *Database) createMySQLTables() error {
 			UNIQUE KEY unique_user_id (user_id)
 		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
 
+		// 用户命名信号源表（多信号源模式，按权重合并候选币种）
+		`CREATE TABLE IF NOT EXISTS user_signal_source_items (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			user_id VARCHAR(255) NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			url VARCHAR(1024) NOT NULL,
+			type VARCHAR(50) NOT NULL DEFAULT 'coin_pool' COMMENT 'coin_pool or oi_top',
+			weight DOUBLE DEFAULT 1,
+			enabled TINYINT(1) DEFAULT 1,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			INDEX idx_user_signal_source_items_user (user_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+
 		// 系统配置表
 		`CREATE TABLE IF NOT EXISTS system_config (
 			` + "`key`" + ` VARCHAR(255) PRIMARY KEY,
@@ -254,6 +274,9 @@ func (d *Database) createMySQLTables() error {
 			raw_ai_response TEXT DEFAULT '',
 			execution_success TINYINT(1) DEFAULT 0,
 			execution_error TEXT DEFAULT '',
+			ai_provider VARCHAR(64) DEFAULT '',
+			ai_model_name VARCHAR(255) DEFAULT '',
+			was_fallback TINYINT(1) DEFAULT 0,
 			FOREIGN KEY (trader_id) REFERENCES traders(id) ON DELETE CASCADE,
 			INDEX idx_strategy_decision_trader (trader_id, decision_time),
 			INDEX idx_strategy_decision_strategy (strategy_id, decision_time)
@@ -279,6 +302,72 @@ func (d *Database) createMySQLTables() error {
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			INDEX idx_email (email)
 		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+
+		// 用户 Webhook 回调配置（events 逗号分隔，为空表示订阅全部事件）
+		`CREATE TABLE IF NOT EXISTS user_webhooks (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			user_id VARCHAR(255) NOT NULL,
+			url VARCHAR(1024) NOT NULL,
+			secret TEXT,
+			events VARCHAR(512) DEFAULT '',
+			enabled TINYINT(1) DEFAULT 1,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			INDEX idx_user_webhooks_user (user_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+
+		// 登出token黑名单（只保存token哈希，expires_at 为 Unix 秒）
+		`CREATE TABLE IF NOT EXISTS token_blacklist (
+			token_hash VARCHAR(64) PRIMARY KEY,
+			expires_at BIGINT NOT NULL,
+			INDEX idx_token_blacklist_expires (expires_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+
+		// 用户级新建交易员默认设置（settings 为 JSON，未设置的字段回退到系统默认值）
+		`CREATE TABLE IF NOT EXISTS user_defaults (
+			user_id VARCHAR(255) PRIMARY KEY,
+			settings TEXT NOT NULL,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+
+		// Webhook 死信记录（多次重试仍投递失败）
+		`CREATE TABLE IF NOT EXISTS webhook_dead_letters (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			webhook_id BIGINT NOT NULL,
+			user_id VARCHAR(255) NOT NULL,
+			event_type VARCHAR(50) NOT NULL,
+			payload LONGTEXT NOT NULL,
+			attempts INT DEFAULT 0,
+			last_error TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			INDEX idx_webhook_dead_letters_user (user_id, created_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+
+		// 交易员周期净值基准历史（baseline_reset_policy 按周/月滚动，保留每个周期的期初/期末净值）
+		`CREATE TABLE IF NOT EXISTS trader_baselines (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			trader_id VARCHAR(255) NOT NULL,
+			policy VARCHAR(20) NOT NULL,
+			baseline DOUBLE NOT NULL,
+			period_start DATETIME NOT NULL,
+			end_equity DOUBLE,
+			period_end DATETIME,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			INDEX idx_trader_baselines_trader (trader_id, period_start)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+
+		// 初始余额（盈亏基准）手动调整审计记录
+		`CREATE TABLE IF NOT EXISTS trader_baseline_audits (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			trader_id VARCHAR(255) NOT NULL,
+			user_id VARCHAR(255) NOT NULL,
+			mode VARCHAR(20) NOT NULL,
+			previous_balance DOUBLE NOT NULL,
+			new_balance DOUBLE NOT NULL,
+			delta DOUBLE NOT NULL,
+			reason TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			INDEX idx_trader_baseline_audits_trader (trader_id, created_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
 	}
 
 	for _, query := range queries {
@@ -441,6 +530,134 @@ func (d *Database) migrateStrategyOrdersAddLeverage() error {
 	return nil
 }
 
+// mysqlColumn MySQL 增量列（表名、列名、列定义）
+type mysqlColumn struct {
+	table      string
+	column     string
+	definition string
+}
+
+// mysqlAddedColumns 与 createTables 中 SQLite 的 ALTER TABLE 迁移对应的 MySQL 增量列
+// MySQL 的 TEXT 列不支持默认值，读取时依赖查询中的 COALESCE；新增列时需同时加入 SQLite 迁移和此列表
+var mysqlAddedColumns = []mysqlColumn{
+	{"ai_models", "max_prompt_tokens", "INT DEFAULT 0"},
+	{"ai_models", "custom_headers", "TEXT DEFAULT NULL"},
+	{"ai_models", "custom_auth_scheme", "VARCHAR(50) DEFAULT ''"},
+	{"strategy_decision_history", "ai_provider", "VARCHAR(64) DEFAULT ''"},
+	{"strategy_decision_history", "ai_model_name", "VARCHAR(255) DEFAULT ''"},
+	{"strategy_decision_history", "was_fallback", "TINYINT(1) DEFAULT 0"},
+	{"users", "display_currency", "VARCHAR(20) DEFAULT 'USDT'"},
+	{"traders", "require_stop_loss", "TINYINT(1) DEFAULT 0"},
+	{"traders", "default_stop_loss_pct", "DOUBLE DEFAULT 0"},
+	{"traders", "exclude_held_from_candidates", "TINYINT(1) DEFAULT 0"},
+	{"traders", "analysis_only", "TINYINT(1) DEFAULT 0"},
+	{"traders", "warmup_minutes", "INT DEFAULT 0"},
+	{"traders", "skip_cycle_if_busy", "TINYINT(1) DEFAULT 0"},
+	{"traders", "max_position_age_hours", "INT DEFAULT 0"},
+	{"traders", "allow_pyramiding", "TINYINT(1) DEFAULT 0"},
+	{"traders", "max_adds_per_position", "INT DEFAULT 2"},
+	{"traders", "enforce_daily_loss_stop", "TINYINT(1) DEFAULT 0"},
+	{"traders", "allow_flip", "TINYINT(1) DEFAULT 0"},
+	{"traders", "min_confidence", "INT DEFAULT 0"},
+	{"traders", "signal_base_position_pct", "DOUBLE DEFAULT 20"},
+	{"traders", "signal_default_add_pct", "DOUBLE DEFAULT 10"},
+	{"traders", "equity_take_profit", "DOUBLE DEFAULT 0"},
+	{"traders", "equity_stop_loss", "DOUBLE DEFAULT 0"},
+	{"traders", "equity_take_profit_pct", "DOUBLE DEFAULT 0"},
+	{"traders", "equity_stop_loss_pct", "DOUBLE DEFAULT 0"},
+	{"traders", "auto_reprotect", "TINYINT(1) DEFAULT 0"},
+	{"traders", "public_display_name", "VARCHAR(255) DEFAULT ''"},
+	{"traders", "public_visibility", "TINYINT(1) DEFAULT 1"},
+	{"traders", "backup_exchange_id", "VARCHAR(255) DEFAULT ''"},
+	{"traders", "trading_schedule", "TEXT DEFAULT NULL"},
+	{"traders", "include_orderbook_depth", "TINYINT(1) DEFAULT 0"},
+	{"traders", "skip_if_btc_move_pct", "DOUBLE DEFAULT 0"},
+	{"traders", "skip_if_funding_above", "DOUBLE DEFAULT 0"},
+	{"traders", "max_open_orders", "INT DEFAULT 10"},
+	{"traders", "breakeven_at_profit_pct", "DOUBLE DEFAULT 0"},
+	{"traders", "trail_stop_after_profit_pct", "DOUBLE DEFAULT 0"},
+	{"traders", "trail_lock_fraction", "DOUBLE DEFAULT 0.5"},
+	{"traders", "max_actions_per_cycle", "INT DEFAULT 10"},
+	{"traders", "approval_required_first_trade", "TINYINT(1) DEFAULT 0"},
+	{"traders", "min_seconds_between_ai_calls", "INT DEFAULT 0"},
+	{"traders", "max_per_symbol_exposure_pct", "DOUBLE DEFAULT 0"},
+	{"traders", "include_recent_trades", "TINYINT(1) DEFAULT 1"},
+	{"traders", "recent_trades_count", "INT DEFAULT 5"},
+	{"traders", "sizing_base", "VARCHAR(20) DEFAULT 'fixed'"},
+	{"traders", "ai_temperature", "DOUBLE DEFAULT NULL"},
+	{"traders", "ai_top_p", "DOUBLE DEFAULT NULL"},
+	{"traders", "ai_max_tokens", "INT DEFAULT 0"},
+	{"traders", "on_ai_failure", "VARCHAR(20) DEFAULT 'hold'"},
+	{"traders", "baseline_reset_policy", "VARCHAR(20) DEFAULT 'none'"},
+	{"traders", "enforce_max_drawdown_stop", "TINYINT(1) DEFAULT 0"},
+	{"traders", "max_drawdown_stop_pct", "DOUBLE DEFAULT 0"},
+	{"traders", "drawdown_stop_flatten", "TINYINT(1) DEFAULT 0"},
+	{"traders", "stop_approach_alert_pct", "DOUBLE DEFAULT 0"},
+	{"traders", "candidate_symbols", "TEXT DEFAULT NULL"},
+	{"traders", "min_holding_minutes", "INT DEFAULT 0"},
+	{"traders", "strategy_sources", "TEXT DEFAULT NULL"},
+	{"traders", "context_indicators", "TEXT DEFAULT NULL"},
+	{"traders", "context_timeframes", "TEXT DEFAULT NULL"},
+	{"traders", "tp1_close_pct", "DOUBLE DEFAULT 0"},
+	{"traders", "tp1_breakeven_stop", "TINYINT(1) DEFAULT 0"},
+	{"traders", "rebalance_targets", "TEXT DEFAULT NULL"},
+	{"traders", "rebalance_tolerance_pct", "DOUBLE DEFAULT 0"},
+	{"traders", "rebalance_interval_minutes", "INT DEFAULT 0"},
+	{"traders", "use_oco", "TINYINT(1) DEFAULT 0"},
+	{"traders", "ai_anomaly_idle_cycles", "INT DEFAULT 0"},
+	{"traders", "ai_anomaly_repeat_cycles", "INT DEFAULT 0"},
+	{"traders", "ai_anomaly_pause", "TINYINT(1) DEFAULT 0"},
+	{"traders", "protective_defaults", "TEXT DEFAULT NULL"},
+	{"traders", "position_audit_minutes", "INT DEFAULT 0"},
+	{"traders", "min_available_balance", "DOUBLE DEFAULT 0"},
+	{"traders", "position_first_seen", "TEXT DEFAULT NULL"},
+	{"traders", "peak_equity", "DOUBLE DEFAULT 0"},
+	{"traders", "drawdown_stop_armed", "TINYINT(1) DEFAULT 1"},
+	{"traders", "first_trade_approved", "TINYINT(1) DEFAULT 0"},
+	{"traders", "position_pyramid", "TEXT DEFAULT NULL"},
+	{"traders", "equity_bracket_hit", "VARCHAR(20) DEFAULT ''"},
+}
+
+// migrateMySQLAddedColumns 补齐 MySQL 中缺失的增量列（按 information_schema 判断，已存在的列跳过）
+func (d *Database) migrateMySQLAddedColumns() error {
+	rows, err := d.db.Query(`
+		SELECT TABLE_NAME, COLUMN_NAME
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE()
+	`)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[strings.ToLower(table+"."+column)] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	added := 0
+	for _, c := range mysqlAddedColumns {
+		if existing[strings.ToLower(c.table+"."+c.column)] {
+			continue
+		}
+		if _, err := d.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.column, c.definition)); err != nil && !isDuplicateColumnError(err) {
+			return fmt.Errorf("添加 %s.%s 列失败: %w", c.table, c.column, err)
+		}
+		added++
+	}
+	if added > 0 {
+		log.Printf("✅ MySQL 增量列迁移完成，新增 %d 列", added)
+	}
+	return nil
+}
+
 // initMySQLDefaultData 初始化MySQL默认数据
 func (d *Database) initMySQLDefaultData() error {
 	// 首先确保 default 用户存在（如果不存在则创建）
@@ -494,16 +711,23 @@ func (d *Database) initMySQLDefaultData() error {
 
 	// 初始化系统配置
 	systemConfigs := map[string]string{
-		"beta_mode":            "false",
-		"api_server_port":      "8080",
-		"use_default_coins":    "true",
-		"default_coins":        `["BTCUSDT","ETHUSDT","SOLUSDT","BNBUSDT","XRPUSDT","DOGEUSDT","ADAUSDT","HYPEUSDT"]`,
-		"max_daily_loss":       "10.0",
-		"max_drawdown":         "20.0",
-		"stop_trading_minutes": "60",
-		"btc_eth_leverage":     "5",
-		"altcoin_leverage":     "5",
-		"jwt_secret":           "",
+		"beta_mode":                   "false",
+		"api_server_port":             "8080",
+		"use_default_coins":           "true",
+		"default_coins":               `["BTCUSDT","ETHUSDT","SOLUSDT","BNBUSDT","XRPUSDT","DOGEUSDT","ADAUSDT","HYPEUSDT"]`,
+		"max_daily_loss":              "10.0",
+		"max_drawdown":                "20.0",
+		"stop_trading_minutes":        "60",
+		"btc_eth_leverage":            "5",
+		"altcoin_leverage":            "5",
+		"jwt_secret":                  "",
+		"max_custom_prompt_length":    "8000",
+		"max_traders_per_user":        "0",
+		"decision_log_retention_days": "90",
+		"auto_resume_traders":         "true",
+		"max_btc_eth_leverage":        "125",
+		"max_altcoin_leverage":        "75",
+		"order_rate_limits":           "",
 	}
 
 	for key, value := range systemConfigs {
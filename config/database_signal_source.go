@@ -0,0 +1,87 @@
+package config
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// UserSignalSourceItem 用户的命名信号源（多信号源模式，按权重合并候选币种）
+type UserSignalSourceItem struct {
+	ID        int64     `json:"id"`
+	UserID    string    `json:"user_id"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	Type      string    `json:"type"`   // "coin_pool" / "oi_top"
+	Weight    float64   `json:"weight"` // 合并排序权重
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CreateUserSignalSourceItem 创建命名信号源，成功后回填 ID
+func (d *Database) CreateUserSignalSourceItem(item *UserSignalSourceItem) error {
+	result, err := d.db.Exec(`INSERT INTO user_signal_source_items (user_id, name, url, type, weight, enabled) VALUES (?, ?, ?, ?, ?, ?)`,
+		item.UserID, item.Name, item.URL, item.Type, item.Weight, item.Enabled)
+	if err != nil {
+		return fmt.Errorf("创建信号源失败: %w", err)
+	}
+	if id, err := result.LastInsertId(); err == nil {
+		item.ID = id
+	}
+	return nil
+}
+
+// GetUserSignalSourceItems 获取用户的全部命名信号源
+func (d *Database) GetUserSignalSourceItems(userID string) ([]*UserSignalSourceItem, error) {
+	rows, err := d.db.Query(`SELECT id, user_id, name, url, type, weight, enabled, created_at, updated_at
+		FROM user_signal_source_items WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*UserSignalSourceItem
+	for rows.Next() {
+		var item UserSignalSourceItem
+		if err := rows.Scan(&item.ID, &item.UserID, &item.Name, &item.URL, &item.Type, &item.Weight, &item.Enabled, &item.CreatedAt, &item.UpdatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, &item)
+	}
+	return items, rows.Err()
+}
+
+// GetUserSignalSourceItem 获取用户的单个命名信号源
+func (d *Database) GetUserSignalSourceItem(userID string, id int64) (*UserSignalSourceItem, error) {
+	items, err := d.GetUserSignalSourceItems(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if item.ID == id {
+			return item, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// UpdateUserSignalSourceItem 更新命名信号源
+func (d *Database) UpdateUserSignalSourceItem(item *UserSignalSourceItem) error {
+	_, err := d.db.Exec(fmt.Sprintf(`UPDATE user_signal_source_items SET name = ?, url = ?, type = ?, weight = ?, enabled = ?, updated_at = %s
+		WHERE id = ? AND user_id = ?`, d.getTimeFunc()),
+		item.Name, item.URL, item.Type, item.Weight, item.Enabled, item.ID, item.UserID)
+	return err
+}
+
+// DeleteUserSignalSourceItem 删除命名信号源
+func (d *Database) DeleteUserSignalSourceItem(userID string, id int64) error {
+	result, err := d.db.Exec(`DELETE FROM user_signal_source_items WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"nofx/crypto"
 	"os"
 	"testing"
@@ -797,3 +798,104 @@ func TestConcurrentWritesWithWAL(t *testing.T) {
 		t.Errorf("并发写入失败次数过多: %d", errorCount)
 	}
 }
+
+// TestCheckTraderQuota 测试交易员数量上限：创建到上限后再创建应被拒绝
+func TestCheckTraderQuota(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+
+	// 默认不限制
+	if _, limit, err := db.CheckTraderQuota(userID); err != nil || limit != 0 {
+		t.Fatalf("默认应不限制，实际 limit=%d err=%v", limit, err)
+	}
+
+	if err := db.SetSystemConfig("max_traders_per_user", "2"); err != nil {
+		t.Fatalf("设置上限失败: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := db.CheckTraderQuota(userID); err != nil {
+			t.Fatalf("第 %d 个交易员不应被拒绝: %v", i+1, err)
+		}
+		err := db.CreateTrader(&TraderRecord{
+			ID:             fmt.Sprintf("quota_trader_%d", i),
+			UserID:         userID,
+			Name:           fmt.Sprintf("Quota Trader %d", i),
+			AIModelID:      "deepseek",
+			ExchangeID:     "binance",
+			InitialBalance: 100,
+		})
+		if err != nil {
+			t.Fatalf("创建交易员失败: %v", err)
+		}
+	}
+
+	count, limit, err := db.CheckTraderQuota(userID)
+	if err == nil {
+		t.Fatal("达到上限后应拒绝创建新的交易员")
+	}
+	if count != 2 || limit != 2 {
+		t.Errorf("期望 count=2 limit=2，实际 count=%d limit=%d", count, limit)
+	}
+
+	// 其他用户不受影响
+	if _, _, err := db.CheckTraderQuota("test-user-002"); err != nil {
+		t.Errorf("其他用户不应受影响: %v", err)
+	}
+}
+
+// TestSetTraderInitialBalanceWithAudit 测试手动调整初始余额：更新交易员记录并写入包含调整前的值和原因的审计记录
+func TestSetTraderInitialBalanceWithAudit(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	err := db.CreateTrader(&TraderRecord{
+		ID:             "baseline_trader",
+		UserID:         "test-user-001",
+		Name:           "Baseline Trader",
+		AIModelID:      "deepseek",
+		ExchangeID:     "binance",
+		InitialBalance: 1000,
+	})
+	if err != nil {
+		t.Fatalf("创建交易员失败: %v", err)
+	}
+
+	audit := &BaselineAudit{
+		TraderID:        "baseline_trader",
+		UserID:          "test-user-001",
+		Mode:            BaselineAuditModeDelta,
+		PreviousBalance: 1000,
+		NewBalance:      1500,
+		Delta:           500,
+		Reason:          "充值 500 USDT",
+	}
+	if err := db.SetTraderInitialBalanceWithAudit(audit); err != nil {
+		t.Fatalf("调整初始余额失败: %v", err)
+	}
+	if audit.ID == 0 {
+		t.Error("审计记录应回填 ID")
+	}
+
+	trader, err := db.GetTraderByID("baseline_trader")
+	if err != nil {
+		t.Fatalf("获取交易员失败: %v", err)
+	}
+	if trader.InitialBalance != 1500 {
+		t.Errorf("期望初始余额 1500，实际 %.2f", trader.InitialBalance)
+	}
+
+	audits, err := db.GetBaselineAudits("baseline_trader", 0)
+	if err != nil {
+		t.Fatalf("获取审计记录失败: %v", err)
+	}
+	if len(audits) != 1 {
+		t.Fatalf("期望 1 条审计记录，实际 %d", len(audits))
+	}
+	got := audits[0]
+	if got.PreviousBalance != 1000 || got.NewBalance != 1500 || got.Delta != 500 || got.Mode != BaselineAuditModeDelta || got.Reason != "充值 500 USDT" {
+		t.Errorf("审计记录不正确: %+v", got)
+	}
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"strconv"
+	"time"
+)
+
+// SaveBlacklistedToken 持久化登出token（仅保存哈希），重复登出时更新过期时间
+func (d *Database) SaveBlacklistedToken(tokenHash string, expiresAt time.Time) error {
+	_, err := d.db.Exec(`REPLACE INTO token_blacklist (token_hash, expires_at) VALUES (?, ?)`, tokenHash, expiresAt.Unix())
+	return err
+}
+
+// LoadBlacklistedTokens 读取全部未过期的黑名单条目 tokenHash -> 过期时间
+func (d *Database) LoadBlacklistedTokens() (map[string]time.Time, error) {
+	rows, err := d.db.Query(`SELECT token_hash, expires_at FROM token_blacklist WHERE expires_at > ?`, time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make(map[string]time.Time)
+	for rows.Next() {
+		var tokenHash string
+		var expiresAt int64
+		if err := rows.Scan(&tokenHash, &expiresAt); err != nil {
+			return nil, err
+		}
+		items[tokenHash] = time.Unix(expiresAt, 0)
+	}
+	return items, rows.Err()
+}
+
+// DeleteExpiredBlacklistedTokens 删除在 before 之前过期的黑名单条目
+func (d *Database) DeleteExpiredBlacklistedTokens(before time.Time) error {
+	_, err := d.db.Exec(`DELETE FROM token_blacklist WHERE expires_at < ?`, before.Unix())
+	return err
+}
+
+// ClearBlacklistedTokens 清空token黑名单
+func (d *Database) ClearBlacklistedTokens() error {
+	_, err := d.db.Exec(`DELETE FROM token_blacklist`)
+	return err
+}
+
+// GetTokenBlacklistPruneInterval 获取token黑名单过期条目的清理间隔（system_config blacklist_prune_minutes，未配置或非法时返回0，由调用方使用默认值）
+func (d *Database) GetTokenBlacklistPruneInterval() time.Duration {
+	value, err := d.GetSystemConfig("blacklist_prune_minutes")
+	if err != nil {
+		return 0
+	}
+	minutes, err := strconv.Atoi(value)
+	if err != nil || minutes <= 0 {
+		return 0
+	}
+	return time.Duration(minutes) * time.Minute
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// UserTraderDefaults 用户级新建交易员默认设置（nil 表示未设置，创建交易员时回退到系统默认值）
+type UserTraderDefaults struct {
+	SystemPromptTemplate *string  `json:"system_prompt_template,omitempty"`
+	ScanIntervalMinutes  *int     `json:"scan_interval_minutes,omitempty"`
+	BTCETHLeverage       *int     `json:"btc_eth_leverage,omitempty"`
+	AltcoinLeverage      *int     `json:"altcoin_leverage,omitempty"`
+	IsCrossMargin        *bool    `json:"is_cross_margin,omitempty"`
+	TradingSymbols       *string  `json:"trading_symbols,omitempty"`
+	RequireStopLoss      *bool    `json:"require_stop_loss,omitempty"`
+	DefaultStopLossPct   *float64 `json:"default_stop_loss_pct,omitempty"`
+	MinConfidence        *int     `json:"min_confidence,omitempty"`
+	EnforceDailyLossStop *bool    `json:"enforce_daily_loss_stop,omitempty"`
+	MaxOpenOrders        *int     `json:"max_open_orders,omitempty"`
+	MaxActionsPerCycle   *int     `json:"max_actions_per_cycle,omitempty"`
+	SizingBase           *string  `json:"sizing_base,omitempty"`
+	OnAIFailure          *string  `json:"on_ai_failure,omitempty"`
+}
+
+// GetUserTraderDefaults 获取用户的新建交易员默认设置（未配置时返回空设置）
+func (d *Database) GetUserTraderDefaults(userID string) (*UserTraderDefaults, error) {
+	var settings string
+	err := d.db.QueryRow(`SELECT settings FROM user_defaults WHERE user_id = ?`, userID).Scan(&settings)
+	if err == sql.ErrNoRows {
+		return &UserTraderDefaults{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	defaults := &UserTraderDefaults{}
+	if settings != "" {
+		if err := json.Unmarshal([]byte(settings), defaults); err != nil {
+			return nil, fmt.Errorf("解析用户默认设置失败: %w", err)
+		}
+	}
+	return defaults, nil
+}
+
+// SaveUserTraderDefaults 保存用户的新建交易员默认设置（整体覆盖）
+func (d *Database) SaveUserTraderDefaults(userID string, defaults *UserTraderDefaults) error {
+	settings, err := json.Marshal(defaults)
+	if err != nil {
+		return fmt.Errorf("序列化用户默认设置失败: %w", err)
+	}
+	_, err = d.db.Exec(`REPLACE INTO user_defaults (user_id, settings) VALUES (?, ?)`, userID, string(settings))
+	return err
+}
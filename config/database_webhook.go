@@ -0,0 +1,124 @@
+package config
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// UserWebhook 用户配置的 Webhook 回调
+type UserWebhook struct {
+	ID        int64     `json:"id"`
+	UserID    string    `json:"user_id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"`      // HMAC 签名密钥，加密存储，不对外返回
+	Events    []string  `json:"events"` // 订阅的事件类型，为空表示全部
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Subscribes 判断 Webhook 是否订阅了指定事件
+func (w *UserWebhook) Subscribes(eventType string) bool {
+	if !w.Enabled {
+		return false
+	}
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDeadLetter 多次重试后仍投递失败的 Webhook 事件
+type WebhookDeadLetter struct {
+	ID        int64     `json:"id"`
+	WebhookID int64     `json:"webhook_id"`
+	UserID    string    `json:"user_id"`
+	EventType string    `json:"event_type"`
+	Payload   string    `json:"payload"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateUserWebhook 创建 Webhook 配置，成功后回填 ID
+func (d *Database) CreateUserWebhook(w *UserWebhook) error {
+	unlock := d.lockSensitiveWrite()
+	defer unlock()
+
+	result, err := d.db.Exec(`INSERT INTO user_webhooks (user_id, url, secret, events, enabled) VALUES (?, ?, ?, ?, ?)`,
+		w.UserID, w.URL, d.encryptSensitiveData(w.Secret), strings.Join(w.Events, ","), w.Enabled)
+	if err != nil {
+		return fmt.Errorf("创建Webhook失败: %w", err)
+	}
+	if id, err := result.LastInsertId(); err == nil {
+		w.ID = id
+	}
+	return nil
+}
+
+// GetUserWebhooks 获取用户的全部 Webhook 配置（密钥已解密）
+func (d *Database) GetUserWebhooks(userID string) ([]*UserWebhook, error) {
+	rows, err := d.db.Query(`SELECT id, user_id, url, COALESCE(secret, ''), COALESCE(events, ''), enabled, created_at
+		FROM user_webhooks WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []*UserWebhook
+	for rows.Next() {
+		var w UserWebhook
+		var events string
+		if err := rows.Scan(&w.ID, &w.UserID, &w.URL, &w.Secret, &events, &w.Enabled, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		w.Secret = d.decryptSensitiveData(w.Secret)
+		for _, e := range strings.Split(events, ",") {
+			if e = strings.TrimSpace(e); e != "" {
+				w.Events = append(w.Events, e)
+			}
+		}
+		webhooks = append(webhooks, &w)
+	}
+	return webhooks, rows.Err()
+}
+
+// GetUserWebhook 获取用户的单个 Webhook 配置
+func (d *Database) GetUserWebhook(userID string, id int64) (*UserWebhook, error) {
+	webhooks, err := d.GetUserWebhooks(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range webhooks {
+		if w.ID == id {
+			return w, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// DeleteUserWebhook 删除用户的 Webhook 配置
+func (d *Database) DeleteUserWebhook(userID string, id int64) error {
+	result, err := d.db.Exec(`DELETE FROM user_webhooks WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RecordWebhookDeadLetter 记录投递失败的 Webhook 事件
+func (d *Database) RecordWebhookDeadLetter(dl *WebhookDeadLetter) error {
+	_, err := d.db.Exec(`INSERT INTO webhook_dead_letters (webhook_id, user_id, event_type, payload, attempts, last_error)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		dl.WebhookID, dl.UserID, dl.EventType, dl.Payload, dl.Attempts, dl.LastError)
+	return err
+}
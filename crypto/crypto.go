@@ -18,6 +18,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -25,6 +26,7 @@ const (
 	storagePrefix    = "ENC:v1:"
 	storageDelimiter = ":"
 	dataKeyEnvName   = "DATA_ENCRYPTION_KEY"
+	dataKeyFileName  = "data_key.json" // 轮换后的数据密钥文件（与RSA私钥同目录）
 )
 
 type EncryptedPayload struct {
@@ -46,7 +48,21 @@ type AADData struct {
 type CryptoService struct {
 	privateKey *rsa.PrivateKey
 	publicKey  *rsa.PublicKey
-	dataKey    []byte
+
+	// 数据加密密钥（支持轮换）
+	keyMu       sync.RWMutex
+	dataKey     []byte
+	previousKey []byte // 轮换前的旧密钥，仅用于解密（兼容轮换期间写入的旧密文）
+	keyVersion  int
+	dataKeyFile string
+	rotationMu  sync.RWMutex // 轮换时独占；写入加密字段时共享持有，保证加密与落库之间不会发生轮换
+}
+
+// dataKeyFileContent 数据密钥文件内容
+type dataKeyFileContent struct {
+	Version     int    `json:"version"`
+	Key         string `json:"key"`
+	PreviousKey string `json:"previous_key,omitempty"`
 }
 
 func NewCryptoService(privateKeyPath string) (*CryptoService, error) {
@@ -74,11 +90,53 @@ func NewCryptoService(privateKeyPath string) (*CryptoService, error) {
 		return nil, fmt.Errorf("failed to load data encryption key: %w", err)
 	}
 
-	return &CryptoService{
-		privateKey: privateKey,
-		publicKey:  &privateKey.PublicKey,
-		dataKey:    dataKey,
-	}, nil
+	cs := &CryptoService{
+		privateKey:  privateKey,
+		publicKey:   &privateKey.PublicKey,
+		dataKey:     dataKey,
+		keyVersion:  1,
+		dataKeyFile: filepath.Join(filepath.Dir(privateKeyPath), dataKeyFileName),
+	}
+
+	// 曾经轮换过密钥时，以密钥文件为准（环境变量中的仍是初始密钥）
+	if err := cs.loadDataKeyFile(); err != nil {
+		return nil, fmt.Errorf("failed to load rotated data key: %w", err)
+	}
+
+	return cs, nil
+}
+
+// loadDataKeyFile 加载轮换后持久化的数据密钥，文件不存在时保持环境变量密钥
+func (cs *CryptoService) loadDataKeyFile() error {
+	raw, err := ioutil.ReadFile(cs.dataKeyFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var content dataKeyFileContent
+	if err := json.Unmarshal(raw, &content); err != nil {
+		return fmt.Errorf("invalid data key file: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(content.Key)
+	if err != nil || len(key) != 32 {
+		return errors.New("invalid data key in key file")
+	}
+
+	var previousKey []byte
+	if content.PreviousKey != "" {
+		if previousKey, err = base64.StdEncoding.DecodeString(content.PreviousKey); err != nil {
+			return errors.New("invalid previous data key in key file")
+		}
+	}
+
+	cs.dataKey = key
+	cs.previousKey = previousKey
+	cs.keyVersion = content.Version
+	log.Printf("🔑 已加载轮换后的数据加密密钥 (v%d)", content.Version)
+	return nil
 }
 
 func GenerateRSAKeyPair(privateKeyPath string) error {
@@ -200,9 +258,128 @@ func normalizeAESKey(raw []byte) ([]byte, bool) {
 }
 
 func (cs *CryptoService) HasDataKey() bool {
+	cs.keyMu.RLock()
+	defer cs.keyMu.RUnlock()
 	return len(cs.dataKey) > 0
 }
 
+// DataKeyVersion 当前数据加密密钥版本（未轮换过为1）
+func (cs *CryptoService) DataKeyVersion() int {
+	cs.keyMu.RLock()
+	defer cs.keyMu.RUnlock()
+	return cs.keyVersion
+}
+
+// NewDataKey 生成新的随机数据加密密钥（AES-256）
+func NewDataKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// ReencryptForStorage 使用当前密钥解密已存储的值，再用 newKey 重新加密
+// 未加密的旧数据直接用 newKey 加密；解密失败时返回错误（轮换需整体回滚）
+func (cs *CryptoService) ReencryptForStorage(value string, newKey []byte, aadParts ...string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	plaintext := value
+	if isEncryptedStorageValue(value) {
+		decrypted, err := cs.DecryptFromStorage(value, aadParts...)
+		if err != nil {
+			return "", err
+		}
+		plaintext = decrypted
+	}
+
+	return sealForStorage(newKey, plaintext, composeAAD(aadParts))
+}
+
+// SwitchDataKey 切换数据加密密钥并持久化到密钥文件
+// 旧密钥暂时保留为 previousKey，轮换事务提交前用旧密钥写入的数据仍可解密；
+// 事务提交成功后应调用 ClearPreviousDataKey 丢弃旧密钥
+func (cs *CryptoService) SwitchDataKey(newKey []byte, version int) error {
+	cs.keyMu.Lock()
+	defer cs.keyMu.Unlock()
+
+	content := dataKeyFileContent{
+		Version:     version,
+		Key:         base64.StdEncoding.EncodeToString(newKey),
+		PreviousKey: base64.StdEncoding.EncodeToString(cs.dataKey),
+	}
+	if err := cs.writeDataKeyFile(content); err != nil {
+		return err
+	}
+
+	cs.previousKey = cs.dataKey
+	cs.dataKey = newKey
+	cs.keyVersion = version
+	return nil
+}
+
+// ClearPreviousDataKey 轮换成功后丢弃旧密钥（内存和密钥文件中均清除）
+func (cs *CryptoService) ClearPreviousDataKey() error {
+	cs.keyMu.Lock()
+	defer cs.keyMu.Unlock()
+
+	if len(cs.previousKey) == 0 {
+		return nil
+	}
+	content := dataKeyFileContent{
+		Version: cs.keyVersion,
+		Key:     base64.StdEncoding.EncodeToString(cs.dataKey),
+	}
+	if err := cs.writeDataKeyFile(content); err != nil {
+		return err
+	}
+
+	cs.previousKey = nil
+	return nil
+}
+
+// writeDataKeyFile 原子写入密钥文件（调用方需持有 keyMu）
+func (cs *CryptoService) writeDataKeyFile(content dataKeyFileContent) error {
+	raw, err := json.MarshalIndent(content, "", "  ")
+	if err != nil {
+		return err
+	}
+	// 先写临时文件再重命名，避免写入一半导致密钥文件损坏
+	tmpFile := cs.dataKeyFile + ".tmp"
+	if err := ioutil.WriteFile(tmpFile, raw, 0600); err != nil {
+		return fmt.Errorf("failed to write data key file: %w", err)
+	}
+	if err := os.Rename(tmpFile, cs.dataKeyFile); err != nil {
+		return fmt.Errorf("failed to replace data key file: %w", err)
+	}
+	return nil
+}
+
+// RestorePreviousDataKey 轮换失败时恢复为旧密钥（新密钥保留为 previousKey）
+func (cs *CryptoService) RestorePreviousDataKey(version int) error {
+	cs.keyMu.RLock()
+	previousKey := cs.previousKey
+	cs.keyMu.RUnlock()
+	if len(previousKey) == 0 {
+		return errors.New("no previous data key")
+	}
+	return cs.SwitchDataKey(previousKey, version)
+}
+
+// LockKeyRotation 获取密钥轮换锁（独占），返回释放函数
+func (cs *CryptoService) LockKeyRotation() func() {
+	cs.rotationMu.Lock()
+	return cs.rotationMu.Unlock
+}
+
+// LockKeyUse 获取密钥使用锁（共享），在"加密 + 写入数据库"期间持有，返回释放函数
+func (cs *CryptoService) LockKeyUse() func() {
+	cs.rotationMu.RLock()
+	return cs.rotationMu.RUnlock
+}
+
 func (cs *CryptoService) GetPublicKeyPEM() string {
 	publicKeyDER, err := x509.MarshalPKIXPublicKey(cs.publicKey)
 	if err != nil {
@@ -228,7 +405,16 @@ func (cs *CryptoService) EncryptForStorage(plaintext string, aadParts ...string)
 		return plaintext, nil
 	}
 
-	block, err := aes.NewCipher(cs.dataKey)
+	cs.keyMu.RLock()
+	dataKey := cs.dataKey
+	cs.keyMu.RUnlock()
+
+	return sealForStorage(dataKey, plaintext, composeAAD(aadParts))
+}
+
+// sealForStorage 使用指定密钥进行 AES-GCM 加密并编码为存储格式
+func sealForStorage(key []byte, plaintext string, aad []byte) (string, error) {
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return "", err
 	}
@@ -243,7 +429,6 @@ func (cs *CryptoService) EncryptForStorage(plaintext string, aadParts ...string)
 		return "", err
 	}
 
-	aad := composeAAD(aadParts)
 	ciphertext := gcm.Seal(nil, nonce, []byte(plaintext), aad)
 
 	return storagePrefix +
@@ -283,31 +468,44 @@ func (cs *CryptoService) DecryptFromStorage(value string, aadParts ...string) (s
 		return "", fmt.Errorf("decode ciphertext failed: %w", err)
 	}
 
-	block, err := aes.NewCipher(cs.dataKey)
-	if err != nil {
-		log.Printf("❌ DecryptFromStorage: create cipher failed: %v", err)
-		return "", err
+	cs.keyMu.RLock()
+	keys := [][]byte{cs.dataKey}
+	if len(cs.previousKey) > 0 {
+		// 密钥轮换后旧密钥仍可解密（轮换期间写入的数据）
+		keys = append(keys, cs.previousKey)
 	}
+	cs.keyMu.RUnlock()
 
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		log.Printf("❌ DecryptFromStorage: create GCM failed: %v", err)
-		return "", err
-	}
+	aad := composeAAD(aadParts)
+	for i, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			log.Printf("❌ DecryptFromStorage: create cipher failed: %v", err)
+			return "", err
+		}
 
-	if len(nonce) != gcm.NonceSize() {
-		log.Printf("❌ DecryptFromStorage: invalid nonce size: expected %d, got %d", gcm.NonceSize(), len(nonce))
-		return "", fmt.Errorf("invalid nonce size: expected %d, got %d", gcm.NonceSize(), len(nonce))
-	}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			log.Printf("❌ DecryptFromStorage: create GCM failed: %v", err)
+			return "", err
+		}
 
-	aad := composeAAD(aadParts)
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, aad)
-	if err != nil {
-		log.Printf("❌ DecryptFromStorage: GCM decryption failed: %v. This usually means DATA_ENCRYPTION_KEY has changed.", err)
-		return "", fmt.Errorf("decryption failed: %w", err)
+		if len(nonce) != gcm.NonceSize() {
+			log.Printf("❌ DecryptFromStorage: invalid nonce size: expected %d, got %d", gcm.NonceSize(), len(nonce))
+			return "", fmt.Errorf("invalid nonce size: expected %d, got %d", gcm.NonceSize(), len(nonce))
+		}
+
+		plaintext, err := gcm.Open(nil, nonce, ciphertext, aad)
+		if err == nil {
+			return string(plaintext), nil
+		}
+		if i == len(keys)-1 {
+			log.Printf("❌ DecryptFromStorage: GCM decryption failed: %v. This usually means DATA_ENCRYPTION_KEY has changed.", err)
+			return "", fmt.Errorf("decryption failed: %w", err)
+		}
 	}
 
-	return string(plaintext), nil
+	return "", errors.New("decryption failed")
 }
 
 func (cs *CryptoService) IsEncryptedStorageValue(value string) bool {
@@ -0,0 +1,123 @@
+package crypto
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// newTestCryptoService 在临时目录创建使用固定数据密钥的 CryptoService
+func newTestCryptoService(t *testing.T, dir string) *CryptoService {
+	t.Helper()
+	t.Setenv(dataKeyEnvName, "test-data-encryption-key")
+	cs, err := NewCryptoService(filepath.Join(dir, "rsa_key"))
+	if err != nil {
+		t.Fatalf("初始化加密服务失败: %v", err)
+	}
+	return cs
+}
+
+// TestDataKeyRotation 测试数据密钥轮换：重新加密、旧密文兼容、重启后加载新密钥
+func TestDataKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	cs := newTestCryptoService(t, dir)
+	if cs.DataKeyVersion() != 1 {
+		t.Fatalf("初始密钥版本应为1，得到 %d", cs.DataKeyVersion())
+	}
+
+	oldCipher, err := cs.EncryptForStorage("secret_api_key")
+	if err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+
+	newKey, err := NewDataKey()
+	if err != nil {
+		t.Fatalf("生成新密钥失败: %v", err)
+	}
+	reencrypted, err := cs.ReencryptForStorage(oldCipher, newKey)
+	if err != nil {
+		t.Fatalf("重新加密失败: %v", err)
+	}
+	plainReencrypted, err := cs.ReencryptForStorage("legacy_plaintext", newKey)
+	if err != nil {
+		t.Fatalf("明文重新加密失败: %v", err)
+	}
+	if !isEncryptedStorageValue(plainReencrypted) {
+		t.Fatal("未加密的旧数据应被加密")
+	}
+
+	if err := cs.SwitchDataKey(newKey, 2); err != nil {
+		t.Fatalf("切换密钥失败: %v", err)
+	}
+	if cs.DataKeyVersion() != 2 {
+		t.Fatalf("轮换后密钥版本应为2，得到 %d", cs.DataKeyVersion())
+	}
+
+	for name, value := range map[string]string{"重新加密的值": reencrypted, "轮换期间写入的旧密文": oldCipher} {
+		decrypted, err := cs.DecryptFromStorage(value)
+		if err != nil || decrypted != "secret_api_key" {
+			t.Fatalf("%s解密失败: %v (得到 %q)", name, err, decrypted)
+		}
+	}
+
+	// 重启后应从密钥文件加载新密钥（环境变量仍为初始密钥）
+	restarted := newTestCryptoService(t, dir)
+	if restarted.DataKeyVersion() != 2 {
+		t.Fatalf("重启后密钥版本应为2，得到 %d", restarted.DataKeyVersion())
+	}
+	decrypted, err := restarted.DecryptFromStorage(plainReencrypted)
+	if err != nil || decrypted != "legacy_plaintext" {
+		t.Fatalf("重启后解密失败: %v (得到 %q)", err, decrypted)
+	}
+
+	// 恢复旧密钥后新密文仍可解密
+	if err := restarted.RestorePreviousDataKey(1); err != nil {
+		t.Fatalf("恢复旧密钥失败: %v", err)
+	}
+	if decrypted, err := restarted.DecryptFromStorage(reencrypted); err != nil || decrypted != "secret_api_key" {
+		t.Fatalf("恢复旧密钥后解密失败: %v (得到 %q)", err, decrypted)
+	}
+}
+
+// TestClearPreviousDataKey 测试轮换提交后清除旧密钥：内存和密钥文件中均不再保留
+func TestClearPreviousDataKey(t *testing.T) {
+	dir := t.TempDir()
+	cs := newTestCryptoService(t, dir)
+
+	oldCipher, err := cs.EncryptForStorage("secret_api_key")
+	if err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+	newKey, err := NewDataKey()
+	if err != nil {
+		t.Fatalf("生成新密钥失败: %v", err)
+	}
+	newCipher, err := cs.ReencryptForStorage(oldCipher, newKey)
+	if err != nil {
+		t.Fatalf("重新加密失败: %v", err)
+	}
+	if err := cs.SwitchDataKey(newKey, 2); err != nil {
+		t.Fatalf("切换密钥失败: %v", err)
+	}
+	if err := cs.ClearPreviousDataKey(); err != nil {
+		t.Fatalf("清除旧密钥失败: %v", err)
+	}
+
+	if _, err := cs.DecryptFromStorage(oldCipher); err == nil {
+		t.Fatal("清除旧密钥后旧密文不应再可解密")
+	}
+	if decrypted, err := cs.DecryptFromStorage(newCipher); err != nil || decrypted != "secret_api_key" {
+		t.Fatalf("新密文解密失败: %v (得到 %q)", err, decrypted)
+	}
+	if err := cs.RestorePreviousDataKey(1); err == nil {
+		t.Fatal("清除旧密钥后不应能恢复旧密钥")
+	}
+
+	// 重启后密钥文件中也不再有旧密钥
+	restarted := newTestCryptoService(t, dir)
+	if restarted.DataKeyVersion() != 2 {
+		t.Fatalf("重启后密钥版本应为2，得到 %d", restarted.DataKeyVersion())
+	}
+	if _, err := restarted.DecryptFromStorage(oldCipher); err == nil {
+		t.Fatal("重启后旧密文不应再可解密")
+	}
+}
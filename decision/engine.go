@@ -46,6 +46,12 @@ type PositionInfo struct {
 	LiquidationPrice float64 `json:"liquidation_price"`
 	MarginUsed       float64 `json:"margin_used"`
 	UpdateTime       int64   `json:"update_time"` // 持仓更新时间戳（毫秒）
+
+	OrderBook  *OrderBookDepth              `json:"order_book,omitempty"` // 盘口深度摘要（未开启或交易所不支持时为空）
+	Indicators []market.TimeframeIndicators `json:"indicators,omitempty"` // 多周期技术指标（未配置时为空）
+
+	// SymbolUnavailable 币种已下架/暂停交易（无法获取行情），由 MarketDataCheck 判定，需用户手动处理
+	SymbolUnavailable bool `json:"symbol_unavailable,omitempty"`
 }
 
 // AccountInfo 账户信息
@@ -62,8 +68,32 @@ type AccountInfo struct {
 
 // CandidateCoin 候选币种（来自币种池）
 type CandidateCoin struct {
-	Symbol  string   `json:"symbol"`
-	Sources []string `json:"sources"` // 来源: "ai500" 和/或 "oi_top"
+	Symbol     string                       `json:"symbol"`
+	Sources    []string                     `json:"sources"`              // 来源: "ai500" 和/或 "oi_top"
+	OrderBook  *OrderBookDepth              `json:"order_book,omitempty"` // 盘口深度摘要（未开启或交易所不支持时为空）
+	Indicators []market.TimeframeIndicators `json:"indicators,omitempty"` // 多周期技术指标（未配置时为空）
+}
+
+// OrderBookDepth 盘口深度摘要（买一/卖一、价差、中间价附近的挂单量），用于评估流动性与滑点
+type OrderBookDepth struct {
+	BestBid     float64 `json:"best_bid"`
+	BestAsk     float64 `json:"best_ask"`
+	SpreadBps   float64 `json:"spread_bps"`    // 买卖价差（基点，相对中间价）
+	DepthBps    float64 `json:"depth_bps"`     // 统计深度的价格范围（中间价上下多少基点）
+	BidDepthUSD float64 `json:"bid_depth_usd"` // 范围内买单名义价值（USDT）
+	AskDepthUSD float64 `json:"ask_depth_usd"` // 范围内卖单名义价值（USDT）
+}
+
+// ClosedTradeInfo 最近平仓的完整交易（开仓到全部平仓），用于让AI复盘近期得失
+type ClosedTradeInfo struct {
+	Symbol      string  `json:"symbol"`
+	Side        string  `json:"side"` // long/short
+	EntryPrice  float64 `json:"entry_price"`
+	ExitPrice   float64 `json:"exit_price"`
+	PnL         float64 `json:"pnl"`          // 盈亏（USDT）
+	PnLPct      float64 `json:"pnl_pct"`      // 盈亏百分比（相对保证金）
+	HoldingTime string  `json:"holding_time"` // 持仓时长
+	Reasoning   string  `json:"reasoning"`    // 开仓时的决策理由
 }
 
 // OITopData 持仓量增长Top数据（用于AI决策参考）
@@ -78,25 +108,36 @@ type OITopData struct {
 
 // Context 交易上下文（传递给AI的完整信息）
 type Context struct {
-	CurrentTime      string                     `json:"current_time"`
-	RuntimeMinutes   int                        `json:"runtime_minutes"`
-	CallCount        int                        `json:"call_count"`
-	Account          AccountInfo                `json:"account"`
-	Positions        []PositionInfo             `json:"positions"`
-	ActiveStrategies []*signal.StrategySnapshot `json:"active_strategies"`
-	CandidateCoins   []CandidateCoin            `json:"candidate_coins"`
-	MarketDataMap    map[string]*market.Data    `json:"-"` // 不序列化，但内部使用
-	OITopDataMap     map[string]*OITopData      `json:"-"` // OI Top数据映射
-	Performance      interface{}                `json:"-"` // 历史表现分析（logger.PerformanceAnalysis）
-	BTCETHLeverage   int                        `json:"-"` // BTC/ETH杠杆倍数（从配置读取）
-	AltcoinLeverage  int                        `json:"-"` // 山寨币杠杆倍数（从配置读取）
-	LastFailureReason string                    `json:"last_failure_reason,omitempty"` // 上一次失败的原因（用于重试）
+	CurrentTime        string                     `json:"current_time"`
+	RuntimeMinutes     int                        `json:"runtime_minutes"`
+	CallCount          int                        `json:"call_count"`
+	Account            AccountInfo                `json:"account"`
+	Positions          []PositionInfo             `json:"positions"`
+	ActiveStrategies   []*signal.StrategySnapshot `json:"active_strategies"`
+	CandidateCoins     []CandidateCoin            `json:"candidate_coins"`
+	MarketDataMap      map[string]*market.Data    `json:"-"`                              // 不序列化，但内部使用
+	OITopDataMap       map[string]*OITopData      `json:"-"`                              // OI Top数据映射
+	Performance        interface{}                `json:"-"`                              // 历史表现分析（logger.PerformanceAnalysis）
+	RecentClosedTrades []ClosedTradeInfo          `json:"recent_closed_trades,omitempty"` // 最近平仓的交易（最新在前，未开启时为空）
+	BTCETHLeverage     int                        `json:"-"`                              // BTC/ETH杠杆倍数（从配置读取）
+	AltcoinLeverage    int                        `json:"-"`                              // 山寨币杠杆倍数（从配置读取）
+	LastFailureReason  string                     `json:"last_failure_reason,omitempty"`  // 上一次失败的原因（用于重试）
+	AllowFlip          bool                       `json:"-"`                              // 是否允许反手动作 flip_long/flip_short
+	MaxPromptTokens    int                        `json:"-"`                              // Prompt token 预算（按AI模型配置，0=不限制）
+
+	// OrderBookFetcher 获取币种盘口深度摘要（nil 表示未开启），仅对最终写入 prompt 的币种调用
+	OrderBookFetcher func(symbol string) *OrderBookDepth `json:"-"`
+	// IndicatorFetcher 获取币种多周期技术指标（nil 表示未配置），仅对最终写入 prompt 的币种调用
+	IndicatorFetcher func(symbol string) []market.TimeframeIndicators `json:"-"`
+	// MarketDataCheck 每个币种获取行情后回调（err 为获取失败原因，held 表示是否为持仓币种），
+	// 返回 true 表示币种已下架/暂停交易，对应持仓标记为 SymbolUnavailable；nil 表示不检查
+	MarketDataCheck func(symbol string, held bool, err error) bool `json:"-"`
 }
 
 // Decision AI的交易决策
 type Decision struct {
 	Symbol string `json:"symbol"`
-	Action string `json:"action"` // "open_long", "open_short", "close_long", "close_short", "partial_close", "set_tp_order", "set_sl_order", "update_stop_loss", "update_take_profit", "hold", "wait"
+	Action string `json:"action"` // "open_long", "open_short", "close_long", "close_short", "flip_long", "flip_short", "partial_close", "set_tp_order", "set_sl_order", "update_stop_loss", "update_take_profit", "hold", "wait"
 
 	// 开仓参数
 	Leverage        int     `json:"leverage,omitempty"`
@@ -133,6 +174,9 @@ type FullDecision struct {
 	CoTTrace      string     `json:"cot_trace"`       // 思维链分析（从原始响应中提取的部分）
 	Decisions     []Decision `json:"decisions"`       // 具体决策列表
 	Timestamp     time.Time  `json:"timestamp"`
+
+	// Served 实际响应本次决策的AI提供商/模型（含是否为备用模型）
+	Served mcp.ServedBy `json:"served"`
 }
 
 // GetFullDecision 获取AI的完整交易决策（批量分析所有币种和持仓）
@@ -149,16 +193,19 @@ func GetFullDecisionWithCustomPrompt(ctx *Context, mcpClient *mcp.Client, custom
 
 	// 2. 构建 System Prompt（固定规则）和 User Prompt（动态数据）
 	systemPrompt := buildSystemPromptWithCustom(ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage, customPrompt, overrideBase, templateName)
-	userPrompt := buildUserPrompt(ctx)
+	userPrompt := buildUserPromptWithinBudget(ctx, ctx.MaxPromptTokens-estimateTokens(systemPrompt))
 
 	// 3. 调用AI API（使用 system + user prompt）
-	aiResponse, err := mcpClient.CallWithMessages(systemPrompt, userPrompt)
+	aiResponse, served, err := mcpClient.CallWithMessagesServed(systemPrompt, userPrompt)
 	if err != nil {
 		return nil, fmt.Errorf("调用AI API失败: %w", err)
 	}
 
 	// 4. 解析AI响应
 	decision, err := parseFullDecisionResponse(aiResponse, ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage)
+	if decision != nil {
+		decision.Served = served
+	}
 	if err != nil {
 		return decision, fmt.Errorf("解析AI响应失败: %w", err)
 	}
@@ -201,6 +248,17 @@ func fetchMarketDataForContext(ctx *Context) error {
 
 	for symbol := range symbolSet {
 		data, err := market.Get(symbol)
+		if err == nil && data.CurrentPrice <= 0 {
+			err = fmt.Errorf("%s 行情无效（当前价格为0）", symbol)
+		}
+		if ctx.MarketDataCheck != nil {
+			unavailable := ctx.MarketDataCheck(symbol, positionSymbols[symbol], err)
+			for i := range ctx.Positions {
+				if ctx.Positions[i].Symbol == symbol {
+					ctx.Positions[i].SymbolUnavailable = unavailable
+				}
+			}
+		}
 		if err != nil {
 			// 单个币种失败不影响整体，只记录错误
 			continue
@@ -227,6 +285,34 @@ func fetchMarketDataForContext(ctx *Context) error {
 		ctx.MarketDataMap[symbol] = data
 	}
 
+	// 按需获取盘口深度（只针对有市场数据的持仓和候选币种）
+	if ctx.OrderBookFetcher != nil {
+		for i := range ctx.Positions {
+			if _, ok := ctx.MarketDataMap[ctx.Positions[i].Symbol]; ok {
+				ctx.Positions[i].OrderBook = ctx.OrderBookFetcher(ctx.Positions[i].Symbol)
+			}
+		}
+		for i := range ctx.CandidateCoins {
+			if _, ok := ctx.MarketDataMap[ctx.CandidateCoins[i].Symbol]; ok {
+				ctx.CandidateCoins[i].OrderBook = ctx.OrderBookFetcher(ctx.CandidateCoins[i].Symbol)
+			}
+		}
+	}
+
+	// 按需获取多周期技术指标（同上，K线在 market 包内按周期缓存）
+	if ctx.IndicatorFetcher != nil {
+		for i := range ctx.Positions {
+			if _, ok := ctx.MarketDataMap[ctx.Positions[i].Symbol]; ok {
+				ctx.Positions[i].Indicators = ctx.IndicatorFetcher(ctx.Positions[i].Symbol)
+			}
+		}
+		for i := range ctx.CandidateCoins {
+			if _, ok := ctx.MarketDataMap[ctx.CandidateCoins[i].Symbol]; ok {
+				ctx.CandidateCoins[i].Indicators = ctx.IndicatorFetcher(ctx.CandidateCoins[i].Symbol)
+			}
+		}
+	}
+
 	// 加载OI Top数据（不影响主流程）
 	oiPositions, err := pool.GetOITopPositions()
 	if err == nil {
@@ -569,13 +655,21 @@ func buildUserPrompt(ctx *Context) string {
 				i+1, pos.Symbol, strings.ToUpper(pos.Side),
 				pos.EntryPrice, pos.MarkPrice, pos.UnrealizedPnLPct, pos.UnrealizedPnL, pos.PeakPnLPct,
 				pos.Leverage, pos.MarginUsed, pos.LiquidationPrice, holdingDuration))
+			if pos.SymbolUnavailable {
+				sb.WriteString("⚠️ symbol_unavailable: 该币种已下架或暂停交易，无法获取行情，已通知用户手动处理，请勿对该持仓做出任何决策\n\n")
+			}
 
 			// 使用FormatMarketData输出完整市场数据
 			if marketData, ok := ctx.MarketDataMap[pos.Symbol]; ok {
 				sb.WriteString(market.Format(marketData))
+				sb.WriteString(formatOrderBookDepth(pos.OrderBook))
+				sb.WriteString(formatTimeframeIndicators(pos.Indicators))
 				sb.WriteString("\n")
 			}
 		}
+		if ctx.AllowFlip {
+			sb.WriteString("💡 如需反向，可使用 `flip_long` / `flip_short` 一步完成反手（平掉反向持仓并开新方向，参数与开仓相同）\n\n")
+		}
 	} else {
 		sb.WriteString("当前持仓: 无\n\n")
 	}
@@ -600,6 +694,8 @@ func buildUserPrompt(ctx *Context) string {
 		// 使用FormatMarketData输出完整市场数据
 		sb.WriteString(fmt.Sprintf("### %d. %s%s\n\n", displayedCount, coin.Symbol, sourceTags))
 		sb.WriteString(market.Format(marketData))
+		sb.WriteString(formatOrderBookDepth(coin.OrderBook))
+		sb.WriteString(formatTimeframeIndicators(coin.Indicators))
 		sb.WriteString("\n")
 	}
 	sb.WriteString("\n")
@@ -620,12 +716,157 @@ func buildUserPrompt(ctx *Context) string {
 		}
 	}
 
+	// 最近平仓交易
+	if len(ctx.RecentClosedTrades) > 0 {
+		sb.WriteString(fmt.Sprintf("## 🧾 最近平仓交易 (%d笔，最新在前)\n", len(ctx.RecentClosedTrades)))
+		for i, trade := range ctx.RecentClosedTrades {
+			sb.WriteString(fmt.Sprintf("%d. %s %s | 入场%.4f 出场%.4f | 盈亏%+.2f USDT (%+.2f%%) | 持仓%s\n",
+				i+1, trade.Symbol, strings.ToUpper(trade.Side), trade.EntryPrice, trade.ExitPrice,
+				trade.PnL, trade.PnLPct, trade.HoldingTime))
+			if reasoning := truncateRunes(trade.Reasoning, recentTradeReasoningMaxRunes); reasoning != "" {
+				sb.WriteString(fmt.Sprintf("   - 开仓理由: %s\n", reasoning))
+			}
+		}
+		sb.WriteString("请结合这些交易的结果反思开仓理由是否成立，避免重复同样的错误。\n\n")
+	}
+
 	sb.WriteString("---\n\n")
 	sb.WriteString("现在请分析并输出决策（思维链 + JSON）\n")
 
 	return sb.String()
 }
 
+// recentTradeReasoningMaxRunes 每笔最近平仓交易写入 prompt 的开仓理由最大字符数（控制 token 占用）
+const recentTradeReasoningMaxRunes = 120
+
+// truncateRunes 按字符截断文本（去掉换行），超出部分以省略号结尾
+func truncateRunes(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max]) + "…"
+}
+
+// formatOrderBookDepth 格式化盘口深度摘要（为空时不输出）
+func formatOrderBookDepth(ob *OrderBookDepth) string {
+	if ob == nil {
+		return ""
+	}
+	return fmt.Sprintf("盘口: 买一%.4f 卖一%.4f | 价差%.1f bps | 中间价±%.0f bps内挂单 买%.0f / 卖%.0f USDT（开仓金额应远小于对应方向挂单量，避免滑点）\n",
+		ob.BestBid, ob.BestAsk, ob.SpreadBps, ob.DepthBps, ob.BidDepthUSD, ob.AskDepthUSD)
+}
+
+// formatTimeframeIndicators 多周期指标每个周期一行（只输出已计算的指标，控制 token 用量）
+func formatTimeframeIndicators(indicators []market.TimeframeIndicators) string {
+	var lines []string
+	for _, tf := range indicators {
+		var parts []string
+		if tf.RSI14 != nil {
+			parts = append(parts, fmt.Sprintf("RSI14=%.1f", *tf.RSI14))
+		}
+		if tf.MACD != nil {
+			parts = append(parts, fmt.Sprintf("MACD=%.6g", *tf.MACD))
+		}
+		if tf.EMA20 != nil && tf.EMA50 != nil {
+			parts = append(parts, fmt.Sprintf("EMA20=%.6g EMA50=%.6g(%s)", *tf.EMA20, *tf.EMA50, emaCrossLabel(tf.EMACross)))
+		}
+		if tf.ATR14 != nil {
+			parts = append(parts, fmt.Sprintf("ATR14=%.6g", *tf.ATR14))
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("- %s: %s\n", tf.Timeframe, strings.Join(parts, " | ")))
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "多周期指标:\n" + strings.Join(lines, "")
+}
+
+// emaCrossLabel EMA交叉状态的中文说明
+func emaCrossLabel(cross string) string {
+	switch cross {
+	case "golden":
+		return "刚金叉"
+	case "death":
+		return "刚死叉"
+	case "above":
+		return "EMA20在上方"
+	case "below":
+		return "EMA20在下方"
+	}
+	return cross
+}
+
+// estimateTokens 粗略估算文本 token 数：ASCII 按约4字符/token，中文等非ASCII字符按1字符/token
+func estimateTokens(s string) int {
+	ascii, other := 0, 0
+	for _, r := range s {
+		if r < 128 {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+// buildUserPromptWithinBudget 构建 User Prompt，超出 token 预算时按优先级从低到高裁剪：
+// 先去掉历史表现（含最近平仓交易），再从列表末尾（评分最低）逐个移除候选币种；账户、持仓和指令部分始终保留
+// budget<=0 表示不限制
+func buildUserPromptWithinBudget(ctx *Context, budget int) string {
+	prompt := buildUserPrompt(ctx)
+	if ctx.MaxPromptTokens <= 0 || estimateTokens(prompt) <= budget {
+		return prompt
+	}
+	original := estimateTokens(prompt)
+
+	// 浅拷贝上下文，裁剪不影响调用方（决策记录等仍使用完整数据）
+	trimmed := *ctx
+	var dropped []string
+	if trimmed.Performance != nil || len(trimmed.RecentClosedTrades) > 0 {
+		trimmed.Performance = nil
+		trimmed.RecentClosedTrades = nil
+		dropped = append(dropped, "历史表现")
+		prompt = buildUserPrompt(&trimmed)
+	}
+
+	heldSymbols := make(map[string]bool)
+	for _, pos := range ctx.Positions {
+		heldSymbols[pos.Symbol] = true
+	}
+	trimmed.CandidateCoins = append([]CandidateCoin(nil), ctx.CandidateCoins...)
+	trimmed.MarketDataMap = make(map[string]*market.Data, len(ctx.MarketDataMap))
+	for symbol, data := range ctx.MarketDataMap {
+		trimmed.MarketDataMap[symbol] = data
+	}
+	for estimateTokens(prompt) > budget && len(trimmed.CandidateCoins) > 0 {
+		last := trimmed.CandidateCoins[len(trimmed.CandidateCoins)-1]
+		trimmed.CandidateCoins = trimmed.CandidateCoins[:len(trimmed.CandidateCoins)-1]
+		if heldSymbols[last.Symbol] {
+			// 持仓币种的市场数据在持仓部分输出，不能删除
+			continue
+		}
+		if _, ok := trimmed.MarketDataMap[last.Symbol]; !ok {
+			continue
+		}
+		delete(trimmed.MarketDataMap, last.Symbol)
+		dropped = append(dropped, "候选币种"+last.Symbol)
+		prompt = buildUserPrompt(&trimmed)
+	}
+
+	remaining := estimateTokens(prompt)
+	log.Printf("✂️ Prompt 超出 token 预算（约%d > %d），已裁剪: %s（裁剪后约%d）",
+		original, budget, strings.Join(dropped, ", "), remaining)
+	if remaining > budget {
+		log.Printf("⚠️ 裁剪后仍超出 token 预算（约%d > %d），持仓与指令部分不可裁剪", remaining, budget)
+	}
+	return prompt
+}
+
 // parseFullDecisionResponse 解析AI的完整决策响应
 func parseFullDecisionResponse(aiResponse string, accountEquity float64, btcEthLeverage, altcoinLeverage int) (*FullDecision, error) {
 	// 🔍 调试：打印AI原始响应（可以看到是否有hello等内容）
@@ -917,41 +1158,57 @@ func findMatchingBracket(s string, start int) int {
 	return -1
 }
 
+// MaxPositionValue 单币种仓位价值上限：BTC/ETH 最多10倍账户净值，山寨币最多1.5倍账户净值
+func MaxPositionValue(symbol string, accountEquity float64) float64 {
+	if symbol == "BTCUSDT" || symbol == "ETHUSDT" {
+		return accountEquity * 10
+	}
+	return accountEquity * 1.5
+}
+
 // validateDecision 验证单个决策的有效性
 func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoinLeverage int) error {
 	// 验证action
 	validActions := map[string]bool{
-		"open_long":                       true,
-		"open_short":                      true,
-		"close_long":                      true,
-		"close_short":                     true,
-		"update_stop_loss":                true,
-		"update_take_profit":              true,
+		"open_long":                        true,
+		"open_short":                       true,
+		"close_long":                       true,
+		"close_short":                      true,
+		"flip_long":                        true, // 反手：平空后开多
+		"flip_short":                       true, // 反手：平多后开空
+		"update_stop_loss":                 true,
+		"update_take_profit":               true,
 		"update_stop_loss_and_take_profit": true, // AI 组合动作
-		"partial_close":                   true,
-		"set_tp_order":                    true,
-		"set_sl_order":                    true,
-		"cancel_order":                    true,
-		"place_long_order":                true,
-		"place_short_order":               true,
-		"place_limit_order":               true, // 通用限价单
-		"hold":                            true,
-		"wait":                            true,
+		"partial_close":                    true,
+		"set_tp_order":                     true,
+		"set_sl_order":                     true,
+		"cancel_order":                     true,
+		"place_long_order":                 true,
+		"place_short_order":                true,
+		"place_limit_order":                true, // 通用限价单
+		"hold":                             true,
+		"wait":                             true,
 	}
 
 	if !validActions[d.Action] {
 		return fmt.Errorf("无效的action: %s", d.Action)
 	}
 
-	// 开仓操作必须提供完整参数
-	if d.Action == "open_long" || d.Action == "open_short" {
+	// 开仓操作必须提供完整参数（反手的开仓部分按对应方向的开仓校验）
+	openAction := d.Action
+	switch d.Action {
+	case "flip_long":
+		openAction = "open_long"
+	case "flip_short":
+		openAction = "open_short"
+	}
+	if openAction == "open_long" || openAction == "open_short" {
 		// 根据币种使用配置的杠杆上限
-		maxLeverage := altcoinLeverage          // 山寨币使用配置的杠杆
-		maxPositionValue := accountEquity * 1.5 // 山寨币最多1.5倍账户净值
+		maxLeverage := altcoinLeverage // 山寨币使用配置的杠杆
 		if d.Symbol == "BTCUSDT" || d.Symbol == "ETHUSDT" {
-			maxLeverage = btcEthLeverage          // BTC和ETH使用配置的杠杆
-			maxPositionValue = accountEquity * 10 // BTC/ETH最多10倍账户净值
+			maxLeverage = btcEthLeverage // BTC和ETH使用配置的杠杆
 		}
+		maxPositionValue := MaxPositionValue(d.Symbol, accountEquity)
 
 		if d.Leverage <= 0 || d.Leverage > maxLeverage {
 			return fmt.Errorf("杠杆必须在1-%d之间（%s，当前配置上限%d倍）: %d", maxLeverage, d.Symbol, maxLeverage, d.Leverage)
@@ -989,7 +1246,7 @@ func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoi
 		}
 
 		// 验证止损止盈的合理性
-		if d.Action == "open_long" {
+		if openAction == "open_long" {
 			if d.StopLoss >= d.TakeProfit {
 				return fmt.Errorf("做多时止损价必须小于止盈价")
 			}
@@ -1002,7 +1259,7 @@ func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoi
 		// 验证风险回报比（必须≥1:3）
 		// 计算入场价（假设当前市价）
 		var entryPrice float64
-		if d.Action == "open_long" {
+		if openAction == "open_long" {
 			// 做多：入场价在止损和止盈之间
 			entryPrice = d.StopLoss + (d.TakeProfit-d.StopLoss)*0.2 // 假设在20%位置入场
 		} else {
@@ -1011,7 +1268,7 @@ func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoi
 		}
 
 		var riskPercent, rewardPercent, riskRewardRatio float64
-		if d.Action == "open_long" {
+		if openAction == "open_long" {
 			riskPercent = (entryPrice - d.StopLoss) / entryPrice * 100
 			rewardPercent = (d.TakeProfit - entryPrice) / entryPrice * 100
 			if riskPercent > 0 {
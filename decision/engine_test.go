@@ -0,0 +1,171 @@
+package decision
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"nofx/market"
+)
+
+// newBudgetTestContext 构建包含1个持仓和多个候选币种的上下文
+func newBudgetTestContext(candidates int) *Context {
+	series := make([]float64, 40)
+	for i := range series {
+		series[i] = 100 + float64(i)
+	}
+	newData := func(symbol string) *market.Data {
+		return &market.Data{
+			Symbol:       symbol,
+			CurrentPrice: 100,
+			IntradaySeries: &market.IntradayData{
+				MidPrices:   series,
+				EMA20Values: series,
+			},
+		}
+	}
+
+	ctx := &Context{
+		CurrentTime:   "2025-01-01 00:00:00",
+		Account:       AccountInfo{TotalEquity: 1000, AvailableBalance: 800},
+		Positions:     []PositionInfo{{Symbol: "BTCUSDT", Side: "long", EntryPrice: 100, MarkPrice: 101, Quantity: 1, Leverage: 5}},
+		MarketDataMap: map[string]*market.Data{"BTCUSDT": newData("BTCUSDT")},
+		Performance:   map[string]float64{"sharpe_ratio": 1.2, "profit_factor": 1.5, "win_rate": 0.6},
+	}
+	for i := 0; i < candidates; i++ {
+		symbol := fmt.Sprintf("COIN%dUSDT", i)
+		ctx.CandidateCoins = append(ctx.CandidateCoins, CandidateCoin{Symbol: symbol, Sources: []string{"ai500"}})
+		ctx.MarketDataMap[symbol] = newData(symbol)
+	}
+	return ctx
+}
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected int
+	}{
+		{name: "空字符串", input: "", expected: 0},
+		{name: "ASCII按4字符计1个", input: "abcdefgh", expected: 2},
+		{name: "ASCII不足4字符向上取整", input: "abcde", expected: 2},
+		{name: "中文按字符计", input: "当前持仓", expected: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := estimateTokens(tt.input); got != tt.expected {
+				t.Errorf("estimateTokens(%q) = %d, want %d", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBuildUserPromptWithinBudget(t *testing.T) {
+	ctx := newBudgetTestContext(10)
+	full := buildUserPrompt(ctx)
+
+	t.Run("未配置预算不裁剪", func(t *testing.T) {
+		if got := buildUserPromptWithinBudget(ctx, 0); got != full {
+			t.Errorf("未配置预算时 prompt 不应变化")
+		}
+	})
+
+	t.Run("超出预算裁剪低优先级内容", func(t *testing.T) {
+		ctx.MaxPromptTokens = estimateTokens(full) / 2
+		got := buildUserPromptWithinBudget(ctx, ctx.MaxPromptTokens)
+
+		if estimateTokens(got) > ctx.MaxPromptTokens {
+			t.Errorf("裁剪后 token 数 %d 仍超过预算 %d", estimateTokens(got), ctx.MaxPromptTokens)
+		}
+		// 持仓与指令部分必须保留
+		for _, keep := range []string{"## 当前持仓", "BTCUSDT LONG", "现在请分析并输出决策"} {
+			if !strings.Contains(got, keep) {
+				t.Errorf("裁剪后缺少必须保留的内容: %s", keep)
+			}
+		}
+		// 先去掉历史表现，再从末尾（评分最低）移除候选币种
+		if strings.Contains(got, "交易表现") {
+			t.Errorf("历史表现应被优先裁剪")
+		}
+		if strings.Contains(got, "COIN9USDT") {
+			t.Errorf("评分最低的候选币种应被裁剪")
+		}
+		if !strings.Contains(got, "COIN0USDT") {
+			t.Errorf("评分最高的候选币种应保留")
+		}
+		// 不修改调用方的上下文
+		if len(ctx.CandidateCoins) != 10 || len(ctx.MarketDataMap) != 11 || ctx.Performance == nil {
+			t.Errorf("裁剪不应修改原始上下文")
+		}
+	})
+}
+
+func TestBuildUserPromptOrderBookDepth(t *testing.T) {
+	ctx := newBudgetTestContext(1)
+	if strings.Contains(buildUserPrompt(ctx), "盘口:") {
+		t.Fatalf("未提供盘口深度时不应输出盘口信息")
+	}
+
+	ctx.Positions[0].OrderBook = &OrderBookDepth{BestBid: 100.9, BestAsk: 101.1, SpreadBps: 19.8, DepthBps: 50, BidDepthUSD: 120000, AskDepthUSD: 95000}
+	ctx.CandidateCoins[0].OrderBook = &OrderBookDepth{BestBid: 99.95, BestAsk: 100.05, SpreadBps: 10, DepthBps: 50, BidDepthUSD: 3000, AskDepthUSD: 2500}
+	prompt := buildUserPrompt(ctx)
+	for _, want := range []string{"价差19.8 bps", "买120000 / 卖95000 USDT", "价差10.0 bps", "买3000 / 卖2500 USDT"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt 缺少盘口信息: %s", want)
+		}
+	}
+}
+
+func TestBuildUserPromptTimeframeIndicators(t *testing.T) {
+	ctx := newBudgetTestContext(1)
+	if strings.Contains(buildUserPrompt(ctx), "多周期指标") {
+		t.Fatalf("未配置多周期指标时不应输出该部分")
+	}
+
+	// 60根逐步上涨的1h K线：只选择 RSI 和 EMA 交叉
+	klines := make([]market.Kline, 60)
+	for i := range klines {
+		price := 100 + float64(i)
+		klines[i] = market.Kline{Open: price - 0.5, High: price + 1, Low: price - 1, Close: price}
+	}
+	selected := []string{market.IndicatorRSI, market.IndicatorEMACross}
+	ctx.CandidateCoins[0].Indicators = []market.TimeframeIndicators{market.ComputeTimeframeIndicators("1h", klines, selected)}
+
+	prompt := buildUserPrompt(ctx)
+	for _, want := range []string{"多周期指标:", "- 1h: RSI14=100.0", "(EMA20在上方)"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt 缺少多周期指标: %s\n%s", want, prompt)
+		}
+	}
+	for _, unwanted := range []string{"MACD=", "ATR14="} {
+		if strings.Contains(prompt, unwanted) {
+			t.Errorf("prompt 不应包含未选择的指标: %s", unwanted)
+		}
+	}
+}
+
+func TestBuildUserPromptRecentClosedTrades(t *testing.T) {
+	ctx := newBudgetTestContext(1)
+	if strings.Contains(buildUserPrompt(ctx), "最近平仓交易") {
+		t.Fatalf("未提供最近平仓交易时不应输出该部分")
+	}
+
+	ctx.RecentClosedTrades = []ClosedTradeInfo{
+		{Symbol: "SOLUSDT", Side: "short", EntryPrice: 150, ExitPrice: 156, PnL: -12.5, PnLPct: -20, HoldingTime: "2h0m0s",
+			Reasoning: "4h 级别跌破支撑\n" + strings.Repeat("成交量放大", 50)},
+	}
+	prompt := buildUserPrompt(ctx)
+	for _, want := range []string{"最近平仓交易 (1笔", "SOLUSDT SHORT | 入场150.0000 出场156.0000 | 盈亏-12.50 USDT (-20.00%)", "开仓理由: 4h 级别跌破支撑 成交量放大"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt 缺少最近平仓交易信息: %s", want)
+		}
+	}
+	if strings.Contains(prompt, strings.Repeat("成交量放大", 50)) {
+		t.Errorf("开仓理由应按 %d 字符截断", recentTradeReasoningMaxRunes)
+	}
+
+	ctx.MaxPromptTokens = estimateTokens(prompt) - 1
+	if trimmed := buildUserPromptWithinBudget(ctx, ctx.MaxPromptTokens); strings.Contains(trimmed, "最近平仓交易") {
+		t.Errorf("超出预算时最近平仓交易应与历史表现一起被裁剪")
+	}
+}
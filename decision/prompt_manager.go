@@ -11,8 +11,9 @@ import (
 
 // PromptTemplate 系统提示词模板
 type PromptTemplate struct {
-	Name    string // 模板名称（文件名，不含扩展名）
-	Content string // 模板内容
+	Name     string           // 模板名称（文件名，不含扩展名）
+	Content  string           // 模板内容
+	Defaults TemplateDefaults // 模板基准设置（同名 .defaults.json，未提供时为 BaseTemplateDefaults）
 }
 
 // PromptManager 提示词管理器
@@ -79,10 +80,16 @@ func (pm *PromptManager) LoadTemplates(dir string) error {
 		fileName := filepath.Base(file)
 		templateName := strings.TrimSuffix(fileName, filepath.Ext(fileName))
 
+		defaults, err := loadTemplateDefaults(dir, templateName)
+		if err != nil {
+			log.Printf("⚠️  读取提示词模板基准设置失败 %s: %v", templateName, err)
+		}
+
 		// 存储模板
 		pm.templates[templateName] = &PromptTemplate{
-			Name:    templateName,
-			Content: string(content),
+			Name:     templateName,
+			Content:  string(content),
+			Defaults: defaults,
 		}
 
 		log.Printf("  📄 加载提示词模板: %s (%s)", templateName, fileName)
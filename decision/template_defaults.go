@@ -0,0 +1,76 @@
+package decision
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+)
+
+// templateDefaultsSuffix 模板基准设置文件后缀（与模板同名，如 prompts/nof1.defaults.json）
+const templateDefaultsSuffix = ".defaults.json"
+
+// TemplateDefaults 提示词模板的基准设置（"标准策略"），用于对比交易员相对模板改动了哪些配置
+type TemplateDefaults struct {
+	CustomPrompt        string  `json:"custom_prompt"`         // 附加的自定义策略prompt
+	OverrideBasePrompt  bool    `json:"override_base_prompt"`  // 是否用自定义prompt覆盖模板
+	ScanIntervalMinutes int     `json:"scan_interval_minutes"` // 决策周期（分钟）
+	BTCETHLeverage      int     `json:"btc_eth_leverage"`      // BTC/ETH杠杆倍数
+	AltcoinLeverage     int     `json:"altcoin_leverage"`      // 山寨币杠杆倍数
+	IsCrossMargin       bool    `json:"is_cross_margin"`       // 是否全仓
+	MinConfidence       int     `json:"min_confidence"`        // 最低执行信心度（0=不限制）
+	DefaultStopLossPct  float64 `json:"default_stop_loss_pct"` // 缺少止损时的默认止损百分比
+}
+
+// BaseTemplateDefaults 所有模板共用的基准设置（与新建交易员的系统默认值一致），模板可通过同名 .defaults.json 覆盖部分字段
+func BaseTemplateDefaults() TemplateDefaults {
+	return TemplateDefaults{
+		ScanIntervalMinutes: 5,
+		BTCETHLeverage:      5,
+		AltcoinLeverage:     5,
+		IsCrossMargin:       true,
+	}
+}
+
+// loadTemplateDefaults 读取模板同名的基准设置文件，不存在时使用 BaseTemplateDefaults
+func loadTemplateDefaults(dir, templateName string) (TemplateDefaults, error) {
+	defaults := BaseTemplateDefaults()
+	content, err := os.ReadFile(filepath.Join(dir, templateName+templateDefaultsSuffix))
+	if os.IsNotExist(err) {
+		return defaults, nil
+	}
+	if err != nil {
+		return defaults, err
+	}
+	if err := json.Unmarshal(content, &defaults); err != nil {
+		return BaseTemplateDefaults(), fmt.Errorf("解析模板基准设置失败: %w", err)
+	}
+	return defaults, nil
+}
+
+// TemplateSettingDiff 交易员设置与模板基准不同的一项
+type TemplateSettingDiff struct {
+	Field    string      `json:"field"`    // 设置项（与交易员配置接口字段名一致）
+	Template interface{} `json:"template"` // 模板基准值
+	Current  interface{} `json:"current"`  // 交易员当前值
+}
+
+// DiffTemplateDefaults 对比交易员当前设置与模板基准设置，返回不同的设置项（按字段名排序，无差异时返回空列表）
+func DiffTemplateDefaults(template, current TemplateDefaults) []TemplateSettingDiff {
+	diffs := []TemplateSettingDiff{}
+	tv, cv := reflect.ValueOf(template), reflect.ValueOf(current)
+	for i := 0; i < tv.NumField(); i++ {
+		if reflect.DeepEqual(tv.Field(i).Interface(), cv.Field(i).Interface()) {
+			continue
+		}
+		diffs = append(diffs, TemplateSettingDiff{
+			Field:    tv.Type().Field(i).Tag.Get("json"),
+			Template: tv.Field(i).Interface(),
+			Current:  cv.Field(i).Interface(),
+		})
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Field < diffs[j].Field })
+	return diffs
+}
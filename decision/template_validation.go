@@ -0,0 +1,63 @@
+package decision
+
+import (
+	"regexp"
+	"sort"
+)
+
+// TemplateVariables 提示词模板中可被替换的占位符（{{NAME}}）
+// 信号执行器(strategy_executor)、策略评估器(strategy_evaluator)、信号解析器(signal_parser)均按此列表逐个替换，
+// 新增占位符时需同时更新替换逻辑和此列表
+var TemplateVariables = []string{
+	// 策略执行器
+	"ACTIVE_STRATEGIES", "ACTIVE_STRATEGY_COUNT", "ADDS_JSON", "AVAILABLE_BALANCE", "AVG_PRICE",
+	"CURRENT_ORDERS_JSON", "CURRENT_POSITION_SIDE", "CURRENT_POSITION_SIZE", "CURRENT_PRICE", "CUSTOM_PROMPT",
+	"ENTRY_PRICE", "EXECUTED_ADD_COUNT", "EXECUTION_STATUS", "INITIAL_BALANCE", "LEVERAGE",
+	"MACD_4H", "MAX_ALLOCATION_PER_STRATEGY", "ORDER_HISTORY_JSON", "PERFORMANCE_INFO", "PREV_STRATEGY_TEXT",
+	"RAW_STRATEGY_TEXT", "RSI_1H", "RSI_4H", "STOP_LOSS", "STRATEGY_DIRECTION",
+	"SYMBOL", "TAKE_PROFITS", "TOTAL_EQUITY", "UNREALIZED_PNL",
+	// 策略评估器
+	"CURRENT_PNL_PCT", "CURRENT_QUANTITY", "CURRENT_SIDE", "STRATEGY_JSON", "STRATEGY_TIME", "TIME_DIFF",
+	// 信号解析器
+	"EMAIL_CONTENT",
+}
+
+// placeholderPattern 匹配 {{NAME}} 形式的占位符（允许两侧空白，便于发现写法错误）
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([^{}]*?)\s*\}\}`)
+
+// FindUnresolvedPlaceholders 返回内容中不在 TemplateVariables 内、替换后会原样发送给AI的占位符（去重、排序）
+func FindUnresolvedPlaceholders(content string) []string {
+	known := make(map[string]bool, len(TemplateVariables))
+	for _, name := range TemplateVariables {
+		known[name] = true
+	}
+
+	seen := map[string]bool{}
+	unresolved := []string{}
+	for _, match := range placeholderPattern.FindAllStringSubmatch(content, -1) {
+		// 仅 {{NAME}} 精确写法会被替换，{{ NAME }} 之类的写法同样视为未解析
+		if known[match[1]] && match[0] == "{{"+match[1]+"}}" {
+			continue
+		}
+		if !seen[match[0]] {
+			seen[match[0]] = true
+			unresolved = append(unresolved, match[0])
+		}
+	}
+	sort.Strings(unresolved)
+	return unresolved
+}
+
+// ValidateTemplate 检查模板中无法被替换的占位符
+func (pm *PromptManager) ValidateTemplate(name string) ([]string, error) {
+	template, err := pm.GetTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+	return FindUnresolvedPlaceholders(template.Content), nil
+}
+
+// ValidateTemplate 检查模板中无法被替换的占位符（全局函数）
+func ValidateTemplate(name string) ([]string, error) {
+	return globalPromptManager.ValidateTemplate(name)
+}
@@ -0,0 +1,62 @@
+package decision
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestValidateTemplate(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"ok.txt":    "交易 {{SYMBOL}} 当前价 {{CURRENT_PRICE}}，方向 {{STRATEGY_DIRECTION}}",
+		"typo.txt":  "交易 {{SYMBOL}} 止损 {{STOP_LOS}}，仓位 {{ CURRENT_PRICE }}，再次 {{STOP_LOS}}",
+		"plain.txt": "没有任何占位符",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pm := NewPromptManager()
+	if err := pm.LoadTemplates(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"ok", []string{}},
+		{"plain", []string{}},
+		{"typo", []string{"{{ CURRENT_PRICE }}", "{{STOP_LOS}}"}},
+	}
+	for _, tt := range tests {
+		got, err := pm.ValidateTemplate(tt.name)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: unresolved = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := pm.ValidateTemplate("missing"); err == nil {
+		t.Error("不存在的模板应返回错误")
+	}
+}
+
+// TestBundledTemplatesResolve 仓库自带的模板不应包含无法替换的占位符
+func TestBundledTemplatesResolve(t *testing.T) {
+	pm := NewPromptManager()
+	if err := pm.LoadTemplates(filepath.Join("..", "prompts")); err != nil {
+		t.Skipf("提示词目录不可用: %v", err)
+	}
+	for _, name := range pm.GetAllTemplateNames() {
+		unresolved, _ := pm.ValidateTemplate(name)
+		if len(unresolved) > 0 {
+			t.Errorf("模板 %s 包含无法替换的占位符: %v", name, unresolved)
+		}
+	}
+}
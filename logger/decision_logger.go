@@ -7,25 +7,32 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"sort"
+	"sync"
 	"time"
 )
 
 // DecisionRecord 决策记录
 type DecisionRecord struct {
-	Timestamp      time.Time          `json:"timestamp"`        // 决策时间
-	CycleNumber    int                `json:"cycle_number"`     // 周期编号
-	SystemPrompt   string             `json:"system_prompt"`    // 系统提示词（发送给AI的系统prompt）
-	InputPrompt    string             `json:"input_prompt"`     // 发送给AI的输入prompt
-	RawAIResponse  string             `json:"raw_ai_response"`  // AI原始响应（未裁剪）
-	CoTTrace       string             `json:"cot_trace"`        // AI思维链（从原始响应提取的部分）
-	DecisionJSON   string             `json:"decision_json"`    // 决策JSON
-	AccountState   AccountSnapshot    `json:"account_state"`    // 账户状态快照
-	Positions      []PositionSnapshot `json:"positions"`        // 持仓快照
-	CandidateCoins []string           `json:"candidate_coins"`  // 候选币种列表
-	Decisions      []DecisionAction   `json:"decisions"`        // 执行的决策
-	ExecutionLog   []string           `json:"execution_log"`    // 执行日志
-	Success        bool               `json:"success"`          // 是否成功
-	ErrorMessage   string             `json:"error_message"`    // 错误信息（如果有）
+	Timestamp      time.Time          `json:"timestamp"`       // 决策时间
+	CycleNumber    int                `json:"cycle_number"`    // 周期编号
+	SystemPrompt   string             `json:"system_prompt"`   // 系统提示词（发送给AI的系统prompt）
+	InputPrompt    string             `json:"input_prompt"`    // 发送给AI的输入prompt
+	RawAIResponse  string             `json:"raw_ai_response"` // AI原始响应（未裁剪）
+	CoTTrace       string             `json:"cot_trace"`       // AI思维链（从原始响应提取的部分）
+	DecisionJSON   string             `json:"decision_json"`   // 决策JSON
+	AccountState   AccountSnapshot    `json:"account_state"`   // 账户状态快照
+	Positions      []PositionSnapshot `json:"positions"`       // 持仓快照
+	CandidateCoins []string           `json:"candidate_coins"` // 候选币种列表
+	Decisions      []DecisionAction   `json:"decisions"`       // 执行的决策
+	ExecutionLog   []string           `json:"execution_log"`   // 执行日志
+	Success        bool               `json:"success"`         // 是否成功
+	ErrorMessage   string             `json:"error_message"`   // 错误信息（如果有）
+
+	// 实际响应本次决策的AI（用于排查质量差异与按提供商计费归属）
+	AIProvider  string `json:"ai_provider"`
+	AIModelName string `json:"ai_model_name"`
+	WasFallback bool   `json:"was_fallback"`
 }
 
 // AccountSnapshot 账户状态快照
@@ -47,26 +54,59 @@ type PositionSnapshot struct {
 	UnrealizedProfit float64 `json:"unrealized_profit"`
 	Leverage         float64 `json:"leverage"`
 	LiquidationPrice float64 `json:"liquidation_price"`
+
+	// SymbolUnavailable 币种已下架或暂停交易（无法获取行情，需手动处理）
+	SymbolUnavailable bool `json:"symbol_unavailable,omitempty"`
 }
 
 // DecisionAction 决策动作
 type DecisionAction struct {
-	Action    string    `json:"action"`    // open_long, open_short, close_long, close_short, update_stop_loss, update_take_profit, partial_close
-	Symbol    string    `json:"symbol"`    // 币种
-	Quantity  float64   `json:"quantity"`  // 数量（部分平仓时使用）
-	Leverage  int       `json:"leverage"`  // 杠杆（开仓时）
-	Price     float64   `json:"price"`     // 执行价格
-	OrderID   int64     `json:"order_id"`  // 订单ID
-	Reasoning string    `json:"reasoning"` // 决策理由
-	Timestamp time.Time `json:"timestamp"` // 执行时间
-	Success   bool      `json:"success"`   // 是否成功
-	Error     string    `json:"error"`     // 错误信息
+	Action     string    `json:"action"`               // open_long, open_short, close_long, close_short, update_stop_loss, update_take_profit, partial_close, reprotect_stop_loss, reprotect_take_profit, manual_reprotect_stop_loss, manual_reprotect_take_profit, breakeven_stop_loss, trailing_stop_loss
+	Symbol     string    `json:"symbol"`               // 币种
+	Quantity   float64   `json:"quantity"`             // 数量（部分平仓时使用）
+	Leverage   int       `json:"leverage"`             // 杠杆（开仓时）
+	Confidence int       `json:"confidence,omitempty"` // AI给出的信心度（0-100，未提供时为0）
+	Price      float64   `json:"price"`                // 执行价格
+	OrderID    int64     `json:"order_id"`             // 订单ID
+	Reasoning  string    `json:"reasoning"`            // 决策理由
+	Timestamp  time.Time `json:"timestamp"`            // 执行时间
+	Success    bool      `json:"success"`              // 是否成功
+	Error      string    `json:"error"`                // 错误信息
+
+	// 执行状态：not_executed=仅分析模式下未执行，warmup_skipped=预热期内未执行，
+	// confidence_gated=信心度低于阈值降级为wait，max_open_orders=限价挂单数量达到上限未挂单，
+	// max_actions_skipped=超过单周期动作上限未执行，pending_approval=首笔交易等待人工审批，
+	// approval_rejected=人工审批拒绝，approval_expired=超时未审批，
+	// symbol_exposure_capped=单币种敞口达到上限未执行，rebalance_add_blocked=回撤/净值止损暂停期间再平衡未加仓，
+	// symbol_unavailable=币种已下架或暂停交易未执行（需手动处理），margin_deficit=保证金不足暂停开新仓，空表示正常执行
+	Status string `json:"status,omitempty"`
+	// 执行备注（如杠杆超过交易所分层上限被下调）
+	Note string `json:"note,omitempty"`
+	// 复合动作的子动作（如反手 flip_long/flip_short 拆分为平仓+开仓），统计时按子动作处理
+	Legs []DecisionAction `json:"legs,omitempty"`
+}
+
+// expandActions 将复合动作展开为子动作，便于按普通开平仓统计
+func expandActions(actions []DecisionAction) []DecisionAction {
+	expanded := make([]DecisionAction, 0, len(actions))
+	for _, action := range actions {
+		if len(action.Legs) > 0 {
+			expanded = append(expanded, action.Legs...)
+			continue
+		}
+		expanded = append(expanded, action)
+	}
+	return expanded
 }
 
 // DecisionLogger 决策日志记录器
 type DecisionLogger struct {
 	logDir      string
 	cycleNumber int
+
+	// 实时订阅者（SSE 推送），见 Subscribe
+	subMu       sync.Mutex
+	subscribers map[chan *DecisionRecord]struct{}
 }
 
 // NewDecisionLogger 创建决策日志记录器
@@ -91,6 +131,15 @@ func NewDecisionLogger(logDir string) *DecisionLogger {
 	}
 }
 
+// OpenDecisionLogger 以只读方式打开已有的决策日志目录（不创建目录、不修改权限）
+// 用于未加载到内存的交易员查询/清理历史记录，目录不存在时各读取方法返回空结果
+func OpenDecisionLogger(logDir string) *DecisionLogger {
+	if logDir == "" {
+		logDir = "decision_logs"
+	}
+	return &DecisionLogger{logDir: logDir}
+}
+
 // LogDecision 记录决策
 func (l *DecisionLogger) LogDecision(record *DecisionRecord) error {
 	l.cycleNumber++
@@ -116,12 +165,16 @@ func (l *DecisionLogger) LogDecision(record *DecisionRecord) error {
 	}
 
 	fmt.Printf("📝 决策记录已保存: %s\n", filename)
+	l.publish(record)
 	return nil
 }
 
 // GetLatestRecords 获取最近N条记录（按时间正序：从旧到新）
 func (l *DecisionLogger) GetLatestRecords(n int) ([]*DecisionRecord, error) {
 	files, err := ioutil.ReadDir(l.logDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("读取日志目录失败: %w", err)
 	}
@@ -158,6 +211,46 @@ func (l *DecisionLogger) GetLatestRecords(n int) ([]*DecisionRecord, error) {
 	return records, nil
 }
 
+// GetLatestSnapshot 获取最近一条包含账户快照的记录（不存在时返回nil）
+// 只读取本地日志文件，不访问交易所，用于停止状态的交易员展示最后已知状态
+func (l *DecisionLogger) GetLatestSnapshot() (*DecisionRecord, error) {
+	files, err := ioutil.ReadDir(l.logDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取日志目录失败: %w", err)
+	}
+
+	// 从最新的文件开始倒序查找
+	for i := len(files) - 1; i >= 0; i-- {
+		file := files[i]
+		if file.IsDir() {
+			continue
+		}
+
+		filepath := filepath.Join(l.logDir, file.Name())
+		data, err := ioutil.ReadFile(filepath)
+		if err != nil {
+			continue
+		}
+
+		var record DecisionRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			continue
+		}
+
+		// 跳过没有账户数据的记录（如构建上下文失败的周期）
+		if record.AccountState.TotalBalance <= 0 && len(record.Positions) == 0 {
+			continue
+		}
+
+		return &record, nil
+	}
+
+	return nil, nil
+}
+
 // GetRecordByDate 获取指定日期的所有记录
 func (l *DecisionLogger) GetRecordByDate(date time.Time) ([]*DecisionRecord, error) {
 	dateStr := date.Format("20060102")
@@ -186,41 +279,69 @@ func (l *DecisionLogger) GetRecordByDate(date time.Time) ([]*DecisionRecord, err
 	return records, nil
 }
 
+// MinRetainedDecisionRecords 清理时至少保留的决策记录数（保证 AnalyzePerformance(100) 有足够历史）
+const MinRetainedDecisionRecords = 100
+
 // CleanOldRecords 清理N天前的旧记录
 func (l *DecisionLogger) CleanOldRecords(days int) error {
-	cutoffTime := time.Now().AddDate(0, 0, -days)
+	removedCount, err := l.PruneRecords(time.Now().AddDate(0, 0, -days), 0)
+	if err != nil {
+		return err
+	}
+
+	if removedCount > 0 {
+		fmt.Printf("🗑️ 已清理 %d 条旧记录（%d天前）\n", removedCount, days)
+	}
 
+	return nil
+}
+
+// PruneRecords 删除 before 之前的决策记录，但始终保留最新的 minKeep 条
+// 返回实际删除的记录数
+func (l *DecisionLogger) PruneRecords(before time.Time, minKeep int) (int, error) {
 	files, err := ioutil.ReadDir(l.logDir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
 	if err != nil {
-		return fmt.Errorf("读取日志目录失败: %w", err)
+		return 0, fmt.Errorf("读取日志目录失败: %w", err)
 	}
 
-	removedCount := 0
+	// 只处理决策记录文件，按修改时间正序（从旧到新）
+	var records []os.FileInfo
 	for _, file := range files {
-		if file.IsDir() {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
 			continue
 		}
-
-		if file.ModTime().Before(cutoffTime) {
-			filepath := filepath.Join(l.logDir, file.Name())
-			if err := os.Remove(filepath); err != nil {
-				fmt.Printf("⚠ 删除旧记录失败 %s: %v\n", file.Name(), err)
-				continue
-			}
-			removedCount++
-		}
+		records = append(records, file)
 	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ModTime().Before(records[j].ModTime())
+	})
 
-	if removedCount > 0 {
-		fmt.Printf("🗑️ 已清理 %d 条旧记录（%d天前）\n", removedCount, days)
+	prunable := len(records) - minKeep
+	removedCount := 0
+	for i := 0; i < prunable; i++ {
+		file := records[i]
+		if !file.ModTime().Before(before) {
+			break
+		}
+		if err := os.Remove(filepath.Join(l.logDir, file.Name())); err != nil {
+			fmt.Printf("⚠ 删除旧记录失败 %s: %v\n", file.Name(), err)
+			continue
+		}
+		removedCount++
 	}
 
-	return nil
+	return removedCount, nil
 }
 
 // GetStatistics 获取统计信息
 func (l *DecisionLogger) GetStatistics() (*Statistics, error) {
 	files, err := ioutil.ReadDir(l.logDir)
+	if os.IsNotExist(err) {
+		return &Statistics{}, nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("读取日志目录失败: %w", err)
 	}
@@ -245,7 +366,7 @@ func (l *DecisionLogger) GetStatistics() (*Statistics, error) {
 
 		stats.TotalCycles++
 
-		for _, action := range record.Decisions {
+		for _, action := range expandActions(record.Decisions) {
 			if action.Success {
 				switch action.Action {
 				case "open_long", "open_short":
@@ -294,6 +415,8 @@ type TradeOutcome struct {
 	OpenTime      time.Time `json:"open_time"`      // 开仓时间
 	CloseTime     time.Time `json:"close_time"`     // 平仓时间
 	WasStopLoss   bool      `json:"was_stop_loss"`  // 是否止损
+	OpenReasoning string    `json:"open_reasoning"` // 开仓时的决策理由
+	CloseAction   string    `json:"close_action"`   // 结束该笔交易的平仓动作（close_long/auto_close_short/partial_close 等）
 }
 
 // PerformanceAnalysis 交易表现分析
@@ -325,16 +448,22 @@ type SymbolPerformance struct {
 
 // AnalyzePerformance 分析最近N个周期的交易表现
 func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAnalysis, error) {
+	analysis, _, err := l.analyzePerformance(lookbackCycles)
+	return analysis, err
+}
+
+// analyzePerformance 分析最近N个周期的交易表现，同时返回窗口内全部已平仓交易（按平仓时间正序，未截断）
+func (l *DecisionLogger) analyzePerformance(lookbackCycles int) (*PerformanceAnalysis, []TradeOutcome, error) {
 	records, err := l.GetLatestRecords(lookbackCycles)
 	if err != nil {
-		return nil, fmt.Errorf("读取历史记录失败: %w", err)
+		return nil, nil, fmt.Errorf("读取历史记录失败: %w", err)
 	}
 
 	if len(records) == 0 {
 		return &PerformanceAnalysis{
 			RecentTrades: []TradeOutcome{},
 			SymbolStats:  make(map[string]*SymbolPerformance),
-		}, nil
+		}, []TradeOutcome{}, nil
 	}
 
 	analysis := &PerformanceAnalysis{
@@ -351,7 +480,7 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 	if err == nil && len(allRecords) > len(records) {
 		// 先从扩大的窗口中收集所有开仓记录
 		for _, record := range allRecords {
-			for _, action := range record.Decisions {
+			for _, action := range expandActions(record.Decisions) {
 				if !action.Success {
 					continue
 				}
@@ -385,6 +514,7 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 						"openTime":  action.Timestamp,
 						"quantity":  action.Quantity,
 						"leverage":  action.Leverage,
+						"reasoning": action.Reasoning,
 					}
 				case "close_long", "close_short", "auto_close_long", "auto_close_short":
 					// 移除已平仓记录
@@ -397,7 +527,7 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 
 	// 遍历分析窗口内的记录，生成交易结果
 	for _, record := range records {
-		for _, action := range record.Decisions {
+		for _, action := range expandActions(record.Decisions) {
 			if !action.Success {
 				continue
 			}
@@ -432,6 +562,7 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 					"openTime":           action.Timestamp,
 					"quantity":           action.Quantity,
 					"leverage":           action.Leverage,
+					"reasoning":          action.Reasoning,
 					"remainingQuantity":  action.Quantity, // 🔧 BUG FIX：追蹤剩餘數量
 					"accumulatedPnL":     0.0,             // 🔧 BUG FIX：累積部分平倉盈虧
 					"partialCloseCount":  0,               // 🔧 BUG FIX：部分平倉次數
@@ -446,6 +577,7 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 					side := openPos["side"].(string)
 					quantity := openPos["quantity"].(float64)
 					leverage := openPos["leverage"].(int)
+					openReasoning, _ := openPos["reasoning"].(string)
 
 					// 🔧 BUG FIX：取得追蹤字段（若不存在則初始化）
 					remainingQty, _ := openPos["remainingQuantity"].(float64)
@@ -508,6 +640,8 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 								Duration:      action.Timestamp.Sub(openTime).String(),
 								OpenTime:      openTime,
 								CloseTime:     action.Timestamp,
+								OpenReasoning: openReasoning,
+								CloseAction:   action.Action,
 							}
 
 							analysis.RecentTrades = append(analysis.RecentTrades, outcome)
@@ -568,6 +702,8 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 							Duration:      action.Timestamp.Sub(openTime).String(),
 							OpenTime:      openTime,
 							CloseTime:     action.Timestamp,
+							OpenReasoning: openReasoning,
+							CloseAction:   action.Action,
 						}
 
 						analysis.RecentTrades = append(analysis.RecentTrades, outcome)
@@ -649,6 +785,8 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 		}
 	}
 
+	allTrades := append([]TradeOutcome(nil), analysis.RecentTrades...)
+
 	// 只保留最近的交易（倒序：最新的在前）
 	if len(analysis.RecentTrades) > 10 {
 		// 反转数组，让最新的在前
@@ -666,7 +804,7 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 	// 计算夏普比率（需要至少2个数据点）
 	analysis.SharpeRatio = l.calculateSharpeRatio(records)
 
-	return analysis, nil
+	return analysis, allTrades, nil
 }
 
 // calculateSharpeRatio 计算夏普比率
@@ -0,0 +1,259 @@
+package logger
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeRecordFile 写入一条决策记录文件并设置其修改时间
+func writeRecordFile(t *testing.T, dir string, cycle int, modTime time.Time) string {
+	t.Helper()
+	name := fmt.Sprintf("decision_%s_cycle%d.json", modTime.Format("20060102_150405"), cycle)
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("{}"), 0600); err != nil {
+		t.Fatalf("写入测试记录失败: %v", err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("设置修改时间失败: %v", err)
+	}
+	return name
+}
+
+func TestPruneRecords(t *testing.T) {
+	now := time.Now()
+	cutoff := now.AddDate(0, 0, -90)
+
+	tests := []struct {
+		name        string
+		oldCount    int // cutoff 之前的记录数
+		recentCount int // cutoff 之后的记录数
+		minKeep     int
+		wantRemoved int
+	}{
+		{
+			name:        "删除过期记录_保留近期记录",
+			oldCount:    5,
+			recentCount: 3,
+			minKeep:     0,
+			wantRemoved: 5,
+		},
+		{
+			name:        "最少保留条数保护过期记录",
+			oldCount:    5,
+			recentCount: 3,
+			minKeep:     6,
+			wantRemoved: 2,
+		},
+		{
+			name:        "总数不足最少保留条数时不删除",
+			oldCount:    3,
+			recentCount: 1,
+			minKeep:     10,
+			wantRemoved: 0,
+		},
+		{
+			name:        "没有过期记录",
+			oldCount:    0,
+			recentCount: 4,
+			minKeep:     0,
+			wantRemoved: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			l := NewDecisionLogger(dir)
+
+			var oldFiles, recentFiles []string
+			for i := 0; i < tt.oldCount; i++ {
+				oldFiles = append(oldFiles, writeRecordFile(t, dir, i+1, cutoff.Add(-time.Duration(tt.oldCount-i)*time.Hour)))
+			}
+			for i := 0; i < tt.recentCount; i++ {
+				recentFiles = append(recentFiles, writeRecordFile(t, dir, tt.oldCount+i+1, now.Add(-time.Duration(tt.recentCount-i)*time.Hour)))
+			}
+
+			removed, err := l.PruneRecords(cutoff, tt.minKeep)
+			if err != nil {
+				t.Fatalf("PruneRecords 返回错误: %v", err)
+			}
+			if removed != tt.wantRemoved {
+				t.Errorf("删除数量 = %d, want %d", removed, tt.wantRemoved)
+			}
+
+			// 近期记录必须全部保留
+			for _, name := range recentFiles {
+				if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+					t.Errorf("近期记录 %s 不应被删除", name)
+				}
+			}
+			// 最旧的 wantRemoved 条过期记录被删除，其余保留
+			for i, name := range oldFiles {
+				_, err := os.Stat(filepath.Join(dir, name))
+				if i < tt.wantRemoved && err == nil {
+					t.Errorf("过期记录 %s 应被删除", name)
+				}
+				if i >= tt.wantRemoved && err != nil {
+					t.Errorf("过期记录 %s 受最少保留条数保护，不应被删除", name)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenDecisionLoggerMissingDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "trader_missing")
+	l := OpenDecisionLogger(dir)
+
+	records, err := l.GetLatestRecords(10)
+	if err != nil || len(records) != 0 {
+		t.Errorf("GetLatestRecords = (%d, %v), want (0, nil)", len(records), err)
+	}
+	snapshot, err := l.GetLatestSnapshot()
+	if err != nil || snapshot != nil {
+		t.Errorf("GetLatestSnapshot = (%v, %v), want (nil, nil)", snapshot, err)
+	}
+	removed, err := l.PruneRecords(time.Now(), 0)
+	if err != nil || removed != 0 {
+		t.Errorf("PruneRecords = (%d, %v), want (0, nil)", removed, err)
+	}
+	stats, err := l.GetStatistics()
+	if err != nil || stats == nil || stats.TotalCycles != 0 {
+		t.Errorf("GetStatistics = (%+v, %v), want 空统计", stats, err)
+	}
+
+	// 只读打开不能创建目录
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("OpenDecisionLogger 不应创建目录 %s", dir)
+	}
+}
+
+func TestGetStatisticsBreakdown(t *testing.T) {
+	l := NewDecisionLogger(t.TempDir())
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	act := func(action, symbol string, price, qty float64, minutes int) DecisionAction {
+		return DecisionAction{Action: action, Symbol: symbol, Price: price, Quantity: qty, Leverage: 5,
+			Timestamp: base.Add(time.Duration(minutes) * time.Minute), Success: true}
+	}
+	cycles := [][]DecisionAction{
+		{act("open_long", "BTCUSDT", 100, 1, 0), act("open_short", "BTCUSDT", 100, 1, 0)},
+		{act("close_long", "BTCUSDT", 110, 0, 10), act("close_short", "BTCUSDT", 105, 0, 10)}, // +10 / -5
+		{act("open_long", "SOLUSDT", 20, 10, 20), act("open_short", "SOLUSDT", 20, 10, 20)},
+		{act("close_long", "SOLUSDT", 18, 0, 30), act("auto_close_short", "SOLUSDT", 17, 0, 30)}, // -20 / +30
+		{act("open_long", "BTCUSDT", 100, 1, 40)},
+		{act("close_long", "BTCUSDT", 90, 0, 50)}, // -10
+		{act("open_long", "ETHUSDT", 3000, 1, 60), {Action: "open_short", Symbol: "ETHUSDT", Success: false}},
+	}
+	for _, decisions := range cycles {
+		if err := l.LogDecision(&DecisionRecord{Success: true, Decisions: decisions}); err != nil {
+			t.Fatalf("写入决策记录失败: %v", err)
+		}
+	}
+
+	breakdown, err := l.GetStatisticsBreakdown([]string{BreakdownBySymbol, BreakdownBySide})
+	if err != nil {
+		t.Fatalf("GetStatisticsBreakdown 返回错误: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		node        *BreakdownNode
+		wantSource  string
+		wantTrades  int
+		wantWins    int
+		wantPnL     float64
+		wantWinRate float64
+		wantActions int
+	}{
+		{"总计", &breakdown.BreakdownNode, "trades", 5, 2, 5, 40, 11},
+		{"BTC", breakdown.Groups["BTCUSDT"], "trades", 3, 1, -5, 100.0 / 3, 6},
+		{"BTC 多", breakdown.Groups["BTCUSDT"].Groups["long"], "trades", 2, 1, 0, 50, 4},
+		{"BTC 空", breakdown.Groups["BTCUSDT"].Groups["short"], "trades", 1, 0, -5, 0, 2},
+		{"SOL 多", breakdown.Groups["SOLUSDT"].Groups["long"], "trades", 1, 0, -20, 0, 2},
+		{"SOL 空", breakdown.Groups["SOLUSDT"].Groups["short"], "trades", 1, 1, 30, 100, 2},
+		{"无平仓交易时按决策日志统计", breakdown.Groups["ETHUSDT"].Groups["long"], "decisions", 0, 0, 0, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.node == nil {
+				t.Fatal("分组不存在")
+			}
+			if tt.node.Source != tt.wantSource {
+				t.Errorf("Source = %s, want %s", tt.node.Source, tt.wantSource)
+			}
+			if tt.node.TotalTrades != tt.wantTrades || tt.node.WinningTrades != tt.wantWins {
+				t.Errorf("交易数/盈利数 = %d/%d, want %d/%d", tt.node.TotalTrades, tt.node.WinningTrades, tt.wantTrades, tt.wantWins)
+			}
+			if math.Abs(tt.node.TotalPnL-tt.wantPnL) > 1e-9 {
+				t.Errorf("TotalPnL = %.4f, want %.4f", tt.node.TotalPnL, tt.wantPnL)
+			}
+			if math.Abs(tt.node.WinRate-tt.wantWinRate) > 1e-9 {
+				t.Errorf("WinRate = %.4f, want %.4f", tt.node.WinRate, tt.wantWinRate)
+			}
+			if tt.node.Actions != tt.wantActions {
+				t.Errorf("Actions = %d, want %d", tt.node.Actions, tt.wantActions)
+			}
+		})
+	}
+
+	t.Run("按动作拆分", func(t *testing.T) {
+		byAction, err := l.GetStatisticsBreakdown([]string{BreakdownByAction})
+		if err != nil {
+			t.Fatalf("GetStatisticsBreakdown 返回错误: %v", err)
+		}
+		auto := byAction.Groups["auto_close_short"]
+		if auto == nil || auto.TotalTrades != 1 || auto.TotalPnL != 30 {
+			t.Errorf("auto_close_short 分组 = %+v, want 1 笔盈利 30", auto)
+		}
+		if open := byAction.Groups["open_long"]; open == nil || open.Source != "decisions" || open.Actions != 4 {
+			t.Errorf("open_long 分组 = %+v, want 4 个成功动作", open)
+		}
+	})
+
+	t.Run("不支持的维度", func(t *testing.T) {
+		if _, err := l.GetStatisticsBreakdown([]string{"exchange"}); err == nil {
+			t.Error("不支持的维度应返回错误")
+		}
+	})
+}
+
+func TestDecisionLoggerSubscribe(t *testing.T) {
+	l := NewDecisionLogger(t.TempDir())
+	records, unsubscribe := l.Subscribe(1)
+
+	if err := l.LogDecision(&DecisionRecord{CoTTrace: "思考过程", Success: true}); err != nil {
+		t.Fatalf("写入决策记录失败: %v", err)
+	}
+	select {
+	case record := <-records:
+		if record.CycleNumber != 1 || record.CoTTrace != "思考过程" {
+			t.Errorf("推送的记录不正确: cycle=%d cot=%q", record.CycleNumber, record.CoTTrace)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("未收到推送的决策记录")
+	}
+
+	// 缓冲区已满时丢弃推送，不阻塞写入
+	for i := 0; i < 3; i++ {
+		if err := l.LogDecision(&DecisionRecord{}); err != nil {
+			t.Fatalf("写入决策记录失败: %v", err)
+		}
+	}
+	if got := len(records); got != 1 {
+		t.Errorf("缓冲区中的记录数 = %d, want 1", got)
+	}
+
+	unsubscribe()
+	unsubscribe() // 重复取消订阅应安全
+	if n := l.SubscriberCount(); n != 0 {
+		t.Errorf("取消订阅后订阅者数量 = %d, want 0", n)
+	}
+	<-records
+	if _, ok := <-records; ok {
+		t.Error("取消订阅后通道应被关闭")
+	}
+}
@@ -0,0 +1,53 @@
+package logger
+
+import "fmt"
+
+// Subscribe 订阅新写入的决策记录（用于 SSE 实时推送），返回记录通道和取消订阅函数
+// 订阅者消费过慢、缓冲区已满时丢弃该条记录，不阻塞 LogDecision；取消订阅后通道会被关闭
+func (l *DecisionLogger) Subscribe(buffer int) (<-chan *DecisionRecord, func()) {
+	if buffer <= 0 {
+		buffer = 1
+	}
+	ch := make(chan *DecisionRecord, buffer)
+
+	l.subMu.Lock()
+	if l.subscribers == nil {
+		l.subscribers = make(map[chan *DecisionRecord]struct{})
+	}
+	l.subscribers[ch] = struct{}{}
+	l.subMu.Unlock()
+
+	unsubscribe := func() {
+		l.subMu.Lock()
+		defer l.subMu.Unlock()
+		if _, ok := l.subscribers[ch]; ok {
+			delete(l.subscribers, ch)
+			close(ch)
+		}
+	}
+	return ch, unsubscribe
+}
+
+// SubscriberCount 当前的实时订阅者数量
+func (l *DecisionLogger) SubscriberCount() int {
+	l.subMu.Lock()
+	defer l.subMu.Unlock()
+	return len(l.subscribers)
+}
+
+// publish 将刚写入的决策记录推送给所有订阅者（推送副本，避免调用方后续修改影响订阅者）
+func (l *DecisionLogger) publish(record *DecisionRecord) {
+	l.subMu.Lock()
+	defer l.subMu.Unlock()
+	if len(l.subscribers) == 0 {
+		return
+	}
+	snapshot := *record
+	for ch := range l.subscribers {
+		select {
+		case ch <- &snapshot:
+		default:
+			fmt.Printf("⚠️ 决策记录订阅者消费过慢，丢弃周期 #%d 的推送\n", record.CycleNumber)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"errors"
 	"nofx/config"
 	"os"
 
@@ -146,6 +147,30 @@ func Shutdown() {
 	}
 }
 
+// Notify 直接推送一条通知消息到Telegram（未启用Telegram时忽略）
+func Notify(message string) {
+	if telegramHook == nil || !telegramHook.enabled || telegramHook.sender == nil {
+		return
+	}
+	telegramHook.sender.SendAsync(escapeMarkdown(message))
+}
+
+// ErrTelegramNotConfigured 未启用Telegram推送
+var ErrTelegramNotConfigured = errors.New("未启用Telegram推送")
+
+// TelegramEnabled 是否已启用Telegram推送
+func TelegramEnabled() bool {
+	return telegramHook != nil && telegramHook.enabled && telegramHook.sender != nil
+}
+
+// SendTelegramTest 同步推送一条测试消息到Telegram，返回发送结果（错误中不含 bot token）
+func SendTelegramTest(message string) error {
+	if !TelegramEnabled() {
+		return ErrTelegramNotConfigured
+	}
+	return telegramHook.sender.SendSync(escapeMarkdown(message))
+}
+
 // ============================================================================
 // 日志记录函数
 // ============================================================================
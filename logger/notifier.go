@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"sync"
+	"time"
+)
+
+// 交易事件类型
+const (
+	EventTradeOpened   = "trade_opened"
+	EventTradeClosed   = "trade_closed"
+	EventDrawdownClose = "drawdown_close"
+	EventTraderStopped = "trader_stopped"
+	EventDailySummary  = "daily_summary"
+	EventEquityBracket = "equity_bracket"
+	EventTest          = "test"
+
+	EventExchangeMaintenance = "exchange_maintenance"
+	EventDrawdownStop        = "drawdown_stop"
+	EventStopApproach        = "stop_approach"
+	EventSymbolUnavailable   = "symbol_unavailable"
+	EventAIAnomaly           = "ai_anomaly"
+	EventMarginDeficit       = "margin_deficit"
+)
+
+// EventTypes 可订阅的事件类型
+var EventTypes = []string{EventTradeOpened, EventTradeClosed, EventDrawdownClose, EventTraderStopped, EventDailySummary, EventEquityBracket, EventExchangeMaintenance, EventDrawdownStop, EventStopApproach, EventSymbolUnavailable, EventAIAnomaly, EventMarginDeficit}
+
+// Event 交易事件
+type Event struct {
+	Type      string                 `json:"event"`
+	TraderID  string                 `json:"trader_id"`
+	UserID    string                 `json:"-"`
+	Message   string                 `json:"message"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// Notifier 事件通知后端（Telegram、Webhook等），实现必须是非阻塞的
+type Notifier interface {
+	NotifyEvent(event Event)
+}
+
+// telegramNotifier 将事件消息推送到Telegram
+type telegramNotifier struct{}
+
+func (telegramNotifier) NotifyEvent(event Event) {
+	if event.Message != "" {
+		Notify(event.Message)
+	}
+}
+
+var (
+	notifiersMu sync.RWMutex
+	notifiers   = []Notifier{telegramNotifier{}}
+)
+
+// RegisterNotifier 注册额外的事件通知后端
+func RegisterNotifier(n Notifier) {
+	notifiersMu.Lock()
+	defer notifiersMu.Unlock()
+	notifiers = append(notifiers, n)
+}
+
+// Emit 将事件分发到所有通知后端
+func Emit(event Event) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	notifiersMu.RLock()
+	defer notifiersMu.RUnlock()
+	for _, n := range notifiers {
+		n.NotifyEvent(event)
+	}
+}
@@ -0,0 +1,163 @@
+package logger
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// 统计拆分维度
+const (
+	BreakdownBySymbol = "symbol" // 按币种
+	BreakdownBySide   = "side"   // 按方向（long/short）
+	BreakdownByAction = "action" // 按动作（已平仓交易按结束该笔交易的平仓动作）
+)
+
+// ValidBreakdownDimension 校验统计拆分维度
+func ValidBreakdownDimension(dimension string) bool {
+	return dimension == BreakdownBySymbol || dimension == BreakdownBySide || dimension == BreakdownByAction
+}
+
+// BreakdownStats 单个分组的统计
+// 有已平仓交易时以交易盈亏为准（source=trades），否则只有决策日志中成功执行的动作数（source=decisions）
+type BreakdownStats struct {
+	Source        string  `json:"source"`         // trades / decisions
+	TotalTrades   int     `json:"total_trades"`   // 已平仓交易数
+	WinningTrades int     `json:"winning_trades"` // 盈利次数
+	LosingTrades  int     `json:"losing_trades"`  // 亏损次数
+	WinRate       float64 `json:"win_rate"`       // 胜率（%）
+	TotalPnL      float64 `json:"total_pn_l"`     // 总盈亏（USDT）
+	AvgPnL        float64 `json:"avg_pn_l"`       // 平均盈亏
+	Actions       int     `json:"actions"`        // 决策日志中成功执行的开平仓动作数
+}
+
+// BreakdownNode 统计拆分树的节点，Groups 按下一个维度的取值继续拆分
+type BreakdownNode struct {
+	BreakdownStats
+	Groups map[string]*BreakdownNode `json:"groups,omitempty"`
+}
+
+// StatisticsBreakdown 按维度嵌套拆分的统计（如 symbol,side → groups[BTCUSDT].groups[long]）
+type StatisticsBreakdown struct {
+	Dimensions []string `json:"dimensions"`
+	BreakdownNode
+}
+
+// child 获取（或创建）下一层分组
+func (n *BreakdownNode) child(key string) *BreakdownNode {
+	if n.Groups == nil {
+		n.Groups = make(map[string]*BreakdownNode)
+	}
+	c, ok := n.Groups[key]
+	if !ok {
+		c = &BreakdownNode{}
+		n.Groups[key] = c
+	}
+	return c
+}
+
+// finalize 计算胜率、平均盈亏和数据来源
+func (n *BreakdownNode) finalize() {
+	n.Source = "decisions"
+	if n.TotalTrades > 0 {
+		n.Source = "trades"
+		n.WinRate = float64(n.WinningTrades) / float64(n.TotalTrades) * 100
+		n.AvgPnL = n.TotalPnL / float64(n.TotalTrades)
+	}
+	for _, c := range n.Groups {
+		c.finalize()
+	}
+}
+
+// actionSide 从动作名推断方向（partial_close 等无法推断时为 unknown）
+func actionSide(action string) string {
+	switch {
+	case strings.HasSuffix(action, "_long"):
+		return "long"
+	case strings.HasSuffix(action, "_short"):
+		return "short"
+	}
+	return "unknown"
+}
+
+// isTradeAction 是否为开平仓动作（止损止盈调整等不计入拆分统计）
+func isTradeAction(action string) bool {
+	switch action {
+	case "open_long", "open_short", "close_long", "close_short", "auto_close_long", "auto_close_short", "partial_close":
+		return true
+	}
+	return false
+}
+
+// GetStatisticsBreakdown 按维度（symbol/side/action，按顺序嵌套）拆分全部决策记录的统计
+// 盈亏统计来自决策日志配对出的已平仓交易，动作数来自决策日志中成功执行的开平仓动作
+func (l *DecisionLogger) GetStatisticsBreakdown(dimensions []string) (*StatisticsBreakdown, error) {
+	for _, dim := range dimensions {
+		if !ValidBreakdownDimension(dim) {
+			return nil, fmt.Errorf("不支持的统计维度: %s（可选 symbol / side / action）", dim)
+		}
+	}
+
+	_, trades, err := l.analyzePerformance(math.MaxInt32)
+	if err != nil {
+		return nil, err
+	}
+	records, err := l.GetLatestRecords(math.MaxInt32)
+	if err != nil {
+		return nil, fmt.Errorf("读取历史记录失败: %w", err)
+	}
+
+	breakdown := &StatisticsBreakdown{Dimensions: dimensions}
+	walk := func(keys func(dim string) string, update func(n *BreakdownNode)) {
+		node := &breakdown.BreakdownNode
+		update(node)
+		for _, dim := range dimensions {
+			node = node.child(keys(dim))
+			update(node)
+		}
+	}
+
+	for _, trade := range trades {
+		t := trade
+		walk(func(dim string) string {
+			switch dim {
+			case BreakdownBySymbol:
+				return t.Symbol
+			case BreakdownBySide:
+				return t.Side
+			}
+			return t.CloseAction
+		}, func(n *BreakdownNode) {
+			n.TotalTrades++
+			n.TotalPnL += t.PnL
+			if t.PnL > 0 {
+				n.WinningTrades++
+			} else if t.PnL < 0 {
+				n.LosingTrades++
+			}
+		})
+	}
+
+	for _, record := range records {
+		for _, action := range expandActions(record.Decisions) {
+			if !action.Success || !isTradeAction(action.Action) {
+				continue
+			}
+			a := action
+			walk(func(dim string) string {
+				switch dim {
+				case BreakdownBySymbol:
+					return a.Symbol
+				case BreakdownBySide:
+					return actionSide(a.Action)
+				}
+				return a.Action
+			}, func(n *BreakdownNode) {
+				n.Actions++
+			})
+		}
+	}
+
+	breakdown.finalize()
+	return breakdown, nil
+}
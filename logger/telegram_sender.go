@@ -1,7 +1,9 @@
 package logger
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -111,6 +113,15 @@ func (s *TelegramSender) send(message string) error {
 	return err
 }
 
+// SendSync 同步发送单条消息（不重试，用于测试推送），返回的错误中隐去 bot token
+func (s *TelegramSender) SendSync(message string) error {
+	err := s.send(message)
+	if err != nil && s.bot.Token != "" {
+		return errors.New(strings.ReplaceAll(err.Error(), s.bot.Token, "***"))
+	}
+	return err
+}
+
 // Stop 停止发送器（优雅关闭）
 func (s *TelegramSender) Stop() {
 	s.once.Do(func() {
@@ -0,0 +1,273 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"nofx/config"
+)
+
+// WebhookStore Webhook配置与死信存储
+type WebhookStore interface {
+	GetUserWebhooks(userID string) ([]*config.UserWebhook, error)
+	RecordWebhookDeadLetter(dl *config.WebhookDeadLetter) error
+}
+
+// webhookDelivery 单次Webhook投递任务
+type webhookDelivery struct {
+	webhook *config.UserWebhook
+	event   Event
+}
+
+// WebhookNotifier 将事件以签名JSON异步POST到用户配置的Webhook
+type WebhookNotifier struct {
+	store         WebhookStore
+	client        *http.Client
+	eventChan     chan Event
+	deliveryChan  chan webhookDelivery
+	workers       int
+	retryCount    int
+	retryInterval time.Duration
+	wg            sync.WaitGroup
+	dispatchWg    sync.WaitGroup
+	stopChan      chan struct{}
+	once          sync.Once
+}
+
+// NewWebhookNotifier 创建Webhook通知器并启动投递协程
+func NewWebhookNotifier(store WebhookStore) *WebhookNotifier {
+	n := &WebhookNotifier{
+		store:         store,
+		client:        NewWebhookHTTPClient(10 * time.Second),
+		eventChan:     make(chan Event, 100),
+		deliveryChan:  make(chan webhookDelivery, 100),
+		workers:       4,
+		retryCount:    3,
+		retryInterval: 2 * time.Second, // 指数退避：2s、4s
+		stopChan:      make(chan struct{}),
+	}
+	n.Start()
+	return n
+}
+
+// Start 启动事件分发与投递协程
+func (n *WebhookNotifier) Start() {
+	n.dispatchWg.Add(1)
+	go n.dispatch()
+	for i := 0; i < n.workers; i++ {
+		n.wg.Add(1)
+		go n.deliver()
+	}
+}
+
+// NotifyEvent 实现Notifier接口（非阻塞）
+func (n *WebhookNotifier) NotifyEvent(event Event) {
+	if event.UserID == "" {
+		return
+	}
+	select {
+	case n.eventChan <- event:
+	default:
+		fmt.Printf("[Webhook] 事件缓冲区已满，事件被丢弃: %s (trader=%s)\n", event.Type, event.TraderID)
+	}
+}
+
+// dispatch 查询用户订阅了该事件的Webhook并生成投递任务
+func (n *WebhookNotifier) dispatch() {
+	defer n.dispatchWg.Done()
+
+	handle := func(event Event) {
+		webhooks, err := n.store.GetUserWebhooks(event.UserID)
+		if err != nil {
+			fmt.Printf("[Webhook] 读取用户Webhook失败: %v\n", err)
+			return
+		}
+		for _, w := range webhooks {
+			if !w.Subscribes(event.Type) {
+				continue
+			}
+			select {
+			case n.deliveryChan <- webhookDelivery{webhook: w, event: event}:
+			default:
+				n.deadLetter(w, event, 0, fmt.Errorf("投递队列已满"))
+			}
+		}
+	}
+
+	for {
+		select {
+		case event := <-n.eventChan:
+			handle(event)
+		case <-n.stopChan:
+			for len(n.eventChan) > 0 {
+				handle(<-n.eventChan)
+			}
+			return
+		}
+	}
+}
+
+// deliver 投递协程
+func (n *WebhookNotifier) deliver() {
+	defer n.wg.Done()
+	for d := range n.deliveryChan {
+		n.sendWithRetry(d.webhook, d.event)
+	}
+}
+
+// sendWithRetry 投递（指数退避重试），全部失败后写入死信
+func (n *WebhookNotifier) sendWithRetry(w *config.UserWebhook, event Event) {
+	var err error
+	interval := n.retryInterval
+	for i := 0; i < n.retryCount; i++ {
+		if err = SendWebhook(n.client, w, event); err == nil {
+			return
+		}
+		if i < n.retryCount-1 {
+			time.Sleep(interval)
+			interval *= 2
+		}
+	}
+	n.deadLetter(w, event, n.retryCount, err)
+}
+
+// deadLetter 记录投递失败的事件
+func (n *WebhookNotifier) deadLetter(w *config.UserWebhook, event Event, attempts int, cause error) {
+	fmt.Printf("[Webhook] 投递失败（已尝试%d次）: %s -> %s: %v\n", attempts, event.Type, w.URL, cause)
+	payload, _ := json.Marshal(event)
+	if err := n.store.RecordWebhookDeadLetter(&config.WebhookDeadLetter{
+		WebhookID: w.ID,
+		UserID:    w.UserID,
+		EventType: event.Type,
+		Payload:   string(payload),
+		Attempts:  attempts,
+		LastError: cause.Error(),
+	}); err != nil {
+		fmt.Printf("[Webhook] 写入死信失败: %v\n", err)
+	}
+}
+
+// Stop 停止通知器（处理完已排队的事件后退出）
+func (n *WebhookNotifier) Stop() {
+	n.once.Do(func() {
+		close(n.stopChan)
+		n.dispatchWg.Wait()
+		close(n.deliveryChan)
+		n.wg.Wait()
+	})
+}
+
+// ErrWebhookAddressNotAllowed Webhook地址解析到本机、内网或链路本地地址
+var ErrWebhookAddressNotAllowed = errors.New("webhook address not allowed")
+
+// isDisallowedWebhookIP 是否为禁止投递的地址（本机、内网、链路本地、未指定地址、组播）
+func isDisallowedWebhookIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified()
+}
+
+// webhookDialControl 在建立连接前检查实际要连接的IP（DNS解析之后），防止DNS重绑定绕过
+func webhookDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || isDisallowedWebhookIP(ip) {
+		return fmt.Errorf("%w: %s", ErrWebhookAddressNotAllowed, host)
+	}
+	return nil
+}
+
+// NewWebhookHTTPClient 创建投递Webhook用的HTTP客户端
+// 每次拨号（包括重定向后的请求）都校验目标IP，拒绝连接本机/内网地址；不走环境代理，避免绕过校验
+func NewWebhookHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: webhookDialControl,
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   5 * time.Second,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       90 * time.Second,
+		},
+	}
+}
+
+// ValidateWebhookURL 校验Webhook地址：必须是 http(s) URL，且主机解析出的所有IP都不能是本机/内网地址
+// 仅用于保存时尽早提示，投递时仍由 NewWebhookHTTPClient 在拨号阶段再次校验
+func ValidateWebhookURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("invalid webhook url: %s", raw)
+	}
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("resolve webhook host: %w", err)
+	}
+	for _, ip := range ips {
+		if isDisallowedWebhookIP(ip.IP) {
+			return fmt.Errorf("%w: %s", ErrWebhookAddressNotAllowed, ip.IP)
+		}
+	}
+	return nil
+}
+
+// SignWebhookPayload 计算payload的HMAC-SHA256签名（hex编码）
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SendWebhook 同步投递一次事件，非2xx响应视为失败
+// 请求头 X-Nofx-Signature 为 "sha256=" + HMAC-SHA256(secret, body)
+func SendWebhook(client *http.Client, w *config.UserWebhook, event Event) error {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("序列化事件失败: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "nofx-webhook/1.0")
+	req.Header.Set("X-Nofx-Event", event.Type)
+	req.Header.Set("X-Nofx-Timestamp", strconv.FormatInt(event.Timestamp.Unix(), 10))
+	if w.Secret != "" {
+		req.Header.Set("X-Nofx-Signature", "sha256="+SignWebhookPayload(w.Secret, body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
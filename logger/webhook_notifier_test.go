@@ -0,0 +1,153 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"nofx/config"
+)
+
+// fakeWebhookStore 内存中的Webhook配置与死信存储
+type fakeWebhookStore struct {
+	mu          sync.Mutex
+	webhooks    []*config.UserWebhook
+	deadLetters []*config.WebhookDeadLetter
+}
+
+func (s *fakeWebhookStore) GetUserWebhooks(userID string) ([]*config.UserWebhook, error) {
+	return s.webhooks, nil
+}
+
+func (s *fakeWebhookStore) RecordWebhookDeadLetter(dl *config.WebhookDeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deadLetters = append(s.deadLetters, dl)
+	return nil
+}
+
+func newTestWebhookNotifier(store WebhookStore) *WebhookNotifier {
+	n := &WebhookNotifier{
+		store:         store,
+		client:        &http.Client{Timeout: time.Second},
+		eventChan:     make(chan Event, 10),
+		deliveryChan:  make(chan webhookDelivery, 10),
+		workers:       1,
+		retryCount:    3,
+		retryInterval: time.Millisecond,
+		stopChan:      make(chan struct{}),
+	}
+	n.Start()
+	return n
+}
+
+func TestWebhookNotifierSignedDelivery(t *testing.T) {
+	var received []Event
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if got, want := r.Header.Get("X-Nofx-Signature"), "sha256="+SignWebhookPayload("s3cret", body); got != want {
+			t.Errorf("签名不匹配: got %q, want %q", got, want)
+		}
+		var event Event
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Errorf("payload 不是合法JSON: %v", err)
+		}
+		mu.Lock()
+		received = append(received, event)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	store := &fakeWebhookStore{webhooks: []*config.UserWebhook{
+		{ID: 1, UserID: "u1", URL: server.URL, Secret: "s3cret", Events: []string{EventTradeOpened}, Enabled: true},
+	}}
+	n := newTestWebhookNotifier(store)
+	n.NotifyEvent(Event{Type: EventTradeOpened, TraderID: "t1", UserID: "u1", Message: "open"})
+	n.NotifyEvent(Event{Type: EventTradeClosed, TraderID: "t1", UserID: "u1", Message: "未订阅"})
+	n.Stop()
+
+	if len(received) != 1 {
+		t.Fatalf("应只投递订阅的事件，实际收到 %d 条", len(received))
+	}
+	if received[0].Type != EventTradeOpened || received[0].TraderID != "t1" {
+		t.Errorf("payload 缺少事件类型或交易员ID: %+v", received[0])
+	}
+	if len(store.deadLetters) != 0 {
+		t.Errorf("投递成功不应写入死信")
+	}
+}
+
+func TestWebhookNotifierRetryAndDeadLetter(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	store := &fakeWebhookStore{webhooks: []*config.UserWebhook{
+		{ID: 7, UserID: "u1", URL: server.URL, Enabled: true},
+	}}
+	n := newTestWebhookNotifier(store)
+	n.NotifyEvent(Event{Type: EventTraderStopped, TraderID: "t1", UserID: "u1"})
+	n.Stop()
+
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Errorf("应重试3次，实际 %d 次", got)
+	}
+	if len(store.deadLetters) != 1 {
+		t.Fatalf("重试失败后应写入1条死信，实际 %d 条", len(store.deadLetters))
+	}
+	dl := store.deadLetters[0]
+	if dl.WebhookID != 7 || dl.EventType != EventTraderStopped || dl.Attempts != 3 {
+		t.Errorf("死信内容错误: %+v", dl)
+	}
+}
+
+func TestWebhookClientRejectsPrivateAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("不应投递到本机地址")
+	}))
+	defer server.Close()
+
+	err := SendWebhook(NewWebhookHTTPClient(time.Second), &config.UserWebhook{URL: server.URL}, Event{Type: EventTest})
+	if !errors.Is(err, ErrWebhookAddressNotAllowed) {
+		t.Fatalf("投递本机地址应被拒绝, got %v", err)
+	}
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		blocked bool
+	}{
+		{name: "本机", url: "http://127.0.0.1:8080/hook", blocked: true},
+		{name: "localhost", url: "http://localhost/hook", blocked: true},
+		{name: "内网", url: "https://10.0.0.5/hook", blocked: true},
+		{name: "云元数据链路本地地址", url: "http://169.254.169.254/latest/meta-data", blocked: true},
+		{name: "IPv6本机", url: "http://[::1]/hook", blocked: true},
+		{name: "公网IP", url: "https://8.8.8.8/hook", blocked: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateWebhookURL(context.Background(), tt.url)
+			if got := errors.Is(err, ErrWebhookAddressNotAllowed); got != tt.blocked {
+				t.Errorf("ValidateWebhookURL(%q) = %v, blocked want %v", tt.url, err, tt.blocked)
+			}
+		})
+	}
+
+	if err := ValidateWebhookURL(context.Background(), "ftp://example.com/hook"); err == nil {
+		t.Error("非 http(s) 地址应校验失败")
+	}
+}
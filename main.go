@@ -8,12 +8,14 @@ import (
 	"nofx/auth"
 	"nofx/config"
 	"nofx/crypto"
+	notify "nofx/logger"
 	"nofx/manager"
 	"nofx/market"
 	"nofx/mcp"
 	"nofx/pkg/logger"
 	"nofx/pool"
 	mysignal "nofx/signal"
+	"nofx/tracing"
 	"os"
 	"os/signal"
 	"strconv"
@@ -227,6 +229,16 @@ func main() {
 	database.SetCryptoService(cryptoService)
 	log.Printf("✅ 加密服务初始化成功")
 
+	// 注册Webhook事件通知（异步投递，不阻塞交易流程）
+	webhookNotifier := notify.NewWebhookNotifier(database)
+	notify.RegisterNotifier(webhookNotifier)
+
+	// 决策周期链路追踪（NOFX_TRACING_ENABLED=true 开启，通过 OTLP/HTTP 导出到 collector）
+	if tracingCfg := tracing.ConfigFromEnv(); tracingCfg != nil {
+		tracing.SetExporter(tracing.NewOTLPExporter(*tracingCfg))
+		log.Printf("✅ 决策周期链路追踪已开启: %s (service=%s)", tracingCfg.Endpoint, tracingCfg.ServiceName)
+	}
+
 	// 同步config.json到数据库
 	if err := syncConfigToDatabase(database, configFile); err != nil {
 		log.Printf("⚠️  同步config.json到数据库失败: %v", err)
@@ -258,6 +270,12 @@ func main() {
 	}
 	auth.SetJWTSecret(jwtSecret)
 
+	// 登出token黑名单：从数据库恢复（重启后已登出的token仍然无效），并定期清理过期条目
+	if err := auth.SetBlacklistStore(database); err != nil {
+		log.Printf("⚠️  加载token黑名单失败: %v", err)
+	}
+	auth.StartBlacklistPruner(database.GetTokenBlacklistPruneInterval())
+
 	// 管理员模式下需要管理员密码，缺失则退出
 
 	log.Printf("✓ 配置数据库初始化成功")
@@ -391,6 +409,9 @@ func main() {
 		log.Fatalf("❌ 加载交易员失败: %v", err)
 	}
 
+	// 启动决策日志保留策略（后台定期清理过期记录）
+	traderManager.StartDecisionLogRetention(database)
+
 	// 获取数据库中的所有交易员配置（用于显示，使用default用户）
 	traders, err := database.GetTraders("default")
 	if err != nil {
@@ -472,8 +493,8 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
-	// TODO: 启动数据库中配置为运行状态的交易员
-	// traderManager.StartAll()
+	// 恢复重启前处于运行状态的交易员（系统配置 auto_resume_traders 控制）
+	traderManager.ResumeRunningTraders(database)
 
 	// 等待退出信号
 	<-sigChan
@@ -486,6 +507,14 @@ func main() {
 	traderManager.StopAll()
 	log.Println("✅ 所有交易员已停止")
 
+	// 投递交易员停止期间产生的Webhook事件
+	webhookNotifier.Stop()
+
+	// 导出交易员停止前记录的链路追踪数据
+	if exporter := tracing.SetExporter(nil); exporter != nil {
+		exporter.Shutdown()
+	}
+
 	// 步骤 2: 关闭 API 服务器
 	log.Println("🛑 停止 API 服务器...")
 	// API服务器通过gin.Default()创建，会在程序退出时自动关闭
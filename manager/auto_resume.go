@@ -0,0 +1,116 @@
+package manager
+
+import (
+	"log"
+	"nofx/config"
+	"nofx/trader"
+)
+
+// resumeStore 启动恢复所需的数据库操作
+type resumeStore interface {
+	AutoResumeTradersEnabled() bool
+	GetAllTraders() ([]*config.TraderRecord, error)
+	GetUserByID(userID string) (*config.User, error)
+	GetAIModels(userID string) ([]*config.AIModelConfig, error)
+	GetExchanges(userID string) ([]*config.ExchangeConfig, error)
+	UpdateTraderStatus(userID, id string, isRunning bool) error
+}
+
+// ResumeRunningTraders 启动时恢复重启前 is_running=true 的交易员
+// 配置仍有效的交易员直接 Run()；所有者已删除、AI模型或交易所不可用、加载失败的交易员标记为已停止。
+// 系统配置 auto_resume_traders=false 时不做任何处理
+func (tm *TraderManager) ResumeRunningTraders(database *config.Database) (resumed, stopped int) {
+	return tm.resumeRunningTraders(database, func(at *trader.AutoTrader) {
+		if err := at.Run(); err != nil {
+			log.Printf("❌ %s 运行错误: %v", at.GetName(), err)
+		}
+	})
+}
+
+// resumeRunningTraders 逐个判断运行中交易员能否恢复，run 在独立协程中启动交易员
+func (tm *TraderManager) resumeRunningTraders(store resumeStore, run func(at *trader.AutoTrader)) (resumed, stopped int) {
+	if !store.AutoResumeTradersEnabled() {
+		log.Printf("⏸ 已关闭自动恢复交易员（auto_resume_traders=false），跳过")
+		return 0, 0
+	}
+
+	traders, err := store.GetAllTraders()
+	if err != nil {
+		log.Printf("⚠️ 自动恢复：获取交易员列表失败: %v", err)
+		return 0, 0
+	}
+
+	for _, traderCfg := range traders {
+		if !traderCfg.IsRunning {
+			continue
+		}
+
+		reason := resumeSkipReason(store, traderCfg)
+		var at *trader.AutoTrader
+		if reason == "" {
+			tm.mu.RLock()
+			at = tm.traders[traderCfg.ID]
+			tm.mu.RUnlock()
+			if at == nil {
+				reason = "交易员未能加载到内存"
+			}
+		}
+
+		if reason != "" {
+			log.Printf("⏹ 自动恢复跳过交易员 %s (%s): %s，标记为已停止", traderCfg.Name, traderCfg.ID, reason)
+			if err := store.UpdateTraderStatus(traderCfg.UserID, traderCfg.ID, false); err != nil {
+				log.Printf("⚠️ 更新交易员 %s 运行状态失败: %v", traderCfg.ID, err)
+			}
+			stopped++
+			continue
+		}
+
+		if running, ok := at.GetStatus()["is_running"].(bool); ok && running {
+			continue
+		}
+		log.Printf("▶️  自动恢复交易员 %s (%s)", traderCfg.Name, traderCfg.ID)
+		go run(at)
+		resumed++
+	}
+
+	log.Printf("✓ 自动恢复完成：恢复 %d 个交易员，停止 %d 个", resumed, stopped)
+	return resumed, stopped
+}
+
+// resumeSkipReason 校验交易员配置，返回不能恢复的原因（空字符串表示可以恢复）
+func resumeSkipReason(store resumeStore, traderCfg *config.TraderRecord) string {
+	if _, err := store.GetUserByID(traderCfg.UserID); err != nil {
+		return "所有者不存在"
+	}
+
+	aiModels, err := store.GetAIModels(traderCfg.UserID)
+	if err != nil {
+		return "获取AI模型配置失败"
+	}
+	var aiModelCfg *config.AIModelConfig
+	for _, model := range aiModels {
+		if model.ID == traderCfg.AIModelID || (aiModelCfg == nil && model.Provider == traderCfg.AIModelID) {
+			aiModelCfg = model
+		}
+	}
+	if aiModelCfg == nil {
+		return "AI模型不存在"
+	}
+	if !aiModelCfg.Enabled {
+		return "AI模型未启用"
+	}
+
+	exchanges, err := store.GetExchanges(traderCfg.UserID)
+	if err != nil {
+		return "获取交易所配置失败"
+	}
+	for _, exchange := range exchanges {
+		if exchange.ID == traderCfg.ExchangeID {
+			if !exchange.Enabled {
+				return "交易所未启用"
+			}
+			return ""
+		}
+	}
+	return "交易所不存在"
+}
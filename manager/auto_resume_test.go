@@ -0,0 +1,130 @@
+package manager
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"nofx/config"
+	"nofx/trader"
+)
+
+// fakeResumeStore 模拟启动时的数据库状态
+type fakeResumeStore struct {
+	autoResume bool
+	traders    []*config.TraderRecord
+	users      map[string]bool
+	aiModels   map[string][]*config.AIModelConfig
+	exchanges  map[string][]*config.ExchangeConfig
+	stopped    map[string]bool
+}
+
+func (s *fakeResumeStore) AutoResumeTradersEnabled() bool { return s.autoResume }
+
+func (s *fakeResumeStore) GetAllTraders() ([]*config.TraderRecord, error) { return s.traders, nil }
+
+func (s *fakeResumeStore) GetUserByID(userID string) (*config.User, error) {
+	if !s.users[userID] {
+		return nil, fmt.Errorf("用户不存在")
+	}
+	return &config.User{ID: userID}, nil
+}
+
+func (s *fakeResumeStore) GetAIModels(userID string) ([]*config.AIModelConfig, error) {
+	return s.aiModels[userID], nil
+}
+
+func (s *fakeResumeStore) GetExchanges(userID string) ([]*config.ExchangeConfig, error) {
+	return s.exchanges[userID], nil
+}
+
+func (s *fakeResumeStore) UpdateTraderStatus(userID, id string, isRunning bool) error {
+	if !isRunning {
+		s.stopped[id] = true
+	}
+	return nil
+}
+
+func TestResumeRunningTraders(t *testing.T) {
+	newStore := func() *fakeResumeStore {
+		return &fakeResumeStore{
+			autoResume: true,
+			traders: []*config.TraderRecord{
+				{ID: "valid", Name: "有效", UserID: "u1", AIModelID: "deepseek", ExchangeID: "binance", IsRunning: true},
+				{ID: "idle", Name: "未运行", UserID: "u1", AIModelID: "deepseek", ExchangeID: "binance", IsRunning: false},
+				{ID: "orphan", Name: "所有者已删除", UserID: "deleted", AIModelID: "deepseek", ExchangeID: "binance", IsRunning: true},
+				{ID: "disabled_exchange", Name: "交易所已禁用", UserID: "u2", AIModelID: "deepseek", ExchangeID: "binance", IsRunning: true},
+				{ID: "not_loaded", Name: "未加载", UserID: "u1", AIModelID: "deepseek", ExchangeID: "binance", IsRunning: true},
+			},
+			users: map[string]bool{"u1": true, "u2": true},
+			aiModels: map[string][]*config.AIModelConfig{
+				"u1": {{ID: "deepseek", Enabled: true}},
+				"u2": {{ID: "deepseek", Enabled: true}},
+			},
+			exchanges: map[string][]*config.ExchangeConfig{
+				"u1": {{ID: "binance", Enabled: true}},
+				"u2": {{ID: "binance", Enabled: false}},
+			},
+			stopped: map[string]bool{},
+		}
+	}
+
+	newManager := func() *TraderManager {
+		tm := NewTraderManager()
+		for _, id := range []string{"valid", "idle", "orphan", "disabled_exchange"} {
+			tm.traders[id] = &trader.AutoTrader{}
+		}
+		return tm
+	}
+
+	t.Run("恢复有效交易员并停止无效交易员", func(t *testing.T) {
+		store := newStore()
+		tm := newManager()
+
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		started := map[*trader.AutoTrader]bool{}
+		wg.Add(1)
+		resumed, stopped := tm.resumeRunningTraders(store, func(at *trader.AutoTrader) {
+			defer wg.Done()
+			mu.Lock()
+			started[at] = true
+			mu.Unlock()
+		})
+
+		done := make(chan struct{})
+		go func() { wg.Wait(); close(done) }()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("有效交易员未被启动")
+		}
+
+		if resumed != 1 || stopped != 3 {
+			t.Errorf("resumed=%d stopped=%d, want 1 和 3", resumed, stopped)
+		}
+		if !started[tm.traders["valid"]] || len(started) != 1 {
+			t.Errorf("应只启动有效交易员，实际启动 %d 个", len(started))
+		}
+		for _, id := range []string{"orphan", "disabled_exchange", "not_loaded"} {
+			if !store.stopped[id] {
+				t.Errorf("交易员 %s 应被标记为已停止", id)
+			}
+		}
+		if store.stopped["valid"] || store.stopped["idle"] {
+			t.Errorf("有效或未运行的交易员不应被修改状态")
+		}
+	})
+
+	t.Run("关闭自动恢复时不做处理", func(t *testing.T) {
+		store := newStore()
+		store.autoResume = false
+		resumed, stopped := newManager().resumeRunningTraders(store, func(at *trader.AutoTrader) {
+			t.Error("关闭自动恢复时不应启动交易员")
+		})
+		if resumed != 0 || stopped != 0 || len(store.stopped) != 0 {
+			t.Errorf("关闭自动恢复时不应改变任何状态")
+		}
+	})
+}
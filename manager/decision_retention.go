@@ -0,0 +1,83 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"nofx/config"
+	"nofx/logger"
+	"time"
+)
+
+// decisionRetentionInterval 决策日志自动清理的执行间隔
+const decisionRetentionInterval = 6 * time.Hour
+
+// StartDecisionLogRetention 启动决策日志保留策略的后台任务
+// 按系统配置 decision_log_retention_days（默认90天）定期清理每个交易员的决策日志文件
+// 和 strategy_decision_history，始终保留最近 logger.MinRetainedDecisionRecords 条记录
+func (tm *TraderManager) StartDecisionLogRetention(database *config.Database) {
+	go func() {
+		// 启动后稍作延迟再执行，避免与交易员加载争抢IO
+		time.Sleep(time.Minute)
+		tm.runDecisionLogRetention(database)
+
+		ticker := time.NewTicker(decisionRetentionInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			tm.runDecisionLogRetention(database)
+		}
+	}()
+}
+
+// runDecisionLogRetention 执行一次全量清理
+func (tm *TraderManager) runDecisionLogRetention(database *config.Database) {
+	days := database.GetDecisionLogRetentionDays()
+	if days <= 0 {
+		return
+	}
+	before := time.Now().UTC().AddDate(0, 0, -days)
+
+	traders, err := database.GetAllTraders()
+	if err != nil {
+		log.Printf("⚠️ 决策日志清理：获取交易员列表失败: %v", err)
+		return
+	}
+
+	totalFiles, totalRows := 0, int64(0)
+	for _, t := range traders {
+		files, rows, err := tm.PruneTraderDecisionLogs(database, t.ID, before)
+		if err != nil {
+			log.Printf("⚠️ 决策日志清理失败 [%s]: %v", t.ID, err)
+			continue
+		}
+		totalFiles += files
+		totalRows += rows
+	}
+
+	if totalFiles > 0 || totalRows > 0 {
+		log.Printf("🗑️ 决策日志清理完成（保留%d天）：删除 %d 个日志文件，%d 条策略决策历史", days, totalFiles, totalRows)
+	}
+}
+
+// DecisionLogger 获取交易员的决策日志记录器：优先使用内存中的记录器，否则直接按目录读取（不创建目录）
+func (tm *TraderManager) DecisionLogger(traderID string) *logger.DecisionLogger {
+	if at, err := tm.GetTrader(traderID); err == nil && at != nil {
+		return at.GetDecisionLogger()
+	}
+	return logger.OpenDecisionLogger(fmt.Sprintf("decision_logs/%s", traderID))
+}
+
+// PruneTraderDecisionLogs 清理指定交易员 before 之前的决策日志和策略决策历史
+// 返回删除的日志文件数和数据库记录数
+func (tm *TraderManager) PruneTraderDecisionLogs(database *config.Database, traderID string, before time.Time) (int, int64, error) {
+	files, err := tm.DecisionLogger(traderID).PruneRecords(before, logger.MinRetainedDecisionRecords)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	rows, err := database.PruneStrategyDecisionHistory(traderID, before, logger.MinRetainedDecisionRecords)
+	if err != nil {
+		return files, 0, err
+	}
+
+	return files, rows, nil
+}
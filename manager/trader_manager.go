@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"log"
 	"nofx/config"
+	"nofx/pool"
+	"nofx/signal"
 	"nofx/trader"
 	"sort"
 	"strconv"
@@ -87,6 +89,21 @@ func (tm *TraderManager) LoadTradersFromDatabase(database *config.Database) erro
 		stopTradingMinutes = val
 	}
 
+	// 系统级杠杆上限（对所有交易员的开仓统一生效）
+	trader.SetLeverageCeilings(database.GetLeverageCeilings())
+	// 各交易所下单/撤单限速（同一交易所的交易员共享额度）
+	trader.SetOrderRateLimits(database.GetOrderRateLimits())
+	// 平台维护模式（开启时所有交易员暂停开新仓）
+	trader.SetMaintenanceMode(database.MaintenanceModeEnabled())
+	// 平仓后残留粉尘持仓的处理方式
+	trader.SetDustHandling(database.GetDustHandling())
+	// 交易所维护期间的退避时长
+	trader.SetExchangeMaintenanceBackoff(database.GetExchangeMaintenanceBackoff())
+	// 签名请求的 recvWindow
+	trader.SetRecvWindow(database.GetRecvWindowMillis())
+	// 反手/开反方向仓位前撤销原方向挂单
+	trader.SetCancelOppositeOrders(database.CancelOppositeOrdersEnabled())
+
 	// 解析默认币种列表
 	var defaultCoins []string
 	if defaultCoinsStr != "" {
@@ -181,6 +198,33 @@ func (tm *TraderManager) LoadTradersFromDatabase(database *config.Database) erro
 	return nil
 }
 
+// loadUserSignalSources 读取用户已启用的命名信号源（多信号源模式），未配置时返回nil
+func loadUserSignalSources(database *config.Database, userID string) []pool.SignalSource {
+	if database == nil {
+		return nil
+	}
+	items, err := database.GetUserSignalSourceItems(userID)
+	if err != nil {
+		log.Printf("⚠️  读取用户 %s 的信号源列表失败: %v", userID, err)
+		return nil
+	}
+
+	var sources []pool.SignalSource
+	for _, item := range items {
+		if !item.Enabled {
+			continue
+		}
+		sources = append(sources, pool.SignalSource{
+			Name:    item.Name,
+			URL:     item.URL,
+			Type:    item.Type,
+			Weight:  item.Weight,
+			Enabled: item.Enabled,
+		})
+	}
+	return sources
+}
+
 // addTraderFromConfig 内部方法：从配置添加交易员（不加锁，因为调用方已加锁）
 func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModelCfg *config.AIModelConfig, exchangeCfg *config.ExchangeConfig, coinPoolURL, oiTopURL string, maxDailyLoss, maxDrawdown float64, stopTradingMinutes int, defaultCoins []string, database *config.Database, userID string) error {
 	if _, exists := tm.traders[traderCfg.ID]; exists {
@@ -211,6 +255,10 @@ func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		effectiveCoinPoolURL = coinPoolURL
 		log.Printf("✓ 交易员 %s 启用 COIN POOL 信号源: %s", traderCfg.Name, coinPoolURL)
 	}
+	var effectiveSignalSources []pool.SignalSource
+	if traderCfg.UseCoinPool {
+		effectiveSignalSources = loadUserSignalSources(database, userID)
+	}
 
 	// 构建AutoTraderConfig
 	traderConfig := trader.AutoTraderConfig{
@@ -223,11 +271,13 @@ func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		HyperliquidPrivateKey: "",
 		HyperliquidTestnet:    exchangeCfg.Testnet,
 		CoinPoolAPIURL:        effectiveCoinPoolURL,
+		SignalSources:         effectiveSignalSources,
 		UseQwen:               aiModelCfg.Provider == "qwen",
 		DeepSeekKey:           "",
 		QwenKey:               "",
 		CustomAPIURL:          aiModelCfg.CustomAPIURL,    // 自定义API URL
 		CustomModelName:       aiModelCfg.CustomModelName, // 自定义模型名称
+		MaxPromptTokens:       aiModelCfg.MaxPromptTokens, // Prompt token 预算
 		ScanInterval:          time.Duration(traderCfg.ScanIntervalMinutes) * time.Minute,
 		InitialBalance:        traderCfg.InitialBalance,
 		BTCETHLeverage:        traderCfg.BTCETHLeverage,
@@ -238,7 +288,72 @@ func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		IsCrossMargin:         traderCfg.IsCrossMargin,
 		DefaultCoins:          defaultCoins,
 		TradingCoins:          tradingCoins,
+		CandidateCoins:        trader.ParseSymbolList(traderCfg.CandidateSymbols),
+		AllowedSymbols:        trader.ParseSymbolList(traderCfg.TradingSymbols),
 		SystemPromptTemplate:  traderCfg.SystemPromptTemplate, // 系统提示词模板
+		RequireStopLoss:       traderCfg.RequireStopLoss,
+		DefaultStopLossPct:    traderCfg.DefaultStopLossPct,
+
+		// 交易选项
+		ExcludeHeldFromCandidates: traderCfg.ExcludeHeldFromCandidates,
+		AnalysisOnly:              traderCfg.AnalysisOnly,
+		WarmupMinutes:             traderCfg.WarmupMinutes,
+		SkipCycleIfBusy:           traderCfg.SkipCycleIfBusy,
+		MaxPositionAgeHours:       traderCfg.MaxPositionAgeHours,
+		AllowPyramiding:           traderCfg.AllowPyramiding,
+		MaxAddsPerPosition:        traderCfg.MaxAddsPerPosition,
+		EnforceDailyLossStop:      traderCfg.EnforceDailyLossStop,
+		AllowFlip:                 traderCfg.AllowFlip,
+		MinConfidence:             traderCfg.MinConfidence,
+		SignalBasePositionPct:     traderCfg.SignalBasePositionPct,
+		SignalDefaultAddPct:       traderCfg.SignalDefaultAddPct,
+		EquityTakeProfit:          traderCfg.EquityTakeProfit,
+		EquityStopLoss:            traderCfg.EquityStopLoss,
+		EquityTakeProfitPct:       traderCfg.EquityTakeProfitPct,
+		EquityStopLossPct:         traderCfg.EquityStopLossPct,
+		AutoReprotect:             traderCfg.AutoReprotect,
+		PublicDisplayName:         traderCfg.PublicDisplayName,
+		PublicVisibility:          traderCfg.PublicVisibility,
+		TradingSchedule:           parseTradingSchedule(traderCfg),
+		IncludeOrderBookDepth:     traderCfg.IncludeOrderBookDepth,
+		SkipIfBTCMovePct:          traderCfg.SkipIfBTCMovePct,
+		SkipIfFundingAbove:        traderCfg.SkipIfFundingAbove,
+		MaxOpenOrders:             traderCfg.MaxOpenOrders,
+		BreakevenAtProfitPct:      traderCfg.BreakevenAtProfitPct,
+		TrailStopAfterProfitPct:   traderCfg.TrailStopAfterProfitPct,
+		TrailLockFraction:         traderCfg.TrailLockFraction,
+		MaxActionsPerCycle:        traderCfg.MaxActionsPerCycle,
+		RequireFirstTradeApproval: traderCfg.RequireFirstTradeApproval,
+		MinSecondsBetweenAICalls:  traderCfg.MinSecondsBetweenAICalls,
+		MaxPerSymbolExposurePct:   traderCfg.MaxPerSymbolExposurePct,
+		IncludeRecentTrades:       traderCfg.IncludeRecentTrades,
+		RecentTradesCount:         traderCfg.RecentTradesCount,
+		SizingBase:                traderCfg.SizingBase,
+		AITemperature:             traderCfg.AITemperature,
+		AITopP:                    traderCfg.AITopP,
+		AIMaxTokens:               traderCfg.AIMaxTokens,
+		OnAIFailure:               traderCfg.OnAIFailure,
+		BaselineResetPolicy:       traderCfg.BaselineResetPolicy,
+		EnforceMaxDrawdownStop:    traderCfg.EnforceMaxDrawdownStop,
+		MaxDrawdownStopPct:        traderCfg.MaxDrawdownStopPct,
+		DrawdownStopFlatten:       traderCfg.DrawdownStopFlatten,
+		StopApproachAlertPct:      traderCfg.StopApproachAlertPct,
+		MinHoldingMinutes:         traderCfg.MinHoldingMinutes,
+		StrategySources:           signal.ParseSources(traderCfg.StrategySources),
+		ContextIndicators:         trader.ParseIndicatorList(traderCfg.ContextIndicators),
+		ContextTimeframes:         trader.ParseIndicatorList(traderCfg.ContextTimeframes),
+		TP1ClosePct:               traderCfg.TP1ClosePct,
+		TP1BreakevenStop:          traderCfg.TP1BreakevenStop,
+		RebalanceTargets:          trader.ParseRebalanceTargets(traderCfg.RebalanceTargets),
+		RebalanceTolerancePct:     traderCfg.RebalanceTolerancePct,
+		RebalanceIntervalMinutes:  traderCfg.RebalanceIntervalMinutes,
+		UseOCO:                    traderCfg.UseOCO,
+		AIAnomalyIdleCycles:       traderCfg.AIAnomalyIdleCycles,
+		AIAnomalyRepeatCycles:     traderCfg.AIAnomalyRepeatCycles,
+		AIAnomalyPause:            traderCfg.AIAnomalyPause,
+		ProtectiveDefaults:        trader.ParseProtectiveDefaults(traderCfg.ProtectiveDefaults),
+		PositionAuditMinutes:      traderCfg.PositionAuditMinutes,
+		MinAvailableBalance:       traderCfg.MinAvailableBalance,
 	}
 
 	// 根据交易所类型设置API密钥
@@ -259,11 +374,18 @@ func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		traderConfig.BitgetTestnet = exchangeCfg.Testnet
 	}
 
+	// 配置了备用密钥时加载备用凭证（主密钥持续鉴权/IP失败时切换）
+	applyBackupExchange(&traderConfig, traderCfg, exchangeCfg, database)
+
 	// 根据AI模型设置API密钥
 	if aiModelCfg.Provider == "qwen" {
 		traderConfig.QwenKey = aiModelCfg.APIKey
 	} else if aiModelCfg.Provider == "deepseek" {
 		traderConfig.DeepSeekKey = aiModelCfg.APIKey
+	} else if aiModelCfg.Provider == "custom" {
+		traderConfig.CustomAPIKey = aiModelCfg.APIKey
+		traderConfig.CustomHeaders = aiModelCfg.CustomHeaders
+		traderConfig.CustomAuthScheme = aiModelCfg.CustomAuthScheme
 	}
 
 	// 创建trader实例
@@ -285,16 +407,7 @@ func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModel
 
 	tm.traders[traderCfg.ID] = at
 	log.Printf("✓ Trader '%s' (%s + %s) 已加载到内存", traderCfg.Name, aiModelCfg.Provider, exchangeCfg.ID)
-
-	// 如果数据库中标记为运行中，则自动启动
-	if traderCfg.IsRunning {
-		go func(name string, t *trader.AutoTrader) {
-			log.Printf("🚀 自动启动 Trader '%s'...", name)
-			if err := t.Run(); err != nil {
-				log.Printf("❌ %s 运行错误: %v", name, err)
-			}
-		}(traderCfg.Name, at)
-	}
+	// 只加载不启动：数据库中标记为运行中的交易员统一由 ResumeRunningTraders 启动，避免重复启动
 
 	return nil
 }
@@ -357,6 +470,10 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		effectiveCoinPoolURL = coinPoolURL
 		log.Printf("✓ 交易员 %s 启用 COIN POOL 信号源: %s", traderCfg.Name, coinPoolURL)
 	}
+	var effectiveSignalSources []pool.SignalSource
+	if traderCfg.UseCoinPool {
+		effectiveSignalSources = loadUserSignalSources(database, userID)
+	}
 
 	// 构建AutoTraderConfig
 	traderConfig := trader.AutoTraderConfig{
@@ -369,11 +486,13 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		HyperliquidPrivateKey: "",
 		HyperliquidTestnet:    exchangeCfg.Testnet,
 		CoinPoolAPIURL:        effectiveCoinPoolURL,
+		SignalSources:         effectiveSignalSources,
 		UseQwen:               aiModelCfg.Provider == "qwen",
 		DeepSeekKey:           "",
 		QwenKey:               "",
 		CustomAPIURL:          aiModelCfg.CustomAPIURL,    // 自定义API URL
 		CustomModelName:       aiModelCfg.CustomModelName, // 自定义模型名称
+		MaxPromptTokens:       aiModelCfg.MaxPromptTokens, // Prompt token 预算
 		ScanInterval:          time.Duration(traderCfg.ScanIntervalMinutes) * time.Minute,
 		InitialBalance:        traderCfg.InitialBalance,
 		BTCETHLeverage:        traderCfg.BTCETHLeverage,
@@ -384,7 +503,72 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		IsCrossMargin:         traderCfg.IsCrossMargin,
 		DefaultCoins:          defaultCoins,
 		TradingCoins:          tradingCoins,
+		CandidateCoins:        trader.ParseSymbolList(traderCfg.CandidateSymbols),
+		AllowedSymbols:        trader.ParseSymbolList(traderCfg.TradingSymbols),
 		SystemPromptTemplate:  traderCfg.SystemPromptTemplate,
+		RequireStopLoss:       traderCfg.RequireStopLoss,
+		DefaultStopLossPct:    traderCfg.DefaultStopLossPct,
+
+		// 交易选项
+		ExcludeHeldFromCandidates: traderCfg.ExcludeHeldFromCandidates,
+		AnalysisOnly:              traderCfg.AnalysisOnly,
+		WarmupMinutes:             traderCfg.WarmupMinutes,
+		SkipCycleIfBusy:           traderCfg.SkipCycleIfBusy,
+		MaxPositionAgeHours:       traderCfg.MaxPositionAgeHours,
+		AllowPyramiding:           traderCfg.AllowPyramiding,
+		MaxAddsPerPosition:        traderCfg.MaxAddsPerPosition,
+		EnforceDailyLossStop:      traderCfg.EnforceDailyLossStop,
+		AllowFlip:                 traderCfg.AllowFlip,
+		MinConfidence:             traderCfg.MinConfidence,
+		SignalBasePositionPct:     traderCfg.SignalBasePositionPct,
+		SignalDefaultAddPct:       traderCfg.SignalDefaultAddPct,
+		EquityTakeProfit:          traderCfg.EquityTakeProfit,
+		EquityStopLoss:            traderCfg.EquityStopLoss,
+		EquityTakeProfitPct:       traderCfg.EquityTakeProfitPct,
+		EquityStopLossPct:         traderCfg.EquityStopLossPct,
+		AutoReprotect:             traderCfg.AutoReprotect,
+		PublicDisplayName:         traderCfg.PublicDisplayName,
+		PublicVisibility:          traderCfg.PublicVisibility,
+		TradingSchedule:           parseTradingSchedule(traderCfg),
+		IncludeOrderBookDepth:     traderCfg.IncludeOrderBookDepth,
+		SkipIfBTCMovePct:          traderCfg.SkipIfBTCMovePct,
+		SkipIfFundingAbove:        traderCfg.SkipIfFundingAbove,
+		MaxOpenOrders:             traderCfg.MaxOpenOrders,
+		BreakevenAtProfitPct:      traderCfg.BreakevenAtProfitPct,
+		TrailStopAfterProfitPct:   traderCfg.TrailStopAfterProfitPct,
+		TrailLockFraction:         traderCfg.TrailLockFraction,
+		MaxActionsPerCycle:        traderCfg.MaxActionsPerCycle,
+		RequireFirstTradeApproval: traderCfg.RequireFirstTradeApproval,
+		MinSecondsBetweenAICalls:  traderCfg.MinSecondsBetweenAICalls,
+		MaxPerSymbolExposurePct:   traderCfg.MaxPerSymbolExposurePct,
+		IncludeRecentTrades:       traderCfg.IncludeRecentTrades,
+		RecentTradesCount:         traderCfg.RecentTradesCount,
+		SizingBase:                traderCfg.SizingBase,
+		AITemperature:             traderCfg.AITemperature,
+		AITopP:                    traderCfg.AITopP,
+		AIMaxTokens:               traderCfg.AIMaxTokens,
+		OnAIFailure:               traderCfg.OnAIFailure,
+		BaselineResetPolicy:       traderCfg.BaselineResetPolicy,
+		EnforceMaxDrawdownStop:    traderCfg.EnforceMaxDrawdownStop,
+		MaxDrawdownStopPct:        traderCfg.MaxDrawdownStopPct,
+		DrawdownStopFlatten:       traderCfg.DrawdownStopFlatten,
+		StopApproachAlertPct:      traderCfg.StopApproachAlertPct,
+		MinHoldingMinutes:         traderCfg.MinHoldingMinutes,
+		StrategySources:           signal.ParseSources(traderCfg.StrategySources),
+		ContextIndicators:         trader.ParseIndicatorList(traderCfg.ContextIndicators),
+		ContextTimeframes:         trader.ParseIndicatorList(traderCfg.ContextTimeframes),
+		TP1ClosePct:               traderCfg.TP1ClosePct,
+		TP1BreakevenStop:          traderCfg.TP1BreakevenStop,
+		RebalanceTargets:          trader.ParseRebalanceTargets(traderCfg.RebalanceTargets),
+		RebalanceTolerancePct:     traderCfg.RebalanceTolerancePct,
+		RebalanceIntervalMinutes:  traderCfg.RebalanceIntervalMinutes,
+		UseOCO:                    traderCfg.UseOCO,
+		AIAnomalyIdleCycles:       traderCfg.AIAnomalyIdleCycles,
+		AIAnomalyRepeatCycles:     traderCfg.AIAnomalyRepeatCycles,
+		AIAnomalyPause:            traderCfg.AIAnomalyPause,
+		ProtectiveDefaults:        trader.ParseProtectiveDefaults(traderCfg.ProtectiveDefaults),
+		PositionAuditMinutes:      traderCfg.PositionAuditMinutes,
+		MinAvailableBalance:       traderCfg.MinAvailableBalance,
 	}
 
 	// 根据交易所类型设置API密钥
@@ -405,11 +589,18 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		traderConfig.BitgetTestnet = exchangeCfg.Testnet
 	}
 
+	// 配置了备用密钥时加载备用凭证（主密钥持续鉴权/IP失败时切换）
+	applyBackupExchange(&traderConfig, traderCfg, exchangeCfg, database)
+
 	// 根据AI模型设置API密钥
 	if aiModelCfg.Provider == "qwen" {
 		traderConfig.QwenKey = aiModelCfg.APIKey
 	} else if aiModelCfg.Provider == "deepseek" {
 		traderConfig.DeepSeekKey = aiModelCfg.APIKey
+	} else if aiModelCfg.Provider == "custom" {
+		traderConfig.CustomAPIKey = aiModelCfg.APIKey
+		traderConfig.CustomHeaders = aiModelCfg.CustomHeaders
+		traderConfig.CustomAuthScheme = aiModelCfg.CustomAuthScheme
 	}
 
 	// 创建trader实例
@@ -458,6 +649,18 @@ func (tm *TraderManager) GetAllTraders() map[string]*trader.AutoTrader {
 	return result
 }
 
+// GetRuntimeStats 获取内存中交易员的运行统计（已加载数、运行中数、当日AI调用总次数）
+func (tm *TraderManager) GetRuntimeStats() (loaded, running, aiCallsToday int) {
+	for _, t := range tm.GetAllTraders() {
+		loaded++
+		if isRunning, _ := t.GetStatus()["is_running"].(bool); isRunning {
+			running++
+		}
+		aiCallsToday += t.GetAICallsToday()
+	}
+	return loaded, running, aiCallsToday
+}
+
 // GetTraderIDs 获取所有trader ID列表
 func (tm *TraderManager) GetTraderIDs() []string {
 	tm.mu.RLock()
@@ -534,7 +737,7 @@ func (tm *TraderManager) GetComparisonData() (map[string]interface{}, error) {
 	return comparison, nil
 }
 
-// GetCompetitionData 获取竞赛数据（全平台所有交易员）
+// GetCompetitionData 获取竞赛数据（全平台公开展示的交易员，名称使用公开展示名称）
 func (tm *TraderManager) GetCompetitionData() (map[string]interface{}, error) {
 	// 🔧 修复：移除缓存机制，改为实时获取，确保删除/停止的交易员立即消失
 
@@ -546,6 +749,10 @@ func (tm *TraderManager) GetCompetitionData() (map[string]interface{}, error) {
 	runningCount := 0
 	
 	for _, t := range tm.traders {
+		// 选择不公开展示的交易员不出现在排行榜中
+		if !t.IsPublic() {
+			continue
+		}
 		status := t.GetStatus()
 		// 严格检查 is_running 状态
 		if isRunning, ok := status["is_running"].(bool); ok && isRunning {
@@ -634,7 +841,7 @@ func (tm *TraderManager) getConcurrentTraderData(traders []*trader.AutoTrader) [
 				// 成功获取账户信息
 				traderData = map[string]interface{}{
 					"trader_id":       trader.GetID(),
-					"trader_name":     trader.GetName(),
+					"trader_name":     trader.PublicName(),
 					"ai_model":        trader.GetAIModel(),
 					"exchange":        trader.GetExchange(),
 					"total_equity":    account["total_equity"],
@@ -649,7 +856,7 @@ func (tm *TraderManager) getConcurrentTraderData(traders []*trader.AutoTrader) [
 				log.Printf("⚠️ 获取交易员 %s 账户信息失败: %v", trader.GetID(), err)
 				traderData = map[string]interface{}{
 					"trader_id":       trader.GetID(),
-					"trader_name":     trader.GetName(),
+					"trader_name":     trader.PublicName(),
 					"ai_model":        trader.GetAIModel(),
 					"exchange":        trader.GetExchange(),
 					"total_equity":    0.0,
@@ -665,7 +872,7 @@ func (tm *TraderManager) getConcurrentTraderData(traders []*trader.AutoTrader) [
 				log.Printf("⏰ 获取交易员 %s 账户信息超时", trader.GetID())
 				traderData = map[string]interface{}{
 					"trader_id":       trader.GetID(),
-					"trader_name":     trader.GetName(),
+					"trader_name":     trader.PublicName(),
 					"ai_model":        trader.GetAIModel(),
 					"exchange":        trader.GetExchange(),
 					"total_equity":    0.0,
@@ -796,6 +1003,21 @@ func (tm *TraderManager) LoadUserTraders(database *config.Database, userID strin
 		stopTradingMinutes = val
 	}
 
+	// 系统级杠杆上限（对所有交易员的开仓统一生效）
+	trader.SetLeverageCeilings(database.GetLeverageCeilings())
+	// 各交易所下单/撤单限速（同一交易所的交易员共享额度）
+	trader.SetOrderRateLimits(database.GetOrderRateLimits())
+	// 平台维护模式（开启时所有交易员暂停开新仓）
+	trader.SetMaintenanceMode(database.MaintenanceModeEnabled())
+	// 平仓后残留粉尘持仓的处理方式
+	trader.SetDustHandling(database.GetDustHandling())
+	// 交易所维护期间的退避时长
+	trader.SetExchangeMaintenanceBackoff(database.GetExchangeMaintenanceBackoff())
+	// 签名请求的 recvWindow
+	trader.SetRecvWindow(database.GetRecvWindowMillis())
+	// 反手/开反方向仓位前撤销原方向挂单
+	trader.SetCancelOppositeOrders(database.CancelOppositeOrdersEnabled())
+
 	// 解析默认币种列表
 	var defaultCoins []string
 	if defaultCoinsStr != "" {
@@ -912,6 +1134,10 @@ func (tm *TraderManager) loadSingleTrader(traderCfg *config.TraderRecord, aiMode
 		effectiveCoinPoolURL = coinPoolURL
 		log.Printf("✓ 交易员 %s 启用 COIN POOL 信号源: %s", traderCfg.Name, coinPoolURL)
 	}
+	var effectiveSignalSources []pool.SignalSource
+	if traderCfg.UseCoinPool {
+		effectiveSignalSources = loadUserSignalSources(database, userID)
+	}
 
 	// 构建AutoTraderConfig
 	traderConfig := trader.AutoTraderConfig{
@@ -924,8 +1150,10 @@ func (tm *TraderManager) loadSingleTrader(traderCfg *config.TraderRecord, aiMode
 		AltcoinLeverage:      traderCfg.AltcoinLeverage,
 		ScanInterval:         time.Duration(traderCfg.ScanIntervalMinutes) * time.Minute,
 		CoinPoolAPIURL:       effectiveCoinPoolURL,
+		SignalSources:        effectiveSignalSources,
 		CustomAPIURL:         aiModelCfg.CustomAPIURL,    // 自定义API URL
 		CustomModelName:      aiModelCfg.CustomModelName, // 自定义模型名称
+		MaxPromptTokens:      aiModelCfg.MaxPromptTokens, // Prompt token 预算
 		UseQwen:              aiModelCfg.Provider == "qwen",
 		MaxDailyLoss:         maxDailyLoss,
 		MaxDrawdown:          maxDrawdown,
@@ -933,8 +1161,73 @@ func (tm *TraderManager) loadSingleTrader(traderCfg *config.TraderRecord, aiMode
 		IsCrossMargin:        traderCfg.IsCrossMargin,
 		DefaultCoins:         defaultCoins,
 		TradingCoins:         tradingCoins,
+		CandidateCoins:       trader.ParseSymbolList(traderCfg.CandidateSymbols),
+		AllowedSymbols:       trader.ParseSymbolList(traderCfg.TradingSymbols),
 		SystemPromptTemplate: traderCfg.SystemPromptTemplate, // 系统提示词模板
 		HyperliquidTestnet:   exchangeCfg.Testnet,            // Hyperliquid测试网
+		RequireStopLoss:      traderCfg.RequireStopLoss,
+		DefaultStopLossPct:   traderCfg.DefaultStopLossPct,
+
+		// 交易选项
+		ExcludeHeldFromCandidates: traderCfg.ExcludeHeldFromCandidates,
+		AnalysisOnly:              traderCfg.AnalysisOnly,
+		WarmupMinutes:             traderCfg.WarmupMinutes,
+		SkipCycleIfBusy:           traderCfg.SkipCycleIfBusy,
+		MaxPositionAgeHours:       traderCfg.MaxPositionAgeHours,
+		AllowPyramiding:           traderCfg.AllowPyramiding,
+		MaxAddsPerPosition:        traderCfg.MaxAddsPerPosition,
+		EnforceDailyLossStop:      traderCfg.EnforceDailyLossStop,
+		AllowFlip:                 traderCfg.AllowFlip,
+		MinConfidence:             traderCfg.MinConfidence,
+		SignalBasePositionPct:     traderCfg.SignalBasePositionPct,
+		SignalDefaultAddPct:       traderCfg.SignalDefaultAddPct,
+		EquityTakeProfit:          traderCfg.EquityTakeProfit,
+		EquityStopLoss:            traderCfg.EquityStopLoss,
+		EquityTakeProfitPct:       traderCfg.EquityTakeProfitPct,
+		EquityStopLossPct:         traderCfg.EquityStopLossPct,
+		AutoReprotect:             traderCfg.AutoReprotect,
+		PublicDisplayName:         traderCfg.PublicDisplayName,
+		PublicVisibility:          traderCfg.PublicVisibility,
+		TradingSchedule:           parseTradingSchedule(traderCfg),
+		IncludeOrderBookDepth:     traderCfg.IncludeOrderBookDepth,
+		SkipIfBTCMovePct:          traderCfg.SkipIfBTCMovePct,
+		SkipIfFundingAbove:        traderCfg.SkipIfFundingAbove,
+		MaxOpenOrders:             traderCfg.MaxOpenOrders,
+		BreakevenAtProfitPct:      traderCfg.BreakevenAtProfitPct,
+		TrailStopAfterProfitPct:   traderCfg.TrailStopAfterProfitPct,
+		TrailLockFraction:         traderCfg.TrailLockFraction,
+		MaxActionsPerCycle:        traderCfg.MaxActionsPerCycle,
+		RequireFirstTradeApproval: traderCfg.RequireFirstTradeApproval,
+		MinSecondsBetweenAICalls:  traderCfg.MinSecondsBetweenAICalls,
+		MaxPerSymbolExposurePct:   traderCfg.MaxPerSymbolExposurePct,
+		IncludeRecentTrades:       traderCfg.IncludeRecentTrades,
+		RecentTradesCount:         traderCfg.RecentTradesCount,
+		SizingBase:                traderCfg.SizingBase,
+		AITemperature:             traderCfg.AITemperature,
+		AITopP:                    traderCfg.AITopP,
+		AIMaxTokens:               traderCfg.AIMaxTokens,
+		OnAIFailure:               traderCfg.OnAIFailure,
+		BaselineResetPolicy:       traderCfg.BaselineResetPolicy,
+		EnforceMaxDrawdownStop:    traderCfg.EnforceMaxDrawdownStop,
+		MaxDrawdownStopPct:        traderCfg.MaxDrawdownStopPct,
+		DrawdownStopFlatten:       traderCfg.DrawdownStopFlatten,
+		StopApproachAlertPct:      traderCfg.StopApproachAlertPct,
+		MinHoldingMinutes:         traderCfg.MinHoldingMinutes,
+		StrategySources:           signal.ParseSources(traderCfg.StrategySources),
+		ContextIndicators:         trader.ParseIndicatorList(traderCfg.ContextIndicators),
+		ContextTimeframes:         trader.ParseIndicatorList(traderCfg.ContextTimeframes),
+		TP1ClosePct:               traderCfg.TP1ClosePct,
+		TP1BreakevenStop:          traderCfg.TP1BreakevenStop,
+		RebalanceTargets:          trader.ParseRebalanceTargets(traderCfg.RebalanceTargets),
+		RebalanceTolerancePct:     traderCfg.RebalanceTolerancePct,
+		RebalanceIntervalMinutes:  traderCfg.RebalanceIntervalMinutes,
+		UseOCO:                    traderCfg.UseOCO,
+		AIAnomalyIdleCycles:       traderCfg.AIAnomalyIdleCycles,
+		AIAnomalyRepeatCycles:     traderCfg.AIAnomalyRepeatCycles,
+		AIAnomalyPause:            traderCfg.AIAnomalyPause,
+		ProtectiveDefaults:        trader.ParseProtectiveDefaults(traderCfg.ProtectiveDefaults),
+		PositionAuditMinutes:      traderCfg.PositionAuditMinutes,
+		MinAvailableBalance:       traderCfg.MinAvailableBalance,
 	}
 
 	// 根据交易所类型设置API密钥
@@ -955,11 +1248,18 @@ func (tm *TraderManager) loadSingleTrader(traderCfg *config.TraderRecord, aiMode
 		traderConfig.BitgetTestnet = exchangeCfg.Testnet
 	}
 
+	// 配置了备用密钥时加载备用凭证（主密钥持续鉴权/IP失败时切换）
+	applyBackupExchange(&traderConfig, traderCfg, exchangeCfg, database)
+
 	// 根据AI模型设置API密钥
 	if aiModelCfg.Provider == "qwen" {
 		traderConfig.QwenKey = aiModelCfg.APIKey
 	} else if aiModelCfg.Provider == "deepseek" {
 		traderConfig.DeepSeekKey = aiModelCfg.APIKey
+	} else if aiModelCfg.Provider == "custom" {
+		traderConfig.CustomAPIKey = aiModelCfg.APIKey
+		traderConfig.CustomHeaders = aiModelCfg.CustomHeaders
+		traderConfig.CustomAuthScheme = aiModelCfg.CustomAuthScheme
 	}
 
 	// 创建trader实例
@@ -983,3 +1283,65 @@ func (tm *TraderManager) loadSingleTrader(traderCfg *config.TraderRecord, aiMode
 	log.Printf("✓ Trader '%s' (%s + %s) 已为用户加载到内存", traderCfg.Name, aiModelCfg.Provider, exchangeCfg.ID)
 	return nil
 }
+
+// applyBackupExchange 按交易员的 backup_exchange_id 加载备用交易所凭证。
+// 备用配置须属于同一交易所（provider 相同）且不同于主配置，否则忽略并记录日志
+func applyBackupExchange(traderConfig *trader.AutoTraderConfig, traderCfg *config.TraderRecord, primary *config.ExchangeConfig, database *config.Database) {
+	if traderCfg.BackupExchangeID == "" || traderCfg.BackupExchangeID == primary.ID {
+		return
+	}
+	exchanges, err := database.GetExchanges(traderCfg.UserID)
+	if err != nil {
+		log.Printf("⚠️  交易员 %s 获取备用交易所配置失败: %v", traderCfg.Name, err)
+		return
+	}
+	var backupCfg *config.ExchangeConfig
+	for _, exchange := range exchanges {
+		if exchange.ID == traderCfg.BackupExchangeID {
+			backupCfg = exchange
+			break
+		}
+	}
+	if backupCfg == nil {
+		log.Printf("⚠️  交易员 %s 的备用交易所 %s 不存在，不启用故障切换", traderCfg.Name, traderCfg.BackupExchangeID)
+		return
+	}
+	if backupCfg.Provider != primary.Provider {
+		log.Printf("⚠️  交易员 %s 的备用交易所 %s(%s) 与主交易所 %s 不一致，不启用故障切换", traderCfg.Name, backupCfg.ID, backupCfg.Provider, primary.Provider)
+		return
+	}
+
+	backup := &trader.AutoTraderConfig{Exchange: backupCfg.Provider}
+	switch backupCfg.Provider {
+	case "binance":
+		backup.BinanceAPIKey = backupCfg.APIKey
+		backup.BinanceSecretKey = backupCfg.SecretKey
+	case "hyperliquid":
+		backup.HyperliquidPrivateKey = backupCfg.APIKey // hyperliquid用APIKey存储private key
+		backup.HyperliquidWalletAddr = backupCfg.HyperliquidWalletAddr
+		backup.HyperliquidTestnet = backupCfg.Testnet
+	case "aster":
+		backup.AsterUser = backupCfg.AsterUser
+		backup.AsterSigner = backupCfg.AsterSigner
+		backup.AsterPrivateKey = backupCfg.AsterPrivateKey
+	case "bitget":
+		backup.BitgetAPIKey = backupCfg.APIKey
+		backup.BitgetSecretKey = backupCfg.SecretKey
+		backup.BitgetPassphrase = backupCfg.Passphrase
+		backup.BitgetTestnet = backupCfg.Testnet
+	default:
+		log.Printf("⚠️  交易员 %s 的交易所 %s 不支持备用密钥", traderCfg.Name, backupCfg.Provider)
+		return
+	}
+	traderConfig.BackupExchange = backup
+}
+
+// parseTradingSchedule 解析交易员的交易时段配置，配置无效时记录日志并按全天可交易处理
+func parseTradingSchedule(traderCfg *config.TraderRecord) []trader.TradingWindow {
+	windows, err := trader.ParseTradingSchedule(traderCfg.TradingSchedule)
+	if err != nil {
+		log.Printf("⚠️  交易员 %s 的交易时段配置无效，按全天可交易处理: %v", traderCfg.Name, err)
+		return nil
+	}
+	return windows
+}
@@ -0,0 +1,120 @@
+package market
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 多周期指标上下文可选的指标
+const (
+	IndicatorRSI      = "rsi"       // RSI(14)
+	IndicatorMACD     = "macd"      // MACD(12,26)
+	IndicatorEMACross = "ema_cross" // EMA20/EMA50 及交叉状态
+	IndicatorATR      = "atr"       // ATR(14)
+)
+
+// ContextIndicators 可加入决策上下文的指标
+var ContextIndicators = []string{IndicatorRSI, IndicatorMACD, IndicatorEMACross, IndicatorATR}
+
+// ContextTimeframes 可加入决策上下文的K线周期（按周期从短到长）
+var ContextTimeframes = []string{"5m", "15m", "1h", "4h", "1d"}
+
+// contextKlineLimit 计算指标使用的K线数量（EMA50 需要足够的历史）
+const contextKlineLimit = 100
+
+// klineCacheTTLs 各周期K线缓存时长：多个交易员、持仓与候选币种在同一周期内重复引用时不重复请求
+var klineCacheTTLs = map[string]time.Duration{
+	"5m":  1 * time.Minute,
+	"15m": 3 * time.Minute,
+	"1h":  5 * time.Minute,
+	"4h":  15 * time.Minute,
+	"1d":  15 * time.Minute,
+}
+
+// TimeframeIndicators 单个K线周期的技术指标（只包含选择的指标，未选择的为空）
+type TimeframeIndicators struct {
+	Timeframe string   `json:"timeframe"`
+	RSI14     *float64 `json:"rsi14,omitempty"`
+	MACD      *float64 `json:"macd,omitempty"`
+	EMA20     *float64 `json:"ema20,omitempty"`
+	EMA50     *float64 `json:"ema50,omitempty"`
+	EMACross  string   `json:"ema_cross,omitempty"` // golden=刚金叉，death=刚死叉，above/below=EMA20在EMA50上方/下方
+	ATR14     *float64 `json:"atr14,omitempty"`
+}
+
+// klineCacheEntry 多周期指标使用的K线缓存
+type klineCacheEntry struct {
+	klines    []Kline
+	fetchedAt time.Time
+}
+
+var indicatorKlineCache sync.Map // symbol|interval -> *klineCacheEntry
+
+// GetKlinesCached 获取用于多周期指标的K线（按周期短期缓存，控制交易所API调用量）
+func GetKlinesCached(symbol, interval string) ([]Kline, error) {
+	symbol = Normalize(symbol)
+	key := symbol + "|" + interval
+	ttl, ok := klineCacheTTLs[interval]
+	if !ok {
+		ttl = time.Minute
+	}
+	if v, ok := indicatorKlineCache.Load(key); ok {
+		if entry := v.(*klineCacheEntry); time.Since(entry.fetchedAt) < ttl {
+			return entry.klines, nil
+		}
+	}
+
+	klines, err := NewAPIClient().GetKlines(symbol, interval, contextKlineLimit)
+	if err != nil {
+		return nil, fmt.Errorf("获取%s %s K线失败: %w", symbol, interval, err)
+	}
+	indicatorKlineCache.Store(key, &klineCacheEntry{klines: klines, fetchedAt: time.Now()})
+	return klines, nil
+}
+
+// ComputeTimeframeIndicators 基于单个周期的K线计算选择的指标（K线不足时对应指标为空）
+func ComputeTimeframeIndicators(timeframe string, klines []Kline, indicators []string) TimeframeIndicators {
+	result := TimeframeIndicators{Timeframe: timeframe}
+	round := func(v float64, digits int) *float64 {
+		p := math.Pow(10, float64(digits))
+		v = math.Round(v*p) / p
+		return &v
+	}
+	for _, indicator := range indicators {
+		switch strings.ToLower(indicator) {
+		case IndicatorRSI:
+			if len(klines) > 14 {
+				result.RSI14 = round(calculateRSI(klines, 14), 2)
+			}
+		case IndicatorMACD:
+			if len(klines) >= 26 {
+				result.MACD = round(calculateMACD(klines), 6)
+			}
+		case IndicatorEMACross:
+			if len(klines) > 50 {
+				ema20, ema50 := calculateEMA(klines, 20), calculateEMA(klines, 50)
+				prev := klines[:len(klines)-1]
+				prevAbove := calculateEMA(prev, 20) > calculateEMA(prev, 50)
+				result.EMA20, result.EMA50 = round(ema20, 6), round(ema50, 6)
+				switch above := ema20 > ema50; {
+				case above && !prevAbove:
+					result.EMACross = "golden"
+				case !above && prevAbove:
+					result.EMACross = "death"
+				case above:
+					result.EMACross = "above"
+				default:
+					result.EMACross = "below"
+				}
+			}
+		case IndicatorATR:
+			if len(klines) > 14 {
+				result.ATR14 = round(calculateATR(klines, 14), 6)
+			}
+		}
+	}
+	return result
+}
@@ -8,6 +8,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -31,6 +32,71 @@ type Client struct {
 	Timeout    time.Duration
 	UseFullURL bool // 是否使用完整URL（不添加/chat/completions）
 	MaxTokens  int  // AI响应的最大token数
+
+	// 采样参数（nil 表示使用默认值）
+	Temperature *float64 // 采样温度，未设置时使用 DefaultTemperature
+	TopP        *float64 // 核采样概率，未设置时不发送（使用提供商默认值）
+
+	// 自定义API的认证方式与附加请求头（仅 ProviderCustom 生效）
+	AuthScheme    string            // 密钥的发送方式，为空时使用 bearer
+	CustomHeaders map[string]string // 附加到每个请求的请求头（如组织ID、网关密钥）
+
+	// Fallback 主模型调用失败时使用的备用模型（可选）
+	Fallback *Client
+}
+
+// 自定义API的认证方式
+const (
+	AuthSchemeBearer  = "bearer"    // Authorization: Bearer <key>（默认，OpenAI兼容）
+	AuthSchemeRaw     = "raw"       // Authorization: <key>（不带 Bearer 前缀）
+	AuthSchemeAPIKey  = "api-key"   // api-key: <key>（Azure OpenAI 风格）
+	AuthSchemeXAPIKey = "x-api-key" // X-Api-Key: <key>
+	AuthSchemeNone    = "none"      // 不发送密钥，认证完全由自定义请求头提供
+)
+
+// reservedHeaders 由客户端自行设置、不允许通过自定义请求头覆盖的请求头
+var reservedHeaders = []string{"Content-Type", "Content-Length", "Host"}
+
+// ServedBy 实际响应本次调用的AI提供商与模型
+type ServedBy struct {
+	Provider    Provider `json:"ai_provider"`
+	Model       string   `json:"ai_model_name"`
+	WasFallback bool     `json:"was_fallback"`
+}
+
+// DefaultTemperature 未配置采样温度时使用的默认值（较低的温度提高JSON格式稳定性）
+const DefaultTemperature = 0.5
+
+// SamplingParams 采样参数（Temperature/TopP 为 nil、MaxTokens 为0时使用默认值）
+type SamplingParams struct {
+	Temperature *float64
+	TopP        *float64
+	MaxTokens   int
+}
+
+// providerMaxTokens 各提供商允许的最大输出token数（未列出的提供商不限制）
+var providerMaxTokens = map[Provider]int{
+	ProviderDeepSeek: 8192,
+	ProviderQwen:     65536,
+}
+
+// ValidateSamplingParams 按提供商校验采样参数范围：temperature 0-2（Qwen 不含2），top_p (0,1]，max_tokens 不超过提供商上限
+func ValidateSamplingParams(provider Provider, params SamplingParams) error {
+	if t := params.Temperature; t != nil {
+		if *t < 0 || *t > 2 || (provider == ProviderQwen && *t == 2) {
+			return fmt.Errorf("temperature 超出范围: %v（%s 允许 0-2）", *t, provider)
+		}
+	}
+	if p := params.TopP; p != nil && (*p <= 0 || *p > 1) {
+		return fmt.Errorf("top_p 超出范围: %v（允许 (0, 1]）", *p)
+	}
+	if params.MaxTokens < 0 {
+		return fmt.Errorf("max_tokens 不能为负数: %d", params.MaxTokens)
+	}
+	if limit := providerMaxTokens[provider]; limit > 0 && params.MaxTokens > limit {
+		return fmt.Errorf("max_tokens 超出范围: %d（%s 最大 %d）", params.MaxTokens, provider, limit)
+	}
+	return nil
 }
 
 func New() *Client {
@@ -123,6 +189,98 @@ func (client *Client) SetCustomAPI(apiURL, apiKey, modelName string) {
 	client.Timeout = 120 * time.Second
 }
 
+// NormalizeAuthScheme 统一认证方式格式（小写、去空格），空值视为 bearer
+func NormalizeAuthScheme(authScheme string) string {
+	authScheme = strings.ToLower(strings.TrimSpace(authScheme))
+	if authScheme == "" {
+		return AuthSchemeBearer
+	}
+	return authScheme
+}
+
+// ValidateCustomAuth 校验自定义API的认证方式与附加请求头（请求头名称须为合法的 HTTP token，值不能包含换行）
+func ValidateCustomAuth(authScheme string, headers map[string]string) error {
+	switch NormalizeAuthScheme(authScheme) {
+	case AuthSchemeBearer, AuthSchemeRaw, AuthSchemeAPIKey, AuthSchemeXAPIKey, AuthSchemeNone:
+	default:
+		return fmt.Errorf("不支持的认证方式: %s（可选 bearer/raw/api-key/x-api-key/none）", authScheme)
+	}
+	for name, value := range headers {
+		if !isHeaderToken(name) {
+			return fmt.Errorf("请求头名称无效: %q", name)
+		}
+		for _, reserved := range reservedHeaders {
+			if strings.EqualFold(name, reserved) {
+				return fmt.Errorf("请求头 %s 由系统设置，不能自定义", reserved)
+			}
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("请求头 %s 的值不能包含换行", name)
+		}
+	}
+	return nil
+}
+
+// isHeaderToken 是否为合法的请求头名称（RFC 7230 token）
+func isHeaderToken(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// SetCustomHeaders 设置自定义API的附加请求头与认证方式（仅 ProviderCustom 生效）
+func (client *Client) SetCustomHeaders(headers map[string]string, authScheme string) {
+	client.CustomHeaders = headers
+	client.AuthScheme = NormalizeAuthScheme(authScheme)
+	if len(headers) > 0 {
+		// 只打印请求头名称，值可能包含密钥
+		names := make([]string, 0, len(headers))
+		for name := range headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		log.Printf("🔧 [MCP] 自定义API附加请求头: %s（认证方式: %s）", strings.Join(names, ", "), client.AuthScheme)
+	}
+}
+
+// requiresAPIKey 是否必须配置API密钥（自定义API使用 none 认证方式时由请求头提供认证）
+func (client *Client) requiresAPIKey() bool {
+	return !(client.Provider == ProviderCustom && client.AuthScheme == AuthSchemeNone)
+}
+
+// setAuthHeaders 设置请求的认证头：自定义API按配置的认证方式发送密钥并附加自定义请求头，其他提供商使用 Bearer
+func (client *Client) setAuthHeaders(req *http.Request) {
+	if client.Provider != ProviderCustom {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", client.APIKey))
+		return
+	}
+
+	switch client.AuthScheme {
+	case AuthSchemeNone:
+	case AuthSchemeRaw:
+		req.Header.Set("Authorization", client.APIKey)
+	case AuthSchemeAPIKey:
+		req.Header.Set("api-key", client.APIKey)
+	case AuthSchemeXAPIKey:
+		req.Header.Set("X-Api-Key", client.APIKey)
+	default:
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", client.APIKey))
+	}
+	// 自定义请求头最后设置，可覆盖上面的认证头（如网关要求不同格式的 Authorization）
+	for name, value := range client.CustomHeaders {
+		req.Header.Set(name, value)
+	}
+}
+
 // SetClient 设置完整的AI配置（高级用户）
 func (client *Client) SetClient(Client Client) {
 	if Client.Timeout == 0 {
@@ -131,9 +289,62 @@ func (client *Client) SetClient(Client Client) {
 	client = &Client
 }
 
+// SetSamplingParams 设置采样参数（MaxTokens 为0时保留当前值）
+func (client *Client) SetSamplingParams(params SamplingParams) {
+	client.Temperature = params.Temperature
+	client.TopP = params.TopP
+	if params.MaxTokens > 0 {
+		client.MaxTokens = params.MaxTokens
+	}
+}
+
+// applySampling 将采样参数写入请求体
+func (client *Client) applySampling(requestBody map[string]interface{}) {
+	temperature := DefaultTemperature
+	if client.Temperature != nil {
+		temperature = *client.Temperature
+	}
+	requestBody["temperature"] = temperature
+	requestBody["max_tokens"] = client.MaxTokens
+	if client.TopP != nil {
+		requestBody["top_p"] = *client.TopP
+	}
+}
+
+// SetFallback 设置备用模型，主模型重试后仍失败时改用备用模型
+func (client *Client) SetFallback(fallback *Client) {
+	client.Fallback = fallback
+}
+
 // CallWithMessages 使用 system + user prompt 调用AI API（推荐）
 func (client *Client) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
-	if client.APIKey == "" {
+	result, _, err := client.CallWithMessagesServed(systemPrompt, userPrompt)
+	return result, err
+}
+
+// CallWithMessagesServed 与 CallWithMessages 相同，但额外返回实际响应的提供商与模型
+// 主模型失败且配置了备用模型时，自动切换到备用模型并标记 WasFallback
+func (client *Client) CallWithMessagesServed(systemPrompt, userPrompt string) (string, ServedBy, error) {
+	result, err := client.callWithRetry(systemPrompt, userPrompt)
+	if err == nil {
+		return result, ServedBy{Provider: client.Provider, Model: client.Model}, nil
+	}
+	if client.Fallback == nil {
+		return "", ServedBy{}, err
+	}
+
+	log.Printf("⚠️  [MCP] 主模型 %s/%s 调用失败，切换备用模型 %s/%s: %v",
+		client.Provider, client.Model, client.Fallback.Provider, client.Fallback.Model, err)
+	result, fbErr := client.Fallback.callWithRetry(systemPrompt, userPrompt)
+	if fbErr != nil {
+		return "", ServedBy{}, fmt.Errorf("主模型失败: %v; 备用模型失败: %w", err, fbErr)
+	}
+	return result, ServedBy{Provider: client.Fallback.Provider, Model: client.Fallback.Model, WasFallback: true}, nil
+}
+
+// callWithRetry 调用单个模型（带重试），不涉及备用模型
+func (client *Client) callWithRetry(systemPrompt, userPrompt string) (string, error) {
+	if client.APIKey == "" && client.requiresAPIKey() {
 		return "", fmt.Errorf("AI API密钥未设置，请先调用 SetDeepSeekAPIKey() 或 SetQwenAPIKey()")
 	}
 
@@ -173,7 +384,7 @@ func (client *Client) CallWithMessages(systemPrompt, userPrompt string) (string,
 
 // StreamWithMessages 使用流式输出调用AI API
 func (client *Client) StreamWithMessages(systemPrompt, userPrompt string, onChunk func(string)) error {
-	if client.APIKey == "" {
+	if client.APIKey == "" && client.requiresAPIKey() {
 		return fmt.Errorf("AI API密钥未设置")
 	}
 
@@ -186,12 +397,11 @@ func (client *Client) StreamWithMessages(systemPrompt, userPrompt string, onChun
 
 	// 构建请求体
 	requestBody := map[string]interface{}{
-		"model":       client.Model,
-		"messages":    messages,
-		"temperature": 0.5,
-		"max_tokens":  client.MaxTokens,
-		"stream":      true,
+		"model":    client.Model,
+		"messages": messages,
+		"stream":   true,
 	}
+	client.applySampling(requestBody)
 
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
@@ -205,7 +415,7 @@ func (client *Client) StreamWithMessages(systemPrompt, userPrompt string, onChun
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", client.APIKey))
+	client.setAuthHeaders(req)
 
 	httpClient := &http.Client{Timeout: client.Timeout}
 	resp, err := httpClient.Do(req)
@@ -306,11 +516,10 @@ func (client *Client) callOnce(systemPrompt, userPrompt string) (string, error)
 
 	// 构建请求体
 	requestBody := map[string]interface{}{
-		"model":       client.Model,
-		"messages":    messages,
-		"temperature": 0.5, // 降低temperature以提高JSON格式稳定性
-		"max_tokens":  client.MaxTokens,
+		"model":    client.Model,
+		"messages": messages,
 	}
+	client.applySampling(requestBody)
 
 	// 注意：response_format 参数仅 OpenAI 支持，DeepSeek/Qwen 不支持
 	// 我们通过强化 prompt 和后处理来确保 JSON 格式正确
@@ -338,17 +547,8 @@ func (client *Client) callOnce(systemPrompt, userPrompt string) (string, error)
 
 	req.Header.Set("Content-Type", "application/json")
 
-	// 根据不同的Provider设置认证方式
-	switch client.Provider {
-	case ProviderDeepSeek:
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", client.APIKey))
-	case ProviderQwen:
-		// 阿里云Qwen使用API-Key认证
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", client.APIKey))
-		// 注意：如果使用的不是兼容模式，可能需要不同的认证方式
-	default:
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", client.APIKey))
-	}
+	// 根据不同的Provider设置认证方式（自定义API可配置认证方式与附加请求头）
+	client.setAuthHeaders(req)
 
 	// 发送请求
 	httpClient := &http.Client{Timeout: client.Timeout}
@@ -0,0 +1,230 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestServer 返回固定状态码与内容的 OpenAI 兼容接口
+func newTestServer(status int, content string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			w.Write([]byte(`{"error":"bad request"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"content": content}},
+			},
+		})
+	}))
+}
+
+func newTestClient(provider Provider, model, baseURL string) *Client {
+	return &Client{
+		Provider:  provider,
+		APIKey:    "test-key",
+		BaseURL:   baseURL,
+		Model:     model,
+		Timeout:   5 * time.Second,
+		MaxTokens: 100,
+	}
+}
+
+func TestCallWithMessagesServedPrimary(t *testing.T) {
+	primary := newTestServer(http.StatusOK, "primary-answer")
+	defer primary.Close()
+	fallback := newTestServer(http.StatusOK, "fallback-answer")
+	defer fallback.Close()
+
+	client := newTestClient(ProviderDeepSeek, "deepseek-chat", primary.URL)
+	client.SetFallback(newTestClient(ProviderQwen, "qwen3-max", fallback.URL))
+
+	resp, served, err := client.CallWithMessagesServed("sys", "user")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp != "primary-answer" {
+		t.Errorf("resp = %q, want primary-answer", resp)
+	}
+	if served.Provider != ProviderDeepSeek || served.Model != "deepseek-chat" || served.WasFallback {
+		t.Errorf("served = %+v, want deepseek/deepseek-chat without fallback", served)
+	}
+}
+
+func TestCallWithMessagesServedFallback(t *testing.T) {
+	// 400 不属于可重试错误，主模型立即失败并切换备用模型
+	primary := newTestServer(http.StatusBadRequest, "")
+	defer primary.Close()
+	fallback := newTestServer(http.StatusOK, "fallback-answer")
+	defer fallback.Close()
+
+	client := newTestClient(ProviderDeepSeek, "deepseek-chat", primary.URL)
+	client.SetFallback(newTestClient(ProviderQwen, "qwen3-max", fallback.URL))
+
+	resp, served, err := client.CallWithMessagesServed("sys", "user")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp != "fallback-answer" {
+		t.Errorf("resp = %q, want fallback-answer", resp)
+	}
+	if served.Provider != ProviderQwen || served.Model != "qwen3-max" || !served.WasFallback {
+		t.Errorf("served = %+v, want qwen/qwen3-max with fallback", served)
+	}
+
+	data, _ := json.Marshal(served)
+	if !strings.Contains(string(data), `"was_fallback":true`) {
+		t.Errorf("json = %s, want was_fallback:true", data)
+	}
+}
+
+func TestCallWithMessagesServedNoFallback(t *testing.T) {
+	primary := newTestServer(http.StatusBadRequest, "")
+	defer primary.Close()
+
+	client := newTestClient(ProviderDeepSeek, "deepseek-chat", primary.URL)
+	if _, _, err := client.CallWithMessagesServed("sys", "user"); err == nil {
+		t.Fatal("expected error when primary fails without fallback")
+	}
+}
+
+func TestCallWithMessagesSamplingParams(t *testing.T) {
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload = nil
+		json.NewDecoder(r.Body).Decode(&payload)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"content": "ok"}},
+			},
+		})
+	}))
+	defer server.Close()
+
+	client := newTestClient(ProviderDeepSeek, "deepseek-chat", server.URL)
+	if _, err := client.CallWithMessages("sys", "user"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if payload["temperature"] != DefaultTemperature {
+		t.Errorf("default temperature = %v, want %v", payload["temperature"], DefaultTemperature)
+	}
+	if _, ok := payload["top_p"]; ok {
+		t.Errorf("top_p should be omitted by default, got %v", payload["top_p"])
+	}
+
+	temperature, topP := 0.0, 0.9
+	client.SetSamplingParams(SamplingParams{Temperature: &temperature, TopP: &topP, MaxTokens: 2048})
+	if _, err := client.CallWithMessages("sys", "user"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if payload["temperature"] != 0.0 || payload["top_p"] != 0.9 || payload["max_tokens"] != 2048.0 {
+		t.Errorf("payload = %v, want temperature=0 top_p=0.9 max_tokens=2048", payload)
+	}
+}
+
+func TestValidateSamplingParams(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name     string
+		provider Provider
+		params   SamplingParams
+		wantErr  bool
+	}{
+		{"默认值", ProviderDeepSeek, SamplingParams{}, false},
+		{"温度为0", ProviderDeepSeek, SamplingParams{Temperature: f(0)}, false},
+		{"DeepSeek 温度上限2", ProviderDeepSeek, SamplingParams{Temperature: f(2)}, false},
+		{"Qwen 温度不能等于2", ProviderQwen, SamplingParams{Temperature: f(2)}, true},
+		{"温度为负数", ProviderCustom, SamplingParams{Temperature: f(-0.1)}, true},
+		{"top_p 为0", ProviderDeepSeek, SamplingParams{TopP: f(0)}, true},
+		{"top_p 超过1", ProviderDeepSeek, SamplingParams{TopP: f(1.1)}, true},
+		{"DeepSeek max_tokens 超上限", ProviderDeepSeek, SamplingParams{MaxTokens: 10000}, true},
+		{"自定义API不限制 max_tokens", ProviderCustom, SamplingParams{MaxTokens: 100000}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSamplingParams(tt.provider, tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSamplingParams() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCustomHeadersAttached(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"content": "ok"}},
+			},
+		})
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		provider   Provider
+		authScheme string
+		apiKey     string
+		want       map[string]string
+	}{
+		{"自定义API默认 Bearer 并附加请求头", ProviderCustom, "", "test-key",
+			map[string]string{"Authorization": "Bearer test-key", "X-Org-Id": "org-1"}},
+		{"自定义API使用 X-Api-Key", ProviderCustom, AuthSchemeXAPIKey, "test-key",
+			map[string]string{"Authorization": "", "X-Api-Key": "test-key", "X-Org-Id": "org-1"}},
+		{"自定义API不发送密钥", ProviderCustom, AuthSchemeNone, "",
+			map[string]string{"Authorization": "", "X-Org-Id": "org-1"}},
+		{"非自定义API忽略附加请求头", ProviderDeepSeek, AuthSchemeXAPIKey, "test-key",
+			map[string]string{"Authorization": "Bearer test-key", "X-Api-Key": "", "X-Org-Id": ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(tt.provider, "gateway-model", server.URL)
+			client.APIKey = tt.apiKey
+			client.SetCustomHeaders(map[string]string{"X-Org-Id": "org-1"}, tt.authScheme)
+			if _, err := client.CallWithMessages("sys", "user"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for name, want := range tt.want {
+				if value := got.Get(name); value != want {
+					t.Errorf("header %s = %q, want %q", name, value, want)
+				}
+			}
+			if got.Get("Content-Type") != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", got.Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestValidateCustomAuth(t *testing.T) {
+	tests := []struct {
+		name       string
+		authScheme string
+		headers    map[string]string
+		wantErr    bool
+	}{
+		{"默认认证方式", "", nil, false},
+		{"大小写不敏感", "X-API-KEY", map[string]string{"OpenAI-Organization": "org-1"}, false},
+		{"不支持的认证方式", "digest", nil, true},
+		{"请求头名称包含空格", "bearer", map[string]string{"X Api Key": "v"}, true},
+		{"请求头名称为空", "bearer", map[string]string{"": "v"}, true},
+		{"不能覆盖 Content-Type", "bearer", map[string]string{"content-type": "text/plain"}, true},
+		{"请求头值包含换行", "bearer", map[string]string{"X-Org-Id": "a\r\nX-Evil: 1"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCustomAuth(tt.authScheme, tt.headers)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCustomAuth() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
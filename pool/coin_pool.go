@@ -91,6 +91,11 @@ func SetDefaultCoins(coins []string) {
 	}
 }
 
+// GetDefaultCoins 获取默认主流币种列表（返回副本）
+func GetDefaultCoins() []string {
+	return append([]string(nil), defaultMainstreamCoins...)
+}
+
 // GetCoinPool 获取币种池列表（带重试和缓存机制）
 func GetCoinPool() ([]CoinInfo, error) {
 	// 优先检查是否启用默认币种列表
@@ -115,7 +120,7 @@ func GetCoinPool() ([]CoinInfo, error) {
 			time.Sleep(2 * time.Second) // 重试前等待2秒
 		}
 
-		coins, err := fetchCoinPool()
+		coins, err := fetchCoinPool(coinPoolConfig.APIURL)
 		if err == nil {
 			if attempt > 1 {
 				log.Printf("✓ 第%d次重试成功", attempt)
@@ -145,14 +150,14 @@ func GetCoinPool() ([]CoinInfo, error) {
 }
 
 // fetchCoinPool 实际执行币种池请求
-func fetchCoinPool() ([]CoinInfo, error) {
+func fetchCoinPool(apiURL string) ([]CoinInfo, error) {
 	log.Printf("🔄 正在请求AI500币种池...")
 
 	client := &http.Client{
 		Timeout: coinPoolConfig.Timeout,
 	}
 
-	resp, err := client.Get(coinPoolConfig.APIURL)
+	resp, err := client.Get(apiURL)
 	if err != nil {
 		return nil, fmt.Errorf("请求币种池API失败: %w", err)
 	}
@@ -436,7 +441,7 @@ func GetOITopPositions() ([]OIPosition, error) {
 			time.Sleep(2 * time.Second)
 		}
 
-		positions, err := fetchOITop()
+		positions, err := fetchOITop(oiTopConfig.APIURL)
 		if err == nil {
 			if attempt > 1 {
 				log.Printf("✓ 第%d次重试成功", attempt)
@@ -466,14 +471,14 @@ func GetOITopPositions() ([]OIPosition, error) {
 }
 
 // fetchOITop 实际执行OI Top请求
-func fetchOITop() ([]OIPosition, error) {
+func fetchOITop(apiURL string) ([]OIPosition, error) {
 	log.Printf("🔄 正在请求OI Top数据...")
 
 	client := &http.Client{
 		Timeout: oiTopConfig.Timeout,
 	}
 
-	resp, err := client.Get(oiTopConfig.APIURL)
+	resp, err := client.Get(apiURL)
 	if err != nil {
 		return nil, fmt.Errorf("请求OI Top API失败: %w", err)
 	}
@@ -585,10 +590,16 @@ type MergedCoinPool struct {
 	OITopCoins    []OIPosition        // 持仓量增长Top20
 	AllSymbols    []string            // 所有不重复的币种符号
 	SymbolSources map[string][]string // 每个币种的来源（"ai500"/"oi_top"）
+	SymbolScores  map[string]float64  // 多信号源加权得分（仅多信号源模式下填充，AllSymbols按此降序）
 }
 
 // GetMergedCoinPool 获取合并后的币种池（AI500 + OI Top，去重）
+// 配置了多信号源时按各源权重合并排序，否则使用单一 COIN POOL / OI Top 配置
 func GetMergedCoinPool(ai500Limit int) (*MergedCoinPool, error) {
+	if sources := enabledSignalSources(); len(sources) > 0 {
+		return mergeSignalSources(sources, ai500Limit), nil
+	}
+
 	// 1. 获取AI500数据
 	ai500TopSymbols, err := GetTopRatedCoins(ai500Limit)
 	if err != nil {
@@ -0,0 +1,161 @@
+package pool
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 信号源类型
+const (
+	SignalSourceTypeCoinPool = "coin_pool" // AI500 评分币种池
+	SignalSourceTypeOITop    = "oi_top"    // 持仓量增长 Top
+)
+
+// SignalSource 用户配置的命名信号源（可配置多个，按权重合并候选币种）
+type SignalSource struct {
+	Name    string
+	URL     string
+	Type    string  // SignalSourceTypeCoinPool / SignalSourceTypeOITop
+	Weight  float64 // 合并排序时的权重（<=0 按 1 处理）
+	Enabled bool
+}
+
+var (
+	signalSourcesMu sync.RWMutex
+	signalSources   []SignalSource
+)
+
+// SetSignalSources 设置多信号源列表，为空时回退到单一 COIN POOL / OI Top 配置
+func SetSignalSources(sources []SignalSource) {
+	signalSourcesMu.Lock()
+	defer signalSourcesMu.Unlock()
+	signalSources = append([]SignalSource(nil), sources...)
+}
+
+// enabledSignalSources 返回已启用且配置了URL的信号源
+func enabledSignalSources() []SignalSource {
+	signalSourcesMu.RLock()
+	defer signalSourcesMu.RUnlock()
+
+	var enabled []SignalSource
+	for _, src := range signalSources {
+		if src.Enabled && strings.TrimSpace(src.URL) != "" {
+			enabled = append(enabled, src)
+		}
+	}
+	return enabled
+}
+
+// sourceLabel 信号源在候选币种 Sources 中的标签（与单源模式保持一致："ai500"/"oi_top"）
+func sourceLabel(sourceType string) string {
+	if sourceType == SignalSourceTypeOITop {
+		return "oi_top"
+	}
+	return "ai500"
+}
+
+// mergeSignalSources 从多个信号源拉取币种并按权重合并排序
+// 每个源内按排名折算为 (n-i)/n 的得分再乘以权重，各源得分累加，
+// 使不同类型（评分 / 持仓量排名）的源可以在同一尺度上比较
+func mergeSignalSources(sources []SignalSource, ai500Limit int) *MergedCoinPool {
+	merged := &MergedCoinPool{
+		SymbolSources: make(map[string][]string),
+		SymbolScores:  make(map[string]float64),
+	}
+
+	for _, src := range sources {
+		var ranked []string
+		switch src.Type {
+		case SignalSourceTypeOITop:
+			positions, err := fetchOITop(src.URL)
+			if err != nil {
+				log.Printf("⚠️  信号源 %s 获取失败: %v", src.Name, err)
+				continue
+			}
+			merged.OITopCoins = append(merged.OITopCoins, positions...)
+			ranked = rankOITopSymbols(positions)
+		default:
+			coins, err := fetchCoinPool(src.URL)
+			if err != nil {
+				log.Printf("⚠️  信号源 %s 获取失败: %v", src.Name, err)
+				continue
+			}
+			merged.AI500Coins = append(merged.AI500Coins, coins...)
+			ranked = rankCoinPoolSymbols(coins, ai500Limit)
+		}
+
+		weight := src.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		label := sourceLabel(src.Type)
+		n := float64(len(ranked))
+		for i, symbol := range ranked {
+			merged.SymbolScores[symbol] += weight * (n - float64(i)) / n
+			if !containsString(merged.SymbolSources[symbol], label) {
+				merged.SymbolSources[symbol] = append(merged.SymbolSources[symbol], label)
+			}
+		}
+	}
+
+	for symbol := range merged.SymbolScores {
+		merged.AllSymbols = append(merged.AllSymbols, symbol)
+	}
+	sort.Slice(merged.AllSymbols, func(i, j int) bool {
+		si, sj := merged.SymbolScores[merged.AllSymbols[i]], merged.SymbolScores[merged.AllSymbols[j]]
+		if si != sj {
+			return si > sj
+		}
+		return merged.AllSymbols[i] < merged.AllSymbols[j]
+	})
+
+	log.Printf("📊 多信号源合并完成: 信号源=%d, 总计(去重)=%d", len(sources), len(merged.AllSymbols))
+	return merged
+}
+
+// rankCoinPoolSymbols 按评分降序取前 limit 个可用币种
+func rankCoinPoolSymbols(coins []CoinInfo, limit int) []string {
+	var available []CoinInfo
+	for _, coin := range coins {
+		if coin.IsAvailable {
+			available = append(available, coin)
+		}
+	}
+	sort.SliceStable(available, func(i, j int) bool {
+		return available[i].Score > available[j].Score
+	})
+	if limit > 0 && len(available) > limit {
+		available = available[:limit]
+	}
+
+	symbols := make([]string, 0, len(available))
+	for _, coin := range available {
+		symbols = append(symbols, normalizeSymbol(coin.Pair))
+	}
+	return symbols
+}
+
+// rankOITopSymbols 按持仓量排名升序返回币种
+func rankOITopSymbols(positions []OIPosition) []string {
+	sorted := append([]OIPosition(nil), positions...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Rank < sorted[j].Rank
+	})
+
+	symbols := make([]string, 0, len(sorted))
+	for _, pos := range sorted {
+		symbols = append(symbols, normalizeSymbol(pos.Symbol))
+	}
+	return symbols
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
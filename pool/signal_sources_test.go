@@ -0,0 +1,68 @@
+package pool
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// newCoinPoolServer 返回固定币种评分的 COIN POOL 接口
+func newCoinPoolServer(t *testing.T, coins []CoinInfo) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var resp CoinPoolAPIResponse
+		resp.Success = true
+		resp.Data.Coins = coins
+		resp.Data.Count = len(coins)
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGetMergedCoinPoolWeightsSignalSources(t *testing.T) {
+	sourceA := newCoinPoolServer(t, []CoinInfo{
+		{Pair: "AAAUSDT", Score: 90},
+		{Pair: "BBBUSDT", Score: 80},
+	})
+	sourceB := newCoinPoolServer(t, []CoinInfo{
+		{Pair: "BBBUSDT", Score: 90},
+		{Pair: "CCCUSDT", Score: 80},
+	})
+	t.Cleanup(func() { SetSignalSources(nil) })
+
+	cases := []struct {
+		name     string
+		weightA  float64
+		weightB  float64
+		expected []string
+	}{
+		// A: AAA=1, BBB=0.5；B: BBB=1, CCC=0.5
+		{"等权重", 1, 1, []string{"BBBUSDT", "AAAUSDT", "CCCUSDT"}},
+		{"B源权重更高", 1, 3, []string{"BBBUSDT", "CCCUSDT", "AAAUSDT"}},
+		{"A源权重更高", 4, 1, []string{"AAAUSDT", "BBBUSDT", "CCCUSDT"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			SetSignalSources([]SignalSource{
+				{Name: "a", URL: sourceA.URL, Type: SignalSourceTypeCoinPool, Weight: tc.weightA, Enabled: true},
+				{Name: "b", URL: sourceB.URL, Type: SignalSourceTypeCoinPool, Weight: tc.weightB, Enabled: true},
+				{Name: "disabled", URL: "http://127.0.0.1:0", Type: SignalSourceTypeOITop, Weight: 100, Enabled: false},
+			})
+
+			merged, err := GetMergedCoinPool(10)
+			if err != nil {
+				t.Fatalf("GetMergedCoinPool 返回错误: %v", err)
+			}
+			if !reflect.DeepEqual(merged.AllSymbols, tc.expected) {
+				t.Fatalf("排序不符合权重: got %v, want %v (scores=%v)", merged.AllSymbols, tc.expected, merged.SymbolScores)
+			}
+			if got := merged.SymbolSources["BBBUSDT"]; !reflect.DeepEqual(got, []string{"ai500"}) {
+				t.Errorf("来源标签应去重为 [ai500]，got %v", got)
+			}
+		})
+	}
+}
@@ -41,11 +41,12 @@ type Monitor struct {
 
 // Email 封装策略邮件的关键信息（正文 + 元数据）
 type Email struct {
-	Body      string
-	Subject   string
-	From      string
-	Date      time.Time
-	MessageID string
+	Body        string
+	Subject     string
+	From        string
+	FromAddress string // 发件人邮箱（作为信号来源标识）
+	Date        time.Time
+	MessageID   string
 }
 
 // NewMonitor 创建新的监听器
@@ -383,11 +384,12 @@ func (m *Monitor) CheckEmails() error {
 				fromName = envelope.From[0].PersonalName
 			}
 			email := &Email{
-				Body:      body,
-				Subject:   envelope.Subject,
-				From:      fromName,
-				Date:      receivedAt,
-				MessageID: messageID,
+				Body:        body,
+				Subject:     envelope.Subject,
+				From:        fromName,
+				FromAddress: fromEmail,
+				Date:        receivedAt,
+				MessageID:   messageID,
 			}
 			m.SignalChan <- email
 
@@ -21,7 +21,7 @@ type StrategyManager struct {
 
 	strategies map[string]*StrategySnapshot
 
-	listeners []StrategyListener
+	listeners []strategySubscription
 
 	notifySuppressUntil time.Time
 	maxActiveAge        time.Duration
@@ -114,7 +114,7 @@ func InitGlobalManager(mcpClient *mcp.Client) error {
 
 	GlobalManager = &StrategyManager{
 		strategies:   make(map[string]*StrategySnapshot),
-		listeners:    make([]StrategyListener, 0),
+		listeners:    make([]strategySubscription, 0),
 		maxActiveAge:  24 * time.Hour,
 		maxAutoExecuteAge: 12 * time.Hour,
 		gmailMonitor: monitor,
@@ -230,17 +230,18 @@ func (sm *StrategyManager) restoreLatestStrategiesFromDB(limit int) {
 			d.RawContent = ps.RawContent
 		}
 
-		symbol := strings.ToUpper(d.Symbol)
-		d.Symbol = symbol
+		d.Symbol = strings.ToUpper(d.Symbol)
+		d.Source = NormalizeSource(d.Source)
+		key := strategyKey(&d)
 
-		if bySymbol[symbol] == nil {
+		if bySymbol[key] == nil {
 			dd := d
-			bySymbol[symbol] = &found{latest: &dd, latestTime: receivedAt}
+			bySymbol[key] = &found{latest: &dd, latestTime: receivedAt}
 			continue
 		}
-		if bySymbol[symbol].prev == nil {
+		if bySymbol[key].prev == nil {
 			dd := d
-			bySymbol[symbol].prev = &dd
+			bySymbol[key].prev = &dd
 		}
 	}
 
@@ -308,7 +309,7 @@ func (sm *StrategyManager) notifyAllLatest(reason string) {
 			strategies = append(strategies, s)
 		}
 	}
-	listenersCopy := append([]StrategyListener(nil), sm.listeners...)
+	listenersCopy := append([]strategySubscription(nil), sm.listeners...)
 	sm.mu.RUnlock()
 
 	if len(strategies) == 0 || len(listenersCopy) == 0 {
@@ -322,8 +323,8 @@ func (sm *StrategyManager) notifyAllLatest(reason string) {
 		if sm.isExpired(snap.Time) || !sm.shouldAutoExecute(snap.Time) {
 			continue
 		}
-		for _, l := range listenersCopy {
-			if l == nil {
+		for _, sub := range listenersCopy {
+			if sub.listener == nil || !sub.wants(snap.Strategy) {
 				continue
 			}
 			go func(fn StrategyListener, s *StrategySnapshot) {
@@ -333,7 +334,7 @@ func (sm *StrategyManager) notifyAllLatest(reason string) {
 					}
 				}()
 				fn(s.Strategy, s.PrevStrategy)
-			}(l, snap)
+			}(sub.listener, snap)
 		}
 	}
 
@@ -361,6 +362,8 @@ func (sm *StrategyManager) loop() {
 				if e.MessageID != "" {
 					decision.SignalID = e.MessageID
 				}
+				// 发件人邮箱作为信号来源，用于按来源路由到订阅的交易员
+				decision.Source = e.FromAddress
 
 				// 更新策略（使用邮件原始时间作为策略时间轴的基准）
 				sm.UpdateStrategy(decision, e.Date)
@@ -372,21 +375,9 @@ func (sm *StrategyManager) loop() {
 	}
 }
 
-// RegisterListener 注册策略更新监听器
+// RegisterListener 注册策略更新监听器（接收所有来源的策略）
 func (sm *StrategyManager) RegisterListener(listener StrategyListener) {
-	if listener == nil {
-		return
-	}
-
-	sm.mu.Lock()
-	sm.listeners = append(sm.listeners, listener)
-	suppressUntil := sm.notifySuppressUntil
-	sm.mu.Unlock()
-
-	// 若 warmup 已结束，为新注册的监听器补发一次“最新策略”
-	if suppressUntil.IsZero() || time.Now().After(suppressUntil) {
-		go sm.notifyAllLatest("listener_registered")
-	}
+	sm.RegisterSourceListener(nil, listener)
 }
 
 func (sm *StrategyManager) UpdateStrategy(newStrat *SignalDecision, receivedAt time.Time) {
@@ -408,10 +399,11 @@ func (sm *StrategyManager) UpdateStrategy(newStrat *SignalDecision, receivedAt t
 			newStrat.Symbol, newStrat.Direction, receivedAt.Unix())
 	}
 
-	// 关键：内存中的 active 策略池按「交易对」维度去重
-	// - map 的 key 使用 symbol，保证同一交易对始终只有一条最新策略
+	// 关键：内存中的 active 策略池按「来源 + 交易对」维度去重
+	// - map 的 key 使用 strategyKey，保证同一来源的同一交易对始终只有一条最新策略
 	// - PrevStrategy 用于记录上一次策略版本，便于 AI 对比前后差异
-	key := newStrat.Symbol
+	newStrat.Source = NormalizeSource(newStrat.Source)
+	key := strategyKey(newStrat)
 
 	var prev *SignalDecision
 	if existing, ok := sm.strategies[key]; ok && existing != nil && existing.Strategy != nil {
@@ -464,9 +456,9 @@ func (sm *StrategyManager) UpdateStrategy(newStrat *SignalDecision, receivedAt t
 		Time:         receivedAt,
 	}
 
-	var listenersCopy []StrategyListener
+	var listenersCopy []strategySubscription
 	if len(sm.listeners) > 0 {
-		listenersCopy = append([]StrategyListener(nil), sm.listeners...)
+		listenersCopy = append([]strategySubscription(nil), sm.listeners...)
 	}
 
 	sm.mu.Unlock()
@@ -489,8 +481,8 @@ func (sm *StrategyManager) UpdateStrategy(newStrat *SignalDecision, receivedAt t
 
 	// 3. 通知所有监听器（仅当满足 warmup/新鲜度要求）
 	if sm.shouldAutoExecute(receivedAt) {
-		for _, l := range listenersCopy {
-			if l == nil {
+		for _, sub := range listenersCopy {
+			if sub.listener == nil || !sub.wants(newStrat) {
 				continue
 			}
 			go func(fn StrategyListener) {
@@ -500,7 +492,7 @@ func (sm *StrategyManager) UpdateStrategy(newStrat *SignalDecision, receivedAt t
 					}
 				}()
 				fn(newStrat, prev)
-			}(l)
+			}(sub.listener)
 		}
 	} else {
 		log.Printf("ℹ️ Skipped notifying listeners (warmup/stale) for strategy id=%s symbol=%s", newStrat.SignalID, newStrat.Symbol)
@@ -106,6 +106,17 @@ func (p *Parser) Parse(emailContent string) (*SignalDecision, error) {
 		return nil, fmt.Errorf("解析结果无效: 缺失止盈或止损设置")
 	}
 
+	// 信号自带的补仓比例合计已超过100%的计划无法完整执行，直接拒绝（底仓比例由各交易员在接收时再校验）
+	addTotal := 0.0
+	for _, a := range decision.Adds {
+		if a.Percent > 0 {
+			addTotal += a.Percent
+		}
+	}
+	if addTotal > 1.0 {
+		return nil, fmt.Errorf("解析结果无效: 补仓比例合计 %.0f%% 超过100%%", addTotal*100)
+	}
+
 	// 保存原始邮件内容用于前端展示
 	decision.RawContent = emailContent
 
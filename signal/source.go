@@ -0,0 +1,100 @@
+package signal
+
+import (
+	"strings"
+	"time"
+)
+
+// SourceFilter 判断交易员是否订阅了指定信号来源（来源为空表示未知来源）
+type SourceFilter func(source string) bool
+
+// strategySubscription 带来源过滤的策略监听器（accepts 为 nil 表示接收所有来源）
+type strategySubscription struct {
+	accepts  SourceFilter
+	listener StrategyListener
+}
+
+func (s strategySubscription) wants(strat *SignalDecision) bool {
+	return s.accepts == nil || strat == nil || s.accepts(strat.Source)
+}
+
+// NormalizeSource 规范化信号来源标识（发件人邮箱，忽略大小写和首尾空白）
+func NormalizeSource(source string) string {
+	return strings.ToLower(strings.TrimSpace(source))
+}
+
+// ParseSources 解析逗号分隔的信号来源订阅列表（发件人邮箱或 @域名），未配置时返回空列表
+func ParseSources(raw string) []string {
+	sources := []string{}
+	for _, source := range strings.Split(raw, ",") {
+		if source = NormalizeSource(source); source != "" {
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
+
+// ValidSource 订阅项格式是否合法：完整邮箱（user@domain）或域名（@domain）
+func ValidSource(source string) bool {
+	at := strings.LastIndex(source, "@")
+	return at >= 0 && at < len(source)-1 && !strings.ContainsAny(source, " ,|")
+}
+
+// MatchSource 信号来源是否在订阅列表中：订阅项为完整邮箱时精确匹配，以 @ 开头时按域名匹配。
+// 未配置订阅列表时接收所有来源（兼容旧配置）；配置了订阅列表时不接收来源未知的策略
+func MatchSource(subscribed []string, source string) bool {
+	if len(subscribed) == 0 {
+		return true
+	}
+	source = NormalizeSource(source)
+	if source == "" {
+		return false
+	}
+	for _, want := range subscribed {
+		want = NormalizeSource(want)
+		if want == source || (strings.HasPrefix(want, "@") && strings.HasSuffix(source, want)) {
+			return true
+		}
+	}
+	return false
+}
+
+// strategyKey 活跃策略池的去重键：同一来源的同一交易对只保留最新策略，不同来源互不覆盖
+func strategyKey(strat *SignalDecision) string {
+	if strat.Source == "" {
+		return strat.Symbol
+	}
+	return strat.Source + "|" + strat.Symbol
+}
+
+// RegisterSourceListener 注册只接收指定来源策略的监听器（accepts 为 nil 时等同 RegisterListener）
+func (sm *StrategyManager) RegisterSourceListener(accepts SourceFilter, listener StrategyListener) {
+	if listener == nil {
+		return
+	}
+
+	sm.mu.Lock()
+	sm.listeners = append(sm.listeners, strategySubscription{accepts: accepts, listener: listener})
+	suppressUntil := sm.notifySuppressUntil
+	sm.mu.Unlock()
+
+	// 若 warmup 已结束，为新注册的监听器补发一次“最新策略”
+	if suppressUntil.IsZero() || time.Now().After(suppressUntil) {
+		go sm.notifyAllLatest("listener_registered")
+	}
+}
+
+// ListActiveStrategiesFor 返回指定来源的活跃策略快照（顺序同 ListActiveStrategies）
+func (sm *StrategyManager) ListActiveStrategiesFor(accepts SourceFilter) []*StrategySnapshot {
+	all := sm.ListActiveStrategies()
+	if accepts == nil {
+		return all
+	}
+	result := make([]*StrategySnapshot, 0, len(all))
+	for _, snap := range all {
+		if accepts(snap.Strategy.Source) {
+			result = append(result, snap)
+		}
+	}
+	return result
+}
@@ -0,0 +1,78 @@
+package signal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMatchSource(t *testing.T) {
+	tests := []struct {
+		name       string
+		subscribed []string
+		source     string
+		want       bool
+	}{
+		{"未配置订阅时接收所有来源", nil, "alpha@signals.io", true},
+		{"未配置订阅时接收未知来源", nil, "", true},
+		{"邮箱精确匹配_忽略大小写", []string{"alpha@signals.io"}, "Alpha@Signals.io", true},
+		{"其他邮箱不匹配", []string{"alpha@signals.io"}, "beta@signals.io", false},
+		{"域名匹配", []string{"@signals.io"}, "beta@signals.io", true},
+		{"域名不匹配相似后缀", []string{"@signals.io"}, "beta@fakesignals.io", false},
+		{"配置订阅后不接收未知来源", []string{"alpha@signals.io"}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchSource(tt.subscribed, tt.source); got != tt.want {
+				t.Errorf("MatchSource(%v, %q) = %v, want %v", tt.subscribed, tt.source, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestStrategyRoutingBySource 来源A的策略只触发订阅了A的交易员，不同来源的同币种策略互不覆盖
+func TestStrategyRoutingBySource(t *testing.T) {
+	sm := &StrategyManager{
+		strategies:        make(map[string]*StrategySnapshot),
+		maxActiveAge:      24 * time.Hour,
+		maxAutoExecuteAge: 12 * time.Hour,
+	}
+	subscribe := func(sources ...string) <-chan *SignalDecision {
+		received := make(chan *SignalDecision, 4)
+		sm.RegisterSourceListener(func(source string) bool {
+			return MatchSource(sources, source)
+		}, func(newStrat, prev *SignalDecision) {
+			received <- newStrat
+		})
+		return received
+	}
+	traderA := subscribe("alpha@signals.io")
+	traderB := subscribe("beta@signals.io")
+	traderAll := subscribe()
+
+	sm.UpdateStrategy(&SignalDecision{SignalID: "a-1", Symbol: "BTCUSDT", Direction: "LONG", Source: "Alpha@Signals.io"}, time.Now())
+
+	for name, ch := range map[string]<-chan *SignalDecision{"A": traderA, "all": traderAll} {
+		select {
+		case got := <-ch:
+			if got.SignalID != "a-1" {
+				t.Errorf("交易员 %s 收到了错误的策略: %s", name, got.SignalID)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("订阅来源A的交易员 %s 未收到策略", name)
+		}
+	}
+	select {
+	case got := <-traderB:
+		t.Fatalf("只订阅来源B的交易员收到了来源A的策略: %s", got.SignalID)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	sm.UpdateStrategy(&SignalDecision{SignalID: "b-1", Symbol: "BTCUSDT", Direction: "SHORT", Source: "beta@signals.io"}, time.Now())
+	if snaps := sm.ListActiveStrategies(); len(snaps) != 2 {
+		t.Fatalf("不同来源的同币种策略应同时保留, got %d", len(snaps))
+	}
+	snaps := sm.ListActiveStrategiesFor(func(source string) bool { return MatchSource([]string{"alpha@signals.io"}, source) })
+	if len(snaps) != 1 || snaps[0].Strategy.SignalID != "a-1" {
+		t.Fatalf("来源A的活跃策略应只有 a-1, got %v", snaps)
+	}
+}
@@ -13,6 +13,7 @@ type SignalDecision struct {
 	Hedge             *HedgeStrategy  `json:"hedge,omitempty"`
 	RawTextSummary    string          `json:"raw_text_summary"`
 	RawContent        string          `json:"raw_content"` // 保存原始邮件全文用于展示
+	Source            string          `json:"source,omitempty"` // 信号来源（发件人邮箱，小写），用于按来源路由到订阅的交易员
 }
 
 type EntryStrategy struct {
@@ -0,0 +1,276 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultOTLPEndpoint    = "http://localhost:4318"
+	defaultServiceName     = "nofx"
+	otlpBatchSize          = 256
+	otlpQueueSize          = 2048
+	otlpFlushInterval      = 5 * time.Second
+	otlpInstrumentationLib = "nofx/trader"
+)
+
+// OTLPConfig OTLP/HTTP 导出配置
+type OTLPConfig struct {
+	Endpoint    string            // 完整的 traces 接收地址，如 http://collector:4318/v1/traces
+	ServiceName string            // resource 属性 service.name
+	Headers     map[string]string // 额外请求头（如鉴权）
+}
+
+// ConfigFromEnv 从环境变量读取 OTLP 导出配置，未开启时返回 nil：
+//   - NOFX_TRACING_ENABLED=true 开启（默认关闭）
+//   - OTEL_EXPORTER_OTLP_TRACES_ENDPOINT 完整地址，或 OTEL_EXPORTER_OTLP_ENDPOINT 基础地址（自动追加 /v1/traces），默认 http://localhost:4318
+//   - OTEL_SERVICE_NAME 服务名，默认 nofx
+//   - OTEL_EXPORTER_OTLP_HEADERS 额外请求头，格式 key1=value1,key2=value2
+func ConfigFromEnv() *OTLPConfig {
+	enabled, _ := strconv.ParseBool(strings.TrimSpace(os.Getenv("NOFX_TRACING_ENABLED")))
+	if !enabled {
+		return nil
+	}
+	cfg := &OTLPConfig{
+		Endpoint:    strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")),
+		ServiceName: strings.TrimSpace(os.Getenv("OTEL_SERVICE_NAME")),
+		Headers:     map[string]string{},
+	}
+	if cfg.Endpoint == "" {
+		base := strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"))
+		if base == "" {
+			base = defaultOTLPEndpoint
+		}
+		cfg.Endpoint = strings.TrimRight(base, "/") + "/v1/traces"
+	}
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = defaultServiceName
+	}
+	for _, pair := range strings.Split(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), ",") {
+		if key, value, ok := strings.Cut(pair, "="); ok && strings.TrimSpace(key) != "" {
+			cfg.Headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return cfg
+}
+
+// OTLPExporter 以 OTLP/HTTP JSON 格式批量异步导出 span（不阻塞交易流程，队列满时丢弃）
+type OTLPExporter struct {
+	cfg      OTLPConfig
+	client   *http.Client
+	spanChan chan SpanData
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	once     sync.Once
+}
+
+// NewOTLPExporter 创建 OTLP 导出器并启动后台批量发送协程
+func NewOTLPExporter(cfg OTLPConfig) *OTLPExporter {
+	e := &OTLPExporter{
+		cfg:      cfg,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		spanChan: make(chan SpanData, otlpQueueSize),
+		stopChan: make(chan struct{}),
+	}
+	e.wg.Add(1)
+	go e.run()
+	return e
+}
+
+// ExportSpan 实现 Exporter 接口（非阻塞）
+func (e *OTLPExporter) ExportSpan(span SpanData) {
+	select {
+	case e.spanChan <- span:
+	default:
+		log.Printf("⚠️ [Tracing] span 队列已满，丢弃 %s", span.Name)
+	}
+}
+
+// Shutdown 发送队列中剩余的 span 后停止
+func (e *OTLPExporter) Shutdown() error {
+	e.once.Do(func() { close(e.stopChan) })
+	e.wg.Wait()
+	return nil
+}
+
+func (e *OTLPExporter) run() {
+	defer e.wg.Done()
+	ticker := time.NewTicker(otlpFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, otlpBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.send(batch); err != nil {
+			log.Printf("⚠️ [Tracing] 导出 %d 个 span 失败: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case span := <-e.spanChan:
+			batch = append(batch, span)
+			if len(batch) >= otlpBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-e.stopChan:
+			for {
+				select {
+				case span := <-e.spanChan:
+					batch = append(batch, span)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (e *OTLPExporter) send(spans []SpanData) error {
+	body, err := json.Marshal(encodeOTLP(e.cfg.ServiceName, spans))
+	if err != nil {
+		return fmt.Errorf("序列化失败: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, e.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.cfg.Headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("collector 返回 HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// OTLP/HTTP JSON 请求体（ExportTraceServiceRequest 的 JSON 映射，只包含用到的字段）
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"` // 1=OK 2=ERROR
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"` // OTLP JSON 中 int64 以字符串表示
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+// encodeOTLP 将 span 编码为 OTLP/HTTP JSON 请求体（span kind 均为 INTERNAL）
+func encodeOTLP(serviceName string, spans []SpanData) otlpRequest {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		status := otlpStatus{Code: 1}
+		if span.Err != "" {
+			status = otlpStatus{Code: 2, Message: span.Err}
+		}
+		encoded = append(encoded, otlpSpan{
+			TraceID:           span.TraceID,
+			SpanID:            span.SpanID,
+			ParentSpanID:      span.ParentSpanID,
+			Name:              span.Name,
+			Kind:              1,
+			StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+			Attributes:        encodeAttributes(span.Attributes),
+			Status:            status,
+		})
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: encodeAttributes(map[string]interface{}{"service.name": serviceName})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: otlpInstrumentationLib}, Spans: encoded}},
+	}}}
+}
+
+// encodeAttributes 编码属性（按 key 排序，输出稳定），不支持的类型按字符串输出
+func encodeAttributes(attrs map[string]interface{}) []otlpKeyValue {
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]otlpKeyValue, 0, len(attrs))
+	for _, key := range keys {
+		var value otlpAnyValue
+		switch v := attrs[key].(type) {
+		case string:
+			value.StringValue = &v
+		case bool:
+			value.BoolValue = &v
+		case int:
+			s := strconv.Itoa(v)
+			value.IntValue = &s
+		case int64:
+			s := strconv.FormatInt(v, 10)
+			value.IntValue = &s
+		case float64:
+			value.DoubleValue = &v
+		default:
+			s := fmt.Sprint(v)
+			value.StringValue = &s
+		}
+		result = append(result, otlpKeyValue{Key: key, Value: value})
+	}
+	return result
+}
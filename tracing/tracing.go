@@ -0,0 +1,200 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// 决策周期链路追踪：每个AI决策周期一条 trace，构建上下文、AI调用、逐个动作执行分别为子 span。
+// 未设置导出器时 StartSpan 返回 nil，*Span 的所有方法对 nil 安全，不产生任何开销
+
+// SpanData 已结束的 span（导出器接收的只读数据）
+type SpanData struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string // 根 span 为空
+	Name         string
+	Start        time.Time
+	End          time.Time
+	Attributes   map[string]interface{} // 值类型：string / bool / int / int64 / float64
+	Err          string                 // 非空表示 span 以错误结束
+}
+
+// Duration span 耗时
+func (d SpanData) Duration() time.Duration {
+	return d.End.Sub(d.Start)
+}
+
+// Exporter span 导出器（OTLP 导出器、测试用内存导出器）
+type Exporter interface {
+	ExportSpan(span SpanData)
+	Shutdown() error
+}
+
+var (
+	exporterMu     sync.RWMutex
+	globalExporter Exporter
+)
+
+// SetExporter 设置全局导出器（nil 表示关闭链路追踪），返回之前的导出器
+func SetExporter(exporter Exporter) Exporter {
+	exporterMu.Lock()
+	defer exporterMu.Unlock()
+	previous := globalExporter
+	globalExporter = exporter
+	return previous
+}
+
+// Enabled 是否已开启链路追踪
+func Enabled() bool {
+	return currentExporter() != nil
+}
+
+func currentExporter() Exporter {
+	exporterMu.RLock()
+	defer exporterMu.RUnlock()
+	return globalExporter
+}
+
+// Span 进行中的 span
+type Span struct {
+	mu       sync.Mutex
+	data     SpanData
+	exporter Exporter
+	ended    bool
+}
+
+// StartSpan 开始一条新 trace 的根 span，未开启链路追踪时返回 nil
+func StartSpan(name string, attrs map[string]interface{}) *Span {
+	exporter := currentExporter()
+	if exporter == nil {
+		return nil
+	}
+	return newSpan(exporter, randomID(16), "", name, attrs)
+}
+
+// StartChild 开始子 span（父 span 为 nil 时返回 nil）
+func (s *Span) StartChild(name string, attrs map[string]interface{}) *Span {
+	if s == nil {
+		return nil
+	}
+	return newSpan(s.exporter, s.data.TraceID, s.data.SpanID, name, attrs)
+}
+
+func newSpan(exporter Exporter, traceID, parentSpanID, name string, attrs map[string]interface{}) *Span {
+	span := &Span{
+		exporter: exporter,
+		data: SpanData{
+			TraceID:      traceID,
+			SpanID:       randomID(8),
+			ParentSpanID: parentSpanID,
+			Name:         name,
+			Start:        time.Now(),
+			Attributes:   make(map[string]interface{}, len(attrs)+2),
+		},
+	}
+	for k, v := range attrs {
+		span.data.Attributes[k] = v
+	}
+	return span
+}
+
+// SetAttribute 设置属性
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Attributes[key] = value
+}
+
+// SetError 标记 span 以错误结束（err 为 nil 时忽略）
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Err = err.Error()
+}
+
+// End 结束 span 并交给导出器：自动记录 latency_ms，未设置 result 时按是否出错填写 ok/error。重复调用只导出一次
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	s.data.Attributes["latency_ms"] = s.data.Duration().Milliseconds()
+	if _, ok := s.data.Attributes["result"]; !ok {
+		if s.data.Err != "" {
+			s.data.Attributes["result"] = "error"
+		} else {
+			s.data.Attributes["result"] = "ok"
+		}
+	}
+	data := s.data
+	s.mu.Unlock()
+	s.exporter.ExportSpan(data)
+}
+
+// randomID 生成 n 字节的随机ID（十六进制），与 OTLP 的 trace_id(16字节)/span_id(8字节) 长度一致
+func randomID(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		// 极少发生：退化为基于时间的ID，保证非全零
+		ts := time.Now().UnixNano()
+		for i := range b {
+			b[i] = byte(ts >> (8 * (i % 8)))
+		}
+		b[0] |= 1
+	}
+	return hex.EncodeToString(b)
+}
+
+// InMemoryExporter 将 span 保存在内存中（测试用）
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+// NewInMemoryExporter 创建内存导出器
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+// ExportSpan 实现 Exporter 接口
+func (e *InMemoryExporter) ExportSpan(span SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+}
+
+// Shutdown 实现 Exporter 接口
+func (e *InMemoryExporter) Shutdown() error {
+	return nil
+}
+
+// Spans 已导出的 span（按结束顺序）
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	spans := make([]SpanData, len(e.spans))
+	copy(spans, e.spans)
+	return spans
+}
+
+// Reset 清空已导出的 span
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
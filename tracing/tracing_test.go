@@ -0,0 +1,128 @@
+package tracing
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSpanHierarchy(t *testing.T) {
+	t.Run("未设置导出器时不记录", func(t *testing.T) {
+		SetExporter(nil)
+		span := StartSpan("decision_cycle", nil)
+		if span != nil {
+			t.Fatalf("StartSpan() = %v, want nil", span)
+		}
+		// nil span 的方法不应 panic
+		child := span.StartChild("ai_call", nil)
+		child.SetAttribute("k", "v")
+		child.SetError(errors.New("boom"))
+		child.End()
+	})
+
+	t.Run("子span继承trace并指向父span", func(t *testing.T) {
+		exporter := NewInMemoryExporter()
+		SetExporter(exporter)
+		defer SetExporter(nil)
+
+		root := StartSpan("decision_cycle", map[string]interface{}{"trader_id": "t1"})
+		child := root.StartChild("execute_action", nil)
+		child.SetError(errors.New("下单失败"))
+		child.End()
+		child.End() // 重复结束只导出一次
+		root.End()
+
+		spans := exporter.Spans()
+		if len(spans) != 2 {
+			t.Fatalf("len(spans) = %d, want 2", len(spans))
+		}
+		got, parent := spans[0], spans[1]
+		if got.TraceID != parent.TraceID || got.ParentSpanID != parent.SpanID || parent.ParentSpanID != "" {
+			t.Errorf("child = %+v, root = %+v, want child under root", got, parent)
+		}
+		if len(parent.TraceID) != 32 || len(parent.SpanID) != 16 {
+			t.Errorf("trace id %q / span id %q have wrong length", parent.TraceID, parent.SpanID)
+		}
+		if got.Attributes["result"] != "error" || got.Err != "下单失败" {
+			t.Errorf("child result = %v err = %q, want error", got.Attributes["result"], got.Err)
+		}
+		if parent.Attributes["result"] != "ok" || parent.Attributes["trader_id"] != "t1" {
+			t.Errorf("root attributes = %v", parent.Attributes)
+		}
+		if _, ok := parent.Attributes["latency_ms"].(int64); !ok {
+			t.Errorf("root latency_ms = %v, want int64", parent.Attributes["latency_ms"])
+		}
+	})
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var received otlpRequest
+	var contentType, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("path = %s, want /v1/traces", r.URL.Path)
+		}
+		contentType, auth = r.Header.Get("Content-Type"), r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Errorf("invalid OTLP JSON: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	t.Setenv("NOFX_TRACING_ENABLED", "true")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", server.URL+"/")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "Authorization=Bearer token")
+	cfg := ConfigFromEnv()
+	if cfg == nil || cfg.Endpoint != server.URL+"/v1/traces" || cfg.ServiceName != "nofx" {
+		t.Fatalf("ConfigFromEnv() = %+v", cfg)
+	}
+
+	exporter := NewOTLPExporter(*cfg)
+	SetExporter(exporter)
+	root := StartSpan("decision_cycle", map[string]interface{}{"trader_id": "t1", "cycle": 3})
+	child := root.StartChild("ai_call", map[string]interface{}{"was_fallback": false, "quantity": 0.5})
+	child.End()
+	root.End()
+	SetExporter(nil)
+	exporter.Shutdown()
+
+	if contentType != "application/json" || auth != "Bearer token" {
+		t.Errorf("headers: Content-Type = %q, Authorization = %q", contentType, auth)
+	}
+	if len(received.ResourceSpans) != 1 || len(received.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("received = %+v, want one resource/scope", received)
+	}
+	if name := received.ResourceSpans[0].Resource.Attributes[0]; name.Key != "service.name" || *name.Value.StringValue != "nofx" {
+		t.Errorf("resource attribute = %+v, want service.name=nofx", name)
+	}
+	spans := received.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 || spans[0].Name != "ai_call" || spans[0].ParentSpanID != spans[1].SpanID {
+		t.Fatalf("spans = %+v, want ai_call under decision_cycle", spans)
+	}
+	attrs := map[string]otlpAnyValue{}
+	for _, kv := range spans[1].Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	if v := attrs["cycle"].IntValue; v == nil || *v != "3" {
+		t.Errorf("cycle = %+v, want intValue 3", attrs["cycle"])
+	}
+	if v := attrs["trader_id"].StringValue; v == nil || *v != "t1" {
+		t.Errorf("trader_id = %+v, want t1", attrs["trader_id"])
+	}
+	if spans[1].Status.Code != 1 {
+		t.Errorf("status = %+v, want OK", spans[1].Status)
+	}
+}
+
+func TestConfigFromEnvDisabledByDefault(t *testing.T) {
+	t.Setenv("NOFX_TRACING_ENABLED", "")
+	if cfg := ConfigFromEnv(); cfg != nil {
+		t.Errorf("ConfigFromEnv() = %+v, want nil when not enabled", cfg)
+	}
+}
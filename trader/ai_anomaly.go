@@ -0,0 +1,197 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"nofx/decision"
+	"nofx/logger"
+)
+
+// AI输出异常类型
+const (
+	AIAnomalyIdle   = "idle"   // 连续 N 个周期有候选币种却没有任何开仓动作（例如一直 wait）
+	AIAnomalyRepeat = "repeat" // 连续 N 个周期输出完全相同的非观望决策（例如每个周期都 open_long 同一币种）
+)
+
+// maxAIAnomalyCycles 异常检测阈值（周期数）上限，同时决定滚动窗口的最大长度
+const maxAIAnomalyCycles = 1000
+
+// ValidateAIAnomalyConfig 校验AI输出异常检测阈值（0 表示关闭对应检测）
+func ValidateAIAnomalyConfig(idleCycles, repeatCycles int) error {
+	if idleCycles < 0 || idleCycles > maxAIAnomalyCycles {
+		return fmt.Errorf("ai_anomaly_idle_cycles 必须在 0 到 %d 之间", maxAIAnomalyCycles)
+	}
+	if repeatCycles < 0 || repeatCycles > maxAIAnomalyCycles {
+		return fmt.Errorf("ai_anomaly_repeat_cycles 必须在 0 到 %d 之间", maxAIAnomalyCycles)
+	}
+	if repeatCycles == 1 {
+		return fmt.Errorf("ai_anomaly_repeat_cycles 至少为 2（单个周期无法判断重复）")
+	}
+	return nil
+}
+
+// aiCycleSummary 一个AI决策周期的摘要（只保留异常检测需要的信息）
+type aiCycleSummary struct {
+	signature  string // 非观望决策的签名（symbol:action 排序后拼接），全部观望时为空
+	opened     bool   // 是否包含开仓类动作
+	candidates int    // 本周期候选币种数量
+}
+
+// aiAnomalyState AI输出异常检测状态（内存中，受 mu 保护，重启后重新统计）
+type aiAnomalyState struct {
+	window []aiCycleSummary // 最近周期的摘要（滚动窗口，长度不超过较大的阈值）
+	kind   string           // 当前异常类型，空表示正常
+	since  time.Time        // 异常首次触发时间
+	cycles int              // 异常模式已持续的周期数
+	paused bool             // ai_anomaly_pause 开启时异常期间暂停开新仓
+}
+
+// summarizeAICycle 生成周期摘要：hold/wait 不计入签名，其余动作按 symbol:action 排序拼接
+func summarizeAICycle(decisions []decision.Decision, candidates int) aiCycleSummary {
+	summary := aiCycleSummary{candidates: candidates}
+	parts := make([]string, 0, len(decisions))
+	for _, d := range decisions {
+		if d.Action == "hold" || d.Action == "wait" {
+			continue
+		}
+		if isOpeningAction(d.Action) {
+			summary.opened = true
+		}
+		parts = append(parts, d.Symbol+":"+d.Action)
+	}
+	sort.Strings(parts)
+	summary.signature = strings.Join(parts, ",")
+	return summary
+}
+
+// evaluateAIAnomaly 按窗口末尾连续出现的模式判断异常（重复决策优先），返回异常类型和已持续的周期数
+func evaluateAIAnomaly(window []aiCycleSummary, idleCycles, repeatCycles int) (string, int) {
+	if repeatCycles > 0 && len(window) > 0 {
+		last := window[len(window)-1].signature
+		run := 0
+		for i := len(window) - 1; i >= 0 && last != "" && window[i].signature == last; i-- {
+			run++
+		}
+		if run >= repeatCycles {
+			return AIAnomalyRepeat, run
+		}
+	}
+	if idleCycles > 0 {
+		run := 0
+		for i := len(window) - 1; i >= 0 && !window[i].opened && window[i].candidates > 0; i-- {
+			run++
+		}
+		if run >= idleCycles {
+			return AIAnomalyIdle, run
+		}
+	}
+	return "", 0
+}
+
+// SetAIAnomalyConfig 【功能】运行时更新AI输出异常检测配置（阈值为 0 表示关闭对应检测；关闭暂停时立即恢复开仓）
+func (at *AutoTrader) SetAIAnomalyConfig(idleCycles, repeatCycles int, pause bool) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.AIAnomalyIdleCycles = idleCycles
+	at.config.AIAnomalyRepeatCycles = repeatCycles
+	at.config.AIAnomalyPause = pause
+	if !pause {
+		at.aiAnomaly.paused = false
+	}
+	if idleCycles <= 0 && repeatCycles <= 0 {
+		at.aiAnomaly = aiAnomalyState{}
+	}
+}
+
+// detectAIAnomaly 【功能】记录本周期AI决策并检测输出异常：首次触发时写入执行日志、发送 ai_anomaly 通知，
+// 开启 ai_anomaly_pause 时暂停开新仓；模式被打破（决策变化或出现开仓）后自动恢复
+func (at *AutoTrader) detectAIAnomaly(record *logger.DecisionRecord, decisions []decision.Decision, candidates int) {
+	at.mu.Lock()
+	idleCycles, repeatCycles, pause := at.config.AIAnomalyIdleCycles, at.config.AIAnomalyRepeatCycles, at.config.AIAnomalyPause
+	if idleCycles <= 0 && repeatCycles <= 0 {
+		at.mu.Unlock()
+		return
+	}
+
+	state := &at.aiAnomaly
+	state.window = append(state.window, summarizeAICycle(decisions, candidates))
+	if limit := max(idleCycles, repeatCycles); len(state.window) > limit {
+		state.window = append([]aiCycleSummary(nil), state.window[len(state.window)-limit:]...)
+	}
+	kind, cycles := evaluateAIAnomaly(state.window, idleCycles, repeatCycles)
+	previous := state.kind
+	signature := state.window[len(state.window)-1].signature
+	triggered := kind != "" && kind != previous
+	switch {
+	case kind == "":
+		*state = aiAnomalyState{window: state.window}
+	case triggered:
+		state.kind, state.since, state.cycles, state.paused = kind, time.Now(), cycles, pause
+	default:
+		state.cycles = cycles
+	}
+	at.mu.Unlock()
+
+	if kind == "" {
+		if previous != "" {
+			log.Printf("✅ [%s] AI输出恢复正常（%s 模式已打破），解除异常标记", at.name, previous)
+			record.ExecutionLog = append(record.ExecutionLog, "✅ AI输出恢复正常，解除异常标记")
+		}
+		return
+	}
+	if !triggered {
+		return
+	}
+
+	var detail string
+	if kind == AIAnomalyRepeat {
+		detail = fmt.Sprintf("连续 %d 个周期输出相同的决策（%s）", cycles, signature)
+	} else {
+		detail = fmt.Sprintf("连续 %d 个周期有候选币种但没有任何开仓", cycles)
+	}
+	action := "仅通知"
+	if pause {
+		action = "暂停开新仓，直到AI输出模式改变"
+	}
+	log.Printf("🚨 [%s] AI输出异常（%s）: %s，%s", at.name, kind, detail, action)
+	record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🚨 AI输出异常（%s）: %s，%s", kind, detail, action))
+	at.emitEvent(logger.EventAIAnomaly,
+		fmt.Sprintf("🚨 [%s] AI输出异常: %s，%s，请检查模型或提示词", at.name, detail, action),
+		map[string]interface{}{
+			"kind":   kind,
+			"cycles": cycles,
+			"paused": pause,
+		})
+}
+
+// aiAnomalyBlocksOpen AI输出异常暂停期间拦截开仓类动作（平仓、调整止损止盈不受影响）
+func (at *AutoTrader) aiAnomalyBlocksOpen(action string) bool {
+	if !isOpeningAction(action) {
+		return false
+	}
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return at.aiAnomaly.paused
+}
+
+// aiAnomalyStatus AI输出异常状态（用于状态接口，正常时为 nil）
+func (at *AutoTrader) aiAnomalyStatus() map[string]interface{} {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	if at.aiAnomaly.kind == "" {
+		return nil
+	}
+	return map[string]interface{}{
+		"kind":   at.aiAnomaly.kind,
+		"since":  at.aiAnomaly.since.Format(time.RFC3339),
+		"cycles": at.aiAnomaly.cycles,
+		"paused": at.aiAnomaly.paused,
+	}
+}
@@ -0,0 +1,31 @@
+package trader
+
+import "time"
+
+// SetMinSecondsBetweenAICalls 【功能】更新两次AI调用之间的最小间隔（秒，0表示不限制）
+func (at *AutoTrader) SetMinSecondsBetweenAICalls(seconds int) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.MinSecondsBetweenAICalls = seconds
+}
+
+// reserveAICall 检查AI调用间隔，允许调用时记录本次调用时间并返回 true，否则返回距下次可调用的剩余时间
+// urgent 为 true 时（如止损/止盈缺失需立即补设）不受间隔限制，但仍记录调用时间
+func (at *AutoTrader) reserveAICall(now time.Time, urgent bool) (bool, time.Duration) {
+	at.mu.RLock()
+	minInterval := time.Duration(at.config.MinSecondsBetweenAICalls) * time.Second
+	at.mu.RUnlock()
+
+	at.aiCallMu.Lock()
+	defer at.aiCallMu.Unlock()
+	if minInterval > 0 && !urgent && !at.lastAICallAt.IsZero() {
+		if remaining := minInterval - now.Sub(at.lastAICallAt); remaining > 0 {
+			return false, remaining
+		}
+	}
+	at.lastAICallAt = now
+	return true, 0
+}
@@ -0,0 +1,158 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"nofx/logger"
+)
+
+// AI调用失败/响应无法解析时的处理策略（on_ai_failure）
+const (
+	AIFailureHold    = "hold"    // 保持现状，不做任何操作（默认）
+	AIFailureFlatten = "flatten" // 平掉所有持仓，回到空仓
+	AIFailurePause   = "pause"   // 暂停开新仓，直到下一次AI决策成功
+)
+
+// ValidAIFailurePolicy 校验AI失败处理策略（空值表示使用默认 hold）
+func ValidAIFailurePolicy(policy string) bool {
+	return policy == "" || policy == AIFailureHold || policy == AIFailureFlatten || policy == AIFailurePause
+}
+
+// SetOnAIFailure 【功能】运行时更新AI失败处理策略（切换为非 pause 策略时解除已有的暂停）
+func (at *AutoTrader) SetOnAIFailure(policy string) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.OnAIFailure = policy
+	if policy != AIFailurePause {
+		at.aiFailurePaused = false
+	}
+}
+
+// aiFailurePolicy 当前生效的AI失败处理策略
+func (at *AutoTrader) aiFailurePolicy() string {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	if !ValidAIFailurePolicy(at.config.OnAIFailure) || at.config.OnAIFailure == "" {
+		return AIFailureHold
+	}
+	return at.config.OnAIFailure
+}
+
+// IsAIFailurePaused 是否因AI决策失败（pause 策略）暂停开新仓中
+func (at *AutoTrader) IsAIFailurePaused() bool {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return at.aiFailurePaused
+}
+
+// aiFailureBlocksOpen AI失败暂停期间拦截开仓类动作（平仓、调整止损止盈不受影响）
+func (at *AutoTrader) aiFailureBlocksOpen(action string) bool {
+	return isOpeningAction(action) && at.IsAIFailurePaused()
+}
+
+// clearAIFailurePause AI决策成功后恢复开仓
+func (at *AutoTrader) clearAIFailurePause() {
+	at.mu.Lock()
+	paused := at.aiFailurePaused
+	at.aiFailurePaused = false
+	at.mu.Unlock()
+	if paused {
+		log.Printf("▶️ [%s] AI决策恢复正常，解除开仓暂停", at.name)
+	}
+}
+
+// handleAIFailure 按 on_ai_failure 策略处理AI调用/解析失败，返回写入决策日志的动作记录和执行日志
+func (at *AutoTrader) handleAIFailure(cause error) ([]logger.DecisionAction, []string) {
+	policy := at.aiFailurePolicy()
+	switch policy {
+	case AIFailurePause:
+		at.mu.Lock()
+		at.aiFailurePaused = true
+		at.mu.Unlock()
+		log.Printf("⏸ [%s] AI决策失败，暂停开新仓直到下一次AI决策成功: %v", at.name, cause)
+		return nil, []string{"⏸ AI决策失败（on_ai_failure=pause）：暂停开新仓，直到下一次AI决策成功"}
+
+	case AIFailureFlatten:
+		return at.flattenOnAIFailure(cause)
+	}
+	return nil, []string{"⏸ AI决策失败（on_ai_failure=hold）：保持现有持仓，不做操作"}
+}
+
+// flattenOnAIFailure flatten 策略：平掉所有持仓（仅分析模式只记录不下单）
+func (at *AutoTrader) flattenOnAIFailure(cause error) ([]logger.DecisionAction, []string) {
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		log.Printf("❌ [%s] AI决策失败后获取持仓失败，无法平仓: %v", at.name, err)
+		return nil, []string{fmt.Sprintf("❌ AI决策失败（on_ai_failure=flatten）：获取持仓失败，未平仓: %v", err)}
+	}
+	normalized := NormalizePositions(positions)
+	if len(normalized) == 0 {
+		return nil, []string{"🧯 AI决策失败（on_ai_failure=flatten）：当前无持仓"}
+	}
+
+	at.mu.RLock()
+	analysisOnly := at.config.AnalysisOnly
+	at.mu.RUnlock()
+
+	actions := make([]logger.DecisionAction, 0, len(normalized))
+	execLog := make([]string, 0, len(normalized)+1)
+	closed, failed := 0, 0
+	for _, pos := range normalized {
+		action := logger.DecisionAction{
+			Action:    "close_" + pos.Side,
+			Symbol:    pos.Symbol,
+			Quantity:  pos.Quantity,
+			Price:     pos.MarkPrice,
+			Timestamp: time.Now(),
+			Status:    "ai_failure_flatten",
+			Reasoning: fmt.Sprintf("AI决策失败，按 on_ai_failure=flatten 平仓: %v", cause),
+		}
+		if analysisOnly {
+			action.Status = "not_executed"
+			execLog = append(execLog, fmt.Sprintf("🧯 %s %s 未平仓（仅分析模式）", pos.Symbol, pos.Side))
+			actions = append(actions, action)
+			continue
+		}
+		if err := at.emergencyClosePosition(pos.Symbol, pos.Side); err != nil {
+			log.Printf("❌ AI失败平仓失败 (%s %s): %v", pos.Symbol, pos.Side, err)
+			action.Error = err.Error()
+			execLog = append(execLog, fmt.Sprintf("❌ %s %s 平仓失败: %v", pos.Symbol, pos.Side, err))
+			failed++
+		} else {
+			action.Success = true
+			at.ClearPeakPnLCache(pos.Symbol, pos.Side)
+			at.forgetPositionFirstSeen(pos.Key())
+			execLog = append(execLog, fmt.Sprintf("🧯 %s %s 已平仓（AI决策失败）", pos.Symbol, pos.Side))
+			closed++
+		}
+		actions = append(actions, action)
+	}
+
+	if !analysisOnly {
+		logger.Notify(fmt.Sprintf("🧯 [%s] AI决策失败，按 on_ai_failure=flatten 平仓 %d 个（失败 %d 个）: %v",
+			at.name, closed, failed, cause))
+	}
+	return actions, execLog
+}
+
+// recordAIFailure 信号模式下AI调用/解析失败：执行 on_ai_failure 策略并写入决策日志
+func (at *AutoTrader) recordAIFailure(symbol string, cause error) {
+	actions, execLog := at.handleAIFailure(cause)
+	if at.decisionLogger == nil {
+		return
+	}
+	record := &logger.DecisionRecord{
+		Success:      false,
+		ErrorMessage: fmt.Sprintf("[signal-ai] %s AI决策失败: %v", symbol, cause),
+		Decisions:    actions,
+		ExecutionLog: execLog,
+	}
+	if err := at.decisionLogger.LogDecision(record); err != nil {
+		log.Printf("⚠ 保存决策记录失败: %v", err)
+	}
+}
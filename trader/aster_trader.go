@@ -37,6 +37,9 @@ type AsterTrader struct {
 	// 缓存交易对精度信息
 	symbolPrecision map[string]SymbolPrecision
 	mu              sync.RWMutex
+
+	// 交易所服务器时钟偏移（签名时间戳按此修正）
+	clock serverClock
 }
 
 // SymbolPrecision 交易对精度信息
@@ -269,8 +272,8 @@ func (t *AsterTrader) normalize(v interface{}) (interface{}, error) {
 // sign 对请求参数进行签名
 func (t *AsterTrader) sign(params map[string]interface{}, nonce uint64) error {
 	// 添加时间戳和接收窗口
-	params["recvWindow"] = "50000"
-	params["timestamp"] = strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10)
+	params["recvWindow"] = strconv.FormatInt(RecvWindowMillis(), 10)
+	params["timestamp"] = strconv.FormatInt(t.clock.nowMillis(), 10)
 
 	// 规范化参数为JSON字符串
 	jsonStr, err := t.normalizeAndStringify(params)
@@ -396,7 +399,7 @@ func (t *AsterTrader) doRequest(method, endpoint string, params map[string]inter
 
 		body, _ := io.ReadAll(resp.Body)
 		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+			return nil, newExchangeHTTPError("HTTP", resp.StatusCode, body)
 		}
 		return body, nil
 
@@ -422,7 +425,7 @@ func (t *AsterTrader) doRequest(method, endpoint string, params map[string]inter
 
 		body, _ := io.ReadAll(resp.Body)
 		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+			return nil, newExchangeHTTPError("HTTP", resp.StatusCode, body)
 		}
 		return body, nil
 
@@ -488,21 +491,9 @@ func (t *AsterTrader) GetBalance() (map[string]interface{}, error) {
 	// Aster 的 crossUnPnl 字段不准确，需要从持仓数据中重新计算
 	totalMarginUsed := 0.0
 	realUnrealizedPnl := 0.0
-	for _, pos := range positions {
-		markPrice := pos["markPrice"].(float64)
-		quantity := pos["positionAmt"].(float64)
-		if quantity < 0 {
-			quantity = -quantity
-		}
-		unrealizedPnl := pos["unRealizedProfit"].(float64)
-		realUnrealizedPnl += unrealizedPnl
-
-		leverage := 10
-		if lev, ok := pos["leverage"].(float64); ok {
-			leverage = int(lev)
-		}
-		marginUsed := (quantity * markPrice) / float64(leverage)
-		totalMarginUsed += marginUsed
+	for _, pos := range NormalizePositions(positions) {
+		realUnrealizedPnl += pos.UnrealizedPnL
+		totalMarginUsed += pos.MarginUsed()
 	}
 
 	// ✅ Aster 正确计算方式:
@@ -575,8 +566,11 @@ func (t *AsterTrader) GetPositions() ([]map[string]interface{}, error) {
 
 // OpenLong 开多单
 func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	if err := throttleOrder("aster", "OpenLong"); err != nil {
+		return nil, err
+	}
 	// 开仓前先取消所有挂单,防止残留挂单导致仓位叠加
-	if err := t.CancelAllOrders(symbol); err != nil {
+	if err := t.cancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消挂单失败(继续开仓): %v", err)
 	}
 
@@ -642,8 +636,11 @@ func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int) (m
 
 // OpenShort 开空单
 func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	if err := throttleOrder("aster", "OpenShort"); err != nil {
+		return nil, err
+	}
 	// 开仓前先取消所有挂单,防止残留挂单导致仓位叠加
-	if err := t.CancelAllOrders(symbol); err != nil {
+	if err := t.cancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消挂单失败(继续开仓): %v", err)
 	}
 
@@ -709,6 +706,7 @@ func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int) (
 
 // CloseLong 平多单
 func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+	throttleRiskOrder("aster", "CloseLong")
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -716,9 +714,9 @@ func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]int
 			return nil, err
 		}
 
-		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "long" {
-				quantity = pos["positionAmt"].(float64)
+		for _, pos := range NormalizePositions(positions) {
+			if pos.Symbol == symbol && pos.Side == "long" {
+				quantity = pos.Quantity
 				break
 			}
 		}
@@ -782,7 +780,7 @@ func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]int
 	log.Printf("✓ 平多仓成功: %s 数量: %s", symbol, qtyStr)
 
 	// 平仓后取消该币种的所有挂单(止损止盈单)
-	if err := t.CancelAllOrders(symbol); err != nil {
+	if err := t.cancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消挂单失败: %v", err)
 	}
 
@@ -791,6 +789,7 @@ func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]int
 
 // CloseShort 平空单
 func (t *AsterTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+	throttleRiskOrder("aster", "CloseShort")
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -798,10 +797,9 @@ func (t *AsterTrader) CloseShort(symbol string, quantity float64) (map[string]in
 			return nil, err
 		}
 
-		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "short" {
-				// Aster的GetPositions已经将空仓数量转换为正数，直接使用
-				quantity = pos["positionAmt"].(float64)
+		for _, pos := range NormalizePositions(positions) {
+			if pos.Symbol == symbol && pos.Side == "short" {
+				quantity = pos.Quantity
 				break
 			}
 		}
@@ -865,13 +863,35 @@ func (t *AsterTrader) CloseShort(symbol string, quantity float64) (map[string]in
 	log.Printf("✓ 平空仓成功: %s 数量: %s", symbol, qtyStr)
 
 	// 平仓后取消该币种的所有挂单(止损止盈单)
-	if err := t.CancelAllOrders(symbol); err != nil {
+	if err := t.cancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消挂单失败: %v", err)
 	}
 
 	return result, nil
 }
 
+// GetMarginMode 查询交易所中该币种当前的仓位模式（与币安相同，positionRisk 的 marginType: cross/isolated）
+func (t *AsterTrader) GetMarginMode(symbol string) (bool, error) {
+	body, err := t.request("GET", "/fapi/v3/positionRisk", map[string]interface{}{"symbol": symbol})
+	if err != nil {
+		return false, fmt.Errorf("查询仓位模式失败: %w", err)
+	}
+
+	var positions []struct {
+		Symbol     string `json:"symbol"`
+		MarginType string `json:"marginType"`
+	}
+	if err := json.Unmarshal(body, &positions); err != nil {
+		return false, fmt.Errorf("解析仓位模式失败: %w", err)
+	}
+	for _, pos := range positions {
+		if pos.Symbol == symbol {
+			return strings.EqualFold(pos.MarginType, "cross") || strings.EqualFold(pos.MarginType, "crossed"), nil
+		}
+	}
+	return false, fmt.Errorf("查询仓位模式失败: 未返回 %s 的仓位信息", symbol)
+}
+
 // SetMarginMode 设置仓位模式
 func (t *AsterTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
 	// Aster支持仓位模式设置
@@ -959,6 +979,7 @@ func (t *AsterTrader) GetMarketPrice(symbol string) (float64, error) {
 
 // SetStopLoss 设置止损
 func (t *AsterTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	throttleRiskOrder("aster", "SetStopLoss")
 	side := "SELL"
 	if positionSide == "SHORT" {
 		side = "BUY"
@@ -1000,6 +1021,7 @@ func (t *AsterTrader) SetStopLoss(symbol string, positionSide string, quantity,
 
 // SetTakeProfit 设置止盈
 func (t *AsterTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	throttleRiskOrder("aster", "SetTakeProfit")
 	side := "SELL"
 	if positionSide == "SHORT" {
 		side = "BUY"
@@ -1039,8 +1061,16 @@ func (t *AsterTrader) SetTakeProfit(symbol string, positionSide string, quantity
 	return err
 }
 
+// SetOCO 设置止损/止盈单（独立的条件单，联动由 AutoTrader 模拟）
+func (t *AsterTrader) SetOCO(symbol, side string, quantity, stopPrice, takeProfitPrice float64) error {
+	return placeOCOLegs(t, symbol, side, quantity, stopPrice, takeProfitPrice)
+}
+
 // CancelStopLossOrders 仅取消止损单（不影响止盈单）
 func (t *AsterTrader) CancelStopLossOrders(symbol string) error {
+	if err := throttleOrder("aster", "CancelStopLossOrders"); err != nil {
+		return err
+	}
 	// 获取该币种的所有未完成订单
 	params := map[string]interface{}{
 		"symbol": symbol,
@@ -1100,6 +1130,9 @@ func (t *AsterTrader) CancelStopLossOrders(symbol string) error {
 
 // CancelTakeProfitOrders 仅取消止盈单（不影响止损单）
 func (t *AsterTrader) CancelTakeProfitOrders(symbol string) error {
+	if err := throttleOrder("aster", "CancelTakeProfitOrders"); err != nil {
+		return err
+	}
 	// 获取该币种的所有未完成订单
 	params := map[string]interface{}{
 		"symbol": symbol,
@@ -1159,6 +1192,14 @@ func (t *AsterTrader) CancelTakeProfitOrders(symbol string) error {
 
 // CancelAllOrders 取消所有订单
 func (t *AsterTrader) CancelAllOrders(symbol string) error {
+	if err := throttleOrder("aster", "CancelAllOrders"); err != nil {
+		return err
+	}
+	return t.cancelAllOrders(symbol)
+}
+
+// cancelAllOrders 取消该币种的所有挂单（不取限速令牌：开仓/平仓内部清理旧委托时使用，一次下单只占一个令牌）
+func (t *AsterTrader) cancelAllOrders(symbol string) error {
 	params := map[string]interface{}{
 		"symbol": symbol,
 	}
@@ -1169,6 +1210,9 @@ func (t *AsterTrader) CancelAllOrders(symbol string) error {
 
 // CancelStopOrders 取消该币种的止盈/止损单（用于调整止盈止损位置）
 func (t *AsterTrader) CancelStopOrders(symbol string) error {
+	if err := throttleOrder("aster", "CancelStopOrders"); err != nil {
+		return err
+	}
 	// 获取该币种的所有未完成订单
 	params := map[string]interface{}{
 		"symbol": symbol,
@@ -1231,10 +1275,9 @@ func (t *AsterTrader) FormatQuantity(symbol string, quantity float64) (string, e
 	return fmt.Sprintf("%v", formatted), nil
 }
 
-// GetOpenOrders 获取当前未成交的委托单（Aster暂不实现，仅为接口兼容）
+// GetOpenOrders 获取当前未成交的委托单（Aster暂不实现，返回 ErrOpenOrdersNotSupported）
 func (t *AsterTrader) GetOpenOrders(symbol string) ([]map[string]interface{}, error) {
-	log.Printf("⚠️  Aster GetOpenOrders 暂不实现")
-	return []map[string]interface{}{}, nil
+	return nil, ErrOpenOrdersNotSupported
 }
 
 // GetOrderHistory 获取历史订单（Aster暂不实现，仅为接口兼容）
@@ -1243,6 +1286,100 @@ func (t *AsterTrader) GetOrderHistory(symbol string, startTime, endTime int64) (
 	return []map[string]interface{}{}, nil
 }
 
+// GetLeverageBrackets 获取杠杆分层（Aster暂不实现，返回空列表）
+func (t *AsterTrader) GetLeverageBrackets(symbol string) ([]LeverageBracket, error) {
+	return []LeverageBracket{}, nil
+}
+
+// GetPriceTickSize 获取价格最小变动单位（复用交易对精度缓存）
+func (t *AsterTrader) GetPriceTickSize(symbol string) (float64, error) {
+	prec, err := t.getPrecision(symbol)
+	if err != nil {
+		return 0, err
+	}
+	return prec.TickSize, nil
+}
+
+// GetServerTime 获取Aster服务器时间（毫秒）
+func (t *AsterTrader) GetServerTime() (int64, error) {
+	resp, err := t.client.Get(t.baseURL + "/fapi/v3/time")
+	if err != nil {
+		return 0, fmt.Errorf("获取服务器时间失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("读取服务器时间失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("获取服务器时间失败 (status %d): %s", resp.StatusCode, string(body))
+	}
+	var result struct {
+		ServerTime int64 `json:"serverTime"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, fmt.Errorf("解析服务器时间失败: %w", err)
+	}
+	return result.ServerTime, nil
+}
+
+// SyncServerTime 按Aster服务器时间校准签名时间戳，返回偏移（服务器时间 - 本地时间）
+func (t *AsterTrader) SyncServerTime() (time.Duration, error) {
+	return t.clock.sync(t.GetServerTime)
+}
+
+// GetOrderBookDepth 获取盘口深度（Aster 暂不支持，返回 nil）
+func (t *AsterTrader) GetOrderBookDepth(symbol string, levels int) (*OrderBook, error) {
+	return nil, nil
+}
+
+// GetBalanceHistory 获取资金流水（基于 /fapi/v3/income，字段与币安一致）
+func (t *AsterTrader) GetBalanceHistory(startTime, endTime int64) ([]map[string]interface{}, error) {
+	now := time.Now().UnixMilli()
+	if endTime == 0 {
+		endTime = now
+	}
+	if startTime == 0 {
+		startTime = now - 7*24*60*60*1000 // 默认最近7天
+	}
+
+	params := map[string]interface{}{
+		"startTime": startTime,
+		"endTime":   endTime,
+		"limit":     1000,
+	}
+	body, err := t.request("GET", "/fapi/v3/income", params)
+	if err != nil {
+		return nil, fmt.Errorf("获取资金流水失败: %w", err)
+	}
+
+	var incomes []struct {
+		Asset      string `json:"asset"`
+		Income     string `json:"income"`
+		IncomeType string `json:"incomeType"`
+		Symbol     string `json:"symbol"`
+		Time       int64  `json:"time"`
+	}
+	if err := json.Unmarshal(body, &incomes); err != nil {
+		return nil, fmt.Errorf("解析资金流水失败: %w", err)
+	}
+
+	result := make([]map[string]interface{}, 0, len(incomes))
+	for _, income := range incomes {
+		amount, _ := strconv.ParseFloat(income.Income, 64)
+		result = append(result, map[string]interface{}{
+			"time":     income.Time,
+			"type":     classifyIncomeType(income.IncomeType, amount),
+			"amount":   amount,
+			"asset":    income.Asset,
+			"symbol":   income.Symbol,
+			"raw_type": income.IncomeType,
+		})
+	}
+	return result, nil
+}
+
 // PlaceLimitOrder 下限价委托开仓单 (Aster Stub)
 func (t *AsterTrader) PlaceLimitOrder(symbol string, side, tradeSide string, quantity float64, price float64, leverage int) (map[string]interface{}, error) {
 	return nil, fmt.Errorf("PlaceLimitOrder not implemented for Aster yet")
@@ -6,7 +6,9 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/stretchr/testify/assert"
@@ -297,3 +299,45 @@ func TestNewAsterTrader(t *testing.T) {
 		})
 	}
 }
+
+// TestAsterTrader_ServerClockOffset 测试服务器时钟偏移被应用到签名请求的时间戳和 recvWindow
+func TestAsterTrader_ServerClockOffset(t *testing.T) {
+	const skew = int64(60000) // 模拟本地时钟比服务器慢60秒
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/fapi/v3/time" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]int64{"serverTime": time.Now().UnixMilli() + skew})
+	}))
+	defer mockServer.Close()
+
+	privateKey, _ := crypto.GenerateKey()
+	trader := &AsterTrader{
+		ctx:             context.Background(),
+		user:            "0x1234567890123456789012345678901234567890",
+		signer:          "0xabcdefabcdefabcdefabcdefabcdefabcdefabcd",
+		privateKey:      privateKey,
+		client:          mockServer.Client(),
+		baseURL:         mockServer.URL,
+		symbolPrecision: make(map[string]SymbolPrecision),
+	}
+
+	offset, err := trader.SyncServerTime()
+	assert.NoError(t, err)
+	assert.InDelta(t, skew, offset.Milliseconds(), 1000)
+
+	defer SetRecvWindow(0)
+	SetRecvWindow(20000)
+
+	params := map[string]interface{}{"symbol": "BTCUSDT"}
+	assert.NoError(t, trader.sign(params, 1))
+	timestamp, err := strconv.ParseInt(params["timestamp"].(string), 10, 64)
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Now().UnixMilli()+skew, timestamp, 1000, "签名时间戳应按服务器时间修正")
+	assert.Equal(t, "20000", params["recvWindow"])
+
+	// recvWindow 超过交易所上限时取上限
+	SetRecvWindow(120000)
+	assert.Equal(t, int64(MaxRecvWindowMillis), RecvWindowMillis())
+}
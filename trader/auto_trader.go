@@ -2,6 +2,7 @@ package trader
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -13,6 +14,7 @@ import (
 	"nofx/mcp"
 	"nofx/pool"
 	"nofx/signal"
+	"nofx/tracing"
 	"os"
 	"strconv"
 	"strings"
@@ -144,12 +146,21 @@ func (at *AutoTrader) detectStrategyDiffFromExchange(strat *signal.SignalDecisio
 		return false, "", nil, false, false
 	}
 
+	// 0) 先去重：取消同价位的重复挂单
+	at.deduplicateOpenOrders(strat.Symbol)
+
+	return at.diffStrategyAgainstExchange(strat, receivedAt)
+}
+
+// diffStrategyAgainstExchange detectStrategyDiffFromExchange 的只读对账部分（不撤单，供执行计划预览复用）
+func (at *AutoTrader) diffStrategyAgainstExchange(strat *signal.SignalDecision, receivedAt time.Time) (bool, string, []expectedPoint, bool, bool) {
+	if strat == nil || strat.Symbol == "" {
+		return false, "", nil, false, false
+	}
+
 	symbol := strat.Symbol
 	wantOpenSide := normalizeSideToBitgetOpenSide(strat.Direction)
 
-	// 0) 先去重：取消同价位的重复挂单
-	at.deduplicateOpenOrders(symbol)
-
 	// 1) 当前持仓
 	var hasPosition bool
 	var posQty float64
@@ -157,19 +168,13 @@ func (at *AutoTrader) detectStrategyDiffFromExchange(strat *signal.SignalDecisio
 	var posEntryPrice float64
 	positions, err := at.trader.GetPositions()
 	if err == nil {
-		for _, p := range positions {
-			if p["symbol"] == symbol {
-				amt, _ := p["positionAmt"].(float64)
-				if amt != 0 {
+		for _, p := range NormalizePositions(positions) {
+			if p.Symbol == symbol {
+				if p.Quantity != 0 {
 					hasPosition = true
-					posQty = math.Abs(amt)
-					sideStr, _ := p["side"].(string) // long/short
-					if strings.ToLower(sideStr) == "short" {
-						posSide = "SHORT"
-					} else {
-						posSide = "LONG"
-					}
-					posEntryPrice, _ = p["entryPrice"].(float64)
+					posQty = p.Quantity
+					posSide = strings.ToUpper(p.Side)
+					posEntryPrice = p.EntryPrice
 				}
 				break
 			}
@@ -192,10 +197,11 @@ func (at *AutoTrader) detectStrategyDiffFromExchange(strat *signal.SignalDecisio
 		orderHistory = []map[string]interface{}{}
 	}
 
-	// 4) 期望点位：entry + adds
+	// 4) 期望点位：entry + adds（交易员显式配置了底仓/默认补仓比例时使用配置值）
+	basePercent, defaultAddPercent := at.signalDiffSizing(len(strat.Adds))
 	var points []expectedPoint
 	if strat.Entry.PriceTarget > 0 {
-		points = append(points, expectedPoint{kind: "entry", price: strat.Entry.PriceTarget, percent: 0.40})
+		points = append(points, expectedPoint{kind: "entry", price: strat.Entry.PriceTarget, percent: basePercent})
 	}
 	for i, a := range strat.Adds {
 		if a.Price <= 0 {
@@ -203,7 +209,7 @@ func (at *AutoTrader) detectStrategyDiffFromExchange(strat *signal.SignalDecisio
 		}
 		pct := a.Percent
 		if pct <= 0 {
-			pct = 0.60 / math.Max(1, float64(len(strat.Adds)))
+			pct = defaultAddPercent
 		}
 		points = append(points, expectedPoint{kind: fmt.Sprintf("add_%d", i+1), price: a.Price, percent: pct})
 	}
@@ -412,7 +418,11 @@ type AutoTraderConfig struct {
 	BitgetPassphrase string // Bitget API Passphrase
 	BitgetTestnet    bool   // 是否使用测试网
 
+	// 备用密钥：同一交易所的另一组API凭证（仅使用上面的交易所凭证字段），主密钥持续鉴权/IP失败时自动切换；nil 表示不启用
+	BackupExchange *AutoTraderConfig
+
 	CoinPoolAPIURL string
+	SignalSources  []pool.SignalSource // 用户命名信号源（按权重合并候选币种），非空时优先于 CoinPoolAPIURL
 
 	// AI配置
 	UseQwen     bool
@@ -420,13 +430,19 @@ type AutoTraderConfig struct {
 	QwenKey     string
 
 	// 自定义AI API配置
-	CustomAPIURL    string
-	CustomAPIKey    string
-	CustomModelName string
+	CustomAPIURL     string
+	CustomAPIKey     string
+	CustomModelName  string
+	CustomHeaders    map[string]string // 附加请求头（如 X-Api-Key、组织ID），仅自定义API生效
+	CustomAuthScheme string            // 密钥的发送方式（bearer/raw/api-key/x-api-key/none，空=bearer）
+	MaxPromptTokens  int               // Prompt token 预算，超出时自动裁剪低优先级内容（0=不限制）
 
 	// 扫描配置
 	ScanInterval time.Duration // 扫描间隔（建议3分钟）
 
+	// 决策上下文
+	IncludeOrderBookDepth bool // 包含盘口深度（买一卖一、价差、附近挂单量），交易所不支持时自动忽略
+
 	// 账户配置
 	InitialBalance float64 // 初始金额（用于计算盈亏，需手动设置）
 
@@ -435,18 +451,154 @@ type AutoTraderConfig struct {
 	AltcoinLeverage int // 山寨币的杠杆倍数
 
 	// 风险控制（仅作为提示，AI可自主决定）
-	MaxDailyLoss    float64       // 最大日亏损百分比（提示）
-	MaxDrawdown     float64       // 最大回撤百分比（提示）
-	StopTradingTime time.Duration // 触发风控后暂停时长
-	EnableDrawdownMonitor bool    // 是否启用回撤监控自动平仓（默认关闭）
+	MaxDailyLoss          float64       // 最大日亏损百分比（提示；开启 EnforceDailyLossStop 后为硬止损）
+	MaxDrawdown           float64       // 最大回撤百分比（提示）
+	StopTradingTime       time.Duration // 触发风控后暂停时长
+	EnforceDailyLossStop  bool          // 日亏损硬止损：达到 MaxDailyLoss 时平仓并暂停交易（默认关闭，仅提示）
+	EnableDrawdownMonitor bool          // 是否启用回撤监控自动平仓（默认关闭）
 
 	// 仓位模式
 	IsCrossMargin bool // true=全仓模式, false=逐仓模式
 
+	// 开仓保护
+	RequireStopLoss    bool            // 开仓必须带有效止损（缺失时拒绝开仓）
+	DefaultStopLossPct float64         // 止损缺失时按仓位最大亏损百分比自动推导止损（0=不推导，直接拒绝）
+	AutoReprotect      bool            // 每个决策周期后校验持仓的止损/止盈单，缺失时按最近决策的价位补设（默认关闭）
+	TradingSchedule    []TradingWindow // 允许开新仓的时段（UTC），为空表示全天；时段外跳过AI决策，持仓保护照常执行
+
+	// 极端行情过滤（周期开始前检查，触发时跳过AI决策，持仓保护照常执行；0=不启用）
+	SkipIfBTCMovePct   float64 // BTC 最近1小时涨跌幅绝对值超过该百分比时跳过
+	SkipIfFundingAbove float64 // BTC 资金费率绝对值超过该百分比时跳过（如 0.1 表示 0.1%）
+
+	// 盈利保护（回撤监控中按峰值收益率收紧止损，只收紧不放松；0=不启用）
+	BreakevenAtProfitPct    float64 // 持仓收益率达到该百分比后止损移至开仓价
+	TrailStopAfterProfitPct float64 // 持仓收益率达到该百分比后跟踪止损，锁定峰值收益的 TrailLockFraction
+	TrailLockFraction       float64 // 跟踪止损锁定的峰值收益比例（0-1），0=使用默认值 0.5
+
+	// 候选币种过滤
+	ExcludeHeldFromCandidates bool // 从候选币种中剔除已持仓币种（持仓仍通过 Positions 提供给AI管理）
+
+	// 运行模式
+	AnalysisOnly    bool // 仅分析模式：完整运行AI决策并记录，但不执行任何下单（决策标记为 not_executed）
+	WarmupMinutes   int  // 启动后预热时长（分钟）：预热期内只记录决策不执行（决策标记为 warmup_skipped），0=不预热
+	SkipCycleIfBusy bool // 已有决策周期在执行时，新的触发直接跳过（返回 ErrCycleInProgress）；默认等待其完成
+
+	// 持仓时长限制
+	MaxPositionAgeHours int // 持仓最长持有时间（小时），超过后自动平仓（仅分析模式下只提醒），0=不限制
+
+	// 加仓策略
+	AllowPyramiding    bool // 允许对同方向已有持仓加仓（默认关闭：已有同向持仓时拒绝开仓）
+	MaxAddsPerPosition int  // 单个持仓最多加仓次数（不含首次开仓），0=使用默认值
+
+	// 反手
+	AllowFlip bool // 允许 flip_long/flip_short 一步平掉反向持仓并开新方向（默认关闭）
+
+	// 信心度门槛
+	MinConfidence int // 开仓/加仓决策的最低信心度（0-100），低于阈值降级为 wait；0=不限制
+
+	// 挂单数量上限（防止补单/兜底逻辑异常时大量挂单）
+	MaxOpenOrders int // 单个币种最多同时存在的限价挂单数（不含止盈止损计划单），0=不限制
+
+	// 单周期动作上限（防止AI异常输出大量动作导致集中下单）
+	MaxActionsPerCycle int // 每个决策周期最多执行的动作数（按优先级排序后截取，hold/wait 不计入），0=不限制
+
+	// 首笔交易人工审批
+	RequireFirstTradeApproval bool // 首笔开仓转入待审批列表，人工审批通过后才执行，之后的交易自动执行
+
+	// AI调用频率下限（控制成本，自主模式与信号模式共用）
+	MinSecondsBetweenAICalls int // 两次AI调用之间的最小间隔（秒），间隔不足时跳过本次调用（止损/止盈补设除外），0=不限制
+
+	// 仓位计算基数（信号模式按百分比计算下单金额时使用）
+	SizingBase string // "fixed"=初始余额（默认），"equity"=账户实时净值（复利）
+
+	// AI采样参数（nil/0 表示使用提供商默认值）
+	AITemperature *float64
+	AITopP        *float64
+	AIMaxTokens   int
+
+	// AI调用/解析失败时的处理策略
+	OnAIFailure string // "hold"=保持现状（默认），"flatten"=平掉所有持仓，"pause"=暂停开新仓直到下一次AI决策成功
+
+	// 周期净值基准滚动策略（initialBalance 不受影响，仍用于全部时间的总收益）
+	BaselineResetPolicy string // "none"=不滚动（默认），"weekly"=每周一，"monthly"=每月1日（UTC）将当时净值记录为新的周期基准
+
+	// 账户最大回撤硬止损（净值较历史峰值回撤，峰值持久化到数据库，重启后保留）
+	EnforceMaxDrawdownStop bool    // 回撤达到阈值时暂停开新仓（默认关闭，MaxDrawdown 仅作提示）
+	MaxDrawdownStopPct     float64 // 回撤阈值（百分比），<=0 时使用 MaxDrawdown
+	DrawdownStopFlatten    bool    // 触发时平掉所有持仓
+
+	// 止损接近提醒（回撤监控每分钟检查，每个持仓按止损价只提醒一次）
+	StopApproachAlertPct float64 // 标记价格距止损在该百分比以内时推送通知，0=关闭（默认）
+
+	// 最短持仓时间（按持仓首次出现时间计算，只约束AI主动平仓，止损/紧急平仓不受限制）
+	MinHoldingMinutes int // 持仓未满该分钟数时拒绝AI平仓/部分平仓/反手，0=不限制（默认）
+
+	// 信号模式策略来源订阅（运行时由 SetStrategySources 更新，受 mu 保护）
+	StrategySources []string // 只跟随这些来源的策略（发件人邮箱或 @域名），为空表示跟随所有来源
+
+	// 信号模式第一止盈分批止盈（补单自检循环检查，每个策略只执行一次）
+	TP1ClosePct      float64 // 标记价格到达 TakeProfits[0] 时部分平仓的百分比，0=关闭（默认）
+	TP1BreakevenStop bool    // 分批止盈后将剩余仓位止损移到开仓价（保本）
+
+	// 目标权重再平衡模式（配置了目标权重时替代AI自主决策，运行时由 SetRebalanceConfig 更新，受 mu 保护）
+	RebalanceTargets         map[string]float64 // 币种 -> 目标权重（多仓名义价值占仓位计算基数的百分比），为空表示不启用
+	RebalanceTolerancePct    float64            // 偏离目标权重超过该百分点时调整，0=默认2
+	RebalanceIntervalMinutes int                // 再平衡检查间隔（分钟），0=默认60
+
+	// 开仓止损/止盈以 OCO 方式联动（运行时由 SetUseOCO 更新，受 mu 保护）：交易所原生支持时直接联动，
+	// 否则由补单自检循环在其中一个成交后撤销另一个
+	UseOCO bool
+
+	// AI输出异常检测（运行时由 SetAIAnomalyConfig 更新，受 mu 保护）：阈值为连续周期数，0=关闭对应检测
+	AIAnomalyIdleCycles   int  // 连续该数量的周期有候选币种却没有任何开仓时告警
+	AIAnomalyRepeatCycles int  // 连续该数量的周期输出相同的非观望决策时告警
+	AIAnomalyPause        bool // 告警期间暂停开新仓，直到AI输出模式改变
+
+	// 信号模式仓位对账间隔（分钟，0=默认30；运行时由 SetPositionAuditInterval 更新，受 mu 保护），
+	// 对账时关闭仓位已结束的策略，并提示没有活跃策略管理的孤儿持仓
+	PositionAuditMinutes int
+
+	// 保证金不足（margin_deficit）判定的可用余额下限（USDT，运行时由 SetMinAvailableBalance 更新，受 mu 保护），
+	// 0 表示仅在可用余额为负时判定
+	MinAvailableBalance float64
+
+	// 默认止损/止盈百分比（运行时由 SetProtectiveDefaults 更新，受 mu 保护）：键为币种或 default，
+	// 决策/信号缺少止损或止盈时按开仓价推导，避免持仓没有保护单
+	ProtectiveDefaults map[string]ProtectiveDefault
+
+	// 自主模式多周期指标上下文（运行时由 SetContextIndicators 更新，受 mu 保护；K线按周期缓存）
+	ContextIndicators []string // 加入候选币种/持仓上下文的指标：rsi / macd / ema_cross / atr，为空表示不加入
+	ContextTimeframes []string // 计算指标的K线周期（如 1h,4h），为空表示不加入
+
+	// 单币种敞口上限（防止集中持仓，自主模式与信号模式开仓/加仓共用）
+	MaxPerSymbolExposurePct float64 // 单个币种持仓名义价值（多空合计，含本次开仓）占账户净值的最大百分比，超过时下调开仓金额，0=不限制
+
+	// 决策上下文附带最近平仓交易（让AI复盘近期具体交易，而不只是汇总统计）
+	IncludeRecentTrades bool // 是否在决策上下文中附带最近平仓交易
+	RecentTradesCount   int  // 附带的最近平仓交易笔数（最多10笔），<=0 时使用默认 5 笔
+
+	// 信号模式仓位（百分比，占初始资金）
+	SignalBasePositionPct float64 // 信号跟单底仓比例，<=0 时使用默认 20%
+	SignalDefaultAddPct   float64 // 信号未指定补仓比例时的默认补仓比例，<=0 时使用默认 10%
+
+	// 账户净值止盈止损（整体平仓并暂停，0=关闭）
+	EquityTakeProfit    float64 // 净值达到该值（USDT）时触发止盈
+	EquityStopLoss      float64 // 净值跌至该值（USDT）时触发止损
+	EquityTakeProfitPct float64 // 净值相对初始余额上涨该百分比时触发止盈
+	EquityStopLossPct   float64 // 净值相对初始余额下跌该百分比时触发止损
+
+	// 公开展示（排行榜/竞赛/收益对比等无需认证的接口）
+	PublicDisplayName string // 公开展示名称，空表示使用交易员名称
+	PublicVisibility  bool   // 是否在公开接口中展示
+
 	// 币种配置
 	DefaultCoins []string // 默认币种列表（从数据库获取）
 	TradingCoins []string // 实际交易币种列表
 
+	// 候选币种池与开仓白名单（运行时由 SetSymbolUniverse 更新，受 mu 保护）
+	CandidateCoins []string // AI选币的候选池（candidate_symbols），为空时沿用 TradingCoins
+	AllowedSymbols []string // 开仓白名单（显式配置的 trading_symbols），为空表示不限制
+
 	// 系统提示词模板
 	SystemPromptTemplate string // 系统提示词模板名称（如 "default", "aggressive"）
 
@@ -466,6 +618,38 @@ type AutoTrader struct {
 	decisionLogger        *logger.DecisionLogger // 决策日志记录器
 	initialBalance        float64
 	dailyPnL              float64
+	dayStartEquity        float64  // 当日起始净值（日盈亏基准，日切时重新记录）
+	dailyLossStopped      bool     // 当日（UTC）是否已触发日亏损硬止损（仅在日切时重置）
+	equityBracketHit      string   // 触发的账户净值止盈/止损（take_profit/stop_loss），非空时暂停交易直到手动启动或修改阈值（持久化）
+	aiFailurePaused       bool     // on_ai_failure=pause 时AI决策失败后暂停开新仓，下一次AI决策成功时恢复
+
+	// AI输出异常检测的滚动窗口和当前异常状态（受 mu 保护），见 detectAIAnomaly
+	aiAnomaly aiAnomalyState
+
+	// 保证金不足状态（受 mu 保护），见 updateMarginDeficit
+	marginDeficit marginDeficitState
+
+	// 交易所维护状态（受 mu 保护）：检测到维护错误后在退避期内不调用交易所和AI，调用成功后清空
+	exchangeMaintenanceSince time.Time
+	exchangeMaintenanceUntil time.Time
+	exchangeMaintenanceErr   string
+
+	// 当前周期的净值基准（受 mu 保护，baseline_reset_policy 非 none 时首次使用从数据库加载）
+	periodBaseline       *sysconfig.TraderBaseline
+	periodBaselineLoaded bool
+
+	// 账户最大回撤硬止损状态（受 mu 保护，峰值和待命状态持久化到数据库），见 updateDrawdownStop
+	peakEquity     float64 // 净值历史峰值
+	drawdownArmed  bool    // 待命中：触发后需净值创新高才重新待命
+	drawdownHalted bool    // 已触发，暂停开新仓直到重启或修改配置
+
+	// 已推送止损接近提醒的持仓 (symbol_side -> 提醒时的止损价)，见 checkStopApproach
+	stopApproachAlerted map[string]float64
+	stopApproachMu      sync.Mutex
+
+	// 交易所服务器时钟偏移（受 mu 保护，服务器时间 - 本地时间），启动时及定期校准
+	clockOffset           time.Duration
+	clockSyncedAt         time.Time
 	customPrompt          string   // 自定义交易策略prompt
 	overrideBasePrompt    bool     // 是否覆盖基础prompt
 	systemPromptTemplate  string   // 系统提示词模板名称
@@ -474,22 +658,57 @@ type AutoTrader struct {
 	lastResetTime         time.Time
 	stopUntil             time.Time
 	isRunning             bool
-	startTime             time.Time          // 系统启动时间
-	callCount             int                // AI调用次数
-	positionFirstSeenTime map[string]int64   // 持仓首次出现时间 (symbol_side -> timestamp毫秒)
-	stopMonitorCh         chan struct{}      // 用于停止监控goroutine
-	monitorWg             sync.WaitGroup     // 用于等待监控goroutine结束
-	peakPnLCache          map[string]float64 // 最高收益缓存 (symbol -> 峰值盈亏百分比)
-	peakPnLCacheMutex     sync.RWMutex       // 缓存读写锁
-	mu                    sync.RWMutex       // 提示词配置读写锁（保护customPrompt、overrideBasePrompt、systemPromptTemplate）
-	lastBalanceSyncTime   time.Time          // 上次余额同步时间
-	database              interface{}        // 数据库引用（用于自动更新余额）
-	userID                string             // 用户ID
-	repairAICooldown      sync.Map           // 策略修复AI调用限频 (strategyID -> time.Time)
-	closedStrategyCache   sync.Map           // 已关闭策略缓存 (strategyID -> bool)，用于快速跳过补单/检查
+	startTime             time.Time               // 系统启动时间
+	callCount             int                     // AI调用次数
+	aiCallsToday          int                     // 当日实际发出的AI请求次数（受 mu 保护）
+	aiCallsDay            string                  // aiCallsToday 对应的日期（2006-01-02）
+	positionFirstSeenTime map[string]int64        // 持仓首次出现时间 (symbol_side -> timestamp毫秒)，持久化到数据库
+	positionFirstSeenMu   sync.Mutex              // 保护 positionFirstSeenTime、positionAgeNotified（决策周期与持仓时长监控并发访问）
+	positionAgeNotified   map[string]int64        // 仅分析模式下已发送持仓超时提醒的持仓 (symbol_side -> 首次出现时间)，同一持仓只提醒一次
+	positionPyramid       map[string]pyramidState // 持仓加仓状态 (symbol_side -> 加仓次数/整体止损)，持久化到数据库
+	positionPyramidMu     sync.Mutex              // 保护 positionPyramid
+	stopMonitorCh         chan struct{}           // 用于停止监控goroutine
+	monitorWg             sync.WaitGroup          // 用于等待监控goroutine结束
+	peakPnLCache          map[string]float64      // 最高收益缓存 (symbol -> 峰值盈亏百分比)
+	peakPnLCacheMutex     sync.RWMutex            // 缓存读写锁
+	mu                    sync.RWMutex            // 提示词配置读写锁（保护customPrompt、overrideBasePrompt、systemPromptTemplate）
+	lastBalanceSyncTime   time.Time               // 上次余额同步时间
+	lastTransferCheckAt   int64                   // 上次检查资金流水的截止时间（毫秒）
+	database              interface{}             // 数据库引用（用于自动更新余额）
+	userID                string                  // 用户ID
+	repairAICooldown      sync.Map                // 策略修复AI调用限频 (strategyID -> time.Time)
+	closedStrategyCache   sync.Map                // 已关闭策略缓存 (strategyID -> bool)，用于快速跳过补单/检查
+	tp1ScaledOut          sync.Map                // 已执行第一止盈分批止盈的策略 (strategyID -> bool)，见 checkTP1ScaleOut
+	unavailableSymbols    sync.Map                // 已下架/暂停交易的币种 (symbol -> unavailableSymbol)，见 checkMarketData
+	ocoLinks              sync.Map                // 模拟 OCO 的保护单联动 (symbol_side -> ocoLink)，见 reconcileOCOOrders
+	symbolDecisionAt      sync.Map                // 自主模式各币种最近一次出现在AI决策中的时间 (symbol -> time.Time)，见 FindOrphanPositions
+	cycleMu               sync.Mutex              // 决策周期锁（串行化定时周期与手动触发的周期），见 runExclusiveCycle
+	symbolLocks           sync.Map                // 信号模式按币种的执行锁 (symbol -> *sync.Mutex)，见 runExclusiveForSymbol
+	leverageBrackets      sync.Map                // 杠杆分层缓存 (symbol -> cachedLeverageBrackets)
+	priceTickSizes        sync.Map                // 价格 tick size 缓存 (symbol -> cachedTickSize)
+	lastManualCycleTime   time.Time               // 上次手动触发决策周期的时间（用于限频）
+
+	// 盘口深度摘要短期缓存 (symbol -> 摘要)，见 orderBookDepth
+	orderBookCache   map[string]orderBookCacheEntry
+	orderBookCacheMu sync.Mutex
+
+	// 币种池降级状态，见 fallbackCandidateCoins
+	lastGoodCoinPool []decision.CandidateCoin // 最近一次成功获取的合并币种池
+	coinPoolDegraded bool                     // 币种池服务不可用，当前使用降级币种
+	coinPoolMu       sync.Mutex
 
 	// 信号模式状态
 	lastExecutedSignalID string // 上次执行的信号ID
+
+	// 首笔交易人工审批状态，见 holdForApproval
+	pendingActions     []*PendingAction // 等待审批的开仓决策（同一时间最多一笔）
+	firstTradeApproved bool             // 首笔交易已审批并执行成功，之后的开仓自动执行（持久化到数据库）
+	approvalInFlight   bool             // 已审批的动作正在执行，不再拦截
+	approvalMu         sync.Mutex
+
+	// 最近一次AI调用时间，见 reserveAICall
+	lastAICallAt time.Time
+	aiCallMu     sync.Mutex
 }
 
 // markStrategyClosed 【功能】将策略标记为已关闭（避免后续继续补单/检查）
@@ -530,8 +749,11 @@ func (at *AutoTrader) hydrateClosedStrategiesFromDB() {
 		if s == nil {
 			continue
 		}
-		if strings.ToUpper(strings.TrimSpace(s.Status)) == "CLOSED" {
+		switch strings.ToUpper(strings.TrimSpace(s.Status)) {
+		case "CLOSED":
 			at.markStrategyClosed(s.StrategyID)
+		case strategyStatusTP1Hit:
+			at.markTP1ScaledOut(s.StrategyID)
 		}
 	}
 }
@@ -541,7 +763,7 @@ func (at *AutoTrader) auditPositionsAndCloseFinishedStrategies() {
 	if at == nil || at.database == nil {
 		return
 	}
-	db, ok := at.database.(*sysconfig.Database)
+	db, ok := at.database.(strategyStatusStore)
 	if !ok {
 		return
 	}
@@ -555,19 +777,7 @@ func (at *AutoTrader) auditPositionsAndCloseFinishedStrategies() {
 	if err != nil {
 		return
 	}
-	posQtyBySymbol := make(map[string]float64)
-	for _, p := range positions {
-		sym, _ := p["symbol"].(string)
-		sym = strings.ToUpper(strings.TrimSpace(sym))
-		if sym == "" {
-			continue
-		}
-		amt, _ := p["positionAmt"].(float64)
-		if amt == 0 {
-			continue
-		}
-		posQtyBySymbol[sym] = math.Abs(amt)
-	}
+	posQtyBySymbol := positionQtyBySymbol(positions)
 
 	for _, st := range statuses {
 		if st == nil {
@@ -621,10 +831,110 @@ func (at *AutoTrader) auditPositionsAndCloseFinishedStrategies() {
 				at.id, st.StrategyID, sym, errNormal, errStops)
 		}
 
-		at.updateStrategyStatus(st.StrategyID, sym, "CLOSED", 0, 0, 0)
+		prevStatus := st.Status
+		st.Status = "CLOSED"
+		st.EntryPrice, st.Quantity, st.RealizedPnL = 0, 0, 0
+		if err := db.UpdateTraderStrategyStatus(st); err != nil {
+			log.Printf("⚠️ 更新策略状态失败: %v", err)
+		}
 		at.markStrategyClosed(st.StrategyID)
 		log.Printf("[position-audit] strategy closed and orders canceled due to missing position: trader=%s strategy=%s symbol=%s prev_status=%s",
-			at.id, st.StrategyID, sym, st.Status)
+			at.id, st.StrategyID, sym, prevStatus)
+	}
+}
+
+// strategyStatusStore 策略执行状态的持久化操作（*sysconfig.Database 实现）
+type strategyStatusStore interface {
+	GetTraderStrategyStatuses(traderID string) ([]*sysconfig.TraderStrategyStatus, error)
+	UpdateTraderStrategyStatus(status *sysconfig.TraderStrategyStatus) error
+}
+
+// isEnteredStrategyStatus 策略状态是否表示已进入持仓阶段（ENTRY/ENTERED/ADD_n/TP1_HIT）
+func isEnteredStrategyStatus(status string) bool {
+	status = strings.ToUpper(strings.TrimSpace(status))
+	return status == "ENTRY" || status == "ENTERED" || strings.HasPrefix(status, "ADD_") || status == strategyStatusTP1Hit
+}
+
+// reconcileStrategyStatusesOnStartup 【功能】信号模式启动时按交易所实际持仓/挂单校正未关闭策略的执行状态，
+// 避免重启后重复入场或漏掉补仓：
+//   - 有持仓：未处于持仓阶段的状态改为 ENTRY，并同步数量/均价
+//   - 无持仓但曾进入持仓阶段：仓位已在停机期间结束，撤销残留挂单并标记 CLOSED
+//   - 从未持仓：保持等待状态（入场单可能尚未下出），由信号执行流程继续处理
+func (at *AutoTrader) reconcileStrategyStatusesOnStartup() {
+	if at == nil || at.database == nil {
+		return
+	}
+	store, ok := at.database.(strategyStatusStore)
+	if !ok {
+		return
+	}
+
+	statuses, err := store.GetTraderStrategyStatuses(at.id)
+	if err != nil || len(statuses) == 0 {
+		return
+	}
+
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		log.Printf("⚠️ [%s] 启动校正策略状态：获取持仓失败，跳过: %v", at.name, err)
+		return
+	}
+	posBySymbol := make(map[string]Position)
+	for _, pos := range NormalizePositions(positions) {
+		posBySymbol[strings.ToUpper(pos.Symbol)] = pos
+	}
+
+	for _, st := range statuses {
+		if st == nil || strings.ToUpper(strings.TrimSpace(st.Status)) == "CLOSED" {
+			continue
+		}
+		sym := strings.ToUpper(strings.TrimSpace(st.Symbol))
+		if sym == "" {
+			continue
+		}
+		prevStatus := st.Status
+		entered := isEnteredStrategyStatus(st.Status) || (st.HadPosition != nil && *st.HadPosition)
+
+		if pos, ok := posBySymbol[sym]; ok && pos.Quantity > 0 {
+			if !isEnteredStrategyStatus(st.Status) {
+				st.Status = "ENTRY"
+			}
+			hadPos := true
+			st.HadPosition = &hadPos
+			st.Quantity = pos.Quantity
+			st.EntryPrice = pos.EntryPrice
+			if err := store.UpdateTraderStrategyStatus(st); err != nil {
+				log.Printf("⚠️ [%s] 启动校正策略状态失败: strategy=%s err=%v", at.name, st.StrategyID, err)
+				continue
+			}
+			log.Printf("🔄 [%s] 启动校正: 策略 %s (%s) 有持仓 %.4f，状态 %s -> %s", at.name, st.StrategyID, sym, pos.Quantity, prevStatus, st.Status)
+			continue
+		}
+
+		if !entered {
+			continue
+		}
+
+		// 只有确实存在残留挂单时才撤单（查询失败时不撤，避免误撤其它策略的单）
+		openOrders, err := at.trader.GetOpenOrders(sym)
+		if err != nil {
+			log.Printf("⚠️ [%s] 启动校正：获取 %s 挂单失败: %v", at.name, sym, err)
+		} else if len(openOrders) > 0 {
+			errNormal := at.trader.CancelAllOrders(sym)
+			errStops := at.trader.CancelStopOrders(sym)
+			if errNormal != nil || errStops != nil {
+				log.Printf("WARN: cancel orders on startup reconcile: trader=%s strategy=%s symbol=%s err_normal=%v err_stops=%v",
+					at.id, st.StrategyID, sym, errNormal, errStops)
+			}
+		}
+		st.Status = "CLOSED"
+		st.Quantity = 0
+		if err := store.UpdateTraderStrategyStatus(st); err != nil {
+			log.Printf("⚠️ [%s] 启动校正策略状态失败: strategy=%s err=%v", at.name, st.StrategyID, err)
+			continue
+		}
+		at.markStrategyClosed(st.StrategyID)
+		log.Printf("🔄 [%s] 启动校正: 策略 %s (%s) 持仓已在停机期间结束，状态 %s -> CLOSED", at.name, st.StrategyID, sym, prevStatus)
 	}
 }
 
@@ -686,6 +996,68 @@ func (at *AutoTrader) SetCrossMarginMode(isCross bool) {
 	at.config.IsCrossMargin = isCross
 }
 
+// SetStopLossGuard 【功能】更新运行中交易员的开仓止损保护配置（无需重启）
+func (at *AutoTrader) SetStopLossGuard(requireStopLoss bool, defaultStopLossPct float64) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.RequireStopLoss = requireStopLoss
+	if defaultStopLossPct >= 0 {
+		at.config.DefaultStopLossPct = defaultStopLossPct
+	}
+}
+
+// SetExcludeHeldFromCandidates 【功能】更新运行中交易员是否从候选币种中剔除已持仓币种（无需重启）
+func (at *AutoTrader) SetExcludeHeldFromCandidates(exclude bool) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.ExcludeHeldFromCandidates = exclude
+}
+
+// SetAnalysisOnly 【功能】切换运行中交易员的仅分析模式（无需重启）
+func (at *AutoTrader) SetAnalysisOnly(analysisOnly bool) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.AnalysisOnly = analysisOnly
+}
+
+// SetWarmupMinutes 【功能】更新预热时长（无需重启，按本次运行的启动时间计算）
+func (at *AutoTrader) SetWarmupMinutes(minutes int) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.WarmupMinutes = minutes
+}
+
+// warmupRemaining 返回本次运行剩余的预热时间（<=0 表示未配置预热或预热已结束）
+// 预热以 Run() 记录的 startTime 为起点，每次启动重新计算，不做持久化
+func (at *AutoTrader) warmupRemaining(now time.Time) time.Duration {
+	at.mu.RLock()
+	minutes := at.config.WarmupMinutes
+	at.mu.RUnlock()
+	if minutes <= 0 {
+		return 0
+	}
+	return at.startTime.Add(time.Duration(minutes) * time.Minute).Sub(now)
+}
+
+// IsAnalysisOnly 是否处于仅分析模式
+func (at *AutoTrader) IsAnalysisOnly() bool {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return at.config.AnalysisOnly
+}
+
 // GetTrader 获取底层交易器接口（用于直接调用交易方法）
 func (at *AutoTrader) GetTrader() Trader {
 	return at.trader
@@ -724,6 +1096,7 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 	if config.AIModel == "custom" {
 		// 使用自定义API
 		mcpClient.SetCustomAPI(config.CustomAPIURL, config.CustomAPIKey, config.CustomModelName)
+		mcpClient.SetCustomHeaders(config.CustomHeaders, config.CustomAuthScheme)
 		log.Printf("🤖 [%s] 使用自定义AI API: %s (模型: %s)", config.Name, config.CustomAPIURL, config.CustomModelName)
 	} else if config.UseQwen || config.AIModel == "qwen" {
 		// 使用Qwen (支持自定义URL和Model)
@@ -743,20 +1116,22 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 		}
 	}
 
+	mcpClient.SetSamplingParams(mcp.SamplingParams{Temperature: config.AITemperature, TopP: config.AITopP, MaxTokens: config.AIMaxTokens})
+
 	// 初始化币种池API
 	if config.CoinPoolAPIURL != "" {
 		pool.SetCoinPoolAPI(config.CoinPoolAPIURL)
 	}
+	if len(config.SignalSources) > 0 {
+		pool.SetSignalSources(config.SignalSources)
+		log.Printf("📡 [%s] 启用多信号源合并: %d 个信号源", config.Name, len(config.SignalSources))
+	}
 
 	// 设置默认交易平台
 	if config.Exchange == "" {
 		config.Exchange = "binance"
 	}
 
-	// 根据配置创建对应的交易器
-	var trader Trader
-	var err error
-
 	// 记录仓位模式（通用）
 	marginModeStr := "全仓"
 	if !config.IsCrossMargin {
@@ -764,27 +1139,23 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 	}
 	log.Printf("📊 [%s] 仓位模式: %s", config.Name, marginModeStr)
 
-	switch config.Exchange {
-	case "binance":
-		log.Printf("🏦 [%s] 使用币安合约交易", config.Name)
-		trader = NewFuturesTrader(config.BinanceAPIKey, config.BinanceSecretKey, userID)
-	case "hyperliquid":
-		log.Printf("🏦 [%s] 使用Hyperliquid交易", config.Name)
-		trader, err = NewHyperliquidTrader(config.HyperliquidPrivateKey, config.HyperliquidWalletAddr, config.HyperliquidTestnet)
-		if err != nil {
-			return nil, fmt.Errorf("初始化Hyperliquid交易器失败: %w", err)
-		}
-	case "aster":
-		log.Printf("🏦 [%s] 使用Aster交易", config.Name)
-		trader, err = NewAsterTrader(config.AsterUser, config.AsterSigner, config.AsterPrivateKey)
+	// 根据配置创建对应的交易器
+	trader, err := newExchangeTrader(config, userID)
+	if err != nil {
+		return nil, err
+	}
+	// 配置了备用密钥时包装为故障切换交易器
+	if config.BackupExchange != nil {
+		backupConfig := *config.BackupExchange
+		backupConfig.Name = config.Name + "(备用)"
+		backupConfig.Exchange = config.Exchange
+		backup, err := newExchangeTrader(backupConfig, userID)
 		if err != nil {
-			return nil, fmt.Errorf("初始化Aster交易器失败: %w", err)
+			log.Printf("⚠️ [%s] 初始化备用密钥失败，不启用故障切换: %v", config.Name, err)
+		} else {
+			log.Printf("🔑 [%s] 已配置备用API密钥，主密钥持续鉴权/IP失败时自动切换", config.Name)
+			trader = newFailoverTrader(config.Name, trader, backup)
 		}
-	case "bitget":
-		log.Printf("🏦 [%s] 使用Bitget合约交易", config.Name)
-		trader = NewBitgetTrader(config.BitgetAPIKey, config.BitgetSecretKey, config.BitgetPassphrase, config.BitgetTestnet)
-	default:
-		return nil, fmt.Errorf("不支持的交易平台: %s", config.Exchange)
 	}
 
 	// 验证初始金额配置
@@ -821,6 +1192,8 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 		callCount:             0,
 		isRunning:             false,
 		positionFirstSeenTime: make(map[string]int64),
+		positionAgeNotified:   make(map[string]int64),
+		positionPyramid:       make(map[string]pyramidState),
 		stopMonitorCh:         make(chan struct{}),
 		monitorWg:             sync.WaitGroup{},
 		peakPnLCache:          make(map[string]float64),
@@ -831,6 +1204,54 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 	}, nil
 }
 
+// newExchangeTrader 根据配置中的交易平台和凭证创建对应的交易器
+func newExchangeTrader(config AutoTraderConfig, userID string) (Trader, error) {
+	var trader Trader
+	var err error
+	switch config.Exchange {
+	case "binance":
+		log.Printf("🏦 [%s] 使用币安合约交易", config.Name)
+		trader = NewFuturesTrader(config.BinanceAPIKey, config.BinanceSecretKey, userID)
+	case "hyperliquid":
+		log.Printf("🏦 [%s] 使用Hyperliquid交易", config.Name)
+		trader, err = NewHyperliquidTrader(config.HyperliquidPrivateKey, config.HyperliquidWalletAddr, config.HyperliquidTestnet)
+		if err != nil {
+			return nil, fmt.Errorf("初始化Hyperliquid交易器失败: %w", err)
+		}
+	case "aster":
+		log.Printf("🏦 [%s] 使用Aster交易", config.Name)
+		trader, err = NewAsterTrader(config.AsterUser, config.AsterSigner, config.AsterPrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("初始化Aster交易器失败: %w", err)
+		}
+	case "bitget":
+		log.Printf("🏦 [%s] 使用Bitget合约交易", config.Name)
+		trader = NewBitgetTrader(config.BitgetAPIKey, config.BitgetSecretKey, config.BitgetPassphrase, config.BitgetTestnet)
+	default:
+		return nil, fmt.Errorf("不支持的交易平台: %s", config.Exchange)
+	}
+	return trader, nil
+}
+
+// warnUnresolvedPlaceholders 检查本交易员将使用的提示词模板，存在无法替换的占位符时记录告警
+func (at *AutoTrader) warnUnresolvedPlaceholders() {
+	at.mu.RLock()
+	templates := []string{at.systemPromptTemplate}
+	at.mu.RUnlock()
+	if at.isSignalMode() {
+		templates = append(templates, "strategy_executor")
+	}
+	for _, name := range templates {
+		unresolved, err := decision.ValidateTemplate(name)
+		if err != nil {
+			continue
+		}
+		if len(unresolved) > 0 {
+			log.Printf("⚠️ [%s] 提示词模板 %s 包含无法替换的占位符 %v，将原样发送给AI", at.name, name, unresolved)
+		}
+	}
+}
+
 // GetConfig returns the trader configuration
 func (at *AutoTrader) GetConfig() *AutoTraderConfig {
 	if at == nil {
@@ -844,6 +1265,13 @@ func (at *AutoTrader) Run() error {
 	at.isRunning = true
 	at.stopMonitorCh = make(chan struct{})
 	at.startTime = time.Now()
+	at.clearDrawdownHalt()
+	at.lastTransferCheckAt = at.startTime.UnixMilli()
+	at.loadPositionFirstSeen()
+	at.loadPyramidState()
+	at.loadDrawdownState()
+	at.loadFirstTradeApproval()
+	at.loadEquityBracketPause()
 
 	log.Println("🚀 AI驱动自动交易系统启动")
 	log.Printf("💰 初始余额: %.2f USDT", at.initialBalance)
@@ -851,8 +1279,26 @@ func (at *AutoTrader) Run() error {
 	at.monitorWg.Add(1)
 	defer at.monitorWg.Done()
 
-	// 模式选择：如果有 Gmail 配置且启用，或者全局信号管理器已启动，则进入信号模式
-	if (at.config.Gmail != nil && at.config.Gmail.Enabled) || signal.GlobalManager != nil {
+	// 首个周期前先检查一次净值止盈止损，避免停机期间越过阈值后照常交易到第一次定时检查
+	at.checkEquityBracket()
+
+	// 【功能】账户净值止盈止损及最大回撤硬止损监控（未配置时不做任何处理，自主决策与信号模式均生效）
+	at.startEquityBracketMonitor()
+
+	// 按交易所服务器时间校准签名时间戳（启动时及定期校准，修正本地时钟漂移）
+	at.startServerClockSync()
+
+	// 启动前检查提示词模板中无法替换的占位符（只告警，不阻止启动）
+	at.warnUnresolvedPlaceholders()
+	at.warnReprotectUnsupported()
+
+	// 模式选择：配置了目标权重时进入再平衡模式（按交易员显式配置，优先于全局信号模式）
+	if at.isRebalanceMode() {
+		return at.RunRebalanceMode()
+	}
+
+	// 如果有 Gmail 配置且启用，或者全局信号管理器已启动，则进入信号模式
+	if at.isSignalMode() {
 		log.Println("📧 模式: 信号跟随模式 (Web3团队策略)")
 		return at.RunSignalMode()
 	}
@@ -861,11 +1307,14 @@ func (at *AutoTrader) Run() error {
 	log.Printf("⚙️  扫描间隔: %v", at.config.ScanInterval)
 	log.Println("🤖 AI将全权决定杠杆、仓位大小、止损止盈等参数")
 
-	// 【功能】回撤监控（默认关闭，仅在显式开启时启用）
-	if at.config.EnableDrawdownMonitor {
+	// 【功能】回撤监控（默认关闭，仅在显式开启时启用；仅分析模式下不自动平仓）
+	if at.config.EnableDrawdownMonitor && !at.IsAnalysisOnly() {
 		at.startDrawdownMonitor()
 	}
 
+	// 【功能】持仓时长监控（MaxPositionAgeHours=0 时不做任何处理，支持运行时修改）
+	at.startPositionAgeMonitor()
+
 	// 循环执行：等待对齐 -> 执行 -> 等待对齐...
 	for at.isRunning {
 		// 1. 等待直到下一个整点间隔（+5秒延迟）以获取闭合K线
@@ -874,8 +1323,13 @@ func (at *AutoTrader) Run() error {
 			return nil
 		}
 
-		// 2. 执行决策周期
-		if err := at.runCycle(); err != nil {
+		// 2. 执行决策周期（与手动触发的周期互斥）
+		var err error
+		if lockErr := at.runExclusiveCycle(func() { err = at.runCycle() }); lockErr != nil {
+			log.Printf("⏭ [%s] %v", at.name, lockErr)
+			continue
+		}
+		if err != nil {
 			log.Printf("❌ 执行失败: %v", err)
 		}
 	}
@@ -883,6 +1337,100 @@ func (at *AutoTrader) Run() error {
 	return nil
 }
 
+// isSignalMode 是否运行在信号跟随模式
+func (at *AutoTrader) isSignalMode() bool {
+	return (at.config.Gmail != nil && at.config.Gmail.Enabled) || signal.GlobalManager != nil
+}
+
+// manualCycleCooldown 手动触发决策周期的最小间隔
+const manualCycleCooldown = 30 * time.Second
+
+// ErrManualCycleTooFrequent 手动触发决策周期过于频繁
+var ErrManualCycleTooFrequent = errors.New("手动触发过于频繁，请稍后再试")
+
+// ErrCycleInProgress 已有决策周期在执行（SkipCycleIfBusy 开启时返回）
+var ErrCycleInProgress = errors.New("决策周期执行中，本次触发已跳过")
+
+// 锁使用约定：
+//   - mu：只保护运行时可热更新的配置字段（提示词、杠杆、交易选项等），持有时间极短，
+//     不得在持有 mu 时调用交易所或AI接口
+//   - cycleMu：串行化 AI 决策周期（定时循环 runCycle 与手动触发 RunOnce），
+//     保证同一时刻只有一个“决策+执行”序列在运行；通过 runExclusiveCycle 获取
+//   - symbolLocks：信号模式下按币种串行化 CheckAndExecuteStrategyWithAI（监听回调与定时对账），
+//     通过 runExclusiveForSymbol 获取
+//   - 获取顺序固定为 cycleMu/symbolLocks → mu，禁止在持有 mu 时再获取 cycleMu 或 symbolLocks
+//   - SkipCycleIfBusy 开启时 cycleMu/symbolLocks 使用 TryLock，忙时直接跳过并返回 ErrCycleInProgress，
+//     否则阻塞等待当前周期完成
+
+// runExclusiveCycle 在决策周期锁内执行 fn
+func (at *AutoTrader) runExclusiveCycle(fn func()) error {
+	return at.runExclusive(&at.cycleMu, fn)
+}
+
+// runExclusiveForSymbol 在指定币种的信号执行锁内执行 fn
+func (at *AutoTrader) runExclusiveForSymbol(symbol string, fn func()) error {
+	lock, _ := at.symbolLocks.LoadOrStore(symbol, &sync.Mutex{})
+	return at.runExclusive(lock.(*sync.Mutex), fn)
+}
+
+// runExclusive 按 SkipCycleIfBusy 策略获取锁（跳过或等待）后执行 fn
+func (at *AutoTrader) runExclusive(lock *sync.Mutex, fn func()) error {
+	at.mu.RLock()
+	skipIfBusy := at.config.SkipCycleIfBusy
+	at.mu.RUnlock()
+
+	if skipIfBusy {
+		if !lock.TryLock() {
+			return ErrCycleInProgress
+		}
+	} else {
+		lock.Lock()
+	}
+	defer lock.Unlock()
+
+	fn()
+	return nil
+}
+
+// SetSkipCycleIfBusy 【功能】更新周期冲突策略（true=跳过，false=等待）
+func (at *AutoTrader) SetSkipCycleIfBusy(skip bool) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.SkipCycleIfBusy = skip
+}
+
+// RunOnce 立即执行一次决策周期（不等待周期对齐），返回本周期的决策记录
+// 与主循环共用 cycleMu，如果主循环正在执行周期，会等待其完成后再执行（SkipCycleIfBusy 开启时返回 ErrCycleInProgress）
+func (at *AutoTrader) RunOnce() (*logger.DecisionRecord, error) {
+	if !at.isRunning {
+		return nil, fmt.Errorf("交易员未运行")
+	}
+	if at.isSignalMode() {
+		return nil, fmt.Errorf("信号跟随模式下不支持手动触发决策周期")
+	}
+
+	at.mu.Lock()
+	if wait := manualCycleCooldown - time.Since(at.lastManualCycleTime); wait > 0 {
+		at.mu.Unlock()
+		return nil, fmt.Errorf("%w（%.0f秒后可再次触发）", ErrManualCycleTooFrequent, wait.Seconds())
+	}
+	at.lastManualCycleTime = time.Now()
+	at.mu.Unlock()
+
+	var record *logger.DecisionRecord
+	var err error
+	if lockErr := at.runExclusiveCycle(func() {
+		log.Printf("▶️ [%s] 手动触发决策周期", at.name)
+		record, err = at.runCycleWithRecord()
+	}); lockErr != nil {
+		return nil, lockErr
+	}
+	return record, err
+}
+
 // Stop 停止自动交易
 func (at *AutoTrader) Stop() {
 	if !at.isRunning {
@@ -892,6 +1440,7 @@ func (at *AutoTrader) Stop() {
 	close(at.stopMonitorCh) // 通知监控goroutine停止
 	at.monitorWg.Wait()     // 等待监控goroutine结束
 	log.Println("⏹ 自动交易系统停止")
+	at.emitEvent(logger.EventTraderStopped, fmt.Sprintf("⏹ [%s] 交易员已停止", at.name), nil)
 }
 
 // waitUntilNextInterval 等待直到下一个时间间隔点（带延迟）
@@ -929,10 +1478,72 @@ func (at *AutoTrader) waitUntilNextInterval() bool {
 	}
 }
 
-// autoSyncBalanceIfNeeded 自动同步余额（每10分钟检查一次，变化>5%才更新）
-func (at *AutoTrader) autoSyncBalanceIfNeeded() {
-	// 距离上次同步不足10分钟，跳过
-	if time.Since(at.lastBalanceSyncTime) < 10*time.Minute {
+// transferCheckInterval 资金流水检查间隔
+const transferCheckInterval = 10 * time.Minute
+
+// sumExternalTransfers 汇总资金流水中 USDT 的充值/提现净额（交易盈亏、资金费、手续费不计入）
+func sumExternalTransfers(history []map[string]interface{}) float64 {
+	net := 0.0
+	for _, entry := range history {
+		if asset, _ := entry["asset"].(string); asset != "" && asset != "USDT" {
+			continue
+		}
+		eventType, _ := entry["type"].(string)
+		if eventType != BalanceEventDeposit && eventType != BalanceEventWithdraw {
+			continue
+		}
+		amount, _ := entry["amount"].(float64)
+		net += amount
+	}
+	return net
+}
+
+// adjustInitialBalanceForTransfers 检测运行期间的外部充值/提现，并等额调整 initialBalance
+// 与 autoSyncBalanceIfNeeded 直接用当前余额覆盖不同，这里只叠加资金流水中的转入/转出净额，已实现盈亏保持不变
+func (at *AutoTrader) adjustInitialBalanceForTransfers() {
+	now := time.Now().UnixMilli()
+	if at.lastTransferCheckAt == 0 {
+		at.lastTransferCheckAt = now
+		return
+	}
+	if time.Duration(now-at.lastTransferCheckAt)*time.Millisecond < transferCheckInterval {
+		return
+	}
+
+	history, err := at.trader.GetBalanceHistory(at.lastTransferCheckAt, now)
+	if err != nil {
+		log.Printf("⚠️ [%s] 查询资金流水失败: %v", at.name, err)
+		return
+	}
+	at.lastTransferCheckAt = now + 1
+
+	net := sumExternalTransfers(history)
+	if net == 0 {
+		return
+	}
+
+	oldBalance := at.initialBalance
+	at.initialBalance += net
+	log.Printf("💸 [%s] 检测到外部资金变动 %+.2f USDT，初始余额 %.2f → %.2f", at.name, net, oldBalance, at.initialBalance)
+	at.adjustPeriodBaselineForTransfers(net)
+	at.adjustPeakEquityForTransfers(net)
+
+	type InitialBalanceAdjuster interface {
+		AdjustTraderInitialBalance(id string, delta float64) error
+	}
+	if db, ok := at.database.(InitialBalanceAdjuster); ok {
+		if err := db.AdjustTraderInitialBalance(at.id, net); err != nil {
+			log.Printf("❌ [%s] 更新数据库初始余额失败: %v", at.name, err)
+		}
+	} else {
+		log.Printf("⚠️ [%s] 数据库引用不可用，初始余额仅在内存中调整", at.name)
+	}
+}
+
+// autoSyncBalanceIfNeeded 自动同步余额（每10分钟检查一次，变化>5%才更新）
+func (at *AutoTrader) autoSyncBalanceIfNeeded() {
+	// 距离上次同步不足10分钟，跳过
+	if time.Since(at.lastBalanceSyncTime) < 10*time.Minute {
 		return
 	}
 
@@ -1026,6 +1637,33 @@ func (at *AutoTrader) autoSyncBalanceIfNeeded() {
 
 // runCycle 运行一个交易周期（使用AI全权决策）
 func (at *AutoTrader) runCycle() error {
+	_, err := at.runCycleWithRecord()
+	return err
+}
+
+// skipCycle 跳过本周期的AI决策（非交易时段、极端行情、AI调用限流等）：记录原因，
+// 非仅分析模式下仍执行持仓保护单校验，并保存决策记录
+func (at *AutoTrader) skipCycle(record *logger.DecisionRecord, reason string) {
+	log.Printf("[%s] %s，不开新仓", at.name, reason)
+	record.ExecutionLog = append(record.ExecutionLog, reason)
+	if !at.IsAnalysisOnly() {
+		at.reprotectPositions(record)
+	}
+	if err := at.decisionLogger.LogDecision(record); err != nil {
+		log.Printf("⚠ 保存决策记录失败: %v", err)
+	}
+}
+
+// runCycleWithRecord 运行一个交易周期，并返回本周期的决策记录（开启链路追踪时整个周期记录为一条 trace）
+func (at *AutoTrader) runCycleWithRecord() (*logger.DecisionRecord, error) {
+	cycleSpan := at.startCycleSpan()
+	record, err := at.runTracedCycle(cycleSpan)
+	at.endCycleSpan(cycleSpan, record, err)
+	return record, err
+}
+
+// runTracedCycle 交易周期主体：构建上下文、AI调用、逐个动作执行分别记录为 cycleSpan 的子 span
+func (at *AutoTrader) runTracedCycle(cycleSpan *tracing.Span) (*logger.DecisionRecord, error) {
 	at.callCount++
 
 	log.Print("\n" + strings.Repeat("=", 70) + "\n")
@@ -1067,13 +1705,33 @@ func (at *AutoTrader) runCycle() error {
 		record.Success = false
 		record.ErrorMessage = fmt.Sprintf("风险控制暂停中，剩余 %.0f 分钟", remaining.Minutes())
 		at.decisionLogger.LogDecision(record)
-		return nil
+		return record, nil
+	}
+	if hit := at.equityBracketPaused(); hit != "" {
+		log.Printf("⏸ 账户净值%s已触发，暂停交易（手动启动交易员或修改阈值后恢复）", equityBracketLabel(hit))
+		record.Success = false
+		record.ErrorMessage = fmt.Sprintf("账户净值%s已触发，暂停交易中", equityBracketLabel(hit))
+		at.decisionLogger.LogDecision(record)
+		return record, nil
+	}
+	if until, active := at.exchangeMaintenanceActive(time.Now()); active {
+		log.Printf("🚧 交易所维护中，跳过本周期（%s 后重试）", until.Format(time.RFC3339))
+		record.Success = false
+		record.ErrorMessage = fmt.Sprintf("交易所维护中（exchange_maintenance），暂停至 %s", until.Format(time.RFC3339))
+		at.decisionLogger.LogDecision(record)
+		return record, nil
 	}
 
-	// 2. 重置日盈亏（每天重置）
-	if time.Since(at.lastResetTime) > 24*time.Hour {
-		at.dailyPnL = 0
-		at.lastResetTime = time.Now()
+	// 2. 重置日盈亏（UTC日切时重置，当日起始净值在此之前保持不变）
+	if !utcDayStart(time.Now()).Equal(utcDayStart(at.lastResetTime)) {
+		at.emitEvent(logger.EventDailySummary,
+			fmt.Sprintf("📅 [%s] 日终汇总: 当日盈亏 %+.2f USDT", at.name, at.dailyPnL),
+			map[string]interface{}{
+				"daily_pnl":       at.dailyPnL,
+				"day_start":       at.lastResetTime,
+				"initial_balance": at.initialBalance,
+			})
+		at.resetDailyPnL()
 		log.Println("📅 日盈亏已重置")
 	}
 
@@ -1082,15 +1740,42 @@ func (at *AutoTrader) runCycle() error {
 	// 例如：用户设置初始余额200，实际余额130（亏70），但自动同步后initialBalance变成130，显示盈利0而不是亏损70
 	// 如果需要同步余额，请使用手动同步功能（API: POST /traders/:id/sync-balance）
 	// at.autoSyncBalanceIfNeeded()
+	// 改为根据交易所资金流水识别充值/提现，只按外部资金变动调整初始余额，不影响盈亏基准
+	at.adjustInitialBalanceForTransfers()
+	// 按 baseline_reset_policy 进入新的周/月时记录新的周期净值基准（不影响初始余额）
+	at.rollBaselineIfDue(time.Now())
+
+	// 非交易时段：不请求AI、不开新仓，只做持仓保护
+	if !at.inTradingWindow(time.Now()) {
+		at.skipCycle(record, "🌙 非交易时段，跳过AI决策")
+		return record, nil
+	}
+
+	// 极端行情：同样跳过AI决策，只做持仓保护
+	if reason := at.marketConditionSkipReason(); reason != "" {
+		at.skipCycle(record, "🌪️ 极端行情，跳过AI决策: "+reason)
+		return record, nil
+	}
 
 	// 4. 收集交易上下文
+	contextSpan := cycleSpan.StartChild("build_context", at.spanAttributes())
 	ctx, err := at.buildTradingContext()
+	contextSpan.SetError(err)
+	contextSpan.End()
 	if err != nil {
 		record.Success = false
+		// 交易所维护：按更长的退避时长暂停，不作为普通失败返回
+		if isExchangeMaintenanceError(at.exchange, err) {
+			until := at.enterExchangeMaintenance(err, time.Now())
+			record.ErrorMessage = fmt.Sprintf("交易所维护中（exchange_maintenance），暂停至 %s: %v", until.Format(time.RFC3339), err)
+			at.decisionLogger.LogDecision(record)
+			return record, nil
+		}
 		record.ErrorMessage = fmt.Sprintf("构建交易上下文失败: %v", err)
 		at.decisionLogger.LogDecision(record)
-		return fmt.Errorf("构建交易上下文失败: %w", err)
+		return record, fmt.Errorf("构建交易上下文失败: %w", err)
 	}
+	at.clearExchangeMaintenance()
 
 	// 保存账户状态快照
 	record.AccountState = logger.AccountSnapshot{
@@ -1123,6 +1808,16 @@ func (at *AutoTrader) runCycle() error {
 	log.Printf("📊 账户净值: %.2f USDT | 可用: %.2f USDT | 持仓: %d",
 		ctx.Account.TotalEquity, ctx.Account.AvailableBalance, ctx.Account.PositionCount)
 
+	// 🛑 日亏损硬止损（代码级风控，不依赖AI判断）
+	if at.checkDailyLossStop(ctx.Account.TotalEquity, ctx.Positions, time.Now()) {
+		record.Success = false
+		record.ErrorMessage = fmt.Sprintf("触发日亏损硬止损，暂停交易至 %s", at.stopUntil.Format(time.RFC3339))
+		at.decisionLogger.LogDecision(record)
+		return record, nil
+	}
+	// 🛑 最大回撤硬止损（更新净值历史峰值；触发后只拦截开仓，AI仍可管理已有持仓）
+	at.updateDrawdownStop(ctx.Account.TotalEquity)
+
 	// 5. 读取当前提示词配置（加锁保护）
 	at.mu.Lock()
 	customPrompt := at.customPrompt
@@ -1130,9 +1825,18 @@ func (at *AutoTrader) runCycle() error {
 	systemPromptTemplate := at.systemPromptTemplate
 	at.mu.Unlock()
 
+	// AI调用间隔不足：跳过本次调用，只做持仓保护
+	if ok, remaining := at.reserveAICall(time.Now(), false); !ok {
+		at.skipCycle(record, fmt.Sprintf("⏳ 距上次AI调用不足最小间隔，跳过AI决策（剩余 %.0f 秒）", remaining.Seconds()))
+		return record, nil
+	}
+
 	// 6. 调用AI获取完整决策
 	log.Printf("🤖 正在请求AI分析并决策... [模板: %s, 覆盖基础: %v]", systemPromptTemplate, overrideBasePrompt)
+	aiSpan := cycleSpan.StartChild("ai_call", at.spanAttributes())
 	decision, err := decision.GetFullDecisionWithCustomPrompt(ctx, at.mcpClient, customPrompt, overrideBasePrompt, systemPromptTemplate)
+	at.countAICallToday()
+	endAICallSpan(aiSpan, decision, err)
 
 	// 即使有错误，也保存思维链、决策和输入prompt（用于debug）
 	if decision != nil {
@@ -1140,6 +1844,9 @@ func (at *AutoTrader) runCycle() error {
 		record.InputPrompt = decision.UserPrompt      // 保存输入提示词
 		record.RawAIResponse = decision.RawAIResponse // 保存AI原始响应（未裁剪）
 		record.CoTTrace = decision.CoTTrace           // 保存思维链（裁剪后）
+		record.AIProvider = string(decision.Served.Provider)
+		record.AIModelName = decision.Served.Model
+		record.WasFallback = decision.Served.WasFallback
 
 		// 🔍 调试：打印字段长度确认数据已保存
 		log.Printf("📝 决策记录字段长度: SystemPrompt=%d, InputPrompt=%d, CoTTrace=%d",
@@ -1172,9 +1879,18 @@ func (at *AutoTrader) runCycle() error {
 			}
 		}
 
+		// 按 on_ai_failure 策略处理（hold/flatten/pause），处理结果记录到决策日志
+		failureActions, failureLog := at.handleAIFailure(err)
+		record.Decisions = append(record.Decisions, failureActions...)
+		record.ExecutionLog = append(record.ExecutionLog, failureLog...)
+
 		at.decisionLogger.LogDecision(record)
-		return fmt.Errorf("获取AI决策失败: %w", err)
+		return record, fmt.Errorf("获取AI决策失败: %w", err)
 	}
+	at.clearAIFailurePause()
+	at.recordUnavailablePositions(record, ctx.Positions)
+	at.detectAIAnomaly(record, decision.Decisions, len(ctx.CandidateCoins))
+	at.recordSymbolDecisions(decision.Decisions)
 
 	// 5. 打印系统提示词（用于调试自定义提示词）
 	log.Print("\n" + strings.Repeat("=", 70) + "\n")
@@ -1211,25 +1927,85 @@ func (at *AutoTrader) runCycle() error {
 	// 8. 对决策排序：确保先平仓后开仓（防止仓位叠加超限）
 	sortedDecisions := sortDecisionsByPriority(decision.Decisions)
 
+	// 动作数量上限：排序后截取，平仓/调整止损等高优先级动作优先保留
+	sortedDecisions, droppedDecisions := capDecisionActions(sortedDecisions, at.maxActionsPerCycle())
+
 	log.Println("🔄 执行顺序（已优化）: 先平仓→后开仓")
 	for i, d := range sortedDecisions {
 		log.Printf("  [%d] %s %s", i+1, d.Symbol, d.Action)
 	}
 	log.Println()
 
+	// 仅分析模式：记录决策但跳过执行
+	analysisOnly := at.IsAnalysisOnly()
+	if analysisOnly {
+		log.Println("⏸ 仅分析模式：以下决策只记录不执行")
+	}
+
+	// 预热期：启动后 WarmupMinutes 内只记录决策不执行，等待指标和币种池稳定
+	warmupLeft := at.warmupRemaining(time.Now())
+	inWarmup := !analysisOnly && warmupLeft > 0
+	if inWarmup {
+		log.Printf("⏳ 预热期（剩余 %v）：以下决策只记录不执行", warmupLeft.Round(time.Second))
+	}
+
 	// 执行决策并记录结果
+	var pendingActions []string
 	for _, d := range sortedDecisions {
 		actionRecord := logger.DecisionAction{
-			Action:    d.Action,
-			Symbol:    d.Symbol,
-			Quantity:  0,
-			Leverage:  d.Leverage,
-			Price:     0,
-			Timestamp: time.Now(),
-			Success:   false,
+			Action:     d.Action,
+			Symbol:     d.Symbol,
+			Quantity:   0,
+			Leverage:   d.Leverage,
+			Price:      0,
+			Confidence: d.Confidence,
+			Timestamp:  time.Now(),
+			Success:    false,
+		}
+
+		// 信心度不足的开仓/加仓降级为 wait（仅分析模式下同样不再推送）
+		if at.confidenceGated(&d) {
+			actionRecord.Reasoning = d.Reasoning
+			actionRecord.Status = "confidence_gated"
+			actionRecord.Note = fmt.Sprintf("信心度 %d 低于阈值 %d，%s 降级为 wait", d.Confidence, at.minConfidence(), d.Action)
+			actionRecord.Action = "wait"
+			log.Printf("  🚫 %s %s", d.Symbol, actionRecord.Note)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🚫 %s %s", d.Symbol, actionRecord.Note))
+			record.Decisions = append(record.Decisions, actionRecord)
+			continue
+		}
+
+		if analysisOnly {
+			actionRecord.Reasoning = d.Reasoning
+			actionRecord.Status = "not_executed"
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⏸ %s %s 未执行（仅分析模式）", d.Symbol, d.Action))
+			if d.Action != "hold" && d.Action != "wait" {
+				pendingActions = append(pendingActions, formatAnalysisOnlyAction(&d))
+			}
+			record.Decisions = append(record.Decisions, actionRecord)
+			continue
+		}
+
+		if inWarmup {
+			actionRecord.Reasoning = d.Reasoning
+			actionRecord.Status = "warmup_skipped"
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⏳ %s %s 未执行（预热期）", d.Symbol, d.Action))
+			record.Decisions = append(record.Decisions, actionRecord)
+			continue
+		}
+
+		if maintenanceBlocksOpen(d.Action) {
+			actionRecord.Reasoning = d.Reasoning
+			actionRecord.Status = "maintenance_skipped"
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🛠 %s %s 未执行（平台维护中）", d.Symbol, d.Action))
+			record.Decisions = append(record.Decisions, actionRecord)
+			continue
 		}
 
-		if err := at.executeDecisionWithRecord(&d, &actionRecord); err != nil {
+		actionSpan := cycleSpan.StartChild("execute_action", at.spanAttributes())
+		err := at.executeDecisionWithRecord(&d, &actionRecord)
+		endActionSpan(actionSpan, &actionRecord, err)
+		if err != nil {
 			log.Printf("❌ 执行决策失败 (%s %s): %v", d.Symbol, d.Action, err)
 			actionRecord.Error = err.Error()
 			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 失败: %v", d.Symbol, d.Action, err))
@@ -1242,13 +2018,43 @@ func (at *AutoTrader) runCycle() error {
 
 		record.Decisions = append(record.Decisions, actionRecord)
 	}
+	at.recordCappedDecisions(record, droppedDecisions)
+
+	// 校验持仓保护单是否仍在交易所（可能因部分成交等被交易所撤销），缺失时补设
+	if !analysisOnly && !inWarmup {
+		at.reprotectPositions(record)
+	}
+
+	// 仅分析模式下推送AI建议的操作，供用户手动执行
+	if len(pendingActions) > 0 {
+		logger.Notify(fmt.Sprintf("🔔 [%s] 仅分析模式 - AI建议操作（未执行）:\n%s", at.name, strings.Join(pendingActions, "\n")))
+	}
 
 	// 9. 保存决策记录
 	if err := at.decisionLogger.LogDecision(record); err != nil {
 		log.Printf("⚠ 保存决策记录失败: %v", err)
 	}
 
-	return nil
+	return record, nil
+}
+
+// formatAnalysisOnlyAction 格式化仅分析模式下的单条建议操作（用于通知推送）
+func formatAnalysisOnlyAction(d *decision.Decision) string {
+	line := fmt.Sprintf("• %s %s", d.Symbol, d.Action)
+	switch d.Action {
+	case "open_long", "open_short", "flip_long", "flip_short":
+		line += fmt.Sprintf(" 仓位%.2f USDT %dx", d.PositionSizeUSD, d.Leverage)
+		if d.StopLoss > 0 {
+			line += fmt.Sprintf(" 止损%.4f", d.StopLoss)
+		}
+		if d.TakeProfit > 0 {
+			line += fmt.Sprintf(" 止盈%.4f", d.TakeProfit)
+		}
+	}
+	if d.Reasoning != "" {
+		line += " - " + d.Reasoning
+	}
+	return line
 }
 
 // buildTradingContext 构建交易上下文
@@ -1273,6 +2079,8 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 	if avail, ok := balance["availableBalance"].(float64); ok {
 		availableBalance = avail
 	}
+	// 可用余额为负或低于下限时进入 margin_deficit 状态（暂停开新仓，优先平仓）
+	at.updateMarginDeficit(balance)
 
 	// Total Equity = 钱包余额 + 未实现盈亏
 	totalEquity := totalWalletBalance + totalUnrealizedProfit
@@ -1289,77 +2097,59 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 	// 当前持仓的key集合（用于清理已平仓的记录）
 	currentPositionKeys := make(map[string]bool)
 
-	for _, pos := range positions {
-		symbol := pos["symbol"].(string)
-		side := pos["side"].(string)
-		entryPrice := pos["entryPrice"].(float64)
-		markPrice := pos["markPrice"].(float64)
-		quantity := pos["positionAmt"].(float64)
-		if quantity < 0 {
-			quantity = -quantity // 空仓数量为负，转为正数
-		}
-
+	for _, pos := range NormalizePositions(positions) {
 		// 跳过已平仓的持仓（quantity = 0），防止"幽灵持仓"传递给AI
-		if quantity == 0 {
+		if pos.Quantity == 0 {
 			continue
 		}
 
-		unrealizedPnl := pos["unRealizedProfit"].(float64)
-		liquidationPrice := pos["liquidationPrice"].(float64)
-
 		// 计算占用保证金（估算）
-		leverage := 10 // 默认值，实际应该从持仓信息获取
-		if lev, ok := pos["leverage"].(float64); ok {
-			leverage = int(lev)
-		}
-		marginUsed := (quantity * markPrice) / float64(leverage)
+		marginUsed := pos.MarginUsed()
 		totalMarginUsed += marginUsed
 
 		// 计算盈亏百分比（基于保证金，考虑杠杆）
-		pnlPct := calculatePnLPercentage(unrealizedPnl, marginUsed)
+		pnlPct := calculatePnLPercentage(pos.UnrealizedPnL, marginUsed)
 
 		// 跟踪持仓首次出现时间
-		posKey := symbol + "_" + side
+		posKey := pos.Key()
 		currentPositionKeys[posKey] = true
-		if _, exists := at.positionFirstSeenTime[posKey]; !exists {
-			// 新持仓，记录当前时间
-			at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
-		}
-		updateTime := at.positionFirstSeenTime[posKey]
+		updateTime := at.touchPositionFirstSeen(posKey, time.Now().UnixMilli())
 
 		// 获取该持仓的历史最高收益率
 		at.peakPnLCacheMutex.RLock()
-		peakPnlPct := at.peakPnLCache[symbol]
+		peakPnlPct := at.peakPnLCache[pos.Symbol]
 		at.peakPnLCacheMutex.RUnlock()
 
 		positionInfos = append(positionInfos, decision.PositionInfo{
-			Symbol:           symbol,
-			Side:             side,
-			EntryPrice:       entryPrice,
-			MarkPrice:        markPrice,
-			Quantity:         quantity,
-			Leverage:         leverage,
-			UnrealizedPnL:    unrealizedPnl,
+			Symbol:           pos.Symbol,
+			Side:             pos.Side,
+			EntryPrice:       pos.EntryPrice,
+			MarkPrice:        pos.MarkPrice,
+			Quantity:         pos.Quantity,
+			Leverage:         pos.Leverage,
+			UnrealizedPnL:    pos.UnrealizedPnL,
 			UnrealizedPnLPct: pnlPct,
 			PeakPnLPct:       peakPnlPct,
-			LiquidationPrice: liquidationPrice,
+			LiquidationPrice: pos.LiquidationPrice,
 			MarginUsed:       marginUsed,
 			UpdateTime:       updateTime,
 		})
 	}
 
 	// 清理已平仓的持仓记录
-	for key := range at.positionFirstSeenTime {
-		if !currentPositionKeys[key] {
-			delete(at.positionFirstSeenTime, key)
-		}
-	}
+	at.prunePositionFirstSeen(currentPositionKeys)
+	at.prunePyramidState(currentPositionKeys)
 
 	// 3. 获取交易员的候选币种池
 	candidateCoins, err := at.getCandidateCoins()
 	if err != nil {
 		return nil, fmt.Errorf("获取候选币种失败: %w", err)
 	}
+	if at.config.ExcludeHeldFromCandidates {
+		candidateCoins = excludeHeldCandidates(candidateCoins, positionInfos)
+	}
+	// 已下架/暂停交易的币种不再作为候选交给AI
+	candidateCoins = at.excludeUnavailableCandidates(candidateCoins, positionInfos)
 
 	// 4. 计算总盈亏
 	totalPnL := totalEquity - at.initialBalance
@@ -1389,6 +2179,8 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		CallCount:       at.callCount,
 		BTCETHLeverage:  at.config.BTCETHLeverage,  // 使用配置的杠杆倍数
 		AltcoinLeverage: at.config.AltcoinLeverage, // 使用配置的杠杆倍数
+		MaxPromptTokens: at.config.MaxPromptTokens, // AI模型的 Prompt token 预算
+		AllowFlip:       at.flipAllowed(),
 		Account: decision.AccountInfo{
 			TotalEquity:      totalEquity,
 			AvailableBalance: availableBalance,
@@ -1402,12 +2194,84 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		CandidateCoins: candidateCoins,
 		Performance:    performance, // 添加历史表现分析
 	}
+	ctx.RecentClosedTrades = at.recentClosedTrades(performance)
+	// 开启盘口深度时由决策引擎按需获取（只针对最终写入 prompt 的币种）
+	ctx.OrderBookFetcher = at.orderBookFetcher()
+	// 配置多周期指标时同样只针对最终写入 prompt 的币种计算
+	ctx.IndicatorFetcher = at.indicatorFetcher()
+	// 获取行情时识别已下架/暂停交易的币种，单个币种不影响整个周期
+	ctx.MarketDataCheck = at.checkMarketData
 
 	return ctx, nil
 }
 
+// excludeHeldCandidates 剔除已有持仓的候选币种（持仓币种已在 Positions 中体现，无需重复作为候选）
+func excludeHeldCandidates(coins []decision.CandidateCoin, positions []decision.PositionInfo) []decision.CandidateCoin {
+	if len(positions) == 0 {
+		return coins
+	}
+	held := make(map[string]bool, len(positions))
+	for _, pos := range positions {
+		held[pos.Symbol] = true
+	}
+	filtered := make([]decision.CandidateCoin, 0, len(coins))
+	for _, coin := range coins {
+		if !held[coin.Symbol] {
+			filtered = append(filtered, coin)
+		}
+	}
+	return filtered
+}
+
 // executeDecisionWithRecord 执行AI决策并记录详细信息
 func (at *AutoTrader) executeDecisionWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
+	err := at.dispatchDecisionWithRecord(decision, actionRecord)
+	if isSymbolUnavailableError(err) {
+		// 下单时交易所返回币种已下架/暂停：标记后续周期不再处理（平仓类动作说明是持仓币种）
+		at.flagSymbolUnavailable(decision.Symbol, !isOpeningAction(decision.Action), err)
+		actionRecord.Status = "symbol_unavailable"
+	}
+	at.emitTradeEvents(actionRecord, err == nil)
+	return err
+}
+
+// dispatchDecisionWithRecord 按动作类型分发执行
+func (at *AutoTrader) dispatchDecisionWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
+	if decision.Action != "hold" && decision.Action != "wait" && at.symbolUnavailable(decision.Symbol) {
+		actionRecord.Status = "symbol_unavailable"
+		return fmt.Errorf("%s 已下架或暂停交易（symbol_unavailable），请手动处理", decision.Symbol)
+	}
+	if isOpeningAction(decision.Action) && !at.inTradingWindow(time.Now()) {
+		return fmt.Errorf("当前不在交易时段，不开新仓")
+	}
+	if maintenanceBlocksOpen(decision.Action) {
+		return fmt.Errorf("平台维护中，暂停开新仓")
+	}
+	if at.aiFailureBlocksOpen(decision.Action) {
+		return fmt.Errorf("AI决策失败后暂停开新仓，等待下一次AI决策成功")
+	}
+	if at.aiAnomalyBlocksOpen(decision.Action) {
+		return fmt.Errorf("AI输出异常（ai_anomaly），暂停开新仓，等待AI输出模式改变")
+	}
+	if at.drawdownStopBlocksOpen(decision.Action) {
+		return fmt.Errorf("账户触发最大回撤硬止损，暂停开新仓")
+	}
+	if at.marginDeficitBlocksOpen(decision.Action) {
+		actionRecord.Status = "margin_deficit"
+		return fmt.Errorf("账户保证金不足（margin_deficit），暂停开新仓，优先平仓降低风险")
+	}
+	if isOpeningAction(decision.Action) && !at.symbolAllowed(decision.Symbol) {
+		return fmt.Errorf("%s 不在交易币种白名单（trading_symbols）中，不开新仓", decision.Symbol)
+	}
+	// 保证金不足时平仓优先，不受最短持仓时间限制
+	if err := at.minHoldingBlocksClose(decision, time.Now()); err != nil && !at.inMarginDeficit() {
+		return err
+	}
+	if isOpeningAction(decision.Action) {
+		if err := at.holdForApproval(decision, actionRecord); err != nil {
+			return err
+		}
+	}
 	switch decision.Action {
 	case "open_long":
 		return at.executeOpenLongWithRecord(decision, actionRecord)
@@ -1423,6 +2287,8 @@ func (at *AutoTrader) executeDecisionWithRecord(decision *decision.Decision, act
 		return at.executeCloseLongWithRecord(decision, actionRecord)
 	case "close_short":
 		return at.executeCloseShortWithRecord(decision, actionRecord)
+	case "flip_long", "flip_short":
+		return at.executeFlipWithRecord(decision, actionRecord)
 	case "update_stop_loss":
 		return at.executeUpdateStopLossWithRecord(decision, actionRecord)
 	case "update_take_profit":
@@ -1441,6 +2307,51 @@ func (at *AutoTrader) executeDecisionWithRecord(decision *decision.Decision, act
 	}
 }
 
+// emitEvent 发送交易事件到所有通知后端（Telegram、Webhook）
+func (at *AutoTrader) emitEvent(eventType, message string, data map[string]interface{}) {
+	logger.Emit(logger.Event{
+		Type:     eventType,
+		TraderID: at.id,
+		UserID:   at.userID,
+		Message:  message,
+		Data:     data,
+	})
+}
+
+// emitTradeEvents 为成功执行的开仓/平仓发送事件；复合动作（反手）按各子动作的执行结果分别发送
+func (at *AutoTrader) emitTradeEvents(actionRecord *logger.DecisionAction, success bool) {
+	if len(actionRecord.Legs) > 0 {
+		for i := range actionRecord.Legs {
+			leg := actionRecord.Legs[i]
+			at.emitTradeEvents(&leg, leg.Success)
+		}
+		return
+	}
+	if !success {
+		return
+	}
+
+	var eventType, verb string
+	switch actionRecord.Action {
+	case "open_long", "open_short":
+		eventType, verb = logger.EventTradeOpened, "开仓"
+	case "close_long", "close_short", "partial_close":
+		eventType, verb = logger.EventTradeClosed, "平仓"
+	default:
+		return
+	}
+	at.emitEvent(eventType,
+		fmt.Sprintf("📣 [%s] %s %s %s: 数量 %.4f @ %.4f", at.name, actionRecord.Symbol, actionRecord.Action, verb, actionRecord.Quantity, actionRecord.Price),
+		map[string]interface{}{
+			"action":   actionRecord.Action,
+			"symbol":   actionRecord.Symbol,
+			"quantity": actionRecord.Quantity,
+			"price":    actionRecord.Price,
+			"leverage": actionRecord.Leverage,
+			"order_id": actionRecord.OrderID,
+		})
+}
+
 // executePlaceLimitOrderWithRecord 【功能】执行限价委托并记录
 func (at *AutoTrader) executePlaceLimitOrderWithRecord(side, tradeSide string, d *decision.Decision, actionRecord *logger.DecisionAction) error {
 	if d == nil {
@@ -1452,6 +2363,7 @@ func (at *AutoTrader) executePlaceLimitOrderWithRecord(side, tradeSide string, d
 	if d.PositionSizeUSD <= 0 {
 		return fmt.Errorf("invalid position_size_usd: %.8f", d.PositionSizeUSD)
 	}
+	d.Price = at.roundOrderPrice(d.Symbol, d.Price, side == "sell")
 
 	lev := d.Leverage
 	if lev <= 0 {
@@ -1461,6 +2373,19 @@ func (at *AutoTrader) executePlaceLimitOrderWithRecord(side, tradeSide string, d
 		}
 	}
 	d.Leverage = lev
+	if tradeSide == "open" {
+		// 🛡️ 单币种敞口上限（按挂单价计算现有仓位价值）
+		exposureNote, err := at.clampToSymbolExposure(d, d.Price)
+		if err != nil {
+			actionRecord.Status = "symbol_exposure_capped"
+			return err
+		}
+		actionRecord.Note = exposureNote
+		if note := at.clampLeverageToBrackets(d, d.PositionSizeUSD); note != "" {
+			actionRecord.Note = joinNotes(actionRecord.Note, note)
+			lev = d.Leverage
+		}
+	}
 
 	// 防重复：同价同方向的limit单已存在则跳过
 	openOrders, err := at.trader.GetOpenOrders(d.Symbol)
@@ -1498,6 +2423,22 @@ func (at *AutoTrader) executePlaceLimitOrderWithRecord(side, tradeSide string, d
 				return nil
 			}
 		}
+
+		// 挂单数量上限：防止补单/兜底逻辑异常时大量挂单
+		if limit := at.maxOpenOrders(); limit > 0 {
+			if count := countLimitOrders(openOrders); count >= limit {
+				actionRecord.Status = "max_open_orders"
+				actionRecord.Note = fmt.Sprintf("%s 已有 %d 个限价挂单，达到上限 %d，跳过挂单", d.Symbol, count, limit)
+				log.Printf("⏭️ [max-open-orders] %s", actionRecord.Note)
+				return fmt.Errorf("%w: %s", ErrMaxOpenOrders, actionRecord.Note)
+			}
+		}
+	} else if limit := at.maxOpenOrders(); limit > 0 {
+		// 配置了挂单数量上限但无法确认现有挂单：不冒险继续挂单
+		actionRecord.Status = "max_open_orders"
+		actionRecord.Note = fmt.Sprintf("%s 获取挂单失败，无法确认是否达到上限 %d，跳过挂单: %v", d.Symbol, limit, err)
+		log.Printf("⏭️ [max-open-orders] %s", actionRecord.Note)
+		return fmt.Errorf("%w: %s", ErrMaxOpenOrders, actionRecord.Note)
 	} else {
 		log.Printf("⚠️ [duplicate-check] 获取挂单失败，继续下单: %v", err)
 	}
@@ -1559,6 +2500,14 @@ func (at *AutoTrader) executePlaceLimitOrderWithRecord(side, tradeSide string, d
 		log.Printf("[signal-ai] SetMarginMode failed symbol=%s err=%v", d.Symbol, err)
 	}
 
+	if tradeSide == "open" {
+		newSide := "long"
+		if side == "sell" {
+			newSide = "short"
+		}
+		actionRecord.Note = joinNotes(actionRecord.Note, at.cancelOppositeDirectionOrders(d.Symbol, newSide))
+	}
+
 	res, err := at.trader.PlaceLimitOrder(d.Symbol, side, tradeSide, quantity, d.Price, lev)
 	if err != nil {
 		log.Printf("❌ [PlaceLimitOrder失败] symbol=%s side=%s tradeSide=%s quantity=%.8f price=%.4f leverage=%d position_size_usd=%.2f err=%v",
@@ -1576,6 +2525,20 @@ func (at *AutoTrader) executePlaceLimitOrderWithRecord(side, tradeSide string, d
 	return nil
 }
 
+// ErrMaxOpenOrders 限价挂单数量达到上限
+var ErrMaxOpenOrders = errors.New("挂单数量达到上限")
+
+// countLimitOrders 统计挂单中的限价单数量（不含止盈止损计划单）
+func countLimitOrders(openOrders []map[string]interface{}) int {
+	count := 0
+	for _, o := range openOrders {
+		if ot, _ := o["type"].(string); strings.EqualFold(ot, "limit") {
+			count++
+		}
+	}
+	return count
+}
+
 // executeCancelOrderWithRecord 【功能】执行撤单并记录
 func (at *AutoTrader) executeCancelOrderWithRecord(d *decision.Decision, actionRecord *logger.DecisionAction) error {
 	if d == nil {
@@ -1618,12 +2581,11 @@ func (at *AutoTrader) executeSetTPOrderWithRecord(d *decision.Decision, actionRe
 	if err != nil {
 		return fmt.Errorf("failed to get positions: %w", err)
 	}
-	var pos map[string]interface{}
-	for _, p := range positions {
-		if p["symbol"] == d.Symbol {
-			amt, _ := p["positionAmt"].(float64)
-			if amt != 0 {
-				pos = p
+	var pos *Position
+	for _, p := range NormalizePositions(positions) {
+		if p.Symbol == d.Symbol {
+			if p.Quantity != 0 {
+				pos = &p
 			}
 			break
 		}
@@ -1632,11 +2594,8 @@ func (at *AutoTrader) executeSetTPOrderWithRecord(d *decision.Decision, actionRe
 		return fmt.Errorf("no position for %s", d.Symbol)
 	}
 
-	posSide := "LONG"
-	if s, ok := pos["side"].(string); ok && strings.ToLower(s) == "short" {
-		posSide = "SHORT"
-	}
-	totalQty := math.Abs(pos["positionAmt"].(float64))
+	posSide := strings.ToUpper(pos.Side)
+	totalQty := pos.Quantity
 	qty := totalQty
 	if d.TpClosePercentage > 0 && d.TpClosePercentage <= 100 {
 		qty = totalQty * (d.TpClosePercentage / 100.0)
@@ -1647,7 +2606,7 @@ func (at *AutoTrader) executeSetTPOrderWithRecord(d *decision.Decision, actionRe
 
 	actionRecord.Price = tp
 	actionRecord.Quantity = qty
-	return at.trader.SetTakeProfit(d.Symbol, posSide, qty, tp)
+	return at.setTakeProfitWithRetry(d.Symbol, posSide, qty, tp)
 }
 
 // executeSetSLOrderWithRecord 【功能】设置止损计划单并记录
@@ -1681,12 +2640,11 @@ func (at *AutoTrader) executeSetSLOrderWithRecord(d *decision.Decision, actionRe
 	if err != nil {
 		return fmt.Errorf("failed to get positions: %w", err)
 	}
-	var pos map[string]interface{}
-	for _, p := range positions {
-		if p["symbol"] == d.Symbol {
-			amt, _ := p["positionAmt"].(float64)
-			if amt != 0 {
-				pos = p
+	var pos *Position
+	for _, p := range NormalizePositions(positions) {
+		if p.Symbol == d.Symbol {
+			if p.Quantity != 0 {
+				pos = &p
 			}
 			break
 		}
@@ -1695,32 +2653,643 @@ func (at *AutoTrader) executeSetSLOrderWithRecord(d *decision.Decision, actionRe
 		return fmt.Errorf("no position for %s", d.Symbol)
 	}
 
-	posSide := "LONG"
-	if s, ok := pos["side"].(string); ok && strings.ToLower(s) == "short" {
-		posSide = "SHORT"
-	}
-	totalQty := math.Abs(pos["positionAmt"].(float64))
+	posSide := strings.ToUpper(pos.Side)
+	totalQty := pos.Quantity
 	if totalQty <= 0 {
 		return fmt.Errorf("invalid sl quantity: %.8f", totalQty)
 	}
 
 	actionRecord.Price = sl
 	actionRecord.Quantity = totalQty
-	return at.trader.SetStopLoss(d.Symbol, posSide, totalQty, sl)
+	return at.setStopLossWithRetry(d.Symbol, posSide, totalQty, sl)
+}
+
+// insufficientPositionErrorCodes 各交易所"仓位不足/可平数量不足"类错误码
+// 交易所的 available 更新可能滞后于持仓，此类错误下重新获取可平数量后重试通常即可成功
+var insufficientPositionErrorCodes = map[string]bool{
+	"43023": true, // Bitget: 仓位不足
+}
+
+// protectiveOrderMaxRetries 保护单（止损/止盈）遇到仓位不足类错误时的最大重试次数
+const protectiveOrderMaxRetries = 2
+
+// protectiveOrderRetryDelay 保护单重试前的等待时间（等待交易所可平数量同步）
+var protectiveOrderRetryDelay = 500 * time.Millisecond
+
+// isInsufficientPositionError 判断是否为仓位不足类错误（只有这类错误才会触发保护单重试）
+// 按交易所返回的错误码判断（见 exchangeAPIError），不匹配错误文本
+func isInsufficientPositionError(err error) bool {
+	var apiErr *exchangeAPIError
+	return errors.As(err, &apiErr) && insufficientPositionErrorCodes[apiErr.code]
+}
+
+// getAvailableQuantity 重新获取指定持仓的可平数量（优先 available，缺失时降级为 positionAmt）
+func (at *AutoTrader) getAvailableQuantity(symbol, positionSide string) (float64, error) {
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		return 0, fmt.Errorf("获取持仓失败: %w", err)
+	}
+	for _, pos := range NormalizePositions(positions) {
+		if pos.Symbol != symbol || !strings.EqualFold(pos.Side, positionSide) {
+			continue
+		}
+		if pos.Available <= 0 {
+			return pos.Quantity, nil
+		}
+		return pos.Available, nil
+	}
+	return 0, fmt.Errorf("持仓不存在: %s %s", symbol, positionSide)
+}
+
+// placeProtectiveOrderWithRetry 下保护单，遇到仓位不足类错误时重新获取可平数量并重试
+// 重试数量取原数量与最新可平数量的较小值，其他错误直接返回
+func (at *AutoTrader) placeProtectiveOrderWithRetry(symbol, positionSide string, quantity float64, place func(qty float64) error) error {
+	err := place(quantity)
+	for attempt := 1; attempt <= protectiveOrderMaxRetries && isInsufficientPositionError(err); attempt++ {
+		time.Sleep(protectiveOrderRetryDelay)
+
+		available, fetchErr := at.getAvailableQuantity(symbol, positionSide)
+		if fetchErr != nil {
+			log.Printf("  ⚠ 仓位不足重试：%v", fetchErr)
+			return err
+		}
+		if available <= 0 {
+			return err
+		}
+		if available < quantity {
+			quantity = available
+		}
+
+		log.Printf("  🔁 仓位不足，按可平数量 %.4f 重试保护单 (%d/%d): %s %s", quantity, attempt, protectiveOrderMaxRetries, symbol, positionSide)
+		err = place(quantity)
+	}
+	return err
+}
+
+// setStopLossWithRetry 设置止损单（仓位不足时自动重试）
+func (at *AutoTrader) setStopLossWithRetry(symbol, positionSide string, quantity, stopPrice float64) error {
+	stopPrice = at.roundOrderPrice(symbol, stopPrice, strings.EqualFold(positionSide, "SHORT"))
+	return at.placeProtectiveOrderWithRetry(symbol, positionSide, quantity, func(qty float64) error {
+		return at.trader.SetStopLoss(symbol, positionSide, qty, stopPrice)
+	})
+}
+
+// setTakeProfitWithRetry 设置止盈单（仓位不足时自动重试）
+func (at *AutoTrader) setTakeProfitWithRetry(symbol, positionSide string, quantity, takeProfitPrice float64) error {
+	takeProfitPrice = at.roundOrderPrice(symbol, takeProfitPrice, strings.EqualFold(positionSide, "SHORT"))
+	return at.placeProtectiveOrderWithRetry(symbol, positionSide, quantity, func(qty float64) error {
+		return at.trader.SetTakeProfit(symbol, positionSide, qty, takeProfitPrice)
+	})
+}
+
+// ensureProtectiveLevels 开仓前校验止损/止盈价格（仅在 requireStopLoss 开启时生效）
+// 多仓止损须低于当前价、止盈须高于当前价，空仓相反；
+// 止损缺失且 defaultStopLossPct>0 时，按仓位最大亏损百分比自动推导止损价
+func ensureProtectiveLevels(d *decision.Decision, price float64, requireStopLoss bool, defaultStopLossPct float64) error {
+	if !requireStopLoss || price <= 0 {
+		return nil
+	}
+	isLong := d.Action == "open_long"
+
+	if d.StopLoss <= 0 {
+		if defaultStopLossPct <= 0 || defaultStopLossPct >= 100 {
+			return fmt.Errorf("❌ %s 缺少止损价，已开启强制止损，拒绝开仓", d.Symbol)
+		}
+		if isLong {
+			d.StopLoss = price * (1 - defaultStopLossPct/100)
+		} else {
+			d.StopLoss = price * (1 + defaultStopLossPct/100)
+		}
+		log.Printf("  🛡️ %s 未提供止损，按最大亏损 %.2f%% 自动设置止损: %.4f", d.Symbol, defaultStopLossPct, d.StopLoss)
+	} else if (isLong && d.StopLoss >= price) || (!isLong && d.StopLoss <= price) {
+		return fmt.Errorf("❌ %s 止损价 %.4f 无效（当前价 %.4f），已开启强制止损，拒绝开仓", d.Symbol, d.StopLoss, price)
+	}
+
+	if d.TakeProfit > 0 && ((isLong && d.TakeProfit <= price) || (!isLong && d.TakeProfit >= price)) {
+		return fmt.Errorf("❌ %s 止盈价 %.4f 无效（当前价 %.4f），已开启强制止损，拒绝开仓", d.Symbol, d.TakeProfit, price)
+	}
+	return nil
+}
+
+// defaultMaxAddsPerPosition 开启加仓但未配置次数时，单个持仓默认最多加仓次数
+const defaultMaxAddsPerPosition = 2
+
+// pyramidState 单个持仓的加仓状态
+type pyramidState struct {
+	adds       int     // 已加仓次数（不含首次开仓）
+	stopLoss   float64 // 当前整体止损价（加仓后为按数量加权的混合止损）
+	takeProfit float64 // 最近一次决策的止盈价（0=未知），用于补设缺失的止盈单
+}
+
+// SetPyramiding 运行时更新加仓策略
+func (at *AutoTrader) SetPyramiding(allow bool, maxAdds int) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.AllowPyramiding = allow
+	at.config.MaxAddsPerPosition = maxAdds
+}
+
+// pyramidingPolicy 返回是否允许加仓及单个持仓最多加仓次数
+func (at *AutoTrader) pyramidingPolicy() (bool, int) {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	maxAdds := at.config.MaxAddsPerPosition
+	if maxAdds <= 0 {
+		maxAdds = defaultMaxAddsPerPosition
+	}
+	return at.config.AllowPyramiding, maxAdds
+}
+
+// SetAllowFlip 运行时更新是否允许反手
+func (at *AutoTrader) SetAllowFlip(allow bool) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.AllowFlip = allow
+}
+
+// flipAllowed 是否允许反手
+func (at *AutoTrader) flipAllowed() bool {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return at.config.AllowFlip
+}
+
+// SetMinConfidence 运行时更新开仓最低信心度
+func (at *AutoTrader) SetMinConfidence(minConfidence int) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.MinConfidence = minConfidence
+}
+
+// minConfidence 获取开仓最低信心度
+func (at *AutoTrader) minConfidence() int {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return at.config.MinConfidence
+}
+
+// DefaultMaxOpenOrders 单个币种默认最多同时存在的限价挂单数
+const DefaultMaxOpenOrders = 10
+
+// SetMaxOpenOrders 运行时更新单币种限价挂单数量上限（0=不限制）
+func (at *AutoTrader) SetMaxOpenOrders(maxOpenOrders int) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.MaxOpenOrders = maxOpenOrders
+}
+
+// maxOpenOrders 获取单币种限价挂单数量上限
+func (at *AutoTrader) maxOpenOrders() int {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return at.config.MaxOpenOrders
+}
+
+// DefaultMaxActionsPerCycle 每个决策周期默认最多执行的动作数
+const DefaultMaxActionsPerCycle = 10
+
+// SetMaxActionsPerCycle 运行时更新单周期动作数量上限（0=不限制）
+func (at *AutoTrader) SetMaxActionsPerCycle(maxActions int) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.MaxActionsPerCycle = maxActions
+}
+
+// maxActionsPerCycle 获取单周期动作数量上限
+func (at *AutoTrader) maxActionsPerCycle() int {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return at.config.MaxActionsPerCycle
+}
+
+// capDecisionActions 按顺序保留前 limit 个动作（需先按优先级排序），hold/wait 不计入上限；
+// 返回保留的决策和被丢弃的决策，limit<=0 时不限制
+func capDecisionActions(sorted []decision.Decision, limit int) (kept, dropped []decision.Decision) {
+	if limit <= 0 {
+		return sorted, nil
+	}
+	count := 0
+	for _, d := range sorted {
+		if d.Action == "hold" || d.Action == "wait" {
+			kept = append(kept, d)
+			continue
+		}
+		if count >= limit {
+			dropped = append(dropped, d)
+			continue
+		}
+		count++
+		kept = append(kept, d)
+	}
+	return kept, dropped
+}
+
+// recordCappedDecisions 将超出单周期动作上限而未执行的决策写入决策记录
+func (at *AutoTrader) recordCappedDecisions(record *logger.DecisionRecord, dropped []decision.Decision) {
+	if len(dropped) == 0 {
+		return
+	}
+	log.Printf("⏭️ 本周期动作数超过上限 %d，跳过 %d 个低优先级动作", at.maxActionsPerCycle(), len(dropped))
+	for _, d := range dropped {
+		record.Decisions = append(record.Decisions, logger.DecisionAction{
+			Action:     d.Action,
+			Symbol:     d.Symbol,
+			Leverage:   d.Leverage,
+			Confidence: d.Confidence,
+			Reasoning:  d.Reasoning,
+			Timestamp:  time.Now(),
+			Status:     "max_actions_skipped",
+			Note:       fmt.Sprintf("超过单周期动作上限 %d，未执行", at.maxActionsPerCycle()),
+		})
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⏭️ %s %s 未执行（超过单周期动作上限）", d.Symbol, d.Action))
+	}
+}
+
+// 信号模式默认仓位比例（百分比）
+const (
+	DefaultSignalBasePositionPct = 20.0
+	DefaultSignalDefaultAddPct   = 10.0
+)
+
+// 信号对账时未显式配置仓位比例的回退值（小数形式）：底仓40%，其余60%按补仓点数均分
+const (
+	signalDiffBasePercent     = 0.40
+	signalDiffTotalAddPercent = 0.60
+)
+
+// ValidSignalSizing 校验信号模式仓位比例（百分比）：底仓与每个补仓比例均需大于0，
+// 且底仓加上全部补仓合计不超过分配资金的100%
+func ValidSignalSizing(basePct float64, addPcts ...float64) bool {
+	if basePct <= 0 {
+		return false
+	}
+	total := basePct
+	for _, pct := range addPcts {
+		if pct <= 0 {
+			return false
+		}
+		total += pct
+	}
+	return total <= 100+1e-9
+}
+
+// EffectiveSignalSizing 将未配置（0）的底仓/默认补仓比例替换为默认值，返回实际生效的百分比
+func EffectiveSignalSizing(basePct, addPct float64) (float64, float64) {
+	if basePct == 0 {
+		basePct = DefaultSignalBasePositionPct
+	}
+	if addPct == 0 {
+		addPct = DefaultSignalDefaultAddPct
+	}
+	return basePct, addPct
+}
+
+// SetSignalSizing 运行时更新信号模式底仓/默认补仓比例（百分比）
+func (at *AutoTrader) SetSignalSizing(basePct, addPct float64) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.SignalBasePositionPct = basePct
+	at.config.SignalDefaultAddPct = addPct
+}
+
+// signalSizing 获取信号模式底仓/默认补仓比例（小数形式），未配置时回退到默认值
+func (at *AutoTrader) signalSizing() (base, add float64) {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	base, add = at.config.SignalBasePositionPct, at.config.SignalDefaultAddPct
+	if base <= 0 {
+		base = DefaultSignalBasePositionPct
+	}
+	if add <= 0 {
+		add = DefaultSignalDefaultAddPct
+	}
+	return base / 100, add / 100
+}
+
+// signalPlanPercents 返回信号底仓比例和每个补仓点的比例（小数形式，未指定比例的补仓点使用默认补仓比例）
+func (at *AutoTrader) signalPlanPercents(strat *signal.SignalDecision) (base float64, adds []float64) {
+	base, defaultAdd := at.signalSizing()
+	for _, a := range strat.Adds {
+		pct := a.Percent
+		if pct <= 0 {
+			pct = defaultAdd
+		}
+		adds = append(adds, pct)
+	}
+	return base, adds
+}
+
+// validSignalPlan 校验信号的底仓加全部补仓合计不超过分配资金的100%
+func (at *AutoTrader) validSignalPlan(strat *signal.SignalDecision) bool {
+	base, adds := at.signalPlanPercents(strat)
+	addPcts := make([]float64, len(adds))
+	for i, pct := range adds {
+		addPcts[i] = pct * 100
+	}
+	return ValidSignalSizing(base*100, addPcts...)
+}
+
+// signalDiffSizing 信号对账使用的底仓/默认补仓比例（小数形式）
+// 只有交易员显式配置了比例时才使用配置值，否则沿用对账原有的 40% 底仓、60% 按补仓点均分
+func (at *AutoTrader) signalDiffSizing(addCount int) (base, add float64) {
+	at.mu.RLock()
+	base, add = at.config.SignalBasePositionPct/100, at.config.SignalDefaultAddPct/100
+	at.mu.RUnlock()
+	if base <= 0 {
+		base = signalDiffBasePercent
+	}
+	if add <= 0 {
+		add = signalDiffTotalAddPercent / math.Max(1, float64(addCount))
+	}
+	return base, add
+}
+
+// confidenceGated 判断开仓类决策是否因信心度不足需要降级为 wait
+// 未配置阈值或AI未返回信心度时不拦截
+func (at *AutoTrader) confidenceGated(d *decision.Decision) bool {
+	minConfidence := at.minConfidence()
+	if minConfidence <= 0 || d.Confidence <= 0 {
+		return false
+	}
+	switch d.Action {
+	case "open_long", "open_short", "flip_long", "flip_short", "place_long_order", "place_short_order":
+		return d.Confidence < minConfidence
+	}
+	return false
+}
+
+// findOpenPosition 查找同币种同方向的已有持仓，无持仓或查询失败时返回 nil（粉尘持仓视为无持仓）
+func (at *AutoTrader) findOpenPosition(symbol, side string) *Position {
+	pos := at.findPosition(symbol, side)
+	if pos == nil || at.isDustPosition(*pos) {
+		return nil
+	}
+	return pos
+}
+
+// findPosition 查找同币种同方向的持仓（包含粉尘持仓），无持仓或查询失败时返回 nil
+func (at *AutoTrader) findPosition(symbol, side string) *Position {
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		return nil
+	}
+	for _, pos := range NormalizePositions(positions) {
+		if pos.Symbol == symbol && pos.Side == side {
+			return &pos
+		}
+	}
+	return nil
+}
+
+// checkPyramidAdd 校验对已有持仓加仓是否超过加仓次数和总仓位价值上限
+func (at *AutoTrader) checkPyramidAdd(d *decision.Decision, existing *Position, price float64) error {
+	_, maxAdds := at.pyramidingPolicy()
+
+	at.positionPyramidMu.Lock()
+	adds := at.positionPyramid[existing.Key()].adds
+	at.positionPyramidMu.Unlock()
+	if adds >= maxAdds {
+		return fmt.Errorf("❌ %s %s 已加仓 %d 次，达到上限 %d 次，拒绝继续加仓", d.Symbol, existing.Side, adds, maxAdds)
+	}
+
+	// 总敞口上限与AI决策校验一致（按账户净值计算）
+	maxValue := decision.MaxPositionValue(d.Symbol, at.accountEquity())
+	totalValue := existing.Quantity*price + d.PositionSizeUSD
+	if totalValue > maxValue {
+		return fmt.Errorf("❌ %s 加仓后仓位价值 %.2f USDT 超过上限 %.2f USDT，拒绝加仓", d.Symbol, totalValue, maxValue)
+	}
+	return nil
+}
+
+// resetPyramidState 首次开仓时重置加仓状态
+func (at *AutoTrader) resetPyramidState(posKey string, stopLoss float64) {
+	at.positionPyramidMu.Lock()
+	at.positionPyramid[posKey] = pyramidState{stopLoss: stopLoss}
+	at.positionPyramidMu.Unlock()
+	at.persistPyramidState()
+}
+
+// updatePyramidStopLoss 调整止损后同步记录的整体止损价（仅已跟踪的持仓）
+func (at *AutoTrader) updatePyramidStopLoss(posKey string, stopLoss float64) {
+	at.positionPyramidMu.Lock()
+	state, ok := at.positionPyramid[posKey]
+	if ok {
+		state.stopLoss = stopLoss
+		at.positionPyramid[posKey] = state
+	}
+	at.positionPyramidMu.Unlock()
+	if ok {
+		at.persistPyramidState()
+	}
+}
+
+// updatePyramidTakeProfit 开仓/加仓/调整止盈后同步记录的止盈价（仅已跟踪的持仓，<=0 时忽略）
+func (at *AutoTrader) updatePyramidTakeProfit(posKey string, takeProfit float64) {
+	if takeProfit <= 0 {
+		return
+	}
+	at.positionPyramidMu.Lock()
+	state, ok := at.positionPyramid[posKey]
+	if ok {
+		state.takeProfit = takeProfit
+		at.positionPyramid[posKey] = state
+	}
+	at.positionPyramidMu.Unlock()
+	if ok {
+		at.persistPyramidState()
+	}
+}
+
+// prunePyramidState 清理已不存在的持仓的加仓状态
+func (at *AutoTrader) prunePyramidState(currentPositionKeys map[string]bool) {
+	pruned := false
+	at.positionPyramidMu.Lock()
+	for key := range at.positionPyramid {
+		if !currentPositionKeys[key] {
+			delete(at.positionPyramid, key)
+			pruned = true
+		}
+	}
+	at.positionPyramidMu.Unlock()
+	if pruned {
+		at.persistPyramidState()
+	}
+}
+
+// pyramidStateStore 持仓加仓状态的持久化操作（*sysconfig.Database 实现）
+type pyramidStateStore interface {
+	GetTraderPositionPyramid(traderID string) (map[string]sysconfig.PositionPyramidState, error)
+	SaveTraderPositionPyramid(traderID string, states map[string]sysconfig.PositionPyramidState) error
+}
+
+// persistPyramidState 将持仓加仓状态保存到数据库，保证重启后加仓次数和止损止盈价位不被重置
+func (at *AutoTrader) persistPyramidState() {
+	store, ok := at.database.(pyramidStateStore)
+	if !ok {
+		return
+	}
+
+	at.positionPyramidMu.Lock()
+	snapshot := make(map[string]sysconfig.PositionPyramidState, len(at.positionPyramid))
+	for k, v := range at.positionPyramid {
+		snapshot[k] = sysconfig.PositionPyramidState{Adds: v.adds, StopLoss: v.stopLoss, TakeProfit: v.takeProfit}
+	}
+	at.positionPyramidMu.Unlock()
+
+	if err := store.SaveTraderPositionPyramid(at.id, snapshot); err != nil {
+		log.Printf("⚠️ [%s] 保存持仓加仓状态失败: %v", at.name, err)
+	}
+}
+
+// loadPyramidState 启动时从数据库恢复持仓加仓状态（已平仓的持仓在下一个周期被清理）
+func (at *AutoTrader) loadPyramidState() {
+	store, ok := at.database.(pyramidStateStore)
+	if !ok {
+		return
+	}
+
+	states, err := store.GetTraderPositionPyramid(at.id)
+	if err != nil {
+		log.Printf("⚠️ [%s] 恢复持仓加仓状态失败: %v", at.name, err)
+		return
+	}
+
+	at.positionPyramidMu.Lock()
+	for k, v := range states {
+		if _, exists := at.positionPyramid[k]; !exists {
+			at.positionPyramid[k] = pyramidState{adds: v.Adds, stopLoss: v.StopLoss, takeProfit: v.TakeProfit}
+		}
+	}
+	at.positionPyramidMu.Unlock()
+
+	if len(states) > 0 {
+		log.Printf("📂 [%s] 已恢复 %d 个持仓的加仓状态", at.name, len(states))
+	}
+}
+
+// recordPyramidAdd 记录一次加仓，返回合并后的总数量和混合止损价
+func (at *AutoTrader) recordPyramidAdd(existing *Position, addQty, addStop float64) (float64, float64) {
+	posKey := existing.Key()
+
+	at.positionPyramidMu.Lock()
+	state := at.positionPyramid[posKey]
+	stopLoss := blendedStopLoss(existing.Quantity, state.stopLoss, addQty, addStop)
+	state.adds++
+	state.stopLoss = stopLoss
+	at.positionPyramid[posKey] = state
+	at.positionPyramidMu.Unlock()
+	at.persistPyramidState()
+
+	log.Printf("  📚 %s 第 %d 次加仓: 原持仓 %.4f + 加仓 %.4f，混合止损 %.4f",
+		posKey, state.adds, existing.Quantity, addQty, stopLoss)
+	return existing.Quantity + addQty, stopLoss
+}
+
+// blendedStopLoss 按数量加权计算加仓后的整体止损价
+// 原止损未知（如重启后）时沿用新止损；新止损缺失时保持原止损
+func blendedStopLoss(existingQty, existingStop, addQty, addStop float64) float64 {
+	if existingStop <= 0 {
+		return addStop
+	}
+	if addStop <= 0 || existingQty+addQty <= 0 {
+		return existingStop
+	}
+	return (existingQty*existingStop + addQty*addStop) / (existingQty + addQty)
+}
+
+// leverageBracketCacheTTL 杠杆分层缓存时长（交易所很少调整分层）
+const leverageBracketCacheTTL = time.Hour
+
+// cachedLeverageBrackets 杠杆分层缓存条目
+type cachedLeverageBrackets struct {
+	brackets  []LeverageBracket
+	fetchedAt time.Time
+}
+
+// getLeverageBrackets 获取币种杠杆分层（带缓存），查询失败或不支持时返回 nil
+func (at *AutoTrader) getLeverageBrackets(symbol string) []LeverageBracket {
+	if v, ok := at.leverageBrackets.Load(symbol); ok {
+		cached := v.(cachedLeverageBrackets)
+		if time.Since(cached.fetchedAt) < leverageBracketCacheTTL {
+			return cached.brackets
+		}
+	}
+
+	brackets, err := at.trader.GetLeverageBrackets(symbol)
+	if err != nil {
+		log.Printf("  ⚠️ 获取 %s 杠杆分层失败，跳过杠杆上限校验: %v", symbol, err)
+		return nil
+	}
+	at.leverageBrackets.Store(symbol, cachedLeverageBrackets{brackets: brackets, fetchedAt: time.Now()})
+	return brackets
+}
+
+// cachedTickSize 价格 tick size 缓存条目
+type cachedTickSize struct {
+	tickSize  float64
+	fetchedAt time.Time
+}
+
+// getPriceTickSize 获取币种价格 tick size（带缓存，与杠杆分层同样的缓存时长），查询失败或未知时返回0
+func (at *AutoTrader) getPriceTickSize(symbol string) float64 {
+	if v, ok := at.priceTickSizes.Load(symbol); ok {
+		cached := v.(cachedTickSize)
+		if time.Since(cached.fetchedAt) < leverageBracketCacheTTL {
+			return cached.tickSize
+		}
+	}
+
+	tickSize, err := at.trader.GetPriceTickSize(symbol)
+	if err != nil {
+		log.Printf("  ⚠️ 获取 %s 价格精度失败，跳过价格对齐: %v", symbol, err)
+		return 0
+	}
+	at.priceTickSizes.Store(symbol, cachedTickSize{tickSize: tickSize, fetchedAt: time.Now()})
+	return tickSize
+}
+
+// roundOrderPrice 将下单价格对齐到交易所 tick size，按保守方向取整：
+// 多仓止损/止盈、买入限价向下取整，空仓止损/止盈、卖出限价向上取整，
+// 即止损远离开仓价、止盈靠近开仓价、限价单不会比原价格更差
+func (at *AutoTrader) roundOrderPrice(symbol string, price float64, roundUp bool) float64 {
+	rounded := RoundPriceToTick(price, at.getPriceTickSize(symbol), roundUp)
+	if rounded != price {
+		log.Printf("  📐 %s 价格按 tick size 对齐: %v -> %v", symbol, price, rounded)
+	}
+	return rounded
+}
+
+// clampLeverageToBrackets 先按系统级杠杆上限、再按交易所杠杆分层下调超限的杠杆，返回调整说明（未调整时为空）
+// notional 为开仓后该币种的总名义价值（加仓时包含已有持仓）
+func (at *AutoTrader) clampLeverageToBrackets(d *decision.Decision, notional float64) string {
+	leverage, note := ClampLeverageToCeiling(d.Symbol, d.Leverage)
+	if note != "" {
+		log.Printf("  ⚠️ %s %s", d.Symbol, note)
+		d.Leverage = leverage
+	}
+
+	maxLeverage := MaxLeverageForNotional(at.getLeverageBrackets(d.Symbol), notional)
+	if maxLeverage <= 0 || d.Leverage <= maxLeverage {
+		return note
+	}
+
+	bracketNote := fmt.Sprintf("杠杆 %dx 超过交易所上限 %dx（名义价值 %.2f USDT），已下调为 %dx", d.Leverage, maxLeverage, notional, maxLeverage)
+	log.Printf("  ⚠️ %s %s", d.Symbol, bracketNote)
+	d.Leverage = maxLeverage
+	if note != "" {
+		return note + "；" + bracketNote
+	}
+	return bracketNote
 }
 
 // executeOpenLongWithRecord 执行开多仓并记录详细信息
 func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
 	log.Printf("  📈 开多仓: %s", decision.Symbol)
 
-	// ⚠️ 关键：检查是否已有同币种同方向持仓，如果有则拒绝开仓（防止仓位叠加超限）
-	positions, err := at.trader.GetPositions()
-	if err == nil {
-		for _, pos := range positions {
-			if pos["symbol"] == decision.Symbol && pos["side"] == "long" {
-				return fmt.Errorf("❌ %s 已有多仓，拒绝开仓以防止仓位叠加超限。如需换仓，请先给出 close_long 决策", decision.Symbol)
-			}
-		}
+	// ⚠️ 关键：检查是否已有同币种同方向持仓，未开启加仓时拒绝开仓（防止仓位叠加超限）
+	existing := at.findOpenPosition(decision.Symbol, "long")
+	if allowPyramiding, _ := at.pyramidingPolicy(); existing != nil && !allowPyramiding {
+		return fmt.Errorf("❌ %s 已有多仓，拒绝开仓以防止仓位叠加超限。如需换仓，请先给出 close_long 决策", decision.Symbol)
 	}
 
 	// 获取当前价格
@@ -1729,11 +3298,44 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 		return err
 	}
 
+	// 加仓：校验加仓次数和总仓位价值上限
+	if existing != nil {
+		if err := at.checkPyramidAdd(decision, existing, marketData.CurrentPrice); err != nil {
+			return err
+		}
+	}
+
+	// 🛡️ 默认止损/止盈：决策缺少保护价位时按币种配置的百分比从开仓价推导
+	defaultsNote := at.applyProtectiveDefaults(decision, marketData.CurrentPrice)
+
+	// 🛡️ 止损保护：开启 RequireStopLoss 时，缺少有效止损的开仓直接拒绝
+	if err := ensureProtectiveLevels(decision, marketData.CurrentPrice, at.config.RequireStopLoss, at.config.DefaultStopLossPct); err != nil {
+		return err
+	}
+
+	// 🛡️ 单币种敞口上限：超过时下调开仓金额，已无额度时拒绝
+	exposureNote, err := at.clampToSymbolExposure(decision, marketData.CurrentPrice)
+	if err != nil {
+		actionRecord.Status = "symbol_exposure_capped"
+		return err
+	}
+	actionRecord.Note = joinNotes(defaultsNote, exposureNote)
+
 	// 计算数量
 	quantity := decision.PositionSizeUSD / marketData.CurrentPrice
 	actionRecord.Quantity = quantity
 	actionRecord.Price = marketData.CurrentPrice
 
+	// 🛡️ 杠杆分层：超过交易所该名义价值档位的最大杠杆时下调，避免下单被拒
+	notional := decision.PositionSizeUSD
+	if existing != nil {
+		notional += existing.Quantity * marketData.CurrentPrice
+	}
+	if note := at.clampLeverageToBrackets(decision, notional); note != "" {
+		actionRecord.Note = joinNotes(actionRecord.Note, note)
+	}
+	actionRecord.Leverage = decision.Leverage
+
 	// ⚠️ 保证金验证：防止保证金不足错误（code=-2019）
 	requiredMargin := decision.PositionSizeUSD / float64(decision.Leverage)
 
@@ -1741,6 +3343,10 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 	if err != nil {
 		return fmt.Errorf("获取账户余额失败: %w", err)
 	}
+	if at.updateMarginDeficit(balance) {
+		actionRecord.Status = "margin_deficit"
+		return fmt.Errorf("❌ 账户保证金不足（margin_deficit），暂停开新仓")
+	}
 	availableBalance := 0.0
 	if avail, ok := balance["availableBalance"].(float64); ok {
 		availableBalance = avail
@@ -1761,6 +3367,9 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 		// 继续执行，不影响交易
 	}
 
+	// 撤销原空方向挂单（反手或AI直接开反方向时，避免旧挂单在新仓位上成交）
+	actionRecord.Note = joinNotes(actionRecord.Note, at.cancelOppositeDirectionOrders(decision.Symbol, "long"))
+
 	// 开仓
 	order, err := at.trader.OpenLong(decision.Symbol, quantity, decision.Leverage)
 	if err != nil {
@@ -1774,18 +3383,24 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 
 	log.Printf("  ✓ 开仓成功，订单ID: %v, 数量: %.4f", order["orderId"], quantity)
 
-	// 记录开仓时间
-	posKey := decision.Symbol + "_long"
-	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
-
-	// 设置止损止盈
-	if err := at.trader.SetStopLoss(decision.Symbol, "LONG", quantity, decision.StopLoss); err != nil {
-		log.Printf("  ⚠ 设置止损失败: %v", err)
-	}
-	if err := at.trader.SetTakeProfit(decision.Symbol, "LONG", quantity, decision.TakeProfit); err != nil {
-		log.Printf("  ⚠ 设置止盈失败: %v", err)
+	// 记录开仓时间（加仓时保留首次开仓时间，止损止盈按合并后的总仓位和混合止损重设）
+	protectQty, stopLoss := quantity, decision.StopLoss
+	if existing == nil {
+		posKey := decision.Symbol + "_long"
+		at.setPositionFirstSeen(posKey, time.Now().UnixMilli())
+		at.resetPyramidState(posKey, decision.StopLoss)
+		at.updatePyramidTakeProfit(posKey, decision.TakeProfit)
+	} else {
+		protectQty, stopLoss = at.recordPyramidAdd(existing, quantity, decision.StopLoss)
+		at.updatePyramidTakeProfit(existing.Key(), decision.TakeProfit)
+		if err := at.trader.CancelStopOrders(decision.Symbol); err != nil {
+			log.Printf("  ⚠ 取消旧止盈止损单失败: %v", err)
+		}
 	}
 
+	// 设置止损止盈（开启 use_oco 时联动下单）
+	at.placeProtectiveOrders(decision.Symbol, "LONG", protectQty, stopLoss, decision.TakeProfit)
+
 	return nil
 }
 
@@ -1793,14 +3408,10 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
 	log.Printf("  📉 开空仓: %s", decision.Symbol)
 
-	// ⚠️ 关键：检查是否已有同币种同方向持仓，如果有则拒绝开仓（防止仓位叠加超限）
-	positions, err := at.trader.GetPositions()
-	if err == nil {
-		for _, pos := range positions {
-			if pos["symbol"] == decision.Symbol && pos["side"] == "short" {
-				return fmt.Errorf("❌ %s 已有空仓，拒绝开仓以防止仓位叠加超限。如需换仓，请先给出 close_short 决策", decision.Symbol)
-			}
-		}
+	// ⚠️ 关键：检查是否已有同币种同方向持仓，未开启加仓时拒绝开仓（防止仓位叠加超限）
+	existing := at.findOpenPosition(decision.Symbol, "short")
+	if allowPyramiding, _ := at.pyramidingPolicy(); existing != nil && !allowPyramiding {
+		return fmt.Errorf("❌ %s 已有空仓，拒绝开仓以防止仓位叠加超限。如需换仓，请先给出 close_short 决策", decision.Symbol)
 	}
 
 	// 获取当前价格
@@ -1809,11 +3420,44 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 		return err
 	}
 
+	// 加仓：校验加仓次数和总仓位价值上限
+	if existing != nil {
+		if err := at.checkPyramidAdd(decision, existing, marketData.CurrentPrice); err != nil {
+			return err
+		}
+	}
+
+	// 🛡️ 默认止损/止盈：决策缺少保护价位时按币种配置的百分比从开仓价推导
+	defaultsNote := at.applyProtectiveDefaults(decision, marketData.CurrentPrice)
+
+	// 🛡️ 止损保护：开启 RequireStopLoss 时，缺少有效止损的开仓直接拒绝
+	if err := ensureProtectiveLevels(decision, marketData.CurrentPrice, at.config.RequireStopLoss, at.config.DefaultStopLossPct); err != nil {
+		return err
+	}
+
+	// 🛡️ 单币种敞口上限：超过时下调开仓金额，已无额度时拒绝
+	exposureNote, err := at.clampToSymbolExposure(decision, marketData.CurrentPrice)
+	if err != nil {
+		actionRecord.Status = "symbol_exposure_capped"
+		return err
+	}
+	actionRecord.Note = joinNotes(defaultsNote, exposureNote)
+
 	// 计算数量
 	quantity := decision.PositionSizeUSD / marketData.CurrentPrice
 	actionRecord.Quantity = quantity
 	actionRecord.Price = marketData.CurrentPrice
 
+	// 🛡️ 杠杆分层：超过交易所该名义价值档位的最大杠杆时下调，避免下单被拒
+	notional := decision.PositionSizeUSD
+	if existing != nil {
+		notional += existing.Quantity * marketData.CurrentPrice
+	}
+	if note := at.clampLeverageToBrackets(decision, notional); note != "" {
+		actionRecord.Note = joinNotes(actionRecord.Note, note)
+	}
+	actionRecord.Leverage = decision.Leverage
+
 	// ⚠️ 保证金验证：防止保证金不足错误（code=-2019）
 	requiredMargin := decision.PositionSizeUSD / float64(decision.Leverage)
 
@@ -1821,6 +3465,10 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 	if err != nil {
 		return fmt.Errorf("获取账户余额失败: %w", err)
 	}
+	if at.updateMarginDeficit(balance) {
+		actionRecord.Status = "margin_deficit"
+		return fmt.Errorf("❌ 账户保证金不足（margin_deficit），暂停开新仓")
+	}
 	availableBalance := 0.0
 	if avail, ok := balance["availableBalance"].(float64); ok {
 		availableBalance = avail
@@ -1841,6 +3489,9 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 		// 继续执行，不影响交易
 	}
 
+	// 撤销原多方向挂单（反手或AI直接开反方向时，避免旧挂单在新仓位上成交）
+	actionRecord.Note = joinNotes(actionRecord.Note, at.cancelOppositeDirectionOrders(decision.Symbol, "short"))
+
 	// 开仓
 	order, err := at.trader.OpenShort(decision.Symbol, quantity, decision.Leverage)
 	if err != nil {
@@ -1854,18 +3505,24 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 
 	log.Printf("  ✓ 开仓成功，订单ID: %v, 数量: %.4f", order["orderId"], quantity)
 
-	// 记录开仓时间
-	posKey := decision.Symbol + "_short"
-	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
-
-	// 设置止损止盈
-	if err := at.trader.SetStopLoss(decision.Symbol, "SHORT", quantity, decision.StopLoss); err != nil {
-		log.Printf("  ⚠ 设置止损失败: %v", err)
-	}
-	if err := at.trader.SetTakeProfit(decision.Symbol, "SHORT", quantity, decision.TakeProfit); err != nil {
-		log.Printf("  ⚠ 设置止盈失败: %v", err)
+	// 记录开仓时间（加仓时保留首次开仓时间，止损止盈按合并后的总仓位和混合止损重设）
+	protectQty, stopLoss := quantity, decision.StopLoss
+	if existing == nil {
+		posKey := decision.Symbol + "_short"
+		at.setPositionFirstSeen(posKey, time.Now().UnixMilli())
+		at.resetPyramidState(posKey, decision.StopLoss)
+		at.updatePyramidTakeProfit(posKey, decision.TakeProfit)
+	} else {
+		protectQty, stopLoss = at.recordPyramidAdd(existing, quantity, decision.StopLoss)
+		at.updatePyramidTakeProfit(existing.Key(), decision.TakeProfit)
+		if err := at.trader.CancelStopOrders(decision.Symbol); err != nil {
+			log.Printf("  ⚠ 取消旧止盈止损单失败: %v", err)
+		}
 	}
 
+	// 设置止损止盈（开启 use_oco 时联动下单）
+	at.placeProtectiveOrders(decision.Symbol, "SHORT", protectQty, stopLoss, decision.TakeProfit)
+
 	return nil
 }
 
@@ -1885,6 +3542,7 @@ func (at *AutoTrader) executeCloseLongWithRecord(decision *decision.Decision, ac
 	if err != nil {
 		return err
 	}
+	at.handleCloseResidual(decision.Symbol, "long")
 
 	// 记录订单ID
 	if orderID, ok := order["orderId"].(int64); ok {
@@ -1911,6 +3569,7 @@ func (at *AutoTrader) executeCloseShortWithRecord(decision *decision.Decision, a
 	if err != nil {
 		return err
 	}
+	at.handleCloseResidual(decision.Symbol, "short")
 
 	// 记录订单ID
 	if orderID, ok := order["orderId"].(int64); ok {
@@ -1921,6 +3580,77 @@ func (at *AutoTrader) executeCloseShortWithRecord(decision *decision.Decision, a
 	return nil
 }
 
+// executeFlipWithRecord 执行反手：flip_long 平空后开多，flip_short 平多后开空
+// 平仓失败时不开仓（持仓保持不变）；平仓成功但开仓失败时不再尝试重开原仓位（同样可能失败），
+// 而是将本次反手明确记录为未完成（当前空仓）并推送通知，避免账户被静默置为空仓
+// 平仓/开仓两条子记录保存在 actionRecord.Legs 中，供交易统计按普通开平仓处理
+func (at *AutoTrader) executeFlipWithRecord(d *decision.Decision, actionRecord *logger.DecisionAction) error {
+	if !at.flipAllowed() {
+		return fmt.Errorf("未开启反手（allow_flip），拒绝执行 %s", d.Action)
+	}
+
+	closeAction, openAction, closeSide := "close_short", "open_long", "short"
+	if d.Action == "flip_short" {
+		closeAction, openAction, closeSide = "close_long", "open_short", "long"
+	}
+
+	existing := at.findOpenPosition(d.Symbol, closeSide)
+	if existing == nil {
+		return fmt.Errorf("%s 没有可反手的%s持仓", d.Symbol, closeSide)
+	}
+
+	// 1. 平掉反向持仓
+	closeDecision := *d
+	closeDecision.Action = closeAction
+	closeRecord := logger.DecisionAction{Action: closeAction, Symbol: d.Symbol, Quantity: existing.Quantity, Timestamp: time.Now()}
+	var closeErr error
+	if closeAction == "close_long" {
+		closeErr = at.executeCloseLongWithRecord(&closeDecision, &closeRecord)
+	} else {
+		closeErr = at.executeCloseShortWithRecord(&closeDecision, &closeRecord)
+	}
+	if closeErr != nil {
+		closeRecord.Error = closeErr.Error()
+		actionRecord.Legs = []logger.DecisionAction{closeRecord}
+		return fmt.Errorf("反手平仓失败，未开新仓（持仓保持不变）: %w", closeErr)
+	}
+	closeRecord.Success = true
+	at.forgetPositionFirstSeen(existing.Key())
+
+	// 2. 开新方向
+	openDecision := *d
+	openDecision.Action = openAction
+	openRecord := logger.DecisionAction{Action: openAction, Symbol: d.Symbol, Leverage: d.Leverage, Timestamp: time.Now()}
+	var openErr error
+	if openAction == "open_long" {
+		openErr = at.executeOpenLongWithRecord(&openDecision, &openRecord)
+	} else {
+		openErr = at.executeOpenShortWithRecord(&openDecision, &openRecord)
+	}
+
+	actionRecord.Price = openRecord.Price
+	actionRecord.Quantity = openRecord.Quantity
+	actionRecord.Leverage = openRecord.Leverage
+	actionRecord.OrderID = openRecord.OrderID
+	if openErr != nil {
+		openRecord.Error = openErr.Error()
+		actionRecord.Legs = []logger.DecisionAction{closeRecord, openRecord}
+		actionRecord.Note = fmt.Sprintf("反手未完成：%s %.4f 已平仓，%s 失败，当前为空仓", closeSide, existing.Quantity, openAction)
+		log.Printf("⚠️ [%s] %s %s", at.name, d.Symbol, actionRecord.Note)
+		logger.Notify(fmt.Sprintf("⚠️ [%s] %s 反手未完成：已平%s仓 %.4f，开仓失败（%v），当前为空仓，请人工确认",
+			at.name, d.Symbol, closeSide, existing.Quantity, openErr))
+		return fmt.Errorf("反手开仓失败（%s 已平仓，当前空仓）: %w", closeSide, openErr)
+	}
+	openRecord.Success = true
+	actionRecord.Legs = []logger.DecisionAction{closeRecord, openRecord}
+	if openRecord.Note != "" {
+		actionRecord.Note = openRecord.Note
+	}
+
+	log.Printf("  ✓ 反手完成: %s %s -> %s", d.Symbol, closeSide, openAction)
+	return nil
+}
+
 // executeUpdateStopLossWithRecord 执行调整止损并记录详细信息
 func (at *AutoTrader) executeUpdateStopLossWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
 	log.Printf("  🎯 调整止损: %s → %.2f", decision.Symbol, decision.NewStopLoss)
@@ -2004,11 +3734,13 @@ func (at *AutoTrader) executeUpdateStopLossWithRecord(decision *decision.Decisio
 
 	// 调用交易所 API 修改止损（使用 available 可平数量）
 	quantity := math.Abs(available)
-	err = at.trader.SetStopLoss(decision.Symbol, positionSide, quantity, decision.NewStopLoss)
+	err = at.setStopLossWithRetry(decision.Symbol, positionSide, quantity, decision.NewStopLoss)
 	if err != nil {
 		return fmt.Errorf("修改止损失败: %w", err)
 	}
 
+	at.updatePyramidStopLoss(decision.Symbol+"_"+strings.ToLower(side), decision.NewStopLoss)
+
 	log.Printf("  ✓ 止损已调整: %.2f (当前价格: %.2f)", decision.NewStopLoss, marketData.CurrentPrice)
 	return nil
 }
@@ -2096,11 +3828,13 @@ func (at *AutoTrader) executeUpdateTakeProfitWithRecord(decision *decision.Decis
 
 	// 调用交易所 API 修改止盈（使用 available 可平数量）
 	quantity := math.Abs(available)
-	err = at.trader.SetTakeProfit(decision.Symbol, positionSide, quantity, decision.NewTakeProfit)
+	err = at.setTakeProfitWithRetry(decision.Symbol, positionSide, quantity, decision.NewTakeProfit)
 	if err != nil {
 		return fmt.Errorf("修改止盈失败: %w", err)
 	}
 
+	at.updatePyramidTakeProfit(decision.Symbol+"_"+strings.ToLower(side), decision.NewTakeProfit)
+
 	log.Printf("  ✓ 止盈已调整: %.2f (当前价格: %.2f)", decision.NewTakeProfit, marketData.CurrentPrice)
 	return nil
 }
@@ -2153,13 +3887,7 @@ func (at *AutoTrader) executePartialCloseWithRecord(decision *decision.Decision,
 	actionRecord.Quantity = closeQuantity
 
 	// 执行平仓
-	var order map[string]interface{}
-	if positionSide == "LONG" {
-		order, err = at.trader.CloseLong(decision.Symbol, closeQuantity)
-	} else {
-		order, err = at.trader.CloseShort(decision.Symbol, closeQuantity)
-	}
-
+	order, err := at.closePositionQuantity(decision.Symbol, strings.ToLower(positionSide), closeQuantity)
 	if err != nil {
 		return fmt.Errorf("部分平仓失败: %w", err)
 	}
@@ -2176,14 +3904,61 @@ func (at *AutoTrader) executePartialCloseWithRecord(decision *decision.Decision,
 	return nil
 }
 
-// GetID 获取trader ID
-func (at *AutoTrader) GetID() string {
-	return at.id
+// GetID 获取trader ID
+func (at *AutoTrader) GetID() string {
+	return at.id
+}
+
+// countAICallToday 实际发出AI请求后累加当日AI调用次数（跨天自动清零）
+func (at *AutoTrader) countAICallToday() {
+	today := time.Now().Format("2006-01-02")
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	if at.aiCallsDay != today {
+		at.aiCallsDay = today
+		at.aiCallsToday = 0
+	}
+	at.aiCallsToday++
+}
+
+// GetAICallsToday 获取当日AI调用次数
+func (at *AutoTrader) GetAICallsToday() int {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	if at.aiCallsDay != time.Now().Format("2006-01-02") {
+		return 0
+	}
+	return at.aiCallsToday
+}
+
+// GetName 获取trader名称
+func (at *AutoTrader) GetName() string {
+	return at.name
+}
+
+// PublicName 获取公开接口展示的名称（未设置展示名称时使用交易员名称）
+func (at *AutoTrader) PublicName() string {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	if at.config.PublicDisplayName != "" {
+		return at.config.PublicDisplayName
+	}
+	return at.name
+}
+
+// IsPublic 是否在公开接口（排行榜/竞赛/收益对比）中展示
+func (at *AutoTrader) IsPublic() bool {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return at.config.PublicVisibility
 }
 
-// GetName 获取trader名称
-func (at *AutoTrader) GetName() string {
-	return at.name
+// SetPublicProfile 运行时更新公开展示名称和可见性
+func (at *AutoTrader) SetPublicProfile(displayName string, visible bool) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.PublicDisplayName = displayName
+	at.config.PublicVisibility = visible
 }
 
 // GetAIModel 获取AI模型
@@ -2191,6 +3966,26 @@ func (at *AutoTrader) GetAIModel() string {
 	return at.aiModel
 }
 
+// GetAIModelName 获取当前配置的AI模型名称（如 deepseek-chat、qwen3-max）
+func (at *AutoTrader) GetAIModelName() string {
+	if at.mcpClient == nil {
+		return ""
+	}
+	return at.mcpClient.Model
+}
+
+// SetAISampling 运行时更新AI采样参数（temperature/top_p/max_tokens）
+func (at *AutoTrader) SetAISampling(params mcp.SamplingParams) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.AITemperature = params.Temperature
+	at.config.AITopP = params.TopP
+	at.config.AIMaxTokens = params.MaxTokens
+	if at.mcpClient != nil {
+		at.mcpClient.SetSamplingParams(params)
+	}
+}
+
 // GetExchange 获取交易所
 func (at *AutoTrader) GetExchange() string {
 	return at.exchange
@@ -2238,19 +4033,32 @@ func (at *AutoTrader) GetStatus() map[string]interface{} {
 	}
 
 	return map[string]interface{}{
-		"trader_id":       at.id,
-		"trader_name":     at.name,
-		"ai_model":        at.aiModel,
-		"exchange":        at.exchange,
-		"is_running":      at.isRunning,
-		"start_time":      at.startTime.Format(time.RFC3339),
-		"runtime_minutes": int(time.Since(at.startTime).Minutes()),
-		"call_count":      at.callCount,
-		"initial_balance": at.initialBalance,
-		"scan_interval":   at.config.ScanInterval.String(),
-		"stop_until":      at.stopUntil.Format(time.RFC3339),
-		"last_reset_time": at.lastResetTime.Format(time.RFC3339),
-		"ai_provider":     aiProvider,
+		"trader_id":        at.id,
+		"trader_name":      at.name,
+		"ai_model":         at.aiModel,
+		"exchange":         at.exchange,
+		"is_running":       at.isRunning,
+		"start_time":       at.startTime.Format(time.RFC3339),
+		"runtime_minutes":  int(time.Since(at.startTime).Minutes()),
+		"call_count":       at.callCount,
+		"initial_balance":  at.initialBalance,
+		"scan_interval":    at.config.ScanInterval.String(),
+		"stop_until":       at.stopUntil.Format(time.RFC3339),
+		"last_reset_time":  at.lastResetTime.Format(time.RFC3339),
+		"ai_provider":      aiProvider,
+		"analysis_only":    at.IsAnalysisOnly(),
+		"equity_bracket":   at.equityBracketStatus(),
+		"order_throttle":   OrderThrottleStats(at.exchange),
+		"exchange_key":     at.activeExchangeKey(),
+		"trading_schedule": at.tradingScheduleStatus(),
+		"pool_degraded":    at.CoinPoolDegraded(),
+
+		"exchange_maintenance": at.exchangeMaintenanceStatus(),
+		"drawdown_stop":        at.drawdownStopStatus(),
+		"clock_skew":           at.clockSkewStatus(),
+		"unavailable_symbols":  at.symbolUnavailableStatus(),
+		"ai_anomaly":           at.aiAnomalyStatus(),
+		"margin_deficit":       at.marginDeficitStatus(),
 	}
 }
 
@@ -2290,21 +4098,9 @@ func (at *AutoTrader) GetAccountInfo() (map[string]interface{}, error) {
 
 	totalMarginUsed := 0.0
 	totalUnrealizedPnL := 0.0
-	for _, pos := range positions {
-		markPrice := pos["markPrice"].(float64)
-		quantity := pos["positionAmt"].(float64)
-		if quantity < 0 {
-			quantity = -quantity
-		}
-		unrealizedPnl := pos["unRealizedProfit"].(float64)
-		totalUnrealizedPnL += unrealizedPnl
-
-		leverage := 10
-		if lev, ok := pos["leverage"].(float64); ok {
-			leverage = int(lev)
-		}
-		marginUsed := (quantity * markPrice) / float64(leverage)
-		totalMarginUsed += marginUsed
+	for _, pos := range NormalizePositions(positions) {
+		totalUnrealizedPnL += pos.UnrealizedPnL
+		totalMarginUsed += pos.MarginUsed()
 	}
 
 	totalPnL := totalEquity - at.initialBalance
@@ -2318,7 +4114,7 @@ func (at *AutoTrader) GetAccountInfo() (map[string]interface{}, error) {
 		marginUsedPct = (totalMarginUsed / totalEquity) * 100
 	}
 
-	return map[string]interface{}{
+	info := map[string]interface{}{
 		// 核心字段
 		"total_equity":      totalEquity,           // 账户净值 = wallet + unrealized
 		"wallet_balance":    totalWalletBalance,    // 钱包余额（不含未实现盈亏）
@@ -2336,7 +4132,13 @@ func (at *AutoTrader) GetAccountInfo() (map[string]interface{}, error) {
 		"position_count":  len(positions),  // 持仓数量
 		"margin_used":     totalMarginUsed, // 保证金占用
 		"margin_used_pct": marginUsedPct,   // 保证金使用率
-	}, nil
+	}
+
+	// 周期盈亏（启用 baseline_reset_policy 时，按当前周期基准计算）
+	for key, value := range at.periodPnLFields(totalEquity) {
+		info[key] = value
+	}
+	return info, nil
 }
 
 // GetPositions 获取持仓列表（用于API）
@@ -2347,39 +4149,23 @@ func (at *AutoTrader) GetPositions() ([]map[string]interface{}, error) {
 	}
 
 	var result []map[string]interface{}
-	for _, pos := range positions {
-		symbol := pos["symbol"].(string)
-		side := pos["side"].(string)
-		entryPrice := pos["entryPrice"].(float64)
-		markPrice := pos["markPrice"].(float64)
-		quantity := pos["positionAmt"].(float64)
-		if quantity < 0 {
-			quantity = -quantity
-		}
-		unrealizedPnl := pos["unRealizedProfit"].(float64)
-		liquidationPrice := pos["liquidationPrice"].(float64)
-
-		leverage := 10
-		if lev, ok := pos["leverage"].(float64); ok {
-			leverage = int(lev)
-		}
-
+	for _, pos := range NormalizePositions(positions) {
 		// 计算占用保证金
-		marginUsed := (quantity * markPrice) / float64(leverage)
+		marginUsed := pos.MarginUsed()
 
 		// 计算盈亏百分比（基于保证金）
-		pnlPct := calculatePnLPercentage(unrealizedPnl, marginUsed)
+		pnlPct := calculatePnLPercentage(pos.UnrealizedPnL, marginUsed)
 
 		result = append(result, map[string]interface{}{
-			"symbol":             symbol,
-			"side":               side,
-			"entry_price":        entryPrice,
-			"mark_price":         markPrice,
-			"quantity":           quantity,
-			"leverage":           leverage,
-			"unrealized_pnl":     unrealizedPnl,
+			"symbol":             pos.Symbol,
+			"side":               pos.Side,
+			"entry_price":        pos.EntryPrice,
+			"mark_price":         pos.MarkPrice,
+			"quantity":           pos.Quantity,
+			"leverage":           pos.Leverage,
+			"unrealized_pnl":     pos.UnrealizedPnL,
 			"unrealized_pnl_pct": pnlPct,
-			"liquidation_price":  liquidationPrice,
+			"liquidation_price":  pos.LiquidationPrice,
 			"margin_used":        marginUsed,
 		})
 	}
@@ -2410,8 +4196,8 @@ func sortDecisionsByPriority(decisions []decision.Decision) []decision.Decision
 			return 1 // 最高优先级：先平仓（包括部分平仓）
 		case "update_stop_loss", "update_take_profit":
 			return 2 // 调整持仓止盈止损
-		case "open_long", "open_short":
-			return 3 // 次优先级：后开仓
+		case "open_long", "open_short", "flip_long", "flip_short":
+			return 3 // 次优先级：后开仓（反手内部自行先平后开）
 		case "hold", "wait":
 			return 4 // 最低优先级：观望
 		default:
@@ -2435,9 +4221,10 @@ func sortDecisionsByPriority(decisions []decision.Decision) []decision.Decision
 	return sorted
 }
 
-// getCandidateCoins 获取交易员的候选币种列表
+// getCandidateCoins 获取交易员的候选币种列表（candidate_symbols 优先，其次 trading_symbols，均未配置时使用默认币种/币种池）
 func (at *AutoTrader) getCandidateCoins() ([]decision.CandidateCoin, error) {
-	if len(at.tradingCoins) == 0 {
+	universe := at.candidateUniverse()
+	if len(universe) == 0 {
 		// 使用数据库配置的默认币种列表
 		var candidateCoins []decision.CandidateCoin
 
@@ -2458,8 +4245,12 @@ func (at *AutoTrader) getCandidateCoins() ([]decision.CandidateCoin, error) {
 			const ai500Limit = 20 // AI500取前20个评分最高的币种
 
 			mergedPool, err := pool.GetMergedCoinPool(ai500Limit)
+			if err == nil && len(mergedPool.AllSymbols) == 0 {
+				err = fmt.Errorf("币种池为空")
+			}
 			if err != nil {
-				return nil, fmt.Errorf("获取合并币种池失败: %w", err)
+				// 币种池服务不可用时降级，避免整个周期失败
+				return at.fallbackCandidateCoins(err), nil
 			}
 
 			// 构建候选币种列表（包含来源信息）
@@ -2473,12 +4264,13 @@ func (at *AutoTrader) getCandidateCoins() ([]decision.CandidateCoin, error) {
 
 			log.Printf("📋 [%s] 数据库无默认币种配置，使用AI500+OI Top: AI500前%d + OI_Top20 = 总计%d个候选币种",
 				at.name, ai500Limit, len(candidateCoins))
+			at.rememberCoinPool(candidateCoins)
 			return candidateCoins, nil
 		}
 	} else {
 		// 使用自定义币种列表
 		var candidateCoins []decision.CandidateCoin
-		for _, coin := range at.tradingCoins {
+		for _, coin := range universe {
 			// 确保币种格式正确（转为大写USDT交易对）
 			symbol := normalizeSymbol(coin)
 			candidateCoins = append(candidateCoins, decision.CandidateCoin{
@@ -2488,11 +4280,16 @@ func (at *AutoTrader) getCandidateCoins() ([]decision.CandidateCoin, error) {
 		}
 
 		log.Printf("📋 [%s] 使用自定义币种: %d个币种 %v",
-			at.name, len(candidateCoins), at.tradingCoins)
+			at.name, len(candidateCoins), universe)
 		return candidateCoins, nil
 	}
 }
 
+// GetCandidateCoins 获取当前候选币种及来源（不调用AI、不下单），供API查询
+func (at *AutoTrader) GetCandidateCoins() ([]decision.CandidateCoin, error) {
+	return at.getCandidateCoins()
+}
+
 // normalizeSymbol 标准化币种符号（确保以USDT结尾）
 func normalizeSymbol(symbol string) string {
 	// 转为大写
@@ -2537,32 +4334,28 @@ func (at *AutoTrader) checkPositionDrawdown() {
 		log.Printf("❌ 回撤监控：获取持仓失败: %v", err)
 		return
 	}
+	normalized := NormalizePositions(positions)
 
-	for _, pos := range positions {
-		symbol := pos["symbol"].(string)
-		side := pos["side"].(string)
-		entryPrice := pos["entryPrice"].(float64)
-		markPrice := pos["markPrice"].(float64)
-		quantity := pos["positionAmt"].(float64)
-		if quantity < 0 {
-			quantity = -quantity // 空仓数量为负，转为正数
-		}
+	// 止损接近提醒（未配置阈值时只清理已平仓持仓的提醒记录）
+	at.checkStopApproach(normalized)
 
-		// 计算当前盈亏百分比
-		leverage := 10 // 默认值
-		if lev, ok := pos["leverage"].(float64); ok {
-			leverage = int(lev)
+	for _, pos := range normalized {
+		symbol, side := pos.Symbol, pos.Side
+		// 开仓均价缺失时无法计算收益率，跳过
+		if pos.EntryPrice <= 0 {
+			continue
 		}
 
+		// 计算当前盈亏百分比
 		var currentPnLPct float64
 		if side == "long" {
-			currentPnLPct = ((markPrice - entryPrice) / entryPrice) * float64(leverage) * 100
+			currentPnLPct = ((pos.MarkPrice - pos.EntryPrice) / pos.EntryPrice) * float64(pos.Leverage) * 100
 		} else {
-			currentPnLPct = ((entryPrice - markPrice) / entryPrice) * float64(leverage) * 100
+			currentPnLPct = ((pos.EntryPrice - pos.MarkPrice) / pos.EntryPrice) * float64(pos.Leverage) * 100
 		}
 
 		// 构造持仓唯一标识（区分多空）
-		posKey := symbol + "_" + side
+		posKey := pos.Key()
 
 		// 获取该持仓的历史最高收益
 		at.peakPnLCacheMutex.RLock()
@@ -2578,6 +4371,9 @@ func (at *AutoTrader) checkPositionDrawdown() {
 			at.UpdatePeakPnL(symbol, side, currentPnLPct)
 		}
 
+		// 盈利保护：按峰值收益率上移止损（保本/跟踪）
+		at.applyProfitProtection(pos, currentPnLPct, math.Max(peakPnLPct, currentPnLPct))
+
 		// 计算回撤（从最高点下跌的幅度）
 		var drawdownPct float64
 		if peakPnLPct > 0 && currentPnLPct < peakPnLPct {
@@ -2594,6 +4390,15 @@ func (at *AutoTrader) checkPositionDrawdown() {
 				log.Printf("❌ 回撤平仓失败 (%s %s): %v", symbol, side, err)
 			} else {
 				log.Printf("✅ 回撤平仓成功: %s %s", symbol, side)
+				at.emitEvent(logger.EventDrawdownClose,
+					fmt.Sprintf("🚨 [%s] %s %s 触发回撤平仓: 收益 %.2f%%（最高 %.2f%%，回撤 %.2f%%）", at.name, symbol, side, currentPnLPct, peakPnLPct, drawdownPct),
+					map[string]interface{}{
+						"symbol":       symbol,
+						"side":         side,
+						"pnl_pct":      currentPnLPct,
+						"peak_pnl_pct": peakPnLPct,
+						"drawdown_pct": drawdownPct,
+					})
 				// 平仓后清理该持仓的缓存
 				at.ClearPeakPnLCache(symbol, side)
 			}
@@ -2605,6 +4410,275 @@ func (at *AutoTrader) checkPositionDrawdown() {
 	}
 }
 
+// touchPositionFirstSeen 返回持仓首次出现时间，未记录过则以 now 记录（新持仓）
+func (at *AutoTrader) touchPositionFirstSeen(posKey string, now int64) int64 {
+	at.positionFirstSeenMu.Lock()
+	firstSeen, exists := at.positionFirstSeenTime[posKey]
+	if !exists {
+		firstSeen = now
+		at.positionFirstSeenTime[posKey] = now
+	}
+	at.positionFirstSeenMu.Unlock()
+
+	if !exists {
+		at.persistPositionFirstSeen()
+	}
+	return firstSeen
+}
+
+// setPositionFirstSeen 记录持仓首次出现时间（开仓时调用，覆盖旧值）
+func (at *AutoTrader) setPositionFirstSeen(posKey string, ts int64) {
+	at.positionFirstSeenMu.Lock()
+	at.positionFirstSeenTime[posKey] = ts
+	at.positionFirstSeenMu.Unlock()
+	at.persistPositionFirstSeen()
+}
+
+// prunePositionFirstSeen 清理已不存在的持仓记录
+func (at *AutoTrader) prunePositionFirstSeen(currentPositionKeys map[string]bool) {
+	at.positionFirstSeenMu.Lock()
+	changed := false
+	for key := range at.positionFirstSeenTime {
+		if !currentPositionKeys[key] {
+			delete(at.positionFirstSeenTime, key)
+			changed = true
+		}
+	}
+	at.positionFirstSeenMu.Unlock()
+
+	if changed {
+		at.persistPositionFirstSeen()
+	}
+}
+
+// forgetPositionFirstSeen 删除单个持仓的首次出现时间（平仓后调用）
+func (at *AutoTrader) forgetPositionFirstSeen(posKey string) {
+	at.positionFirstSeenMu.Lock()
+	delete(at.positionFirstSeenTime, posKey)
+	at.positionFirstSeenMu.Unlock()
+	at.persistPositionFirstSeen()
+}
+
+// persistPositionFirstSeen 将持仓首次出现时间保存到数据库，保证重启后持仓时长不被重置
+func (at *AutoTrader) persistPositionFirstSeen() {
+	db, ok := at.database.(*sysconfig.Database)
+	if !ok || db == nil {
+		return
+	}
+
+	at.positionFirstSeenMu.Lock()
+	snapshot := make(map[string]int64, len(at.positionFirstSeenTime))
+	for k, v := range at.positionFirstSeenTime {
+		snapshot[k] = v
+	}
+	at.positionFirstSeenMu.Unlock()
+
+	if err := db.SaveTraderPositionFirstSeen(at.id, snapshot); err != nil {
+		log.Printf("⚠️ [%s] 保存持仓首次出现时间失败: %v", at.name, err)
+	}
+}
+
+// loadPositionFirstSeen 启动时从数据库恢复持仓首次出现时间
+func (at *AutoTrader) loadPositionFirstSeen() {
+	db, ok := at.database.(*sysconfig.Database)
+	if !ok || db == nil {
+		return
+	}
+
+	firstSeen, err := db.GetTraderPositionFirstSeen(at.id)
+	if err != nil {
+		log.Printf("⚠️ [%s] 恢复持仓首次出现时间失败: %v", at.name, err)
+		return
+	}
+
+	at.positionFirstSeenMu.Lock()
+	for k, v := range firstSeen {
+		if _, exists := at.positionFirstSeenTime[k]; !exists {
+			at.positionFirstSeenTime[k] = v
+		}
+	}
+	at.positionFirstSeenMu.Unlock()
+
+	if len(firstSeen) > 0 {
+		log.Printf("📂 [%s] 已恢复 %d 个持仓的首次出现时间", at.name, len(firstSeen))
+	}
+}
+
+// positionAgeCheckInterval 持仓时长检查间隔
+const positionAgeCheckInterval = 5 * time.Minute
+
+// startPositionAgeMonitor 启动持仓时长监控
+func (at *AutoTrader) startPositionAgeMonitor() {
+	at.monitorWg.Add(1)
+	go func() {
+		defer at.monitorWg.Done()
+
+		ticker := time.NewTicker(positionAgeCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				// 与决策周期互斥，避免和AI决策同时操作同一持仓
+				if err := at.runExclusiveCycle(func() { at.checkPositionAge(time.Now()) }); err != nil {
+					log.Printf("⏭ [%s] 持仓时长检查: %v", at.name, err)
+				}
+			case <-at.stopMonitorCh:
+				return
+			}
+		}
+	}()
+}
+
+// checkPositionAge 检查持仓时长，超过 MaxPositionAgeHours 的持仓通过 emergencyClosePosition 平仓并推送通知
+// 仅分析模式下只推送提醒，不平仓
+func (at *AutoTrader) checkPositionAge(now time.Time) {
+	at.mu.RLock()
+	maxAgeHours := at.config.MaxPositionAgeHours
+	analysisOnly := at.config.AnalysisOnly
+	at.mu.RUnlock()
+	if maxAgeHours <= 0 {
+		return
+	}
+	maxAge := time.Duration(maxAgeHours) * time.Hour
+
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		log.Printf("❌ 持仓时长监控：获取持仓失败: %v", err)
+		return
+	}
+
+	for _, pos := range positions {
+		symbol, _ := pos["symbol"].(string)
+		side, _ := pos["side"].(string)
+		if positionAmt, _ := pos["positionAmt"].(float64); positionAmt == 0 {
+			continue
+		}
+
+		posKey := symbol + "_" + side
+		firstSeen := at.touchPositionFirstSeen(posKey, now.UnixMilli())
+		age := now.Sub(time.UnixMilli(firstSeen))
+		if age < maxAge {
+			continue
+		}
+
+		if analysisOnly {
+			if at.markPositionAgeNotified(posKey, firstSeen) {
+				logger.Notify(fmt.Sprintf("⏰ [%s] %s %s 已持仓 %.1f 小时，超过上限 %d 小时（仅分析模式，未平仓）", at.name, symbol, side, age.Hours(), maxAgeHours))
+			}
+			continue
+		}
+
+		log.Printf("⏰ 持仓超时平仓: %s %s | 已持仓 %.1f 小时 | 上限 %d 小时", symbol, side, age.Hours(), maxAgeHours)
+		if err := at.emergencyClosePosition(symbol, side); err != nil {
+			log.Printf("❌ 持仓超时平仓失败 (%s %s): %v", symbol, side, err)
+			logger.Notify(fmt.Sprintf("❌ [%s] %s %s 持仓超时平仓失败: %v", at.name, symbol, side, err))
+			continue
+		}
+
+		at.ClearPeakPnLCache(symbol, side)
+		at.forgetPositionFirstSeen(posKey)
+		logger.Notify(fmt.Sprintf("⏰ [%s] %s %s 已持仓 %.1f 小时，超过上限 %d 小时，已自动平仓", at.name, symbol, side, age.Hours(), maxAgeHours))
+	}
+}
+
+// markPositionAgeNotified 标记持仓已发送超时提醒，首次标记返回 true（按首次出现时间区分同一币种方向的新持仓）
+func (at *AutoTrader) markPositionAgeNotified(posKey string, firstSeen int64) bool {
+	at.positionFirstSeenMu.Lock()
+	defer at.positionFirstSeenMu.Unlock()
+	if at.positionAgeNotified[posKey] == firstSeen {
+		return false
+	}
+	at.positionAgeNotified[posKey] = firstSeen
+	return true
+}
+
+// SetMaxPositionAgeHours 【功能】更新持仓最长持有时间（无需重启）
+func (at *AutoTrader) SetMaxPositionAgeHours(hours int) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.MaxPositionAgeHours = hours
+}
+
+// SetEnforceDailyLossStop 【功能】开启/关闭日亏损硬止损（无需重启）
+func (at *AutoTrader) SetEnforceDailyLossStop(enforce bool) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.EnforceDailyLossStop = enforce
+}
+
+// resetDailyPnL 日切时重置日盈亏，下个周期重新记录当日起始净值
+func (at *AutoTrader) resetDailyPnL() {
+	at.dailyPnL = 0
+	at.dayStartEquity = 0
+	at.dailyLossStopped = false
+	at.lastResetTime = time.Now()
+}
+
+// utcDayStart 返回 t 所在UTC自然日的0点
+func utcDayStart(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// checkDailyLossStop 更新当日盈亏（已实现+未实现，即净值变化），开启硬止损且当日亏损达到
+// MaxDailyLoss（相对初始余额的百分比）时平掉所有持仓，并暂停交易至UTC次日0点
+// （配置了 StopTradingTime 时按该时长暂停，但不会超过UTC次日0点）。返回是否触发
+// 暂停结束不会重置日盈亏基准，当日亏损仍超过上限时会再次触发
+func (at *AutoTrader) checkDailyLossStop(totalEquity float64, positions []decision.PositionInfo, now time.Time) bool {
+	if at.dayStartEquity <= 0 {
+		at.dayStartEquity = totalEquity
+	}
+	at.dailyPnL = totalEquity - at.dayStartEquity
+
+	at.mu.RLock()
+	enforce := at.config.EnforceDailyLossStop
+	maxDailyLoss := at.config.MaxDailyLoss
+	cooldown := at.config.StopTradingTime
+	analysisOnly := at.config.AnalysisOnly
+	at.mu.RUnlock()
+	if !enforce || maxDailyLoss <= 0 || at.initialBalance <= 0 {
+		return false
+	}
+
+	lossPct := -at.dailyPnL / at.initialBalance * 100
+	if lossPct < maxDailyLoss {
+		return false
+	}
+
+	at.stopUntil = utcDayStart(now).Add(24 * time.Hour)
+	if cooldown > 0 && now.Add(cooldown).Before(at.stopUntil) {
+		at.stopUntil = now.Add(cooldown)
+	}
+	at.dailyLossStopped = true
+	log.Printf("🛑 [%s] 触发日亏损硬止损: 当日亏损 %.2f%%（上限 %.2f%%），暂停交易至 %s",
+		at.name, lossPct, maxDailyLoss, at.stopUntil.Format(time.RFC3339))
+
+	// 仅分析模式不下单，只暂停并提醒
+	closed, failed := 0, 0
+	if !analysisOnly {
+		for _, pos := range positions {
+			if err := at.emergencyClosePosition(pos.Symbol, pos.Side); err != nil {
+				log.Printf("❌ 日亏损止损平仓失败 (%s %s): %v", pos.Symbol, pos.Side, err)
+				failed++
+				continue
+			}
+			at.ClearPeakPnLCache(pos.Symbol, pos.Side)
+			at.forgetPositionFirstSeen(pos.Symbol + "_" + pos.Side)
+			closed++
+		}
+	}
+
+	logger.Notify(fmt.Sprintf("🛑 [%s] 触发日亏损硬止损: 当日亏损 %.2f%%（上限 %.2f%%），已平仓 %d 个（失败 %d 个），暂停交易至 %s",
+		at.name, lossPct, maxDailyLoss, closed, failed, at.stopUntil.Format(time.RFC3339)))
+	return true
+}
+
 // 紧急平仓函数
 func (at *AutoTrader) emergencyClosePosition(symbol, side string) error {
 	switch side {
@@ -2624,6 +4698,7 @@ func (at *AutoTrader) emergencyClosePosition(symbol, side string) error {
 		return fmt.Errorf("未知的持仓方向: %s", side)
 	}
 
+	at.handleCloseResidual(symbol, side)
 	return nil
 }
 
@@ -2683,22 +4758,28 @@ func (at *AutoTrader) RunSignalMode() error {
 	reconcileTicker := time.NewTicker(20 * time.Second)
 	defer reconcileTicker.Stop()
 
-	// ⚡️ 仓位对账定时器（30分钟）：若仓位已消失则关闭策略，避免继续跑
-	positionAuditTicker := time.NewTicker(30 * time.Minute)
+	// ⚡️ 仓位对账定时器（默认30分钟，可配置）：若仓位已消失则关闭策略，避免继续跑；同时提示孤儿持仓
+	positionAuditTicker := time.NewTicker(at.positionAuditInterval())
 	defer positionAuditTicker.Stop()
 
-	// 启动时恢复已关闭策略缓存
+	// 启动时恢复已关闭策略缓存，并按交易所实际状态校正未关闭策略（须在注册监听前完成）
 	at.hydrateClosedStrategiesFromDB()
+	at.reconcileStrategyStatusesOnStartup()
 
-	// ⚡️ 策略更新监听：策略一到就立刻触发一次（避免“更新了不触发”）
+	// ⚡️ 策略更新监听：策略一到就立刻触发一次（避免“更新了不触发”），只接收订阅来源的策略
 	if signal.GlobalManager != nil {
-		signal.GlobalManager.RegisterListener(func(newStrat, prev *signal.SignalDecision) {
+		signal.GlobalManager.RegisterSourceListener(at.subscribesToStrategySource, func(newStrat, prev *signal.SignalDecision) {
 			if newStrat == nil {
 				return
 			}
 			if at.isStrategyClosed(newStrat.SignalID) {
 				return
 			}
+			// 按本交易员的仓位比例，底仓加全部补仓超过分配资金100%的计划直接拒绝
+			if !at.validSignalPlan(newStrat) {
+				log.Printf("⚠️ [signal-listener] %s 底仓加全部补仓比例合计超过100%%，拒绝该信号 id=%s", newStrat.Symbol, newStrat.SignalID)
+				return
+			}
 			receivedAt := at.getStrategyReceivedAt(newStrat.SignalID)
 			diff, report, missing, missingSL, missingTP := at.detectStrategyDiffFromExchange(newStrat, receivedAt)
 			if diff && at.shouldTriggerRepairAI(newStrat.SignalID) {
@@ -2713,16 +4794,26 @@ func (at *AutoTrader) RunSignalMode() error {
 	for at.isRunning {
 		select {
 		case <-reconcileTicker.C:
+			// 模拟 OCO：已平仓持仓的止损/止盈单其中一个成交后撤销另一个
+			at.reconcileOCOOrders()
 			// 快速自检：遍历所有活跃策略，只做差异检查；有差异立刻调用AI（把openOrders+history喂给AI）
 			if signal.GlobalManager == nil {
 				continue
 			}
-			snaps := signal.GlobalManager.ListActiveStrategies()
+			snaps := signal.GlobalManager.ListActiveStrategiesFor(at.subscribesToStrategySource)
+			// 第一止盈分批止盈：先于差异检查执行，避免同一轮内按旧仓位数量补单
+			strategies := make([]*signal.SignalDecision, 0, len(snaps))
+			for _, snap := range snaps {
+				if snap != nil && snap.Strategy != nil {
+					strategies = append(strategies, snap.Strategy)
+				}
+			}
+			at.checkTP1ScaleOut(strategies)
 			for _, snap := range snaps {
 				if snap == nil || snap.Strategy == nil {
 					continue
 				}
-				if at.isStrategyClosed(snap.Strategy.SignalID) {
+				if at.isStrategyClosed(snap.Strategy.SignalID) || !at.validSignalPlan(snap.Strategy) {
 					continue
 				}
 				diff, report, missing, missingSL, missingTP := at.detectStrategyDiffFromExchange(snap.Strategy, snap.Time)
@@ -2734,6 +4825,9 @@ func (at *AutoTrader) RunSignalMode() error {
 
 		case <-positionAuditTicker.C:
 			at.auditPositionsAndCloseFinishedStrategies()
+			at.warnOrphanPositions()
+			// 对账间隔可能已在运行时修改
+			positionAuditTicker.Reset(at.positionAuditInterval())
 
 		case <-ticker.C:
 			// 如果全局管理器未初始化或未启动，等待
@@ -2754,6 +4848,11 @@ func (at *AutoTrader) RunSignalMode() error {
 
 // CheckAndExecuteStrategy 检查当前状态并执行策略
 func (at *AutoTrader) CheckAndExecuteStrategy(strat *signal.SignalDecision) {
+	if hit := at.equityBracketPaused(); hit != "" {
+		log.Printf("⏸ [%s] 账户净值%s已触发，跳过信号执行", at.name, equityBracketLabel(hit))
+		return
+	}
+
 	// 1. 获取行情
 	marketData, err := market.Get(strat.Symbol)
 	if err != nil {
@@ -2767,13 +4866,11 @@ func (at *AutoTrader) CheckAndExecuteStrategy(strat *signal.SignalDecision) {
 
 	positions, err := at.trader.GetPositions()
 	if err == nil {
-		for _, pos := range positions {
-			if pos["symbol"] == strat.Symbol {
-				amt := pos["positionAmt"].(float64)
-				if amt != 0 {
-					currentQty = math.Abs(amt)
-					side := pos["side"].(string)
-					currentSide = strings.ToUpper(side)
+		for _, pos := range NormalizePositions(positions) {
+			if pos.Symbol == strat.Symbol {
+				if pos.Quantity != 0 {
+					currentQty = pos.Quantity
+					currentSide = strings.ToUpper(pos.Side)
 				}
 				break
 			}
@@ -2792,15 +4889,17 @@ func (at *AutoTrader) CheckAndExecuteStrategy(strat *signal.SignalDecision) {
 		} else {
 			at.trader.CloseShort(strat.Symbol, 0)
 		}
+		at.cancelOppositeDirectionOrders(strat.Symbol, strings.ToLower(targetSide))
 		return
 	}
 
 	// B. 计算期望仓位比例
-	// 基础仓位 (底仓)
-	expectedPercent := 0.2
+	// 基础仓位 (底仓)，比例由交易员配置决定
+	basePercent, addPercents := at.signalPlanPercents(strat)
+	expectedPercent := basePercent
 
 	// 加上所有已触发的补仓点
-	for _, add := range strat.Adds {
+	for i, add := range strat.Adds {
 		triggered := false
 		if targetSide == "LONG" && marketData.CurrentPrice <= add.Price {
 			triggered = true
@@ -2810,21 +4909,17 @@ func (at *AutoTrader) CheckAndExecuteStrategy(strat *signal.SignalDecision) {
 		}
 
 		if triggered {
-			p := add.Percent
-			if p == 0 {
-				p = 0.1
-			} // 默认补 10%
-			expectedPercent += p
+			expectedPercent += addPercents[i]
 		}
 	}
+	// 总仓位不超过分配资金的 100%（超配计划已在接收时拒绝，这里兜底运行时修改比例的情况）
+	if expectedPercent > 1.0 {
+		expectedPercent = 1.0
+	}
 
 	// C. 检查是否需要开仓/补仓
 	currentSizeUSD := currentQty * marketData.CurrentPrice
-	// 避免除以0
-	if at.initialBalance <= 0 {
-		at.initialBalance = 1000
-	} // 兜底
-	currentPercent := currentSizeUSD / at.initialBalance
+	currentPercent := currentSizeUSD / at.sizingBase()
 
 	// 如果当前仓位明显小于期望 (差距 > 5%)
 	if currentPercent < (expectedPercent - 0.05) {
@@ -2846,17 +4941,44 @@ func (at *AutoTrader) executeSignalTrade(strat *signal.SignalDecision, actionTyp
 	if percent <= 0 {
 		return
 	}
+	if !at.inTradingWindow(time.Now()) {
+		log.Printf("🌙 [%s] 当前不在交易时段，跳过信号 %s %s", at.name, strat.Symbol, actionType)
+		return
+	}
+	if MaintenanceMode() {
+		log.Printf("🛠 [%s] 平台维护中，跳过信号 %s %s", at.name, strat.Symbol, actionType)
+		return
+	}
+	if at.IsAIFailurePaused() {
+		log.Printf("⏸ [%s] AI决策失败后暂停开新仓，跳过信号 %s %s", at.name, strat.Symbol, actionType)
+		return
+	}
+	if at.drawdownStopHalted() {
+		log.Printf("🛑 [%s] 已触发最大回撤硬止损，跳过信号 %s %s", at.name, strat.Symbol, actionType)
+		return
+	}
+	if at.refreshMarginDeficit() {
+		log.Printf("🚨 [%s] 账户保证金不足（margin_deficit），跳过信号 %s %s", at.name, strat.Symbol, actionType)
+		return
+	}
 
 	// 计算下单金额
-	sizeUSD := at.initialBalance * percent
+	sizeUSD := at.sizingBase() * percent
 	quantity := sizeUSD / currentPrice
 	leverage := strat.LeverageRecommend
 	if leverage == 0 {
 		leverage = 5
 	}
+	if clamped, note := ClampLeverageToCeiling(strat.Symbol, leverage); note != "" {
+		log.Printf("⚠️ %s %s", strat.Symbol, note)
+		leverage = clamped
+	}
 
 	// 确定方向
 	isShort := strings.ToUpper(strat.Direction) == "SHORT"
+	if at.holdSignalForApproval(strat, isShort, sizeUSD, leverage, currentPrice) {
+		return
+	}
 
 	log.Printf("🚀 执行 %s: %s 数量: %.4f 杠杆: %d", actionType, strat.Symbol, quantity, leverage)
 
@@ -2872,10 +4994,9 @@ func (at *AutoTrader) executeSignalTrade(strat *signal.SignalDecision, actionTyp
 		return
 	}
 
-	// 设置止盈止损
-	slPrice := strat.StopLoss.Price
-	if len(strat.TakeProfits) > 0 {
-		tpPrice := strat.TakeProfits[0].Price
+	// 设置止盈止损（信号未给出时按默认百分比推导）
+	slPrice, tpPrice := at.signalProtectiveLevels(strat, currentPrice)
+	if slPrice > 0 || tpPrice > 0 {
 		side := "LONG"
 		if isShort {
 			side = "SHORT"
@@ -2884,18 +5005,18 @@ func (at *AutoTrader) executeSignalTrade(strat *signal.SignalDecision, actionTyp
 		// 重新获取总持仓以设置总SL/TP
 		positions, _ := at.trader.GetPositions()
 		totalQty := quantity
-		for _, p := range positions {
-			if p["symbol"] == strat.Symbol {
-				totalQty = math.Abs(p["positionAmt"].(float64))
+		for _, p := range NormalizePositions(positions) {
+			if p.Symbol == strat.Symbol {
+				totalQty = p.Quantity
 				break
 			}
 		}
 
 		if slPrice > 0 {
-			at.trader.SetStopLoss(strat.Symbol, side, totalQty, slPrice)
+			at.trader.SetStopLoss(strat.Symbol, side, totalQty, at.roundOrderPrice(strat.Symbol, slPrice, side == "SHORT"))
 		}
 		if tpPrice > 0 {
-			at.trader.SetTakeProfit(strat.Symbol, side, totalQty, tpPrice)
+			at.trader.SetTakeProfit(strat.Symbol, side, totalQty, at.roundOrderPrice(strat.Symbol, tpPrice, side == "SHORT"))
 		}
 	}
 }
@@ -2908,8 +5029,8 @@ type AIExecutionResult struct {
 }
 
 // convertDecisionToExecution 将通用 Decision 结构转换为单币种执行结果
-// 【功能】把老的 Decision JSON 结构适配为当前执行模块使用的结果格式
-func convertDecisionToExecution(decisions []decision.Decision, symbol string, initialBalance float64) AIExecutionResult {
+// 【功能】把老的 Decision JSON 结构适配为当前执行模块使用的结果格式，sizingBase 为仓位计算基数（见 AutoTrader.sizingBase）
+func convertDecisionToExecution(decisions []decision.Decision, symbol string, sizingBase float64) AIExecutionResult {
 	// 默认结果：安全等待
 	result := AIExecutionResult{
 		Action:        "WAIT",
@@ -2960,10 +5081,10 @@ func convertDecisionToExecution(decisions []decision.Decision, symbol string, in
 		result.Action = "WAIT"
 	}
 
-	// 计算资金占比：使用 position_size_usd / initialBalance
-	if chosen.PositionSizeUSD > 0 && initialBalance > 0 {
-		amt := math.Min(chosen.PositionSizeUSD, initialBalance)
-		pct := amt / initialBalance
+	// 计算资金占比：使用 position_size_usd / sizingBase
+	if chosen.PositionSizeUSD > 0 && sizingBase > 0 {
+		amt := math.Min(chosen.PositionSizeUSD, sizingBase)
+		pct := amt / sizingBase
 		if pct > 1 {
 			pct = 1
 		}
@@ -2994,37 +5115,17 @@ func (at *AutoTrader) placeMissingLimitOrdersFallback(
 		return
 	}
 
-	leverage := strat.LeverageRecommend
-	if leverage <= 0 {
-		leverage = 5
-	}
-	totalInvestmentUSD := at.initialBalance
-	if totalInvestmentUSD <= 0 {
-		totalInvestmentUSD = 1000
+	side := "buy"
+	if strings.ToUpper(strings.TrimSpace(strat.Direction)) == "SHORT" {
+		side = "sell"
 	}
+	totalInvestmentUSD := at.sizingBase()
 
 	for _, m := range missing {
-		if m.price <= 0 || m.percent <= 0 {
+		d := fallbackLimitOrderDecision(strat, m, totalInvestmentUSD)
+		if d == nil {
 			continue
 		}
-		marginUSD := totalInvestmentUSD * m.percent
-		notionalUSD := marginUSD * float64(leverage)
-
-		action := "place_long_order"
-		side := "buy"
-		if strings.ToUpper(strings.TrimSpace(strat.Direction)) == "SHORT" {
-			action = "place_short_order"
-			side = "sell"
-		}
-
-		d := &decision.Decision{
-			Symbol:          strat.Symbol,
-			Action:          action,
-			Leverage:        leverage,
-			PositionSizeUSD: notionalUSD,
-			Price:           m.price,
-			Reasoning:       "Fallback placement due to missing order detected by diff audit.",
-		}
 		ar := &logger.DecisionAction{
 			Symbol:    d.Symbol,
 			Action:    d.Action,
@@ -3051,6 +5152,7 @@ func (at *AutoTrader) placeMissingLimitOrdersFallback(
 			"signal_fallback",
 			"",
 			"",
+			mcp.ServedBy{}, // 确定性补单，未经过AI
 			execErr,
 		)
 	}
@@ -3058,9 +5160,28 @@ func (at *AutoTrader) placeMissingLimitOrdersFallback(
 
 // CheckAndExecuteStrategyWithAI 【功能】发现差异后调用AI，让AI依据当前委托+历史委托决定如何补齐
 func (at *AutoTrader) CheckAndExecuteStrategyWithAI(strat *signal.SignalDecision, extraDirective string, missing []expectedPoint, missingSL, missingTP bool) {
-	if strat != nil && at.isStrategyClosed(strat.SignalID) {
+	if strat == nil || at.isStrategyClosed(strat.SignalID) {
+		return
+	}
+
+	// 同一币种的信号执行互斥（监听回调与定时对账可能同时触发）
+	if err := at.runExclusiveForSymbol(strat.Symbol, func() {
+		at.checkAndExecuteStrategyWithAI(strat, extraDirective, missing, missingSL, missingTP, nil)
+	}); err != nil {
+		log.Printf("⏭ [%s] %s: %v", at.name, strat.Symbol, err)
+	}
+}
+
+// checkAndExecuteStrategyWithAI CheckAndExecuteStrategyWithAI 的实际执行逻辑（调用方需持有币种执行锁）
+// preview 非 nil 时为执行计划预览：只生成计划动作写入 preview，不下单、不记录决策历史、不占用AI调用间隔
+func (at *AutoTrader) checkAndExecuteStrategyWithAI(strat *signal.SignalDecision, extraDirective string, missing []expectedPoint, missingSL, missingTP bool, preview *SignalPreview) {
+	// 交易所维护退避期内不请求行情和AI
+	if until, active := at.exchangeMaintenanceActive(time.Now()); active {
+		log.Printf("🚧 [%s] 交易所维护中，跳过信号 %s（%s 后重试）", at.name, strat.Symbol, until.Format(time.RFC3339))
+		preview.fail(fmt.Errorf("交易所维护中，%s 后重试", until.Format(time.RFC3339)))
 		return
 	}
+
 	// 信号模式：每次执行前从DB同步最新配置，确保配置面板修改立即生效
 	at.syncTraderConfigFromDB()
 
@@ -3071,6 +5192,7 @@ func (at *AutoTrader) CheckAndExecuteStrategyWithAI(strat *signal.SignalDecision
 	klines1h, err := apiClient.GetKlines(strat.Symbol, "1h", 100)
 	if err != nil {
 		log.Printf("❌ 获取1h K线失败: %v", err)
+		preview.fail(fmt.Errorf("获取1h K线失败: %w", err))
 		return
 	}
 
@@ -3078,6 +5200,7 @@ func (at *AutoTrader) CheckAndExecuteStrategyWithAI(strat *signal.SignalDecision
 	klines4h, err := apiClient.GetKlines(strat.Symbol, "4h", 100)
 	if err != nil {
 		log.Printf("❌ 获取4h K线失败: %v", err)
+		preview.fail(fmt.Errorf("获取4h K线失败: %w", err))
 		return
 	}
 
@@ -3106,16 +5229,24 @@ func (at *AutoTrader) CheckAndExecuteStrategyWithAI(strat *signal.SignalDecision
 	// var unrealizedPnl float64 = 0
 
 	positions, err := at.trader.GetPositions()
+	if err != nil && isExchangeMaintenanceError(at.exchange, err) {
+		if preview != nil {
+			preview.fail(err)
+			return
+		}
+		at.enterExchangeMaintenance(err, time.Now())
+		return
+	}
 	if err == nil {
-		for _, pos := range positions {
-			if pos["symbol"] == strat.Symbol {
-				amt := pos["positionAmt"].(float64)
-				if amt != 0 {
-					currentQty = math.Abs(amt)
-					side := pos["side"].(string)
-					currentSide = strings.ToUpper(side)
-					avgPrice = pos["entryPrice"].(float64)
-					// unrealizedPnl = pos["unRealizedProfit"].(float64)
+		if preview == nil {
+			at.clearExchangeMaintenance()
+		}
+		for _, pos := range NormalizePositions(positions) {
+			if pos.Symbol == strat.Symbol {
+				if pos.Quantity != 0 {
+					currentQty = pos.Quantity
+					currentSide = strings.ToUpper(pos.Side)
+					avgPrice = pos.EntryPrice
 				}
 				break
 			}
@@ -3136,6 +5267,7 @@ func (at *AutoTrader) CheckAndExecuteStrategyWithAI(strat *signal.SignalDecision
 	promptContent, err := ioutil.ReadFile("prompts/strategy_executor.txt")
 	if err != nil {
 		log.Printf("❌ 读取Prompt模板失败: %v", err)
+		preview.fail(fmt.Errorf("读取Prompt模板失败: %w", err))
 		return
 	}
 
@@ -3144,14 +5276,14 @@ func (at *AutoTrader) CheckAndExecuteStrategyWithAI(strat *signal.SignalDecision
 	// 补齐模板缺失字段（避免前端/提示词残留{{...}}导致AI误判）
 	prevText := "N/A"
 	activeCount := 1
-	maxAlloc := at.initialBalance
+	maxAlloc := at.sizingBase()
 	activeSimple := []map[string]interface{}{}
 	if signal.GlobalManager != nil {
-		snaps := signal.GlobalManager.ListActiveStrategies()
+		snaps := signal.GlobalManager.ListActiveStrategiesFor(at.subscribesToStrategySource)
 		if len(snaps) > 0 {
 			activeCount = len(snaps)
 			if activeCount > 0 {
-				maxAlloc = at.initialBalance / float64(activeCount)
+				maxAlloc = maxAlloc / float64(activeCount)
 			}
 			for _, s := range snaps {
 				if s != nil && s.Strategy != nil {
@@ -3335,9 +5467,27 @@ func (at *AutoTrader) CheckAndExecuteStrategyWithAI(strat *signal.SignalDecision
 	log.Printf("[signal-ai] prompt assembled trader=%s symbol=%s template=%s system_prompt_len=%d input_prompt_len=%d",
 		at.id, strat.Symbol, sysTemplateName, len(systemPrompt), len(prompt))
 
-	resp, err := at.mcpClient.CallWithMessages(systemPrompt, prompt)
+	// AI调用间隔不足时跳过本次调用；止损/止盈缺失属于紧急保护，不受限制（预览不占用调用间隔）
+	urgent := missingSL || missingTP
+	reserveCall := func() (bool, time.Duration) {
+		if preview != nil {
+			return true, 0
+		}
+		return at.reserveAICall(time.Now(), urgent)
+	}
+	if ok, remaining := reserveCall(); !ok {
+		log.Printf("⏳ [signal-ai] %s 距上次AI调用不足最小间隔，跳过本次调用（剩余 %.0f 秒）", strat.Symbol, remaining.Seconds())
+		return
+	}
+	resp, served, err := at.mcpClient.CallWithMessagesServed(systemPrompt, prompt)
+	at.countAICallToday()
 	if err != nil {
 		log.Printf("❌ AI调用失败: %v", err)
+		if preview != nil {
+			preview.fail(fmt.Errorf("AI调用失败: %w", err))
+			return
+		}
+		at.recordAIFailure(strat.Symbol, err)
 		return
 	}
 
@@ -3345,8 +5495,16 @@ func (at *AutoTrader) CheckAndExecuteStrategyWithAI(strat *signal.SignalDecision
 	decisions, err := decision.ExtractDecisionsFromResponse(resp)
 	if err != nil {
 		log.Printf("❌ 解析AI结果失败: %v", err)
+		if preview != nil {
+			preview.fail(fmt.Errorf("解析AI结果失败: %w", err))
+			return
+		}
+		at.recordAIFailure(strat.Symbol, fmt.Errorf("解析AI结果失败: %w", err))
 		return
 	}
+	if preview == nil {
+		at.clearAIFailurePause()
+	}
 
 	// 6. 多动作逐条执行（避免“只补TP/SL不补入场/补仓”）
 	if len(decisions) == 0 {
@@ -3368,11 +5526,19 @@ func (at *AutoTrader) CheckAndExecuteStrategyWithAI(strat *signal.SignalDecision
 		// 二次强提示重试一次
 		retryDirective := diffDirective + " STRICT_MODE: You must output actions to fix the missing items. Do NOT output wait. Place limit orders for all missing entry/add prices."
 		promptRetry := strings.ReplaceAll(prompt, diffDirective, retryDirective)
-		resp2, err2 := at.mcpClient.CallWithMessages(systemPrompt, promptRetry)
+		// 重试同样受调用间隔限制，跳过时走下方的兜底补单
+		err2 := fmt.Errorf("距上次AI调用不足最小间隔，跳过重试")
+		var resp2 string
+		var served2 mcp.ServedBy
+		if ok, _ := reserveCall(); ok {
+			resp2, served2, err2 = at.mcpClient.CallWithMessagesServed(systemPrompt, promptRetry)
+			at.countAICallToday()
+		}
 		if err2 == nil {
 			if ds2, errx := decision.ExtractDecisionsFromResponse(resp2); errx == nil && len(ds2) > 0 {
 				decisions = ds2
 				resp = resp2
+				served = served2
 				hasActionable = false
 				for i := range decisions {
 					a := strings.ToLower(strings.TrimSpace(decisions[i].Action))
@@ -3385,9 +5551,17 @@ func (at *AutoTrader) CheckAndExecuteStrategyWithAI(strat *signal.SignalDecision
 		}
 	}
 
+	if preview != nil {
+		preview.AIResponse = resp
+	}
+
 	// 仍然 wait-only：走兜底补单，确保不是“只检查不执行”
 	if strings.Contains(diffDirective, "DIFF_DETECTED") && !hasActionable {
 		log.Printf("[signal-ai] wait-only on diff detected; fallback to deterministic limit placement symbol=%s", strat.Symbol)
+		if preview != nil {
+			preview.planFallback(strat, missing, at.sizingBase())
+			return
+		}
 		at.placeMissingLimitOrdersFallback(strat, missing, currentPrice, rsi1h, rsi4h, macdHist4h, currentSide, currentQty)
 		if missingSL || missingTP {
 			at.CheckStrategyCompletion(strat)
@@ -3457,6 +5631,12 @@ func (at *AutoTrader) CheckAndExecuteStrategyWithAI(strat *signal.SignalDecision
 			placedPrices[priceKey] = true
 		}
 
+		// 预览：在执行前截断，只记录计划动作
+		if preview != nil {
+			preview.Actions = append(preview.Actions, SignalPlannedAction{Decision: d, Source: "ai"})
+			continue
+		}
+
 		actionRecord := &logger.DecisionAction{
 			Symbol:    d.Symbol,
 			Action:    d.Action,
@@ -3473,7 +5653,7 @@ func (at *AutoTrader) CheckAndExecuteStrategyWithAI(strat *signal.SignalDecision
 			log.Printf("✅ [ai-exec] action=%s symbol=%s done", d.Action, d.Symbol)
 		}
 
-		at.saveStrategyDecisionHistoryFromDecision(strat, &d, actionRecord, currentPrice, rsi1h, rsi4h, macdHist4h, currentSide, currentQty, systemPrompt, prompt, resp, execErr)
+		at.saveStrategyDecisionHistoryFromDecision(strat, &d, actionRecord, currentPrice, rsi1h, rsi4h, macdHist4h, currentSide, currentQty, systemPrompt, prompt, resp, served, execErr)
 	}
 }
 
@@ -3482,17 +5662,39 @@ func (at *AutoTrader) executeAIAction(result AIExecutionResult, strat *signal.Si
 	if result.Action == "WAIT" {
 		return
 	}
+	if MaintenanceMode() && (strings.Contains(result.Action, "OPEN") || strings.Contains(result.Action, "ADD")) {
+		log.Printf("🛠 [%s] 平台维护中，跳过信号 %s %s", at.name, strat.Symbol, result.Action)
+		return
+	}
+	if at.IsAIFailurePaused() && (strings.Contains(result.Action, "OPEN") || strings.Contains(result.Action, "ADD")) {
+		log.Printf("⏸ [%s] AI决策失败后暂停开新仓，跳过信号 %s %s", at.name, strat.Symbol, result.Action)
+		return
+	}
+	if at.drawdownStopHalted() && (strings.Contains(result.Action, "OPEN") || strings.Contains(result.Action, "ADD")) {
+		log.Printf("🛑 [%s] 已触发最大回撤硬止损，跳过信号 %s %s", at.name, strat.Symbol, result.Action)
+		return
+	}
+	if (strings.Contains(result.Action, "OPEN") || strings.Contains(result.Action, "ADD")) && at.refreshMarginDeficit() {
+		log.Printf("🚨 [%s] 账户保证金不足（margin_deficit），跳过信号 %s %s", at.name, strat.Symbol, result.Action)
+		return
+	}
 
 	// 计算金额
-	if at.initialBalance <= 0 {
-		at.initialBalance = 1000
-	}
-	sizeUSD := at.initialBalance * result.AmountPercent
+	sizeUSD := at.sizingBase() * result.AmountPercent
 	quantity := sizeUSD / currentPrice
 	leverage := strat.LeverageRecommend
 	if leverage == 0 {
 		leverage = 5
 	}
+	if clamped, note := ClampLeverageToCeiling(strat.Symbol, leverage); note != "" {
+		log.Printf("⚠️ %s %s", strat.Symbol, note)
+		leverage = clamped
+	}
+
+	isOpen := strings.Contains(result.Action, "OPEN") || strings.Contains(result.Action, "ADD")
+	if isOpen && result.AmountPercent > 0 && at.holdSignalForApproval(strat, strings.Contains(result.Action, "SHORT"), sizeUSD, leverage, currentPrice) {
+		return
+	}
 
 	var err error
 
@@ -3520,7 +5722,7 @@ func (at *AutoTrader) executeAIAction(result AIExecutionResult, strat *signal.Si
 	} else {
 		// 成功后设置止盈止损 (如果是开仓/加仓)
 		if strings.Contains(result.Action, "OPEN") || strings.Contains(result.Action, "ADD") {
-			at.setStrategySLTP(strat, quantity)
+			at.setStrategySLTP(strat, quantity, currentPrice)
 			// 更新状态到数据库
 			at.updateStrategyStatus(strat.SignalID, strat.Symbol, result.Action, currentPrice, quantity, 0)
 
@@ -3538,33 +5740,30 @@ func (at *AutoTrader) executeAIAction(result AIExecutionResult, strat *signal.Si
 	}
 }
 
-// setStrategySLTP 设置策略的止盈止损
-func (at *AutoTrader) setStrategySLTP(strat *signal.SignalDecision, quantity float64) {
+// setStrategySLTP 设置策略的止盈止损（信号未给出时按默认百分比从当前价推导）
+func (at *AutoTrader) setStrategySLTP(strat *signal.SignalDecision, quantity, currentPrice float64) {
 	// 获取最新总持仓
 	positions, _ := at.trader.GetPositions()
 	totalQty := quantity
-	for _, p := range positions {
-		if p["symbol"] == strat.Symbol {
-			totalQty = math.Abs(p["positionAmt"].(float64))
+	for _, p := range NormalizePositions(positions) {
+		if p.Symbol == strat.Symbol {
+			totalQty = p.Quantity
 			break
 		}
 	}
 
-	slPrice := strat.StopLoss.Price
+	slPrice, tpPrice := at.signalProtectiveLevels(strat, currentPrice)
 	side := "LONG"
 	if strat.Direction == "SHORT" {
 		side = "SHORT"
 	}
 
 	if slPrice > 0 {
-		at.trader.SetStopLoss(strat.Symbol, side, totalQty, slPrice)
+		at.trader.SetStopLoss(strat.Symbol, side, totalQty, at.roundOrderPrice(strat.Symbol, slPrice, side == "SHORT"))
 	}
 
-	if len(strat.TakeProfits) > 0 {
-		tpPrice := strat.TakeProfits[0].Price
-		if tpPrice > 0 {
-			at.trader.SetTakeProfit(strat.Symbol, side, totalQty, tpPrice)
-		}
+	if tpPrice > 0 {
+		at.trader.SetTakeProfit(strat.Symbol, side, totalQty, at.roundOrderPrice(strat.Symbol, tpPrice, side == "SHORT"))
 	}
 }
 
@@ -3643,6 +5842,7 @@ func (at *AutoTrader) saveStrategyDecisionHistoryFromDecision(
 	positionSide string,
 	positionQty float64,
 	systemPrompt, inputPrompt, rawResponse string,
+	served mcp.ServedBy,
 	execErr error,
 ) {
 	if strat == nil || d == nil || at.database == nil {
@@ -3655,8 +5855,8 @@ func (at *AutoTrader) saveStrategyDecisionHistoryFromDecision(
 	}
 
 	amtPct := 0.0
-	if at.initialBalance > 0 && d.PositionSizeUSD > 0 {
-		amtPct = d.PositionSizeUSD / at.initialBalance
+	if d.PositionSizeUSD > 0 {
+		amtPct = d.PositionSizeUSD / at.sizingBase()
 		if amtPct > 1 {
 			amtPct = 1
 		}
@@ -3685,6 +5885,9 @@ func (at *AutoTrader) saveStrategyDecisionHistoryFromDecision(
 		RawAIResponse:    rawResponse,
 		ExecutionSuccess: execErr == nil,
 		ExecutionError:   "",
+		AIProvider:       string(served.Provider),
+		AIModelName:      served.Model,
+		WasFallback:      served.WasFallback,
 	}
 	if actionRecord != nil {
 		if actionRecord.Reasoning != "" {
@@ -3722,18 +5925,11 @@ func (at *AutoTrader) CheckStrategyCompletion(strat *signal.SignalDecision) {
 	// 2. 检查是否有该策略的持仓
 	var posQty float64
 	var posSide string
-	for _, pos := range positions {
-		if pos["symbol"] == strat.Symbol {
-			amt := pos["positionAmt"].(float64)
-			if amt != 0 {
-				posQty = math.Abs(amt)
-				if amt > 0 {
-					posSide = "LONG"
-				} else {
-					posSide = "SHORT"
-				}
-				break
-			}
+	for _, pos := range NormalizePositions(positions) {
+		if pos.Symbol == strat.Symbol && pos.Quantity != 0 {
+			posQty = pos.Quantity
+			posSide = strings.ToUpper(pos.Side)
+			break
 		}
 	}
 
@@ -3749,17 +5945,7 @@ func (at *AutoTrader) CheckStrategyCompletion(strat *signal.SignalDecision) {
 		return
 	}
 
-	hasStopLoss := false
-	hasTakeProfit := false
-	for _, order := range openOrders {
-		orderType, _ := order["type"].(string)
-		if orderType == "stop_loss" || orderType == "loss_plan" || orderType == "pos_loss" {
-			hasStopLoss = true
-		}
-		if orderType == "take_profit" || orderType == "profit_plan" || orderType == "pos_profit" {
-			hasTakeProfit = true
-		}
-	}
+	hasStopLoss, hasTakeProfit := hasProtectiveOrders(openOrders)
 
 	// 4. 检查策略是否要求止损/止盈
 	needsStopLoss := strat.StopLoss.Price > 0 && !hasStopLoss
@@ -3776,7 +5962,7 @@ func (at *AutoTrader) CheckStrategyCompletion(strat *signal.SignalDecision) {
 
 	if needsStopLoss {
 		log.Printf("  🛡️ [二次检查] 自动补设止损: %.4f", strat.StopLoss.Price)
-		if err := at.trader.SetStopLoss(strat.Symbol, posSide, posQty, strat.StopLoss.Price); err != nil {
+		if err := at.setStopLossWithRetry(strat.Symbol, posSide, posQty, strat.StopLoss.Price); err != nil {
 			log.Printf("  ❌ [二次检查] 设置止损失败: %v", err)
 		}
 	}
@@ -3784,7 +5970,7 @@ func (at *AutoTrader) CheckStrategyCompletion(strat *signal.SignalDecision) {
 	if needsTakeProfit {
 		tpPrice := strat.TakeProfits[0].Price
 		log.Printf("  💰 [二次检查] 自动补设止盈: %.4f", tpPrice)
-		if err := at.trader.SetTakeProfit(strat.Symbol, posSide, posQty, tpPrice); err != nil {
+		if err := at.setTakeProfitWithRetry(strat.Symbol, posSide, posQty, tpPrice); err != nil {
 			log.Printf("  ❌ [二次检查] 设置止盈失败: %v", err)
 		}
 	}
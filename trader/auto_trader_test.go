@@ -3,15 +3,22 @@ package trader
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"math"
+	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"nofx/config"
 	"nofx/decision"
 	"nofx/logger"
 	"nofx/market"
+	"nofx/mcp"
 	"nofx/pool"
 	"nofx/signal"
+	"nofx/tracing"
 
 	"github.com/agiledragon/gomonkey/v2"
 	"github.com/stretchr/testify/suite"
@@ -104,6 +111,8 @@ func (s *AutoTraderTestSuite) SetupTest() {
 		callCount:             0,
 		isRunning:             false,
 		positionFirstSeenTime: make(map[string]int64),
+		positionAgeNotified:   make(map[string]int64),
+		positionPyramid:       make(map[string]pyramidState),
 		stopMonitorCh:         make(chan struct{}),
 		peakPnLCache:          make(map[string]float64),
 		lastBalanceSyncTime:   time.Now(),
@@ -248,6 +257,36 @@ func (s *AutoTraderTestSuite) TestPeakPnLCache() {
 	})
 }
 
+// TestGetMonitorState 测试内存监控状态快照：价格上涨后回撤监控更新的峰值收益能被读取到
+func (s *AutoTraderTestSuite) TestGetMonitorState() {
+	position := func(markPrice float64) []map[string]interface{} {
+		return []map[string]interface{}{
+			{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.1, "entryPrice": 50000.0, "markPrice": markPrice, "leverage": 1.0},
+		}
+	}
+	s.autoTrader.config.EnableDrawdownMonitor = true
+	s.autoTrader.stopUntil = time.Now().Add(30 * time.Minute)
+	s.autoTrader.setPositionFirstSeen("BTCUSDT_long", 1700000000000)
+
+	s.mockTrader.positions = position(51000)
+	s.autoTrader.checkPositionDrawdown()
+	state := s.autoTrader.GetMonitorState()
+	s.Require().Len(state.Positions, 1)
+	s.Require().NotNil(state.Positions[0].PeakPnLPct)
+	s.InDelta(2.0, *state.Positions[0].PeakPnLPct, 1e-9)
+
+	// 价格继续上涨后峰值随之更新
+	s.mockTrader.positions = position(52000)
+	s.autoTrader.checkPositionDrawdown()
+	state = s.autoTrader.GetMonitorState()
+	s.Require().Len(state.Positions, 1)
+	s.Equal("BTCUSDT_long", state.Positions[0].Key)
+	s.InDelta(4.0, *state.Positions[0].PeakPnLPct, 1e-9)
+	s.Equal(int64(1700000000000), state.Positions[0].FirstSeenMs)
+	s.True(state.DrawdownMonitorEnabled)
+	s.True(state.Paused)
+}
+
 // ============================================================
 // 层次 4: GetStatus 测试
 // ============================================================
@@ -326,6 +365,79 @@ func (s *AutoTraderTestSuite) TestGetPositions() {
 	})
 }
 
+// TestNormalizePosition 测试交易所原始持仓到标准 Position 的转换
+func (s *AutoTraderTestSuite) TestNormalizePosition() {
+	s.Run("字符串数字和缺失字段", func() {
+		pos, ok := NormalizePosition(map[string]interface{}{
+			"symbol":      "BTCUSDT",
+			"side":        "LONG",
+			"entryPrice":  "50000",
+			"positionAmt": "0.1",
+			"markPrice":   nil,
+		})
+		s.True(ok)
+		s.Equal("long", pos.Side)
+		s.Equal(50000.0, pos.EntryPrice)
+		s.Equal(50000.0, pos.MarkPrice) // 标记价格缺失时回退为开仓均价
+		s.Equal(0.1, pos.Quantity)
+		s.Equal(0.1, pos.Available)
+		s.Equal(defaultPositionLeverage, pos.Leverage)
+		s.Equal(0.0, pos.UnrealizedPnL)
+	})
+
+	s.Run("缺失side时按数量正负推断", func() {
+		pos, ok := NormalizePosition(map[string]interface{}{"symbol": "ETHUSDT", "positionAmt": -0.5, "leverage": 5})
+		s.True(ok)
+		s.Equal("short", pos.Side)
+		s.Equal(0.5, pos.Quantity)
+		s.Equal(5, pos.Leverage)
+	})
+
+	s.Run("无法识别的条目被跳过", func() {
+		positions := NormalizePositions([]map[string]interface{}{
+			nil,
+			{"side": "long", "positionAmt": 1.0},     // 缺少 symbol
+			{"symbol": 123, "positionAmt": 1.0},      // symbol 类型错误
+			{"symbol": "BTCUSDT", "side": "unknown"}, // 方向无法确定
+			{"symbol": "SOLUSDT", "side": "short", "positionAmt": -2.0},
+		})
+		s.Equal(1, len(positions))
+		s.Equal("SOLUSDT", positions[0].Symbol)
+	})
+}
+
+// TestMalformedPositionsNoPanic 交易所返回残缺/类型异常的持仓时，各处读取持仓的逻辑都不应 panic
+func (s *AutoTraderTestSuite) TestMalformedPositionsNoPanic() {
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 50000.0}, nil
+	})
+
+	s.mockTrader.positions = []map[string]interface{}{
+		{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.1, "entryPrice": 50000.0, "markPrice": nil},
+		{"symbol": "ETHUSDT", "positionAmt": "-0.5", "entryPrice": "3000", "leverage": "abc"},
+		{"symbol": "SOLUSDT", "side": "short"},
+		{"positionAmt": 1.0},
+		{},
+	}
+	defer func() { s.mockTrader.positions = []map[string]interface{}{} }()
+
+	s.NotPanics(func() {
+		positions, err := s.autoTrader.GetPositions()
+		s.NoError(err)
+		s.Equal(3, len(positions))
+	})
+	s.NotPanics(func() {
+		_, err := s.autoTrader.GetAccountInfo()
+		s.NoError(err)
+	})
+	s.NotPanics(func() {
+		ctx, err := s.autoTrader.buildTradingContext()
+		s.NoError(err)
+		s.Equal(2, len(ctx.Positions)) // 数量为0的持仓不传给AI
+	})
+	s.NotPanics(func() { s.autoTrader.checkPositionDrawdown() })
+}
+
 // ============================================================
 // 层次 7: getCandidateCoins 测试
 // ============================================================
@@ -376,6 +488,37 @@ func (s *AutoTraderTestSuite) TestGetCandidateCoins() {
 
 		s.NoError(err)
 		s.Equal(2, len(coins))
+		s.False(s.autoTrader.CoinPoolDegraded())
+	})
+
+	s.Run("币种池服务不可用时使用上次成功的币种池", func() {
+		s.patches.ApplyFunc(pool.GetMergedCoinPool, func(ai500Limit int) (*pool.MergedCoinPool, error) {
+			return nil, errors.New("connection refused")
+		})
+
+		coins, err := s.autoTrader.getCandidateCoins()
+
+		s.NoError(err)
+		s.Equal(2, len(coins))
+		s.True(s.autoTrader.CoinPoolDegraded())
+		s.Equal(true, s.autoTrader.GetStatus()["pool_degraded"])
+	})
+
+	s.Run("无缓存时降级为默认主流币种并继续构建上下文", func() {
+		s.autoTrader.lastGoodCoinPool = nil
+		s.patches.ApplyFunc(pool.GetMergedCoinPool, func(ai500Limit int) (*pool.MergedCoinPool, error) {
+			return nil, errors.New("connection refused")
+		})
+		s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+			return &market.Data{Symbol: symbol, CurrentPrice: 50000.0}, nil
+		})
+
+		ctx, err := s.autoTrader.buildTradingContext()
+
+		s.Require().NoError(err)
+		s.Equal(len(pool.GetDefaultCoins()), len(ctx.CandidateCoins))
+		s.Contains(ctx.CandidateCoins[0].Sources, "default")
+		s.True(s.autoTrader.CoinPoolDegraded())
 	})
 }
 
@@ -401,6 +544,460 @@ func (s *AutoTraderTestSuite) TestBuildTradingContext() {
 	s.Equal(5, ctx.AltcoinLeverage)
 }
 
+// TestBuildTradingContextRecentClosedTrades 测试决策上下文按配置附带最近平仓交易
+func (s *AutoTraderTestSuite) TestBuildTradingContextRecentClosedTrades() {
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 50000.0}, nil
+	})
+	recent := []logger.TradeOutcome{
+		{Symbol: "ETHUSDT", Side: "short", OpenPrice: 3000, ClosePrice: 3100, PnL: -50, PnLPct: -16.7, Duration: "1h30m0s", OpenReasoning: "跌破4h支撑"},
+		{Symbol: "BTCUSDT", Side: "long", OpenPrice: 50000, ClosePrice: 51000, PnL: 100, PnLPct: 20, Duration: "3h0m0s", OpenReasoning: "突破前高"},
+		{Symbol: "SOLUSDT", Side: "long", OpenPrice: 150, ClosePrice: 155, PnL: 10, PnLPct: 6.7, Duration: "45m0s"},
+	}
+	s.patches.ApplyMethod(reflect.TypeOf(s.mockLogger), "AnalyzePerformance",
+		func(_ *logger.DecisionLogger, _ int) (*logger.PerformanceAnalysis, error) {
+			return &logger.PerformanceAnalysis{TotalTrades: len(recent), RecentTrades: recent}, nil
+		})
+
+	s.Run("开启时附带最近N笔平仓交易（含开仓理由）", func() {
+		s.autoTrader.SetRecentClosedTrades(true, 2)
+
+		ctx, err := s.autoTrader.buildTradingContext()
+
+		s.Require().NoError(err)
+		s.Require().Len(ctx.RecentClosedTrades, 2)
+		s.Equal(decision.ClosedTradeInfo{
+			Symbol: "ETHUSDT", Side: "short", EntryPrice: 3000, ExitPrice: 3100,
+			PnL: -50, PnLPct: -16.7, HoldingTime: "1h30m0s", Reasoning: "跌破4h支撑",
+		}, ctx.RecentClosedTrades[0])
+		s.Equal("BTCUSDT", ctx.RecentClosedTrades[1].Symbol)
+	})
+
+	s.Run("笔数未配置时使用默认值且不超过已有交易数", func() {
+		s.autoTrader.SetRecentClosedTrades(true, 0)
+
+		ctx, err := s.autoTrader.buildTradingContext()
+
+		s.Require().NoError(err)
+		s.Len(ctx.RecentClosedTrades, len(recent))
+	})
+
+	s.Run("关闭时不附带", func() {
+		s.autoTrader.SetRecentClosedTrades(false, 5)
+
+		ctx, err := s.autoTrader.buildTradingContext()
+
+		s.Require().NoError(err)
+		s.Empty(ctx.RecentClosedTrades)
+	})
+}
+
+// TestExcludeHeldCandidates 测试从候选币种中剔除已持仓币种
+func (s *AutoTraderTestSuite) TestExcludeHeldCandidates() {
+	coins := []decision.CandidateCoin{
+		{Symbol: "BTCUSDT", Sources: []string{"ai500"}},
+		{Symbol: "ETHUSDT", Sources: []string{"ai500"}},
+		{Symbol: "SOLUSDT", Sources: []string{"oi_top"}},
+	}
+
+	s.Run("无持仓时保持不变", func() {
+		s.Equal(coins, excludeHeldCandidates(coins, nil))
+	})
+
+	s.Run("剔除已持仓币种", func() {
+		positions := []decision.PositionInfo{{Symbol: "BTCUSDT", Side: "long"}, {Symbol: "ETHUSDT", Side: "short"}}
+		result := excludeHeldCandidates(coins, positions)
+		s.Equal(1, len(result))
+		s.Equal("SOLUSDT", result[0].Symbol)
+	})
+}
+
+// TestProtectiveOrderRetry 测试保护单在仓位不足（43023）时重新获取可平数量并重试
+func (s *AutoTraderTestSuite) TestProtectiveOrderRetry() {
+	originalDelay := protectiveOrderRetryDelay
+	protectiveOrderRetryDelay = 0
+	defer func() { protectiveOrderRetryDelay = originalDelay }()
+
+	insufficientErr := fmt.Errorf("set stop loss failed: %w", &exchangeAPIError{status: 200, code: "43023", text: "bitget api error: code=43023, msg=仓位不足"})
+
+	s.Run("可平数量短暂不足_重试成功", func() {
+		s.mockTrader.positions = []map[string]interface{}{
+			{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.5, "available": 0.3},
+		}
+		s.mockTrader.stopLossErrs = []error{insufficientErr}
+		s.mockTrader.stopLossQuantity = nil
+
+		err := s.autoTrader.setStopLossWithRetry("BTCUSDT", "LONG", 0.5, 48000)
+		s.NoError(err)
+		s.Equal([]float64{0.5, 0.3}, s.mockTrader.stopLossQuantity)
+	})
+
+	s.Run("持续仓位不足_重试耗尽后返回错误", func() {
+		s.mockTrader.positions = []map[string]interface{}{
+			{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.5, "available": 0.5},
+		}
+		s.mockTrader.stopLossErrs = []error{insufficientErr, insufficientErr, insufficientErr}
+		s.mockTrader.stopLossQuantity = nil
+
+		err := s.autoTrader.setStopLossWithRetry("BTCUSDT", "LONG", 0.5, 48000)
+		s.Error(err)
+		s.Equal(1+protectiveOrderMaxRetries, len(s.mockTrader.stopLossQuantity))
+	})
+
+	s.Run("其他错误不重试", func() {
+		s.mockTrader.stopLossErrs = []error{&exchangeAPIError{status: 200, code: "40762", text: "bitget api error: code=40762, msg=余额不足"}}
+		s.mockTrader.stopLossQuantity = nil
+
+		err := s.autoTrader.setStopLossWithRetry("BTCUSDT", "LONG", 0.5, 48000)
+		s.Error(err)
+		s.Equal(1, len(s.mockTrader.stopLossQuantity))
+	})
+
+	s.Run("只按解析出的错误码判断，不匹配错误文本", func() {
+		s.False(isInsufficientPositionError(errors.New("order 43023 failed: code=43023")))
+		s.True(isInsufficientPositionError(insufficientErr))
+	})
+}
+
+// TestWarmupRemaining 测试预热期按启动时间计算
+func (s *AutoTraderTestSuite) TestWarmupRemaining() {
+	start := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	s.autoTrader.startTime = start
+
+	s.Run("未配置预热", func() {
+		s.autoTrader.SetWarmupMinutes(0)
+		s.Equal(time.Duration(0), s.autoTrader.warmupRemaining(start))
+	})
+
+	s.Run("预热期内", func() {
+		s.autoTrader.SetWarmupMinutes(30)
+		s.Equal(20*time.Minute, s.autoTrader.warmupRemaining(start.Add(10*time.Minute)))
+	})
+
+	s.Run("预热已结束", func() {
+		s.autoTrader.SetWarmupMinutes(30)
+		s.True(s.autoTrader.warmupRemaining(start.Add(31*time.Minute)) <= 0)
+	})
+}
+
+// TestAdjustInitialBalanceForTransfers 测试按资金流水中的充值/提现调整初始余额
+func (s *AutoTraderTestSuite) TestAdjustInitialBalanceForTransfers() {
+	s.mockTrader.balanceHistory = []map[string]interface{}{
+		{"type": BalanceEventDeposit, "amount": 500.0, "asset": "USDT"},
+		{"type": BalanceEventWithdraw, "amount": -200.0, "asset": "USDT"},
+		{"type": BalanceEventRealizedPnL, "amount": 80.0, "asset": "USDT"},
+		{"type": BalanceEventCommission, "amount": -1.5, "asset": "USDT"},
+		{"type": BalanceEventDeposit, "amount": 1.0, "asset": "BNB"},
+	}
+
+	s.Run("只统计USDT充值提现净额", func() {
+		s.Equal(300.0, sumExternalTransfers(s.mockTrader.balanceHistory))
+	})
+
+	s.Run("未到检查间隔时跳过", func() {
+		s.autoTrader.initialBalance = 1000
+		s.autoTrader.lastTransferCheckAt = time.Now().UnixMilli()
+		s.autoTrader.adjustInitialBalanceForTransfers()
+		s.Equal(1000.0, s.autoTrader.initialBalance)
+	})
+
+	s.Run("按净额调整初始余额并持久化", func() {
+		s.autoTrader.initialBalance = 1000
+		s.autoTrader.lastTransferCheckAt = time.Now().Add(-transferCheckInterval - time.Minute).UnixMilli()
+		s.autoTrader.adjustInitialBalanceForTransfers()
+		s.Equal(1300.0, s.autoTrader.initialBalance)
+		s.Equal([]float64{300.0}, s.mockDB.adjustedDeltas)
+	})
+}
+
+// TestSetInitialBalanceBaseline 测试手动调整初始余额：之后的总盈亏按新基准计算，增量调整同步调整回撤峰值
+func (s *AutoTraderTestSuite) TestSetInitialBalanceBaseline() {
+	defer func() {
+		s.autoTrader.initialBalance = 10000
+		s.autoTrader.peakEquity = 0
+	}()
+
+	s.Run("直接设置新基准", func() {
+		s.autoTrader.initialBalance = 10000
+		s.autoTrader.peakEquity = 10500
+		previous := s.autoTrader.SetInitialBalanceBaseline(10050, false)
+		s.Equal(10000.0, previous)
+		s.Equal(10050.0, s.autoTrader.initialBalance)
+		s.Equal(10500.0, s.autoTrader.peakEquity, "纠正基准不应改变回撤峰值")
+
+		info, err := s.autoTrader.GetAccountInfo()
+		s.Require().NoError(err)
+		s.Equal(10050.0, info["initial_balance"])
+		s.InDelta(50.0, info["total_pnl"].(float64), 1e-9) // 10100 - 10050
+	})
+
+	s.Run("按已知提现增量调整", func() {
+		s.autoTrader.initialBalance = 10000
+		s.autoTrader.peakEquity = 10500
+		previous := s.autoTrader.SetInitialBalanceBaseline(10000-2000, true)
+		s.Equal(10000.0, previous)
+		s.Equal(8000.0, s.autoTrader.initialBalance)
+		s.Equal(8500.0, s.autoTrader.peakEquity)
+
+		info, err := s.autoTrader.GetAccountInfo()
+		s.Require().NoError(err)
+		s.InDelta(2100.0, info["total_pnl"].(float64), 1e-9) // 10100 - 8000
+	})
+}
+
+// TestCycleSerialization 测试并发触发的决策周期串行执行、互不重叠
+func (s *AutoTraderTestSuite) TestCycleSerialization() {
+	s.Run("默认等待_两次触发串行执行", func() {
+		s.autoTrader.SetSkipCycleIfBusy(false)
+
+		var active, maxActive, runs int32
+		cycle := func() {
+			n := atomic.AddInt32(&active, 1)
+			for {
+				m := atomic.LoadInt32(&maxActive)
+				if n <= m || atomic.CompareAndSwapInt32(&maxActive, m, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			atomic.AddInt32(&runs, 1)
+			atomic.AddInt32(&active, -1)
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.NoError(s.autoTrader.runExclusiveCycle(cycle))
+			}()
+		}
+		wg.Wait()
+
+		s.Equal(int32(2), atomic.LoadInt32(&runs))
+		s.Equal(int32(1), atomic.LoadInt32(&maxActive))
+	})
+
+	s.Run("开启跳过_执行中的触发返回ErrCycleInProgress", func() {
+		s.autoTrader.SetSkipCycleIfBusy(true)
+		defer s.autoTrader.SetSkipCycleIfBusy(false)
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+		done := make(chan error)
+		go func() {
+			done <- s.autoTrader.runExclusiveCycle(func() {
+				close(started)
+				<-release
+			})
+		}()
+		<-started
+
+		ran := false
+		err := s.autoTrader.runExclusiveCycle(func() { ran = true })
+		s.True(errors.Is(err, ErrCycleInProgress))
+		s.False(ran)
+
+		close(release)
+		s.NoError(<-done)
+	})
+
+	s.Run("信号模式按币种加锁_不同币种互不阻塞", func() {
+		s.autoTrader.SetSkipCycleIfBusy(true)
+		defer s.autoTrader.SetSkipCycleIfBusy(false)
+
+		err := s.autoTrader.runExclusiveForSymbol("BTCUSDT", func() {
+			s.True(errors.Is(s.autoTrader.runExclusiveForSymbol("BTCUSDT", func() {}), ErrCycleInProgress))
+			s.NoError(s.autoTrader.runExclusiveForSymbol("ETHUSDT", func() {}))
+		})
+		s.NoError(err)
+	})
+}
+
+// TestCheckPositionAge 测试超过最长持有时间的持仓被平仓
+func (s *AutoTraderTestSuite) TestCheckPositionAge() {
+	now := time.Now()
+	s.mockTrader.positions = []map[string]interface{}{
+		{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.1},
+		{"symbol": "ETHUSDT", "side": "long", "positionAmt": 1.0},
+	}
+	s.autoTrader.positionFirstSeenTime["BTCUSDT_long"] = now.Add(-5 * time.Hour).UnixMilli()
+	s.autoTrader.positionFirstSeenTime["ETHUSDT_long"] = now.Add(-3 * time.Hour).UnixMilli()
+
+	s.Run("未配置时不处理", func() {
+		s.autoTrader.SetMaxPositionAgeHours(0)
+		s.autoTrader.checkPositionAge(now)
+		s.Contains(s.autoTrader.positionFirstSeenTime, "BTCUSDT_long")
+	})
+
+	s.Run("超时持仓平仓_未超时保留", func() {
+		s.autoTrader.SetMaxPositionAgeHours(4)
+		s.autoTrader.checkPositionAge(now)
+		_, exists := s.autoTrader.positionFirstSeenTime["BTCUSDT_long"]
+		s.False(exists)
+		s.Contains(s.autoTrader.positionFirstSeenTime, "ETHUSDT_long")
+	})
+
+	s.Run("平仓失败时保留记录", func() {
+		s.autoTrader.positionFirstSeenTime["BTCUSDT_long"] = now.Add(-5 * time.Hour).UnixMilli()
+		s.mockTrader.shouldFailCloseLong = true
+		defer func() { s.mockTrader.shouldFailCloseLong = false }()
+
+		s.autoTrader.checkPositionAge(now)
+		s.Contains(s.autoTrader.positionFirstSeenTime, "BTCUSDT_long")
+	})
+
+	s.Run("仅分析模式下同一持仓只提醒一次", func() {
+		var notified []string
+		s.patches.ApplyFunc(logger.Notify, func(message string) {
+			notified = append(notified, message)
+		})
+		s.autoTrader.SetAnalysisOnly(true)
+		defer s.autoTrader.SetAnalysisOnly(false)
+		s.mockTrader.closedPositions = nil
+
+		s.autoTrader.checkPositionAge(now)
+		s.autoTrader.checkPositionAge(now.Add(5 * time.Minute))
+		s.Len(notified, 1)
+		s.Contains(notified[0], "BTCUSDT long")
+		s.Empty(s.mockTrader.closedPositions)
+
+		// 平仓后同一币种方向的新持仓重新计时，超时后再次提醒
+		s.autoTrader.positionFirstSeenTime["BTCUSDT_long"] = now.Add(-6 * time.Hour).UnixMilli()
+		s.autoTrader.checkPositionAge(now.Add(10 * time.Minute))
+		s.Len(notified, 2)
+	})
+}
+
+// TestDailyLossStop 测试日亏损硬止损：超过上限后平仓并暂停交易
+func (s *AutoTraderTestSuite) TestDailyLossStop() {
+	now := time.Date(2026, 1, 10, 15, 0, 0, 0, time.UTC)
+	positions := []decision.PositionInfo{{Symbol: "BTCUSDT", Side: "long", Quantity: 0.1}}
+	s.autoTrader.config.MaxDailyLoss = 5.0
+	defer func() {
+		s.autoTrader.SetEnforceDailyLossStop(false)
+		s.autoTrader.config.MaxDailyLoss = 0
+		s.autoTrader.resetDailyPnL()
+		s.autoTrader.stopUntil = time.Time{}
+	}()
+
+	s.Run("未开启时仅记录日盈亏", func() {
+		s.autoTrader.resetDailyPnL()
+		s.False(s.autoTrader.checkDailyLossStop(10000.0, positions, now))
+		s.False(s.autoTrader.checkDailyLossStop(9000.0, positions, now))
+		s.Equal(-1000.0, s.autoTrader.dailyPnL)
+		s.True(s.autoTrader.stopUntil.IsZero())
+	})
+
+	s.Run("亏损未达上限不触发", func() {
+		s.autoTrader.SetEnforceDailyLossStop(true)
+		s.autoTrader.resetDailyPnL()
+		s.False(s.autoTrader.checkDailyLossStop(10000.0, positions, now))
+		s.False(s.autoTrader.checkDailyLossStop(9600.0, positions, now))
+	})
+
+	s.Run("超过上限暂停至UTC次日并拒绝执行周期", func() {
+		s.autoTrader.SetEnforceDailyLossStop(true)
+		s.autoTrader.resetDailyPnL()
+		s.autoTrader.checkDailyLossStop(10000.0, positions, now)
+
+		s.True(s.autoTrader.checkDailyLossStop(9400.0, positions, now))
+		s.Equal(time.Date(2026, 1, 11, 0, 0, 0, 0, time.UTC), s.autoTrader.stopUntil)
+		s.True(s.autoTrader.dailyLossStopped)
+
+		// 暂停期间决策周期直接返回，不调用AI
+		s.autoTrader.stopUntil = time.Now().Add(time.Hour)
+		record, err := s.autoTrader.runCycleWithRecord()
+		s.NoError(err)
+		s.False(record.Success)
+		s.Contains(record.ErrorMessage, "风险控制暂停中")
+	})
+
+	s.Run("配置暂停时长时按时长暂停", func() {
+		s.autoTrader.SetEnforceDailyLossStop(true)
+		s.autoTrader.config.StopTradingTime = 2 * time.Hour
+		defer func() { s.autoTrader.config.StopTradingTime = 0 }()
+		s.autoTrader.resetDailyPnL()
+		s.autoTrader.checkDailyLossStop(10000.0, positions, now)
+
+		s.True(s.autoTrader.checkDailyLossStop(9000.0, positions, now))
+		s.Equal(now.Add(2*time.Hour), s.autoTrader.stopUntil)
+	})
+
+	s.Run("暂停时长不超过UTC次日且暂停结束不重置日盈亏", func() {
+		s.autoTrader.SetEnforceDailyLossStop(true)
+		s.autoTrader.config.StopTradingTime = 12 * time.Hour
+		defer func() { s.autoTrader.config.StopTradingTime = 0 }()
+		s.autoTrader.resetDailyPnL()
+		s.autoTrader.checkDailyLossStop(10000.0, positions, now)
+
+		s.True(s.autoTrader.checkDailyLossStop(9000.0, positions, now))
+		s.Equal(time.Date(2026, 1, 11, 0, 0, 0, 0, time.UTC), s.autoTrader.stopUntil)
+
+		// 暂停结束后仍沿用当日起始净值
+		later := now.Add(8 * time.Hour)
+		s.autoTrader.stopUntil = later
+		s.False(s.autoTrader.checkDailyLossStop(9800.0, positions, later))
+		s.Equal(10000.0, s.autoTrader.dayStartEquity)
+		s.Equal(-200.0, s.autoTrader.dailyPnL)
+	})
+}
+
+// TestAnalysisOnlyMode 测试仅分析模式的切换与建议操作格式化
+func (s *AutoTraderTestSuite) TestAnalysisOnlyMode() {
+	s.Run("运行时切换", func() {
+		s.False(s.autoTrader.IsAnalysisOnly())
+		s.autoTrader.SetAnalysisOnly(true)
+		s.True(s.autoTrader.IsAnalysisOnly())
+		s.Equal(true, s.autoTrader.GetStatus()["analysis_only"])
+		s.autoTrader.SetAnalysisOnly(false)
+		s.False(s.autoTrader.IsAnalysisOnly())
+	})
+
+	s.Run("开仓建议包含仓位和止损止盈", func() {
+		d := &decision.Decision{Symbol: "BTCUSDT", Action: "open_long", PositionSizeUSD: 100, Leverage: 5, StopLoss: 48000, TakeProfit: 55000, Reasoning: "突破"}
+		s.Equal("• BTCUSDT open_long 仓位100.00 USDT 5x 止损48000.0000 止盈55000.0000 - 突破", formatAnalysisOnlyAction(d))
+	})
+
+	s.Run("平仓建议", func() {
+		d := &decision.Decision{Symbol: "ETHUSDT", Action: "close_short"}
+		s.Equal("• ETHUSDT close_short", formatAnalysisOnlyAction(d))
+	})
+
+	s.Run("决策只记录为 not_executed，不调用任何下单方法", func() {
+		s.patches.ApplyPrivateMethod(reflect.TypeOf(s.autoTrader), "buildTradingContext",
+			func(_ *AutoTrader) (*decision.Context, error) {
+				return &decision.Context{Account: decision.AccountInfo{TotalEquity: 10000, AvailableBalance: 10000}}, nil
+			})
+		s.patches.ApplyFunc(decision.GetFullDecisionWithCustomPrompt,
+			func(_ *decision.Context, _ *mcp.Client, _ string, _ bool, _ string) (*decision.FullDecision, error) {
+				return &decision.FullDecision{
+					Decisions: []decision.Decision{
+						{Symbol: "BTCUSDT", Action: "open_long", PositionSizeUSD: 100, Leverage: 5},
+						{Symbol: "ETHUSDT", Action: "open_short", PositionSizeUSD: 100, Leverage: 5},
+						{Symbol: "SOLUSDT", Action: "close_long"},
+					},
+				}, nil
+			})
+		s.mockTrader = new(MockTrader)
+		s.autoTrader.trader = s.mockTrader
+		s.autoTrader.SetAnalysisOnly(true)
+		defer s.autoTrader.SetAnalysisOnly(false)
+
+		record, err := s.autoTrader.runCycleWithRecord()
+		s.NoError(err)
+
+		s.Equal(0.0, s.mockTrader.lastOpenLongQty, "不应调用 OpenLong")
+		s.Empty(s.mockTrader.orderCalls, "不应调用 OpenShort/CancelOrder")
+		s.Empty(s.mockTrader.closedPositions, "不应调用 CloseLong/CloseShort")
+		s.Require().Len(record.Decisions, 3)
+		for _, a := range record.Decisions {
+			s.Equal("not_executed", a.Status, "%s %s", a.Symbol, a.Action)
+			s.False(a.Success)
+		}
+	})
+}
+
 // ============================================================
 // 层次 9: 交易执行测试
 // ============================================================
@@ -510,32 +1107,57 @@ func (s *AutoTraderTestSuite) TestExecuteOpenPosition() {
 	}
 }
 
-// TestExecuteClosePosition 测试平仓操作（多空通用）
-func (s *AutoTraderTestSuite) TestExecuteClosePosition() {
+// TestRequireStopLoss 测试开启强制止损后的开仓校验
+func (s *AutoTraderTestSuite) TestRequireStopLoss() {
 	tests := []struct {
-		name          string
-		action        string
-		currentPrice  float64
-		expectedOrder int64
-		executeFn     func(*decision.Decision, *logger.DecisionAction) error
+		name        string
+		action      string
+		stopLoss    float64
+		takeProfit  float64
+		defaultPct  float64
+		expectedErr string
+		expectedSL  float64
 	}{
 		{
-			name:          "成功平多仓",
-			action:        "close_long",
-			currentPrice:  51000.0,
-			expectedOrder: 123458,
-			executeFn: func(d *decision.Decision, a *logger.DecisionAction) error {
-				return s.autoTrader.executeCloseLongWithRecord(d, a)
-			},
+			name:        "多仓_缺少止损_拒绝开仓",
+			action:      "open_long",
+			expectedErr: "缺少止损价",
 		},
 		{
-			name:          "成功平空仓",
-			action:        "close_short",
-			currentPrice:  49000.0,
-			expectedOrder: 123459,
-			executeFn: func(d *decision.Decision, a *logger.DecisionAction) error {
-				return s.autoTrader.executeCloseShortWithRecord(d, a)
-			},
+			name:        "空仓_缺少止损_拒绝开仓",
+			action:      "open_short",
+			expectedErr: "缺少止损价",
+		},
+		{
+			name:        "多仓_止损高于当前价_拒绝开仓",
+			action:      "open_long",
+			stopLoss:    51000.0,
+			expectedErr: "止损价",
+		},
+		{
+			name:        "空仓_止盈高于当前价_拒绝开仓",
+			action:      "open_short",
+			stopLoss:    51000.0,
+			takeProfit:  52000.0,
+			expectedErr: "止盈价",
+		},
+		{
+			name:       "多仓_有效止损_正常开仓",
+			action:     "open_long",
+			stopLoss:   49000.0,
+			expectedSL: 49000.0,
+		},
+		{
+			name:       "多仓_缺少止损_按默认百分比推导",
+			action:     "open_long",
+			defaultPct: 2.0,
+			expectedSL: 49000.0,
+		},
+		{
+			name:       "空仓_缺少止损_按默认百分比推导",
+			action:     "open_short",
+			defaultPct: 2.0,
+			expectedSL: 51000.0,
 		},
 	}
 
@@ -543,774 +1165,3565 @@ func (s *AutoTraderTestSuite) TestExecuteClosePosition() {
 		time.Sleep(time.Millisecond)
 		s.Run(tt.name, func() {
 			s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
-				return &market.Data{Symbol: symbol, CurrentPrice: tt.currentPrice}, nil
+				return &market.Data{Symbol: symbol, CurrentPrice: 50000.0}, nil
 			})
+			s.autoTrader.config.RequireStopLoss = true
+			s.autoTrader.config.DefaultStopLossPct = tt.defaultPct
+			s.mockTrader.positions = []map[string]interface{}{}
 
-			decision := &decision.Decision{Action: tt.action, Symbol: "BTCUSDT"}
+			d := &decision.Decision{
+				Action:          tt.action,
+				Symbol:          "BTCUSDT",
+				PositionSizeUSD: 1000.0,
+				Leverage:        10,
+				StopLoss:        tt.stopLoss,
+				TakeProfit:      tt.takeProfit,
+			}
 			actionRecord := &logger.DecisionAction{Action: tt.action, Symbol: "BTCUSDT"}
 
-			err := tt.executeFn(decision, actionRecord)
+			var err error
+			if tt.action == "open_long" {
+				err = s.autoTrader.executeOpenLongWithRecord(d, actionRecord)
+			} else {
+				err = s.autoTrader.executeOpenShortWithRecord(d, actionRecord)
+			}
 
-			s.NoError(err)
-			s.Equal(tt.expectedOrder, actionRecord.OrderID)
-			s.Equal(tt.currentPrice, actionRecord.Price)
+			if tt.expectedErr != "" {
+				s.Error(err)
+				s.Contains(err.Error(), tt.expectedErr)
+				s.Equal(int64(0), actionRecord.OrderID)
+			} else {
+				s.NoError(err)
+				s.True(math.Abs(tt.expectedSL-d.StopLoss) < 1e-6, "止损价应为 %.4f，实际 %.4f", tt.expectedSL, d.StopLoss)
+			}
+
+			// 恢复默认状态
+			s.autoTrader.config.RequireStopLoss = false
+			s.autoTrader.config.DefaultStopLossPct = 0
+			s.mockTrader.positions = []map[string]interface{}{}
 		})
 	}
 }
 
-// TestExecuteUpdateStopOrTakeProfit 测试更新止损/止盈（多空通用）
-func (s *AutoTraderTestSuite) TestExecuteUpdateStopOrTakeProfit() {
-	// 使用指针变量来控制 market.Get 的返回值
-	var testPrice *float64
+// TestPyramiding 测试加仓策略：默认拒绝同向叠加，开启后按次数和总仓位价值上限加仓
+func (s *AutoTraderTestSuite) TestPyramiding() {
 	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
-		price := 50000.0
-		if testPrice != nil {
-			price = *testPrice
+		return &market.Data{Symbol: symbol, CurrentPrice: 50000.0}, nil
+	})
+	defer s.autoTrader.SetPyramiding(false, 0)
+
+	openLong := func(stopLoss float64) error {
+		d := &decision.Decision{Action: "open_long", Symbol: "BTCUSDT", PositionSizeUSD: 1000.0, Leverage: 10, StopLoss: stopLoss}
+		return s.autoTrader.executeOpenLongWithRecord(d, &logger.DecisionAction{Action: "open_long", Symbol: "BTCUSDT"})
+	}
+
+	s.Run("未开启时拒绝同向开仓", func() {
+		s.mockTrader.positions = []map[string]interface{}{
+			{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.02, "entryPrice": 48000.0},
 		}
-		return &market.Data{Symbol: symbol, CurrentPrice: price}, nil
+		err := openLong(49000.0)
+		s.Error(err)
+		s.Contains(err.Error(), "已有多仓")
 	})
 
-	tests := []struct {
-		name         string
-		action       string
-		symbol       string
-		side         string
-		currentPrice float64
-		newPrice     float64
-		hasPosition  bool
-		expectedErr  string
-		executeFn    func(*decision.Decision, *logger.DecisionAction) error
-	}{
+	s.Run("开启后加仓至次数上限", func() {
+		s.autoTrader.SetPyramiding(true, 2)
+		s.autoTrader.resetPyramidState("BTCUSDT_long", 47000.0)
+		s.mockTrader.positions = []map[string]interface{}{
+			{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.02, "entryPrice": 48000.0},
+		}
+		s.mockTrader.stopLossQuantity = nil
+
+		s.NoError(openLong(49000.0))
+		state := s.autoTrader.positionPyramid["BTCUSDT_long"]
+		s.Equal(1, state.adds)
+		s.True(math.Abs(state.stopLoss-48000.0) < 1e-6, "混合止损应为 48000，实际 %.4f", state.stopLoss)
+		// 止损按合并后的总仓位设置
+		s.True(math.Abs(s.mockTrader.stopLossQuantity[0]-0.04) < 1e-9)
+
+		s.NoError(openLong(49000.0))
+		s.Equal(2, s.autoTrader.positionPyramid["BTCUSDT_long"].adds)
+
+		err := openLong(49000.0)
+		s.Error(err)
+		s.Contains(err.Error(), "达到上限")
+	})
+
+	s.Run("重启后恢复加仓次数", func() {
+		before := s.autoTrader.positionPyramid["BTCUSDT_long"]
+		s.autoTrader.positionPyramid = make(map[string]pyramidState)
+		s.autoTrader.loadPyramidState()
+		s.Equal(before, s.autoTrader.positionPyramid["BTCUSDT_long"])
+		s.Equal(2, before.adds)
+
+		err := openLong(49000.0)
+		s.Error(err)
+		s.Contains(err.Error(), "达到上限")
+	})
+
+	s.Run("超过总仓位价值上限拒绝加仓", func() {
+		s.autoTrader.SetPyramiding(true, 5)
+		s.autoTrader.resetPyramidState("BTCUSDT_long", 47000.0)
+		// 账户净值 10100，BTC 上限 101000；已有 2.1 BTC × 50000 = 105000
+		s.mockTrader.positions = []map[string]interface{}{
+			{"symbol": "BTCUSDT", "side": "long", "positionAmt": 2.1, "entryPrice": 48000.0},
+		}
+		err := openLong(49000.0)
+		s.Error(err)
+		s.Contains(err.Error(), "超过上限")
+	})
+
+	s.Run("混合止损计算", func() {
+		s.Equal(49000.0, blendedStopLoss(1, 0, 1, 49000))     // 原止损未知
+		s.Equal(47000.0, blendedStopLoss(1, 47000, 1, 0))     // 新止损缺失
+		s.Equal(47500.0, blendedStopLoss(3, 47000, 1, 49000)) // 按数量加权
+	})
+
+	s.mockTrader.positions = []map[string]interface{}{}
+}
+
+// TestLeverageBrackets 测试按交易所杠杆分层下调超限杠杆
+func (s *AutoTraderTestSuite) TestLeverageBrackets() {
+	brackets := []LeverageBracket{
+		{Bracket: 1, MaxLeverage: 50, NotionalFloor: 0, NotionalCap: 50000},
+		{Bracket: 2, MaxLeverage: 20, NotionalFloor: 50000, NotionalCap: 250000},
+		{Bracket: 3, MaxLeverage: 10, NotionalFloor: 250000, NotionalCap: 0},
+	}
+
+	s.Run("按名义价值查找最大杠杆", func() {
+		s.Equal(0, MaxLeverageForNotional(nil, 1000))
+		s.Equal(50, MaxLeverageForNotional(brackets, 1000))
+		s.Equal(20, MaxLeverageForNotional(brackets, 50000))
+		s.Equal(10, MaxLeverageForNotional(brackets, 1e9))
+	})
+
+	s.Run("开仓杠杆超限时下调并记录", func() {
+		s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+			return &market.Data{Symbol: symbol, CurrentPrice: 50000.0}, nil
+		})
+		s.mockTrader.leverageBrackets = []LeverageBracket{{Bracket: 1, MaxLeverage: 5, NotionalCap: 0}}
+		s.autoTrader.leverageBrackets.Delete("BTCUSDT")
+		defer func() {
+			s.mockTrader.leverageBrackets = nil
+			s.autoTrader.leverageBrackets.Delete("BTCUSDT")
+		}()
+
+		d := &decision.Decision{Action: "open_long", Symbol: "BTCUSDT", PositionSizeUSD: 1000.0, Leverage: 10}
+		actionRecord := &logger.DecisionAction{Action: "open_long", Symbol: "BTCUSDT"}
+		s.NoError(s.autoTrader.executeOpenLongWithRecord(d, actionRecord))
+		s.Equal(5, d.Leverage)
+		s.Equal(5, actionRecord.Leverage)
+		s.Contains(actionRecord.Note, "已下调为 5x")
+	})
+
+	s.Run("未超限或分层未知时不调整", func() {
+		s.autoTrader.leverageBrackets.Delete("ETHUSDT")
+		d := &decision.Decision{Symbol: "ETHUSDT", Leverage: 10}
+		s.Equal("", s.autoTrader.clampLeverageToBrackets(d, 1000))
+		s.Equal(10, d.Leverage)
+	})
+}
+
+// TestLeverageCeiling 测试系统级杠杆上限：执行时超限杠杆被下调
+func (s *AutoTraderTestSuite) TestLeverageCeiling() {
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 50000.0}, nil
+	})
+	SetLeverageCeilings(10, 3)
+	defer SetLeverageCeilings(config.DefaultMaxBTCETHLeverage, config.DefaultMaxAltcoinLeverage)
+
+	s.Run("按币种区分上限", func() {
+		s.Equal(10, LeverageCeiling("BTCUSDT"))
+		s.Equal(3, LeverageCeiling("SOLUSDT"))
+	})
+
+	s.Run("开仓杠杆超过系统上限时下调并记录", func() {
+		d := &decision.Decision{Action: "open_long", Symbol: "BTCUSDT", PositionSizeUSD: 1000.0, Leverage: 50}
+		actionRecord := &logger.DecisionAction{Action: "open_long", Symbol: "BTCUSDT"}
+		s.NoError(s.autoTrader.executeOpenLongWithRecord(d, actionRecord))
+		s.Equal(10, d.Leverage)
+		s.Equal(10, actionRecord.Leverage)
+		s.Contains(actionRecord.Note, "超过系统上限 10x")
+	})
+
+	s.Run("未超限时不调整", func() {
+		leverage, note := ClampLeverageToCeiling("SOLUSDT", 3)
+		s.Equal(3, leverage)
+		s.Empty(note)
+	})
+}
+
+// TestSignalBasePositionSizing 测试信号模式首次入场使用交易员配置的底仓比例
+func (s *AutoTraderTestSuite) TestSignalBasePositionSizing() {
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 50000.0}, nil
+	})
+	s.mockTrader.positions = []map[string]interface{}{}
+	s.autoTrader.initialBalance = 10000
+	strat := &signal.SignalDecision{Symbol: "BTCUSDT", Direction: "LONG"}
+
+	s.Run("按配置底仓比例入场", func() {
+		s.autoTrader.SetSignalSizing(10, 5)
+		s.autoTrader.CheckAndExecuteStrategy(strat)
+		s.InDelta(10000*0.10/50000.0, s.mockTrader.lastOpenLongQty, 1e-9)
+	})
+
+	s.Run("未配置时使用默认20%", func() {
+		s.autoTrader.SetSignalSizing(0, 0)
+		s.autoTrader.CheckAndExecuteStrategy(strat)
+		s.InDelta(10000*0.20/50000.0, s.mockTrader.lastOpenLongQty, 1e-9)
+	})
+
+	s.Run("校验底仓与补仓合计不超过100%", func() {
+		s.True(ValidSignalSizing(20, 10))
+		s.False(ValidSignalSizing(90, 20))
+		s.False(ValidSignalSizing(0, 10))
+		s.True(ValidSignalSizing(40, 20, 20, 20), "底仓加全部补仓恰好100%")
+		s.False(ValidSignalSizing(40, 30, 30, 10), "底仓加全部补仓超过100%")
+		s.False(ValidSignalSizing(20, 10, 0))
+		base, add := EffectiveSignalSizing(0, 0)
+		s.Equal(DefaultSignalBasePositionPct, base)
+		s.Equal(DefaultSignalDefaultAddPct, add)
+	})
+
+	s.Run("底仓加全部补仓超过100%的计划在接收时拒绝", func() {
+		s.autoTrader.SetSignalSizing(50, 30)
+		defer s.autoTrader.SetSignalSizing(0, 0)
+		over := &signal.SignalDecision{Symbol: "BTCUSDT", Direction: "LONG", Adds: []signal.AddStrategy{{Price: 40000}, {Price: 30000}}}
+		s.False(s.autoTrader.validSignalPlan(over))
+		s.True(s.autoTrader.validSignalPlan(&signal.SignalDecision{Symbol: "BTCUSDT", Direction: "LONG", Adds: []signal.AddStrategy{{Price: 40000}}}))
+	})
+
+	s.Run("运行时期望仓位按100%封顶", func() {
+		s.autoTrader.SetSignalSizing(50, 30)
+		defer s.autoTrader.SetSignalSizing(0, 0)
+		s.mockTrader.lastOpenLongQty = 0
+		// 两个补仓点均已触发：50% + 30% + 30% = 110%，按 100% 开仓
+		s.autoTrader.CheckAndExecuteStrategy(&signal.SignalDecision{
+			Symbol:    "BTCUSDT",
+			Direction: "LONG",
+			Adds:      []signal.AddStrategy{{Price: 60000}, {Price: 55000}},
+		})
+		s.InDelta(10000*1.0/50000.0, s.mockTrader.lastOpenLongQty, 1e-9)
+	})
+
+	s.Run("对账比例仅在显式配置时使用配置值", func() {
+		s.autoTrader.SetSignalSizing(0, 0)
+		base, add := s.autoTrader.signalDiffSizing(3)
+		s.InDelta(0.40, base, 1e-9)
+		s.InDelta(0.20, add, 1e-9)
+
+		s.autoTrader.SetSignalSizing(10, 5)
+		defer s.autoTrader.SetSignalSizing(0, 0)
+		base, add = s.autoTrader.signalDiffSizing(3)
+		s.InDelta(0.10, base, 1e-9)
+		s.InDelta(0.05, add, 1e-9)
+	})
+}
+
+// TestSizingBase 测试仓位计算基数：相同比例下 fixed 按初始余额、equity 按账户实时净值计算仓位
+func (s *AutoTraderTestSuite) TestSizingBase() {
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 50000.0}, nil
+	})
+	s.mockTrader.positions = []map[string]interface{}{}
+	s.autoTrader.initialBalance = 5000
+	s.autoTrader.SetSignalSizing(10, 5)
+	strat := &signal.SignalDecision{Symbol: "BTCUSDT", Direction: "LONG"}
+
+	s.Run("fixed 按初始余额计算", func() {
+		s.autoTrader.SetSizingBase(SizingBaseFixed)
+		s.Equal(5000.0, s.autoTrader.sizingBase())
+		s.autoTrader.CheckAndExecuteStrategy(strat)
+		s.InDelta(5000*0.10/50000.0, s.mockTrader.lastOpenLongQty, 1e-9)
+	})
+
+	s.Run("equity 按账户净值（钱包余额+未实现盈亏）计算", func() {
+		s.autoTrader.SetSizingBase(SizingBaseEquity)
+		s.Equal(10100.0, s.autoTrader.sizingBase())
+		s.autoTrader.CheckAndExecuteStrategy(strat)
+		s.InDelta(10100*0.10/50000.0, s.mockTrader.lastOpenLongQty, 1e-9)
+	})
+
+	s.Run("净值获取失败时回退到初始余额", func() {
+		s.autoTrader.SetSizingBase(SizingBaseEquity)
+		s.mockTrader.shouldFailBalance = true
+		defer func() { s.mockTrader.shouldFailBalance = false }()
+		s.Equal(5000.0, s.autoTrader.sizingBase())
+	})
+
+	s.Run("校验仓位计算基数", func() {
+		s.True(ValidSizingBase(""))
+		s.True(ValidSizingBase(SizingBaseEquity))
+		s.False(ValidSizingBase("compound"))
+	})
+}
+
+// TestPreviewSignalStrategy 测试信号执行计划预览：返回与策略入场/补仓一致的计划挂单，且不触碰交易所
+func (s *AutoTraderTestSuite) TestPreviewSignalStrategy() {
+	klines := make([]market.Kline, 50)
+	for i := range klines {
+		klines[i] = market.Kline{Close: 50500}
+	}
+	s.patches.ApplyMethod(reflect.TypeOf(&market.APIClient{}), "GetKlines",
+		func(_ *market.APIClient, symbol, interval string, limit int) ([]market.Kline, error) {
+			return klines, nil
+		})
+	s.patches.ApplyFunc(ioutil.ReadFile, func(name string) ([]byte, error) {
+		return []byte("{{SYMBOL}} {{ENTRY_PRICE}} {{ADDS_JSON}}\n{{CUSTOM_PROMPT}}"), nil
+	})
+	aiResponse := ""
+	aiCalls := 0
+	s.patches.ApplyMethod(reflect.TypeOf(&mcp.Client{}), "CallWithMessagesServed",
+		func(_ *mcp.Client, systemPrompt, userPrompt string) (string, mcp.ServedBy, error) {
+			aiCalls++
+			return aiResponse, mcp.ServedBy{}, nil
+		})
+
+	s.mockTrader.positions = []map[string]interface{}{}
+	s.autoTrader.initialBalance = 10000
+	s.autoTrader.SetSignalSizing(10, 5)
+	newStrategy := func() *signal.SignalDecision {
+		return &signal.SignalDecision{
+			SignalID:  "preview-1",
+			Symbol:    "BTCUSDT",
+			Direction: "LONG",
+			Entry:     signal.EntryStrategy{PriceTarget: 50000},
+			Adds:      []signal.AddStrategy{{Price: 49000, Percent: 0.1}, {Price: 48000}},
+			StopLoss:  signal.StopLossStrategy{Price: 47000},
+		}
+	}
+	assertExchangeUntouched := func() {
+		s.Zero(s.mockTrader.lastLimitPrice, "预览不应下限价单")
+		s.Zero(s.mockTrader.lastOpenLongQty, "预览不应市价开仓")
+		s.False(s.mockTrader.SetStopLossCalled, "预览不应设置止损")
+		s.Empty(s.mockTrader.closedPositions, "预览不应平仓")
+	}
+
+	s.Run("AI只返回wait时计划兜底挂单与入场/补仓点位一致", func() {
+		aiResponse = `[{"symbol":"BTCUSDT","action":"wait","reasoning":"price far away"}]`
+		preview, err := s.autoTrader.PreviewSignalStrategy(newStrategy())
+		s.Require().NoError(err)
+
+		s.True(preview.DiffDetected)
+		s.Len(preview.MissingPoints, 3)
+		s.Require().Len(preview.Actions, 3)
+		want := []struct {
+			kind    string
+			price   float64
+			percent float64
+		}{{"entry", 50000, 0.10}, {"add_1", 49000, 0.10}, {"add_2", 48000, 0.05}}
+		for i, w := range want {
+			a := preview.Actions[i]
+			s.Equal("fallback", a.Source)
+			s.Equal(w.kind, a.Kind)
+			s.Equal("place_long_order", a.Action)
+			s.Equal(w.price, a.Price)
+			s.Equal(10, a.Leverage, "使用交易员配置的 BTC/ETH 杠杆")
+			s.InDelta(10000*w.percent*10, a.PositionSizeUSD, 1e-6)
+		}
+		assertExchangeUntouched()
+	})
+
+	s.Run("AI给出的挂单动作只进入计划不执行", func() {
+		aiResponse = `[{"action":"place_limit_order","price":50000,"leverage":20},{"action":"place_long_order","price":50000},{"action":"place_long_order","price":49000}]`
+		preview, err := s.autoTrader.PreviewSignalStrategy(newStrategy())
+		s.Require().NoError(err)
+
+		s.Require().Len(preview.Actions, 2, "同一价位的重复挂单只保留一个")
+		for i, price := range []float64{50000, 49000} {
+			s.Equal("ai", preview.Actions[i].Source)
+			s.Equal("BTCUSDT", preview.Actions[i].Symbol)
+			s.Equal("place_long_order", preview.Actions[i].Action)
+			s.Equal(price, preview.Actions[i].Price)
+			s.Equal(10, preview.Actions[i].Leverage)
+		}
+		assertExchangeUntouched()
+	})
+
+	s.Run("预览不占用AI调用间隔", func() {
+		s.autoTrader.SetMinSecondsBetweenAICalls(3600)
+		defer s.autoTrader.SetMinSecondsBetweenAICalls(0)
+		aiResponse = `[{"symbol":"BTCUSDT","action":"wait","reasoning":"wait"}]`
+		before := aiCalls
+		for i := 0; i < 2; i++ {
+			_, err := s.autoTrader.PreviewSignalStrategy(newStrategy())
+			s.Require().NoError(err)
+		}
+		s.Equal(before+4, aiCalls, "每次预览都调用AI（wait-only 时再强提示重试一次）")
+	})
+
+	s.Run("缺少交易对或点位时返回错误", func() {
+		_, err := s.autoTrader.PreviewSignalStrategy(&signal.SignalDecision{Direction: "LONG"})
+		s.Error(err)
+		_, err = s.autoTrader.PreviewSignalStrategy(&signal.SignalDecision{Symbol: "BTCUSDT", Direction: "LONG"})
+		s.Error(err)
+	})
+}
+
+// TestEquityBracket 测试账户净值止盈：净值越过阈值时平掉所有持仓并暂停交易
+func (s *AutoTraderTestSuite) TestEquityBracket() {
+	s.autoTrader.initialBalance = 10000
+	s.mockTrader.positions = []map[string]interface{}{
+		{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.1, "entryPrice": 50000.0, "markPrice": 55000.0},
+		{"symbol": "ETHUSDT", "side": "short", "positionAmt": -2.0, "entryPrice": 3000.0, "markPrice": 2800.0},
+	}
+	defer func() {
+		s.mockTrader.positions = []map[string]interface{}{}
+		s.mockTrader.balance = nil
+		s.autoTrader.SetEquityBracket(0, 0, 0, 0)
+	}()
+
+	s.Run("未配置时不检查", func() {
+		s.False(s.autoTrader.checkEquityBracket())
+		s.Empty(s.mockTrader.closedPositions)
+	})
+
+	s.Run("净值未达到止盈不触发", func() {
+		s.autoTrader.SetEquityBracket(0, 0, 10, 0)
+		s.mockTrader.balance = map[string]interface{}{"totalWalletBalance": 10500.0, "totalUnrealizedProfit": 400.0, "availableBalance": 9000.0}
+		s.False(s.autoTrader.checkEquityBracket())
+		s.Empty(s.autoTrader.equityBracketPaused())
+	})
+
+	s.Run("净值越过止盈时全部平仓并暂停", func() {
+		// 净值 = 钱包余额 + 未实现盈亏 = 10500 + 900 = 11400 >= 11000
+		s.mockTrader.balance = map[string]interface{}{"totalWalletBalance": 10500.0, "totalUnrealizedProfit": 900.0, "availableBalance": 9000.0}
+		s.True(s.autoTrader.checkEquityBracket())
+		s.Equal([]string{"BTCUSDT_long", "ETHUSDT_short"}, s.mockTrader.closedPositions)
+		s.Equal(equityBracketTakeProfit, s.autoTrader.equityBracketPaused())
+		s.Equal(equityBracketTakeProfit, s.autoTrader.GetStatus()["equity_bracket"].(map[string]interface{})["triggered"])
+
+		// 暂停期间不重复触发
+		s.False(s.autoTrader.checkEquityBracket())
+		s.Len(s.mockTrader.closedPositions, 2)
+	})
+
+	s.Run("重启后恢复暂停状态", func() {
+		s.Equal(equityBracketTakeProfit, s.mockDB.equityBracketHit)
+		s.autoTrader.equityBracketHit = ""
+		s.autoTrader.loadEquityBracketPause()
+		s.Equal(equityBracketTakeProfit, s.autoTrader.equityBracketPaused())
+	})
+
+	s.Run("修改阈值后解除暂停", func() {
+		s.autoTrader.SetEquityBracket(0, 9000, 0, 0)
+		s.Empty(s.autoTrader.equityBracketPaused())
+		s.Empty(s.mockDB.equityBracketHit)
+	})
+
+	s.Run("手动启动时解除暂停", func() {
+		s.autoTrader.equityBracketHit = equityBracketStopLoss
+		s.mockDB.equityBracketHit = equityBracketStopLoss
+		s.autoTrader.ResumeEquityBracket()
+		s.Empty(s.autoTrader.equityBracketPaused())
+		s.Empty(s.mockDB.equityBracketHit)
+	})
+
+	s.Run("阈值校验", func() {
+		s.True(ValidEquityBracket(12000, 9000, 20, 10))
+		s.False(ValidEquityBracket(9000, 12000, 0, 0))
+		s.False(ValidEquityBracket(0, 0, 0, 100))
+		s.False(ValidEquityBracket(-1, 0, 0, 0))
+	})
+}
+
+// TestReconcileStrategyStatusesOnStartup 测试信号模式重启时按实际持仓校正策略执行状态
+func (s *AutoTraderTestSuite) TestReconcileStrategyStatusesOnStartup() {
+	s.mockDB.strategyStatuses = map[string]*config.TraderStrategyStatus{
+		"s_live":    {TraderID: "test_trader", StrategyID: "s_live", Symbol: "BTCUSDT", Status: "WAITING"},
+		"s_entry":   {TraderID: "test_trader", StrategyID: "s_entry", Symbol: "XRPUSDT", Status: "ENTRY"},
+		"s_gone":    {TraderID: "test_trader", StrategyID: "s_gone", Symbol: "ETHUSDT", Status: "ADD_1"},
+		"s_pending": {TraderID: "test_trader", StrategyID: "s_pending", Symbol: "SOLUSDT", Status: "WAITING"},
+	}
+	s.mockTrader.positions = []map[string]interface{}{
+		{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.2, "entryPrice": 50000.0, "markPrice": 51000.0},
+		{"symbol": "XRPUSDT", "side": "long", "positionAmt": 100.0, "entryPrice": 0.5, "markPrice": 0.5},
+	}
+	defer func() {
+		s.mockDB.strategyStatuses = nil
+		s.mockTrader.positions = []map[string]interface{}{}
+	}()
+
+	s.autoTrader.reconcileStrategyStatusesOnStartup()
+
+	live := s.mockDB.strategyStatuses["s_live"]
+	s.Equal("ENTRY", live.Status)
+	s.Require().NotNil(live.HadPosition)
+	s.True(*live.HadPosition)
+	s.Equal(0.2, live.Quantity)
+	s.Equal(50000.0, live.EntryPrice)
+
+	entry := s.mockDB.strategyStatuses["s_entry"]
+	s.Equal("ENTRY", entry.Status)
+	s.Require().NotNil(entry.HadPosition)
+	s.True(*entry.HadPosition)
+	s.Equal(100.0, entry.Quantity)
+	s.False(s.autoTrader.isStrategyClosed("s_entry"))
+
+	s.Equal("CLOSED", s.mockDB.strategyStatuses["s_gone"].Status)
+	s.True(s.autoTrader.isStrategyClosed("s_gone"))
+
+	s.Equal("WAITING", s.mockDB.strategyStatuses["s_pending"].Status)
+	s.False(s.autoTrader.isStrategyClosed("s_pending"))
+}
+
+// TestTP1ScaleOut 测试信号模式到达第一止盈时分批止盈并将止损移到开仓价，同一策略只执行一次
+func (s *AutoTraderTestSuite) TestTP1ScaleOut() {
+	s.mockDB.strategyStatuses = map[string]*config.TraderStrategyStatus{}
+	s.mockDB.decisions = nil
+	defer func() {
+		s.mockDB.strategyStatuses = nil
+		s.mockDB.decisions = nil
+		s.autoTrader.SetTP1ScaleOut(0, false)
+	}()
+	strat := &signal.SignalDecision{
+		SignalID:    "s_tp1",
+		Symbol:      "BTCUSDT",
+		Direction:   "LONG",
+		StopLoss:    signal.StopLossStrategy{Price: 48000},
+		TakeProfits: []signal.TPStrategy{{Price: 52000}, {Price: 55000}},
+	}
+	setup := func(markPrice float64) {
+		s.mockTrader = new(MockTrader)
+		s.autoTrader.trader = s.mockTrader
+		s.mockTrader.positions = []map[string]interface{}{
+			{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.2, "entryPrice": 50000.0, "markPrice": markPrice, "leverage": 10.0},
+		}
+	}
+
+	s.Run("未配置时不执行", func() {
+		setup(52500)
+		s.Equal(0, s.autoTrader.checkTP1ScaleOut([]*signal.SignalDecision{strat}))
+		s.Empty(s.mockTrader.closedPositions)
+	})
+
+	s.autoTrader.SetTP1ScaleOut(50, true)
+	s.Run("未到达第一止盈不执行", func() {
+		setup(51900)
+		s.Equal(0, s.autoTrader.checkTP1ScaleOut([]*signal.SignalDecision{strat}))
+		s.Empty(s.mockTrader.closedPositions)
+		s.False(s.mockTrader.SetStopLossCalled)
+	})
+
+	s.Run("到达第一止盈时部分平仓并保本", func() {
+		setup(52100)
+		s.Equal(1, s.autoTrader.checkTP1ScaleOut([]*signal.SignalDecision{strat}))
+		s.Equal([]string{"BTCUSDT_long"}, s.mockTrader.closedPositions)
+		s.Require().Len(s.mockTrader.closedQuantities, 1)
+		s.InDelta(0.1, s.mockTrader.closedQuantities[0], 1e-9)
+		s.True(s.mockTrader.SetStopLossCalled)
+		s.Equal(50000.0, s.mockTrader.LastSLPrice, "止损移到开仓价")
+		s.Require().Len(s.mockTrader.stopLossQuantity, 1)
+		s.InDelta(0.1, s.mockTrader.stopLossQuantity[0], 1e-9, "保本止损覆盖剩余仓位")
+
+		s.Require().Len(s.mockDB.decisions, 2, "两个动作都写入决策历史")
+		s.Equal("partial_close", s.mockDB.decisions[0].Action)
+		s.Equal("update_stop_loss", s.mockDB.decisions[1].Action)
+		s.True(s.mockDB.decisions[0].ExecutionSuccess)
+		s.Equal(strategyStatusTP1Hit, s.mockDB.strategyStatuses["s_tp1"].Status)
+	})
+
+	s.Run("同一策略只执行一次", func() {
+		setup(53000)
+		s.mockTrader.positions[0]["positionAmt"] = 0.1
+		s.Equal(0, s.autoTrader.checkTP1ScaleOut([]*signal.SignalDecision{strat}))
+		s.Empty(s.mockTrader.closedPositions)
+		s.False(s.mockTrader.SetStopLossCalled)
+		s.Len(s.mockDB.decisions, 2)
+	})
+}
+
+// TestRebalancePortfolio 测试目标权重再平衡：超配减仓、低配加仓，容忍带内不调整
+func (s *AutoTraderTestSuite) TestRebalancePortfolio() {
+	defer s.autoTrader.SetRebalanceConfig(nil, 0, 0)
+	s.mockTrader.positions = []map[string]interface{}{
+		{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.1, "entryPrice": 48000.0, "markPrice": 50000.0, "leverage": 5.0},
+		{"symbol": "ETHUSDT", "side": "long", "positionAmt": 1.0, "entryPrice": 1100.0, "markPrice": 1000.0, "leverage": 5.0},
+		{"symbol": "SOLUSDT", "side": "long", "positionAmt": 10.0, "entryPrice": 100.0, "markPrice": 105.0, "leverage": 5.0},
+	}
+
+	s.Run("未配置目标权重时不调整", func() {
+		actions, err := s.autoTrader.rebalancePortfolio()
+		s.NoError(err)
+		s.Empty(actions)
+		s.False(s.autoTrader.isRebalanceMode())
+	})
+
+	// 仓位计算基数 10000：BTC 50%（目标40）、ETH 10%（目标20）、SOL 10.5%（目标10，容忍带内）
+	s.autoTrader.SetRebalanceConfig(map[string]float64{"BTCUSDT": 40, "ETHUSDT": 20, "SOLUSDT": 10}, 2, 0)
+	s.Run("偏离超过容忍带时调整回目标权重", func() {
+		actions, err := s.autoTrader.rebalancePortfolio()
+		s.NoError(err)
+		s.Require().Len(actions, 2, "SOL 在容忍带内不调整")
+
+		s.Equal("BTCUSDT", actions[0].Symbol)
+		s.Equal("partial_close", actions[0].Action)
+		s.True(actions[0].Success)
+		s.Equal([]string{"BTCUSDT_long"}, s.mockTrader.closedPositions)
+		s.InDelta(0.02, s.mockTrader.closedQuantities[0], 1e-9, "减仓 10% × 10000 / 50000")
+
+		s.Equal("ETHUSDT", actions[1].Symbol)
+		s.Equal("open_long", actions[1].Action)
+		s.True(actions[1].Success)
+		s.InDelta(1.0, s.mockTrader.lastOpenLongQty, 1e-9, "加仓 10% × 10000 / 1000")
+	})
+}
+
+// TestSymbolUnavailable 测试持仓币种下架：行情获取失败时标记 symbol_unavailable 并通知，其余币种照常决策执行
+func (s *AutoTraderTestSuite) TestSymbolUnavailable() {
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		if symbol == "LUNAUSDT" {
+			return nil, errors.New("获取5分钟K线失败: <APIError> code=-1121, msg=Invalid symbol.")
+		}
+		return &market.Data{Symbol: symbol, CurrentPrice: 50000.0}, nil
+	})
+	s.patches.ApplyFunc(pool.GetOITopPositions, func() ([]pool.OIPosition, error) {
+		return nil, errors.New("offline")
+	})
+	var userPrompt string
+	s.patches.ApplyMethod(reflect.TypeOf(&mcp.Client{}), "CallWithMessagesServed",
+		func(_ *mcp.Client, _, prompt string) (string, mcp.ServedBy, error) {
+			userPrompt = prompt
+			return `[{"symbol":"BTCUSDT","action":"close_long","reasoning":"止盈"},{"symbol":"LUNAUSDT","action":"close_long","reasoning":"平仓"}]`, mcp.ServedBy{}, nil
+		})
+
+	s.autoTrader.customPrompt, s.autoTrader.overrideBasePrompt = "测试策略", true
+	s.autoTrader.defaultCoins = []string{"BTC", "LUNA"}
+	s.mockTrader.positions = []map[string]interface{}{
+		{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.1, "entryPrice": 48000.0, "markPrice": 50000.0, "leverage": 5.0},
+		{"symbol": "LUNAUSDT", "side": "long", "positionAmt": 1000.0, "entryPrice": 1.0, "markPrice": 0.5, "leverage": 5.0},
+	}
+
+	record, err := s.autoTrader.runCycleWithRecord()
+	s.Require().NoError(err, "单个币种下架不应导致整个周期失败")
+	s.True(record.Success)
+	s.Contains(userPrompt, "symbol_unavailable", "AI应被告知该持仓需手动处理")
+
+	s.True(s.autoTrader.symbolUnavailable("LUNAUSDT"))
+	s.False(s.autoTrader.symbolUnavailable("BTCUSDT"))
+	for _, pos := range record.Positions {
+		s.Equal(pos.Symbol == "LUNAUSDT", pos.SymbolUnavailable, pos.Symbol)
+	}
+
+	s.Require().Len(record.Decisions, 2)
+	for _, action := range record.Decisions {
+		if action.Symbol == "LUNAUSDT" {
+			s.False(action.Success)
+			s.Equal("symbol_unavailable", action.Status)
+		} else {
+			s.True(action.Success, "其他币种照常执行")
+		}
+	}
+	s.Equal([]string{"BTCUSDT_long"}, s.mockTrader.closedPositions)
+
+	s.Run("下架币种不再作为候选币种", func() {
+		coins := s.autoTrader.excludeUnavailableCandidates([]decision.CandidateCoin{{Symbol: "BTCUSDT"}, {Symbol: "LUNAUSDT"}}, nil)
+		s.Require().Len(coins, 1)
+		s.Equal("BTCUSDT", coins[0].Symbol)
+		s.Len(s.autoTrader.GetStatus()["unavailable_symbols"], 1)
+	})
+
+	s.Run("行情恢复后解除标记", func() {
+		s.False(s.autoTrader.checkMarketData("LUNAUSDT", true, nil))
+		s.False(s.autoTrader.symbolUnavailable("LUNAUSDT"))
+		s.False(s.autoTrader.checkMarketData("ETHUSDT", false, errors.New("i/o timeout")), "临时错误不标记")
+	})
+}
+
+// TestDecisionCycleTracing 测试决策周期链路追踪：根 span 下包含构建上下文、AI调用和逐个动作执行的子 span
+func (s *AutoTraderTestSuite) TestDecisionCycleTracing() {
+	exporter := tracing.NewInMemoryExporter()
+	tracing.SetExporter(exporter)
+	defer tracing.SetExporter(nil)
+
+	s.patches.ApplyPrivateMethod(reflect.TypeOf(s.autoTrader), "buildTradingContext",
+		func(_ *AutoTrader) (*decision.Context, error) {
+			return &decision.Context{Account: decision.AccountInfo{TotalEquity: 10000, AvailableBalance: 10000}}, nil
+		})
+	s.patches.ApplyFunc(decision.GetFullDecisionWithCustomPrompt,
+		func(_ *decision.Context, _ *mcp.Client, _ string, _ bool, _ string) (*decision.FullDecision, error) {
+			return &decision.FullDecision{
+				Decisions: []decision.Decision{
+					{Symbol: "ETHUSDT", Action: "close_long"},
+					{Symbol: "BTCUSDT", Action: "hold"},
+				},
+				Served: mcp.ServedBy{Provider: mcp.ProviderDeepSeek, Model: "deepseek-chat"},
+			}, nil
+		})
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 3000.0}, nil
+	})
+	s.mockTrader.shouldFailCloseLong = true
+	aiCalls := s.autoTrader.GetAICallsToday()
+
+	_, err := s.autoTrader.runCycleWithRecord()
+	s.NoError(err)
+	s.Equal(aiCalls+1, s.autoTrader.GetAICallsToday(), "实际发出AI请求后计入当日调用次数")
+
+	spans := exporter.Spans()
+	s.Require().Len(spans, 5, "build_context + ai_call + 2 × execute_action + decision_cycle")
+	byName := map[string][]tracing.SpanData{}
+	for _, span := range spans {
+		byName[span.Name] = append(byName[span.Name], span)
+	}
+	s.Require().Len(byName["decision_cycle"], 1)
+	root := byName["decision_cycle"][0]
+	s.Empty(root.ParentSpanID)
+	s.Equal(s.autoTrader.id, root.Attributes["trader_id"])
+	s.Equal(2, root.Attributes["action_count"])
+	s.Equal("ok", root.Attributes["result"])
+
+	for _, span := range spans[:4] {
+		s.Equal(root.TraceID, span.TraceID)
+		s.Equal(root.SpanID, span.ParentSpanID, "%s 应为周期的子 span", span.Name)
+		s.Equal(s.autoTrader.id, span.Attributes["trader_id"])
+		s.Contains(span.Attributes, "latency_ms")
+	}
+	s.Require().Len(byName["ai_call"], 1)
+	s.Equal("deepseek-chat", byName["ai_call"][0].Attributes["ai_model"])
+
+	actions := byName["execute_action"]
+	s.Require().Len(actions, 2)
+	s.Equal("ETHUSDT", actions[0].Attributes["symbol"])
+	s.Equal("close_long", actions[0].Attributes["action"])
+	s.Equal("error", actions[0].Attributes["result"])
+	s.Contains(actions[0].Err, "failed to close long")
+	s.Equal("BTCUSDT", actions[1].Attributes["symbol"])
+	s.Equal("hold", actions[1].Attributes["action"])
+	s.Equal("ok", actions[1].Attributes["result"])
+}
+
+// TestFlipPosition 测试反手：平空后开多，开仓失败时明确记录为未完成
+func (s *AutoTraderTestSuite) TestFlipPosition() {
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 50000.0}, nil
+	})
+	s.mockTrader.positions = []map[string]interface{}{
+		{"symbol": "BTCUSDT", "side": "short", "positionAmt": -0.02, "entryPrice": 51000.0},
+	}
+	defer func() {
+		s.autoTrader.SetAllowFlip(false)
+		s.mockTrader.shouldFailOpenLong = false
+	}()
+
+	newFlip := func() (*decision.Decision, *logger.DecisionAction) {
+		d := &decision.Decision{Action: "flip_long", Symbol: "BTCUSDT", PositionSizeUSD: 1000.0, Leverage: 10, StopLoss: 49000.0, TakeProfit: 56000.0}
+		return d, &logger.DecisionAction{Action: d.Action, Symbol: d.Symbol}
+	}
+
+	s.Run("未开启时拒绝反手", func() {
+		d, record := newFlip()
+		err := s.autoTrader.executeDecisionWithRecord(d, record)
+		s.Error(err)
+		s.Contains(err.Error(), "allow_flip")
+		s.Empty(record.Legs)
+	})
+
+	s.Run("反手成功：先平空再开多", func() {
+		s.autoTrader.SetAllowFlip(true)
+		d, record := newFlip()
+		s.NoError(s.autoTrader.executeDecisionWithRecord(d, record))
+		s.Require().Len(record.Legs, 2)
+		s.Equal("close_short", record.Legs[0].Action)
+		s.True(record.Legs[0].Success)
+		s.Equal(int64(123459), record.Legs[0].OrderID)
+		s.Equal("open_long", record.Legs[1].Action)
+		s.True(record.Legs[1].Success)
+		s.Equal(int64(123456), record.OrderID)
+		s.InDelta(0.02, record.Quantity, 1e-9)
+	})
+
+	s.Run("平仓成功但开仓失败时记录为未完成", func() {
+		s.autoTrader.SetAllowFlip(true)
+		s.mockTrader.shouldFailOpenLong = true
+		d, record := newFlip()
+		err := s.autoTrader.executeDecisionWithRecord(d, record)
+		s.Error(err)
+		s.Contains(err.Error(), "当前空仓")
+		s.Require().Len(record.Legs, 2)
+		s.True(record.Legs[0].Success)
+		s.False(record.Legs[1].Success)
+		s.NotEmpty(record.Legs[1].Error)
+		s.Contains(record.Note, "反手未完成")
+	})
+
+	s.Run("没有反向持仓时不执行", func() {
+		s.autoTrader.SetAllowFlip(true)
+		d, record := newFlip()
+		d.Action = "flip_short"
+		err := s.autoTrader.executeDecisionWithRecord(d, record)
+		s.Error(err)
+		s.Contains(err.Error(), "没有可反手")
+	})
+}
+
+// TestConfidenceGate 测试信心度门槛：低信心度的开仓降级为 wait，未配置或未返回信心度时不拦截
+func (s *AutoTraderTestSuite) TestConfidenceGate() {
+	defer s.autoTrader.SetMinConfidence(0)
+
+	tests := []struct {
+		name          string
+		minConfidence int
+		action        string
+		confidence    int
+		gated         bool
+	}{
+		{name: "低信心度开仓被拦截", minConfidence: 75, action: "open_long", confidence: 60, gated: true},
+		{name: "低信心度反手被拦截", minConfidence: 75, action: "flip_short", confidence: 60, gated: true},
+		{name: "达到阈值放行", minConfidence: 75, action: "open_short", confidence: 75, gated: false},
+		{name: "平仓不受限制", minConfidence: 75, action: "close_long", confidence: 10, gated: false},
+		{name: "AI未返回信心度不拦截", minConfidence: 75, action: "open_long", confidence: 0, gated: false},
+		{name: "阈值为0不拦截", minConfidence: 0, action: "open_long", confidence: 10, gated: false},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			s.autoTrader.SetMinConfidence(tt.minConfidence)
+			d := &decision.Decision{Symbol: "BTCUSDT", Action: tt.action, Confidence: tt.confidence}
+			s.Equal(tt.gated, s.autoTrader.confidenceGated(d))
+		})
+	}
+}
+
+// TestPriceTickRounding 测试下单价格按 tick size 保守对齐
+func (s *AutoTraderTestSuite) TestPriceTickRounding() {
+	s.Run("按方向对齐到 tick size", func() {
+		s.Equal(95.1, RoundPriceToTick(95.1234, 0.1, false))
+		s.Equal(95.2, RoundPriceToTick(95.1234, 0.1, true))
+		s.Equal(0.3, RoundPriceToTick(0.3, 0.1, true))
+		s.Equal(1.025, RoundPriceToTick(1.0371, 0.025, false))
+		s.Equal(95.1234, RoundPriceToTick(95.1234, 0, false))
+	})
+
+	s.mockTrader.priceTickSize = 0.1
+	s.autoTrader.priceTickSizes.Delete("BTCUSDT")
+	defer func() {
+		s.mockTrader.priceTickSize = 0
+		s.autoTrader.priceTickSizes.Delete("BTCUSDT")
+	}()
+
+	s.Run("多仓止损止盈向下取整", func() {
+		s.NoError(s.autoTrader.setStopLossWithRetry("BTCUSDT", "LONG", 0.1, 48000.123))
+		s.NoError(s.autoTrader.setTakeProfitWithRetry("BTCUSDT", "LONG", 0.1, 52000.987))
+		s.Equal(48000.1, s.mockTrader.LastSLPrice)
+		s.Equal(52000.9, s.mockTrader.LastTPPrice)
+	})
+
+	s.Run("空仓止损止盈向上取整", func() {
+		s.NoError(s.autoTrader.setStopLossWithRetry("BTCUSDT", "SHORT", 0.1, 52000.123))
+		s.NoError(s.autoTrader.setTakeProfitWithRetry("BTCUSDT", "SHORT", 0.1, 48000.987))
+		s.Equal(52000.2, s.mockTrader.LastSLPrice)
+		s.Equal(48001.0, s.mockTrader.LastTPPrice)
+	})
+
+	s.Run("限价单价格对齐后下单", func() {
+		d := &decision.Decision{Action: "place_long_order", Symbol: "BTCUSDT", Price: 50000.1789, PositionSizeUSD: 1000.0, Leverage: 5}
+		actionRecord := &logger.DecisionAction{Action: d.Action, Symbol: d.Symbol}
+		s.NoError(s.autoTrader.executePlaceLimitOrderWithRecord("buy", "open", d, actionRecord))
+		s.Equal(50000.1, s.mockTrader.lastLimitPrice)
+		s.Equal(50000.1, actionRecord.Price)
+	})
+}
+
+// TestExecuteClosePosition 测试平仓操作（多空通用）
+func (s *AutoTraderTestSuite) TestExecuteClosePosition() {
+	tests := []struct {
+		name          string
+		action        string
+		currentPrice  float64
+		expectedOrder int64
+		executeFn     func(*decision.Decision, *logger.DecisionAction) error
+	}{
+		{
+			name:          "成功平多仓",
+			action:        "close_long",
+			currentPrice:  51000.0,
+			expectedOrder: 123458,
+			executeFn: func(d *decision.Decision, a *logger.DecisionAction) error {
+				return s.autoTrader.executeCloseLongWithRecord(d, a)
+			},
+		},
+		{
+			name:          "成功平空仓",
+			action:        "close_short",
+			currentPrice:  49000.0,
+			expectedOrder: 123459,
+			executeFn: func(d *decision.Decision, a *logger.DecisionAction) error {
+				return s.autoTrader.executeCloseShortWithRecord(d, a)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		time.Sleep(time.Millisecond)
+		s.Run(tt.name, func() {
+			s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+				return &market.Data{Symbol: symbol, CurrentPrice: tt.currentPrice}, nil
+			})
+
+			decision := &decision.Decision{Action: tt.action, Symbol: "BTCUSDT"}
+			actionRecord := &logger.DecisionAction{Action: tt.action, Symbol: "BTCUSDT"}
+
+			err := tt.executeFn(decision, actionRecord)
+
+			s.NoError(err)
+			s.Equal(tt.expectedOrder, actionRecord.OrderID)
+			s.Equal(tt.currentPrice, actionRecord.Price)
+		})
+	}
+}
+
+// TestCloseDustResidual 测试平仓后残留粉尘持仓：按配置再次平仓或直接按已平仓处理，不再阻塞后续开仓
+func (s *AutoTraderTestSuite) TestCloseDustResidual() {
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 50000.0}, nil
+	})
+	defer SetDustHandling(DustHandlingClose)
+	residual := func(qty float64) {
+		s.mockTrader.positions = []map[string]interface{}{
+			{"symbol": "BTCUSDT", "side": "long", "positionAmt": qty, "entryPrice": 50000.0, "markPrice": 50000.0},
+		}
+		s.mockTrader.closedPositions = nil
+		s.autoTrader.UpdatePeakPnL("BTCUSDT", "long", 8.0)
+		s.autoTrader.setPositionFirstSeen("BTCUSDT_long", 1700000000000)
+	}
+	closeLong := func() {
+		d := &decision.Decision{Action: "close_long", Symbol: "BTCUSDT"}
+		s.Require().NoError(s.autoTrader.executeCloseLongWithRecord(d, &logger.DecisionAction{Action: "close_long", Symbol: "BTCUSDT"}))
+	}
+
+	s.Run("close 模式：残留粉尘再次平仓并清理缓存", func() {
+		SetDustHandling(DustHandlingClose)
+		residual(0.00001)
+
+		closeLong()
+
+		s.Equal([]string{"BTCUSDT_long", "BTCUSDT_long"}, s.mockTrader.closedPositions)
+		_, hasPeak := s.autoTrader.GetPeakPnLCache()["BTCUSDT_long"]
+		s.False(hasPeak)
+		s.Empty(s.autoTrader.GetMonitorState().Positions)
+		s.Nil(s.autoTrader.findOpenPosition("BTCUSDT", "long"), "粉尘持仓不应阻塞开仓")
+	})
+
+	s.Run("mark 模式：不下单，直接按已平仓处理", func() {
+		SetDustHandling(DustHandlingMark)
+		residual(0.00001)
+
+		closeLong()
+
+		s.Equal([]string{"BTCUSDT_long"}, s.mockTrader.closedPositions)
+		s.Empty(s.autoTrader.GetMonitorState().Positions)
+	})
+
+	s.Run("非粉尘残留视为正常持仓", func() {
+		SetDustHandling(DustHandlingClose)
+		residual(0.5)
+
+		closeLong()
+
+		s.Equal([]string{"BTCUSDT_long"}, s.mockTrader.closedPositions)
+		s.Equal(8.0, s.autoTrader.GetPeakPnLCache()["BTCUSDT_long"])
+		s.NotNil(s.autoTrader.findOpenPosition("BTCUSDT", "long"))
+	})
+
+	s.Run("off 模式：粉尘持仓仍视为持仓", func() {
+		SetDustHandling(DustHandlingOff)
+		residual(0.00001)
+		s.NotNil(s.autoTrader.findOpenPosition("BTCUSDT", "long"))
+	})
+
+	s.Run("手动清理粉尘持仓", func() {
+		SetDustHandling(DustHandlingOff)
+		residual(0.00001)
+
+		results, err := s.autoTrader.SweepDust()
+
+		s.Require().NoError(err)
+		s.Require().Len(results, 1)
+		s.Equal("BTCUSDT", results[0].Symbol)
+		s.Equal("marked_flat", results[0].Action, "模拟交易所平仓后仍有残留")
+		s.Equal([]string{"BTCUSDT_long"}, s.mockTrader.closedPositions)
+	})
+}
+
+// TestOnAIFailure 测试AI响应无法解析时各 on_ai_failure 策略的处理
+func (s *AutoTraderTestSuite) TestOnAIFailure() {
+	_, parseErr := decision.ExtractDecisionsFromResponse("```json\n[{\"symbol\": \"BTCUSDT\", \"action\": \n```")
+	s.Require().Error(parseErr)
+
+	setup := func(policy string) {
+		s.mockTrader.positions = []map[string]interface{}{
+			{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.5, "entryPrice": 50000.0, "markPrice": 51000.0},
+			{"symbol": "ETHUSDT", "side": "short", "positionAmt": 2.0, "entryPrice": 3000.0, "markPrice": 2950.0},
+		}
+		s.mockTrader.closedPositions = nil
+		s.autoTrader.SetOnAIFailure(policy)
+		s.autoTrader.clearAIFailurePause()
+	}
+	openLong := func() error {
+		d := &decision.Decision{Action: "open_long", Symbol: "SOLUSDT", PositionSizeUSD: 100, Leverage: 5}
+		return s.autoTrader.dispatchDecisionWithRecord(d, &logger.DecisionAction{Action: "open_long", Symbol: "SOLUSDT"})
+	}
+	latestRecord := func() *logger.DecisionRecord {
+		records, err := s.autoTrader.decisionLogger.GetLatestRecords(1)
+		s.Require().NoError(err)
+		s.Require().Len(records, 1)
+		return records[0]
+	}
+	defer s.autoTrader.SetOnAIFailure(AIFailureHold)
+
+	s.Run("hold（默认）：保持持仓，不暂停开仓", func() {
+		setup("")
+		s.autoTrader.recordAIFailure("BTCUSDT", parseErr)
+
+		s.Empty(s.mockTrader.closedPositions)
+		s.False(s.autoTrader.IsAIFailurePaused())
+		record := latestRecord()
+		s.False(record.Success)
+		s.Contains(record.ErrorMessage, "AI决策失败")
+		s.Require().Len(record.ExecutionLog, 1)
+		s.Contains(record.ExecutionLog[0], "on_ai_failure=hold")
+	})
+
+	s.Run("flatten：平掉所有持仓并记录平仓动作", func() {
+		setup(AIFailureFlatten)
+		s.autoTrader.UpdatePeakPnL("BTCUSDT", "long", 5.0)
+		s.autoTrader.recordAIFailure("BTCUSDT", parseErr)
+
+		s.ElementsMatch([]string{"BTCUSDT_long", "ETHUSDT_short"}, s.mockTrader.closedPositions)
+		_, hasPeak := s.autoTrader.GetPeakPnLCache()["BTCUSDT_long"]
+		s.False(hasPeak)
+		s.False(s.autoTrader.IsAIFailurePaused())
+		record := latestRecord()
+		s.Require().Len(record.Decisions, 2)
+		for _, d := range record.Decisions {
+			s.Equal("ai_failure_flatten", d.Status)
+			s.True(d.Success)
+		}
+		s.Equal("close_long", record.Decisions[0].Action)
+		s.Equal("close_short", record.Decisions[1].Action)
+	})
+
+	s.Run("pause：暂停开新仓直到下一次AI决策成功", func() {
+		setup(AIFailurePause)
+		s.autoTrader.recordAIFailure("BTCUSDT", parseErr)
+
+		s.Empty(s.mockTrader.closedPositions)
+		s.True(s.autoTrader.IsAIFailurePaused())
+		s.Contains(latestRecord().ExecutionLog[0], "on_ai_failure=pause")
+		err := openLong()
+		s.Require().Error(err)
+		s.Contains(err.Error(), "暂停开新仓")
+		s.False(s.autoTrader.aiFailureBlocksOpen("close_long"), "暂停期间仍允许平仓")
+
+		s.autoTrader.clearAIFailurePause()
+		s.False(s.autoTrader.IsAIFailurePaused())
+		s.False(s.autoTrader.aiFailureBlocksOpen("open_long"))
+	})
+}
+
+// TestExchangeMaintenanceBackoff 测试交易所维护错误触发延长退避，恢复后回到正常节奏
+func (s *AutoTraderTestSuite) TestExchangeMaintenanceBackoff() {
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 50000.0}, nil
+	})
+	SetExchangeMaintenanceBackoff(30 * time.Minute)
+	defer SetExchangeMaintenanceBackoff(0)
+	s.mockTrader = new(MockTrader)
+	s.autoTrader.trader = s.mockTrader
+	maintenanceStatus := func() string {
+		return s.autoTrader.GetStatus()["exchange_maintenance"].(map[string]interface{})["status"].(string)
+	}
+
+	s.Run("识别维护错误，鉴权等普通错误不算维护", func() {
+		s.True(isExchangeMaintenanceError("binance", errors.New("<APIError> code=-1016, msg=This service is no longer available.")))
+		s.True(isExchangeMaintenanceError("bitget", errors.New(`http 400: {"code":"40725","msg":"service return an error"}`)))
+		s.True(isExchangeMaintenanceError("hyperliquid", errors.New("HTTP 503: Service Unavailable")))
+		s.False(isExchangeMaintenanceError("binance", errors.New("<APIError> code=-2015, msg=Invalid API-key, IP, or permissions for action.")))
+		s.False(isExchangeMaintenanceError("bitget", errors.New("failed to get balance")))
+	})
+
+	s.Run("维护错误触发延长退避，不作为普通失败", func() {
+		s.mockTrader.balanceErr = errors.New("HTTP 503: Service Unavailable")
+		before := time.Now()
+
+		record, err := s.autoTrader.runCycleWithRecord()
+
+		s.NoError(err, "交易所维护不应作为周期失败返回")
+		s.False(record.Success)
+		s.Contains(record.ErrorMessage, "exchange_maintenance")
+		until, active := s.autoTrader.exchangeMaintenanceActive(time.Now())
+		s.True(active)
+		s.WithinDuration(before.Add(30*time.Minute), until, 5*time.Second)
+		s.Equal("exchange_maintenance", maintenanceStatus())
+	})
+
+	s.Run("退避期内跳过周期，不调用交易所", func() {
+		s.mockTrader.balanceErr = nil
+		s.mockTrader.shouldFailBalance = true // 若调用交易所会返回普通错误
+
+		record, err := s.autoTrader.runCycleWithRecord()
+
+		s.NoError(err)
+		s.Contains(record.ErrorMessage, "交易所维护中")
+		s.Equal("exchange_maintenance", maintenanceStatus())
+	})
+
+	s.Run("退避结束后调用成功即恢复正常", func() {
+		s.mockTrader.shouldFailBalance = false
+		s.autoTrader.mu.Lock()
+		s.autoTrader.exchangeMaintenanceUntil = time.Now().Add(-time.Second)
+		s.autoTrader.mu.Unlock()
+		// 本周期在AI调用前因调用间隔限制结束，避免真实请求AI
+		s.autoTrader.SetMinSecondsBetweenAICalls(3600)
+		defer s.autoTrader.SetMinSecondsBetweenAICalls(0)
+		s.autoTrader.reserveAICall(time.Now(), false)
+
+		record, err := s.autoTrader.runCycleWithRecord()
+
+		s.NoError(err)
+		s.Empty(record.ErrorMessage)
+		s.Equal("normal", maintenanceStatus())
+		_, active := s.autoTrader.exchangeMaintenanceActive(time.Now())
+		s.False(active)
+	})
+}
+
+// TestExecuteUpdateStopOrTakeProfit 测试更新止损/止盈（多空通用）
+func (s *AutoTraderTestSuite) TestExecuteUpdateStopOrTakeProfit() {
+	// 使用指针变量来控制 market.Get 的返回值
+	var testPrice *float64
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		price := 50000.0
+		if testPrice != nil {
+			price = *testPrice
+		}
+		return &market.Data{Symbol: symbol, CurrentPrice: price}, nil
+	})
+
+	tests := []struct {
+		name         string
+		action       string
+		symbol       string
+		side         string
+		currentPrice float64
+		newPrice     float64
+		hasPosition  bool
+		expectedErr  string
+		executeFn    func(*decision.Decision, *logger.DecisionAction) error
+	}{
+		{
+			name:         "成功更新多头止损",
+			action:       "update_stop_loss",
+			symbol:       "BTCUSDT",
+			side:         "long",
+			currentPrice: 52000.0,
+			newPrice:     51000.0,
+			hasPosition:  true,
+			executeFn: func(d *decision.Decision, a *logger.DecisionAction) error {
+				return s.autoTrader.executeUpdateStopLossWithRecord(d, a)
+			},
+		},
+		{
+			name:         "成功更新空头止损",
+			action:       "update_stop_loss",
+			symbol:       "ETHUSDT",
+			side:         "short",
+			currentPrice: 2900.0,
+			newPrice:     2950.0,
+			hasPosition:  true,
+			executeFn: func(d *decision.Decision, a *logger.DecisionAction) error {
+				return s.autoTrader.executeUpdateStopLossWithRecord(d, a)
+			},
+		},
+		{
+			name:         "成功更新多头止盈",
+			action:       "update_take_profit",
+			symbol:       "BTCUSDT",
+			side:         "long",
+			currentPrice: 52000.0,
+			newPrice:     55000.0,
+			hasPosition:  true,
+			executeFn: func(d *decision.Decision, a *logger.DecisionAction) error {
+				return s.autoTrader.executeUpdateTakeProfitWithRecord(d, a)
+			},
+		},
+		{
+			name:         "成功更新空头止盈",
+			action:       "update_take_profit",
+			symbol:       "ETHUSDT",
+			side:         "short",
+			currentPrice: 2900.0,
+			newPrice:     2800.0,
+			hasPosition:  true,
+			executeFn: func(d *decision.Decision, a *logger.DecisionAction) error {
+				return s.autoTrader.executeUpdateTakeProfitWithRecord(d, a)
+			},
+		},
+		{
+			name:         "多头止损价格不合理",
+			action:       "update_stop_loss",
+			symbol:       "BTCUSDT",
+			side:         "long",
+			currentPrice: 50000.0,
+			newPrice:     51000.0,
+			hasPosition:  true,
+			expectedErr:  "多单止损必须低于当前价格",
+			executeFn: func(d *decision.Decision, a *logger.DecisionAction) error {
+				return s.autoTrader.executeUpdateStopLossWithRecord(d, a)
+			},
+		},
+		{
+			name:         "多头止盈价格不合理",
+			action:       "update_take_profit",
+			symbol:       "BTCUSDT",
+			side:         "long",
+			currentPrice: 50000.0,
+			newPrice:     49000.0,
+			hasPosition:  true,
+			expectedErr:  "多单止盈必须高于当前价格",
+			executeFn: func(d *decision.Decision, a *logger.DecisionAction) error {
+				return s.autoTrader.executeUpdateTakeProfitWithRecord(d, a)
+			},
+		},
+		{
+			name:         "止损_持仓不存在",
+			action:       "update_stop_loss",
+			symbol:       "BTCUSDT",
+			currentPrice: 50000.0,
+			newPrice:     49000.0,
+			hasPosition:  false,
+			expectedErr:  "持仓不存在",
+			executeFn: func(d *decision.Decision, a *logger.DecisionAction) error {
+				return s.autoTrader.executeUpdateStopLossWithRecord(d, a)
+			},
+		},
+		{
+			name:         "止盈_持仓不存在",
+			action:       "update_take_profit",
+			symbol:       "BTCUSDT",
+			currentPrice: 50000.0,
+			newPrice:     55000.0,
+			hasPosition:  false,
+			expectedErr:  "持仓不存在",
+			executeFn: func(d *decision.Decision, a *logger.DecisionAction) error {
+				return s.autoTrader.executeUpdateTakeProfitWithRecord(d, a)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		time.Sleep(time.Millisecond)
+		s.Run(tt.name, func() {
+			// 设置当前测试用例的价格
+			testPrice = &tt.currentPrice
+
+			if tt.hasPosition {
+				s.mockTrader.positions = []map[string]interface{}{
+					{"symbol": tt.symbol, "side": tt.side, "positionAmt": 0.1},
+				}
+			} else {
+				s.mockTrader.positions = []map[string]interface{}{}
+			}
+
+			decision := &decision.Decision{Action: tt.action, Symbol: tt.symbol}
+			if tt.action == "update_stop_loss" {
+				decision.NewStopLoss = tt.newPrice
+			} else {
+				decision.NewTakeProfit = tt.newPrice
+			}
+			actionRecord := &logger.DecisionAction{Action: tt.action, Symbol: tt.symbol}
+
+			err := tt.executeFn(decision, actionRecord)
+
+			if tt.expectedErr != "" {
+				s.Error(err)
+				s.Contains(err.Error(), tt.expectedErr)
+			} else {
+				s.NoError(err)
+				s.Equal(tt.currentPrice, actionRecord.Price)
+			}
+
+			// 恢复默认状态
+			s.mockTrader.positions = []map[string]interface{}{}
+		})
+	}
+}
+
+func (s *AutoTraderTestSuite) TestExecutePartialCloseWithRecord() {
+	s.Run("成功部分平仓", func() {
+		// 设置持仓
+		s.mockTrader.positions = []map[string]interface{}{
+			{
+				"symbol":      "BTCUSDT",
+				"side":        "long",
+				"positionAmt": 0.1,
+				"entryPrice":  50000.0,
+				"markPrice":   52000.0,
+			},
+		}
+
+		// Mock market.Get
+		s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+			return &market.Data{
+				Symbol:       symbol,
+				CurrentPrice: 52000.0,
+			}, nil
+		})
+
+		decision := &decision.Decision{
+			Action:          "partial_close",
+			Symbol:          "BTCUSDT",
+			ClosePercentage: 50.0,
+		}
+
+		actionRecord := &logger.DecisionAction{
+			Action: "partial_close",
+			Symbol: "BTCUSDT",
+		}
+
+		err := s.autoTrader.executePartialCloseWithRecord(decision, actionRecord)
+
+		s.NoError(err)
+		s.Equal(0.05, actionRecord.Quantity) // 50% of 0.1
+	})
+
+	s.Run("无效的平仓百分比", func() {
+		decision := &decision.Decision{
+			Action:          "partial_close",
+			Symbol:          "BTCUSDT",
+			ClosePercentage: 150.0, // 无效
+		}
+
+		actionRecord := &logger.DecisionAction{}
+
+		err := s.autoTrader.executePartialCloseWithRecord(decision, actionRecord)
+
+		s.Error(err)
+		s.Contains(err.Error(), "平仓百分比必须在 0-100 之间")
+	})
+}
+
+// ============================================================
+// 层次 10: executeDecisionWithRecord 路由测试
+// ============================================================
+
+func (s *AutoTraderTestSuite) TestExecuteDecisionWithRecord() {
+	// Mock market.Get
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{
+			Symbol:       symbol,
+			CurrentPrice: 50000.0,
+		}, nil
+	})
+
+	s.Run("路由到open_long", func() {
+		decision := &decision.Decision{
+			Action:          "open_long",
+			Symbol:          "BTCUSDT",
+			PositionSizeUSD: 1000.0,
+			Leverage:        10,
+		}
+		actionRecord := &logger.DecisionAction{}
+
+		err := s.autoTrader.executeDecisionWithRecord(decision, actionRecord)
+		s.NoError(err)
+	})
+
+	s.Run("路由到close_long", func() {
+		decision := &decision.Decision{
+			Action: "close_long",
+			Symbol: "BTCUSDT",
+		}
+		actionRecord := &logger.DecisionAction{}
+
+		err := s.autoTrader.executeDecisionWithRecord(decision, actionRecord)
+		s.NoError(err)
+	})
+
+	s.Run("路由到hold_不执行", func() {
+		decision := &decision.Decision{
+			Action: "hold",
+			Symbol: "BTCUSDT",
+		}
+		actionRecord := &logger.DecisionAction{}
+
+		err := s.autoTrader.executeDecisionWithRecord(decision, actionRecord)
+		s.NoError(err)
+	})
+
+	s.Run("未知action返回错误", func() {
+		decision := &decision.Decision{
+			Action: "unknown_action",
+			Symbol: "BTCUSDT",
+		}
+		actionRecord := &logger.DecisionAction{}
+
+		err := s.autoTrader.executeDecisionWithRecord(decision, actionRecord)
+		s.Error(err)
+		s.Contains(err.Error(), "未知的action")
+	})
+}
+
+func (s *AutoTraderTestSuite) TestCheckPositionDrawdown() {
+	tests := []struct {
+		name             string
+		setupPositions   func()
+		setupPeakPnL     func()
+		setupFailures    func()
+		cleanupFailures  func()
+		expectedCacheKey string
+		shouldClearCache bool
+		skipCacheCheck   bool
+	}{
+		{
+			name:            "获取持仓失败_不panic",
+			setupFailures:   func() { s.mockTrader.shouldFailPositions = true },
+			cleanupFailures: func() { s.mockTrader.shouldFailPositions = false },
+			skipCacheCheck:  true,
+		},
+		{
+			name:           "无持仓_不panic",
+			setupPositions: func() { s.mockTrader.positions = []map[string]interface{}{} },
+			skipCacheCheck: true,
+		},
+		{
+			name: "收益不足5%_不触发平仓",
+			setupPositions: func() {
+				s.mockTrader.positions = []map[string]interface{}{
+					{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.1, "entryPrice": 50000.0, "markPrice": 50150.0, "leverage": 10.0},
+				}
+			},
+			setupPeakPnL:   func() { s.autoTrader.ClearPeakPnLCache("BTCUSDT", "long") },
+			skipCacheCheck: true,
+		},
+		{
+			name: "回撤不足40%_不触发平仓",
+			setupPositions: func() {
+				s.mockTrader.positions = []map[string]interface{}{
+					{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.1, "entryPrice": 50000.0, "markPrice": 50400.0, "leverage": 10.0},
+				}
+			},
+			setupPeakPnL:   func() { s.autoTrader.UpdatePeakPnL("BTCUSDT", "long", 10.0) },
+			skipCacheCheck: true,
+		},
+		{
+			name: "多头_触发回撤平仓",
+			setupPositions: func() {
+				s.mockTrader.positions = []map[string]interface{}{
+					{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.1, "entryPrice": 50000.0, "markPrice": 50300.0, "leverage": 10.0},
+				}
+			},
+			setupPeakPnL:     func() { s.autoTrader.UpdatePeakPnL("BTCUSDT", "long", 10.0) },
+			expectedCacheKey: "BTCUSDT_long",
+			shouldClearCache: true,
+		},
+		{
+			name: "空头_触发回撤平仓",
+			setupPositions: func() {
+				s.mockTrader.positions = []map[string]interface{}{
+					{"symbol": "ETHUSDT", "side": "short", "positionAmt": -0.5, "entryPrice": 3000.0, "markPrice": 2982.0, "leverage": 10.0},
+				}
+			},
+			setupPeakPnL:     func() { s.autoTrader.UpdatePeakPnL("ETHUSDT", "short", 10.0) },
+			expectedCacheKey: "ETHUSDT_short",
+			shouldClearCache: true,
+		},
+		{
+			name: "多头_平仓失败_保留缓存",
+			setupPositions: func() {
+				s.mockTrader.positions = []map[string]interface{}{
+					{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.1, "entryPrice": 50000.0, "markPrice": 50300.0, "leverage": 10.0},
+				}
+			},
+			setupPeakPnL:     func() { s.autoTrader.UpdatePeakPnL("BTCUSDT", "long", 10.0) },
+			setupFailures:    func() { s.mockTrader.shouldFailCloseLong = true },
+			cleanupFailures:  func() { s.mockTrader.shouldFailCloseLong = false },
+			expectedCacheKey: "BTCUSDT_long",
+			shouldClearCache: false,
+		},
+		{
+			name: "空头_平仓失败_保留缓存",
+			setupPositions: func() {
+				s.mockTrader.positions = []map[string]interface{}{
+					{"symbol": "ETHUSDT", "side": "short", "positionAmt": -0.5, "entryPrice": 3000.0, "markPrice": 2982.0, "leverage": 10.0},
+				}
+			},
+			setupPeakPnL:     func() { s.autoTrader.UpdatePeakPnL("ETHUSDT", "short", 10.0) },
+			setupFailures:    func() { s.mockTrader.shouldFailCloseShort = true },
+			cleanupFailures:  func() { s.mockTrader.shouldFailCloseShort = false },
+			expectedCacheKey: "ETHUSDT_short",
+			shouldClearCache: false,
+		},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			if tt.setupPositions != nil {
+				tt.setupPositions()
+			}
+			if tt.setupPeakPnL != nil {
+				tt.setupPeakPnL()
+			}
+			if tt.setupFailures != nil {
+				tt.setupFailures()
+			}
+			if tt.cleanupFailures != nil {
+				defer tt.cleanupFailures()
+			}
+
+			s.autoTrader.checkPositionDrawdown()
+
+			if !tt.skipCacheCheck {
+				cache := s.autoTrader.GetPeakPnLCache()
+				_, exists := cache[tt.expectedCacheKey]
+				if tt.shouldClearCache {
+					s.False(exists, "峰值缓存应该被清理")
+				} else {
+					s.True(exists, "峰值缓存不应该被清理")
+				}
+			}
+
+			// 清理状态
+			s.mockTrader.positions = []map[string]interface{}{}
+		})
+	}
+}
+
+// ============================================================
+// Mock 实现
+// ============================================================
+
+// partialCloseMockTrader 支持只减仓（PartialCloser）的模拟交易器
+type partialCloseMockTrader struct {
+	*MockTrader
+}
+
+func (m *partialCloseMockTrader) ReducePosition(symbol, side string, quantity float64) (map[string]interface{}, error) {
+	m.closedPositions = append(m.closedPositions, symbol+"_"+side)
+	m.closedQuantities = append(m.closedQuantities, quantity)
+	return map[string]interface{}{"symbol": symbol}, nil
+}
+
+// MockDatabase 模拟数据库
+type MockDatabase struct {
+	shouldFail       bool
+	adjustedDeltas   []float64
+	strategyStatuses map[string]*config.TraderStrategyStatus // strategyID -> 状态
+	decisions        []*config.StrategyDecisionHistory       // SaveStrategyDecision 记录
+	pyramidStates    map[string]config.PositionPyramidState  // SaveTraderPositionPyramid 保存的加仓状态
+	equityBracketHit string                                  // SaveTraderEquityBracketHit 保存的净值止盈止损暂停状态
+}
+
+func (m *MockDatabase) GetTraderEquityBracketHit(traderID string) (string, error) {
+	return m.equityBracketHit, nil
+}
+
+func (m *MockDatabase) SaveTraderEquityBracketHit(traderID, hit string) error {
+	if m.shouldFail {
+		return errors.New("database error")
+	}
+	m.equityBracketHit = hit
+	return nil
+}
+
+func (m *MockDatabase) GetTraderPositionPyramid(traderID string) (map[string]config.PositionPyramidState, error) {
+	states := make(map[string]config.PositionPyramidState, len(m.pyramidStates))
+	for k, v := range m.pyramidStates {
+		states[k] = v
+	}
+	return states, nil
+}
+
+func (m *MockDatabase) SaveTraderPositionPyramid(traderID string, states map[string]config.PositionPyramidState) error {
+	if m.shouldFail {
+		return errors.New("database error")
+	}
+	m.pyramidStates = states
+	return nil
+}
+
+func (m *MockDatabase) SaveStrategyDecision(history *config.StrategyDecisionHistory) error {
+	m.decisions = append(m.decisions, history)
+	return nil
+}
+
+func (m *MockDatabase) GetTraderStrategyStatuses(traderID string) ([]*config.TraderStrategyStatus, error) {
+	var statuses []*config.TraderStrategyStatus
+	for _, st := range m.strategyStatuses {
+		copied := *st
+		statuses = append(statuses, &copied)
+	}
+	return statuses, nil
+}
+
+func (m *MockDatabase) UpdateTraderStrategyStatus(status *config.TraderStrategyStatus) error {
+	if m.shouldFail {
+		return errors.New("database error")
+	}
+	copied := *status
+	m.strategyStatuses[status.StrategyID] = &copied
+	return nil
+}
+
+func (m *MockDatabase) AdjustTraderInitialBalance(traderID string, delta float64) error {
+	if m.shouldFail {
+		return errors.New("database error")
+	}
+	m.adjustedDeltas = append(m.adjustedDeltas, delta)
+	return nil
+}
+
+func (m *MockDatabase) UpdateTraderInitialBalance(userID, traderID string, newBalance float64) error {
+	if m.shouldFail {
+		return errors.New("database error")
+	}
+	return nil
+}
+
+// MockTrader 增强版（添加错误控制）
+type MockTrader struct {
+	balance              map[string]interface{}
+	positions            []map[string]interface{}
+	openOrders           []map[string]interface{} // 用于 GetOpenOrders 返回
+	openOrdersErr        error                    // 非nil时 GetOpenOrders 返回该错误
+	balanceHistory       []map[string]interface{} // 用于 GetBalanceHistory 返回
+	leverageBrackets     []LeverageBracket        // 用于 GetLeverageBrackets 返回
+	priceTickSize        float64                  // 用于 GetPriceTickSize 返回
+	orderBook            *OrderBook               // 用于 GetOrderBookDepth 返回
+	orderBookCalls       int                      // GetOrderBookDepth 调用次数
+	lastLimitPrice       float64                  // 最近一次 PlaceLimitOrder 的价格
+	lastOpenLongQty      float64                  // 最近一次 OpenLong 的数量
+	exchangeIsolated     bool                     // GetMarginMode 返回逐仓（默认全仓）
+	marginModeLocked     bool                     // SetMarginMode 不生效（模拟有持仓时交易所锁定仓位模式）
+	marginModeSet        []bool                   // SetMarginMode 调用记录
+	closedPositions      []string                 // CloseLong/CloseShort 调用记录（symbol_side）
+	closedQuantities     []float64                // CloseLong/CloseShort 调用的平仓数量
+	orderCalls           []string                 // CancelOrder/OpenShort 调用顺序（cancel:订单ID / open_short:symbol）
+	shouldFailBalance    bool
+	balanceErr           error // GetBalance 返回的指定错误（如模拟交易所维护）
+	shouldFailPositions  bool
+	shouldFailOpenLong   bool
+	shouldFailCloseLong  bool
+	shouldFailCloseShort bool
+
+	// 调用跟踪
+	SetStopLossCalled   bool
+	SetTakeProfitCalled bool
+	LastSLPrice         float64
+	LastTPPrice         float64
+
+	// 止损下单错误队列（按调用顺序依次返回，用尽后返回nil）
+	stopLossErrs     []error
+	stopLossQuantity []float64
+
+	ocoCalls      int      // SetOCO 调用次数
+	cancelledKind []string // CancelStopLossOrders/CancelTakeProfitOrders/CancelStopOrders 调用记录（stop_loss:symbol 等）
+}
+
+func (m *MockTrader) GetBalance() (map[string]interface{}, error) {
+	if m.balanceErr != nil {
+		return nil, m.balanceErr
+	}
+	if m.shouldFailBalance {
+		return nil, errors.New("failed to get balance")
+	}
+	if m.balance == nil {
+		return map[string]interface{}{
+			"totalWalletBalance":    10000.0,
+			"availableBalance":      8000.0,
+			"totalUnrealizedProfit": 100.0,
+		}, nil
+	}
+	return m.balance, nil
+}
+
+func (m *MockTrader) GetPositions() ([]map[string]interface{}, error) {
+	if m.shouldFailPositions {
+		return nil, errors.New("failed to get positions")
+	}
+	if m.positions == nil {
+		return []map[string]interface{}{}, nil
+	}
+	return m.positions, nil
+}
+
+func (m *MockTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	if m.shouldFailOpenLong {
+		return nil, errors.New("failed to open long")
+	}
+	m.lastOpenLongQty = quantity
+	return map[string]interface{}{
+		"orderId": int64(123456),
+		"symbol":  symbol,
+	}, nil
+}
+
+func (m *MockTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	m.orderCalls = append(m.orderCalls, "open_short:"+symbol)
+	return map[string]interface{}{
+		"orderId": int64(123457),
+		"symbol":  symbol,
+	}, nil
+}
+
+func (m *MockTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+	if m.shouldFailCloseLong {
+		return nil, errors.New("failed to close long")
+	}
+	m.closedPositions = append(m.closedPositions, symbol+"_long")
+	m.closedQuantities = append(m.closedQuantities, quantity)
+	return map[string]interface{}{
+		"orderId": int64(123458),
+		"symbol":  symbol,
+	}, nil
+}
+
+func (m *MockTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+	if m.shouldFailCloseShort {
+		return nil, errors.New("failed to close short")
+	}
+	m.closedPositions = append(m.closedPositions, symbol+"_short")
+	m.closedQuantities = append(m.closedQuantities, quantity)
+	return map[string]interface{}{
+		"orderId": int64(123459),
+		"symbol":  symbol,
+	}, nil
+}
+
+func (m *MockTrader) SetLeverage(symbol string, leverage int) error {
+	return nil
+}
+
+func (m *MockTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
+	m.marginModeSet = append(m.marginModeSet, isCrossMargin)
+	if m.marginModeLocked {
+		return nil
+	}
+	m.exchangeIsolated = !isCrossMargin
+	return nil
+}
+
+func (m *MockTrader) GetMarginMode(symbol string) (bool, error) {
+	return !m.exchangeIsolated, nil
+}
+
+func (m *MockTrader) GetMarketPrice(symbol string) (float64, error) {
+	return 50000.0, nil
+}
+
+func (m *MockTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	m.SetStopLossCalled = true
+	m.LastSLPrice = stopPrice
+	m.stopLossQuantity = append(m.stopLossQuantity, quantity)
+	if len(m.stopLossErrs) > 0 {
+		err := m.stopLossErrs[0]
+		m.stopLossErrs = m.stopLossErrs[1:]
+		return err
+	}
+	return nil
+}
+
+func (m *MockTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	m.SetTakeProfitCalled = true
+	m.LastTPPrice = takeProfitPrice
+	return nil
+}
+
+func (m *MockTrader) SetOCO(symbol, side string, quantity, stopPrice, takeProfitPrice float64) error {
+	m.ocoCalls++
+	m.LastSLPrice = stopPrice
+	m.LastTPPrice = takeProfitPrice
+	return nil
+}
+
+func (m *MockTrader) CancelStopLossOrders(symbol string) error {
+	m.cancelledKind = append(m.cancelledKind, "stop_loss:"+symbol)
+	return nil
+}
+
+func (m *MockTrader) CancelTakeProfitOrders(symbol string) error {
+	m.cancelledKind = append(m.cancelledKind, "take_profit:"+symbol)
+	return nil
+}
+
+func (m *MockTrader) CancelAllOrders(symbol string) error {
+	return nil
+}
+
+func (m *MockTrader) CancelStopOrders(symbol string) error {
+	m.cancelledKind = append(m.cancelledKind, "stop_orders:"+symbol)
+	return nil
+}
+
+func (m *MockTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
+	return fmt.Sprintf("%.4f", quantity), nil
+}
+
+func (m *MockTrader) GetOpenOrders(symbol string) ([]map[string]interface{}, error) {
+	if m.openOrdersErr != nil {
+		return nil, m.openOrdersErr
+	}
+	if m.openOrders != nil {
+		return m.openOrders, nil
+	}
+	return []map[string]interface{}{}, nil
+}
+
+func (m *MockTrader) GetOrderHistory(symbol string, startTime, endTime int64) ([]map[string]interface{}, error) {
+	return []map[string]interface{}{}, nil
+}
+
+func (m *MockTrader) GetBalanceHistory(startTime, endTime int64) ([]map[string]interface{}, error) {
+	return m.balanceHistory, nil
+}
+
+func (m *MockTrader) GetLeverageBrackets(symbol string) ([]LeverageBracket, error) {
+	return m.leverageBrackets, nil
+}
+
+func (m *MockTrader) GetPriceTickSize(symbol string) (float64, error) {
+	return m.priceTickSize, nil
+}
+
+func (m *MockTrader) GetOrderBookDepth(symbol string, levels int) (*OrderBook, error) {
+	m.orderBookCalls++
+	return m.orderBook, nil
+}
+
+func (m *MockTrader) GetServerTime() (int64, error) {
+	return time.Now().UnixMilli(), nil
+}
+
+func (m *MockTrader) CancelOrder(symbol, orderId string) error {
+	m.orderCalls = append(m.orderCalls, "cancel:"+orderId)
+	return nil
+}
+
+func (m *MockTrader) PlaceLimitOrder(symbol string, side, tradeSide string, quantity float64, price float64, leverage int) (map[string]interface{}, error) {
+	m.lastLimitPrice = price
+	return map[string]interface{}{
+		"orderId": int64(123460),
+		"symbol":  symbol,
+	}, nil
+}
+
+
+// TestCheckStrategyCompletion 测试二次检查补单逻辑
+func (s *AutoTraderTestSuite) TestCheckStrategyCompletion() {
+	// 1. 准备测试数据
+	symbol := "ETHUSDT"
+	strat := &signal.SignalDecision{
+		SignalID: "test-001",
+		Symbol:   symbol,
+		StopLoss: signal.StopLossStrategy{Price: 3000.0},
+		TakeProfits: []signal.TPStrategy{
+			{Price: 3500.0, Percent: 1.0},
+		},
+	}
+
+	// 场景A: 有持仓，无止损 => 应该触发 SetStopLoss 和 SetTakeProfit
+	s.mockTrader = new(MockTrader)
+	s.autoTrader.trader = s.mockTrader
+
+	// Mock GetPositions: 返回 0.1 ETH 多单
+	s.mockTrader.positions = []map[string]interface{}{
+		{
+			"symbol":      symbol,
+			"positionAmt": 0.1,
+		},
+	}
+	// Mock GetOpenOrders: 空列表（无止损）
+	s.mockTrader.openOrders = []map[string]interface{}{}
+
+	// 执行测试
+	s.autoTrader.CheckStrategyCompletion(strat)
+
+	// 验证调用
+	s.T().Logf("Scenario A: SetStopLossCalled=%v, SetTakeProfitCalled=%v", s.mockTrader.SetStopLossCalled, s.mockTrader.SetTakeProfitCalled)
+	s.True(s.mockTrader.SetStopLossCalled, "应该并且只能调用 SetStopLoss")
+	s.True(s.mockTrader.SetTakeProfitCalled, "应该并且只能调用 SetTakeProfit")
+	s.Equal(3000.0, s.mockTrader.LastSLPrice)
+	s.Equal(3500.0, s.mockTrader.LastTPPrice)
+
+	// 场景B: 有持仓，已有止损 => 不应重复设置
+	s.mockTrader = new(MockTrader)
+	s.autoTrader.trader = s.mockTrader
+
+	s.mockTrader.positions = []map[string]interface{}{
 		{
-			name:         "成功更新多头止损",
-			action:       "update_stop_loss",
-			symbol:       "BTCUSDT",
-			side:         "long",
-			currentPrice: 52000.0,
-			newPrice:     51000.0,
-			hasPosition:  true,
-			executeFn: func(d *decision.Decision, a *logger.DecisionAction) error {
-				return s.autoTrader.executeUpdateStopLossWithRecord(d, a)
-			},
+			"symbol":      symbol,
+			"positionAmt": 0.1,
 		},
+	}
+
+	// Mock GetOpenOrders: 返回已有止损单
+	s.mockTrader.openOrders = []map[string]interface{}{
 		{
-			name:         "成功更新空头止损",
-			action:       "update_stop_loss",
-			symbol:       "ETHUSDT",
-			side:         "short",
-			currentPrice: 2900.0,
-			newPrice:     2950.0,
-			hasPosition:  true,
-			executeFn: func(d *decision.Decision, a *logger.DecisionAction) error {
-				return s.autoTrader.executeUpdateStopLossWithRecord(d, a)
-			},
+			"symbol": symbol,
+			"type":   "stop_loss", // 表示已存在
 		},
 		{
-			name:         "成功更新多头止盈",
-			action:       "update_take_profit",
-			symbol:       "BTCUSDT",
-			side:         "long",
-			currentPrice: 52000.0,
-			newPrice:     55000.0,
-			hasPosition:  true,
-			executeFn: func(d *decision.Decision, a *logger.DecisionAction) error {
-				return s.autoTrader.executeUpdateTakeProfitWithRecord(d, a)
-			},
+			"symbol": symbol,
+			"type":   "take_profit",
 		},
+	}
+
+	// 执行测试
+	s.autoTrader.CheckStrategyCompletion(strat)
+
+	// 验证不调用
+	s.T().Logf("Scenario B: SetStopLossCalled=%v, SetTakeProfitCalled=%v", s.mockTrader.SetStopLossCalled, s.mockTrader.SetTakeProfitCalled)
+	s.False(s.mockTrader.SetStopLossCalled, "不应重复设置止损")
+	s.False(s.mockTrader.SetTakeProfitCalled, "不应重复设置止盈")
+}
+
+// ============================================================
+// 测试套件入口
+// ============================================================
+
+// TestAutoTraderTestSuite 运行 AutoTrader 测试套件
+func TestAutoTraderTestSuite(t *testing.T) {
+	suite.Run(t, new(AutoTraderTestSuite))
+}
+
+// ============================================================
+// 独立的单元测试 - calculatePnLPercentage 函数测试
+// ============================================================
+
+func TestCalculatePnLPercentage(t *testing.T) {
+	tests := []struct {
+		name          string
+		unrealizedPnl float64
+		marginUsed    float64
+		expected      float64
+	}{
 		{
-			name:         "成功更新空头止盈",
-			action:       "update_take_profit",
-			symbol:       "ETHUSDT",
-			side:         "short",
-			currentPrice: 2900.0,
-			newPrice:     2800.0,
-			hasPosition:  true,
-			executeFn: func(d *decision.Decision, a *logger.DecisionAction) error {
-				return s.autoTrader.executeUpdateTakeProfitWithRecord(d, a)
-			},
+			name:          "正常盈利 - 10倍杠杆",
+			unrealizedPnl: 100.0,  // 盈利 100 USDT
+			marginUsed:    1000.0, // 保证金 1000 USDT
+			expected:      10.0,   // 10% 收益率
 		},
 		{
-			name:         "多头止损价格不合理",
-			action:       "update_stop_loss",
-			symbol:       "BTCUSDT",
-			side:         "long",
-			currentPrice: 50000.0,
-			newPrice:     51000.0,
-			hasPosition:  true,
-			expectedErr:  "多单止损必须低于当前价格",
-			executeFn: func(d *decision.Decision, a *logger.DecisionAction) error {
-				return s.autoTrader.executeUpdateStopLossWithRecord(d, a)
-			},
+			name:          "正常亏损 - 10倍杠杆",
+			unrealizedPnl: -50.0,  // 亏损 50 USDT
+			marginUsed:    1000.0, // 保证金 1000 USDT
+			expected:      -5.0,   // -5% 收益率
 		},
 		{
-			name:         "多头止盈价格不合理",
-			action:       "update_take_profit",
-			symbol:       "BTCUSDT",
-			side:         "long",
-			currentPrice: 50000.0,
-			newPrice:     49000.0,
-			hasPosition:  true,
-			expectedErr:  "多单止盈必须高于当前价格",
-			executeFn: func(d *decision.Decision, a *logger.DecisionAction) error {
-				return s.autoTrader.executeUpdateTakeProfitWithRecord(d, a)
-			},
+			name:          "高杠杆盈利 - 价格上涨1%，20倍杠杆",
+			unrealizedPnl: 200.0,  // 盈利 200 USDT
+			marginUsed:    1000.0, // 保证金 1000 USDT
+			expected:      20.0,   // 20% 收益率
 		},
 		{
-			name:         "止损_持仓不存在",
-			action:       "update_stop_loss",
-			symbol:       "BTCUSDT",
-			currentPrice: 50000.0,
-			newPrice:     49000.0,
-			hasPosition:  false,
-			expectedErr:  "持仓不存在",
-			executeFn: func(d *decision.Decision, a *logger.DecisionAction) error {
-				return s.autoTrader.executeUpdateStopLossWithRecord(d, a)
-			},
+			name:          "保证金为0 - 边界情况",
+			unrealizedPnl: 100.0,
+			marginUsed:    0.0,
+			expected:      0.0, // 应该返回 0 而不是除以零错误
 		},
 		{
-			name:         "止盈_持仓不存在",
-			action:       "update_take_profit",
-			symbol:       "BTCUSDT",
-			currentPrice: 50000.0,
-			newPrice:     55000.0,
-			hasPosition:  false,
-			expectedErr:  "持仓不存在",
-			executeFn: func(d *decision.Decision, a *logger.DecisionAction) error {
-				return s.autoTrader.executeUpdateTakeProfitWithRecord(d, a)
-			},
+			name:          "负保证金 - 边界情况",
+			unrealizedPnl: 100.0,
+			marginUsed:    -1000.0,
+			expected:      0.0, // 应该返回 0（异常情况）
+		},
+		{
+			name:          "盈亏为0",
+			unrealizedPnl: 0.0,
+			marginUsed:    1000.0,
+			expected:      0.0,
+		},
+		{
+			name:          "小额交易",
+			unrealizedPnl: 0.5,
+			marginUsed:    10.0,
+			expected:      5.0,
+		},
+		{
+			name:          "大额盈利",
+			unrealizedPnl: 5000.0,
+			marginUsed:    10000.0,
+			expected:      50.0,
+		},
+		{
+			name:          "极小保证金",
+			unrealizedPnl: 1.0,
+			marginUsed:    0.01,
+			expected:      10000.0, // 100倍收益率
 		},
 	}
 
 	for _, tt := range tests {
-		time.Sleep(time.Millisecond)
-		s.Run(tt.name, func() {
-			// 设置当前测试用例的价格
-			testPrice = &tt.currentPrice
+		t.Run(tt.name, func(t *testing.T) {
+			result := calculatePnLPercentage(tt.unrealizedPnl, tt.marginUsed)
 
-			if tt.hasPosition {
-				s.mockTrader.positions = []map[string]interface{}{
-					{"symbol": tt.symbol, "side": tt.side, "positionAmt": 0.1},
-				}
-			} else {
-				s.mockTrader.positions = []map[string]interface{}{}
+			// 使用精度比较，避免浮点数误差
+			if math.Abs(result-tt.expected) > 0.0001 {
+				t.Errorf("calculatePnLPercentage(%v, %v) = %v, want %v",
+					tt.unrealizedPnl, tt.marginUsed, result, tt.expected)
 			}
+		})
+	}
+}
 
-			decision := &decision.Decision{Action: tt.action, Symbol: tt.symbol}
-			if tt.action == "update_stop_loss" {
-				decision.NewStopLoss = tt.newPrice
-			} else {
-				decision.NewTakeProfit = tt.newPrice
-			}
-			actionRecord := &logger.DecisionAction{Action: tt.action, Symbol: tt.symbol}
+// TestCalculatePnLPercentage_RealWorldScenarios 真实场景测试
+func TestCalculatePnLPercentage_RealWorldScenarios(t *testing.T) {
+	t.Run("BTC 10倍杠杆，价格上涨2%", func(t *testing.T) {
+		// 开仓：1000 USDT 保证金，10倍杠杆 = 10000 USDT 仓位
+		// 价格上涨 2% = 200 USDT 盈利
+		// 收益率 = 200 / 1000 = 20%
+		result := calculatePnLPercentage(200.0, 1000.0)
+		expected := 20.0
+		if math.Abs(result-expected) > 0.0001 {
+			t.Errorf("BTC场景: got %v, want %v", result, expected)
+		}
+	})
+
+	t.Run("ETH 5倍杠杆，价格下跌3%", func(t *testing.T) {
+		// 开仓：2000 USDT 保证金，5倍杠杆 = 10000 USDT 仓位
+		// 价格下跌 3% = -300 USDT 亏损
+		// 收益率 = -300 / 2000 = -15%
+		result := calculatePnLPercentage(-300.0, 2000.0)
+		expected := -15.0
+		if math.Abs(result-expected) > 0.0001 {
+			t.Errorf("ETH场景: got %v, want %v", result, expected)
+		}
+	})
+
+	t.Run("SOL 20倍杠杆，价格上涨0.5%", func(t *testing.T) {
+		// 开仓：500 USDT 保证金，20倍杠杆 = 10000 USDT 仓位
+		// 价格上涨 0.5% = 50 USDT 盈利
+		// 收益率 = 50 / 500 = 10%
+		result := calculatePnLPercentage(50.0, 500.0)
+		expected := 10.0
+		if math.Abs(result-expected) > 0.0001 {
+			t.Errorf("SOL场景: got %v, want %v", result, expected)
+		}
+	})
+}
+
+// TestReprotectPositions 测试决策周期后补设被交易所撤销的保护单
+func (s *AutoTraderTestSuite) TestReprotectPositions() {
+	setup := func(enabled bool) {
+		s.mockTrader = new(MockTrader)
+		s.autoTrader.trader = s.mockTrader
+		s.autoTrader.SetAutoReprotect(enabled)
+		s.autoTrader.resetPyramidState("ETHUSDT_long", 3000.0)
+		s.autoTrader.updatePyramidTakeProfit("ETHUSDT_long", 3500.0)
+		s.mockTrader.positions = []map[string]interface{}{
+			{"symbol": "ETHUSDT", "side": "long", "positionAmt": 0.5, "entryPrice": 3200.0},
+		}
+		// 止损单已被交易所撤销，只剩止盈单
+		s.mockTrader.openOrders = []map[string]interface{}{
+			{"symbol": "ETHUSDT", "type": "profit_plan", "price": 3500.0},
+		}
+	}
+	defer s.autoTrader.SetAutoReprotect(false)
+
+	s.Run("止损单缺失时按最近决策价位补设并记录", func() {
+		setup(true)
+		record := &logger.DecisionRecord{}
+		s.autoTrader.reprotectPositions(record)
+
+		s.True(s.mockTrader.SetStopLossCalled)
+		s.Equal(3000.0, s.mockTrader.LastSLPrice)
+		s.Equal([]float64{0.5}, s.mockTrader.stopLossQuantity)
+		s.False(s.mockTrader.SetTakeProfitCalled, "止盈单仍在，不应重复设置")
+
+		s.Require().Len(record.Decisions, 1)
+		s.Equal("reprotect_stop_loss", record.Decisions[0].Action)
+		s.Equal("ETHUSDT", record.Decisions[0].Symbol)
+		s.True(record.Decisions[0].Success)
+		s.Len(record.ExecutionLog, 1)
+	})
+
+	s.Run("未开启时不校验", func() {
+		setup(false)
+		record := &logger.DecisionRecord{}
+		s.autoTrader.reprotectPositions(record)
+
+		s.False(s.mockTrader.SetStopLossCalled)
+		s.Empty(record.Decisions)
+	})
+
+	s.Run("没有决策价位的持仓不补设", func() {
+		setup(true)
+		s.autoTrader.prunePyramidState(map[string]bool{})
+		record := &logger.DecisionRecord{}
+		s.autoTrader.reprotectPositions(record)
+
+		s.False(s.mockTrader.SetStopLossCalled)
+		s.Empty(record.Decisions)
+	})
+
+	s.Run("交易所不支持查询委托时跳过补设", func() {
+		setup(true)
+		s.mockTrader.openOrdersErr = ErrOpenOrdersNotSupported
+		record := &logger.DecisionRecord{}
+		s.autoTrader.reprotectPositions(record)
+
+		s.False(s.mockTrader.SetStopLossCalled)
+		s.False(s.mockTrader.SetTakeProfitCalled)
+		s.Empty(record.Decisions)
+	})
+}
+
+// TestOCOProtectiveOrders 测试 OCO 保护单：开启 use_oco 时联动下单，模拟 OCO 时止盈成交后撤销联动的止损单
+func (s *AutoTraderTestSuite) TestOCOProtectiveOrders() {
+	setup := func(useOCO bool) {
+		s.mockTrader = new(MockTrader)
+		s.autoTrader.trader = s.mockTrader
+		s.autoTrader.SetUseOCO(useOCO)
+		s.autoTrader.ocoLinks.Delete("ETHUSDT_long")
+	}
+	defer s.autoTrader.SetUseOCO(false)
+	linked := func() bool {
+		_, ok := s.autoTrader.ocoLinks.Load("ETHUSDT_long")
+		return ok
+	}
 
-			err := tt.executeFn(decision, actionRecord)
+	s.Run("开启时以OCO下单并登记联动", func() {
+		setup(true)
+		s.autoTrader.placeProtectiveOrders("ETHUSDT", "LONG", 0.5, 3000, 3500)
 
-			if tt.expectedErr != "" {
-				s.Error(err)
-				s.Contains(err.Error(), tt.expectedErr)
-			} else {
-				s.NoError(err)
-				s.Equal(tt.currentPrice, actionRecord.Price)
-			}
+		s.Equal(1, s.mockTrader.ocoCalls)
+		s.Equal(3000.0, s.mockTrader.LastSLPrice)
+		s.Equal(3500.0, s.mockTrader.LastTPPrice)
+		s.False(s.mockTrader.SetStopLossCalled, "OCO 下单不应再单独设置止损")
+		s.True(linked())
+	})
 
-			// 恢复默认状态
-			s.mockTrader.positions = []map[string]interface{}{}
-		})
-	}
-}
+	s.Run("止盈成交后撤销联动的止损单", func() {
+		setup(true)
+		s.autoTrader.placeProtectiveOrders("ETHUSDT", "LONG", 0.5, 3000, 3500)
+		// 止盈已成交：持仓消失，只剩止损单
+		s.mockTrader.positions = []map[string]interface{}{}
+		s.mockTrader.openOrders = []map[string]interface{}{
+			{"symbol": "ETHUSDT", "type": "loss_plan", "price": 3000.0},
+		}
+		s.autoTrader.reconcileOCOOrders()
 
-func (s *AutoTraderTestSuite) TestExecutePartialCloseWithRecord() {
-	s.Run("成功部分平仓", func() {
-		// 设置持仓
+		s.Equal([]string{"stop_loss:ETHUSDT"}, s.mockTrader.cancelledKind)
+		s.False(linked(), "处理后应移除联动")
+	})
+
+	s.Run("持仓仍在时不撤单", func() {
+		setup(true)
+		s.autoTrader.placeProtectiveOrders("ETHUSDT", "LONG", 0.5, 3000, 3500)
 		s.mockTrader.positions = []map[string]interface{}{
-			{
-				"symbol":      "BTCUSDT",
-				"side":        "long",
-				"positionAmt": 0.1,
-				"entryPrice":  50000.0,
-				"markPrice":   52000.0,
-			},
+			{"symbol": "ETHUSDT", "side": "long", "positionAmt": 0.5, "entryPrice": 3200.0},
+		}
+		s.mockTrader.openOrders = []map[string]interface{}{
+			{"symbol": "ETHUSDT", "type": "loss_plan", "price": 3000.0},
 		}
+		s.autoTrader.reconcileOCOOrders()
 
-		// Mock market.Get
-		s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
-			return &market.Data{
-				Symbol:       symbol,
-				CurrentPrice: 52000.0,
-			}, nil
-		})
+		s.Empty(s.mockTrader.cancelledKind)
+		s.True(linked())
+	})
 
-		decision := &decision.Decision{
-			Action:          "partial_close",
-			Symbol:          "BTCUSDT",
-			ClosePercentage: 50.0,
+	s.Run("未开启时设置独立保护单", func() {
+		setup(false)
+		s.autoTrader.placeProtectiveOrders("ETHUSDT", "LONG", 0.5, 3000, 3500)
+
+		s.Zero(s.mockTrader.ocoCalls)
+		s.True(s.mockTrader.SetStopLossCalled)
+		s.True(s.mockTrader.SetTakeProfitCalled)
+		s.False(linked())
+	})
+}
+
+// TestAIAnomalyDetection 测试AI输出异常检测：连续重复相同决策或有候选却一直不开仓达到阈值后标记异常
+func (s *AutoTraderTestSuite) TestAIAnomalyDetection() {
+	defer s.autoTrader.SetAIAnomalyConfig(0, 0, false)
+	openLong := []decision.Decision{{Symbol: "BTCUSDT", Action: "open_long"}, {Symbol: "ETHUSDT", Action: "hold"}}
+	wait := []decision.Decision{{Symbol: "BTCUSDT", Action: "wait"}}
+
+	s.Run("重复决策达到阈值后触发并暂停开仓", func() {
+		s.autoTrader.SetAIAnomalyConfig(0, 0, false)
+		s.autoTrader.SetAIAnomalyConfig(0, 3, true)
+		for i := 0; i < 2; i++ {
+			s.autoTrader.detectAIAnomaly(&logger.DecisionRecord{}, openLong, 5)
+			s.Nil(s.autoTrader.aiAnomalyStatus(), "第 %d 个周期不应触发", i+1)
 		}
+		record := &logger.DecisionRecord{}
+		s.autoTrader.detectAIAnomaly(record, openLong, 5)
+
+		status := s.autoTrader.aiAnomalyStatus()
+		s.Require().NotNil(status)
+		s.Equal(AIAnomalyRepeat, status["kind"])
+		s.Equal(3, status["cycles"])
+		s.NotEmpty(record.ExecutionLog)
+		s.True(s.autoTrader.aiAnomalyBlocksOpen("open_long"))
+		s.False(s.autoTrader.aiAnomalyBlocksOpen("close_long"), "平仓不受影响")
+
+		// 决策变化后解除
+		s.autoTrader.detectAIAnomaly(&logger.DecisionRecord{}, wait, 5)
+		s.Nil(s.autoTrader.aiAnomalyStatus())
+		s.False(s.autoTrader.aiAnomalyBlocksOpen("open_long"))
+	})
 
-		actionRecord := &logger.DecisionAction{
-			Action: "partial_close",
-			Symbol: "BTCUSDT",
+	s.Run("有候选却持续不开仓", func() {
+		s.autoTrader.SetAIAnomalyConfig(0, 0, false)
+		s.autoTrader.SetAIAnomalyConfig(4, 0, false)
+		// 没有候选币种的周期不计入
+		s.autoTrader.detectAIAnomaly(&logger.DecisionRecord{}, wait, 0)
+		for i := 0; i < 3; i++ {
+			s.autoTrader.detectAIAnomaly(&logger.DecisionRecord{}, wait, 5)
 		}
+		s.Nil(s.autoTrader.aiAnomalyStatus())
 
-		err := s.autoTrader.executePartialCloseWithRecord(decision, actionRecord)
+		s.autoTrader.detectAIAnomaly(&logger.DecisionRecord{}, wait, 5)
+		status := s.autoTrader.aiAnomalyStatus()
+		s.Require().NotNil(status)
+		s.Equal(AIAnomalyIdle, status["kind"])
+		s.False(s.autoTrader.aiAnomalyBlocksOpen("open_long"), "未开启暂停时只通知")
+	})
 
-		s.NoError(err)
-		s.Equal(0.05, actionRecord.Quantity) // 50% of 0.1
+	s.Run("阈值校验", func() {
+		s.NoError(ValidateAIAnomalyConfig(0, 0))
+		s.NoError(ValidateAIAnomalyConfig(10, 3))
+		s.Error(ValidateAIAnomalyConfig(-1, 0))
+		s.Error(ValidateAIAnomalyConfig(0, 1))
+		s.Error(ValidateAIAnomalyConfig(0, maxAIAnomalyCycles+1))
 	})
+}
 
-	s.Run("无效的平仓百分比", func() {
-		decision := &decision.Decision{
-			Action:          "partial_close",
-			Symbol:          "BTCUSDT",
-			ClosePercentage: 150.0, // 无效
-		}
+// TestProtectiveDefaults 测试默认止损/止盈：开仓决策缺少保护价位时按币种配置（或 default）从开仓价推导
+func (s *AutoTraderTestSuite) TestProtectiveDefaults() {
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 50000.0}, nil
+	})
+	s.autoTrader.SetProtectiveDefaults(NormalizeProtectiveDefaults(map[string]ProtectiveDefault{
+		"default": {StopLossPct: 3, TakeProfitPct: 6},
+		"btc":     {StopLossPct: 2, TakeProfitPct: 4},
+	}))
+	defer s.autoTrader.SetProtectiveDefaults(nil)
+
+	s.Run("开多未提供止损止盈时按币种配置推导", func() {
+		s.mockTrader = new(MockTrader)
+		s.autoTrader.trader = s.mockTrader
+		d := &decision.Decision{Action: "open_long", Symbol: "BTCUSDT", PositionSizeUSD: 1000.0, Leverage: 5}
+		actionRecord := &logger.DecisionAction{Action: "open_long", Symbol: "BTCUSDT"}
+		s.NoError(s.autoTrader.executeOpenLongWithRecord(d, actionRecord))
+
+		s.InDelta(49000.0, d.StopLoss, 1e-6)
+		s.InDelta(52000.0, d.TakeProfit, 1e-6)
+		s.True(s.mockTrader.SetStopLossCalled)
+		s.True(s.mockTrader.SetTakeProfitCalled)
+		s.InDelta(49000.0, s.mockTrader.LastSLPrice, 1e-6)
+		s.InDelta(52000.0, s.mockTrader.LastTPPrice, 1e-6)
+		s.Contains(actionRecord.Note, "按默认")
+	})
 
-		actionRecord := &logger.DecisionAction{}
+	s.Run("开空未配置币种使用default，已给出的止损保持不变", func() {
+		s.mockTrader = new(MockTrader)
+		s.autoTrader.trader = s.mockTrader
+		d := &decision.Decision{Action: "open_short", Symbol: "ETHUSDT", PositionSizeUSD: 1000.0, Leverage: 5, StopLoss: 50800}
+		s.NoError(s.autoTrader.executeOpenShortWithRecord(d, &logger.DecisionAction{Action: "open_short", Symbol: "ETHUSDT"}))
 
-		err := s.autoTrader.executePartialCloseWithRecord(decision, actionRecord)
+		s.InDelta(50800.0, d.StopLoss, 1e-6)
+		s.InDelta(47000.0, d.TakeProfit, 1e-6)
+	})
 
-		s.Error(err)
-		s.Contains(err.Error(), "平仓百分比必须在 0-100 之间")
+	s.Run("配置校验", func() {
+		s.NoError(ValidateProtectiveDefaults(map[string]ProtectiveDefault{"default": {StopLossPct: 3, TakeProfitPct: 6}}))
+		s.Error(ValidateProtectiveDefaults(map[string]ProtectiveDefault{"default": {StopLossPct: 100}}))
+		s.Error(ValidateProtectiveDefaults(map[string]ProtectiveDefault{"BTCUSDT": {TakeProfitPct: -1}}))
 	})
 }
 
-// ============================================================
-// 层次 10: executeDecisionWithRecord 路由测试
-// ============================================================
+// TestOrphanPositions 测试孤儿持仓：策略已移除的持仓被列为孤儿并可一键平仓，仍有活跃策略的持仓不受影响
+func (s *AutoTraderTestSuite) TestOrphanPositions() {
+	hadPosition := true
+	s.mockDB.strategyStatuses = map[string]*config.TraderStrategyStatus{
+		"sig-btc": {StrategyID: "sig-btc", Symbol: "BTCUSDT", Status: "ENTERED", HadPosition: &hadPosition},
+		"sig-eth": {StrategyID: "sig-eth", Symbol: "ETHUSDT", Status: "ENTERED", HadPosition: &hadPosition},
+	}
+	s.mockTrader.positions = []map[string]interface{}{
+		{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.1, "entryPrice": 50000.0},
+		{"symbol": "ETHUSDT", "side": "long", "positionAmt": 1.0, "entryPrice": 3000.0},
+	}
 
-func (s *AutoTraderTestSuite) TestExecuteDecisionWithRecord() {
-	// Mock market.Get
+	s.Run("信号模式：策略已移除的持仓列为孤儿", func() {
+		previous := signal.GlobalManager
+		signal.GlobalManager = &signal.StrategyManager{}
+		defer func() { signal.GlobalManager = previous }()
+		// BTCUSDT 的策略已从活跃策略池移除，只剩 ETHUSDT 的策略
+		signal.GlobalManager.UpdateStrategy(&signal.SignalDecision{SignalID: "sig-eth", Symbol: "ETHUSDT", Direction: "LONG"}, time.Now())
+
+		orphans, err := s.autoTrader.FindOrphanPositions()
+		s.Require().NoError(err)
+		s.Require().Len(orphans, 1)
+		s.Equal("BTCUSDT", orphans[0].Symbol)
+		s.Equal("long", orphans[0].Side)
+		s.Equal(OrphanReasonNoActiveStrategy, orphans[0].Reason)
+		s.Equal("sig-btc", orphans[0].StrategyID)
+
+		results, err := s.autoTrader.CloseOrphanPositions()
+		s.Require().NoError(err)
+		s.Require().Len(results, 1)
+		s.True(results[0].Success)
+		s.Equal([]string{"BTCUSDT_long"}, s.mockTrader.closedPositions)
+		s.True(s.autoTrader.isStrategyClosed("sig-btc"))
+		s.False(s.autoTrader.isStrategyClosed("sig-eth"))
+	})
+
+	s.Run("自主模式：长时间没有决策涉及的持仓列为孤儿", func() {
+		s.autoTrader.startTime = time.Now().Add(-2 * time.Hour)
+		s.autoTrader.recordSymbolDecisions([]decision.Decision{{Symbol: "ETHUSDT", Action: "hold"}})
+
+		orphans, err := s.autoTrader.FindOrphanPositions()
+		s.Require().NoError(err)
+		s.Require().Len(orphans, 1)
+		s.Equal("BTCUSDT", orphans[0].Symbol)
+		s.Equal(OrphanReasonNoRecentDecision, orphans[0].Reason)
+		s.Nil(orphans[0].LastDecisionAt)
+
+		// 刚启动时不判定
+		s.autoTrader.startTime = time.Now()
+		orphans, err = s.autoTrader.FindOrphanPositions()
+		s.Require().NoError(err)
+		s.Empty(orphans)
+	})
+
+	s.Run("对账间隔", func() {
+		s.Equal(30*time.Minute, s.autoTrader.positionAuditInterval())
+		s.autoTrader.SetPositionAuditInterval(10)
+		s.Equal(10*time.Minute, s.autoTrader.positionAuditInterval())
+		s.NoError(ValidatePositionAuditInterval(0))
+		s.Error(ValidatePositionAuditInterval(1))
+	})
+}
+
+// TestMarginDeficit 测试保证金不足：可用余额为负时进入 margin_deficit，拦截开仓、放行平仓，余额恢复后解除
+func (s *AutoTraderTestSuite) TestMarginDeficit() {
 	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
-		return &market.Data{
-			Symbol:       symbol,
-			CurrentPrice: 50000.0,
-		}, nil
+		return &market.Data{Symbol: symbol, CurrentPrice: 50000.0}, nil
+	})
+	s.mockTrader.positions = []map[string]interface{}{
+		{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.1, "entryPrice": 52000.0, "markPrice": 50000.0, "leverage": 10.0},
+	}
+	s.mockTrader.balance["availableBalance"] = -120.0
+
+	s.Run("可用余额为负时拦截开仓", func() {
+		s.True(s.autoTrader.updateMarginDeficit(s.mockTrader.balance))
+		status := s.autoTrader.marginDeficitStatus()
+		s.Equal(true, status["active"])
+		s.Equal(-120.0, status["available_balance"])
+
+		actionRecord := &logger.DecisionAction{Action: "open_short", Symbol: "ETHUSDT"}
+		err := s.autoTrader.executeDecisionWithRecord(&decision.Decision{Action: "open_short", Symbol: "ETHUSDT", PositionSizeUSD: 100, Leverage: 5}, actionRecord)
+		s.Require().Error(err)
+		s.Contains(err.Error(), "margin_deficit")
+		s.Equal("margin_deficit", actionRecord.Status)
+		s.Error(s.autoTrader.dispatchDecisionWithRecord(&decision.Decision{Action: "flip_short", Symbol: "BTCUSDT"}, &logger.DecisionAction{}))
 	})
 
-	s.Run("路由到open_long", func() {
-		decision := &decision.Decision{
-			Action:          "open_long",
-			Symbol:          "BTCUSDT",
-			PositionSizeUSD: 1000.0,
-			Leverage:        10,
-		}
-		actionRecord := &logger.DecisionAction{}
+	s.Run("平仓不受影响且不受最短持仓时间限制", func() {
+		s.autoTrader.SetMinHoldingMinutes(30)
+		defer s.autoTrader.SetMinHoldingMinutes(0)
+		s.autoTrader.setPositionFirstSeen("BTCUSDT_long", time.Now().Add(-time.Minute).UnixMilli())
+		defer s.autoTrader.forgetPositionFirstSeen("BTCUSDT_long")
 
-		err := s.autoTrader.executeDecisionWithRecord(decision, actionRecord)
+		s.NoError(s.autoTrader.dispatchDecisionWithRecord(&decision.Decision{Action: "close_long", Symbol: "BTCUSDT"}, &logger.DecisionAction{}))
+		s.Equal([]string{"BTCUSDT_long"}, s.mockTrader.closedPositions)
+	})
+
+	s.Run("余额恢复后解除，低于配置下限时同样进入", func() {
+		s.mockTrader.balance["availableBalance"] = 800.0
+		s.False(s.autoTrader.updateMarginDeficit(s.mockTrader.balance))
+		s.False(s.autoTrader.marginDeficitBlocksOpen("open_long"))
+
+		s.autoTrader.SetMinAvailableBalance(1000)
+		s.True(s.autoTrader.updateMarginDeficit(s.mockTrader.balance))
+		s.True(s.autoTrader.marginDeficitBlocksOpen("open_long"))
+		s.False(s.autoTrader.marginDeficitBlocksOpen("close_long"))
+	})
+}
+
+// TestTradingSchedule 测试交易时段：时段外跳过决策周期、禁止开仓，持仓保护照常执行
+func (s *AutoTraderTestSuite) TestTradingSchedule() {
+	defer s.autoTrader.SetTradingSchedule(nil)
+	defer s.autoTrader.SetAutoReprotect(false)
+
+	s.Run("时段判断", func() {
+		weekdays := []TradingWindow{{Days: []int{1, 2, 3, 4, 5}, Start: "08:00", End: "16:00"}}
+		overnight := []TradingWindow{{Days: []int{5}, Start: "22:00", End: "02:00"}}
+		// 2026-01-05 为周一
+		at := func(day, hour, minute int) time.Time { return time.Date(2026, 1, 5+day, hour, minute, 0, 0, time.UTC) }
+
+		s.True(tradingScheduleActive(nil, at(5, 3, 0)), "未配置时段时全天可交易")
+		s.True(tradingScheduleActive(weekdays, at(0, 10, 0)))
+		s.False(tradingScheduleActive(weekdays, at(0, 16, 0)), "结束时间不包含在内")
+		s.False(tradingScheduleActive(weekdays, at(5, 10, 0)), "周六不在时段内")
+		s.True(tradingScheduleActive(overnight, at(4, 23, 0)), "周五夜间")
+		s.True(tradingScheduleActive(overnight, at(5, 1, 0)), "跨零点按开始日期判断")
+		s.False(tradingScheduleActive(overnight, at(6, 1, 0)))
+		s.False(tradingScheduleActive(overnight, at(4, 1, 0)), "周四开始的时段不允许")
+	})
+
+	s.Run("配置校验", func() {
+		s.NoError(ValidateTradingSchedule([]TradingWindow{{Start: "00:00", End: "24:00"}}))
+		s.Error(ValidateTradingSchedule([]TradingWindow{{Start: "25:00", End: "02:00"}}))
+		s.Error(ValidateTradingSchedule([]TradingWindow{{Start: "08:00", End: "08:00"}}))
+		s.Error(ValidateTradingSchedule([]TradingWindow{{Days: []int{7}, Start: "08:00", End: "09:00"}}))
+
+		windows, err := ParseTradingSchedule(FormatTradingSchedule([]TradingWindow{{Days: []int{1}, Start: "08:00", End: "16:00"}}))
+		s.NoError(err)
+		s.Len(windows, 1)
+		windows, err = ParseTradingSchedule("")
 		s.NoError(err)
+		s.Empty(windows)
 	})
 
-	s.Run("路由到close_long", func() {
-		decision := &decision.Decision{
-			Action: "close_long",
-			Symbol: "BTCUSDT",
+	// 当前时间之后2-3小时的时段，保证当前处于时段外
+	now := time.Now().UTC()
+	blocked := []TradingWindow{{Start: now.Add(2 * time.Hour).Format("15:04"), End: now.Add(3 * time.Hour).Format("15:04")}}
+
+	s.Run("时段外跳过周期但仍补设保护单", func() {
+		s.mockTrader = new(MockTrader)
+		s.autoTrader.trader = s.mockTrader
+		s.autoTrader.SetTradingSchedule(blocked)
+		s.autoTrader.SetAutoReprotect(true)
+		s.autoTrader.resetPyramidState("ETHUSDT_long", 3000.0)
+		s.mockTrader.positions = []map[string]interface{}{
+			{"symbol": "ETHUSDT", "side": "long", "positionAmt": 0.5, "entryPrice": 3200.0},
 		}
-		actionRecord := &logger.DecisionAction{}
 
-		err := s.autoTrader.executeDecisionWithRecord(decision, actionRecord)
+		record, err := s.autoTrader.runCycleWithRecord()
 		s.NoError(err)
+		s.Contains(record.ExecutionLog, "🌙 非交易时段，跳过AI决策")
+		s.True(s.mockTrader.SetStopLossCalled, "时段外仍应补设缺失的止损单")
+		s.Require().Len(record.Decisions, 1)
+		s.Equal("reprotect_stop_loss", record.Decisions[0].Action)
+		s.Equal(false, s.autoTrader.GetStatus()["trading_schedule"].(map[string]interface{})["active"])
 	})
 
-	s.Run("路由到hold_不执行", func() {
-		decision := &decision.Decision{
-			Action: "hold",
-			Symbol: "BTCUSDT",
-		}
-		actionRecord := &logger.DecisionAction{}
+	s.Run("时段外禁止开仓", func() {
+		s.mockTrader = new(MockTrader)
+		s.autoTrader.trader = s.mockTrader
+		s.autoTrader.SetTradingSchedule(blocked)
 
-		err := s.autoTrader.executeDecisionWithRecord(decision, actionRecord)
+		d := &decision.Decision{Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 100}
+		err := s.autoTrader.executeDecisionWithRecord(d, &logger.DecisionAction{})
+		s.Error(err)
+		s.Contains(err.Error(), "不在交易时段")
+		s.Equal(0.0, s.mockTrader.lastOpenLongQty)
+	})
+}
+
+// TestMaintenanceMode 测试平台维护模式：拦截开仓/加仓和信号开仓，平仓与持仓保护照常执行
+func (s *AutoTraderTestSuite) TestMaintenanceMode() {
+	SetMaintenanceMode(true)
+	defer SetMaintenanceMode(false)
+	defer s.autoTrader.SetAutoReprotect(false)
+
+	s.mockTrader = new(MockTrader)
+	s.autoTrader.trader = s.mockTrader
+
+	s.Run("维护中禁止开仓", func() {
+		d := &decision.Decision{Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 100}
+		err := s.autoTrader.executeDecisionWithRecord(d, &logger.DecisionAction{})
+		s.Error(err)
+		s.Contains(err.Error(), "平台维护中")
+		s.Equal(0.0, s.mockTrader.lastOpenLongQty)
+	})
+
+	s.Run("维护中跳过信号开仓", func() {
+		strat := &signal.SignalDecision{Symbol: "BTCUSDT", Direction: "LONG"}
+		s.autoTrader.executeSignalTrade(strat, "ENTRY", 0.2, 50000.0)
+		s.Equal(0.0, s.mockTrader.lastOpenLongQty)
+	})
+
+	s.Run("维护中平仓照常执行", func() {
+		s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+			return &market.Data{Symbol: symbol, CurrentPrice: 51000.0}, nil
+		})
+		d := &decision.Decision{Symbol: "BTCUSDT", Action: "close_long"}
+		err := s.autoTrader.executeDecisionWithRecord(d, &logger.DecisionAction{Action: "close_long", Symbol: "BTCUSDT"})
 		s.NoError(err)
+		s.Equal([]string{"BTCUSDT_long"}, s.mockTrader.closedPositions)
 	})
 
-	s.Run("未知action返回错误", func() {
-		decision := &decision.Decision{
-			Action: "unknown_action",
-			Symbol: "BTCUSDT",
+	s.Run("维护中仍补设保护单", func() {
+		s.autoTrader.SetAutoReprotect(true)
+		s.autoTrader.resetPyramidState("ETHUSDT_long", 3000.0)
+		s.mockTrader.positions = []map[string]interface{}{
+			{"symbol": "ETHUSDT", "side": "long", "positionAmt": 0.5, "entryPrice": 3200.0},
 		}
-		actionRecord := &logger.DecisionAction{}
 
-		err := s.autoTrader.executeDecisionWithRecord(decision, actionRecord)
-		s.Error(err)
-		s.Contains(err.Error(), "未知的action")
+		record := &logger.DecisionRecord{}
+		s.autoTrader.reprotectPositions(record)
+		s.True(s.mockTrader.SetStopLossCalled)
+		s.Require().Len(record.Decisions, 1)
+		s.Equal("reprotect_stop_loss", record.Decisions[0].Action)
 	})
 }
 
-func (s *AutoTraderTestSuite) TestCheckPositionDrawdown() {
-	tests := []struct {
-		name             string
-		setupPositions   func()
-		setupPeakPnL     func()
-		setupFailures    func()
-		cleanupFailures  func()
-		expectedCacheKey string
-		shouldClearCache bool
-		skipCacheCheck   bool
-	}{
-		{
-			name:            "获取持仓失败_不panic",
-			setupFailures:   func() { s.mockTrader.shouldFailPositions = true },
-			cleanupFailures: func() { s.mockTrader.shouldFailPositions = false },
-			skipCacheCheck:  true,
-		},
-		{
-			name:           "无持仓_不panic",
-			setupPositions: func() { s.mockTrader.positions = []map[string]interface{}{} },
-			skipCacheCheck: true,
-		},
-		{
-			name: "收益不足5%_不触发平仓",
-			setupPositions: func() {
-				s.mockTrader.positions = []map[string]interface{}{
-					{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.1, "entryPrice": 50000.0, "markPrice": 50150.0, "leverage": 10.0},
-				}
-			},
-			setupPeakPnL:   func() { s.autoTrader.ClearPeakPnLCache("BTCUSDT", "long") },
-			skipCacheCheck: true,
-		},
-		{
-			name: "回撤不足40%_不触发平仓",
-			setupPositions: func() {
-				s.mockTrader.positions = []map[string]interface{}{
-					{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.1, "entryPrice": 50000.0, "markPrice": 50400.0, "leverage": 10.0},
-				}
-			},
-			setupPeakPnL:   func() { s.autoTrader.UpdatePeakPnL("BTCUSDT", "long", 10.0) },
-			skipCacheCheck: true,
-		},
-		{
-			name: "多头_触发回撤平仓",
-			setupPositions: func() {
-				s.mockTrader.positions = []map[string]interface{}{
-					{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.1, "entryPrice": 50000.0, "markPrice": 50300.0, "leverage": 10.0},
-				}
-			},
-			setupPeakPnL:     func() { s.autoTrader.UpdatePeakPnL("BTCUSDT", "long", 10.0) },
-			expectedCacheKey: "BTCUSDT_long",
-			shouldClearCache: true,
-		},
-		{
-			name: "空头_触发回撤平仓",
-			setupPositions: func() {
-				s.mockTrader.positions = []map[string]interface{}{
-					{"symbol": "ETHUSDT", "side": "short", "positionAmt": -0.5, "entryPrice": 3000.0, "markPrice": 2982.0, "leverage": 10.0},
-				}
-			},
-			setupPeakPnL:     func() { s.autoTrader.UpdatePeakPnL("ETHUSDT", "short", 10.0) },
-			expectedCacheKey: "ETHUSDT_short",
-			shouldClearCache: true,
-		},
-		{
-			name: "多头_平仓失败_保留缓存",
-			setupPositions: func() {
-				s.mockTrader.positions = []map[string]interface{}{
-					{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.1, "entryPrice": 50000.0, "markPrice": 50300.0, "leverage": 10.0},
-				}
-			},
-			setupPeakPnL:     func() { s.autoTrader.UpdatePeakPnL("BTCUSDT", "long", 10.0) },
-			setupFailures:    func() { s.mockTrader.shouldFailCloseLong = true },
-			cleanupFailures:  func() { s.mockTrader.shouldFailCloseLong = false },
-			expectedCacheKey: "BTCUSDT_long",
-			shouldClearCache: false,
-		},
-		{
-			name: "空头_平仓失败_保留缓存",
-			setupPositions: func() {
-				s.mockTrader.positions = []map[string]interface{}{
-					{"symbol": "ETHUSDT", "side": "short", "positionAmt": -0.5, "entryPrice": 3000.0, "markPrice": 2982.0, "leverage": 10.0},
-				}
-			},
-			setupPeakPnL:     func() { s.autoTrader.UpdatePeakPnL("ETHUSDT", "short", 10.0) },
-			setupFailures:    func() { s.mockTrader.shouldFailCloseShort = true },
-			cleanupFailures:  func() { s.mockTrader.shouldFailCloseShort = false },
-			expectedCacheKey: "ETHUSDT_short",
-			shouldClearCache: false,
-		},
+// TestOrderBookDepth 测试盘口深度摘要计算与缓存
+func (s *AutoTraderTestSuite) TestOrderBookDepth() {
+	book := &OrderBook{
+		Bids: []OrderBookLevel{{Price: 99.9, Quantity: 10}, {Price: 99.6, Quantity: 20}, {Price: 99.0, Quantity: 100}},
+		Asks: []OrderBookLevel{{Price: 100.1, Quantity: 5}, {Price: 100.4, Quantity: 10}, {Price: 101.0, Quantity: 100}},
 	}
 
-	for _, tt := range tests {
-		s.Run(tt.name, func() {
-			if tt.setupPositions != nil {
-				tt.setupPositions()
-			}
-			if tt.setupPeakPnL != nil {
-				tt.setupPeakPnL()
-			}
-			if tt.setupFailures != nil {
-				tt.setupFailures()
-			}
-			if tt.cleanupFailures != nil {
-				defer tt.cleanupFailures()
-			}
+	s.Run("摘要计算", func() {
+		depth := summarizeOrderBook(book, 50)
+		s.Require().NotNil(depth)
+		s.Equal(99.9, depth.BestBid)
+		s.Equal(100.1, depth.BestAsk)
+		s.InDelta(20.0, depth.SpreadBps, 0.01)
+		// 中间价100，±50bps 即 [99.5, 100.5]
+		s.InDelta(99.9*10+99.6*20, depth.BidDepthUSD, 1e-6)
+		s.InDelta(100.1*5+100.4*10, depth.AskDepthUSD, 1e-6)
+
+		s.True(summarizeOrderBook(nil, 50) == nil, "交易所不支持时不输出")
+		s.True(summarizeOrderBook(&OrderBook{Bids: book.Bids}, 50) == nil, "缺少卖盘时不输出")
+	})
 
-			s.autoTrader.checkPositionDrawdown()
+	s.Run("未开启时不获取", func() {
+		s.autoTrader.SetIncludeOrderBookDepth(false)
+		s.True(s.autoTrader.orderBookFetcher() == nil)
+	})
 
-			if !tt.skipCacheCheck {
-				cache := s.autoTrader.GetPeakPnLCache()
-				_, exists := cache[tt.expectedCacheKey]
-				if tt.shouldClearCache {
-					s.False(exists, "峰值缓存应该被清理")
-				} else {
-					s.True(exists, "峰值缓存不应该被清理")
-				}
-			}
+	s.Run("开启后按需获取并缓存", func() {
+		s.mockTrader = &MockTrader{orderBook: book}
+		s.autoTrader.trader = s.mockTrader
+		s.autoTrader.orderBookCache = nil
+		s.autoTrader.SetIncludeOrderBookDepth(true)
+		defer s.autoTrader.SetIncludeOrderBookDepth(false)
+
+		fetch := s.autoTrader.orderBookFetcher()
+		s.Require().NotNil(fetch)
+		s.Equal(99.9, fetch("BTCUSDT").BestBid)
+		fetch("BTCUSDT")
+		s.Equal(1, s.mockTrader.orderBookCalls, "缓存期内不重复请求")
+	})
+}
 
-			// 清理状态
-			s.mockTrader.positions = []map[string]interface{}{}
-		})
-	}
+// TestContextIndicators 测试多周期指标上下文配置
+func (s *AutoTraderTestSuite) TestContextIndicators() {
+	var fetched []string
+	s.patches.ApplyFunc(market.GetKlinesCached, func(symbol, interval string) ([]market.Kline, error) {
+		fetched = append(fetched, symbol+"@"+interval)
+		klines := make([]market.Kline, 60)
+		for i := range klines {
+			price := 100 + float64(i)
+			klines[i] = market.Kline{Open: price - 0.5, High: price + 1, Low: price - 1, Close: price}
+		}
+		return klines, nil
+	})
+
+	s.Run("配置校验", func() {
+		s.Equal([]string{"rsi", "ema_cross"}, ParseIndicatorList(" RSI, ,ema_cross "))
+		s.NoError(ValidateContextIndicators([]string{"rsi", "atr"}, []string{"1h", "4h"}))
+		s.Error(ValidateContextIndicators([]string{"bollinger"}, []string{"1h"}))
+		s.Error(ValidateContextIndicators([]string{"rsi"}, []string{"2h"}))
+		s.Error(ValidateContextIndicators([]string{"rsi"}, []string{"5m", "15m", "1h", "4h"}), "周期数量受限")
+	})
+
+	s.Run("未配置时不获取", func() {
+		s.autoTrader.SetContextIndicators(nil, []string{"1h"})
+		s.True(s.autoTrader.indicatorFetcher() == nil)
+	})
+
+	s.Run("只包含选择的指标和周期", func() {
+		s.autoTrader.SetContextIndicators([]string{"rsi", "ema_cross"}, []string{"1h", "4h"})
+		defer s.autoTrader.SetContextIndicators(nil, nil)
+
+		fetch := s.autoTrader.indicatorFetcher()
+		s.Require().NotNil(fetch)
+		result := fetch("ETHUSDT")
+		s.Equal([]string{"ETHUSDT@1h", "ETHUSDT@4h"}, fetched)
+		s.Require().Len(result, 2)
+		for _, tf := range result {
+			s.Require().NotNil(tf.RSI14)
+			s.Equal("above", tf.EMACross)
+			s.Nil(tf.MACD, "未选择的指标不输出")
+			s.Nil(tf.ATR14, "未选择的指标不输出")
+		}
+		s.Equal("4h", result[1].Timeframe)
+	})
 }
 
-// ============================================================
-// Mock 实现
-// ============================================================
+// TestPublicProfile 测试公开展示名称与可见性
+func (s *AutoTraderTestSuite) TestPublicProfile() {
+	defer s.autoTrader.SetPublicProfile("", false)
 
-// MockDatabase 模拟数据库
-type MockDatabase struct {
-	shouldFail bool
+	s.autoTrader.SetPublicProfile("", true)
+	s.True(s.autoTrader.IsPublic())
+	s.Equal(s.autoTrader.GetName(), s.autoTrader.PublicName(), "未设置展示名称时使用交易员名称")
+
+	s.autoTrader.SetPublicProfile("Trader-ABC123", false)
+	s.False(s.autoTrader.IsPublic())
+	s.Equal("Trader-ABC123", s.autoTrader.PublicName())
 }
 
-func (m *MockDatabase) UpdateTraderInitialBalance(userID, traderID string, newBalance float64) error {
-	if m.shouldFail {
-		return errors.New("database error")
+// TestReduceAllPositions 测试所有持仓按比例整体减仓
+func (s *AutoTraderTestSuite) TestReduceAllPositions() {
+	setup := func() {
+		s.mockTrader = new(MockTrader)
+		s.autoTrader.trader = s.mockTrader
+		s.mockTrader.positions = []map[string]interface{}{
+			{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.4, "entryPrice": 50000.0},
+			{"symbol": "ETHUSDT", "side": "short", "positionAmt": -2.0, "entryPrice": 3000.0},
+		}
+		s.autoTrader.prunePyramidState(map[string]bool{})
+		s.autoTrader.resetPyramidState("BTCUSDT_long", 45000.0)
+		s.autoTrader.updatePyramidTakeProfit("BTCUSDT_long", 55000.0)
 	}
-	return nil
+
+	s.Run("按比例减仓并按剩余数量重设止盈止损", func() {
+		setup()
+		results, err := s.autoTrader.ReduceAllPositions(50)
+		s.Require().NoError(err)
+		s.Require().Len(results, 2)
+
+		s.Equal([]string{"BTCUSDT_long"}, s.mockTrader.closedPositions)
+		s.InDelta(0.2, s.mockTrader.closedQuantities[0], 1e-9)
+
+		btc := results[0]
+		s.True(btc.Success)
+		s.True(btc.Reprotected)
+		s.InDelta(0.2, btc.RemainingQuantity, 1e-9)
+		s.Require().Len(s.mockTrader.stopLossQuantity, 1)
+		s.InDelta(0.2, s.mockTrader.stopLossQuantity[0], 1e-9)
+		s.Equal(45000.0, s.mockTrader.LastSLPrice)
+		s.Equal(55000.0, s.mockTrader.LastTPPrice)
+
+		// 交易所平仓会撤销所有挂单且不知道止盈止损价位：拒绝部分减仓
+		eth := results[1]
+		s.False(eth.Success)
+		s.NotEmpty(eth.Error)
+		s.InDelta(2.0, eth.RemainingQuantity, 1e-9)
+	})
+
+	s.Run("支持只减仓时未知价位的持仓照常减仓", func() {
+		setup()
+		s.autoTrader.trader = &partialCloseMockTrader{MockTrader: s.mockTrader}
+		defer func() { s.autoTrader.trader = s.mockTrader }()
+		results, err := s.autoTrader.ReduceAllPositions(50)
+		s.Require().NoError(err)
+		s.Require().Len(results, 2)
+
+		eth := results[1]
+		s.True(eth.Success)
+		s.False(eth.Reprotected)
+		s.NotEmpty(eth.Note)
+		s.Equal([]string{"BTCUSDT_long", "ETHUSDT_short"}, s.mockTrader.closedPositions)
+		s.InDelta(1.0, s.mockTrader.closedQuantities[1], 1e-9)
+	})
+
+	s.Run("比例超过100按全部平仓处理", func() {
+		setup()
+		results, err := s.autoTrader.ReduceAllPositions(150)
+		s.Require().NoError(err)
+		s.Require().Len(results, 2)
+		s.InDelta(0.4, s.mockTrader.closedQuantities[0], 1e-9)
+		s.InDelta(0.0, results[0].RemainingQuantity, 1e-9)
+		s.False(s.mockTrader.SetStopLossCalled, "全部平仓后不应重设止损")
+	})
+
+	s.Run("比例为0时拒绝", func() {
+		setup()
+		_, err := s.autoTrader.ReduceAllPositions(0)
+		s.Error(err)
+		s.Empty(s.mockTrader.closedPositions)
+	})
 }
 
-// MockTrader 增强版（添加错误控制）
-type MockTrader struct {
-	balance              map[string]interface{}
-	positions            []map[string]interface{}
-	openOrders           []map[string]interface{} // 用于 GetOpenOrders 返回
-	shouldFailBalance    bool
-	shouldFailPositions  bool
-	shouldFailOpenLong   bool
-	shouldFailCloseLong  bool
-	shouldFailCloseShort bool
+// TestMarketConditionGate 测试极端行情过滤
+func (s *AutoTraderTestSuite) TestMarketConditionGate() {
+	defer s.autoTrader.SetMarketConditionGate(0, 0)
+	btc := &market.Data{Symbol: "BTCUSDT", CurrentPrice: 50000.0, PriceChange1h: -6.5, FundingRate: 0.0001}
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return btc, nil
+	})
 
-	// 调用跟踪
-	SetStopLossCalled   bool
-	SetTakeProfitCalled bool
-	LastSLPrice         float64
-	LastTPPrice         float64
+	s.Run("未启用时不检查", func() {
+		s.autoTrader.SetMarketConditionGate(0, 0)
+		s.Empty(s.autoTrader.marketConditionSkipReason())
+	})
+
+	s.Run("阈值判断", func() {
+		s.autoTrader.SetMarketConditionGate(10, 0)
+		s.Empty(s.autoTrader.marketConditionSkipReason(), "涨跌幅未超过阈值")
+		s.autoTrader.SetMarketConditionGate(0, 0.005)
+		s.Contains(s.autoTrader.marketConditionSkipReason(), "资金费率")
+		s.autoTrader.SetMarketConditionGate(0, 0.05)
+		s.Empty(s.autoTrader.marketConditionSkipReason(), "资金费率 0.01% 未超过阈值")
+	})
+
+	s.Run("BTC大幅波动时跳过周期但仍补设保护单", func() {
+		s.mockTrader = new(MockTrader)
+		s.autoTrader.trader = s.mockTrader
+		s.autoTrader.SetMarketConditionGate(5, 0)
+		s.autoTrader.SetAutoReprotect(true)
+		defer s.autoTrader.SetAutoReprotect(false)
+		s.autoTrader.resetPyramidState("ETHUSDT_long", 3000.0)
+		s.mockTrader.positions = []map[string]interface{}{
+			{"symbol": "ETHUSDT", "side": "long", "positionAmt": 0.5, "entryPrice": 3200.0},
+		}
+
+		record, err := s.autoTrader.runCycleWithRecord()
+		s.NoError(err)
+		s.Require().NotEmpty(record.ExecutionLog)
+		s.Contains(record.ExecutionLog[0], "极端行情，跳过AI决策")
+		s.Contains(record.ExecutionLog[0], "-6.50%")
+		s.True(s.mockTrader.SetStopLossCalled, "跳过周期时仍应补设缺失的止损单")
+		s.Require().Len(record.Decisions, 1)
+		s.Equal("reprotect_stop_loss", record.Decisions[0].Action)
+		s.Equal(0.0, s.mockTrader.lastOpenLongQty)
+	})
 }
 
-func (m *MockTrader) GetBalance() (map[string]interface{}, error) {
-	if m.shouldFailBalance {
-		return nil, errors.New("failed to get balance")
-	}
-	if m.balance == nil {
-		return map[string]interface{}{
-			"totalWalletBalance":    10000.0,
-			"availableBalance":      8000.0,
-			"totalUnrealizedProfit": 100.0,
-		}, nil
+// TestReprotectPosition 测试手动重设单个持仓的保护单
+func (s *AutoTraderTestSuite) TestReprotectPosition() {
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 3000.0}, nil
+	})
+	setup := func() {
+		s.mockTrader = new(MockTrader)
+		s.autoTrader.trader = s.mockTrader
+		s.mockTrader.positions = []map[string]interface{}{
+			{"symbol": "ETHUSDT", "side": "long", "positionAmt": 0.5, "available": 0.3, "entryPrice": 2900.0},
+		}
+		s.mockTrader.openOrders = []map[string]interface{}{
+			{"order_id": "sl-1", "type": "loss_plan", "price": 2700.0},
+			{"order_id": "limit-1", "type": "limit", "price": 2800.0},
+		}
+		s.autoTrader.prunePyramidState(map[string]bool{})
 	}
-	return m.balance, nil
+
+	s.Run("按指定价位和可平数量重设", func() {
+		setup()
+		result, err := s.autoTrader.ReprotectPosition("eth", 2800.0, 3300.0)
+		s.Require().NoError(err)
+		s.Equal("ETHUSDT", result.Symbol)
+		s.Empty(result.Errors)
+		s.Equal(2800.0, s.mockTrader.LastSLPrice)
+		s.Equal(3300.0, s.mockTrader.LastTPPrice)
+		s.Equal([]float64{0.3}, s.mockTrader.stopLossQuantity, "应使用可平数量")
+		s.Equal([]string{"sl-1"}, result.OrderIDs)
+
+		records, err := s.autoTrader.decisionLogger.GetLatestRecords(1)
+		s.Require().NoError(err)
+		s.Require().Len(records, 1)
+		s.Require().Len(records[0].Decisions, 2)
+		s.Equal("manual_reprotect_stop_loss", records[0].Decisions[0].Action)
+		s.Equal("manual_reprotect_take_profit", records[0].Decisions[1].Action)
+	})
+
+	s.Run("未提供价位时使用最近记录的价位", func() {
+		setup()
+		s.autoTrader.resetPyramidState("ETHUSDT_long", 2750.0)
+		s.autoTrader.updatePyramidTakeProfit("ETHUSDT_long", 3200.0)
+
+		result, err := s.autoTrader.ReprotectPosition("ETHUSDT", 0, 0)
+		s.Require().NoError(err)
+		s.Equal(2750.0, result.StopLoss)
+		s.Equal(3200.0, result.TakeProfit)
+		s.Equal(2750.0, s.mockTrader.LastSLPrice)
+	})
+
+	s.Run("价位方向错误时拒绝且不下单", func() {
+		setup()
+		_, err := s.autoTrader.ReprotectPosition("ETHUSDT", 3100.0, 3300.0)
+		s.Error(err)
+		s.Contains(err.Error(), "多单止损必须低于当前价格")
+		s.False(s.mockTrader.SetStopLossCalled)
+		s.False(s.mockTrader.SetTakeProfitCalled)
+	})
+
+	s.Run("没有持仓时返回 ErrPositionNotFound", func() {
+		setup()
+		_, err := s.autoTrader.ReprotectPosition("BTCUSDT", 40000.0, 0)
+		s.ErrorIs(err, ErrPositionNotFound)
+	})
 }
 
-func (m *MockTrader) GetPositions() ([]map[string]interface{}, error) {
-	if m.shouldFailPositions {
-		return nil, errors.New("failed to get positions")
+// TestMaxOpenOrders 测试限价挂单数量上限
+func (s *AutoTraderTestSuite) TestMaxOpenOrders() {
+	defer s.autoTrader.SetMaxOpenOrders(0)
+	s.mockTrader = new(MockTrader)
+	s.autoTrader.trader = s.mockTrader
+	s.mockTrader.openOrders = []map[string]interface{}{
+		{"order_id": "1", "type": "limit", "side": "open_long", "price": 48000.0},
+		{"order_id": "2", "type": "limit", "side": "open_long", "price": 47000.0},
+		{"order_id": "3", "type": "loss_plan", "price": 45000.0}, // 止损计划单不计入
 	}
-	if m.positions == nil {
-		return []map[string]interface{}{}, nil
+	place := func(price float64) (*logger.DecisionAction, error) {
+		d := &decision.Decision{Action: "place_long_order", Symbol: "BTCUSDT", Price: price, PositionSizeUSD: 1000.0, Leverage: 5}
+		actionRecord := &logger.DecisionAction{Action: d.Action, Symbol: d.Symbol}
+		return actionRecord, s.autoTrader.executeDecisionWithRecord(d, actionRecord)
 	}
-	return m.positions, nil
+
+	s.Run("未达到上限时正常挂单", func() {
+		s.autoTrader.SetMaxOpenOrders(3)
+		_, err := place(46000.0)
+		s.NoError(err)
+		s.Equal(46000.0, s.mockTrader.lastLimitPrice)
+	})
+
+	s.Run("达到上限后跳过挂单", func() {
+		s.mockTrader.lastLimitPrice = 0
+		s.autoTrader.SetMaxOpenOrders(2)
+		actionRecord, err := place(46000.0)
+		s.ErrorIs(err, ErrMaxOpenOrders)
+		s.Equal("max_open_orders", actionRecord.Status)
+		s.Contains(actionRecord.Note, "达到上限 2")
+		s.Equal(0.0, s.mockTrader.lastLimitPrice, "达到上限后不应下单")
+	})
+
+	s.Run("获取挂单失败时不继续挂单", func() {
+		s.mockTrader.lastLimitPrice = 0
+		s.mockTrader.openOrdersErr = errors.New("network error")
+		defer func() { s.mockTrader.openOrdersErr = nil }()
+		s.autoTrader.SetMaxOpenOrders(3)
+		actionRecord, err := place(46000.0)
+		s.ErrorIs(err, ErrMaxOpenOrders)
+		s.Equal("max_open_orders", actionRecord.Status)
+		s.Contains(actionRecord.Note, "获取挂单失败")
+		s.Equal(0.0, s.mockTrader.lastLimitPrice, "无法确认挂单数量时不应下单")
+	})
+
+	s.Run("0表示不限制", func() {
+		s.autoTrader.SetMaxOpenOrders(0)
+		_, err := place(46000.0)
+		s.NoError(err)
+		s.Equal(46000.0, s.mockTrader.lastLimitPrice)
+	})
 }
 
-func (m *MockTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
-	if m.shouldFailOpenLong {
-		return nil, errors.New("failed to open long")
+// TestCancelOppositeOrders 测试开反方向仓位前撤销原方向挂单
+func (s *AutoTraderTestSuite) TestCancelOppositeOrders() {
+	defer SetCancelOppositeOrders(true)
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 50000.0}, nil
+	})
+	openShort := func() *logger.DecisionAction {
+		s.mockTrader = new(MockTrader)
+		s.autoTrader.trader = s.mockTrader
+		s.mockTrader.openOrders = []map[string]interface{}{
+			{"order_id": "L1", "type": "limit", "side": "buy", "trade_side": "open", "pos_side": "long", "price": 49000.0},
+			{"order_id": "S1", "type": "limit", "side": "sell", "trade_side": "open", "pos_side": "short", "price": 52000.0},
+		}
+		d := &decision.Decision{Action: "open_short", Symbol: "BTCUSDT", PositionSizeUSD: 1000.0, Leverage: 5, StopLoss: 52500.0, TakeProfit: 45000.0}
+		actionRecord := &logger.DecisionAction{Action: d.Action, Symbol: d.Symbol}
+		s.Require().NoError(s.autoTrader.executeDecisionWithRecord(d, actionRecord))
+		return actionRecord
 	}
-	return map[string]interface{}{
-		"orderId": int64(123456),
-		"symbol":  symbol,
-	}, nil
+
+	s.Run("开空前先撤销挂着的开多单，保留同方向挂单", func() {
+		SetCancelOppositeOrders(true)
+		actionRecord := openShort()
+		s.Equal([]string{"cancel:L1", "open_short:BTCUSDT"}, s.mockTrader.orderCalls)
+		s.Contains(actionRecord.Note, "撤销原long方向挂单 1 个")
+	})
+
+	s.Run("关闭后保留原方向挂单", func() {
+		SetCancelOppositeOrders(false)
+		openShort()
+		s.Equal([]string{"open_short:BTCUSDT"}, s.mockTrader.orderCalls)
+	})
 }
 
-func (m *MockTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
-	return map[string]interface{}{
-		"orderId": int64(123457),
-		"symbol":  symbol,
-	}, nil
+// memoryBaselineStore 内存中的周期净值基准存储（实现 baselineStore）
+type memoryBaselineStore struct {
+	baselines []*config.TraderBaseline
 }
 
-func (m *MockTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
-	if m.shouldFailCloseLong {
-		return nil, errors.New("failed to close long")
+func (m *memoryBaselineStore) GetLatestTraderBaseline(traderID string) (*config.TraderBaseline, error) {
+	if len(m.baselines) == 0 {
+		return nil, nil
 	}
-	return map[string]interface{}{
-		"orderId": int64(123458),
-		"symbol":  symbol,
-	}, nil
+	return m.baselines[len(m.baselines)-1], nil
 }
 
-func (m *MockTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
-	if m.shouldFailCloseShort {
-		return nil, errors.New("failed to close short")
+func (m *memoryBaselineStore) RollTraderBaseline(prev *config.TraderBaseline, endEquity float64, next *config.TraderBaseline) error {
+	if prev != nil {
+		periodEnd := next.PeriodStart
+		prev.EndEquity, prev.PeriodEnd = &endEquity, &periodEnd
 	}
-	return map[string]interface{}{
-		"orderId": int64(123459),
-		"symbol":  symbol,
-	}, nil
+	next.ID = int64(len(m.baselines) + 1)
+	m.baselines = append(m.baselines, next)
+	return nil
 }
 
-func (m *MockTrader) SetLeverage(symbol string, leverage int) error {
+func (m *memoryBaselineStore) AdjustTraderBaseline(id int64, delta float64) error {
+	for _, b := range m.baselines {
+		if b.ID == id {
+			b.Baseline += delta
+		}
+	}
 	return nil
 }
 
-func (m *MockTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
+// TestBaselineReset 测试按月滚动周期净值基准：进入新月份时记录新基准，上一周期的记录保留并补记期末净值
+func (s *AutoTraderTestSuite) TestBaselineReset() {
+	origDatabase := s.autoTrader.database
+	defer func() {
+		s.autoTrader.database = origDatabase
+		s.autoTrader.SetBaselineResetPolicy("")
+		s.autoTrader.periodBaseline, s.autoTrader.periodBaselineLoaded = nil, false
+	}()
+
+	january := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := &memoryBaselineStore{baselines: []*config.TraderBaseline{
+		{ID: 1, Policy: BaselineResetMonthly, Baseline: 10000.0, PeriodStart: january},
+	}}
+	s.mockTrader = new(MockTrader)
+	s.mockTrader.balance = map[string]interface{}{"totalWalletBalance": 10800.0, "totalUnrealizedProfit": 200.0}
+	s.autoTrader.trader = s.mockTrader
+	s.autoTrader.database = store
+	s.autoTrader.periodBaseline, s.autoTrader.periodBaselineLoaded = nil, false
+	s.autoTrader.SetBaselineResetPolicy(BaselineResetMonthly)
+	initialBalance := s.autoTrader.initialBalance
+
+	s.autoTrader.rollBaselineIfDue(time.Date(2026, 1, 20, 12, 0, 0, 0, time.UTC))
+	s.Len(store.baselines, 1, "同一月份内不应滚动")
+
+	february := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	s.autoTrader.rollBaselineIfDue(time.Date(2026, 2, 3, 8, 0, 0, 0, time.UTC))
+	s.Require().Len(store.baselines, 2)
+	prior, current := store.baselines[0], store.baselines[1]
+	s.Equal(10000.0, prior.Baseline, "上一周期的基准应保留")
+	s.Require().NotNil(prior.EndEquity)
+	s.Equal(11000.0, *prior.EndEquity)
+	s.Require().NotNil(prior.PeriodEnd)
+	s.True(prior.PeriodEnd.Equal(february))
+	s.Equal(11000.0, current.Baseline)
+	s.True(current.PeriodStart.Equal(february))
+	s.Equal(initialBalance, s.autoTrader.initialBalance, "滚动周期基准不应修改初始余额")
+
+	s.autoTrader.rollBaselineIfDue(time.Date(2026, 2, 10, 0, 0, 0, 0, time.UTC))
+	s.Len(store.baselines, 2, "同一周期只记录一次")
+
+	fields := s.autoTrader.periodPnLFields(11500.0)
+	s.Equal(500.0, fields["period_pnl"])
+	s.Equal(11000.0, fields["period_baseline"])
+
+	s.True(baselinePeriodStart(BaselineResetWeekly, time.Date(2026, 10, 16, 15, 0, 0, 0, time.UTC)).Equal(time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)))
+	s.True(baselinePeriodStart(BaselineResetNone, time.Now()).IsZero())
+}
+
+// memoryDrawdownStore 内存中的净值峰值存储（实现 drawdownStateStore）
+type memoryDrawdownStore struct {
+	peak  float64
+	armed bool
+}
+
+func (m *memoryDrawdownStore) GetTraderDrawdownState(traderID string) (float64, bool, error) {
+	return m.peak, m.armed, nil
+}
+
+func (m *memoryDrawdownStore) SaveTraderDrawdownState(traderID string, peakEquity float64, armed bool) error {
+	m.peak, m.armed = peakEquity, armed
 	return nil
 }
 
-func (m *MockTrader) GetMarketPrice(symbol string) (float64, error) {
-	return 50000.0, nil
-}
+// TestMaxDrawdownStop 测试最大回撤硬止损：净值较峰值回撤超过阈值时暂停开新仓并平仓，回升后需创新高才重新待命
+func (s *AutoTraderTestSuite) TestMaxDrawdownStop() {
+	origDatabase := s.autoTrader.database
+	s.mockTrader.positions = []map[string]interface{}{
+		{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.1, "entryPrice": 50000.0, "markPrice": 45000.0},
+	}
+	defer func() {
+		s.autoTrader.database = origDatabase
+		s.mockTrader.positions = []map[string]interface{}{}
+		s.autoTrader.SetMaxDrawdownStop(false, 0, false)
+		s.autoTrader.peakEquity, s.autoTrader.drawdownArmed = 0, false
+	}()
+
+	// 峰值已持久化：重启后从存储恢复
+	store := &memoryDrawdownStore{peak: 10000, armed: true}
+	s.autoTrader.database = store
+	s.autoTrader.loadDrawdownState()
+	s.autoTrader.SetMaxDrawdownStop(true, 20, true)
+
+	s.Run("回撤未达到阈值不触发", func() {
+		s.False(s.autoTrader.updateDrawdownStop(8100))
+		s.False(s.autoTrader.drawdownStopHalted())
+		s.Empty(s.mockTrader.closedPositions)
+	})
+
+	s.Run("回撤超过阈值时暂停开仓并平仓", func() {
+		s.True(s.autoTrader.updateDrawdownStop(7900))
+		s.True(s.autoTrader.drawdownStopHalted())
+		s.True(s.autoTrader.drawdownStopBlocksOpen("open_long"))
+		s.False(s.autoTrader.drawdownStopBlocksOpen("close_long"))
+		s.Equal([]string{"BTCUSDT_long"}, s.mockTrader.closedPositions)
+		s.Equal(10000.0, store.peak)
+		s.False(store.armed, "触发后应持久化为未待命")
+		s.Equal(true, s.autoTrader.GetStatus()["drawdown_stop"].(map[string]interface{})["halted"])
+	})
+
+	s.Run("回升未创新高时不重新待命", func() {
+		s.autoTrader.clearDrawdownHalt()
+		s.False(s.autoTrader.updateDrawdownStop(9000))
+		s.False(s.autoTrader.updateDrawdownStop(7800))
+		s.False(s.autoTrader.drawdownStopHalted())
+		s.Len(s.mockTrader.closedPositions, 1)
+	})
+
+	s.Run("创新高后重新待命", func() {
+		s.False(s.autoTrader.updateDrawdownStop(10500))
+		s.Equal(10500.0, store.peak)
+		s.True(store.armed)
+		s.True(s.autoTrader.updateDrawdownStop(8300))
+		s.True(s.autoTrader.drawdownStopHalted())
+	})
+
+	s.Run("未设置阈值时使用系统最大回撤", func() {
+		s.autoTrader.config.MaxDrawdown = 15
+		s.autoTrader.SetMaxDrawdownStop(true, 0, false)
+		s.Equal(15.0, s.autoTrader.maxDrawdownStopPct())
+		s.False(s.autoTrader.drawdownStopHalted(), "修改配置后解除暂停")
+		s.True(ValidMaxDrawdownStopPct(0))
+		s.False(ValidMaxDrawdownStopPct(100))
+		s.False(ValidMaxDrawdownStopPct(-1))
+	})
+}
+
+// TestStopApproachAlert 测试止损接近提醒：价格进入止损附近时只提醒一次，停留在区间内不重复提醒
+func (s *AutoTraderTestSuite) TestStopApproachAlert() {
+	defer s.autoTrader.SetStopApproachAlertPct(0)
+	s.autoTrader.resetPyramidState("BTCUSDT_long", 48000)
+	tick := func(markPrice float64) int {
+		return s.autoTrader.checkStopApproach(NormalizePositions([]map[string]interface{}{
+			{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.1, "entryPrice": 50000.0, "markPrice": markPrice, "leverage": 10.0},
+		}))
+	}
+
+	s.Run("未配置时不提醒", func() {
+		s.Equal(0, tick(48100))
+	})
+
+	s.autoTrader.SetStopApproachAlertPct(2)
+	s.Run("距止损较远时不提醒", func() {
+		s.Equal(0, tick(50000))
+	})
+
+	s.Run("进入提醒区间只提醒一次", func() {
+		s.Equal(1, tick(48800)) // 距止损 1.64%
+		s.Equal(0, tick(48500))
+		s.Equal(0, tick(48900))
+	})
+
+	s.Run("止损价调整后可再次提醒", func() {
+		s.autoTrader.updatePyramidStopLoss("BTCUSDT_long", 48600)
+		s.Equal(1, tick(48900))
+		s.Equal(0, tick(48700))
+	})
+
+	s.Run("持仓消失后清除提醒记录", func() {
+		s.autoTrader.checkStopApproach(nil)
+		s.Empty(s.autoTrader.stopApproachAlerted)
+	})
+}
+
+// TestSymbolUniverse 测试候选币种池（candidate_symbols）与开仓白名单（trading_symbols）分别及同时配置时的行为
+func (s *AutoTraderTestSuite) TestSymbolUniverse() {
+	s.autoTrader.defaultCoins = []string{"BTCUSDT", "ETHUSDT"}
+	defer s.autoTrader.SetSymbolUniverse(nil, nil)
+	candidates := func() []string {
+		coins, err := s.autoTrader.getCandidateCoins()
+		s.Require().NoError(err)
+		symbols := make([]string, 0, len(coins))
+		for _, coin := range coins {
+			symbols = append(symbols, coin.Symbol)
+		}
+		return symbols
+	}
+	openRejected := func(symbol string) bool {
+		err := s.autoTrader.dispatchDecisionWithRecord(&decision.Decision{Symbol: symbol, Action: "open_long"}, &logger.DecisionAction{})
+		return err != nil && err.Error() == fmt.Sprintf("%s 不在交易币种白名单（trading_symbols）中，不开新仓", symbol)
+	}
+
+	s.Run("均未配置时使用默认币种且不限制开仓", func() {
+		s.autoTrader.SetSymbolUniverse(ParseSymbolList(""), ParseSymbolList(""))
+		s.Equal([]string{"BTCUSDT", "ETHUSDT"}, candidates())
+		s.True(s.autoTrader.symbolAllowed("DOGEUSDT"))
+	})
+
+	s.Run("只配置 trading_symbols 时同时作为候选池和白名单", func() {
+		s.autoTrader.SetSymbolUniverse(nil, ParseSymbolList("SOLUSDT, bnb"))
+		s.Equal([]string{"SOLUSDT", "BNBUSDT"}, candidates())
+		s.True(s.autoTrader.symbolAllowed("BNBUSDT"))
+		s.True(openRejected("ETHUSDT"))
+	})
+
+	s.Run("只配置 candidate_symbols 时只决定候选池", func() {
+		s.autoTrader.SetSymbolUniverse(ParseSymbolList("XRPUSDT,ADAUSDT"), nil)
+		s.Equal([]string{"XRPUSDT", "ADAUSDT"}, candidates())
+		s.True(s.autoTrader.symbolAllowed("DOGEUSDT"))
+	})
+
+	s.Run("同时配置时候选池与白名单分离", func() {
+		s.autoTrader.SetSymbolUniverse(ParseSymbolList("XRPUSDT,ADAUSDT,SOLUSDT"), ParseSymbolList("SOLUSDT"))
+		s.Equal([]string{"XRPUSDT", "ADAUSDT", "SOLUSDT"}, candidates())
+		s.True(s.autoTrader.symbolAllowed("SOLUSDT"))
+		s.True(openRejected("XRPUSDT"), "候选池中但不在白名单的币种不能开仓")
+		err := s.autoTrader.dispatchDecisionWithRecord(&decision.Decision{Symbol: "XRPUSDT", Action: "close_long"}, &logger.DecisionAction{})
+		if err != nil {
+			s.NotContains(err.Error(), "trading_symbols", "平仓不受白名单限制")
+		}
+	})
+}
+
+// TestProfitProtection 测试盈利保护：收益达到阈值后止损移至保本价，继续上涨后跟踪锁定峰值收益
+func (s *AutoTraderTestSuite) TestProfitProtection() {
+	defer s.autoTrader.SetProfitProtection(0, 0, 0)
+	markPrice := 3000.0
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: markPrice}, nil
+	})
+	setup := func(side string) {
+		s.mockTrader = new(MockTrader)
+		s.autoTrader.trader = s.mockTrader
+		s.autoTrader.ClearPeakPnLCache("ETHUSDT", side)
+		s.autoTrader.prunePyramidState(map[string]bool{})
+		// 开仓时的初始止损：多单 2900，空单 3100
+		initialStop := 2900.0
+		if side == "short" {
+			initialStop = 3100.0
+		}
+		s.autoTrader.resetPyramidState("ETHUSDT_"+side, initialStop)
+	}
+	// 模拟价格变动后执行一次回撤监控
+	moveTo := func(side string, price float64) {
+		markPrice = price
+		s.mockTrader.SetStopLossCalled = false
+		s.mockTrader.positions = []map[string]interface{}{
+			{"symbol": "ETHUSDT", "side": side, "positionAmt": 1.0, "entryPrice": 3000.0, "markPrice": price, "leverage": 10.0},
+		}
+		s.autoTrader.checkPositionDrawdown()
+	}
+	latestAction := func() string {
+		records, err := s.autoTrader.decisionLogger.GetLatestRecords(1)
+		s.Require().NoError(err)
+		s.Require().Len(records, 1)
+		s.Require().Len(records[0].Decisions, 1)
+		return records[0].Decisions[0].Action
+	}
+
+	s.Run("未启用时不调整止损", func() {
+		s.autoTrader.SetProfitProtection(0, 0, 0)
+		setup("long")
+		moveTo("long", 3100.0)
+		s.False(s.mockTrader.SetStopLossCalled)
+	})
+
+	s.Run("达到保本阈值后止损移至开仓价", func() {
+		s.autoTrader.SetProfitProtection(5, 0, 0)
+		setup("long")
+		moveTo("long", 3010.0) // 收益 3.3%，未达到阈值
+		s.False(s.mockTrader.SetStopLossCalled)
+
+		moveTo("long", 3030.0) // 收益 10%
+		s.True(s.mockTrader.SetStopLossCalled)
+		s.Equal(3000.0, s.mockTrader.LastSLPrice)
+		s.Equal("breakeven_stop_loss", latestAction())
+
+		moveTo("long", 3045.0)
+		s.False(s.mockTrader.SetStopLossCalled, "止损已在保本价，不重复调整")
+	})
+
+	s.Run("超过跟踪阈值后按峰值收益上移止损且只收紧", func() {
+		s.autoTrader.SetProfitProtection(5, 15, 0.5)
+		setup("long")
+		moveTo("long", 3060.0) // 收益 20%，锁定 10% → 3030
+		s.True(s.mockTrader.SetStopLossCalled)
+		s.InDelta(3030.0, s.mockTrader.LastSLPrice, 1e-6)
+		s.Equal("trailing_stop_loss", latestAction())
+
+		moveTo("long", 3090.0) // 收益 30%，锁定 15% → 3045
+		s.True(s.mockTrader.SetStopLossCalled)
+		s.InDelta(3045.0, s.mockTrader.LastSLPrice, 1e-6)
+
+		moveTo("long", 3075.0) // 回落，峰值不变
+		s.False(s.mockTrader.SetStopLossCalled, "价格回落时不放松止损")
+	})
+
+	s.Run("空单跟踪止损下移", func() {
+		s.autoTrader.SetProfitProtection(0, 15, 0.5)
+		setup("short")
+		moveTo("short", 2940.0) // 收益 20%，锁定 10% → 2970
+		s.True(s.mockTrader.SetStopLossCalled)
+		s.InDelta(2970.0, s.mockTrader.LastSLPrice, 1e-6)
+	})
+
+	s.Run("价格已回落到目标止损之外时不调整", func() {
+		s.autoTrader.SetProfitProtection(0, 15, 0.5)
+		setup("long")
+		s.autoTrader.UpdatePeakPnL("ETHUSDT", "long", 20.0)
+		moveTo("long", 3010.0) // 目标止损 3030 高于现价
+		s.False(s.mockTrader.SetStopLossCalled)
+	})
+}
+
+// TestMaxActionsPerCycle 测试单周期动作上限：排序后只保留前N个高优先级动作，其余记录为跳过
+func (s *AutoTraderTestSuite) TestMaxActionsPerCycle() {
+	defer s.autoTrader.SetMaxActionsPerCycle(0)
+	var oversized []decision.Decision
+	for i := 0; i < 8; i++ {
+		oversized = append(oversized, decision.Decision{Symbol: fmt.Sprintf("COIN%dUSDT", i), Action: "open_long"})
+	}
+	oversized = append(oversized,
+		decision.Decision{Symbol: "BTCUSDT", Action: "wait"},
+		decision.Decision{Symbol: "ETHUSDT", Action: "close_long"},
+		decision.Decision{Symbol: "SOLUSDT", Action: "update_stop_loss"},
+		decision.Decision{Symbol: "BNBUSDT", Action: "close_short"},
+	)
+
+	s.Run("排序后保留高优先级动作，hold/wait不计入", func() {
+		kept, dropped := capDecisionActions(sortDecisionsByPriority(oversized), 4)
+		s.Require().Len(kept, 5)
+		s.Equal("close_long", kept[0].Action)
+		s.Equal("close_short", kept[1].Action)
+		s.Equal("update_stop_loss", kept[2].Action)
+		s.Equal("open_long", kept[3].Action)
+		s.Equal("wait", kept[4].Action)
+		s.Len(dropped, 7)
+		for _, d := range dropped {
+			s.Equal("open_long", d.Action)
+		}
+	})
+
+	s.Run("未设置上限时全部保留", func() {
+		kept, dropped := capDecisionActions(oversized, 0)
+		s.Len(kept, len(oversized))
+		s.Empty(dropped)
+	})
+
+	s.Run("被截掉的动作记录为跳过", func() {
+		s.autoTrader.SetMaxActionsPerCycle(4)
+		_, dropped := capDecisionActions(sortDecisionsByPriority(oversized), s.autoTrader.maxActionsPerCycle())
+		record := &logger.DecisionRecord{}
+		s.autoTrader.recordCappedDecisions(record, dropped)
+
+		s.Require().Len(record.Decisions, 7)
+		for _, a := range record.Decisions {
+			s.Equal("max_actions_skipped", a.Status)
+			s.False(a.Success)
+		}
+		s.Len(record.ExecutionLog, 7)
+	})
+}
+
+// TestFirstTradeApproval 测试首笔交易人工审批：首笔开仓挂起，审批且执行成功后，之后自动执行
+func (s *AutoTraderTestSuite) TestFirstTradeApproval() {
+	defer s.autoTrader.SetRequireFirstTradeApproval(false)
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 50000.0}, nil
+	})
+	openLong := func() *decision.Decision {
+		return &decision.Decision{Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 1000}
+	}
+
+	s.Run("审批的开仓执行失败时下一笔仍需审批", func() {
+		s.mockTrader = &MockTrader{shouldFailOpenLong: true}
+		s.autoTrader.trader = s.mockTrader
+		s.autoTrader.SetRequireFirstTradeApproval(true)
+
+		s.ErrorIs(s.autoTrader.executeDecisionWithRecord(openLong(), &logger.DecisionAction{}), ErrPendingApproval)
+		pending := s.autoTrader.PendingActions()
+		s.Require().Len(pending, 1)
+		result, err := s.autoTrader.ApprovePendingAction(pending[0].ID)
+		s.Error(err)
+		s.False(result.Success)
+
+		s.ErrorIs(s.autoTrader.executeDecisionWithRecord(openLong(), &logger.DecisionAction{}), ErrPendingApproval)
+		s.autoTrader.SetRequireFirstTradeApproval(false)
+	})
+
+	s.Run("首笔开仓挂起，审批后立即执行", func() {
+		s.mockTrader = new(MockTrader)
+		s.autoTrader.trader = s.mockTrader
+		s.autoTrader.SetRequireFirstTradeApproval(true)
+
+		actionRecord := &logger.DecisionAction{}
+		err := s.autoTrader.executeDecisionWithRecord(openLong(), actionRecord)
+		s.ErrorIs(err, ErrPendingApproval)
+		s.Equal("pending_approval", actionRecord.Status)
+		s.Equal(0.0, s.mockTrader.lastOpenLongQty)
+
+		// 已有待审批动作时，其他开仓不再重复挂起
+		s.ErrorIs(s.autoTrader.executeDecisionWithRecord(openLong(), &logger.DecisionAction{}), ErrPendingApproval)
+		pending := s.autoTrader.PendingActions()
+		s.Require().Len(pending, 1)
+
+		_, err = s.autoTrader.ApprovePendingAction("missing")
+		s.ErrorIs(err, ErrPendingActionNotFound)
+
+		result, err := s.autoTrader.ApprovePendingAction(pending[0].ID)
+		s.NoError(err)
+		s.True(result.Success)
+		s.Greater(s.mockTrader.lastOpenLongQty, 0.0)
+		s.Empty(s.autoTrader.PendingActions())
+	})
 
-func (m *MockTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
-	m.SetStopLossCalled = true
-	m.LastSLPrice = stopPrice
-	return nil
-}
+	s.Run("审批后的开仓自动执行", func() {
+		s.mockTrader = new(MockTrader)
+		s.autoTrader.trader = s.mockTrader
 
-func (m *MockTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
-	m.SetTakeProfitCalled = true
-	m.LastTPPrice = takeProfitPrice
-	return nil
-}
+		s.NoError(s.autoTrader.executeDecisionWithRecord(openLong(), &logger.DecisionAction{}))
+		s.Greater(s.mockTrader.lastOpenLongQty, 0.0)
+	})
 
-func (m *MockTrader) CancelStopLossOrders(symbol string) error {
-	return nil
-}
+	s.Run("拒绝与过期写入决策日志", func() {
+		s.mockTrader = new(MockTrader)
+		s.autoTrader.trader = s.mockTrader
+		s.autoTrader.SetRequireFirstTradeApproval(false)
+		s.autoTrader.SetRequireFirstTradeApproval(true)
+
+		s.ErrorIs(s.autoTrader.executeDecisionWithRecord(openLong(), &logger.DecisionAction{}), ErrPendingApproval)
+		pending := s.autoTrader.PendingActions()
+		s.Require().Len(pending, 1)
+		s.NoError(s.autoTrader.RejectPendingAction(pending[0].ID))
+		records, err := s.autoTrader.decisionLogger.GetLatestRecords(1)
+		s.Require().NoError(err)
+		s.Require().Len(records, 1)
+		s.Equal("approval_rejected", records[0].Decisions[0].Status)
+
+		// 拒绝后下一笔开仓仍需审批；超时未审批的动作被移除并记录
+		s.ErrorIs(s.autoTrader.executeDecisionWithRecord(openLong(), &logger.DecisionAction{}), ErrPendingApproval)
+		s.autoTrader.approvalMu.Lock()
+		s.autoTrader.pendingActions[0].ExpiresAt = time.Now().Add(-time.Minute)
+		s.autoTrader.approvalMu.Unlock()
+		s.Empty(s.autoTrader.PendingActions())
+		records, err = s.autoTrader.decisionLogger.GetLatestRecords(1)
+		s.Require().NoError(err)
+		s.Require().Len(records, 1)
+		s.Equal("approval_expired", records[0].Decisions[0].Status)
+		s.Equal(0.0, s.mockTrader.lastOpenLongQty)
+	})
 
-func (m *MockTrader) CancelTakeProfitOrders(symbol string) error {
-	return nil
+	s.Run("信号开仓同样需要审批", func() {
+		s.mockTrader = new(MockTrader)
+		s.autoTrader.trader = s.mockTrader
+
+		strat := &signal.SignalDecision{SignalID: "sig_approval", Symbol: "BTCUSDT", Direction: "LONG"}
+		s.autoTrader.executeSignalTrade(strat, "ENTRY", 0.2, 50000.0)
+		s.autoTrader.executeAIAction(AIExecutionResult{Action: "ADD_LONG", AmountPercent: 0.2}, strat, 50000.0)
+		s.Equal(0.0, s.mockTrader.lastOpenLongQty)
+		pending := s.autoTrader.PendingActions()
+		s.Require().Len(pending, 1)
+		s.Equal("open_long", pending[0].Decision.Action)
+		s.Equal("BTCUSDT", pending[0].Decision.Symbol)
+	})
 }
 
-func (m *MockTrader) CancelAllOrders(symbol string) error {
-	return nil
-}
+// TestAICallThrottle 测试AI调用最小间隔：快速连续触发被限流，紧急保护不受限制
+func (s *AutoTraderTestSuite) TestAICallThrottle() {
+	defer s.autoTrader.SetMinSecondsBetweenAICalls(0)
+	start := time.Now()
 
-func (m *MockTrader) CancelStopOrders(symbol string) error {
-	return nil
-}
+	s.Run("未设置间隔时不限流", func() {
+		s.autoTrader.SetMinSecondsBetweenAICalls(0)
+		for i := 0; i < 3; i++ {
+			ok, _ := s.autoTrader.reserveAICall(start, false)
+			s.True(ok)
+		}
+	})
 
-func (m *MockTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
-	return fmt.Sprintf("%.4f", quantity), nil
-}
+	s.Run("快速连续触发按最小间隔限流", func() {
+		s.autoTrader.SetMinSecondsBetweenAICalls(60)
+		allowed := 0
+		// 5分钟内每10秒触发一次，最多每60秒放行一次
+		for sec := 0; sec < 300; sec += 10 {
+			if ok, _ := s.autoTrader.reserveAICall(start.Add(time.Duration(60+sec)*time.Second), false); ok {
+				allowed++
+			}
+		}
+		s.Equal(5, allowed)
 
-func (m *MockTrader) GetOpenOrders(symbol string) ([]map[string]interface{}, error) {
-	if m.openOrders != nil {
-		return m.openOrders, nil
-	}
-	return []map[string]interface{}{}, nil
-}
+		ok, remaining := s.autoTrader.reserveAICall(start.Add(355*time.Second), false)
+		s.False(ok)
+		s.Equal(5*time.Second, remaining)
+	})
 
-func (m *MockTrader) GetOrderHistory(symbol string, startTime, endTime int64) ([]map[string]interface{}, error) {
-	return []map[string]interface{}{}, nil
-}
+	s.Run("紧急保护不受限制", func() {
+		ok, _ := s.autoTrader.reserveAICall(start.Add(356*time.Second), true)
+		s.True(ok)
+		// 紧急调用同样计入间隔
+		ok, _ = s.autoTrader.reserveAICall(start.Add(400*time.Second), false)
+		s.False(ok)
+	})
 
-func (m *MockTrader) CancelOrder(symbol, orderId string) error {
-	return nil
-}
+	s.Run("限流时跳过AI调用但仍补设保护单", func() {
+		s.mockTrader = new(MockTrader)
+		s.autoTrader.trader = s.mockTrader
+		s.autoTrader.SetAutoReprotect(true)
+		defer s.autoTrader.SetAutoReprotect(false)
+		s.autoTrader.resetPyramidState("ETHUSDT_long", 3000.0)
+		s.mockTrader.positions = []map[string]interface{}{
+			{"symbol": "ETHUSDT", "side": "long", "positionAmt": 0.5, "entryPrice": 3200.0},
+		}
 
-func (m *MockTrader) PlaceLimitOrder(symbol string, side, tradeSide string, quantity float64, price float64, leverage int) (map[string]interface{}, error) {
-	return map[string]interface{}{
-		"orderId": int64(123460),
-		"symbol":  symbol,
-	}, nil
+		record := &logger.DecisionRecord{}
+		s.autoTrader.skipCycle(record, "⏳ 距上次AI调用不足最小间隔，跳过AI决策（剩余 30 秒）")
+		s.Require().NotEmpty(record.ExecutionLog)
+		s.Contains(record.ExecutionLog[0], "跳过AI决策")
+		s.True(s.mockTrader.SetStopLossCalled)
+	})
 }
 
+// TestMarginMode 测试交易所仓位模式与配置不一致时的警告与校准
+func (s *AutoTraderTestSuite) TestMarginMode() {
+	defer func() { s.autoTrader.config.IsCrossMargin = false }()
+	s.autoTrader.config.IsCrossMargin = true
 
-// TestCheckStrategyCompletion 测试二次检查补单逻辑
-func (s *AutoTraderTestSuite) TestCheckStrategyCompletion() {
-	// 1. 准备测试数据
-	symbol := "ETHUSDT"
-	strat := &signal.SignalDecision{
-		SignalID: "test-001",
-		Symbol:   symbol,
-		StopLoss: signal.StopLossStrategy{Price: 3000.0},
-		TakeProfits: []signal.TPStrategy{
-			{Price: 3500.0, Percent: 1.0},
-		},
-	}
+	s.Run("模式一致时无警告", func() {
+		s.mockTrader = new(MockTrader)
+		s.autoTrader.trader = s.mockTrader
 
-	// 场景A: 有持仓，无止损 => 应该触发 SetStopLoss 和 SetTakeProfit
-	s.mockTrader = new(MockTrader)
-	s.autoTrader.trader = s.mockTrader
+		status, err := s.autoTrader.CheckMarginMode("btc")
+		s.Require().NoError(err)
+		s.Equal("BTCUSDT", status.Symbol)
+		s.True(status.Matches)
+		s.Empty(status.Warning)
+	})
 
-	// Mock GetPositions: 返回 0.1 ETH 多单
-	s.mockTrader.positions = []map[string]interface{}{
-		{
-			"symbol":      symbol,
-			"positionAmt": 0.1,
-		},
-	}
-	// Mock GetOpenOrders: 空列表（无止损）
-	s.mockTrader.openOrders = []map[string]interface{}{}
+	s.Run("模式不一致且有持仓时警告并不修改", func() {
+		s.mockTrader = &MockTrader{exchangeIsolated: true, marginModeLocked: true}
+		s.mockTrader.positions = []map[string]interface{}{
+			{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.1, "entryPrice": 50000.0},
+		}
+		s.autoTrader.trader = s.mockTrader
+
+		status, err := s.autoTrader.ReconcileMarginMode("BTCUSDT")
+		s.Require().NoError(err)
+		s.False(status.Matches)
+		s.True(status.HasPosition)
+		s.False(status.Reconciled)
+		s.Contains(status.Warning, "逐仓")
+		s.Contains(status.Warning, "已有持仓")
+		s.Empty(s.mockTrader.marginModeSet, "有持仓时不应尝试修改仓位模式")
+	})
 
-	// 执行测试
-	s.autoTrader.CheckStrategyCompletion(strat)
+	s.Run("模式不一致且无持仓时按配置校准", func() {
+		s.mockTrader = &MockTrader{exchangeIsolated: true}
+		s.autoTrader.trader = s.mockTrader
 
-	// 验证调用
-	s.T().Logf("Scenario A: SetStopLossCalled=%v, SetTakeProfitCalled=%v", s.mockTrader.SetStopLossCalled, s.mockTrader.SetTakeProfitCalled)
-	s.True(s.mockTrader.SetStopLossCalled, "应该并且只能调用 SetStopLoss")
-	s.True(s.mockTrader.SetTakeProfitCalled, "应该并且只能调用 SetTakeProfit")
-	s.Equal(3000.0, s.mockTrader.LastSLPrice)
-	s.Equal(3500.0, s.mockTrader.LastTPPrice)
+		status, err := s.autoTrader.ReconcileMarginMode("BTCUSDT")
+		s.Require().NoError(err)
+		s.True(status.Matches)
+		s.True(status.Reconciled)
+		s.Equal([]bool{true}, s.mockTrader.marginModeSet)
+	})
+}
 
-	// 场景B: 有持仓，已有止损 => 不应重复设置
-	s.mockTrader = new(MockTrader)
-	s.autoTrader.trader = s.mockTrader
+// TestSymbolExposureCap 测试单币种敞口上限：超限开仓被下调到剩余额度，无额度时拒绝
+func (s *AutoTraderTestSuite) TestSymbolExposureCap() {
+	defer s.autoTrader.SetMaxPerSymbolExposurePct(0)
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 50000.0}, nil
+	})
+	// 默认余额净值 10000+100=10100，上限20% → 2020 USDT
+	s.autoTrader.SetMaxPerSymbolExposurePct(20)
 
-	s.mockTrader.positions = []map[string]interface{}{
-		{
-			"symbol":      symbol,
-			"positionAmt": 0.1,
-		},
-	}
+	s.Run("超限开仓下调到上限", func() {
+		s.mockTrader = new(MockTrader)
+		s.autoTrader.trader = s.mockTrader
+		// 同币种已有 1000 USDT 空仓，剩余额度 1020 USDT
+		s.mockTrader.positions = []map[string]interface{}{
+			{"symbol": "BTCUSDT", "side": "short", "positionAmt": 0.02, "entryPrice": 50000.0, "markPrice": 50000.0},
+		}
 
-	// Mock GetOpenOrders: 返回已有止损单
-	s.mockTrader.openOrders = []map[string]interface{}{
-		{
-			"symbol": symbol,
-			"type":   "stop_loss", // 表示已存在
-		},
-		{
-			"symbol": symbol,
-			"type":   "take_profit",
-		},
-	}
+		d := &decision.Decision{Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 2000}
+		actionRecord := &logger.DecisionAction{}
+		s.Require().NoError(s.autoTrader.executeDecisionWithRecord(d, actionRecord))
+		s.InDelta(1020.0, d.PositionSizeUSD, 1e-9)
+		s.InDelta(1020.0/50000.0, s.mockTrader.lastOpenLongQty, 1e-9)
+		s.Contains(actionRecord.Note, "单币种上限")
+	})
 
-	// 执行测试
-	s.autoTrader.CheckStrategyCompletion(strat)
+	s.Run("额度内开仓不下调", func() {
+		s.mockTrader = new(MockTrader)
+		s.autoTrader.trader = s.mockTrader
 
-	// 验证不调用
-	s.T().Logf("Scenario B: SetStopLossCalled=%v, SetTakeProfitCalled=%v", s.mockTrader.SetStopLossCalled, s.mockTrader.SetTakeProfitCalled)
-	s.False(s.mockTrader.SetStopLossCalled, "不应重复设置止损")
-	s.False(s.mockTrader.SetTakeProfitCalled, "不应重复设置止盈")
-}
+		d := &decision.Decision{Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 1500}
+		s.Require().NoError(s.autoTrader.executeDecisionWithRecord(d, &logger.DecisionAction{}))
+		s.InDelta(1500.0/50000.0, s.mockTrader.lastOpenLongQty, 1e-9)
+	})
 
-// ============================================================
-// 测试套件入口
-// ============================================================
+	s.Run("已达上限时拒绝开仓", func() {
+		s.mockTrader = new(MockTrader)
+		s.autoTrader.trader = s.mockTrader
+		s.mockTrader.positions = []map[string]interface{}{
+			{"symbol": "BTCUSDT", "side": "short", "positionAmt": 0.05, "entryPrice": 50000.0, "markPrice": 50000.0},
+		}
 
-// TestAutoTraderTestSuite 运行 AutoTrader 测试套件
-func TestAutoTraderTestSuite(t *testing.T) {
-	suite.Run(t, new(AutoTraderTestSuite))
+		d := &decision.Decision{Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 500}
+		actionRecord := &logger.DecisionAction{}
+		err := s.autoTrader.executeDecisionWithRecord(d, actionRecord)
+		s.ErrorIs(err, ErrSymbolExposureCap)
+		s.Equal("symbol_exposure_capped", actionRecord.Status)
+		s.Equal(0.0, s.mockTrader.lastOpenLongQty)
+	})
 }
 
-// ============================================================
-// 独立的单元测试 - calculatePnLPercentage 函数测试
-// ============================================================
-
-func TestCalculatePnLPercentage(t *testing.T) {
-	tests := []struct {
-		name          string
-		unrealizedPnl float64
-		marginUsed    float64
-		expected      float64
-	}{
-		{
-			name:          "正常盈利 - 10倍杠杆",
-			unrealizedPnl: 100.0,  // 盈利 100 USDT
-			marginUsed:    1000.0, // 保证金 1000 USDT
-			expected:      10.0,   // 10% 收益率
-		},
-		{
-			name:          "正常亏损 - 10倍杠杆",
-			unrealizedPnl: -50.0,  // 亏损 50 USDT
-			marginUsed:    1000.0, // 保证金 1000 USDT
-			expected:      -5.0,   // -5% 收益率
-		},
-		{
-			name:          "高杠杆盈利 - 价格上涨1%，20倍杠杆",
-			unrealizedPnl: 200.0,  // 盈利 200 USDT
-			marginUsed:    1000.0, // 保证金 1000 USDT
-			expected:      20.0,   // 20% 收益率
-		},
-		{
-			name:          "保证金为0 - 边界情况",
-			unrealizedPnl: 100.0,
-			marginUsed:    0.0,
-			expected:      0.0, // 应该返回 0 而不是除以零错误
-		},
-		{
-			name:          "负保证金 - 边界情况",
-			unrealizedPnl: 100.0,
-			marginUsed:    -1000.0,
-			expected:      0.0, // 应该返回 0（异常情况）
-		},
-		{
-			name:          "盈亏为0",
-			unrealizedPnl: 0.0,
-			marginUsed:    1000.0,
-			expected:      0.0,
-		},
-		{
-			name:          "小额交易",
-			unrealizedPnl: 0.5,
-			marginUsed:    10.0,
-			expected:      5.0,
-		},
-		{
-			name:          "大额盈利",
-			unrealizedPnl: 5000.0,
-			marginUsed:    10000.0,
-			expected:      50.0,
-		},
-		{
-			name:          "极小保证金",
-			unrealizedPnl: 1.0,
-			marginUsed:    0.01,
-			expected:      10000.0, // 100倍收益率
-		},
+// TestMinHoldingTime 测试最短持仓时间：持仓过新时拒绝AI平仓，标记价格已触及止损或紧急平仓时放行
+func (s *AutoTraderTestSuite) TestMinHoldingTime() {
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 50000.0}, nil
+	})
+	defer s.autoTrader.SetMinHoldingMinutes(0)
+	defer s.autoTrader.forgetPositionFirstSeen("BTCUSDT_long")
+	s.autoTrader.resetPyramidState("BTCUSDT_long", 48000)
+	setup := func(markPrice float64, heldFor time.Duration) {
+		s.mockTrader = new(MockTrader)
+		s.autoTrader.trader = s.mockTrader
+		s.mockTrader.positions = []map[string]interface{}{
+			{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.1, "entryPrice": 50000.0, "markPrice": markPrice, "leverage": 10.0},
+		}
+		s.autoTrader.setPositionFirstSeen("BTCUSDT_long", time.Now().Add(-heldFor).UnixMilli())
+	}
+	closeLong := func(action string) error {
+		return s.autoTrader.dispatchDecisionWithRecord(&decision.Decision{Symbol: "BTCUSDT", Action: action}, &logger.DecisionAction{})
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := calculatePnLPercentage(tt.unrealizedPnl, tt.marginUsed)
+	s.Run("未配置时不限制", func() {
+		setup(50000, time.Minute)
+		s.NoError(closeLong("close_long"))
+		s.Equal([]string{"BTCUSDT_long"}, s.mockTrader.closedPositions)
+	})
 
-			// 使用精度比较，避免浮点数误差
-			if math.Abs(result-tt.expected) > 0.0001 {
-				t.Errorf("calculatePnLPercentage(%v, %v) = %v, want %v",
-					tt.unrealizedPnl, tt.marginUsed, result, tt.expected)
-			}
-		})
-	}
-}
+	s.autoTrader.SetMinHoldingMinutes(30)
+	s.Run("持仓未满最短时间时拒绝AI平仓", func() {
+		setup(50000, 5*time.Minute)
+		err := closeLong("close_long")
+		s.Require().Error(err)
+		s.Contains(err.Error(), "未达到最短持仓时间 30 分钟")
+		s.Error(closeLong("partial_close"))
+		s.Error(closeLong("flip_short"))
+		s.Empty(s.mockTrader.closedPositions)
+	})
 
-// TestCalculatePnLPercentage_RealWorldScenarios 真实场景测试
-func TestCalculatePnLPercentage_RealWorldScenarios(t *testing.T) {
-	t.Run("BTC 10倍杠杆，价格上涨2%", func(t *testing.T) {
-		// 开仓：1000 USDT 保证金，10倍杠杆 = 10000 USDT 仓位
-		// 价格上涨 2% = 200 USDT 盈利
-		// 收益率 = 200 / 1000 = 20%
-		result := calculatePnLPercentage(200.0, 1000.0)
-		expected := 20.0
-		if math.Abs(result-expected) > 0.0001 {
-			t.Errorf("BTC场景: got %v, want %v", result, expected)
-		}
+	s.Run("反向持仓不受影响", func() {
+		setup(50000, 5*time.Minute)
+		s.NoError(s.autoTrader.minHoldingBlocksClose(&decision.Decision{Symbol: "BTCUSDT", Action: "close_short"}, time.Now()))
 	})
 
-	t.Run("ETH 5倍杠杆，价格下跌3%", func(t *testing.T) {
-		// 开仓：2000 USDT 保证金，5倍杠杆 = 10000 USDT 仓位
-		// 价格下跌 3% = -300 USDT 亏损
-		// 收益率 = -300 / 2000 = -15%
-		result := calculatePnLPercentage(-300.0, 2000.0)
-		expected := -15.0
-		if math.Abs(result-expected) > 0.0001 {
-			t.Errorf("ETH场景: got %v, want %v", result, expected)
-		}
+	s.Run("标记价格已触及止损时放行", func() {
+		setup(47900, 5*time.Minute)
+		s.NoError(closeLong("close_long"))
+		s.Equal([]string{"BTCUSDT_long"}, s.mockTrader.closedPositions)
 	})
 
-	t.Run("SOL 20倍杠杆，价格上涨0.5%", func(t *testing.T) {
-		// 开仓：500 USDT 保证金，20倍杠杆 = 10000 USDT 仓位
-		// 价格上涨 0.5% = 50 USDT 盈利
-		// 收益率 = 50 / 500 = 10%
-		result := calculatePnLPercentage(50.0, 500.0)
-		expected := 10.0
-		if math.Abs(result-expected) > 0.0001 {
-			t.Errorf("SOL场景: got %v, want %v", result, expected)
-		}
+	s.Run("紧急平仓不受限制", func() {
+		setup(50000, 5*time.Minute)
+		s.NoError(s.autoTrader.emergencyClosePosition("BTCUSDT", "long"))
+		s.Equal([]string{"BTCUSDT_long"}, s.mockTrader.closedPositions)
+	})
+
+	s.Run("满足最短持仓时间后可以平仓", func() {
+		setup(50000, 31*time.Minute)
+		s.NoError(closeLong("close_long"))
+		s.Equal([]string{"BTCUSDT_long"}, s.mockTrader.closedPositions)
 	})
 }
@@ -0,0 +1,193 @@
+package trader
+
+import (
+	"log"
+	"time"
+
+	sysconfig "nofx/config"
+)
+
+// 周期净值基准滚动策略（baseline_reset_policy）
+const (
+	BaselineResetNone    = "none"    // 不滚动，只按初始余额统计总收益（默认）
+	BaselineResetWeekly  = "weekly"  // 每周一 00:00（UTC）将当时净值记录为新的周期基准
+	BaselineResetMonthly = "monthly" // 每月1日 00:00（UTC）将当时净值记录为新的周期基准
+)
+
+// ValidBaselineResetPolicy 校验周期净值基准滚动策略（空值表示使用默认 none）
+func ValidBaselineResetPolicy(policy string) bool {
+	return policy == "" || policy == BaselineResetNone || policy == BaselineResetWeekly || policy == BaselineResetMonthly
+}
+
+// baselineStore 周期净值基准历史的持久化操作（*sysconfig.Database 实现）
+type baselineStore interface {
+	GetLatestTraderBaseline(traderID string) (*sysconfig.TraderBaseline, error)
+	RollTraderBaseline(prev *sysconfig.TraderBaseline, endEquity float64, next *sysconfig.TraderBaseline) error
+	AdjustTraderBaseline(id int64, delta float64) error
+}
+
+// baselinePeriodStart t 所在周期的开始时间（UTC），不滚动时返回零值
+func baselinePeriodStart(policy string, t time.Time) time.Time {
+	t = t.UTC()
+	switch policy {
+	case BaselineResetWeekly:
+		daysSinceMonday := (int(t.Weekday()) + 6) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, time.UTC)
+	case BaselineResetMonthly:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Time{}
+}
+
+// SetBaselineResetPolicy 【功能】运行时更新周期净值基准滚动策略（下一个决策周期生效）
+func (at *AutoTrader) SetBaselineResetPolicy(policy string) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.BaselineResetPolicy = policy
+}
+
+// baselineResetPolicy 当前生效的周期净值基准滚动策略
+func (at *AutoTrader) baselineResetPolicy() string {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	if !ValidBaselineResetPolicy(at.config.BaselineResetPolicy) || at.config.BaselineResetPolicy == "" {
+		return BaselineResetNone
+	}
+	return at.config.BaselineResetPolicy
+}
+
+// currentPeriodBaseline 当前周期的净值基准（首次调用时从数据库加载，没有记录时为 nil）
+func (at *AutoTrader) currentPeriodBaseline(store baselineStore) *sysconfig.TraderBaseline {
+	at.mu.RLock()
+	baseline, loaded := at.periodBaseline, at.periodBaselineLoaded
+	at.mu.RUnlock()
+	if loaded {
+		return baseline
+	}
+
+	baseline, err := store.GetLatestTraderBaseline(at.id)
+	if err != nil {
+		log.Printf("⚠️ [%s] 加载周期净值基准失败: %v", at.name, err)
+		return nil
+	}
+	at.mu.Lock()
+	at.periodBaseline, at.periodBaselineLoaded = baseline, true
+	at.mu.Unlock()
+	return baseline
+}
+
+// rollBaselineIfDue 进入新周期时将当前账户净值记录为新的周期基准
+// 上一周期的记录保留并补记期末净值，initialBalance 保持不变（全部时间的总收益不受影响）
+func (at *AutoTrader) rollBaselineIfDue(now time.Time) {
+	policy := at.baselineResetPolicy()
+	periodStart := baselinePeriodStart(policy, now)
+	if periodStart.IsZero() {
+		return
+	}
+	store, ok := at.database.(baselineStore)
+	if !ok {
+		return
+	}
+
+	current := at.currentPeriodBaseline(store)
+	if current != nil && !current.PeriodStart.Before(periodStart) {
+		return
+	}
+
+	// 净值获取失败时不滚动（避免以错误的净值作为基准），下个周期重试
+	balance, err := at.trader.GetBalance()
+	if err != nil {
+		log.Printf("⚠️ [%s] 获取账户净值失败，暂不滚动周期净值基准: %v", at.name, err)
+		return
+	}
+	wallet, _ := balance["totalWalletBalance"].(float64)
+	unrealized, _ := balance["totalUnrealizedProfit"].(float64)
+	equity := wallet + unrealized
+	if equity <= 0 {
+		log.Printf("⚠️ [%s] 账户净值无效 (%.2f)，暂不滚动周期净值基准", at.name, equity)
+		return
+	}
+
+	next := &sysconfig.TraderBaseline{TraderID: at.id, Policy: policy, Baseline: equity, PeriodStart: periodStart}
+	if err := store.RollTraderBaseline(current, equity, next); err != nil {
+		log.Printf("❌ [%s] 记录周期净值基准失败: %v", at.name, err)
+		return
+	}
+	at.mu.Lock()
+	at.periodBaseline = next
+	at.mu.Unlock()
+
+	if current != nil {
+		log.Printf("📆 [%s] 周期净值基准已滚动: %s 起基准 %.2f USDT（上一周期 %.2f → %.2f）",
+			at.name, periodStart.Format("2006-01-02"), equity, current.Baseline, equity)
+	} else {
+		log.Printf("📆 [%s] 已记录周期净值基准: %s 起基准 %.2f USDT", at.name, periodStart.Format("2006-01-02"), equity)
+	}
+}
+
+// adjustPeriodBaselineForTransfers 外部充值/提现时等额调整当前周期基准，使周期盈亏只反映交易结果
+func (at *AutoTrader) adjustPeriodBaselineForTransfers(net float64) {
+	at.mu.Lock()
+	baseline := at.periodBaseline
+	if baseline != nil {
+		baseline.Baseline += net
+	}
+	at.mu.Unlock()
+	if baseline == nil || baseline.ID <= 0 {
+		return
+	}
+	if store, ok := at.database.(baselineStore); ok {
+		if err := store.AdjustTraderBaseline(baseline.ID, net); err != nil {
+			log.Printf("❌ [%s] 更新周期净值基准失败: %v", at.name, err)
+		}
+	}
+}
+
+// SetInitialBalanceBaseline 【功能】手动调整初始余额（全部时间盈亏基准），返回调整前的值。
+// transfer=true 表示调整对应一笔已知的充值/提现，当前周期基准和回撤峰值同步等额调整（与自动检测的资金变动一致）；
+// 否则只是纠正错误的基准，净值本身没有变化，周期基准和峰值保持不变。数据库由调用方（API）连同审计记录一起更新
+func (at *AutoTrader) SetInitialBalanceBaseline(newBaseline float64, transfer bool) float64 {
+	if at == nil {
+		return 0
+	}
+	at.mu.Lock()
+	previous := at.initialBalance
+	at.initialBalance = newBaseline
+	at.mu.Unlock()
+
+	delta := newBaseline - previous
+	log.Printf("🧾 [%s] 手动调整初始余额 %.2f → %.2f USDT（%+.2f）", at.name, previous, newBaseline, delta)
+	if transfer && delta != 0 {
+		at.adjustPeriodBaselineForTransfers(delta)
+		at.adjustPeakEquityForTransfers(delta)
+	}
+	return previous
+}
+
+// periodPnLFields 当前周期的盈亏字段（用于账户信息接口，未启用滚动或尚无基准时为 nil）
+func (at *AutoTrader) periodPnLFields(totalEquity float64) map[string]interface{} {
+	policy := at.baselineResetPolicy()
+	if policy == BaselineResetNone {
+		return nil
+	}
+	at.mu.RLock()
+	var baseline sysconfig.TraderBaseline
+	if at.periodBaseline != nil {
+		baseline = *at.periodBaseline
+	}
+	at.mu.RUnlock()
+	if baseline.Baseline <= 0 {
+		return nil
+	}
+	periodPnL := totalEquity - baseline.Baseline
+	return map[string]interface{}{
+		"baseline_reset_policy": policy,
+		"period_start":          baseline.PeriodStart,
+		"period_baseline":       baseline.Baseline,
+		"period_pnl":            periodPnL,
+		"period_pnl_pct":        periodPnL / baseline.Baseline * 100,
+	}
+}
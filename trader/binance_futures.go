@@ -45,7 +45,8 @@ func getBrOrderID() string {
 
 // FuturesTrader 币安合约交易器
 type FuturesTrader struct {
-	client *futures.Client
+	client   *futures.Client
+	clientMu sync.RWMutex // 保护 client 指针替换（时钟校准时发布新的 client，不修改正在使用的 client）
 
 	// 余额缓存
 	cachedBalance     map[string]interface{}
@@ -59,6 +60,9 @@ type FuturesTrader struct {
 
 	// 缓存有效期（15秒）
 	cacheDuration time.Duration
+
+	// 交易所服务器时钟偏移
+	clock serverClock
 }
 
 // NewFuturesTrader 创建合约交易器
@@ -70,13 +74,18 @@ func NewFuturesTrader(apiKey, secretKey string, userId string) *FuturesTrader {
 		client = hookRes.GetResult()
 	}
 
-	// 同步时间，避免 Timestamp ahead 错误
-	syncBinanceServerTime(client)
 	trader := &FuturesTrader{
 		client:        client,
 		cacheDuration: 15 * time.Second, // 15秒缓存
 	}
 
+	// 同步时间，避免 Timestamp ahead 错误
+	if offset, err := trader.SyncServerTime(); err != nil {
+		log.Printf("⚠️ 同步币安服务器时间失败: %v", err)
+	} else {
+		log.Printf("⏱ 已同步币安服务器时间，偏移 %dms", offset.Milliseconds())
+	}
+
 	// 设置双向持仓模式（Hedge Mode）
 	// 这是必需的，因为代码中使用了 PositionSide (LONG/SHORT)
 	if err := trader.setDualSidePosition(); err != nil {
@@ -89,9 +98,9 @@ func NewFuturesTrader(apiKey, secretKey string, userId string) *FuturesTrader {
 // setDualSidePosition 设置双向持仓模式（初始化时调用）
 func (t *FuturesTrader) setDualSidePosition() error {
 	// 尝试设置双向持仓模式
-	err := t.client.NewChangePositionModeService().
+	err := t.api().NewChangePositionModeService().
 		DualSide(true). // true = 双向持仓（Hedge Mode）
-		Do(context.Background())
+		Do(context.Background(), signedOpt())
 
 	if err != nil {
 		// 如果错误信息包含"No need to change"，说明已经是双向持仓模式
@@ -108,18 +117,36 @@ func (t *FuturesTrader) setDualSidePosition() error {
 	return nil
 }
 
-// syncBinanceServerTime 同步币安服务器时间，确保请求时间戳合法
-func syncBinanceServerTime(client *futures.Client) {
-	serverTime, err := client.NewServerTimeService().Do(context.Background())
+// GetServerTime 获取币安服务器时间（毫秒）
+func (t *FuturesTrader) GetServerTime() (int64, error) {
+	return t.api().NewServerTimeService().Do(context.Background())
+}
+
+// SyncServerTime 按币安服务器时间校准签名时间戳，返回偏移（服务器时间 - 本地时间）
+// go-binance 签名时使用 本地时间 - client.TimeOffset，因此 TimeOffset 取偏移的相反数
+func (t *FuturesTrader) SyncServerTime() (time.Duration, error) {
+	offset, err := t.clock.sync(t.GetServerTime)
 	if err != nil {
-		log.Printf("⚠️ 同步币安服务器时间失败: %v", err)
-		return
+		return 0, err
 	}
+	t.clientMu.Lock()
+	client := *t.client
+	client.TimeOffset = -offset.Milliseconds()
+	t.client = &client
+	t.clientMu.Unlock()
+	return offset, nil
+}
 
-	now := time.Now().UnixMilli()
-	offset := now - serverTime
-	client.TimeOffset = offset
-	log.Printf("⏱ 已同步币安服务器时间，偏移 %dms", offset)
+// api 当前使用的 client（go-binance 签名时无锁读取 TimeOffset，因此校准时整体替换 client 而不是原地修改）
+func (t *FuturesTrader) api() *futures.Client {
+	t.clientMu.RLock()
+	defer t.clientMu.RUnlock()
+	return t.client
+}
+
+// signedOpt 签名请求的参数（recvWindow 可通过 system_config recv_window_ms 放宽）
+func signedOpt() futures.RequestOption {
+	return futures.WithRecvWindow(RecvWindowMillis())
 }
 
 // GetBalance 获取账户余额（带缓存）
@@ -136,7 +163,7 @@ func (t *FuturesTrader) GetBalance() (map[string]interface{}, error) {
 
 	// 缓存过期或不存在，调用API
 	log.Printf("🔄 缓存过期，正在调用币安API获取账户余额...")
-	account, err := t.client.NewGetAccountService().Do(context.Background())
+	account, err := t.api().NewGetAccountService().Do(context.Background(), signedOpt())
 	if err != nil {
 		log.Printf("❌ 币安API调用失败: %v", err)
 		return nil, fmt.Errorf("获取账户信息失败: %w", err)
@@ -175,7 +202,7 @@ func (t *FuturesTrader) GetPositions() ([]map[string]interface{}, error) {
 
 	// 缓存过期或不存在，调用API
 	log.Printf("🔄 缓存过期，正在调用币安API获取持仓信息...")
-	positions, err := t.client.NewGetPositionRiskService().Do(context.Background())
+	positions, err := t.api().NewGetPositionRiskService().Do(context.Background(), signedOpt())
 	if err != nil {
 		return nil, fmt.Errorf("获取持仓失败: %w", err)
 	}
@@ -215,6 +242,20 @@ func (t *FuturesTrader) GetPositions() ([]map[string]interface{}, error) {
 	return result, nil
 }
 
+// GetMarginMode 查询交易所中该币种当前的仓位模式（positionRisk 的 marginType: cross/isolated）
+func (t *FuturesTrader) GetMarginMode(symbol string) (bool, error) {
+	positions, err := t.api().NewGetPositionRiskService().Symbol(symbol).Do(context.Background(), signedOpt())
+	if err != nil {
+		return false, fmt.Errorf("查询仓位模式失败: %w", err)
+	}
+	for _, pos := range positions {
+		if pos.Symbol == symbol {
+			return strings.EqualFold(pos.MarginType, "cross") || strings.EqualFold(pos.MarginType, "crossed"), nil
+		}
+	}
+	return false, fmt.Errorf("查询仓位模式失败: 未返回 %s 的仓位信息", symbol)
+}
+
 // SetMarginMode 设置仓位模式
 func (t *FuturesTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
 	var marginType futures.MarginType
@@ -225,10 +266,10 @@ func (t *FuturesTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
 	}
 
 	// 尝试设置仓位模式
-	err := t.client.NewChangeMarginTypeService().
+	err := t.api().NewChangeMarginTypeService().
 		Symbol(symbol).
 		MarginType(marginType).
-		Do(context.Background())
+		Do(context.Background(), signedOpt())
 
 	marginModeStr := "全仓"
 	if !isCrossMargin {
@@ -289,10 +330,10 @@ func (t *FuturesTrader) SetLeverage(symbol string, leverage int) error {
 	}
 
 	// 切换杠杆
-	_, err = t.client.NewChangeLeverageService().
+	_, err = t.api().NewChangeLeverageService().
 		Symbol(symbol).
 		Leverage(leverage).
-		Do(context.Background())
+		Do(context.Background(), signedOpt())
 
 	if err != nil {
 		// 如果错误信息包含"No need to change"，说明杠杆已经是目标值
@@ -314,8 +355,11 @@ func (t *FuturesTrader) SetLeverage(symbol string, leverage int) error {
 
 // OpenLong 开多仓
 func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	if err := throttleOrder("binance", "OpenLong"); err != nil {
+		return nil, err
+	}
 	// 先取消该币种的所有委托单（清理旧的止损止盈单）
-	if err := t.CancelAllOrders(symbol); err != nil {
+	if err := t.cancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
 	}
 
@@ -344,14 +388,14 @@ func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int)
 	}
 
 	// 创建市价买入订单（使用br ID）
-	order, err := t.client.NewCreateOrderService().
+	order, err := t.api().NewCreateOrderService().
 		Symbol(symbol).
 		Side(futures.SideTypeBuy).
 		PositionSide(futures.PositionSideTypeLong).
 		Type(futures.OrderTypeMarket).
 		Quantity(quantityStr).
 		NewClientOrderID(getBrOrderID()).
-		Do(context.Background())
+		Do(context.Background(), signedOpt())
 
 	if err != nil {
 		return nil, fmt.Errorf("开多仓失败: %w", err)
@@ -359,6 +403,7 @@ func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int)
 
 	log.Printf("✓ 开多仓成功: %s 数量: %s", symbol, quantityStr)
 	log.Printf("  订单ID: %d", order.OrderID)
+	t.invalidatePositionsCache()
 
 	result := make(map[string]interface{})
 	result["orderId"] = order.OrderID
@@ -369,8 +414,11 @@ func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int)
 
 // OpenShort 开空仓
 func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	if err := throttleOrder("binance", "OpenShort"); err != nil {
+		return nil, err
+	}
 	// 先取消该币种的所有委托单（清理旧的止损止盈单）
-	if err := t.CancelAllOrders(symbol); err != nil {
+	if err := t.cancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
 	}
 
@@ -399,14 +447,14 @@ func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int)
 	}
 
 	// 创建市价卖出订单（使用br ID）
-	order, err := t.client.NewCreateOrderService().
+	order, err := t.api().NewCreateOrderService().
 		Symbol(symbol).
 		Side(futures.SideTypeSell).
 		PositionSide(futures.PositionSideTypeShort).
 		Type(futures.OrderTypeMarket).
 		Quantity(quantityStr).
 		NewClientOrderID(getBrOrderID()).
-		Do(context.Background())
+		Do(context.Background(), signedOpt())
 
 	if err != nil {
 		return nil, fmt.Errorf("开空仓失败: %w", err)
@@ -414,6 +462,7 @@ func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int)
 
 	log.Printf("✓ 开空仓成功: %s 数量: %s", symbol, quantityStr)
 	log.Printf("  订单ID: %d", order.OrderID)
+	t.invalidatePositionsCache()
 
 	result := make(map[string]interface{})
 	result["orderId"] = order.OrderID
@@ -424,6 +473,7 @@ func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int)
 
 // CloseLong 平多仓
 func (t *FuturesTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+	throttleRiskOrder("binance", "CloseLong")
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -431,9 +481,9 @@ func (t *FuturesTrader) CloseLong(symbol string, quantity float64) (map[string]i
 			return nil, err
 		}
 
-		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "long" {
-				quantity = pos["positionAmt"].(float64)
+		for _, pos := range NormalizePositions(positions) {
+			if pos.Symbol == symbol && pos.Side == "long" {
+				quantity = pos.Quantity
 				break
 			}
 		}
@@ -450,23 +500,24 @@ func (t *FuturesTrader) CloseLong(symbol string, quantity float64) (map[string]i
 	}
 
 	// 创建市价卖出订单（平多，使用br ID）
-	order, err := t.client.NewCreateOrderService().
+	order, err := t.api().NewCreateOrderService().
 		Symbol(symbol).
 		Side(futures.SideTypeSell).
 		PositionSide(futures.PositionSideTypeLong).
 		Type(futures.OrderTypeMarket).
 		Quantity(quantityStr).
 		NewClientOrderID(getBrOrderID()).
-		Do(context.Background())
+		Do(context.Background(), signedOpt())
 
 	if err != nil {
 		return nil, fmt.Errorf("平多仓失败: %w", err)
 	}
 
 	log.Printf("✓ 平多仓成功: %s 数量: %s", symbol, quantityStr)
+	t.invalidatePositionsCache()
 
 	// 平仓后取消该币种的所有挂单（止损止盈单）
-	if err := t.CancelAllOrders(symbol); err != nil {
+	if err := t.cancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消挂单失败: %v", err)
 	}
 
@@ -479,6 +530,7 @@ func (t *FuturesTrader) CloseLong(symbol string, quantity float64) (map[string]i
 
 // CloseShort 平空仓
 func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+	throttleRiskOrder("binance", "CloseShort")
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -486,9 +538,9 @@ func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (map[string]
 			return nil, err
 		}
 
-		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "short" {
-				quantity = -pos["positionAmt"].(float64) // 空仓数量是负的，取绝对值
+		for _, pos := range NormalizePositions(positions) {
+			if pos.Symbol == symbol && pos.Side == "short" {
+				quantity = pos.Quantity
 				break
 			}
 		}
@@ -505,23 +557,24 @@ func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (map[string]
 	}
 
 	// 创建市价买入订单（平空，使用br ID）
-	order, err := t.client.NewCreateOrderService().
+	order, err := t.api().NewCreateOrderService().
 		Symbol(symbol).
 		Side(futures.SideTypeBuy).
 		PositionSide(futures.PositionSideTypeShort).
 		Type(futures.OrderTypeMarket).
 		Quantity(quantityStr).
 		NewClientOrderID(getBrOrderID()).
-		Do(context.Background())
+		Do(context.Background(), signedOpt())
 
 	if err != nil {
 		return nil, fmt.Errorf("平空仓失败: %w", err)
 	}
 
 	log.Printf("✓ 平空仓成功: %s 数量: %s", symbol, quantityStr)
+	t.invalidatePositionsCache()
 
 	// 平仓后取消该币种的所有挂单（止损止盈单）
-	if err := t.CancelAllOrders(symbol); err != nil {
+	if err := t.cancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消挂单失败: %v", err)
 	}
 
@@ -532,12 +585,59 @@ func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (map[string]
 	return result, nil
 }
 
+// ReducePosition 按数量市价减仓（不撤销止盈止损等其余挂单）
+// 双向持仓模式下按 positionSide 反向下单即为平仓，不会反向开仓（该模式下不能再传 reduceOnly）
+func (t *FuturesTrader) ReducePosition(symbol, side string, quantity float64) (map[string]interface{}, error) {
+	throttleRiskOrder("binance", "ReducePosition")
+	log.Printf("📊 减仓: %s %s 数量: %.4f", symbol, side, quantity)
+
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	orderSide, positionSide := futures.SideTypeSell, futures.PositionSideTypeLong
+	if side == "short" {
+		orderSide, positionSide = futures.SideTypeBuy, futures.PositionSideTypeShort
+	}
+	order, err := t.api().NewCreateOrderService().
+		Symbol(symbol).
+		Side(orderSide).
+		PositionSide(positionSide).
+		Type(futures.OrderTypeMarket).
+		Quantity(quantityStr).
+		NewClientOrderID(getBrOrderID()).
+		Do(context.Background(), signedOpt())
+	if err != nil {
+		return nil, fmt.Errorf("减仓失败: %w", err)
+	}
+
+	log.Printf("✓ 减仓成功: %s %s 数量: %s 订单ID: %d", symbol, side, quantityStr, order.OrderID)
+	t.invalidatePositionsCache()
+
+	result := make(map[string]interface{})
+	result["orderId"] = order.OrderID
+	result["symbol"] = order.Symbol
+	result["status"] = order.Status
+	return result, nil
+}
+
+// invalidatePositionsCache 下单成功后立即失效本地持仓缓存，确保后续读取到最新状态
+func (t *FuturesTrader) invalidatePositionsCache() {
+	t.positionsCacheMutex.Lock()
+	t.positionsCacheTime = time.Time{}
+	t.positionsCacheMutex.Unlock()
+}
+
 // CancelStopLossOrders 仅取消止损单（不影响止盈单）
 func (t *FuturesTrader) CancelStopLossOrders(symbol string) error {
+	if err := throttleOrder("binance", "CancelStopLossOrders"); err != nil {
+		return err
+	}
 	// 获取该币种的所有未完成订单
-	orders, err := t.client.NewListOpenOrdersService().
+	orders, err := t.api().NewListOpenOrdersService().
 		Symbol(symbol).
-		Do(context.Background())
+		Do(context.Background(), signedOpt())
 
 	if err != nil {
 		return fmt.Errorf("获取未完成订单失败: %w", err)
@@ -551,10 +651,10 @@ func (t *FuturesTrader) CancelStopLossOrders(symbol string) error {
 
 		// 只取消止损订单（不取消止盈订单）
 		if orderType == futures.OrderTypeStopMarket || orderType == futures.OrderTypeStop {
-			_, err := t.client.NewCancelOrderService().
+			_, err := t.api().NewCancelOrderService().
 				Symbol(symbol).
 				OrderID(order.OrderID).
-				Do(context.Background())
+				Do(context.Background(), signedOpt())
 
 			if err != nil {
 				errMsg := fmt.Sprintf("订单ID %d: %v", order.OrderID, err)
@@ -584,10 +684,13 @@ func (t *FuturesTrader) CancelStopLossOrders(symbol string) error {
 
 // CancelTakeProfitOrders 仅取消止盈单（不影响止损单）
 func (t *FuturesTrader) CancelTakeProfitOrders(symbol string) error {
+	if err := throttleOrder("binance", "CancelTakeProfitOrders"); err != nil {
+		return err
+	}
 	// 获取该币种的所有未完成订单
-	orders, err := t.client.NewListOpenOrdersService().
+	orders, err := t.api().NewListOpenOrdersService().
 		Symbol(symbol).
-		Do(context.Background())
+		Do(context.Background(), signedOpt())
 
 	if err != nil {
 		return fmt.Errorf("获取未完成订单失败: %w", err)
@@ -601,10 +704,10 @@ func (t *FuturesTrader) CancelTakeProfitOrders(symbol string) error {
 
 		// 只取消止盈订单（不取消止损订单）
 		if orderType == futures.OrderTypeTakeProfitMarket || orderType == futures.OrderTypeTakeProfit {
-			_, err := t.client.NewCancelOrderService().
+			_, err := t.api().NewCancelOrderService().
 				Symbol(symbol).
 				OrderID(order.OrderID).
-				Do(context.Background())
+				Do(context.Background(), signedOpt())
 
 			if err != nil {
 				errMsg := fmt.Sprintf("订单ID %d: %v", order.OrderID, err)
@@ -634,9 +737,17 @@ func (t *FuturesTrader) CancelTakeProfitOrders(symbol string) error {
 
 // CancelAllOrders 取消该币种的所有挂单
 func (t *FuturesTrader) CancelAllOrders(symbol string) error {
-	err := t.client.NewCancelAllOpenOrdersService().
+	if err := throttleOrder("binance", "CancelAllOrders"); err != nil {
+		return err
+	}
+	return t.cancelAllOrders(symbol)
+}
+
+// cancelAllOrders 取消该币种的所有挂单（不取限速令牌：开仓/平仓内部清理旧委托时使用，一次下单只占一个令牌）
+func (t *FuturesTrader) cancelAllOrders(symbol string) error {
+	err := t.api().NewCancelAllOpenOrdersService().
 		Symbol(symbol).
-		Do(context.Background())
+		Do(context.Background(), signedOpt())
 
 	if err != nil {
 		return fmt.Errorf("取消挂单失败: %w", err)
@@ -648,10 +759,13 @@ func (t *FuturesTrader) CancelAllOrders(symbol string) error {
 
 // CancelStopOrders 取消该币种的止盈/止损单（用于调整止盈止损位置）
 func (t *FuturesTrader) CancelStopOrders(symbol string) error {
+	if err := throttleOrder("binance", "CancelStopOrders"); err != nil {
+		return err
+	}
 	// 获取该币种的所有未完成订单
-	orders, err := t.client.NewListOpenOrdersService().
+	orders, err := t.api().NewListOpenOrdersService().
 		Symbol(symbol).
-		Do(context.Background())
+		Do(context.Background(), signedOpt())
 
 	if err != nil {
 		return fmt.Errorf("获取未完成订单失败: %w", err)
@@ -668,10 +782,10 @@ func (t *FuturesTrader) CancelStopOrders(symbol string) error {
 			orderType == futures.OrderTypeStop ||
 			orderType == futures.OrderTypeTakeProfit {
 
-			_, err := t.client.NewCancelOrderService().
+			_, err := t.api().NewCancelOrderService().
 				Symbol(symbol).
 				OrderID(order.OrderID).
-				Do(context.Background())
+				Do(context.Background(), signedOpt())
 
 			if err != nil {
 				log.Printf("  ⚠ 取消订单 %d 失败: %v", order.OrderID, err)
@@ -695,7 +809,7 @@ func (t *FuturesTrader) CancelStopOrders(symbol string) error {
 
 // GetMarketPrice 获取市场价格
 func (t *FuturesTrader) GetMarketPrice(symbol string) (float64, error) {
-	prices, err := t.client.NewListPricesService().Symbol(symbol).Do(context.Background())
+	prices, err := t.api().NewListPricesService().Symbol(symbol).Do(context.Background())
 	if err != nil {
 		return 0, fmt.Errorf("获取价格失败: %w", err)
 	}
@@ -722,6 +836,7 @@ func (t *FuturesTrader) CalculatePositionSize(balance, riskPercent, price float6
 
 // SetStopLoss 设置止损单
 func (t *FuturesTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	throttleRiskOrder("binance", "SetStopLoss")
 	var side futures.SideType
 	var posSide futures.PositionSideType
 
@@ -739,7 +854,7 @@ func (t *FuturesTrader) SetStopLoss(symbol string, positionSide string, quantity
 		return err
 	}
 
-	_, err = t.client.NewCreateOrderService().
+	_, err = t.api().NewCreateOrderService().
 		Symbol(symbol).
 		Side(side).
 		PositionSide(posSide).
@@ -748,7 +863,7 @@ func (t *FuturesTrader) SetStopLoss(symbol string, positionSide string, quantity
 		Quantity(quantityStr).
 		WorkingType(futures.WorkingTypeContractPrice).
 		ClosePosition(true).
-		Do(context.Background())
+		Do(context.Background(), signedOpt())
 
 	if err != nil {
 		return fmt.Errorf("设置止损失败: %w", err)
@@ -760,6 +875,7 @@ func (t *FuturesTrader) SetStopLoss(symbol string, positionSide string, quantity
 
 // SetTakeProfit 设置止盈单
 func (t *FuturesTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	throttleRiskOrder("binance", "SetTakeProfit")
 	var side futures.SideType
 	var posSide futures.PositionSideType
 
@@ -777,7 +893,7 @@ func (t *FuturesTrader) SetTakeProfit(symbol string, positionSide string, quanti
 		return err
 	}
 
-	_, err = t.client.NewCreateOrderService().
+	_, err = t.api().NewCreateOrderService().
 		Symbol(symbol).
 		Side(side).
 		PositionSide(posSide).
@@ -786,7 +902,7 @@ func (t *FuturesTrader) SetTakeProfit(symbol string, positionSide string, quanti
 		Quantity(quantityStr).
 		WorkingType(futures.WorkingTypeContractPrice).
 		ClosePosition(true).
-		Do(context.Background())
+		Do(context.Background(), signedOpt())
 
 	if err != nil {
 		return fmt.Errorf("设置止盈失败: %w", err)
@@ -796,6 +912,17 @@ func (t *FuturesTrader) SetTakeProfit(symbol string, positionSide string, quanti
 	return nil
 }
 
+// SetOCO 设置联动的止损/止盈单：两者均为 closePosition（Close-All）条件单，
+// 任一触发平仓后交易所自动撤销另一个，无需本地模拟
+func (t *FuturesTrader) SetOCO(symbol, side string, quantity, stopPrice, takeProfitPrice float64) error {
+	return placeOCOLegs(t, symbol, side, quantity, stopPrice, takeProfitPrice)
+}
+
+// NativeOCO 实现 NativeOCOTrader 接口
+func (t *FuturesTrader) NativeOCO() bool {
+	return true
+}
+
 // GetMinNotional 获取最小名义价值（Binance要求）
 func (t *FuturesTrader) GetMinNotional(symbol string) float64 {
 	// 使用保守的默认值 10 USDT，确保订单能够通过交易所验证
@@ -824,7 +951,7 @@ func (t *FuturesTrader) CheckMinNotional(symbol string, quantity float64) error
 
 // GetSymbolPrecision 获取交易对的数量精度
 func (t *FuturesTrader) GetSymbolPrecision(symbol string) (int, error) {
-	exchangeInfo, err := t.client.NewExchangeInfoService().Do(context.Background())
+	exchangeInfo, err := t.api().NewExchangeInfoService().Do(context.Background())
 	if err != nil {
 		return 0, fmt.Errorf("获取交易规则失败: %w", err)
 	}
@@ -902,10 +1029,57 @@ func (t *FuturesTrader) FormatQuantity(symbol string, quantity float64) (string,
 	return fmt.Sprintf(format, quantity), nil
 }
 
-// GetOpenOrders 获取当前未成交的委托单（Binance暂不实现，仅为接口兼容）
+// GetOpenOrders 获取当前未成交的委托单（含止盈止损单），symbol 为空时查询所有币种
+// 止损/止盈单的 type 统一为 stop_loss/take_profit，price 为触发价，与其它交易所保持一致
 func (t *FuturesTrader) GetOpenOrders(symbol string) ([]map[string]interface{}, error) {
-	log.Printf("⚠️  Binance GetOpenOrders 暂不实现")
-	return []map[string]interface{}{}, nil
+	service := t.api().NewListOpenOrdersService()
+	if symbol != "" {
+		service = service.Symbol(symbol)
+	}
+	orders, err := service.Do(context.Background(), signedOpt())
+	if err != nil {
+		return nil, fmt.Errorf("获取未完成订单失败: %w", err)
+	}
+
+	result := make([]map[string]interface{}, 0, len(orders))
+	for _, order := range orders {
+		price, _ := strconv.ParseFloat(order.Price, 64)
+		quantity, _ := strconv.ParseFloat(order.OrigQuantity, 64)
+		filledSize, _ := strconv.ParseFloat(order.ExecutedQuantity, 64)
+		avgPrice, _ := strconv.ParseFloat(order.AvgPrice, 64)
+
+		orderType := strings.ToLower(string(order.Type))
+		category := "normal"
+		switch order.Type {
+		case futures.OrderTypeStopMarket, futures.OrderTypeStop:
+			orderType, category = "stop_loss", "plan"
+		case futures.OrderTypeTakeProfitMarket, futures.OrderTypeTakeProfit:
+			orderType, category = "take_profit", "plan"
+		case futures.OrderTypeTrailingStopMarket:
+			category = "plan"
+		}
+		if category == "plan" {
+			price, _ = strconv.ParseFloat(order.StopPrice, 64)
+		}
+
+		result = append(result, map[string]interface{}{
+			"order_id":       order.OrderID,
+			"symbol":         order.Symbol,
+			"type":           orderType,
+			"price":          price,
+			"quantity":       quantity,
+			"filled_size":    filledSize,
+			"avg_price":      avgPrice,
+			"side":           strings.ToLower(string(order.Side)),
+			"pos_side":       strings.ToLower(string(order.PositionSide)),
+			"reduce_only":    order.ReduceOnly || order.ClosePosition,
+			"status":         strings.ToLower(string(order.Status)),
+			"created_at":     order.Time,
+			"client_oid":     order.ClientOrderID,
+			"order_category": category,
+		})
+	}
+	return result, nil
 }
 
 // GetOrderHistory 获取历史订单（Binance暂不实现，仅为接口兼容）
@@ -914,14 +1088,153 @@ func (t *FuturesTrader) GetOrderHistory(symbol string, startTime, endTime int64)
 	return []map[string]interface{}{}, nil
 }
 
+// GetBalanceHistory 获取资金流水（基于 /fapi/v1/income）
+func (t *FuturesTrader) GetBalanceHistory(startTime, endTime int64) ([]map[string]interface{}, error) {
+	now := time.Now().UnixMilli()
+	if endTime == 0 {
+		endTime = now
+	}
+	if startTime == 0 {
+		startTime = now - 7*24*60*60*1000 // 默认最近7天
+	}
+
+	incomes, err := t.api().NewGetIncomeHistoryService().
+		StartTime(startTime).
+		EndTime(endTime).
+		Limit(1000).
+		Do(context.Background(), signedOpt())
+	if err != nil {
+		return nil, fmt.Errorf("获取资金流水失败: %w", err)
+	}
+
+	result := make([]map[string]interface{}, 0, len(incomes))
+	for _, income := range incomes {
+		amount, _ := strconv.ParseFloat(income.Income, 64)
+		result = append(result, map[string]interface{}{
+			"time":     income.Time,
+			"type":     classifyIncomeType(income.IncomeType, amount),
+			"amount":   amount,
+			"asset":    income.Asset,
+			"symbol":   income.Symbol,
+			"raw_type": income.IncomeType,
+		})
+	}
+	return result, nil
+}
+
+// GetLeverageBrackets 获取杠杆分层（基于 /fapi/v1/leverageBracket）
+func (t *FuturesTrader) GetLeverageBrackets(symbol string) ([]LeverageBracket, error) {
+	res, err := t.api().NewGetLeverageBracketService().Symbol(symbol).Do(context.Background(), signedOpt())
+	if err != nil {
+		return nil, fmt.Errorf("获取杠杆分层失败: %w", err)
+	}
+
+	result := []LeverageBracket{}
+	for _, lb := range res {
+		if lb.Symbol != symbol {
+			continue
+		}
+		for _, b := range lb.Brackets {
+			result = append(result, LeverageBracket{
+				Bracket:          b.Bracket,
+				MaxLeverage:      b.InitialLeverage,
+				NotionalFloor:    b.NotionalFloor,
+				NotionalCap:      b.NotionalCap,
+				MaintMarginRatio: b.MaintMarginRatio,
+			})
+		}
+	}
+	return result, nil
+}
+
+// GetPriceTickSize 获取价格最小变动单位（基于 exchangeInfo 的 PRICE_FILTER）
+func (t *FuturesTrader) GetPriceTickSize(symbol string) (float64, error) {
+	exchangeInfo, err := t.api().NewExchangeInfoService().Do(context.Background())
+	if err != nil {
+		return 0, fmt.Errorf("获取交易规则失败: %w", err)
+	}
+
+	for _, s := range exchangeInfo.Symbols {
+		if s.Symbol != symbol {
+			continue
+		}
+		for _, filter := range s.Filters {
+			if filter["filterType"] == "PRICE_FILTER" {
+				tickSizeStr, _ := filter["tickSize"].(string)
+				tickSize, err := strconv.ParseFloat(tickSizeStr, 64)
+				if err != nil {
+					return 0, fmt.Errorf("解析 %s tickSize 失败: %w", symbol, err)
+				}
+				return tickSize, nil
+			}
+		}
+	}
+	return 0, nil
+}
+
+// GetOrderBookDepth 获取盘口深度（/fapi/v1/depth，levels 取值 5/10/20/50/100/500/1000）
+func (t *FuturesTrader) GetOrderBookDepth(symbol string, levels int) (*OrderBook, error) {
+	depth, err := t.api().NewDepthService().Symbol(symbol).Limit(levels).Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取盘口深度失败: %w", err)
+	}
+
+	book := &OrderBook{}
+	for _, bid := range depth.Bids {
+		price, _ := strconv.ParseFloat(bid.Price, 64)
+		qty, _ := strconv.ParseFloat(bid.Quantity, 64)
+		book.Bids = append(book.Bids, OrderBookLevel{Price: price, Quantity: qty})
+	}
+	for _, ask := range depth.Asks {
+		price, _ := strconv.ParseFloat(ask.Price, 64)
+		qty, _ := strconv.ParseFloat(ask.Quantity, 64)
+		book.Asks = append(book.Asks, OrderBookLevel{Price: price, Quantity: qty})
+	}
+	return book, nil
+}
+
+// classifyIncomeType 将币安/Aster 的 incomeType 映射为统一的资金流水类型
+func classifyIncomeType(incomeType string, amount float64) string {
+	switch incomeType {
+	case "TRANSFER":
+		if amount >= 0 {
+			return BalanceEventDeposit
+		}
+		return BalanceEventWithdraw
+	case "REALIZED_PNL":
+		return BalanceEventRealizedPnL
+	case "FUNDING_FEE":
+		return BalanceEventFundingFee
+	case "COMMISSION":
+		return BalanceEventCommission
+	default:
+		return BalanceEventOther
+	}
+}
+
 // PlaceLimitOrder 下限价委托开仓单 (Binance Stub)
 func (t *FuturesTrader) PlaceLimitOrder(symbol string, side, tradeSide string, quantity float64, price float64, leverage int) (map[string]interface{}, error) {
 	return nil, fmt.Errorf("PlaceLimitOrder not implemented for Binance Futures yet")
 }
 
-// CancelOrder 取消指定的委托单 (Binance Stub)
+// CancelOrder 取消指定的委托单（orderId 为 GetOpenOrders 返回的 order_id）
 func (t *FuturesTrader) CancelOrder(symbol, orderId string) error {
-	return fmt.Errorf("CancelOrder not implemented for Binance Futures yet")
+	if err := throttleOrder("binance", "CancelOrder"); err != nil {
+		return err
+	}
+	id, err := strconv.ParseInt(orderId, 10, 64)
+	if err != nil {
+		return fmt.Errorf("无效的订单ID %q: %w", orderId, err)
+	}
+	_, err = t.api().NewCancelOrderService().
+		Symbol(symbol).
+		OrderID(id).
+		Do(context.Background(), signedOpt())
+	if err != nil {
+		return fmt.Errorf("取消订单失败: %w", err)
+	}
+	log.Printf("  ✓ 已取消订单 %s (订单ID: %d)", symbol, id)
+	return nil
 }
 
 
@@ -418,3 +418,91 @@ func TestGetBrOrderID(t *testing.T) {
 		ids[id] = true
 	}
 }
+
+// TestFuturesTrader_GetOpenOrders 测试查询当前委托：止盈止损单统一类型并以触发价作为价格
+func TestFuturesTrader_GetOpenOrders(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var respBody interface{} = map[string]interface{}{}
+		if r.URL.Path == "/fapi/v1/openOrders" {
+			respBody = []map[string]interface{}{
+				{"symbol": "BTCUSDT", "orderId": 1, "type": "LIMIT", "side": "BUY", "positionSide": "LONG", "price": "48000", "origQty": "0.01", "status": "NEW"},
+				{"symbol": "BTCUSDT", "orderId": 2, "type": "STOP_MARKET", "side": "SELL", "positionSide": "LONG", "price": "0", "stopPrice": "45000", "origQty": "0.01", "closePosition": true, "status": "NEW"},
+				{"symbol": "BTCUSDT", "orderId": 3, "type": "TAKE_PROFIT_MARKET", "side": "SELL", "positionSide": "LONG", "price": "0", "stopPrice": "55000", "origQty": "0.01", "reduceOnly": true, "status": "NEW"},
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(respBody)
+	}))
+	defer mockServer.Close()
+
+	client := futures.NewClient("test_api_key", "test_secret_key")
+	client.BaseURL = mockServer.URL
+	client.HTTPClient = mockServer.Client()
+	trader := &FuturesTrader{client: client}
+
+	orders, err := trader.GetOpenOrders("BTCUSDT")
+	assert.NoError(t, err)
+	assert.Len(t, orders, 3)
+
+	assert.Equal(t, "limit", orders[0]["type"])
+	assert.Equal(t, 48000.0, orders[0]["price"])
+	assert.Equal(t, "long", orderDirection(orders[0]))
+	assert.False(t, isProtectiveOrder(orders[0]))
+
+	assert.Equal(t, "stop_loss", orders[1]["type"])
+	assert.Equal(t, 45000.0, orders[1]["price"])
+	assert.True(t, isClosingOrder(orders[1]))
+
+	hasStopLoss, hasTakeProfit := hasProtectiveOrders(orders)
+	assert.True(t, hasStopLoss)
+	assert.True(t, hasTakeProfit)
+	assert.Equal(t, 55000.0, orders[2]["price"])
+}
+
+// TestFuturesTrader_CloseInvalidatesPositionsCache 测试平仓成功后持仓缓存立即失效，避免残仓检查读到旧持仓
+func TestFuturesTrader_CloseInvalidatesPositionsCache(t *testing.T) {
+	suite := NewBinanceFuturesTestSuite(t)
+	defer suite.Cleanup()
+
+	trader := suite.Trader.(*FuturesTrader)
+	trader.cacheDuration = 15 * time.Second
+
+	_, err := trader.GetPositions()
+	assert.NoError(t, err)
+	assert.False(t, trader.positionsCacheTime.IsZero())
+
+	_, err = trader.CloseLong("BTCUSDT", 0.1)
+	assert.NoError(t, err)
+	assert.True(t, trader.positionsCacheTime.IsZero(), "平仓后应失效持仓缓存")
+}
+
+// TestFuturesTrader_SyncServerTime 测试时钟校准发布新的 client，不修改请求中正在使用的 client
+func TestFuturesTrader_SyncServerTime(t *testing.T) {
+	const skew = int64(60000) // 模拟本地时钟比服务器慢60秒
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]int64{"serverTime": time.Now().UnixMilli() + skew})
+	}))
+	defer mockServer.Close()
+
+	client := futures.NewClient("test_api_key", "test_secret_key")
+	client.BaseURL = mockServer.URL
+	client.HTTPClient = mockServer.Client()
+	trader := &FuturesTrader{client: client}
+
+	// 校准与请求并发进行（go test -race 下不应报告数据竞争）
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			_ = trader.api().TimeOffset
+		}
+	}()
+	offset, err := trader.SyncServerTime()
+	<-done
+
+	assert.NoError(t, err)
+	assert.InDelta(t, skew, offset.Milliseconds(), 1000)
+	assert.InDelta(t, -skew, trader.api().TimeOffset, 1000, "签名时间戳应按服务器时间修正")
+	assert.Equal(t, int64(0), client.TimeOffset, "不应原地修改已发布的 client")
+	assert.Equal(t, mockServer.URL, trader.api().BaseURL)
+}
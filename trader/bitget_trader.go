@@ -38,6 +38,9 @@ type BitgetTrader struct {
 
 	// 缓存有效期（15秒）
 	cacheDuration time.Duration
+
+	// 交易所服务器时钟偏移（签名时间戳按此修正）
+	clock serverClock
 }
 
 // min 返回两个整数中的较小值
@@ -127,8 +130,8 @@ func (t *BitgetTrader) request(method, endpoint string, params map[string]string
 		requestPath += "?" + queryString
 	}
 
-	// 生成时间戳（毫秒）
-	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	// 生成时间戳（毫秒，按服务器时钟偏移修正）
+	timestamp := strconv.FormatInt(t.clock.nowMillis(), 10)
 
 	// 生成签名
 	sign := t.sign(timestamp, method, requestPath, bodyStr)
@@ -156,7 +159,7 @@ func (t *BitgetTrader) request(method, endpoint string, params map[string]string
 
 	// 检查HTTP状态码
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("http %d: %s", resp.StatusCode, string(respBody))
+		return nil, newExchangeHTTPError("http", resp.StatusCode, respBody)
 	}
 
 	// 解析响应检查业务错误码
@@ -168,7 +171,7 @@ func (t *BitgetTrader) request(method, endpoint string, params map[string]string
 	code, ok := result["code"].(string)
 	if !ok || code != "00000" {
 		msg, _ := result["msg"].(string)
-		return nil, fmt.Errorf("bitget api error: code=%s, msg=%s", code, msg)
+		return nil, &exchangeAPIError{status: resp.StatusCode, code: code, text: fmt.Sprintf("bitget api error: code=%s, msg=%s", code, msg)}
 	}
 
 	return respBody, nil
@@ -338,6 +341,9 @@ func (t *BitgetTrader) GetPositions() ([]map[string]interface{}, error) {
 
 // OpenLong 开多仓
 func (t *BitgetTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	if err := throttleOrder("bitget", "OpenLong"); err != nil {
+		return nil, err
+	}
 	log.Printf("📊 开多仓: %s 数量: %.4f 杠杆: %dx", symbol, quantity, leverage)
 
 	// 先尝试设置杠杆（如果交易所已是该杠杆，会返回“无需变更”之类的提示，可安全忽略）
@@ -396,6 +402,9 @@ func (t *BitgetTrader) OpenLong(symbol string, quantity float64, leverage int) (
 
 // OpenShort 开空仓
 func (t *BitgetTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	if err := throttleOrder("bitget", "OpenShort"); err != nil {
+		return nil, err
+	}
 	log.Printf("📊 开空仓: %s 数量: %.4f 杠杆: %dx", symbol, quantity, leverage)
 
 	// 同步设置杠杆
@@ -453,6 +462,9 @@ func (t *BitgetTrader) OpenShort(symbol string, quantity float64, leverage int)
 // side: "buy"(做多) | "sell"(做空)
 // tradeSide: "open"(开仓) | "close"(平仓)
 func (t *BitgetTrader) PlaceLimitOrder(symbol string, side, tradeSide string, quantity float64, price float64, leverage int) (map[string]interface{}, error) {
+	if err := throttleOrder("bitget", "PlaceLimitOrder"); err != nil {
+		return nil, err
+	}
 	log.Printf("⏱️ 下限价委托: %s %s %s 数量: %.4f 价格: %.4f 杠杆: %dx",
 		symbol, side, tradeSide, quantity, price, leverage)
 
@@ -525,6 +537,9 @@ func (t *BitgetTrader) PlaceLimitOrder(symbol string, side, tradeSide string, qu
 
 // CancelOrder 取消指定的委托单
 func (t *BitgetTrader) CancelOrder(symbol, orderId string) error {
+	if err := throttleOrder("bitget", "CancelOrder"); err != nil {
+		return err
+	}
 	log.Printf("🗑️ 取消订单: %s (ID: %s)", symbol, orderId)
 
 	body := map[string]interface{}{
@@ -550,6 +565,7 @@ func (t *BitgetTrader) CancelOrder(symbol, orderId string) error {
 // CloseLong 平多仓（使用 Bitget 官方一键平仓接口）
 // 参考文档：https://www.bitget.com/zh-CN/api-doc/contract/trade/Flash-Close-Position
 func (t *BitgetTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+	throttleRiskOrder("bitget", "CloseLong")
 	log.Printf("📊 平多仓: %s（使用一键市价平仓接口）", symbol)
 
 	// 先强制刷新一次持仓，避免使用旧缓存导致“已平仓仍再次平”的情况
@@ -638,6 +654,7 @@ func (t *BitgetTrader) CloseLong(symbol string, quantity float64) (map[string]in
 // CloseShort 平空仓（使用 Bitget 官方一键平仓接口）
 // 参考文档：https://www.bitget.com/zh-CN/api-doc/contract/trade/Flash-Close-Position
 func (t *BitgetTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+	throttleRiskOrder("bitget", "CloseShort")
 	log.Printf("📊 平空仓: %s（使用一键市价平仓接口）", symbol)
 
 	// 先强制刷新一次持仓，避免使用旧缓存导致“已平仓仍再次平”的情况
@@ -723,6 +740,61 @@ func (t *BitgetTrader) CloseShort(symbol string, quantity float64) (map[string]i
 	return result, nil
 }
 
+// ReducePosition 按数量市价减仓（只减仓，不撤销止盈止损等其余挂单）
+// 一键平仓接口会平掉全部持仓并撤销所有挂单，部分平仓需使用 place-order 的 close 单
+func (t *BitgetTrader) ReducePosition(symbol, side string, quantity float64) (map[string]interface{}, error) {
+	throttleRiskOrder("bitget", "ReducePosition")
+	log.Printf("📊 减仓: %s %s 数量: %.4f", symbol, side, quantity)
+
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	// 双向持仓模式下平仓单的 side 与持仓方向一致（buy=平多，sell=平空）
+	orderSide := "buy"
+	if side == "short" {
+		orderSide = "sell"
+	}
+	body := map[string]interface{}{
+		"symbol":      symbol,
+		"productType": "USDT-FUTURES",
+		"marginMode":  "crossed",
+		"marginCoin":  "USDT",
+		"side":        orderSide,
+		"tradeSide":   "close",
+		"orderType":   "market",
+		"size":        quantityStr,
+		"reduceOnly":  "YES", // 单向持仓模式下生效
+	}
+
+	respBody, err := t.request("POST", "/api/v2/mix/order/place-order", nil, body)
+	if err != nil {
+		return nil, fmt.Errorf("reduce position failed: %w", err)
+	}
+
+	var response struct {
+		Data struct {
+			OrderId string `json:"orderId"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return nil, fmt.Errorf("parse response failed: %w", err)
+	}
+
+	log.Printf("✓ 减仓成功: %s %s 数量: %s 订单ID: %s", symbol, side, quantityStr, response.Data.OrderId)
+	// 成功后立即失效本地持仓缓存，确保后续读取到最新状态
+	t.positionsCacheMutex.Lock()
+	t.positionsCacheTime = time.Time{}
+	t.positionsCacheMutex.Unlock()
+
+	return map[string]interface{}{
+		"orderId": response.Data.OrderId,
+		"symbol":  symbol,
+		"status":  "NEW",
+	}, nil
+}
+
 // SetLeverage 设置杠杆
 func (t *BitgetTrader) SetLeverage(symbol string, leverage int) error {
 	log.Printf("⚙️ 设置杠杆: %s %dx (多空双向)", symbol, leverage)
@@ -753,6 +825,34 @@ func (t *BitgetTrader) SetLeverage(symbol string, leverage int) error {
 }
 
 
+// GetMarginMode 查询交易所中该币种当前的仓位模式（单币种账户信息的 marginMode: crossed/isolated）
+func (t *BitgetTrader) GetMarginMode(symbol string) (bool, error) {
+	// GET /api/v2/mix/account/account
+	respBody, err := t.request("GET", "/api/v2/mix/account/account", map[string]string{
+		"symbol":      symbol,
+		"productType": "USDT-FUTURES",
+		"marginCoin":  "USDT",
+	}, nil)
+	if err != nil {
+		return false, fmt.Errorf("get margin mode failed: %w", err)
+	}
+
+	var response struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+		Data struct {
+			MarginMode string `json:"marginMode"` // crossed/isolated
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return false, fmt.Errorf("parse response failed: %w", err)
+	}
+	if response.Data.MarginMode == "" {
+		return false, fmt.Errorf("get margin mode failed: empty marginMode for %s", symbol)
+	}
+	return response.Data.MarginMode == "crossed", nil
+}
+
 // SetMarginMode 设置仓位模式
 func (t *BitgetTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
 	marginMode := "crossed"
@@ -819,6 +919,7 @@ func (t *BitgetTrader) GetMarketPrice(symbol string) (float64, error) {
 
 // SetStopLoss 设置止损单
 func (t *BitgetTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	throttleRiskOrder("bitget", "SetStopLoss")
 	log.Printf("  🛡️ 设置止损: %s %s 数量: %.4f 止损价: %.4f", symbol, positionSide, quantity, stopPrice)
 
 	quantityStr, err := t.FormatQuantity(symbol, quantity)
@@ -858,6 +959,7 @@ func (t *BitgetTrader) SetStopLoss(symbol string, positionSide string, quantity,
 
 // SetTakeProfit 设置止盈单
 func (t *BitgetTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	throttleRiskOrder("bitget", "SetTakeProfit")
 	log.Printf("  💰 设置止盈: %s %s 数量: %.4f 止盈价: %.4f", symbol, positionSide, quantity, takeProfitPrice)
 
 	quantityStr, err := t.FormatQuantity(symbol, quantity)
@@ -895,6 +997,11 @@ func (t *BitgetTrader) SetTakeProfit(symbol string, positionSide string, quantit
 	return nil
 }
 
+// SetOCO 设置止损/止盈单（独立的计划委托，联动由 AutoTrader 模拟）
+func (t *BitgetTrader) SetOCO(symbol, side string, quantity, stopPrice, takeProfitPrice float64) error {
+	return placeOCOLegs(t, symbol, side, quantity, stopPrice, takeProfitPrice)
+}
+
 // CancelStopLossOrders 仅取消止损单（使用 Bitget 计划委托撤单接口）
 func (t *BitgetTrader) CancelStopLossOrders(symbol string) error {
 	log.Printf("  🗑️ 取消止损单: %s", symbol)
@@ -912,6 +1019,9 @@ func (t *BitgetTrader) CancelTakeProfitOrders(symbol string) error {
 // cancelPlanOrders 取消指定类型的计划委托单（内部方法）
 // planType: "loss_plan"（止损）| "profit_plan"（止盈）| "normal_plan"（普通计划委托）| "pos_loss"（仓位止损）| "pos_profit"（仓位止盈）| "moving_plan"（移动止盈止损）
 func (t *BitgetTrader) cancelPlanOrders(symbol string, planType string) error {
+	if err := throttleOrder("bitget", "cancelPlanOrders"); err != nil {
+		return err
+	}
 	// POST /api/v2/mix/order/cancel-plan-order
 	// 参考文档：https://www.bitget.com/zh-CN/api-doc/contract/plan/Cancel-Plan-Order
 	body := map[string]interface{}{
@@ -939,6 +1049,9 @@ func (t *BitgetTrader) cancelPlanOrders(symbol string, planType string) error {
 // 参考文档: https://www.bitget.com/api-doc/contract/plan/Cancel-Plan-Order
 // 注意: Bitget API 使用 orderIdList 数组格式，每项需包含 orderId 或 clientOid
 func (t *BitgetTrader) CancelPlanOrder(symbol, orderId, planType string) error {
+	if err := throttleOrder("bitget", "CancelPlanOrder"); err != nil {
+		return err
+	}
 	log.Printf("🗑️ 取消计划单: %s (ID: %s, Type: %s)", symbol, orderId, planType)
 
 	// POST /api/v2/mix/order/cancel-plan-order
@@ -969,6 +1082,9 @@ func (t *BitgetTrader) CancelPlanOrder(symbol, orderId, planType string) error {
 
 // CancelAllOrders 取消该币种的所有限价/市价委托单（不含计划单）
 func (t *BitgetTrader) CancelAllOrders(symbol string) error {
+	if err := throttleOrder("bitget", "CancelAllOrders"); err != nil {
+		return err
+	}
 	// POST /api/v2/mix/order/cancel-all-orders
 	body := map[string]interface{}{
 		"symbol":      symbol,
@@ -1628,6 +1744,241 @@ func (t *BitgetTrader) GetOrderHistory(symbol string, startTime, endTime int64)
 	return result, nil
 }
 
+// GetBalanceHistory 获取资金流水（GET /api/v2/mix/account/bill）
+// startTime/endTime: 毫秒时间戳，0表示默认最近7天（Bitget 单次查询跨度最多90天）
+func (t *BitgetTrader) GetBalanceHistory(startTime, endTime int64) ([]map[string]interface{}, error) {
+	result := []map[string]interface{}{}
+
+	now := time.Now().UnixMilli()
+	if endTime == 0 {
+		endTime = now
+	}
+	if startTime == 0 {
+		startTime = now - 7*24*60*60*1000
+	}
+
+	params := map[string]string{
+		"productType": "USDT-FUTURES",
+		"coin":        "USDT",
+		"startTime":   strconv.FormatInt(startTime, 10),
+		"endTime":     strconv.FormatInt(endTime, 10),
+		"limit":       "100",
+	}
+	respBody, err := t.request("GET", "/api/v2/mix/account/bill", params, nil)
+	if err != nil {
+		// 43025 常见为“暂无数据”，视为正常返回空
+		if strings.Contains(err.Error(), "code=43025") {
+			return result, nil
+		}
+		return nil, fmt.Errorf("get balance history failed: %w", err)
+	}
+
+	var resp struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+		Data struct {
+			Bills []struct {
+				BillID       string `json:"billId"`
+				Symbol       string `json:"symbol"`
+				Amount       string `json:"amount"`
+				Fee          string `json:"fee"`
+				BusinessType string `json:"businessType"`
+				Coin         string `json:"coin"`
+				CTime        string `json:"cTime"`
+			} `json:"bills"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, fmt.Errorf("parse balance history failed: %w", err)
+	}
+
+	for _, bill := range resp.Data.Bills {
+		amount, _ := strconv.ParseFloat(bill.Amount, 64)
+		fee, _ := strconv.ParseFloat(bill.Fee, 64)
+		cTime, _ := strconv.ParseInt(bill.CTime, 10, 64)
+		result = append(result, map[string]interface{}{
+			"time":     cTime,
+			"type":     classifyBitgetBusinessType(bill.BusinessType),
+			"amount":   amount,
+			"fee":      fee,
+			"asset":    bill.Coin,
+			"symbol":   bill.Symbol,
+			"raw_type": bill.BusinessType,
+		})
+	}
+
+	return result, nil
+}
+
+// GetLeverageBrackets 获取杠杆分层（基于 /api/v2/mix/market/query-position-lever）
+func (t *BitgetTrader) GetLeverageBrackets(symbol string) ([]LeverageBracket, error) {
+	respBody, err := t.request("GET", "/api/v2/mix/market/query-position-lever", map[string]string{
+		"symbol":      symbol,
+		"productType": "USDT-FUTURES",
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("get leverage brackets failed: %w", err)
+	}
+
+	var resp struct {
+		Code string `json:"code"`
+		Data []struct {
+			Level          string `json:"level"`
+			StartUnit      string `json:"startUnit"`
+			EndUnit        string `json:"endUnit"`
+			Leverage       string `json:"leverage"`
+			KeepMarginRate string `json:"keepMarginRate"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, fmt.Errorf("parse leverage brackets failed: %w", err)
+	}
+
+	result := make([]LeverageBracket, 0, len(resp.Data))
+	for _, d := range resp.Data {
+		level, _ := strconv.Atoi(d.Level)
+		leverage, _ := strconv.ParseFloat(d.Leverage, 64)
+		floor, _ := strconv.ParseFloat(d.StartUnit, 64)
+		notionalCap, _ := strconv.ParseFloat(d.EndUnit, 64)
+		mmr, _ := strconv.ParseFloat(d.KeepMarginRate, 64)
+		result = append(result, LeverageBracket{
+			Bracket:          level,
+			MaxLeverage:      int(leverage),
+			NotionalFloor:    floor,
+			NotionalCap:      notionalCap,
+			MaintMarginRatio: mmr,
+		})
+	}
+	return result, nil
+}
+
+// GetPriceTickSize 获取价格最小变动单位（基于 /api/v2/mix/market/contracts 的 pricePlace/priceEndStep）
+func (t *BitgetTrader) GetPriceTickSize(symbol string) (float64, error) {
+	respBody, err := t.request("GET", "/api/v2/mix/market/contracts", map[string]string{
+		"symbol":      symbol,
+		"productType": "USDT-FUTURES",
+	}, nil)
+	if err != nil {
+		return 0, fmt.Errorf("get contract info failed: %w", err)
+	}
+
+	var resp struct {
+		Code string `json:"code"`
+		Data []struct {
+			Symbol       string `json:"symbol"`
+			PricePlace   string `json:"pricePlace"`   // 价格小数位
+			PriceEndStep string `json:"priceEndStep"` // 价格步长（以最小小数位为单位）
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return 0, fmt.Errorf("parse contract info failed: %w", err)
+	}
+
+	for _, d := range resp.Data {
+		if d.Symbol != symbol {
+			continue
+		}
+		place, err := strconv.Atoi(d.PricePlace)
+		if err != nil {
+			return 0, fmt.Errorf("parse pricePlace failed: %w", err)
+		}
+		endStep, err := strconv.ParseFloat(d.PriceEndStep, 64)
+		if err != nil || endStep <= 0 {
+			endStep = 1
+		}
+		return endStep * math.Pow10(-place), nil
+	}
+	return 0, nil
+}
+
+// GetServerTime 获取Bitget服务器时间（毫秒，/api/v2/public/time）
+func (t *BitgetTrader) GetServerTime() (int64, error) {
+	respBody, err := t.request("GET", "/api/v2/public/time", nil, nil)
+	if err != nil {
+		return 0, fmt.Errorf("get server time failed: %w", err)
+	}
+	var resp struct {
+		Data struct {
+			ServerTime string `json:"serverTime"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return 0, fmt.Errorf("parse server time failed: %w", err)
+	}
+	return strconv.ParseInt(resp.Data.ServerTime, 10, 64)
+}
+
+// SyncServerTime 按Bitget服务器时间校准签名时间戳，返回偏移（服务器时间 - 本地时间）
+// Bitget 没有 recvWindow 参数，时间戳与服务器相差超过30秒即拒绝请求
+func (t *BitgetTrader) SyncServerTime() (time.Duration, error) {
+	return t.clock.sync(t.GetServerTime)
+}
+
+// GetOrderBookDepth 获取盘口深度（/api/v2/mix/market/merge-depth，levels 取值 1/5/15/50）
+func (t *BitgetTrader) GetOrderBookDepth(symbol string, levels int) (*OrderBook, error) {
+	limit := "max"
+	for _, l := range []int{1, 5, 15, 50} {
+		if levels <= l {
+			limit = strconv.Itoa(l)
+			break
+		}
+	}
+	respBody, err := t.request("GET", "/api/v2/mix/market/merge-depth", map[string]string{
+		"symbol":      symbol,
+		"productType": "USDT-FUTURES",
+		"limit":       limit,
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("get order book failed: %w", err)
+	}
+
+	// 价格/数量可能为字符串或数字，json.Number 均可解析
+	var resp struct {
+		Code string `json:"code"`
+		Data struct {
+			Asks [][]json.Number `json:"asks"`
+			Bids [][]json.Number `json:"bids"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, fmt.Errorf("parse order book failed: %w", err)
+	}
+
+	parseLevels := func(rows [][]json.Number) []OrderBookLevel {
+		levels := make([]OrderBookLevel, 0, len(rows))
+		for _, row := range rows {
+			if len(row) < 2 {
+				continue
+			}
+			price, _ := row[0].Float64()
+			qty, _ := row[1].Float64()
+			levels = append(levels, OrderBookLevel{Price: price, Quantity: qty})
+		}
+		return levels
+	}
+	return &OrderBook{Bids: parseLevels(resp.Data.Bids), Asks: parseLevels(resp.Data.Asks)}, nil
+}
+
+// classifyBitgetBusinessType 将 Bitget 账单 businessType 映射为统一的资金流水类型
+func classifyBitgetBusinessType(businessType string) string {
+	switch {
+	case strings.HasPrefix(businessType, "trans_from_"):
+		return BalanceEventDeposit
+	case strings.HasPrefix(businessType, "trans_to_"):
+		return BalanceEventWithdraw
+	case strings.HasPrefix(businessType, "close_"),
+		strings.HasPrefix(businessType, "force_close_"),
+		strings.HasPrefix(businessType, "burst_"):
+		return BalanceEventRealizedPnL
+	case businessType == "contract_settle_fee":
+		return BalanceEventFundingFee
+	case strings.HasPrefix(businessType, "open_"):
+		return BalanceEventCommission
+	default:
+		return BalanceEventOther
+	}
+}
+
 // GetPlanOrderHistory 获取计划单历史（止盈/止损等）
 // startTime/endTime: 毫秒时间戳；部分版本的接口可能忽略该范围，但保留参数用于兼容
 func (t *BitgetTrader) GetPlanOrderHistory(symbol string, startTime, endTime int64) ([]map[string]interface{}, error) {
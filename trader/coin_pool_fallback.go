@@ -0,0 +1,51 @@
+package trader
+
+import (
+	"log"
+
+	"nofx/decision"
+	"nofx/pool"
+)
+
+// rememberCoinPool 记录最近一次成功获取的币种池，并清除降级状态
+func (at *AutoTrader) rememberCoinPool(coins []decision.CandidateCoin) {
+	at.coinPoolMu.Lock()
+	defer at.coinPoolMu.Unlock()
+	if at.coinPoolDegraded {
+		log.Printf("✅ [%s] 币种池服务已恢复", at.name)
+	}
+	at.lastGoodCoinPool = append([]decision.CandidateCoin(nil), coins...)
+	at.coinPoolDegraded = false
+}
+
+// fallbackCandidateCoins 币种池获取失败时的降级候选币种：
+// 优先使用最近一次成功的币种池，否则使用全局默认主流币种
+func (at *AutoTrader) fallbackCandidateCoins(cause error) []decision.CandidateCoin {
+	at.coinPoolMu.Lock()
+	defer at.coinPoolMu.Unlock()
+	at.coinPoolDegraded = true
+
+	if len(at.lastGoodCoinPool) > 0 {
+		log.Printf("⚠️ [%s] 获取合并币种池失败，使用上次成功的币种池（%d个）: %v",
+			at.name, len(at.lastGoodCoinPool), cause)
+		return append([]decision.CandidateCoin(nil), at.lastGoodCoinPool...)
+	}
+
+	var candidateCoins []decision.CandidateCoin
+	for _, coin := range pool.GetDefaultCoins() {
+		candidateCoins = append(candidateCoins, decision.CandidateCoin{
+			Symbol:  normalizeSymbol(coin),
+			Sources: []string{"default"},
+		})
+	}
+	log.Printf("⚠️ [%s] 获取合并币种池失败，降级使用默认主流币种（%d个）: %v",
+		at.name, len(candidateCoins), cause)
+	return candidateCoins
+}
+
+// CoinPoolDegraded 币种池是否处于降级状态（外部币种池服务不可用）
+func (at *AutoTrader) CoinPoolDegraded() bool {
+	at.coinPoolMu.Lock()
+	defer at.coinPoolMu.Unlock()
+	return at.coinPoolDegraded
+}
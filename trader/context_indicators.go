@@ -0,0 +1,84 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"nofx/market"
+)
+
+// maxContextTimeframes 多周期指标最多选择的周期数（控制 prompt token 用量和K线请求量）
+const maxContextTimeframes = 3
+
+// ParseIndicatorList 解析逗号分隔的指标/周期列表（去除空白和空项，指标名统一小写，未配置时返回空列表）
+func ParseIndicatorList(raw string) []string {
+	items := []string{}
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ValidateContextIndicators 校验多周期指标配置：指标和周期必须在支持列表内，周期最多 maxContextTimeframes 个
+func ValidateContextIndicators(indicators, timeframes []string) error {
+	for _, indicator := range indicators {
+		if !containsString(market.ContextIndicators, indicator) {
+			return fmt.Errorf("不支持的指标 %s（可选 %s）", indicator, strings.Join(market.ContextIndicators, " / "))
+		}
+	}
+	for _, timeframe := range timeframes {
+		if !containsString(market.ContextTimeframes, timeframe) {
+			return fmt.Errorf("不支持的周期 %s（可选 %s）", timeframe, strings.Join(market.ContextTimeframes, " / "))
+		}
+	}
+	if len(timeframes) > maxContextTimeframes {
+		return fmt.Errorf("最多选择 %d 个周期", maxContextTimeframes)
+	}
+	return nil
+}
+
+// containsString 列表中是否包含指定字符串
+func containsString(items []string, target string) bool {
+	for _, item := range items {
+		if item == target {
+			return true
+		}
+	}
+	return false
+}
+
+// SetContextIndicators 【功能】运行时更新决策上下文的多周期指标（指标或周期为空时不加入），下一个决策周期生效
+func (at *AutoTrader) SetContextIndicators(indicators, timeframes []string) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.ContextIndicators = indicators
+	at.config.ContextTimeframes = timeframes
+}
+
+// indicatorFetcher 返回决策上下文使用的多周期指标获取函数，未配置时返回 nil
+func (at *AutoTrader) indicatorFetcher() func(symbol string) []market.TimeframeIndicators {
+	at.mu.RLock()
+	indicators, timeframes := at.config.ContextIndicators, at.config.ContextTimeframes
+	at.mu.RUnlock()
+	if len(indicators) == 0 || len(timeframes) == 0 {
+		return nil
+	}
+	return func(symbol string) []market.TimeframeIndicators {
+		result := make([]market.TimeframeIndicators, 0, len(timeframes))
+		for _, timeframe := range timeframes {
+			klines, err := market.GetKlinesCached(symbol, timeframe)
+			if err != nil {
+				log.Printf("⚠️ [%s] %v，跳过该周期指标", at.name, err)
+				continue
+			}
+			result = append(result, market.ComputeTimeframeIndicators(timeframe, klines, indicators))
+		}
+		return result
+	}
+}
@@ -0,0 +1,207 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+
+	"nofx/logger"
+)
+
+// ValidMaxDrawdownStopPct 校验最大回撤硬止损阈值（0 表示使用系统 max_drawdown）
+func ValidMaxDrawdownStopPct(pct float64) bool {
+	return pct >= 0 && pct < 100
+}
+
+// drawdownStateStore 净值峰值与回撤止损待命状态的持久化操作（*sysconfig.Database 实现）
+type drawdownStateStore interface {
+	GetTraderDrawdownState(traderID string) (peakEquity float64, armed bool, err error)
+	SaveTraderDrawdownState(traderID string, peakEquity float64, armed bool) error
+}
+
+// SetMaxDrawdownStop 【功能】运行时更新账户最大回撤硬止损配置，同时解除已触发的暂停
+// （待命状态不变：已触发过的止损仍需净值创新高后才会再次触发）
+func (at *AutoTrader) SetMaxDrawdownStop(enforce bool, pct float64, flatten bool) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.EnforceMaxDrawdownStop = enforce
+	at.config.MaxDrawdownStopPct = pct
+	at.config.DrawdownStopFlatten = flatten
+	at.drawdownHalted = false
+}
+
+// maxDrawdownStopPct 生效的最大回撤阈值（百分比），未开启硬止损时为 0
+func (at *AutoTrader) maxDrawdownStopPct() float64 {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	if !at.config.EnforceMaxDrawdownStop {
+		return 0
+	}
+	if at.config.MaxDrawdownStopPct > 0 {
+		return at.config.MaxDrawdownStopPct
+	}
+	return at.config.MaxDrawdown
+}
+
+// loadDrawdownState 启动时从数据库恢复净值历史峰值和回撤止损待命状态
+func (at *AutoTrader) loadDrawdownState() {
+	store, ok := at.database.(drawdownStateStore)
+	if !ok {
+		return
+	}
+	peak, armed, err := store.GetTraderDrawdownState(at.id)
+	if err != nil {
+		log.Printf("⚠️ [%s] 恢复净值峰值失败: %v", at.name, err)
+		return
+	}
+	at.mu.Lock()
+	at.peakEquity, at.drawdownArmed = peak, armed
+	at.mu.Unlock()
+	if peak > 0 && !armed {
+		log.Printf("📂 [%s] 已恢复净值历史峰值 %.2f USDT（最大回撤止损已触发过，净值创新高后重新待命）", at.name, peak)
+	} else if peak > 0 {
+		log.Printf("📂 [%s] 已恢复净值历史峰值 %.2f USDT", at.name, peak)
+	}
+}
+
+// saveDrawdownState 持久化净值历史峰值和回撤止损待命状态
+func (at *AutoTrader) saveDrawdownState(peak float64, armed bool) {
+	store, ok := at.database.(drawdownStateStore)
+	if !ok {
+		return
+	}
+	if err := store.SaveTraderDrawdownState(at.id, peak, armed); err != nil {
+		log.Printf("⚠️ [%s] 保存净值峰值失败: %v", at.name, err)
+	}
+}
+
+// adjustPeakEquityForTransfers 外部充值/提现时等额调整净值峰值，使回撤只反映交易结果
+func (at *AutoTrader) adjustPeakEquityForTransfers(net float64) {
+	at.mu.Lock()
+	if at.peakEquity <= 0 {
+		at.mu.Unlock()
+		return
+	}
+	at.peakEquity += net
+	peak, armed := at.peakEquity, at.drawdownArmed
+	at.mu.Unlock()
+	at.saveDrawdownState(peak, armed)
+}
+
+// drawdownStopHalted 是否因最大回撤硬止损暂停开新仓中
+func (at *AutoTrader) drawdownStopHalted() bool {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return at.drawdownHalted
+}
+
+// drawdownStopBlocksOpen 最大回撤硬止损暂停期间拦截开仓类动作（平仓、调整止损止盈不受影响）
+func (at *AutoTrader) drawdownStopBlocksOpen(action string) bool {
+	return isOpeningAction(action) && at.drawdownStopHalted()
+}
+
+// clearDrawdownHalt 解除最大回撤硬止损暂停（交易员重新启动时调用）
+func (at *AutoTrader) clearDrawdownHalt() {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.drawdownHalted = false
+}
+
+// drawdownStopStatus 最大回撤硬止损状态（用于 GetStatus）
+func (at *AutoTrader) drawdownStopStatus() map[string]interface{} {
+	pct := at.maxDrawdownStopPct()
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	status := map[string]interface{}{
+		"enforce":     at.config.EnforceMaxDrawdownStop,
+		"stop_pct":    pct,
+		"flatten":     at.config.DrawdownStopFlatten,
+		"peak_equity": at.peakEquity,
+		"armed":       at.drawdownArmed,
+		"halted":      at.drawdownHalted,
+	}
+	if pct > 0 && at.peakEquity > 0 {
+		status["stop_at"] = at.peakEquity * (1 - pct/100)
+	}
+	return status
+}
+
+// updateDrawdownStop 按账户净值（钱包余额+未实现盈亏）更新历史峰值并检查最大回撤硬止损。
+// 净值创新高时更新峰值并重新待命；待命中且回撤达到阈值时暂停开新仓（配置了平仓时平掉所有持仓，
+// 仅分析模式下不平仓）并推送通知，之后需净值创新高才会再次触发。返回是否触发
+func (at *AutoTrader) updateDrawdownStop(equity float64) bool {
+	if equity <= 0 {
+		return false
+	}
+	pct := at.maxDrawdownStopPct()
+
+	at.mu.Lock()
+	if equity > at.peakEquity {
+		at.peakEquity = equity
+		at.drawdownArmed = true
+		at.mu.Unlock()
+		at.saveDrawdownState(equity, true)
+		return false
+	}
+	peak := at.peakEquity
+	if pct <= 0 || !at.drawdownArmed || equity > peak*(1-pct/100) {
+		at.mu.Unlock()
+		return false
+	}
+	at.drawdownArmed = false
+	at.drawdownHalted = true
+	flatten := at.config.DrawdownStopFlatten && !at.config.AnalysisOnly
+	at.mu.Unlock()
+	at.saveDrawdownState(peak, false)
+
+	drawdownPct := (peak - equity) / peak * 100
+	log.Printf("🛑 [%s] 触发最大回撤硬止损: 净值 %.2f USDT 较峰值 %.2f 回撤 %.2f%%（阈值 %.2f%%），暂停开新仓",
+		at.name, equity, peak, drawdownPct, pct)
+
+	closed, failed := 0, 0
+	if flatten {
+		positions, err := at.trader.GetPositions()
+		if err != nil {
+			log.Printf("❌ 最大回撤止损：获取持仓失败: %v", err)
+		}
+		for _, pos := range NormalizePositions(positions) {
+			if err := at.emergencyClosePosition(pos.Symbol, pos.Side); err != nil {
+				log.Printf("❌ 最大回撤止损平仓失败 (%s %s): %v", pos.Symbol, pos.Side, err)
+				failed++
+				continue
+			}
+			at.ClearPeakPnLCache(pos.Symbol, pos.Side)
+			at.forgetPositionFirstSeen(pos.Symbol + "_" + pos.Side)
+			closed++
+		}
+	}
+
+	at.emitEvent(logger.EventDrawdownStop,
+		fmt.Sprintf("🛑 [%s] 触发最大回撤硬止损: 净值 %.2f USDT 较峰值 %.2f 回撤 %.2f%%（阈值 %.2f%%），已平仓 %d 个（失败 %d 个），暂停开新仓",
+			at.name, equity, peak, drawdownPct, pct, closed, failed),
+		map[string]interface{}{
+			"total_equity": equity,
+			"peak_equity":  peak,
+			"drawdown_pct": drawdownPct,
+			"threshold":    pct,
+			"closed":       closed,
+			"failed":       failed,
+		})
+	return true
+}
+
+// checkDrawdownStop 获取账户净值并检查最大回撤硬止损（由净值监控定期调用）
+func (at *AutoTrader) checkDrawdownStop() bool {
+	if at.maxDrawdownStopPct() <= 0 {
+		return false
+	}
+	info, err := at.GetAccountInfo()
+	if err != nil {
+		log.Printf("❌ 最大回撤止损监控：获取账户信息失败: %v", err)
+		return false
+	}
+	equity, _ := info["total_equity"].(float64)
+	return at.updateDrawdownStop(equity)
+}
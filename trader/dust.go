@@ -0,0 +1,117 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"sync/atomic"
+)
+
+// 平仓后残留"粉尘"持仓（数量低于交易所最小下单精度，无法再下单平掉）的处理方式（system_config dust_handling）
+const (
+	DustHandlingClose = "close" // 再尝试一次全部平仓，仍有残留时按已平仓处理（默认）
+	DustHandlingMark  = "mark"  // 不下单，直接按已平仓处理
+	DustHandlingOff   = "off"   // 不处理，粉尘持仓视为普通持仓
+)
+
+// dustHandling 粉尘持仓处理方式（system_config dust_handling 的内存缓存）
+var dustHandling atomic.Value
+
+// ValidDustHandling 校验粉尘持仓处理方式
+func ValidDustHandling(mode string) bool {
+	return mode == DustHandlingClose || mode == DustHandlingMark || mode == DustHandlingOff
+}
+
+// SetDustHandling 更新粉尘持仓处理方式（不合法的值按默认 close 处理）
+func SetDustHandling(mode string) {
+	if !ValidDustHandling(mode) {
+		mode = DustHandlingClose
+	}
+	dustHandling.Store(mode)
+}
+
+// DustHandling 当前的粉尘持仓处理方式
+func DustHandling() string {
+	if mode, ok := dustHandling.Load().(string); ok {
+		return mode
+	}
+	return DustHandlingClose
+}
+
+// DustSweepResult 单个粉尘持仓的处理结果
+type DustSweepResult struct {
+	Symbol   string  `json:"symbol"`
+	Side     string  `json:"side"`
+	Quantity float64 `json:"quantity"`
+	Action   string  `json:"action"`          // "closed"=清理平仓成功 / "marked_flat"=按已平仓处理
+	Error    string  `json:"error,omitempty"` // 清理平仓失败的原因
+}
+
+// isDustQuantity 数量按交易所精度格式化后为0，即低于最小下单量，无法再通过下单平掉
+func (at *AutoTrader) isDustQuantity(symbol string, quantity float64) bool {
+	if quantity <= 0 {
+		return false
+	}
+	formatted, err := at.trader.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return false
+	}
+	qty, err := strconv.ParseFloat(formatted, 64)
+	return err == nil && qty == 0
+}
+
+// isDustPosition 持仓是否为需要按已平仓处理的粉尘持仓（dust_handling=off 时始终为 false）
+func (at *AutoTrader) isDustPosition(pos Position) bool {
+	return DustHandling() != DustHandlingOff && at.isDustQuantity(pos.Symbol, pos.Quantity)
+}
+
+// sweepDustPosition 处理单个粉尘持仓：close 模式先尝试全部平仓，残留仍在时清理峰值缓存和首次出现时间，按已平仓处理
+func (at *AutoTrader) sweepDustPosition(pos Position, mode string) DustSweepResult {
+	result := DustSweepResult{Symbol: pos.Symbol, Side: pos.Side, Quantity: pos.Quantity, Action: "marked_flat"}
+	if mode == DustHandlingClose {
+		var err error
+		if pos.Side == "long" {
+			_, err = at.trader.CloseLong(pos.Symbol, 0) // 0 = 全部平仓
+		} else {
+			_, err = at.trader.CloseShort(pos.Symbol, 0)
+		}
+		if err != nil {
+			result.Error = err.Error()
+		} else if residual := at.findPosition(pos.Symbol, pos.Side); residual == nil {
+			result.Action = "closed"
+		}
+	}
+
+	at.ClearPeakPnLCache(pos.Symbol, pos.Side)
+	at.forgetPositionFirstSeen(pos.Key())
+	log.Printf("🧹 [%s] 粉尘持仓 %s %s 数量 %.8f 已处理: %s", at.name, pos.Symbol, pos.Side, pos.Quantity, result.Action)
+	return result
+}
+
+// handleCloseResidual 平仓成功后检查是否残留粉尘持仓，按 dust_handling 配置处理（非粉尘的残留视为正常持仓，不处理）
+func (at *AutoTrader) handleCloseResidual(symbol, side string) {
+	mode := DustHandling()
+	if mode == DustHandlingOff {
+		return
+	}
+	residual := at.findPosition(symbol, side)
+	if residual == nil || !at.isDustQuantity(symbol, residual.Quantity) {
+		return
+	}
+	at.sweepDustPosition(*residual, mode)
+}
+
+// SweepDust 手动清理所有粉尘持仓（无论 dust_handling 配置，均先尝试平仓）
+func (at *AutoTrader) SweepDust() ([]DustSweepResult, error) {
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+	results := []DustSweepResult{}
+	for _, pos := range NormalizePositions(positions) {
+		if at.isDustQuantity(pos.Symbol, pos.Quantity) {
+			results = append(results, at.sweepDustPosition(pos, DustHandlingClose))
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,237 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"nofx/logger"
+)
+
+// equityBracketCheckInterval 账户净值止盈止损检查间隔
+const equityBracketCheckInterval = time.Minute
+
+// 账户净值止盈止损触发类型
+const (
+	equityBracketTakeProfit = "take_profit"
+	equityBracketStopLoss   = "stop_loss"
+)
+
+// equityBracketStateStore 净值止盈止损暂停状态的持久化操作（*sysconfig.Database 实现）
+type equityBracketStateStore interface {
+	GetTraderEquityBracketHit(traderID string) (string, error)
+	SaveTraderEquityBracketHit(traderID, hit string) error
+}
+
+// equityBracketLabel 触发类型的中文描述
+func equityBracketLabel(hit string) string {
+	if hit == equityBracketTakeProfit {
+		return "止盈"
+	}
+	return "止损"
+}
+
+// ValidEquityBracket 校验净值止盈止损阈值：均不能为负，止损百分比需小于100，同时设置绝对值时止损需低于止盈
+func ValidEquityBracket(takeProfit, stopLoss, takeProfitPct, stopLossPct float64) bool {
+	if takeProfit < 0 || stopLoss < 0 || takeProfitPct < 0 || stopLossPct < 0 || stopLossPct >= 100 {
+		return false
+	}
+	return takeProfit == 0 || stopLoss == 0 || stopLoss < takeProfit
+}
+
+// SetEquityBracket 运行时更新账户净值止盈止损阈值（0=关闭），同时解除已触发的暂停
+func (at *AutoTrader) SetEquityBracket(takeProfit, stopLoss, takeProfitPct, stopLossPct float64) {
+	at.mu.Lock()
+	at.config.EquityTakeProfit = takeProfit
+	at.config.EquityStopLoss = stopLoss
+	at.config.EquityTakeProfitPct = takeProfitPct
+	at.config.EquityStopLossPct = stopLossPct
+	wasPaused := at.equityBracketHit != ""
+	at.equityBracketHit = ""
+	at.mu.Unlock()
+	if wasPaused {
+		at.saveEquityBracketPause("")
+	}
+}
+
+// equityBracketLevels 计算生效的净值止盈/止损价位（USDT），同时配置绝对值和百分比时取先触发的一侧
+func (at *AutoTrader) equityBracketLevels() (takeProfit, stopLoss float64) {
+	at.mu.RLock()
+	takeProfit, stopLoss = at.config.EquityTakeProfit, at.config.EquityStopLoss
+	takeProfitPct, stopLossPct := at.config.EquityTakeProfitPct, at.config.EquityStopLossPct
+	at.mu.RUnlock()
+
+	if takeProfitPct > 0 && at.initialBalance > 0 {
+		pctLevel := at.initialBalance * (1 + takeProfitPct/100)
+		if takeProfit <= 0 || pctLevel < takeProfit {
+			takeProfit = pctLevel
+		}
+	}
+	if stopLossPct > 0 && at.initialBalance > 0 {
+		stopLoss = math.Max(stopLoss, at.initialBalance*(1-stopLossPct/100))
+	}
+	return takeProfit, stopLoss
+}
+
+// equityBracketPaused 返回已触发的净值止盈/止损类型，空字符串表示未暂停
+func (at *AutoTrader) equityBracketPaused() string {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return at.equityBracketHit
+}
+
+// ResumeEquityBracket 解除净值止盈止损暂停（用户手动启动交易员时调用；进程重启自动恢复的交易员保持暂停）
+func (at *AutoTrader) ResumeEquityBracket() {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	wasPaused := at.equityBracketHit != ""
+	at.equityBracketHit = ""
+	at.mu.Unlock()
+	if wasPaused {
+		log.Printf("▶️ [%s] 手动启动，解除账户净值止盈止损暂停", at.name)
+		at.saveEquityBracketPause("")
+	}
+}
+
+// loadEquityBracketPause 从数据库恢复净值止盈止损暂停状态（启动时调用）
+func (at *AutoTrader) loadEquityBracketPause() {
+	store, ok := at.database.(equityBracketStateStore)
+	if !ok {
+		return
+	}
+	hit, err := store.GetTraderEquityBracketHit(at.id)
+	if err != nil {
+		log.Printf("⚠️ [%s] 恢复净值止盈止损暂停状态失败: %v", at.name, err)
+		return
+	}
+	at.mu.Lock()
+	at.equityBracketHit = hit
+	at.mu.Unlock()
+	if hit != "" {
+		log.Printf("⏸ [%s] 账户净值%s此前已触发，继续暂停交易（手动启动交易员或修改阈值后恢复）", at.name, equityBracketLabel(hit))
+	}
+}
+
+// saveEquityBracketPause 持久化净值止盈止损暂停状态
+func (at *AutoTrader) saveEquityBracketPause(hit string) {
+	store, ok := at.database.(equityBracketStateStore)
+	if !ok {
+		return
+	}
+	if err := store.SaveTraderEquityBracketHit(at.id, hit); err != nil {
+		log.Printf("⚠️ [%s] 保存净值止盈止损暂停状态失败: %v", at.name, err)
+	}
+}
+
+// equityBracketStatus 净值止盈止损状态（用于 GetStatus）
+func (at *AutoTrader) equityBracketStatus() map[string]interface{} {
+	takeProfit, stopLoss := at.equityBracketLevels()
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return map[string]interface{}{
+		"take_profit":     at.config.EquityTakeProfit,
+		"stop_loss":       at.config.EquityStopLoss,
+		"take_profit_pct": at.config.EquityTakeProfitPct,
+		"stop_loss_pct":   at.config.EquityStopLossPct,
+		"take_profit_at":  takeProfit,
+		"stop_loss_at":    stopLoss,
+		"triggered":       at.equityBracketHit,
+	}
+}
+
+// startEquityBracketMonitor 启动账户净值止盈止损监控（同时检查最大回撤硬止损，见 checkDrawdownStop）
+func (at *AutoTrader) startEquityBracketMonitor() {
+	at.monitorWg.Add(1)
+	go func() {
+		defer at.monitorWg.Done()
+
+		ticker := time.NewTicker(equityBracketCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				// 与决策周期互斥，避免平仓时AI同时开仓
+				if err := at.runExclusiveCycle(func() {
+					at.checkEquityBracket()
+					at.checkDrawdownStop()
+				}); err != nil {
+					log.Printf("⏭ [%s] 净值止盈止损检查: %v", at.name, err)
+				}
+			case <-at.stopMonitorCh:
+				return
+			}
+		}
+	}()
+}
+
+// checkEquityBracket 按账户净值（钱包余额+未实现盈亏）检查整体止盈/止损，触发时平掉所有持仓、
+// 暂停交易并推送通知。仅分析模式下只暂停和提醒，不平仓。返回是否触发
+func (at *AutoTrader) checkEquityBracket() bool {
+	if at.equityBracketPaused() != "" {
+		return false
+	}
+	takeProfit, stopLoss := at.equityBracketLevels()
+	if takeProfit <= 0 && stopLoss <= 0 {
+		return false
+	}
+
+	info, err := at.GetAccountInfo()
+	if err != nil {
+		log.Printf("❌ 净值止盈止损监控：获取账户信息失败: %v", err)
+		return false
+	}
+	equity, _ := info["total_equity"].(float64)
+
+	var hit string
+	var level float64
+	switch {
+	case takeProfit > 0 && equity >= takeProfit:
+		hit, level = equityBracketTakeProfit, takeProfit
+	case stopLoss > 0 && equity <= stopLoss:
+		hit, level = equityBracketStopLoss, stopLoss
+	default:
+		return false
+	}
+
+	at.mu.Lock()
+	at.equityBracketHit = hit
+	analysisOnly := at.config.AnalysisOnly
+	at.mu.Unlock()
+	at.saveEquityBracketPause(hit)
+	log.Printf("🛑 [%s] 触发账户净值%s: 净值 %.2f USDT（阈值 %.2f），平掉所有持仓并暂停交易",
+		at.name, equityBracketLabel(hit), equity, level)
+
+	closed, failed := 0, 0
+	if !analysisOnly {
+		positions, err := at.trader.GetPositions()
+		if err != nil {
+			log.Printf("❌ 净值止盈止损：获取持仓失败: %v", err)
+		}
+		for _, pos := range NormalizePositions(positions) {
+			if err := at.emergencyClosePosition(pos.Symbol, pos.Side); err != nil {
+				log.Printf("❌ 净值止盈止损平仓失败 (%s %s): %v", pos.Symbol, pos.Side, err)
+				failed++
+				continue
+			}
+			at.ClearPeakPnLCache(pos.Symbol, pos.Side)
+			at.forgetPositionFirstSeen(pos.Symbol + "_" + pos.Side)
+			closed++
+		}
+	}
+
+	at.emitEvent(logger.EventEquityBracket,
+		fmt.Sprintf("🛑 [%s] 触发账户净值%s: 净值 %.2f USDT（阈值 %.2f），已平仓 %d 个（失败 %d 个），交易已暂停",
+			at.name, equityBracketLabel(hit), equity, level, closed, failed),
+		map[string]interface{}{
+			"trigger":      hit,
+			"total_equity": equity,
+			"threshold":    level,
+			"closed":       closed,
+			"failed":       failed,
+		})
+	return true
+}
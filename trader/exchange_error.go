@@ -0,0 +1,36 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// exchangeAPIError 交易所返回的错误（HTTP状态码 + 解析出的业务错误码）
+// Error() 保持各交易所原有的错误文本，调用方按 code/status 字段判断错误类型，而不是匹配错误文本
+type exchangeAPIError struct {
+	status int    // HTTP状态码
+	code   string // 交易所业务错误码（无法解析时为空）
+	text   string
+}
+
+func (e *exchangeAPIError) Error() string {
+	return e.text
+}
+
+// newExchangeHTTPError 非200响应：尝试从响应体中解析业务错误码（兼容数字和字符串形式的 code 字段）
+// prefix 为原有错误文本前缀（如 "HTTP" / "http"）
+func newExchangeHTTPError(prefix string, status int, body []byte) *exchangeAPIError {
+	var parsed struct {
+		Code json.RawMessage `json:"code"`
+	}
+	code := ""
+	if err := json.Unmarshal(body, &parsed); err == nil && len(parsed.Code) > 0 {
+		code = strings.Trim(string(parsed.Code), `"`)
+	}
+	return &exchangeAPIError{
+		status: status,
+		code:   code,
+		text:   fmt.Sprintf("%s %d: %s", prefix, status, string(body)),
+	}
+}
@@ -0,0 +1,138 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"nofx/logger"
+)
+
+// defaultExchangeMaintenanceBackoff 检测到交易所维护时的默认退避时长
+const defaultExchangeMaintenanceBackoff = 15 * time.Minute
+
+// exchangeMaintenanceBackoff 交易所维护退避时长（system_config exchange_backoff_minutes 的内存缓存，纳秒）
+var exchangeMaintenanceBackoff atomic.Int64
+
+// SetExchangeMaintenanceBackoff 更新交易所维护退避时长（<=0 时使用默认值）
+func SetExchangeMaintenanceBackoff(d time.Duration) {
+	if d <= 0 {
+		d = defaultExchangeMaintenanceBackoff
+	}
+	exchangeMaintenanceBackoff.Store(int64(d))
+}
+
+// ExchangeMaintenanceBackoff 当前的交易所维护退避时长
+func ExchangeMaintenanceBackoff() time.Duration {
+	if d := exchangeMaintenanceBackoff.Load(); d > 0 {
+		return time.Duration(d)
+	}
+	return defaultExchangeMaintenanceBackoff
+}
+
+// commonMaintenancePatterns 各交易所维护期间普遍返回的错误特征（小写匹配）
+var commonMaintenancePatterns = []string{
+	"maintenance", "system upgrade", "service unavailable", "http 503", "status 503",
+}
+
+// exchangeMaintenancePatterns 各交易所特有的维护错误特征（小写匹配）
+// 币安/Aster: -1016 服务暂停; Bitget: 40725 服务端返回错误（维护期间）
+var exchangeMaintenancePatterns = map[string][]string{
+	"binance": {"code=-1016", "service is no longer available"},
+	"aster":   {"\"code\":-1016", "service is no longer available"},
+	"bitget":  {"40725", "system maintenance"},
+}
+
+// isExchangeMaintenanceError 判断错误是否为交易所维护（停机升级期间返回的错误，与鉴权/参数错误区分）
+func isExchangeMaintenanceError(exchange string, err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, pattern := range commonMaintenancePatterns {
+		if strings.Contains(msg, pattern) {
+			return true
+		}
+	}
+	for _, pattern := range exchangeMaintenancePatterns[strings.ToLower(exchange)] {
+		if strings.Contains(msg, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// enterExchangeMaintenance 检测到交易所维护：在退避时长内不再调用交易所和AI，首次进入时通知用户；返回退避结束时间
+func (at *AutoTrader) enterExchangeMaintenance(cause error, now time.Time) time.Time {
+	until := now.Add(ExchangeMaintenanceBackoff())
+
+	at.mu.Lock()
+	first := at.exchangeMaintenanceSince.IsZero()
+	if first {
+		at.exchangeMaintenanceSince = now
+	}
+	at.exchangeMaintenanceUntil = until
+	at.exchangeMaintenanceErr = cause.Error()
+	at.mu.Unlock()
+
+	if !first {
+		log.Printf("🚧 [%s] 交易所仍在维护中，继续暂停至 %s: %v", at.name, until.Format(time.RFC3339), cause)
+		return until
+	}
+	log.Printf("🚧 [%s] 检测到交易所 %s 维护，暂停调用交易所和AI至 %s: %v", at.name, at.exchange, until.Format(time.RFC3339), cause)
+	at.emitEvent(logger.EventExchangeMaintenance,
+		fmt.Sprintf("🚧 [%s] 交易所 %s 维护中，暂停交易至 %s（恢复后自动继续）", at.name, at.exchange, until.Format(time.RFC3339)),
+		map[string]interface{}{
+			"status":   "exchange_maintenance",
+			"exchange": at.exchange,
+			"until":    until,
+			"error":    cause.Error(),
+		})
+	return until
+}
+
+// exchangeMaintenanceActive 是否处于交易所维护退避期内（退避期结束后允许试探调用，调用成功才退出维护状态）
+func (at *AutoTrader) exchangeMaintenanceActive(now time.Time) (time.Time, bool) {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return at.exchangeMaintenanceUntil, now.Before(at.exchangeMaintenanceUntil)
+}
+
+// clearExchangeMaintenance 交易所调用成功后退出维护状态，恢复正常节奏并通知用户
+func (at *AutoTrader) clearExchangeMaintenance() {
+	at.mu.Lock()
+	since := at.exchangeMaintenanceSince
+	at.exchangeMaintenanceSince = time.Time{}
+	at.exchangeMaintenanceUntil = time.Time{}
+	at.exchangeMaintenanceErr = ""
+	at.mu.Unlock()
+	if since.IsZero() {
+		return
+	}
+
+	log.Printf("✅ [%s] 交易所 %s 已恢复，维护持续 %.0f 分钟，恢复正常交易", at.name, at.exchange, time.Since(since).Minutes())
+	at.emitEvent(logger.EventExchangeMaintenance,
+		fmt.Sprintf("✅ [%s] 交易所 %s 维护结束，已恢复正常交易", at.name, at.exchange),
+		map[string]interface{}{
+			"status":   "normal",
+			"exchange": at.exchange,
+			"since":    since,
+		})
+}
+
+// exchangeMaintenanceStatus 交易所维护状态（用于状态接口）
+func (at *AutoTrader) exchangeMaintenanceStatus() map[string]interface{} {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	if at.exchangeMaintenanceSince.IsZero() {
+		return map[string]interface{}{"status": "normal"}
+	}
+	return map[string]interface{}{
+		"status": "exchange_maintenance",
+		"since":  at.exchangeMaintenanceSince.Format(time.RFC3339),
+		"until":  at.exchangeMaintenanceUntil.Format(time.RFC3339),
+		"error":  at.exchangeMaintenanceErr,
+	}
+}
@@ -0,0 +1,294 @@
+package trader
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2/common"
+)
+
+// failoverAuthErrorThreshold 连续多少次鉴权/IP类错误后切换到另一组密钥（偶发错误不切换）
+const failoverAuthErrorThreshold = 3
+
+// authErrorCodes 交易所鉴权/IP限制类错误码
+// 币安/Aster: -2014/-2015 API-key无效或IP未授权, -1022 签名无效; Bitget: 40037 API Key不存在, 40014 权限不足, 40018 IP不在白名单, 40009 签名错误
+var authErrorCodes = map[string]bool{
+	"-2014": true, "-2015": true, "-1022": true,
+	"40009": true, "40014": true, "40018": true, "40037": true,
+}
+
+// isAuthOrIPError 判断错误是否为鉴权失败或IP限制（换一组密钥可能恢复）
+// 只按交易所返回的错误码/HTTP状态码判断，不匹配错误文本（避免订单号、价格等数字误判）
+func isAuthOrIPError(err error) bool {
+	var binanceErr *common.APIError
+	if errors.As(err, &binanceErr) {
+		return authErrorCodes[strconv.FormatInt(binanceErr.Code, 10)]
+	}
+	var apiErr *exchangeAPIError
+	if errors.As(err, &apiErr) {
+		return authErrorCodes[apiErr.code] || apiErr.status == http.StatusUnauthorized || apiErr.status == http.StatusForbidden
+	}
+	return false
+}
+
+// failoverTrader 主备密钥故障切换交易器：默认使用主密钥，连续出现鉴权/IP错误时切换到另一组密钥并重试当前请求。
+// 仅在交易员配置了备用密钥时使用，单密钥交易员不经过此包装
+type failoverTrader struct {
+	name string
+
+	mu         sync.Mutex
+	traders    [2]Trader // 0=主密钥, 1=备用密钥
+	active     int
+	authErrors int // 当前密钥连续鉴权/IP错误次数
+}
+
+func newFailoverTrader(name string, primary, backup Trader) *failoverTrader {
+	return &failoverTrader{name: name, traders: [2]Trader{primary, backup}}
+}
+
+// current 返回当前使用的交易器及其序号
+func (f *failoverTrader) current() (Trader, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.traders[f.active], f.active
+}
+
+// ActiveKey 返回当前使用的密钥（"primary"/"backup"）
+func (f *failoverTrader) ActiveKey() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.active == 1 {
+		return "backup"
+	}
+	return "primary"
+}
+
+// observe 记录请求结果，返回是否已切换密钥（used 为发起请求时使用的密钥序号，避免并发请求重复切换）
+func (f *failoverTrader) observe(used int, err error) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if used != f.active {
+		// 其他请求已完成切换，直接用新密钥重试
+		return isAuthOrIPError(err)
+	}
+	if !isAuthOrIPError(err) {
+		f.authErrors = 0
+		return false
+	}
+	f.authErrors++
+	if f.authErrors < failoverAuthErrorThreshold {
+		return false
+	}
+
+	from, to := "主密钥", "备用密钥"
+	if f.active == 1 {
+		from, to = "备用密钥", "主密钥"
+	}
+	log.Printf("🔑 [%s] %s连续 %d 次鉴权/IP错误（%v），切换到%s", f.name, from, f.authErrors, err, to)
+	f.active = 1 - f.active
+	f.authErrors = 0
+	return true
+}
+
+// do 使用当前密钥执行请求；触发切换时用新密钥重试一次（鉴权失败的请求未被交易所受理，重试是安全的）
+func (f *failoverTrader) do(fn func(t Trader) error) error {
+	t, idx := f.current()
+	err := fn(t)
+	if f.observe(idx, err) {
+		t, idx = f.current()
+		err = fn(t)
+		f.observe(idx, err)
+	}
+	return err
+}
+
+func (f *failoverTrader) GetBalance() (result map[string]interface{}, err error) {
+	err = f.do(func(t Trader) error { result, err = t.GetBalance(); return err })
+	return result, err
+}
+
+func (f *failoverTrader) GetPositions() (result []map[string]interface{}, err error) {
+	err = f.do(func(t Trader) error { result, err = t.GetPositions(); return err })
+	return result, err
+}
+
+func (f *failoverTrader) OpenLong(symbol string, quantity float64, leverage int) (result map[string]interface{}, err error) {
+	err = f.do(func(t Trader) error { result, err = t.OpenLong(symbol, quantity, leverage); return err })
+	return result, err
+}
+
+func (f *failoverTrader) OpenShort(symbol string, quantity float64, leverage int) (result map[string]interface{}, err error) {
+	err = f.do(func(t Trader) error { result, err = t.OpenShort(symbol, quantity, leverage); return err })
+	return result, err
+}
+
+func (f *failoverTrader) CloseLong(symbol string, quantity float64) (result map[string]interface{}, err error) {
+	err = f.do(func(t Trader) error { result, err = t.CloseLong(symbol, quantity); return err })
+	return result, err
+}
+
+func (f *failoverTrader) CloseShort(symbol string, quantity float64) (result map[string]interface{}, err error) {
+	err = f.do(func(t Trader) error { result, err = t.CloseShort(symbol, quantity); return err })
+	return result, err
+}
+
+func (f *failoverTrader) PlaceLimitOrder(symbol string, side, tradeSide string, quantity float64, price float64, leverage int) (result map[string]interface{}, err error) {
+	err = f.do(func(t Trader) error {
+		result, err = t.PlaceLimitOrder(symbol, side, tradeSide, quantity, price, leverage)
+		return err
+	})
+	return result, err
+}
+
+func (f *failoverTrader) CancelOrder(symbol, orderId string) error {
+	return f.do(func(t Trader) error { return t.CancelOrder(symbol, orderId) })
+}
+
+func (f *failoverTrader) SetLeverage(symbol string, leverage int) error {
+	return f.do(func(t Trader) error { return t.SetLeverage(symbol, leverage) })
+}
+
+func (f *failoverTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
+	return f.do(func(t Trader) error { return t.SetMarginMode(symbol, isCrossMargin) })
+}
+
+func (f *failoverTrader) GetMarginMode(symbol string) (isCross bool, err error) {
+	err = f.do(func(t Trader) error { isCross, err = t.GetMarginMode(symbol); return err })
+	return isCross, err
+}
+
+func (f *failoverTrader) GetMarketPrice(symbol string) (price float64, err error) {
+	err = f.do(func(t Trader) error { price, err = t.GetMarketPrice(symbol); return err })
+	return price, err
+}
+
+func (f *failoverTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	return f.do(func(t Trader) error { return t.SetStopLoss(symbol, positionSide, quantity, stopPrice) })
+}
+
+func (f *failoverTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	return f.do(func(t Trader) error { return t.SetTakeProfit(symbol, positionSide, quantity, takeProfitPrice) })
+}
+
+func (f *failoverTrader) SetOCO(symbol, side string, quantity, stopPrice, takeProfitPrice float64) error {
+	return f.do(func(t Trader) error { return t.SetOCO(symbol, side, quantity, stopPrice, takeProfitPrice) })
+}
+
+func (f *failoverTrader) CancelStopLossOrders(symbol string) error {
+	return f.do(func(t Trader) error { return t.CancelStopLossOrders(symbol) })
+}
+
+func (f *failoverTrader) CancelTakeProfitOrders(symbol string) error {
+	return f.do(func(t Trader) error { return t.CancelTakeProfitOrders(symbol) })
+}
+
+func (f *failoverTrader) CancelAllOrders(symbol string) error {
+	return f.do(func(t Trader) error { return t.CancelAllOrders(symbol) })
+}
+
+func (f *failoverTrader) CancelStopOrders(symbol string) error {
+	return f.do(func(t Trader) error { return t.CancelStopOrders(symbol) })
+}
+
+func (f *failoverTrader) FormatQuantity(symbol string, quantity float64) (result string, err error) {
+	err = f.do(func(t Trader) error { result, err = t.FormatQuantity(symbol, quantity); return err })
+	return result, err
+}
+
+func (f *failoverTrader) GetOpenOrders(symbol string) (result []map[string]interface{}, err error) {
+	err = f.do(func(t Trader) error { result, err = t.GetOpenOrders(symbol); return err })
+	return result, err
+}
+
+func (f *failoverTrader) GetOrderHistory(symbol string, startTime, endTime int64) (result []map[string]interface{}, err error) {
+	err = f.do(func(t Trader) error { result, err = t.GetOrderHistory(symbol, startTime, endTime); return err })
+	return result, err
+}
+
+func (f *failoverTrader) GetBalanceHistory(startTime, endTime int64) (result []map[string]interface{}, err error) {
+	err = f.do(func(t Trader) error { result, err = t.GetBalanceHistory(startTime, endTime); return err })
+	return result, err
+}
+
+func (f *failoverTrader) GetLeverageBrackets(symbol string) (result []LeverageBracket, err error) {
+	err = f.do(func(t Trader) error { result, err = t.GetLeverageBrackets(symbol); return err })
+	return result, err
+}
+
+func (f *failoverTrader) GetPriceTickSize(symbol string) (result float64, err error) {
+	err = f.do(func(t Trader) error { result, err = t.GetPriceTickSize(symbol); return err })
+	return result, err
+}
+
+func (f *failoverTrader) GetOrderBookDepth(symbol string, levels int) (result *OrderBook, err error) {
+	err = f.do(func(t Trader) error { result, err = t.GetOrderBookDepth(symbol, levels); return err })
+	return result, err
+}
+
+func (f *failoverTrader) GetServerTime() (result int64, err error) {
+	err = f.do(func(t Trader) error { result, err = t.GetServerTime(); return err })
+	return result, err
+}
+
+// SyncServerTime 主备两组密钥的交易器各自校准服务器时间（切换密钥后无需重新校准），返回当前密钥的时钟偏移
+func (f *failoverTrader) SyncServerTime() (time.Duration, error) {
+	_, active := f.current()
+	var offset time.Duration
+	var activeErr error
+	for i, t := range f.traders {
+		syncer, ok := t.(ClockSyncer)
+		if !ok {
+			continue
+		}
+		o, err := syncer.SyncServerTime()
+		if i == active {
+			offset, activeErr = o, err
+		} else if err != nil {
+			log.Printf("⚠️ [%s] 备用密钥交易器同步服务器时间失败: %v", f.name, err)
+		}
+	}
+	return offset, activeErr
+}
+
+// ReducePosition 转发可选的只减仓能力（PartialCloser），当前交易器不支持时回退到 CloseLong/CloseShort
+func (f *failoverTrader) ReducePosition(symbol, side string, quantity float64) (result map[string]interface{}, err error) {
+	err = f.do(func(t Trader) error {
+		if closer, ok := t.(PartialCloser); ok {
+			result, err = closer.ReducePosition(symbol, side, quantity)
+		} else if side == "short" {
+			result, err = t.CloseShort(symbol, quantity)
+		} else {
+			result, err = t.CloseLong(symbol, quantity)
+		}
+		return err
+	})
+	return result, err
+}
+
+// GetPlanOrderHistory 转发可选的计划单历史查询，当前交易器不支持时返回空列表
+func (f *failoverTrader) GetPlanOrderHistory(symbol string, startTime, endTime int64) (result []map[string]interface{}, err error) {
+	err = f.do(func(t Trader) error {
+		ph, ok := t.(interface {
+			GetPlanOrderHistory(symbol string, startTime, endTime int64) ([]map[string]interface{}, error)
+		})
+		if !ok {
+			result = []map[string]interface{}{}
+			return nil
+		}
+		result, err = ph.GetPlanOrderHistory(symbol, startTime, endTime)
+		return err
+	})
+	return result, err
+}
+
+// activeExchangeKey 返回交易员当前使用的交易所密钥（未配置备用密钥时为 "primary"）
+func (at *AutoTrader) activeExchangeKey() string {
+	if f, ok := at.trader.(*failoverTrader); ok {
+		return f.ActiveKey()
+	}
+	return "primary"
+}
@@ -0,0 +1,95 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/adshao/go-binance/v2/common"
+)
+
+// authFailTrader 模拟API密钥失效的交易器：所有余额查询返回鉴权错误
+type authFailTrader struct {
+	*MockTrader
+	calls int
+}
+
+func (m *authFailTrader) GetBalance() (map[string]interface{}, error) {
+	m.calls++
+	return nil, fmt.Errorf("获取账户信息失败: %w", &common.APIError{Code: -2015, Message: "Invalid API-key, IP, or permissions for action."})
+}
+
+func TestFailoverTraderSwitchesToBackup(t *testing.T) {
+	primary := &authFailTrader{MockTrader: &MockTrader{}}
+	backup := &MockTrader{}
+	f := newFailoverTrader("test", primary, backup)
+
+	// 未达到连续错误阈值前仍使用主密钥
+	for i := 1; i < failoverAuthErrorThreshold; i++ {
+		if _, err := f.GetBalance(); err == nil {
+			t.Fatalf("第%d次请求应返回主密钥的鉴权错误", i)
+		}
+		if f.ActiveKey() != "primary" {
+			t.Fatalf("第%d次错误后不应切换密钥", i)
+		}
+	}
+
+	// 达到阈值后切换到备用密钥，并用备用密钥重试当前请求
+	balance, err := f.GetBalance()
+	if err != nil {
+		t.Fatalf("切换后应使用备用密钥成功返回: %v", err)
+	}
+	if balance["totalWalletBalance"] != 10000.0 {
+		t.Errorf("应返回备用密钥的余额, got %v", balance)
+	}
+	if f.ActiveKey() != "backup" {
+		t.Errorf("ActiveKey = %s, want backup", f.ActiveKey())
+	}
+
+	// 之后的请求直接走备用密钥
+	calls := primary.calls
+	if _, err := f.GetBalance(); err != nil {
+		t.Fatalf("备用密钥请求失败: %v", err)
+	}
+	if primary.calls != calls {
+		t.Errorf("切换后不应再调用主密钥")
+	}
+}
+
+func TestFailoverTraderIgnoresOtherErrors(t *testing.T) {
+	primary := &MockTrader{shouldFailBalance: true}
+	f := newFailoverTrader("test", primary, &MockTrader{})
+
+	for i := 0; i < failoverAuthErrorThreshold*2; i++ {
+		f.GetBalance()
+	}
+	if f.ActiveKey() != "primary" {
+		t.Errorf("非鉴权/IP错误不应触发切换")
+	}
+}
+
+func TestIsAuthOrIPError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{fmt.Errorf("获取账户信息失败: %w", &common.APIError{Code: -2015, Message: "Invalid API-key, IP, or permissions for action."}), true},
+		{&common.APIError{Code: -1022, Message: "Signature for this request is not valid."}, true},
+		{&exchangeAPIError{status: 200, code: "40018", text: "bitget api error: code=40018, msg=Invalid IP"}, true},
+		{newExchangeHTTPError("http", 400, []byte(`{"code":"40037","msg":"Apikey does not exist"}`)), true},
+		{newExchangeHTTPError("HTTP", 401, []byte(`{"code":-2015,"msg":"Invalid API-key"}`)), true},
+		{newExchangeHTTPError("HTTP", 403, []byte("<html>forbidden</html>")), true},
+		{&common.APIError{Code: -2019, Message: "Margin is insufficient."}, false},
+		{newExchangeHTTPError("HTTP", 400, []byte(`{"code":-1013,"msg":"Filter failure"}`)), false},
+		// 错误文本中恰好包含鉴权错误码/关键字时不应误判
+		{errors.New("开多仓失败: order 1234540009 rejected"), false},
+		{errors.New("HTTP status 403 forbidden unauthorized"), false},
+		{errors.New("context deadline exceeded"), false},
+	}
+	for _, tt := range tests {
+		if got := isAuthOrIPError(tt.err); got != tt.want {
+			t.Errorf("isAuthOrIPError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
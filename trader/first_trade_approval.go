@@ -0,0 +1,282 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/signal"
+)
+
+// PendingActionTTL 待审批动作的有效期，超时未审批自动过期
+const PendingActionTTL = 30 * time.Minute
+
+// ErrPendingApproval 首笔开仓已转入人工审批，本次未执行
+var ErrPendingApproval = errors.New("首笔交易等待人工审批")
+
+// ErrPendingActionNotFound 待审批动作不存在（已处理或已过期）
+var ErrPendingActionNotFound = errors.New("待审批动作不存在或已过期")
+
+// PendingAction 等待人工审批的开仓决策
+type PendingAction struct {
+	ID        string            `json:"id"`
+	Decision  decision.Decision `json:"decision"`
+	CreatedAt time.Time         `json:"created_at"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// firstTradeApprovalStore 首笔交易审批状态的持久化操作（*sysconfig.Database 实现）
+type firstTradeApprovalStore interface {
+	GetTraderFirstTradeApproved(traderID string) (bool, error)
+	SaveTraderFirstTradeApproved(traderID string, approved bool) error
+}
+
+// SetRequireFirstTradeApproval 【功能】开启/关闭首笔交易人工审批；从关闭切换为开启时重新要求审批下一笔开仓
+func (at *AutoTrader) SetRequireFirstTradeApproval(required bool) {
+	at.mu.Lock()
+	wasRequired := at.config.RequireFirstTradeApproval
+	at.config.RequireFirstTradeApproval = required
+	at.mu.Unlock()
+
+	at.approvalMu.Lock()
+	defer at.approvalMu.Unlock()
+	if required && !wasRequired {
+		at.firstTradeApproved = false
+	}
+	if !required {
+		at.pendingActions = nil
+	}
+}
+
+// loadFirstTradeApproval 启动时从数据库恢复首笔交易审批状态（已审批过的交易员重启后不再重复要求审批）
+func (at *AutoTrader) loadFirstTradeApproval() {
+	store, ok := at.database.(firstTradeApprovalStore)
+	if !ok {
+		return
+	}
+	approved, err := store.GetTraderFirstTradeApproved(at.id)
+	if err != nil {
+		log.Printf("⚠️ [%s] 恢复首笔交易审批状态失败: %v", at.name, err)
+		return
+	}
+	at.approvalMu.Lock()
+	at.firstTradeApproved = approved
+	at.approvalMu.Unlock()
+}
+
+// saveFirstTradeApproved 持久化首笔交易审批状态
+func (at *AutoTrader) saveFirstTradeApproved(approved bool) {
+	store, ok := at.database.(firstTradeApprovalStore)
+	if !ok {
+		return
+	}
+	if err := store.SaveTraderFirstTradeApproved(at.id, approved); err != nil {
+		log.Printf("⚠️ [%s] 保存首笔交易审批状态失败: %v", at.name, err)
+	}
+}
+
+// holdForApproval 首笔开仓未审批时转入待审批列表（同一时间只保留一笔），返回 ErrPendingApproval；无需审批时返回 nil
+func (at *AutoTrader) holdForApproval(d *decision.Decision, actionRecord *logger.DecisionAction) error {
+	at.mu.RLock()
+	required := at.config.RequireFirstTradeApproval
+	at.mu.RUnlock()
+	if !required {
+		return nil
+	}
+
+	now := time.Now()
+	at.approvalMu.Lock()
+	if at.firstTradeApproved || at.approvalInFlight {
+		at.approvalMu.Unlock()
+		return nil
+	}
+	expired := at.expirePendingActionsLocked(now)
+	if len(at.pendingActions) == 0 {
+		pending := &PendingAction{
+			ID:        fmt.Sprintf("pa_%d", now.UnixNano()),
+			Decision:  *d,
+			CreatedAt: now,
+			ExpiresAt: now.Add(PendingActionTTL),
+		}
+		at.pendingActions = append(at.pendingActions, pending)
+		actionRecord.Note = fmt.Sprintf("首笔交易需人工审批（ID: %s，%v 内有效）", pending.ID, PendingActionTTL)
+	} else {
+		actionRecord.Note = fmt.Sprintf("已有首笔交易等待审批（ID: %s），本次开仓不执行", at.pendingActions[0].ID)
+	}
+	at.approvalMu.Unlock()
+
+	at.logPendingOutcomes(expired, "approval_expired", "超时未审批，已过期")
+	actionRecord.Status = "pending_approval"
+	log.Printf("🙋 [%s] %s %s %s", at.name, d.Symbol, d.Action, actionRecord.Note)
+	return fmt.Errorf("%w: %s", ErrPendingApproval, actionRecord.Note)
+}
+
+// holdSignalForApproval 信号模式的开仓/加仓同样需要首笔交易审批：转换为开仓决策转入待审批列表，返回 true 表示本次不执行
+// 审批通过后按开仓决策执行（仓位金额、杠杆、止损止盈沿用信号计算结果）
+func (at *AutoTrader) holdSignalForApproval(strat *signal.SignalDecision, isShort bool, sizeUSD float64, leverage int, currentPrice float64) bool {
+	action := "open_long"
+	if isShort {
+		action = "open_short"
+	}
+	slPrice, tpPrice := at.signalProtectiveLevels(strat, currentPrice)
+	d := &decision.Decision{
+		Symbol:          strat.Symbol,
+		Action:          action,
+		Leverage:        leverage,
+		PositionSizeUSD: sizeUSD,
+		StopLoss:        slPrice,
+		TakeProfit:      tpPrice,
+		Reasoning:       fmt.Sprintf("信号 %s", strat.SignalID),
+	}
+	actionRecord := &logger.DecisionAction{
+		Action:    action,
+		Symbol:    strat.Symbol,
+		Leverage:  leverage,
+		Reasoning: d.Reasoning,
+		Timestamp: time.Now(),
+	}
+	return at.holdForApproval(d, actionRecord) != nil
+}
+
+// expirePendingActionsLocked 移除已过期的待审批动作并返回（调用方需持有 approvalMu）
+func (at *AutoTrader) expirePendingActionsLocked(now time.Time) []*PendingAction {
+	var expired []*PendingAction
+	kept := at.pendingActions[:0]
+	for _, p := range at.pendingActions {
+		if now.After(p.ExpiresAt) {
+			expired = append(expired, p)
+		} else {
+			kept = append(kept, p)
+		}
+	}
+	at.pendingActions = kept
+	return expired
+}
+
+// setApprovalInFlight 标记已审批动作正在执行
+func (at *AutoTrader) setApprovalInFlight(inFlight bool) {
+	at.approvalMu.Lock()
+	at.approvalInFlight = inFlight
+	at.approvalMu.Unlock()
+}
+
+// takePendingAction 取出指定ID的待审批动作（过期的动作先被移除）
+func (at *AutoTrader) takePendingAction(id string) (*PendingAction, error) {
+	at.approvalMu.Lock()
+	expired := at.expirePendingActionsLocked(time.Now())
+	var found *PendingAction
+	for i, p := range at.pendingActions {
+		if p.ID == id {
+			found = p
+			at.pendingActions = append(at.pendingActions[:i], at.pendingActions[i+1:]...)
+			break
+		}
+	}
+	at.approvalMu.Unlock()
+
+	at.logPendingOutcomes(expired, "approval_expired", "超时未审批，已过期")
+	if found == nil {
+		return nil, fmt.Errorf("%w: %s", ErrPendingActionNotFound, id)
+	}
+	return found, nil
+}
+
+// PendingActions 获取当前待审批的动作（已过期的动作会被移除并记录）
+func (at *AutoTrader) PendingActions() []PendingAction {
+	at.approvalMu.Lock()
+	expired := at.expirePendingActionsLocked(time.Now())
+	result := make([]PendingAction, 0, len(at.pendingActions))
+	for _, p := range at.pendingActions {
+		result = append(result, *p)
+	}
+	at.approvalMu.Unlock()
+
+	at.logPendingOutcomes(expired, "approval_expired", "超时未审批，已过期")
+	return result
+}
+
+// ApprovePendingAction 审批通过并立即执行待审批动作；执行成功后之后的交易自动执行，执行失败时下一笔开仓仍需审批
+func (at *AutoTrader) ApprovePendingAction(id string) (*logger.DecisionAction, error) {
+	pending, err := at.takePendingAction(id)
+	if err != nil {
+		return nil, err
+	}
+
+	d := pending.Decision
+	actionRecord := &logger.DecisionAction{
+		Action:     d.Action,
+		Symbol:     d.Symbol,
+		Leverage:   d.Leverage,
+		Confidence: d.Confidence,
+		Reasoning:  d.Reasoning,
+		Timestamp:  time.Now(),
+		Note:       fmt.Sprintf("人工审批通过（ID: %s）", pending.ID),
+	}
+	var execErr error
+	runErr := at.runExclusiveCycle(func() {
+		// 已审批的动作执行时不再被审批拦截；执行成功后才标记首笔交易已审批
+		at.setApprovalInFlight(true)
+		defer at.setApprovalInFlight(false)
+		execErr = at.executeDecisionWithRecord(&d, actionRecord)
+	})
+	if runErr != nil {
+		execErr = runErr
+	}
+	if execErr == nil {
+		at.approvalMu.Lock()
+		at.firstTradeApproved = true
+		at.approvalMu.Unlock()
+		at.saveFirstTradeApproved(true)
+	}
+	actionRecord.Success = execErr == nil
+	if execErr != nil {
+		actionRecord.Error = execErr.Error()
+	}
+	log.Printf("✅ [%s] 首笔交易审批通过: %s %s (成功: %v)", at.name, d.Symbol, d.Action, execErr == nil)
+	at.logApprovalRecord(*actionRecord)
+	return actionRecord, execErr
+}
+
+// RejectPendingAction 拒绝待审批动作（记录到决策日志，不执行）；下一笔开仓仍需审批
+func (at *AutoTrader) RejectPendingAction(id string) error {
+	pending, err := at.takePendingAction(id)
+	if err != nil {
+		return err
+	}
+	log.Printf("🚫 [%s] 首笔交易审批被拒绝: %s %s", at.name, pending.Decision.Symbol, pending.Decision.Action)
+	at.logPendingOutcomes([]*PendingAction{pending}, "approval_rejected", "人工审批拒绝，未执行")
+	return nil
+}
+
+// logPendingOutcomes 将被拒绝/过期的待审批动作写入决策日志
+func (at *AutoTrader) logPendingOutcomes(actions []*PendingAction, status, note string) {
+	for _, p := range actions {
+		a := logger.DecisionAction{
+			Action:     p.Decision.Action,
+			Symbol:     p.Decision.Symbol,
+			Leverage:   p.Decision.Leverage,
+			Confidence: p.Decision.Confidence,
+			Reasoning:  p.Decision.Reasoning,
+			Timestamp:  time.Now(),
+			Status:     status,
+			Note:       fmt.Sprintf("%s（ID: %s）", note, p.ID),
+		}
+		at.logApprovalRecord(a)
+	}
+}
+
+// logApprovalRecord 以单独的决策记录保存审批相关的动作
+func (at *AutoTrader) logApprovalRecord(a logger.DecisionAction) {
+	record := &logger.DecisionRecord{
+		ExecutionLog: []string{fmt.Sprintf("🙋 %s %s %s", a.Symbol, a.Action, a.Note)},
+		Decisions:    []logger.DecisionAction{a},
+		Success:      a.Error == "",
+		ErrorMessage: a.Error,
+	}
+	if err := at.decisionLogger.LogDecision(record); err != nil {
+		log.Printf("⚠ 保存决策记录失败: %v", err)
+	}
+}
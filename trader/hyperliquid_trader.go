@@ -8,6 +8,7 @@ import (
 	"log"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/sonirico/go-hyperliquid"
@@ -318,6 +319,12 @@ func (t *HyperliquidTrader) SetMarginMode(symbol string, isCrossMargin bool) err
 	return nil
 }
 
+// GetMarginMode 返回当前使用的仓位模式
+// Hyperliquid 的仓位模式随 SetLeverage 一并设置，没有单独的查询接口，返回下次设置杠杆时使用的模式
+func (t *HyperliquidTrader) GetMarginMode(symbol string) (bool, error) {
+	return t.isCrossMargin, nil
+}
+
 // SetLeverage 设置杠杆
 func (t *HyperliquidTrader) SetLeverage(symbol string, leverage int) error {
 	// Hyperliquid symbol格式（去掉USDT后缀）
@@ -336,8 +343,11 @@ func (t *HyperliquidTrader) SetLeverage(symbol string, leverage int) error {
 
 // OpenLong 开多仓
 func (t *HyperliquidTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	if err := throttleOrder("hyperliquid", "OpenLong"); err != nil {
+		return nil, err
+	}
 	// 先取消该币种的所有委托单
-	if err := t.CancelAllOrders(symbol); err != nil {
+	if err := t.cancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败: %v", err)
 	}
 
@@ -394,8 +404,11 @@ func (t *HyperliquidTrader) OpenLong(symbol string, quantity float64, leverage i
 
 // OpenShort 开空仓
 func (t *HyperliquidTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	if err := throttleOrder("hyperliquid", "OpenShort"); err != nil {
+		return nil, err
+	}
 	// 先取消该币种的所有委托单
-	if err := t.CancelAllOrders(symbol); err != nil {
+	if err := t.cancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败: %v", err)
 	}
 
@@ -452,6 +465,7 @@ func (t *HyperliquidTrader) OpenShort(symbol string, quantity float64, leverage
 
 // CloseLong 平多仓
 func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+	throttleRiskOrder("hyperliquid", "CloseLong")
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -459,9 +473,9 @@ func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (map[stri
 			return nil, err
 		}
 
-		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "long" {
-				quantity = pos["positionAmt"].(float64)
+		for _, pos := range NormalizePositions(positions) {
+			if pos.Symbol == symbol && pos.Side == "long" {
+				quantity = pos.Quantity
 				break
 			}
 		}
@@ -510,7 +524,7 @@ func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (map[stri
 	log.Printf("✓ 平多仓成功: %s 数量: %.4f", symbol, roundedQuantity)
 
 	// 平仓后取消该币种的所有挂单
-	if err := t.CancelAllOrders(symbol); err != nil {
+	if err := t.cancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消挂单失败: %v", err)
 	}
 
@@ -524,6 +538,7 @@ func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (map[stri
 
 // CloseShort 平空仓
 func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+	throttleRiskOrder("hyperliquid", "CloseShort")
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -531,9 +546,9 @@ func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[str
 			return nil, err
 		}
 
-		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "short" {
-				quantity = pos["positionAmt"].(float64)
+		for _, pos := range NormalizePositions(positions) {
+			if pos.Symbol == symbol && pos.Side == "short" {
+				quantity = pos.Quantity
 				break
 			}
 		}
@@ -582,7 +597,7 @@ func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[str
 	log.Printf("✓ 平空仓成功: %s 数量: %.4f", symbol, roundedQuantity)
 
 	// 平仓后取消该币种的所有挂单
-	if err := t.CancelAllOrders(symbol); err != nil {
+	if err := t.cancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消挂单失败: %v", err)
 	}
 
@@ -614,6 +629,14 @@ func (t *HyperliquidTrader) CancelTakeProfitOrders(symbol string) error {
 
 // CancelAllOrders 取消该币种的所有挂单
 func (t *HyperliquidTrader) CancelAllOrders(symbol string) error {
+	if err := throttleOrder("hyperliquid", "CancelAllOrders"); err != nil {
+		return err
+	}
+	return t.cancelAllOrders(symbol)
+}
+
+// cancelAllOrders 取消该币种的所有挂单（不取限速令牌：开仓/平仓内部清理旧委托时使用，一次下单只占一个令牌）
+func (t *HyperliquidTrader) cancelAllOrders(symbol string) error {
 	coin := convertSymbolToHyperliquid(symbol)
 
 	// 获取所有挂单
@@ -638,6 +661,9 @@ func (t *HyperliquidTrader) CancelAllOrders(symbol string) error {
 
 // CancelStopOrders 取消该币种的止盈/止损单（用于调整止盈止损位置）
 func (t *HyperliquidTrader) CancelStopOrders(symbol string) error {
+	if err := throttleOrder("hyperliquid", "CancelStopOrders"); err != nil {
+		return err
+	}
 	coin := convertSymbolToHyperliquid(symbol)
 
 	// 获取所有挂单
@@ -694,6 +720,7 @@ func (t *HyperliquidTrader) GetMarketPrice(symbol string) (float64, error) {
 
 // SetStopLoss 设置止损单
 func (t *HyperliquidTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	throttleRiskOrder("hyperliquid", "SetStopLoss")
 	coin := convertSymbolToHyperliquid(symbol)
 
 	isBuy := positionSide == "SHORT" // 空仓止损=买入，多仓止损=卖出
@@ -731,6 +758,7 @@ func (t *HyperliquidTrader) SetStopLoss(symbol string, positionSide string, quan
 
 // SetTakeProfit 设置止盈单
 func (t *HyperliquidTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	throttleRiskOrder("hyperliquid", "SetTakeProfit")
 	coin := convertSymbolToHyperliquid(symbol)
 
 	isBuy := positionSide == "SHORT" // 空仓止盈=买入，多仓止盈=卖出
@@ -766,6 +794,11 @@ func (t *HyperliquidTrader) SetTakeProfit(symbol string, positionSide string, qu
 	return nil
 }
 
+// SetOCO 设置止损/止盈单（独立的触发单，联动由 AutoTrader 模拟）
+func (t *HyperliquidTrader) SetOCO(symbol, side string, quantity, stopPrice, takeProfitPrice float64) error {
+	return placeOCOLegs(t, symbol, side, quantity, stopPrice, takeProfitPrice)
+}
+
 // FormatQuantity 格式化数量到正确的精度
 func (t *HyperliquidTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
 	coin := convertSymbolToHyperliquid(symbol)
@@ -776,10 +809,9 @@ func (t *HyperliquidTrader) FormatQuantity(symbol string, quantity float64) (str
 	return fmt.Sprintf(formatStr, quantity), nil
 }
 
-// GetOpenOrders 获取当前未成交的委托单（Hyperliquid暂不实现，仅为接口兼容）
+// GetOpenOrders 获取当前未成交的委托单（Hyperliquid暂不实现，返回 ErrOpenOrdersNotSupported）
 func (t *HyperliquidTrader) GetOpenOrders(symbol string) ([]map[string]interface{}, error) {
-	log.Printf("⚠️  Hyperliquid GetOpenOrders 暂不实现")
-	return []map[string]interface{}{}, nil
+	return nil, ErrOpenOrdersNotSupported
 }
 
 // GetOrderHistory 获取历史订单（Hyperliquid暂不实现，仅为接口兼容）
@@ -788,6 +820,30 @@ func (t *HyperliquidTrader) GetOrderHistory(symbol string, startTime, endTime in
 	return []map[string]interface{}{}, nil
 }
 
+// GetBalanceHistory 获取资金流水（Hyperliquid暂不实现，返回空列表）
+func (t *HyperliquidTrader) GetBalanceHistory(startTime, endTime int64) ([]map[string]interface{}, error) {
+	return []map[string]interface{}{}, nil
+}
+
+// GetLeverageBrackets 获取杠杆分层（Hyperliquid暂不实现，返回空列表）
+func (t *HyperliquidTrader) GetLeverageBrackets(symbol string) ([]LeverageBracket, error) {
+	return []LeverageBracket{}, nil
+}
+
+// GetPriceTickSize 获取价格最小变动单位（Hyperliquid 按有效数字限制价格，下单时已自行处理，返回0）
+func (t *HyperliquidTrader) GetPriceTickSize(symbol string) (float64, error) {
+	return 0, nil
+}
+
+// GetOrderBookDepth 获取盘口深度（Hyperliquid 暂不支持，返回 nil）
+func (t *HyperliquidTrader) GetOrderBookDepth(symbol string, levels int) (*OrderBook, error) {
+	return nil, nil
+}
+
+// GetServerTime 获取服务器时间（Hyperliquid 以本地毫秒时间作为签名 nonce，不使用 recvWindow，直接返回本地时间）
+func (t *HyperliquidTrader) GetServerTime() (int64, error) {
+	return time.Now().UnixMilli(), nil
+}
 
 // getSzDecimals 获取币种的数量精度
 func (t *HyperliquidTrader) getSzDecimals(coin string) int {
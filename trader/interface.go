@@ -1,5 +1,11 @@
 package trader
 
+import "errors"
+
+// ErrOpenOrdersNotSupported 交易所未实现查询当前委托（GetOpenOrders）
+// 依赖挂单列表做判断的流程（保护单补设、反向挂单撤销、OCO 对账等）遇到此错误时应跳过，而不是把它当作"没有挂单"
+var ErrOpenOrdersNotSupported = errors.New("exchange does not support listing open orders")
+
 // Trader 交易器统一接口
 // 支持多个交易平台（币安、Hyperliquid等）
 type Trader interface {
@@ -7,6 +13,7 @@ type Trader interface {
 	GetBalance() (map[string]interface{}, error)
 
 	// GetPositions 获取所有持仓
+	// 各交易所返回字段可能不一致，业务代码应通过 NormalizePositions 转换为标准 Position 后使用
 	GetPositions() ([]map[string]interface{}, error)
 
 	// OpenLong 开多仓
@@ -35,6 +42,9 @@ type Trader interface {
 	// SetMarginMode 设置仓位模式 (true=全仓, false=逐仓)
 	SetMarginMode(symbol string, isCrossMargin bool) error
 
+	// GetMarginMode 查询交易所中该币种当前的仓位模式 (true=全仓, false=逐仓)
+	GetMarginMode(symbol string) (isCross bool, err error)
+
 	// GetMarketPrice 获取市场价格
 	GetMarketPrice(symbol string) (float64, error)
 
@@ -44,6 +54,10 @@ type Trader interface {
 	// SetTakeProfit 设置止盈单
 	SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error
 
+	// SetOCO 同时设置联动的止损单和止盈单（one-cancels-other），side 为 "LONG"/"SHORT"
+	// 交易所原生支持时（见 NativeOCOTrader）其中一个成交后另一个自动撤销，否则下两个独立保护单，由 AutoTrader 模拟联动
+	SetOCO(symbol, side string, quantity, stopPrice, takeProfitPrice float64) error
+
 	// CancelStopLossOrders 仅取消止损单（修复 BUG：调整止损时不删除止盈）
 	CancelStopLossOrders(symbol string) error
 
@@ -59,10 +73,82 @@ type Trader interface {
 	// FormatQuantity 格式化数量到正确的精度
 	FormatQuantity(symbol string, quantity float64) (string, error)
 
-	// GetOpenOrders 获取当前未成交的委托单（含止盈止损计划单）
+	// GetOpenOrders 获取当前未成交的委托单（含止盈止损计划单），交易所未实现时返回 ErrOpenOrdersNotSupported
 	GetOpenOrders(symbol string) ([]map[string]interface{}, error)
 
 	// GetOrderHistory 获取历史订单（已成交/已取消）
 	// startTime/endTime: 时间戳（毫秒），0表示使用默认值
 	GetOrderHistory(symbol string, startTime, endTime int64) ([]map[string]interface{}, error)
+
+	// GetBalanceHistory 获取账户资金流水（充值/提现、已实现盈亏、资金费、手续费）
+	// startTime/endTime: 时间戳（毫秒），0表示使用默认值；不支持的交易所返回空列表
+	// 每条记录包含 time(int64毫秒)、type(见 BalanceEvent* 常量)、amount(带符号)、asset、symbol
+	GetBalanceHistory(startTime, endTime int64) ([]map[string]interface{}, error)
+
+	// GetLeverageBrackets 获取币种的杠杆分层（名义价值越大，允许的最大杠杆越低），按档位升序
+	// 不支持的交易所返回空列表
+	GetLeverageBrackets(symbol string) ([]LeverageBracket, error)
+
+	// GetPriceTickSize 获取币种的价格最小变动单位（tick size），用于下单前对齐价格
+	// 不支持或未知时返回0（调用方不做对齐）
+	GetPriceTickSize(symbol string) (float64, error)
+
+	// GetOrderBookDepth 获取盘口深度（买卖各 levels 档，买单按价格降序、卖单按价格升序）
+	// 不支持的交易所返回 nil
+	GetOrderBookDepth(symbol string, levels int) (*OrderBook, error)
+
+	// GetServerTime 获取交易所服务器时间（毫秒时间戳），用于修正本地时钟偏移
+	GetServerTime() (int64, error)
+}
+
+// PartialCloser 支持按数量只减仓（reduce-only）平仓、且不撤销其余挂单的交易器（可选能力）
+// 未实现时部分平仓回退到 CloseLong/CloseShort（部分交易所会顺带撤销止盈止损单）
+type PartialCloser interface {
+	// ReducePosition 市价减仓 side("long"/"short") 方向持仓 quantity 数量
+	ReducePosition(symbol, side string, quantity float64) (map[string]interface{}, error)
+}
+
+// LeverageBracket 杠杆分层：持仓名义价值在 [NotionalFloor, NotionalCap) 区间内时允许的最大杠杆
+type LeverageBracket struct {
+	Bracket          int     `json:"bracket"`            // 档位（从1开始）
+	MaxLeverage      int     `json:"max_leverage"`       // 该档位最大杠杆
+	NotionalFloor    float64 `json:"notional_floor"`     // 名义价值下限（USDT）
+	NotionalCap      float64 `json:"notional_cap"`       // 名义价值上限（USDT，0表示无上限）
+	MaintMarginRatio float64 `json:"maint_margin_ratio"` // 维持保证金率
+}
+
+// OrderBookLevel 盘口单档价格与数量
+type OrderBookLevel struct {
+	Price    float64 `json:"price"`
+	Quantity float64 `json:"quantity"`
 }
+
+// OrderBook 盘口深度快照
+type OrderBook struct {
+	Bids []OrderBookLevel `json:"bids"` // 买单（价格降序）
+	Asks []OrderBookLevel `json:"asks"` // 卖单（价格升序）
+}
+
+// MaxLeverageForNotional 返回指定名义价值可用的最大杠杆；brackets 为空时返回0（未知）
+// 名义价值超过所有档位时按最高档位（最低杠杆）处理
+func MaxLeverageForNotional(brackets []LeverageBracket, notional float64) int {
+	if len(brackets) == 0 {
+		return 0
+	}
+	for _, b := range brackets {
+		if notional >= b.NotionalFloor && (b.NotionalCap <= 0 || notional < b.NotionalCap) {
+			return b.MaxLeverage
+		}
+	}
+	return brackets[len(brackets)-1].MaxLeverage
+}
+
+// 资金流水类型（GetBalanceHistory 返回的 type 字段）
+const (
+	BalanceEventDeposit     = "deposit"      // 转入/充值
+	BalanceEventWithdraw    = "withdraw"     // 转出/提现
+	BalanceEventRealizedPnL = "realized_pnl" // 已实现盈亏
+	BalanceEventFundingFee  = "funding_fee"  // 资金费
+	BalanceEventCommission  = "commission"   // 手续费
+	BalanceEventOther       = "other"        // 其他
+)
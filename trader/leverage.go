@@ -0,0 +1,48 @@
+package trader
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"nofx/config"
+)
+
+// leverageCeilings 系统级杠杆上限（system_config max_btc_eth_leverage / max_altcoin_leverage），
+// 对AI决策和信号开仓统一生效
+var leverageCeilings = struct {
+	sync.RWMutex
+	btcEth  int
+	altcoin int
+}{btcEth: config.DefaultMaxBTCETHLeverage, altcoin: config.DefaultMaxAltcoinLeverage}
+
+// SetLeverageCeilings 更新系统级杠杆上限（<=0 的值保持不变）
+func SetLeverageCeilings(btcEth, altcoin int) {
+	leverageCeilings.Lock()
+	defer leverageCeilings.Unlock()
+	if btcEth > 0 {
+		leverageCeilings.btcEth = btcEth
+	}
+	if altcoin > 0 {
+		leverageCeilings.altcoin = altcoin
+	}
+}
+
+// LeverageCeiling 返回币种适用的系统级杠杆上限
+func LeverageCeiling(symbol string) int {
+	leverageCeilings.RLock()
+	defer leverageCeilings.RUnlock()
+	if symbol == "BTCUSDT" || symbol == "ETHUSDT" {
+		return leverageCeilings.btcEth
+	}
+	return leverageCeilings.altcoin
+}
+
+// ClampLeverageToCeiling 将杠杆下调到系统级上限以内，返回调整后的杠杆和说明（未调整时为空）
+func ClampLeverageToCeiling(symbol string, leverage int) (int, string) {
+	ceiling := LeverageCeiling(strings.ToUpper(symbol))
+	if ceiling <= 0 || leverage <= ceiling {
+		return leverage, ""
+	}
+	return ceiling, fmt.Sprintf("杠杆 %dx 超过系统上限 %dx，已下调为 %dx", leverage, ceiling, ceiling)
+}
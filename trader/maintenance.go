@@ -0,0 +1,22 @@
+package trader
+
+import "sync/atomic"
+
+// maintenanceMode 平台维护模式（system_config maintenance_mode 的内存缓存）：
+// 开启时所有交易员暂停开新仓/加仓，平仓和持仓保护照常执行。每个周期都会检查，因此不直接读库
+var maintenanceMode atomic.Bool
+
+// SetMaintenanceMode 更新平台维护模式
+func SetMaintenanceMode(enabled bool) {
+	maintenanceMode.Store(enabled)
+}
+
+// MaintenanceMode 当前是否处于平台维护模式
+func MaintenanceMode() bool {
+	return maintenanceMode.Load()
+}
+
+// maintenanceBlocksOpen 维护模式下是否拦截该动作（只拦截开新仓/加仓类动作）
+func maintenanceBlocksOpen(action string) bool {
+	return MaintenanceMode() && isOpeningAction(action)
+}
@@ -0,0 +1,174 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+)
+
+// ErrPositionNotFound 指定币种没有持仓
+var ErrPositionNotFound = errors.New("持仓不存在")
+
+// ReprotectResult 手动重设单个持仓保护单的结果
+type ReprotectResult struct {
+	Symbol     string   `json:"symbol"`
+	Side       string   `json:"side"`
+	Quantity   float64  `json:"quantity"` // 保护单数量（可平数量）
+	StopLoss   float64  `json:"stop_loss,omitempty"`
+	TakeProfit float64  `json:"take_profit,omitempty"`
+	OrderIDs   []string `json:"order_ids"` // 重设后交易所中的止盈止损单ID（交易所不支持查询委托时为空）
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// validateProtectiveLevels 校验止损/止盈价相对当前价的方向（多单止损低于现价、止盈高于现价，空单相反）
+func validateProtectiveLevels(side string, currentPrice, stopLoss, takeProfit float64) error {
+	long := strings.EqualFold(side, "long")
+	if stopLoss > 0 {
+		if long && stopLoss >= currentPrice {
+			return fmt.Errorf("多单止损必须低于当前价格 (当前: %.4f, 止损: %.4f)", currentPrice, stopLoss)
+		}
+		if !long && stopLoss <= currentPrice {
+			return fmt.Errorf("空单止损必须高于当前价格 (当前: %.4f, 止损: %.4f)", currentPrice, stopLoss)
+		}
+	}
+	if takeProfit > 0 {
+		if long && takeProfit <= currentPrice {
+			return fmt.Errorf("多单止盈必须高于当前价格 (当前: %.4f, 止盈: %.4f)", currentPrice, takeProfit)
+		}
+		if !long && takeProfit >= currentPrice {
+			return fmt.Errorf("空单止盈必须低于当前价格 (当前: %.4f, 止盈: %.4f)", currentPrice, takeProfit)
+		}
+	}
+	return nil
+}
+
+// ReprotectPosition 手动重设单个持仓的止损/止盈单：撤销旧保护单后按可平数量重新设置。
+// stopLoss/takeProfit 为0时使用最近记录的价位；与决策周期互斥执行，并作为一条手动记录写入决策日志
+func (at *AutoTrader) ReprotectPosition(symbol string, stopLoss, takeProfit float64) (*ReprotectResult, error) {
+	if at.IsAnalysisOnly() {
+		return nil, fmt.Errorf("仅分析模式下不设置保护单")
+	}
+	symbol = normalizeSymbol(symbol)
+
+	var result *ReprotectResult
+	var runErr error
+	err := at.runExclusiveCycle(func() {
+		result, runErr = at.reprotectPosition(symbol, stopLoss, takeProfit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, runErr
+}
+
+// reprotectPosition ReprotectPosition 的实际执行逻辑（调用方需持有周期锁）
+func (at *AutoTrader) reprotectPosition(symbol string, stopLoss, takeProfit float64) (*ReprotectResult, error) {
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+	var pos *Position
+	for _, p := range NormalizePositions(positions) {
+		if p.Symbol == symbol && p.Quantity != 0 {
+			p := p
+			pos = &p
+			break
+		}
+	}
+	if pos == nil {
+		return nil, fmt.Errorf("%w: %s", ErrPositionNotFound, symbol)
+	}
+
+	// 未提供的价位使用最近记录的价位（决策记录或交易所现有挂单）
+	lastStopLoss, lastTakeProfit := at.protectiveLevels(*pos)
+	if stopLoss <= 0 {
+		stopLoss = lastStopLoss
+	}
+	if takeProfit <= 0 {
+		takeProfit = lastTakeProfit
+	}
+	if stopLoss <= 0 && takeProfit <= 0 {
+		return nil, fmt.Errorf("未提供止损/止盈价位，且 %s 没有最近记录的价位", pos.Key())
+	}
+
+	// 先整体校验，避免止损已重设而止盈校验失败
+	marketData, err := market.Get(symbol)
+	if err != nil {
+		return nil, fmt.Errorf("获取 %s 行情失败: %w", symbol, err)
+	}
+	if err := validateProtectiveLevels(pos.Side, marketData.CurrentPrice, stopLoss, takeProfit); err != nil {
+		return nil, err
+	}
+
+	quantity := pos.Available
+	if quantity <= 0 {
+		quantity = pos.Quantity
+	}
+	result := &ReprotectResult{Symbol: symbol, Side: pos.Side, Quantity: quantity, StopLoss: stopLoss, TakeProfit: takeProfit, OrderIDs: []string{}}
+	record := &logger.DecisionRecord{ExecutionLog: []string{}, Success: true}
+	log.Printf("🛡️ [%s] 手动重设保护单: %s 止损=%.4f 止盈=%.4f", at.name, pos.Key(), stopLoss, takeProfit)
+
+	// 复用调整止损/止盈逻辑（撤销旧单、使用 available 可平数量、同步加仓状态中的价位）
+	if stopLoss > 0 {
+		d := &decision.Decision{Symbol: symbol, Action: "update_stop_loss", NewStopLoss: stopLoss}
+		at.recordManualReprotect(record, result, "manual_reprotect_stop_loss", pos, quantity, stopLoss,
+			at.executeUpdateStopLossWithRecord(d, &logger.DecisionAction{}))
+	}
+	if takeProfit > 0 {
+		d := &decision.Decision{Symbol: symbol, Action: "update_take_profit", NewTakeProfit: takeProfit}
+		at.recordManualReprotect(record, result, "manual_reprotect_take_profit", pos, quantity, takeProfit,
+			at.executeUpdateTakeProfitWithRecord(d, &logger.DecisionAction{}))
+	}
+
+	if openOrders, err := at.trader.GetOpenOrders(symbol); err == nil {
+		for _, order := range openOrders {
+			if sl, tp := hasProtectiveOrders([]map[string]interface{}{order}); !sl && !tp {
+				continue
+			}
+			if id, ok := order["order_id"]; ok && fmt.Sprint(id) != "" {
+				result.OrderIDs = append(result.OrderIDs, fmt.Sprint(id))
+			}
+		}
+	}
+
+	record.Success = len(result.Errors) == 0
+	if !record.Success {
+		record.ErrorMessage = strings.Join(result.Errors, "; ")
+	}
+	if err := at.decisionLogger.LogDecision(record); err != nil {
+		log.Printf("⚠ 保存决策记录失败: %v", err)
+	}
+	return result, nil
+}
+
+// recordManualReprotect 将一次手动保护单重设写入决策记录和返回结果
+func (at *AutoTrader) recordManualReprotect(record *logger.DecisionRecord, result *ReprotectResult, action string, pos *Position, quantity, price float64, err error) {
+	label := "止损"
+	if action == "manual_reprotect_take_profit" {
+		label = "止盈"
+	}
+	actionRecord := logger.DecisionAction{
+		Action:    action,
+		Symbol:    pos.Symbol,
+		Quantity:  quantity,
+		Price:     price,
+		Timestamp: time.Now(),
+		Success:   err == nil,
+		Note:      fmt.Sprintf("手动重设 %s 持仓的%s单", pos.Side, label),
+	}
+	if err != nil {
+		log.Printf("  ❌ %s 手动重设%s失败: %v", pos.Key(), label, err)
+		actionRecord.Error = err.Error()
+		result.Errors = append(result.Errors, fmt.Sprintf("重设%s失败: %v", label, err))
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s 手动重设%s失败: %v", pos.Symbol, label, err))
+	} else {
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🛡️ %s 手动重设%s %.4f", pos.Symbol, label, price))
+	}
+	record.Decisions = append(record.Decisions, actionRecord)
+}
@@ -0,0 +1,118 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"nofx/logger"
+)
+
+// marginDeficitState 保证金不足状态（可用余额为负或低于配置下限）
+type marginDeficitState struct {
+	active    bool
+	since     time.Time
+	available float64 // 最近一次检查的可用余额
+}
+
+// ValidateMinAvailableBalance 校验 margin_deficit 判定的可用余额下限（0 表示仅在可用余额为负时判定）
+func ValidateMinAvailableBalance(minAvailable float64) error {
+	if minAvailable < 0 {
+		return fmt.Errorf("min_available_balance 不能为负数")
+	}
+	return nil
+}
+
+// SetMinAvailableBalance 【功能】运行时更新可用余额下限，下一次读取余额时重新判定
+func (at *AutoTrader) SetMinAvailableBalance(minAvailable float64) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.MinAvailableBalance = minAvailable
+}
+
+// availableBalanceOf 从 GetBalance 结果中读取可用余额（字段缺失时返回 false）
+func availableBalanceOf(balance map[string]interface{}) (float64, bool) {
+	available, ok := balance["availableBalance"].(float64)
+	return available, ok
+}
+
+// updateMarginDeficit 【功能】按 GetBalance 的可用余额更新 margin_deficit 状态：可用余额为负或低于 min_available_balance 时
+// 进入该状态（暂停开新仓、平仓不受最短持仓时间限制），首次进入时写日志并通知用户；恢复后自动解除。
+// 余额中没有可用余额字段时保持原状态。返回当前是否处于保证金不足状态
+func (at *AutoTrader) updateMarginDeficit(balance map[string]interface{}) bool {
+	available, ok := availableBalanceOf(balance)
+	at.mu.Lock()
+	if !ok {
+		active := at.marginDeficit.active
+		at.mu.Unlock()
+		return active
+	}
+	minAvailable := at.config.MinAvailableBalance
+	deficit := available < 0 || (minAvailable > 0 && available < minAvailable)
+	entered := deficit && !at.marginDeficit.active
+	recovered := !deficit && at.marginDeficit.active
+	switch {
+	case entered:
+		at.marginDeficit = marginDeficitState{active: true, since: time.Now(), available: available}
+	case recovered:
+		at.marginDeficit = marginDeficitState{available: available}
+	default:
+		at.marginDeficit.available = available
+	}
+	at.mu.Unlock()
+
+	if recovered {
+		log.Printf("✅ [%s] 可用余额已恢复到 %.2f USDT，解除 margin_deficit 状态", at.name, available)
+	}
+	if entered {
+		log.Printf("🚨 [%s] 保证金不足（可用余额 %.2f USDT，下限 %.2f），进入 margin_deficit 状态：暂停开新仓，优先平仓降低风险",
+			at.name, available, minAvailable)
+		at.emitEvent(logger.EventMarginDeficit,
+			fmt.Sprintf("🚨 [%s] 保证金不足：可用余额 %.2f USDT，已暂停开新仓，请尽快减仓或补充保证金", at.name, available),
+			map[string]interface{}{
+				"available_balance":     available,
+				"min_available_balance": minAvailable,
+			})
+	}
+	return deficit
+}
+
+// refreshMarginDeficit 重新读取余额并判定保证金不足（信号模式开仓/加仓前使用），读取失败时沿用上次的状态
+func (at *AutoTrader) refreshMarginDeficit() bool {
+	balance, err := at.trader.GetBalance()
+	if err != nil {
+		log.Printf("⚠️ [%s] 获取账户余额失败，沿用上次的保证金状态: %v", at.name, err)
+		return at.inMarginDeficit()
+	}
+	return at.updateMarginDeficit(balance)
+}
+
+// inMarginDeficit 是否处于保证金不足状态
+func (at *AutoTrader) inMarginDeficit() bool {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return at.marginDeficit.active
+}
+
+// marginDeficitBlocksOpen 保证金不足期间拦截开仓类动作（平仓、调整止损止盈不受影响）
+func (at *AutoTrader) marginDeficitBlocksOpen(action string) bool {
+	return isOpeningAction(action) && at.inMarginDeficit()
+}
+
+// marginDeficitStatus 保证金不足状态（用于 GetStatus）
+func (at *AutoTrader) marginDeficitStatus() map[string]interface{} {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	status := map[string]interface{}{
+		"active":                at.marginDeficit.active,
+		"available_balance":     at.marginDeficit.available,
+		"min_available_balance": at.config.MinAvailableBalance,
+	}
+	if at.marginDeficit.active {
+		status["since"] = at.marginDeficit.since.Format(time.RFC3339)
+	}
+	return status
+}
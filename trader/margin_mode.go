@@ -0,0 +1,89 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+)
+
+// MarginModeStatus 交易所实际仓位模式与交易员配置的对比结果
+type MarginModeStatus struct {
+	Symbol          string `json:"symbol"`
+	ConfiguredCross bool   `json:"configured_cross"` // 交易员配置（true=全仓）
+	ExchangeCross   bool   `json:"exchange_cross"`   // 交易所实际模式（true=全仓）
+	Matches         bool   `json:"matches"`
+	HasPosition     bool   `json:"has_position"` // 该币种已有持仓（多数交易所此时禁止修改仓位模式）
+	Reconciled      bool   `json:"reconciled"`   // 本次已按配置修改交易所仓位模式
+	Warning         string `json:"warning,omitempty"`
+}
+
+// marginModeLabel 仓位模式中文名称
+func marginModeLabel(isCross bool) string {
+	if isCross {
+		return "全仓"
+	}
+	return "逐仓"
+}
+
+// CheckMarginMode 查询交易所中该币种的实际仓位模式，与交易员配置不一致时给出警告
+func (at *AutoTrader) CheckMarginMode(symbol string) (*MarginModeStatus, error) {
+	symbol = normalizeSymbol(symbol)
+	exchangeCross, err := at.trader.GetMarginMode(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	at.mu.RLock()
+	configuredCross := at.config.IsCrossMargin
+	at.mu.RUnlock()
+
+	status := &MarginModeStatus{
+		Symbol:          symbol,
+		ConfiguredCross: configuredCross,
+		ExchangeCross:   exchangeCross,
+		Matches:         exchangeCross == configuredCross,
+	}
+	if status.Matches {
+		return status, nil
+	}
+
+	if raw, err := at.trader.GetPositions(); err == nil {
+		for _, pos := range NormalizePositions(raw) {
+			if pos.Symbol == symbol {
+				status.HasPosition = true
+				break
+			}
+		}
+	}
+	status.Warning = fmt.Sprintf("交易所仓位模式为%s，与交易员配置的%s不一致，下单可能被拒绝或按%s计算保证金",
+		marginModeLabel(exchangeCross), marginModeLabel(configuredCross), marginModeLabel(exchangeCross))
+	if status.HasPosition {
+		status.Warning += "；该币种已有持仓，交易所锁定了仓位模式，需平仓后才能调整"
+	}
+	log.Printf("⚠️ [%s] %s %s", at.name, symbol, status.Warning)
+	return status, nil
+}
+
+// ReconcileMarginMode 按交易员配置修改交易所中该币种的仓位模式，返回修改后的对比结果
+// 已有持仓时交易所不允许修改，返回的结果中保留不一致警告
+func (at *AutoTrader) ReconcileMarginMode(symbol string) (*MarginModeStatus, error) {
+	status, err := at.CheckMarginMode(symbol)
+	if err != nil || status.Matches || status.HasPosition {
+		return status, err
+	}
+	if at.IsAnalysisOnly() {
+		return nil, fmt.Errorf("仅分析模式下不修改仓位模式")
+	}
+
+	if err := at.trader.SetMarginMode(status.Symbol, status.ConfiguredCross); err != nil {
+		return nil, fmt.Errorf("设置仓位模式失败: %w", err)
+	}
+	updated, err := at.CheckMarginMode(status.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	updated.Reconciled = updated.Matches
+	if updated.Reconciled {
+		log.Printf("✓ [%s] %s 仓位模式已调整为%s", at.name, status.Symbol, marginModeLabel(status.ConfiguredCross))
+	}
+	return updated, nil
+}
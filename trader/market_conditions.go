@@ -0,0 +1,47 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	"nofx/market"
+)
+
+// marketConditionSymbol 极端行情判断使用的参考币种
+const marketConditionSymbol = "BTCUSDT"
+
+// SetMarketConditionGate 【功能】更新极端行情过滤阈值（无需重启，0表示不启用）
+func (at *AutoTrader) SetMarketConditionGate(btcMovePct, fundingAbovePct float64) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.SkipIfBTCMovePct = btcMovePct
+	at.config.SkipIfFundingAbove = fundingAbovePct
+}
+
+// marketConditionSkipReason 检查极端行情条件，触发时返回跳过原因，否则返回空字符串
+// BTC 1小时涨跌幅由最近的3分钟K线计算；行情获取失败时不拦截，避免数据源故障导致停止交易
+func (at *AutoTrader) marketConditionSkipReason() string {
+	at.mu.RLock()
+	movePct, fundingPct := at.config.SkipIfBTCMovePct, at.config.SkipIfFundingAbove
+	at.mu.RUnlock()
+	if movePct <= 0 && fundingPct <= 0 {
+		return ""
+	}
+
+	data, err := market.Get(marketConditionSymbol)
+	if err != nil {
+		log.Printf("⚠️ [%s] 获取%s行情失败，跳过极端行情检查: %v", at.name, marketConditionSymbol, err)
+		return ""
+	}
+	if movePct > 0 && math.Abs(data.PriceChange1h) >= movePct {
+		return fmt.Sprintf("BTC 1小时涨跌幅 %+.2f%% 超过阈值 %.2f%%", data.PriceChange1h, movePct)
+	}
+	if funding := data.FundingRate * 100; fundingPct > 0 && math.Abs(funding) >= fundingPct {
+		return fmt.Sprintf("BTC 资金费率 %+.4f%% 超过阈值 %.4f%%", funding, fundingPct)
+	}
+	return ""
+}
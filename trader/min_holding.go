@@ -0,0 +1,73 @@
+package trader
+
+import (
+	"fmt"
+	"time"
+
+	"nofx/decision"
+)
+
+// maxMinHoldingMinutes 最短持仓时间上限（7天），防止误配置导致持仓长期无法由AI平仓
+const maxMinHoldingMinutes = 7 * 24 * 60
+
+// ValidMinHoldingMinutes 校验最短持仓时间（0 表示不限制）
+func ValidMinHoldingMinutes(minutes int) bool {
+	return minutes >= 0 && minutes <= maxMinHoldingMinutes
+}
+
+// SetMinHoldingMinutes 【功能】运行时更新最短持仓时间（分钟，0=不限制）
+func (at *AutoTrader) SetMinHoldingMinutes(minutes int) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.MinHoldingMinutes = minutes
+}
+
+// discretionaryCloseSides AI主动平仓动作涉及的持仓方向（反手会平掉反向持仓，部分平仓按币种检查多空两侧）
+func discretionaryCloseSides(action string) []string {
+	switch action {
+	case "close_long", "flip_short":
+		return []string{"long"}
+	case "close_short", "flip_long":
+		return []string{"short"}
+	case "partial_close":
+		return []string{"long", "short"}
+	}
+	return nil
+}
+
+// minHoldingBlocksClose 持仓未达到最短持仓时间时拒绝AI主动平仓（返回拒绝原因）。
+// 只拦截经决策执行的平仓/部分平仓/反手；标记价格已触及止损的平仓视为止损离场放行，
+// 止损单成交、紧急平仓和各类监控平仓不经过此检查。首次出现时间未知的持仓不拦截
+func (at *AutoTrader) minHoldingBlocksClose(d *decision.Decision, now time.Time) error {
+	at.mu.RLock()
+	minHolding := time.Duration(at.config.MinHoldingMinutes) * time.Minute
+	at.mu.RUnlock()
+	if minHolding <= 0 {
+		return nil
+	}
+
+	for _, side := range discretionaryCloseSides(d.Action) {
+		posKey := d.Symbol + "_" + side
+		at.positionFirstSeenMu.Lock()
+		firstSeen, known := at.positionFirstSeenTime[posKey]
+		at.positionFirstSeenMu.Unlock()
+		if !known {
+			continue
+		}
+		held := now.Sub(time.UnixMilli(firstSeen))
+		if held >= minHolding {
+			continue
+		}
+		if pos := at.findOpenPosition(d.Symbol, side); pos != nil && pos.MarkPrice > 0 {
+			if stopLoss, _ := at.protectiveLevels(*pos); stopLoss > 0 && stopDistancePct(*pos, stopLoss) <= 0 {
+				continue
+			}
+		}
+		return fmt.Errorf("%s 仅持有 %.1f 分钟，未达到最短持仓时间 %d 分钟，拒绝AI主动平仓",
+			posKey, held.Minutes(), int(minHolding.Minutes()))
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package trader
+
+import (
+	"sort"
+	"time"
+)
+
+// PositionMonitorState 单个持仓在内存中的监控状态
+type PositionMonitorState struct {
+	Key         string   `json:"key"`                     // symbol_side
+	PeakPnLPct  *float64 `json:"peak_pnl_pct"`            // 回撤监控记录的最高收益率（%），尚未记录时为 null
+	FirstSeenMs int64    `json:"first_seen_ms,omitempty"` // 持仓首次出现时间（毫秒时间戳）
+}
+
+// MonitorState 交易员内存中的回撤监控/风控状态快照（只读，用于排查回撤平仓为何触发或未触发）
+type MonitorState struct {
+	DrawdownMonitorEnabled bool                   `json:"drawdown_monitor_enabled"`
+	StopUntil              time.Time              `json:"stop_until"`
+	Paused                 bool                   `json:"paused"` // 当前是否处于风控暂停期（stop_until 之前）
+	Positions              []PositionMonitorState `json:"positions"`
+}
+
+// GetMonitorState 获取峰值收益缓存、持仓首次出现时间等内存监控状态的快照（按持仓标识排序）
+func (at *AutoTrader) GetMonitorState() MonitorState {
+	peaks := at.GetPeakPnLCache()
+
+	at.positionFirstSeenMu.Lock()
+	firstSeen := make(map[string]int64, len(at.positionFirstSeenTime))
+	for k, v := range at.positionFirstSeenTime {
+		firstSeen[k] = v
+	}
+	at.positionFirstSeenMu.Unlock()
+
+	at.mu.RLock()
+	enabled := at.config.EnableDrawdownMonitor
+	at.mu.RUnlock()
+
+	byKey := make(map[string]*PositionMonitorState, len(peaks)+len(firstSeen))
+	entry := func(key string) *PositionMonitorState {
+		if e, ok := byKey[key]; ok {
+			return e
+		}
+		e := &PositionMonitorState{Key: key}
+		byKey[key] = e
+		return e
+	}
+	for k, v := range peaks {
+		peak := v
+		entry(k).PeakPnLPct = &peak
+	}
+	for k, v := range firstSeen {
+		entry(k).FirstSeenMs = v
+	}
+
+	positions := make([]PositionMonitorState, 0, len(byKey))
+	for _, e := range byKey {
+		positions = append(positions, *e)
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i].Key < positions[j].Key })
+
+	return MonitorState{
+		DrawdownMonitorEnabled: enabled,
+		StopUntil:              at.stopUntil,
+		Paused:                 time.Now().Before(at.stopUntil),
+		Positions:              positions,
+	}
+}
@@ -0,0 +1,176 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// NativeOCOTrader 交易所原生支持止损/止盈联动（一个成交后另一个自动撤销）的交易器（可选能力）
+// 未实现时 SetOCO 下的是两个独立保护单，由 AutoTrader 在补单自检循环中模拟 OCO
+type NativeOCOTrader interface {
+	NativeOCO() bool
+}
+
+// supportsNativeOCO 交易器是否原生支持 OCO
+func supportsNativeOCO(t Trader) bool {
+	native, ok := t.(NativeOCOTrader)
+	return ok && native.NativeOCO()
+}
+
+// placeOCOLegs 依次下止损单和止盈单；止盈失败时撤销刚下的止损单，保证两个保护单同时存在或都不存在
+// （调用方可安全重试，或回退为独立保护单）。供不支持原生 OCO 的交易所实现 SetOCO
+func placeOCOLegs(t Trader, symbol, positionSide string, quantity, stopPrice, takeProfitPrice float64) error {
+	if err := t.SetStopLoss(symbol, positionSide, quantity, stopPrice); err != nil {
+		return err
+	}
+	if err := t.SetTakeProfit(symbol, positionSide, quantity, takeProfitPrice); err != nil {
+		if cancelErr := t.CancelStopLossOrders(symbol); cancelErr != nil {
+			log.Printf("  ⚠ OCO 止盈下单失败后撤销止损单失败: %v", cancelErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// ocoLink 模拟 OCO 的一对保护单（同一持仓的止损单和止盈单）
+type ocoLink struct {
+	Symbol       string
+	PositionSide string // LONG / SHORT
+	StopLoss     float64
+	TakeProfit   float64
+	Since        time.Time
+}
+
+// SetUseOCO 【功能】运行时切换开仓止损/止盈的 OCO 联动（之后的开仓生效，已登记的联动照常处理）
+func (at *AutoTrader) SetUseOCO(enabled bool) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.UseOCO = enabled
+}
+
+// useOCO 是否开启 OCO 联动
+func (at *AutoTrader) useOCO() bool {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return at.config.UseOCO
+}
+
+// placeProtectiveOrders 开仓后设置止损/止盈：开启 use_oco 且两个价位都有效时以 OCO 方式下单
+// （不支持原生 OCO 的交易所登记联动，由补单自检循环模拟），OCO 下单失败或未开启时设置独立的止损单和止盈单
+func (at *AutoTrader) placeProtectiveOrders(symbol, positionSide string, quantity, stopLoss, takeProfit float64) {
+	if at.useOCO() && stopLoss > 0 && takeProfit > 0 {
+		isShort := strings.EqualFold(positionSide, "SHORT")
+		stopPrice := at.roundOrderPrice(symbol, stopLoss, isShort)
+		takeProfitPrice := at.roundOrderPrice(symbol, takeProfit, isShort)
+		err := at.placeProtectiveOrderWithRetry(symbol, positionSide, quantity, func(qty float64) error {
+			return at.trader.SetOCO(symbol, positionSide, qty, stopPrice, takeProfitPrice)
+		})
+		if err == nil {
+			if !supportsNativeOCO(at.trader) {
+				at.ocoLinks.Store(ocoLinkKey(symbol, positionSide), ocoLink{
+					Symbol:       symbol,
+					PositionSide: strings.ToUpper(positionSide),
+					StopLoss:     stopPrice,
+					TakeProfit:   takeProfitPrice,
+					Since:        time.Now(),
+				})
+			}
+			log.Printf("  🔗 OCO 保护单已设置: %s %s 止损 %.4f / 止盈 %.4f", symbol, positionSide, stopPrice, takeProfitPrice)
+			return
+		}
+		log.Printf("  ⚠ OCO 保护单设置失败，改为独立止损/止盈单: %v", err)
+	}
+
+	if err := at.setStopLossWithRetry(symbol, positionSide, quantity, stopLoss); err != nil {
+		log.Printf("  ⚠ 设置止损失败: %v", err)
+	}
+	if err := at.setTakeProfitWithRetry(symbol, positionSide, quantity, takeProfit); err != nil {
+		log.Printf("  ⚠ 设置止盈失败: %v", err)
+	}
+}
+
+func ocoLinkKey(symbol, positionSide string) string {
+	return symbol + "_" + strings.ToLower(positionSide)
+}
+
+// reconcileOCOOrders 模拟 OCO：检查已登记的保护单联动，持仓已平（其中一个保护单成交）时撤销仍挂着的另一个，
+// 避免遗留保护单在之后的新仓位上误触发。持仓仍在时不处理（缺失的保护单由保护单校验补设）；
+// 同币种另一方向仍有持仓时不做按币种的撤单，避免误撤另一方向的保护单
+func (at *AutoTrader) reconcileOCOOrders() {
+	var links []ocoLink
+	at.ocoLinks.Range(func(_, value interface{}) bool {
+		links = append(links, value.(ocoLink))
+		return true
+	})
+	if len(links) == 0 {
+		return
+	}
+
+	rawPositions, err := at.trader.GetPositions()
+	if err != nil {
+		log.Printf("⚠️ [OCO] 获取持仓失败: %v", err)
+		return
+	}
+	held := make(map[string]bool)
+	heldSymbols := make(map[string]bool)
+	for _, pos := range NormalizePositions(rawPositions) {
+		if pos.Quantity > 0 {
+			held[pos.Key()] = true
+			heldSymbols[pos.Symbol] = true
+		}
+	}
+
+	for _, link := range links {
+		key := ocoLinkKey(link.Symbol, link.PositionSide)
+		if held[key] {
+			continue
+		}
+		if heldSymbols[link.Symbol] {
+			log.Printf("  ℹ️  [OCO] %s 已平仓，但同币种另一方向仍有持仓，不撤销剩余保护单", key)
+			at.ocoLinks.Delete(key)
+			continue
+		}
+
+		openOrders, err := at.trader.GetOpenOrders(link.Symbol)
+		if errors.Is(err, ErrOpenOrdersNotSupported) {
+			// 无法判断成交的是哪一个保护单，保留联动记录，不撤单
+			log.Printf("  ℹ️  [OCO] 交易所不支持查询当前委托，跳过联动撤单")
+			return
+		}
+		if err != nil {
+			log.Printf("⚠️ [OCO] 获取 %s 委托失败: %v", link.Symbol, err)
+			continue
+		}
+		if err := at.cancelOCOSibling(link, openOrders); err != nil {
+			log.Printf("❌ [OCO] %s 撤销剩余保护单失败（下次自检重试）: %v", key, err)
+			continue
+		}
+		at.ocoLinks.Delete(key)
+	}
+}
+
+// cancelOCOSibling 持仓已平时按剩余挂单判断成交的是哪一个保护单，并撤销另一个
+func (at *AutoTrader) cancelOCOSibling(link ocoLink, openOrders []map[string]interface{}) error {
+	hasStopLoss, hasTakeProfit := hasProtectiveOrders(openOrders)
+	key := ocoLinkKey(link.Symbol, link.PositionSide)
+	switch {
+	case hasStopLoss && !hasTakeProfit:
+		log.Printf("🔗 [OCO] %s 止盈已成交（%.4f），撤销联动的止损单", key, link.TakeProfit)
+		return at.trader.CancelStopLossOrders(link.Symbol)
+	case hasTakeProfit && !hasStopLoss:
+		log.Printf("🔗 [OCO] %s 止损已成交（%.4f），撤销联动的止盈单", key, link.StopLoss)
+		return at.trader.CancelTakeProfitOrders(link.Symbol)
+	case hasStopLoss && hasTakeProfit:
+		log.Printf("🔗 [OCO] %s 持仓已平（非保护单成交），撤销剩余的止损/止盈单", key)
+		if err := at.trader.CancelStopOrders(link.Symbol); err != nil {
+			return fmt.Errorf("撤销止损/止盈单失败: %w", err)
+		}
+	}
+	return nil
+}
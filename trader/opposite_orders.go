@@ -0,0 +1,134 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// keepOppositeOrders 开反方向仓位时保留原方向挂单（system_config cancel_opposite_orders=false 的内存缓存）。
+// 零值即默认行为：反手/开反方向仓位前撤销原方向挂单，避免旧挂单在新仓位上成交
+var keepOppositeOrders atomic.Bool
+
+// SetCancelOppositeOrders 开启/关闭开反方向仓位前撤销原方向挂单
+func SetCancelOppositeOrders(enabled bool) {
+	keepOppositeOrders.Store(!enabled)
+}
+
+// CancelOppositeOrders 开反方向仓位前是否撤销原方向挂单（默认开启）
+func CancelOppositeOrders() bool {
+	return !keepOppositeOrders.Load()
+}
+
+// orderDirection 挂单所属的持仓方向（long/short，无法判断时为空）
+// 优先使用持仓方向字段；否则按 side 判断（open_long/close_long、Bitget 计划单的 long/short、双向持仓模式下的 buy/sell）
+func orderDirection(order map[string]interface{}) string {
+	if posSide, _ := order["pos_side"].(string); posSide != "" {
+		switch strings.ToLower(posSide) {
+		case "long":
+			return "long"
+		case "short":
+			return "short"
+		}
+	}
+	side, _ := order["side"].(string)
+	side = strings.ToLower(side)
+	switch {
+	case strings.HasSuffix(side, "_long"), side == "long", side == "buy":
+		return "long"
+	case strings.HasSuffix(side, "_short"), side == "short", side == "sell":
+		return "short"
+	}
+	return ""
+}
+
+// isProtectiveOrder 是否为止盈止损类计划单
+func isProtectiveOrder(order map[string]interface{}) bool {
+	orderType, _ := order["type"].(string)
+	switch orderType {
+	case "stop_loss", "take_profit", "loss_plan", "profit_plan", "pos_loss", "pos_profit", "moving_plan":
+		return true
+	}
+	category, _ := order["order_category"].(string)
+	return category == "plan"
+}
+
+// isClosingOrder 是否为平仓/只减仓挂单
+func isClosingOrder(order map[string]interface{}) bool {
+	if reduceOnly, _ := order["reduce_only"].(bool); reduceOnly {
+		return true
+	}
+	tradeSide, _ := order["trade_side"].(string)
+	side, _ := order["side"].(string)
+	return strings.EqualFold(tradeSide, "close") || strings.HasPrefix(strings.ToLower(side), "close_")
+}
+
+// cancelOppositeDirectionOrders 开 newSide 方向仓位前撤销该币种原方向（反方向）的挂单，返回写入决策记录的说明
+// 原方向仍有持仓时只撤开仓挂单，保留其平仓单和止盈止损单；原方向已无持仓时一并撤销，
+// 止盈止损单按币种整体撤销，仅在新方向尚无持仓时执行（避免误撤新方向的保护单）。
+// 查询或撤单失败只记录日志，不阻止开仓
+func (at *AutoTrader) cancelOppositeDirectionOrders(symbol, newSide string) string {
+	if !CancelOppositeOrders() {
+		return ""
+	}
+	oldSide := "long"
+	if newSide == "long" {
+		oldSide = "short"
+	}
+
+	openOrders, err := at.trader.GetOpenOrders(symbol)
+	if errors.Is(err, ErrOpenOrdersNotSupported) {
+		return ""
+	}
+	if err != nil {
+		log.Printf("  ⚠️ [反向挂单] 获取 %s 挂单失败，跳过撤销原%s方向挂单: %v", symbol, oldSide, err)
+		return ""
+	}
+
+	oldPositionOpen := at.findOpenPosition(symbol, oldSide) != nil
+	cancelled, failed := 0, 0
+	staleProtective := false
+	for _, order := range openOrders {
+		if orderDirection(order) != oldSide {
+			continue
+		}
+		if isProtectiveOrder(order) {
+			staleProtective = staleProtective || !oldPositionOpen
+			continue
+		}
+		if oldPositionOpen && isClosingOrder(order) {
+			continue
+		}
+		orderID := fmt.Sprint(order["order_id"])
+		if order["order_id"] == nil || orderID == "" {
+			continue
+		}
+		if err := at.trader.CancelOrder(symbol, orderID); err != nil {
+			log.Printf("  ⚠️ [反向挂单] 撤销 %s 原%s方向挂单 %s 失败: %v", symbol, oldSide, orderID, err)
+			failed++
+			continue
+		}
+		cancelled++
+	}
+
+	note := ""
+	if cancelled > 0 || failed > 0 {
+		note = fmt.Sprintf("开%s前撤销原%s方向挂单 %d 个", newSide, oldSide, cancelled)
+		if failed > 0 {
+			note += fmt.Sprintf("（%d 个撤销失败）", failed)
+		}
+	}
+	if staleProtective && at.findOpenPosition(symbol, newSide) == nil {
+		if err := at.trader.CancelStopOrders(symbol); err != nil {
+			log.Printf("  ⚠️ [反向挂单] 撤销 %s 原%s方向止盈止损单失败: %v", symbol, oldSide, err)
+		} else {
+			note = joinNotes(note, fmt.Sprintf("已撤销原%s方向残留的止盈止损单", oldSide))
+		}
+	}
+	if note != "" {
+		log.Printf("  🧹 [反向挂单] %s %s", symbol, note)
+	}
+	return note
+}
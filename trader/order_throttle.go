@@ -0,0 +1,168 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultOrderRateLimits 各交易所下单/撤单默认限速（次/秒），按交易所文档限额保守取值：
+// 币安合约 300单/10秒、Bitget 下单撤单 10次/秒、Hyperliquid 按IP权重限额、Aster 与币安规则一致但额度更低
+var DefaultOrderRateLimits = map[string]float64{
+	"binance":     10,
+	"bitget":      5,
+	"hyperliquid": 5,
+	"aster":       5,
+}
+
+// orderThrottleMaxWait 令牌不足时最长等待时间，超过则放弃本次请求（返回 ErrOrderThrottled），留给下个周期重试
+var orderThrottleMaxWait = 5 * time.Second
+
+// orderThrottleLogThreshold 等待超过该时长才打印日志，避免平滑的小等待刷屏
+const orderThrottleLogThreshold = 100 * time.Millisecond
+
+// ErrOrderThrottled 下单/撤单请求因交易所限速等待超时而被放弃
+var ErrOrderThrottled = errors.New("下单限速：等待令牌超时")
+
+// orderBucket 单个交易所的令牌桶（容量=1秒的请求数，允许短时突发）
+type orderBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+
+	// 限速统计
+	waits     int64
+	waitTotal time.Duration
+	rejected  int64
+}
+
+// orderThrottles 各交易所的令牌桶（按交易所共享：同一IP/账户下所有交易员共用额度）
+var orderThrottles = struct {
+	sync.Mutex
+	limits  map[string]float64
+	buckets map[string]*orderBucket
+	now     func() time.Time
+	sleep   func(time.Duration)
+}{
+	limits:  map[string]float64{},
+	buckets: map[string]*orderBucket{},
+	now:     time.Now,
+	sleep:   time.Sleep,
+}
+
+// SetOrderRateLimits 更新各交易所下单限速（次/秒，<=0 的值使用默认值）
+func SetOrderRateLimits(limits map[string]float64) {
+	orderThrottles.Lock()
+	defer orderThrottles.Unlock()
+	for exchange, rate := range limits {
+		if rate <= 0 {
+			delete(orderThrottles.limits, exchange)
+		} else {
+			orderThrottles.limits[exchange] = rate
+		}
+		if bucket, ok := orderThrottles.buckets[exchange]; ok {
+			bucket.rate = orderRateLimitLocked(exchange)
+			if bucket.tokens > bucket.rate {
+				bucket.tokens = bucket.rate
+			}
+		}
+	}
+}
+
+// orderRateLimitLocked 返回交易所生效的限速（需持有 orderThrottles 锁），0 表示不限速
+func orderRateLimitLocked(exchange string) float64 {
+	if rate, ok := orderThrottles.limits[exchange]; ok {
+		return rate
+	}
+	return DefaultOrderRateLimits[exchange]
+}
+
+// reserveOrderToken 预占一个令牌，返回需要等待的时长；capped 时等待超过上限则不预占并返回 false
+func reserveOrderToken(exchange string, capped bool) (time.Duration, bool) {
+	orderThrottles.Lock()
+	defer orderThrottles.Unlock()
+
+	rate := orderRateLimitLocked(exchange)
+	if rate <= 0 {
+		return 0, true
+	}
+	now := orderThrottles.now()
+	bucket, ok := orderThrottles.buckets[exchange]
+	if !ok {
+		bucket = &orderBucket{rate: rate, tokens: rate, last: now}
+		orderThrottles.buckets[exchange] = bucket
+	}
+
+	// 按流逝时间补充令牌（不超过桶容量）
+	if elapsed := now.Sub(bucket.last).Seconds(); elapsed > 0 {
+		bucket.tokens += elapsed * bucket.rate
+		if bucket.tokens > bucket.rate {
+			bucket.tokens = bucket.rate
+		}
+		bucket.last = now
+	}
+
+	var wait time.Duration
+	if bucket.tokens < 1 {
+		wait = time.Duration((1 - bucket.tokens) / bucket.rate * float64(time.Second))
+		if capped && wait > orderThrottleMaxWait {
+			bucket.rejected++
+			return wait, false
+		}
+		bucket.waits++
+		bucket.waitTotal += wait
+	}
+	// 令牌可以为负：后续请求按排队顺序依次等待
+	bucket.tokens--
+	return wait, true
+}
+
+// throttleOrder 开仓/撤单前按交易所限速取令牌：令牌不足时阻塞等待（平滑突发），等待超过上限则返回 ErrOrderThrottled。
+// 每个对外的下单/撤单操作只取一个令牌（内部顺带的撤单不再重复取）
+func throttleOrder(exchange, op string) error {
+	wait, ok := reserveOrderToken(exchange, true)
+	if !ok {
+		log.Printf("🚦 [%s] %s 下单限速：需等待 %v 超过上限 %v，放弃本次请求", exchange, op, wait.Round(time.Millisecond), orderThrottleMaxWait)
+		return fmt.Errorf("%w (%s %s)", ErrOrderThrottled, exchange, op)
+	}
+	waitOrderToken(exchange, op, wait)
+	return nil
+}
+
+// throttleRiskOrder 平仓、设置止损/止盈等降低风险的请求取令牌：同样排队以遵守交易所限额，
+// 但不设等待上限，永远不会因限速被放弃（否则可能在行情剧烈时无法平仓或留下无保护的持仓）
+func throttleRiskOrder(exchange, op string) {
+	wait, _ := reserveOrderToken(exchange, false)
+	waitOrderToken(exchange, op, wait)
+}
+
+// waitOrderToken 等待已预占的令牌可用
+func waitOrderToken(exchange, op string, wait time.Duration) {
+	if wait <= 0 {
+		return
+	}
+	if wait >= orderThrottleLogThreshold {
+		log.Printf("🚦 [%s] %s 触发下单限速，等待 %v", exchange, op, wait.Round(time.Millisecond))
+	}
+	orderThrottles.sleep(wait)
+}
+
+// OrderThrottleStats 返回交易所的下单限速统计（用于状态接口）
+func OrderThrottleStats(exchange string) map[string]interface{} {
+	orderThrottles.Lock()
+	defer orderThrottles.Unlock()
+	stats := map[string]interface{}{
+		"rate_per_second": orderRateLimitLocked(exchange),
+		"waits":           int64(0),
+		"wait_total_ms":   int64(0),
+		"rejected":        int64(0),
+	}
+	if bucket, ok := orderThrottles.buckets[exchange]; ok {
+		stats["waits"] = bucket.waits
+		stats["wait_total_ms"] = bucket.waitTotal.Milliseconds()
+		stats["rejected"] = bucket.rejected
+	}
+	return stats
+}
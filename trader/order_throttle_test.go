@@ -0,0 +1,72 @@
+package trader
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestThrottleOrder(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	var slept []time.Duration
+	origNow, origSleep := orderThrottles.now, orderThrottles.sleep
+	orderThrottles.now = func() time.Time { return now }
+	orderThrottles.sleep = func(d time.Duration) {
+		slept = append(slept, d)
+		now = now.Add(d)
+	}
+	defer func() {
+		orderThrottles.now, orderThrottles.sleep = origNow, origSleep
+		delete(orderThrottles.buckets, "testex")
+		SetOrderRateLimits(map[string]float64{"testex": 0})
+	}()
+
+	SetOrderRateLimits(map[string]float64{"testex": 2})
+
+	// 桶容量为1秒的请求数：前2次立即通过
+	for i := 0; i < 2; i++ {
+		if err := throttleOrder("testex", "OpenLong"); err != nil {
+			t.Fatalf("第%d次请求不应被限速: %v", i+1, err)
+		}
+	}
+	if len(slept) != 0 {
+		t.Fatalf("突发额度内不应等待，实际等待 %v", slept)
+	}
+
+	// 第3次需等待半秒补充令牌（平滑而不是拒绝）
+	if err := throttleOrder("testex", "CancelOrder"); err != nil {
+		t.Fatalf("令牌不足时应等待而不是拒绝: %v", err)
+	}
+	if len(slept) != 1 || slept[0] != 500*time.Millisecond {
+		t.Fatalf("应等待 500ms，实际 %v", slept)
+	}
+
+	stats := OrderThrottleStats("testex")
+	if stats["waits"] != int64(1) || stats["wait_total_ms"] != int64(500) || stats["rate_per_second"] != 2.0 {
+		t.Errorf("限速统计不正确: %v", stats)
+	}
+
+	// 需等待时间超过上限时放弃请求
+	origMaxWait := orderThrottleMaxWait
+	orderThrottleMaxWait = 100 * time.Millisecond
+	defer func() { orderThrottleMaxWait = origMaxWait }()
+	if err := throttleOrder("testex", "OpenShort"); !errors.Is(err, ErrOrderThrottled) {
+		t.Fatalf("等待超过上限应返回 ErrOrderThrottled，实际 %v", err)
+	}
+	if stats := OrderThrottleStats("testex"); stats["rejected"] != int64(1) {
+		t.Errorf("被放弃的请求应计入 rejected: %v", stats)
+	}
+
+	// 平仓/止损等降低风险的请求不受等待上限限制：排队等待而不是放弃
+	slept = nil
+	throttleRiskOrder("testex", "SetStopLoss")
+	throttleRiskOrder("testex", "CloseLong")
+	if len(slept) != 2 || slept[0] != 500*time.Millisecond || slept[1] != 500*time.Millisecond {
+		t.Fatalf("降低风险的请求应排队等待，实际等待 %v", slept)
+	}
+
+	// 未配置限速的交易所不限速
+	if wait, ok := reserveOrderToken("unknown", true); !ok || wait != 0 {
+		t.Errorf("未配置限速的交易所不应等待: wait=%v ok=%v", wait, ok)
+	}
+}
@@ -0,0 +1,98 @@
+package trader
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"nofx/decision"
+)
+
+const (
+	orderBookLevels   = 100              // 每次获取的盘口档数
+	orderBookDepthBps = 50.0             // 统计中间价上下多少基点内的挂单量
+	orderBookCacheTTL = 15 * time.Second // 盘口缓存时长（同一周期内持仓与候选重复引用时不重复请求）
+)
+
+// orderBookCacheEntry 盘口摘要缓存（depth 为 nil 表示交易所不支持或获取失败）
+type orderBookCacheEntry struct {
+	depth     *decision.OrderBookDepth
+	fetchedAt time.Time
+}
+
+// summarizeOrderBook 计算买一/卖一、价差以及中间价上下 depthBps 基点内的挂单名义价值
+func summarizeOrderBook(book *OrderBook, depthBps float64) *decision.OrderBookDepth {
+	if book == nil || len(book.Bids) == 0 || len(book.Asks) == 0 {
+		return nil
+	}
+	bestBid, bestAsk := book.Bids[0].Price, book.Asks[0].Price
+	mid := (bestBid + bestAsk) / 2
+	if mid <= 0 {
+		return nil
+	}
+
+	summary := &decision.OrderBookDepth{
+		BestBid:   bestBid,
+		BestAsk:   bestAsk,
+		SpreadBps: math.Round((bestAsk-bestBid)/mid*10000*100) / 100,
+		DepthBps:  depthBps,
+	}
+	low, high := mid*(1-depthBps/10000), mid*(1+depthBps/10000)
+	for _, bid := range book.Bids {
+		if bid.Price < low {
+			break
+		}
+		summary.BidDepthUSD += bid.Price * bid.Quantity
+	}
+	for _, ask := range book.Asks {
+		if ask.Price > high {
+			break
+		}
+		summary.AskDepthUSD += ask.Price * ask.Quantity
+	}
+	return summary
+}
+
+// SetIncludeOrderBookDepth 【功能】切换决策上下文是否包含盘口深度（无需重启）
+func (at *AutoTrader) SetIncludeOrderBookDepth(enabled bool) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.IncludeOrderBookDepth = enabled
+}
+
+// orderBookFetcher 返回决策上下文使用的盘口获取函数，未开启时返回 nil
+func (at *AutoTrader) orderBookFetcher() func(symbol string) *decision.OrderBookDepth {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	if !at.config.IncludeOrderBookDepth {
+		return nil
+	}
+	return at.orderBookDepth
+}
+
+// orderBookDepth 获取币种盘口深度摘要（带短期缓存），交易所不支持或获取失败时返回 nil
+func (at *AutoTrader) orderBookDepth(symbol string) *decision.OrderBookDepth {
+	at.orderBookCacheMu.Lock()
+	if entry, ok := at.orderBookCache[symbol]; ok && time.Since(entry.fetchedAt) < orderBookCacheTTL {
+		at.orderBookCacheMu.Unlock()
+		return entry.depth
+	}
+	at.orderBookCacheMu.Unlock()
+
+	book, err := at.trader.GetOrderBookDepth(symbol, orderBookLevels)
+	if err != nil {
+		log.Printf("⚠️ [%s] 获取 %s 盘口深度失败: %v", at.name, symbol, err)
+	}
+	depth := summarizeOrderBook(book, orderBookDepthBps)
+
+	at.orderBookCacheMu.Lock()
+	if at.orderBookCache == nil {
+		at.orderBookCache = make(map[string]orderBookCacheEntry)
+	}
+	at.orderBookCache[symbol] = orderBookCacheEntry{depth: depth, fetchedAt: time.Now()}
+	at.orderBookCacheMu.Unlock()
+	return depth
+}
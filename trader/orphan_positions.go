@@ -0,0 +1,280 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	sysconfig "nofx/config"
+	"nofx/decision"
+	"nofx/signal"
+)
+
+// 信号模式仓位对账间隔（分钟）
+const (
+	defaultPositionAuditMinutes = 30
+	minPositionAuditMinutes     = 5
+	maxPositionAuditMinutes     = 24 * 60
+)
+
+// minOrphanDecisionWindow 自主模式下判定孤儿持仓的最短无决策时长（实际取 3 个扫描周期与该值的较大者）
+const minOrphanDecisionWindow = 30 * time.Minute
+
+// 孤儿持仓原因
+const (
+	OrphanReasonNoActiveStrategy = "no_active_strategy" // 信号模式：没有活跃策略管理该币种（策略已移除、过期或已关闭）
+	OrphanReasonNoRecentDecision = "no_recent_decision" // 自主模式：最近一段时间内没有任何AI决策涉及该币种
+)
+
+// OrphanPosition 没有被任何策略/决策管理的持仓
+type OrphanPosition struct {
+	Symbol         string     `json:"symbol"`
+	Side           string     `json:"side"`
+	Quantity       float64    `json:"quantity"`
+	EntryPrice     float64    `json:"entry_price"`
+	UnrealizedPnL  float64    `json:"unrealized_pnl"`
+	Reason         string     `json:"reason"`
+	StrategyID     string     `json:"strategy_id,omitempty"`      // 信号模式：最近管理该币种的策略（已不再活跃）
+	StrategyStatus string     `json:"strategy_status,omitempty"`  // 该策略记录的执行状态
+	LastDecisionAt *time.Time `json:"last_decision_at,omitempty"` // 自主模式：最近一次决策涉及该币种的时间
+}
+
+// ValidatePositionAuditInterval 校验仓位对账间隔（0 表示使用默认值）
+func ValidatePositionAuditInterval(minutes int) error {
+	if minutes != 0 && (minutes < minPositionAuditMinutes || minutes > maxPositionAuditMinutes) {
+		return fmt.Errorf("仓位对账间隔必须在 %d 到 %d 分钟之间", minPositionAuditMinutes, maxPositionAuditMinutes)
+	}
+	return nil
+}
+
+// SetPositionAuditInterval 【功能】运行时更新信号模式仓位对账间隔，下一次对账后生效
+func (at *AutoTrader) SetPositionAuditInterval(minutes int) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.PositionAuditMinutes = minutes
+}
+
+// positionAuditInterval 当前的仓位对账间隔（未配置时默认30分钟）
+func (at *AutoTrader) positionAuditInterval() time.Duration {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	minutes := at.config.PositionAuditMinutes
+	if minutes <= 0 {
+		minutes = defaultPositionAuditMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// positionQtyBySymbol 按币种汇总交易所持仓数量（绝对值，忽略空仓），用于策略与实际持仓对账
+func positionQtyBySymbol(positions []map[string]interface{}) map[string]float64 {
+	qtyBySymbol := make(map[string]float64)
+	for _, p := range positions {
+		sym, _ := p["symbol"].(string)
+		sym = strings.ToUpper(strings.TrimSpace(sym))
+		if sym == "" {
+			continue
+		}
+		amt := math.Abs(positionFloat(p, "positionAmt", "quantity", "size"))
+		if amt == 0 {
+			continue
+		}
+		qtyBySymbol[sym] = amt
+	}
+	return qtyBySymbol
+}
+
+// recordSymbolDecisions 记录本周期AI决策涉及的币种（含 hold），用于自主模式判断孤儿持仓
+func (at *AutoTrader) recordSymbolDecisions(decisions []decision.Decision) {
+	now := time.Now()
+	for _, d := range decisions {
+		if d.Symbol != "" {
+			at.symbolDecisionAt.Store(strings.ToUpper(d.Symbol), now)
+		}
+	}
+}
+
+// orphanDecisionWindow 自主模式下持仓超过该时长没有任何决策涉及即视为孤儿持仓
+func (at *AutoTrader) orphanDecisionWindow() time.Duration {
+	window := 3 * at.config.ScanInterval
+	if window < minOrphanDecisionWindow {
+		window = minOrphanDecisionWindow
+	}
+	return window
+}
+
+// FindOrphanPositions 【功能】列出没有被管理的持仓：信号模式下沿用仓位对账的持仓/策略对照，
+// 币种没有活跃策略（策略已移除、过期或已关闭）即为孤儿；自主模式下最近一段时间没有AI决策涉及该币种即为孤儿
+// （交易员启动后未满判定时长时不判定）
+func (at *AutoTrader) FindOrphanPositions() ([]OrphanPosition, error) {
+	rawPositions, err := at.trader.GetPositions()
+	if err != nil {
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+	qtyBySymbol := positionQtyBySymbol(rawPositions)
+
+	orphans := []OrphanPosition{}
+	for _, pos := range NormalizePositions(rawPositions) {
+		sym := strings.ToUpper(pos.Symbol)
+		if pos.Quantity <= 0 || qtyBySymbol[sym] <= 0 {
+			continue
+		}
+		orphans = append(orphans, OrphanPosition{
+			Symbol:        pos.Symbol,
+			Side:          pos.Side,
+			Quantity:      pos.Quantity,
+			EntryPrice:    pos.EntryPrice,
+			UnrealizedPnL: pos.UnrealizedPnL,
+		})
+	}
+	if len(orphans) == 0 {
+		return orphans, nil
+	}
+
+	if at.isSignalMode() {
+		return at.filterSignalOrphans(orphans), nil
+	}
+	return at.filterAutonomousOrphans(orphans), nil
+}
+
+// filterSignalOrphans 信号模式：保留没有活跃策略的持仓，并附上最近记录的策略状态
+func (at *AutoTrader) filterSignalOrphans(positions []OrphanPosition) []OrphanPosition {
+	active := make(map[string]bool)
+	if signal.GlobalManager != nil {
+		for _, snap := range signal.GlobalManager.ListActiveStrategiesFor(at.subscribesToStrategySource) {
+			if snap == nil || snap.Strategy == nil || at.isStrategyClosed(snap.Strategy.SignalID) {
+				continue
+			}
+			active[strings.ToUpper(strings.TrimSpace(snap.Strategy.Symbol))] = true
+		}
+	}
+
+	// 每个币种最近更新的策略状态
+	lastStrategy := make(map[string]*sysconfig.TraderStrategyStatus)
+	if store, ok := at.database.(strategyStatusStore); ok {
+		if statuses, err := store.GetTraderStrategyStatuses(at.id); err == nil {
+			for _, st := range statuses {
+				if st == nil {
+					continue
+				}
+				sym := strings.ToUpper(strings.TrimSpace(st.Symbol))
+				if prev, ok := lastStrategy[sym]; !ok || st.UpdatedAt.After(prev.UpdatedAt) {
+					lastStrategy[sym] = st
+				}
+			}
+		}
+	}
+
+	orphans := []OrphanPosition{}
+	for _, pos := range positions {
+		sym := strings.ToUpper(pos.Symbol)
+		if active[sym] {
+			continue
+		}
+		pos.Reason = OrphanReasonNoActiveStrategy
+		if st, ok := lastStrategy[sym]; ok {
+			pos.StrategyID, pos.StrategyStatus = st.StrategyID, st.Status
+		}
+		orphans = append(orphans, pos)
+	}
+	return orphans
+}
+
+// filterAutonomousOrphans 自主模式：保留超过判定时长没有AI决策涉及的持仓
+func (at *AutoTrader) filterAutonomousOrphans(positions []OrphanPosition) []OrphanPosition {
+	window := at.orphanDecisionWindow()
+	now := time.Now()
+	orphans := []OrphanPosition{}
+	for _, pos := range positions {
+		var lastDecision *time.Time
+		if value, ok := at.symbolDecisionAt.Load(strings.ToUpper(pos.Symbol)); ok {
+			t := value.(time.Time)
+			lastDecision = &t
+		}
+		switch {
+		case lastDecision != nil && now.Sub(*lastDecision) <= window:
+			continue
+		case lastDecision == nil && now.Sub(at.startTime) <= window:
+			continue
+		}
+		pos.Reason = OrphanReasonNoRecentDecision
+		pos.LastDecisionAt = lastDecision
+		orphans = append(orphans, pos)
+	}
+	return orphans
+}
+
+// warnOrphanPositions 仓位对账时提示孤儿持仓（只记录日志，平仓需通过接口手动执行）
+func (at *AutoTrader) warnOrphanPositions() {
+	orphans, err := at.FindOrphanPositions()
+	if err != nil {
+		log.Printf("⚠️ [%s] 检查孤儿持仓失败: %v", at.name, err)
+		return
+	}
+	for _, o := range orphans {
+		log.Printf("⚠️ [position-audit] 孤儿持仓: trader=%s symbol=%s side=%s qty=%.4f reason=%s strategy=%s",
+			at.id, o.Symbol, o.Side, o.Quantity, o.Reason, o.StrategyID)
+	}
+}
+
+// CloseOrphanPositions 【功能】平掉所有孤儿持仓（全平并撤销该币种的挂单），返回每个持仓的执行结果
+func (at *AutoTrader) CloseOrphanPositions() ([]ReduceResult, error) {
+	if at.IsAnalysisOnly() {
+		return nil, fmt.Errorf("仅分析模式下不执行平仓")
+	}
+
+	var results []ReduceResult
+	var runErr error
+	err := at.runExclusiveCycle(func() {
+		orphans, err := at.FindOrphanPositions()
+		if err != nil {
+			runErr = err
+			return
+		}
+		if len(orphans) > 0 {
+			log.Printf("🧹 [%s] 平掉孤儿持仓 %d 个", at.name, len(orphans))
+		}
+		for _, o := range orphans {
+			results = append(results, at.closeOrphanPosition(o))
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, runErr
+}
+
+// closeOrphanPosition 全平单个孤儿持仓；信号模式下同时将对应策略标记为已关闭
+func (at *AutoTrader) closeOrphanPosition(o OrphanPosition) ReduceResult {
+	result := ReduceResult{Symbol: o.Symbol, Side: o.Side, RemainingQuantity: o.Quantity}
+	var err error
+	if o.Side == "short" {
+		_, err = at.trader.CloseShort(o.Symbol, 0)
+	} else {
+		_, err = at.trader.CloseLong(o.Symbol, 0)
+	}
+	if err != nil {
+		log.Printf("  ❌ 孤儿持仓 %s_%s 平仓失败: %v", o.Symbol, o.Side, err)
+		result.Error = err.Error()
+		return result
+	}
+	result.Success = true
+	result.ClosedQuantity = o.Quantity
+	result.RemainingQuantity = 0
+	log.Printf("  ✓ 孤儿持仓 %s_%s 已平仓 %.4f", o.Symbol, o.Side, o.Quantity)
+
+	if err := at.trader.CancelStopOrders(o.Symbol); err != nil {
+		result.Note = fmt.Sprintf("撤销止盈止损单失败: %v", err)
+	}
+	at.ClearPeakPnLCache(o.Symbol, o.Side)
+	at.forgetPositionFirstSeen(o.Symbol + "_" + o.Side)
+	if o.StrategyID != "" && !at.isStrategyClosed(o.StrategyID) {
+		at.updateStrategyStatus(o.StrategyID, o.Symbol, "CLOSED", 0, 0, 0)
+		at.markStrategyClosed(o.StrategyID)
+	}
+	return result
+}
@@ -0,0 +1,136 @@
+package trader
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// defaultPositionLeverage 交易所未返回杠杆（或返回非法值）时使用的默认杠杆
+const defaultPositionLeverage = 10
+
+// Position 交易所无关的标准持仓结构
+// 各交易所 GetPositions 返回的 map 字段名/类型并不完全一致（字符串数字、缺失字段、side 大小写等），
+// 业务代码统一通过 NormalizePosition 转换后使用，不再直接对 map 做类型断言
+type Position struct {
+	Symbol           string
+	Side             string  // long / short
+	EntryPrice       float64 // 开仓均价
+	MarkPrice        float64 // 标记价格（缺失时回退为开仓均价）
+	Quantity         float64 // 持仓数量（绝对值）
+	Available        float64 // 可平数量（缺失时等于 Quantity）
+	Leverage         int     // 杠杆倍数（缺失或非法时为默认值）
+	UnrealizedPnL    float64 // 未实现盈亏
+	LiquidationPrice float64 // 强平价格（未知时为0）
+}
+
+// MarginUsed 估算占用保证金
+func (p Position) MarginUsed() float64 {
+	return (p.Quantity * p.MarkPrice) / float64(p.Leverage)
+}
+
+// Key 持仓唯一标识（区分多空）
+func (p Position) Key() string {
+	return p.Symbol + "_" + p.Side
+}
+
+// NormalizePosition 将交易所返回的原始持仓 map 转换为标准 Position
+// 缺少 symbol 或无法确定方向时返回 false；数值字段缺失或类型异常时按0处理，不会 panic
+func NormalizePosition(raw map[string]interface{}) (Position, bool) {
+	symbol, _ := raw["symbol"].(string)
+	if symbol == "" {
+		return Position{}, false
+	}
+
+	amt := positionFloat(raw, "positionAmt", "quantity", "size")
+	side := normalizePositionSide(raw["side"], amt)
+	if side == "" {
+		return Position{}, false
+	}
+
+	pos := Position{
+		Symbol:           symbol,
+		Side:             side,
+		EntryPrice:       positionFloat(raw, "entryPrice", "entry_price"),
+		MarkPrice:        positionFloat(raw, "markPrice", "mark_price"),
+		Quantity:         math.Abs(amt),
+		UnrealizedPnL:    positionFloat(raw, "unRealizedProfit", "unrealizedPnl", "unrealized_pnl"),
+		LiquidationPrice: positionFloat(raw, "liquidationPrice", "liquidation_price"),
+		Leverage:         int(positionFloat(raw, "leverage")),
+	}
+	if pos.MarkPrice <= 0 {
+		pos.MarkPrice = pos.EntryPrice
+	}
+	if pos.Leverage <= 0 {
+		pos.Leverage = defaultPositionLeverage
+	}
+	if _, ok := raw["available"]; ok {
+		pos.Available = math.Abs(positionFloat(raw, "available"))
+	} else {
+		pos.Available = pos.Quantity
+	}
+	return pos, true
+}
+
+// NormalizePositions 批量转换原始持仓，跳过无法识别的条目
+func NormalizePositions(raw []map[string]interface{}) []Position {
+	positions := make([]Position, 0, len(raw))
+	for _, r := range raw {
+		if pos, ok := NormalizePosition(r); ok {
+			positions = append(positions, pos)
+		}
+	}
+	return positions
+}
+
+// normalizePositionSide 统一持仓方向为 long/short，缺失或为 BOTH（单向持仓）时按数量正负推断
+func normalizePositionSide(v interface{}, amt float64) string {
+	s, _ := v.(string)
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "long", "buy":
+		return "long"
+	case "short", "sell":
+		return "short"
+	}
+	if amt > 0 {
+		return "long"
+	}
+	if amt < 0 {
+		return "short"
+	}
+	return ""
+}
+
+// positionFloat 按候选字段名依次取值并转换为 float64，兼容数字、字符串和 json.Number
+func positionFloat(raw map[string]interface{}, keys ...string) float64 {
+	for _, key := range keys {
+		v, ok := raw[key]
+		if !ok || v == nil {
+			continue
+		}
+		switch n := v.(type) {
+		case float64:
+			if math.IsNaN(n) || math.IsInf(n, 0) {
+				return 0
+			}
+			return n
+		case float32:
+			return float64(n)
+		case int:
+			return float64(n)
+		case int64:
+			return float64(n)
+		case json.Number:
+			f, _ := n.Float64()
+			return f
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+			if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+				return 0
+			}
+			return f
+		}
+	}
+	return 0
+}
@@ -0,0 +1,44 @@
+package trader
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// RoundPriceToTick 将价格对齐到 tickSize 的整数倍，roundUp 为 true 时向上取整，否则向下取整
+// tickSize<=0（未知）或价格非法时原样返回
+func RoundPriceToTick(price, tickSize float64, roundUp bool) float64 {
+	if tickSize <= 0 || price <= 0 || math.IsNaN(price) || math.IsInf(price, 0) {
+		return price
+	}
+
+	// 容差避免浮点误差把已对齐的价格多推一档（如 0.3/0.1=2.9999999999999996）
+	const epsilon = 1e-9
+	steps := price / tickSize
+	if roundUp {
+		steps = math.Ceil(steps - epsilon)
+	} else {
+		steps = math.Floor(steps + epsilon)
+	}
+	if steps <= 0 {
+		// 价格低于一个 tick 时保留最小有效价格
+		steps = 1
+	}
+
+	// 按 tickSize 的小数位数截断浮点尾差，保证格式化后不会出现多余小数
+	rounded, err := strconv.ParseFloat(strconv.FormatFloat(steps*tickSize, 'f', tickDecimals(tickSize), 64), 64)
+	if err != nil {
+		return steps * tickSize
+	}
+	return rounded
+}
+
+// tickDecimals 计算 tickSize 的小数位数（0.01→2, 0.025→3, 10→0）
+func tickDecimals(tickSize float64) int {
+	s := strconv.FormatFloat(tickSize, 'f', -1, 64)
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		return len(s) - i - 1
+	}
+	return 0
+}
@@ -0,0 +1,118 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"nofx/decision"
+	"nofx/logger"
+)
+
+// DefaultTrailLockFraction 跟踪止损默认锁定的峰值收益比例
+const DefaultTrailLockFraction = 0.5
+
+// SetProfitProtection 【功能】更新盈利保护规则（保本止损/跟踪止损，0表示不启用；lockFraction 为0时使用默认值）
+func (at *AutoTrader) SetProfitProtection(breakevenPct, trailPct, lockFraction float64) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.BreakevenAtProfitPct = breakevenPct
+	at.config.TrailStopAfterProfitPct = trailPct
+	at.config.TrailLockFraction = lockFraction
+}
+
+// profitProtectionStop 根据峰值收益率计算保护性止损价，未触发时返回0
+// 收益率为含杠杆的持仓收益率（与回撤监控一致）：保本阈值触发后止损移至开仓价，
+// 跟踪阈值触发后止损锁定峰值收益的 lockFraction，两者都触发时取更有利的价位
+func profitProtectionStop(pos Position, peakPnLPct, breakevenPct, trailPct, lockFraction float64) (stop float64, action string) {
+	if pos.EntryPrice <= 0 || pos.Leverage <= 0 {
+		return 0, ""
+	}
+	if breakevenPct > 0 && peakPnLPct >= breakevenPct {
+		stop, action = pos.EntryPrice, "breakeven_stop_loss"
+	}
+	if trailPct > 0 && peakPnLPct >= trailPct {
+		if lockFraction <= 0 {
+			lockFraction = DefaultTrailLockFraction
+		}
+		move := peakPnLPct * lockFraction / (float64(pos.Leverage) * 100)
+		trail := pos.EntryPrice * (1 + move)
+		if pos.Side == "short" {
+			trail = pos.EntryPrice * (1 - move)
+		}
+		if stop == 0 || stopImproves(pos.Side, stop, trail) {
+			stop, action = trail, "trailing_stop_loss"
+		}
+	}
+	return stop, action
+}
+
+// stopImproves 新止损是否比当前止损更有利（多单更高、空单更低；当前无止损时视为更有利）
+func stopImproves(side string, current, next float64) bool {
+	if current <= 0 {
+		return true
+	}
+	if side == "short" {
+		return next < current
+	}
+	return next > current
+}
+
+// applyProfitProtection 按盈利保护规则上移（空单下移）止损，只收紧不放松，每次移动写入决策日志
+func (at *AutoTrader) applyProfitProtection(pos Position, currentPnLPct, peakPnLPct float64) {
+	at.mu.RLock()
+	breakevenPct, trailPct, lockFraction := at.config.BreakevenAtProfitPct, at.config.TrailStopAfterProfitPct, at.config.TrailLockFraction
+	at.mu.RUnlock()
+	if breakevenPct <= 0 && trailPct <= 0 {
+		return
+	}
+
+	stop, action := profitProtectionStop(pos, peakPnLPct, breakevenPct, trailPct, lockFraction)
+	if stop <= 0 {
+		return
+	}
+	// 价格已回落到目标止损之外时无法设置（多单止损必须低于现价，空单相反）
+	if (pos.Side == "long" && stop >= pos.MarkPrice) || (pos.Side == "short" && stop <= pos.MarkPrice) {
+		return
+	}
+	currentStop, _ := at.protectiveLevels(pos)
+	if !stopImproves(pos.Side, currentStop, stop) {
+		return
+	}
+
+	note := fmt.Sprintf("收益 %.2f%%（峰值 %.2f%%），止损 %.4f → %.4f", currentPnLPct, peakPnLPct, currentStop, stop)
+	if at.IsAnalysisOnly() {
+		log.Printf("🔒 [%s] 仅分析模式，不调整止损: %s %s", at.name, pos.Key(), note)
+		return
+	}
+	log.Printf("🔒 [%s] 盈利保护调整止损: %s %s", at.name, pos.Key(), note)
+
+	actionRecord := logger.DecisionAction{
+		Action:    action,
+		Symbol:    pos.Symbol,
+		Quantity:  pos.Available,
+		Price:     stop,
+		Timestamp: time.Now(),
+		Note:      note,
+	}
+	d := &decision.Decision{Symbol: pos.Symbol, Action: "update_stop_loss", NewStopLoss: stop}
+	err := at.executeUpdateStopLossWithRecord(d, &logger.DecisionAction{})
+	actionRecord.Success = err == nil
+
+	record := &logger.DecisionRecord{ExecutionLog: []string{}, Success: err == nil}
+	if err != nil {
+		log.Printf("❌ [%s] 盈利保护调整止损失败 (%s): %v", at.name, pos.Key(), err)
+		actionRecord.Error = err.Error()
+		record.ErrorMessage = err.Error()
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s 盈利保护调整止损失败: %v", pos.Symbol, err))
+	} else {
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🔒 %s %s", pos.Symbol, note))
+	}
+	record.Decisions = append(record.Decisions, actionRecord)
+	if err := at.decisionLogger.LogDecision(record); err != nil {
+		log.Printf("⚠ 保存决策记录失败: %v", err)
+	}
+}
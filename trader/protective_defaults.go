@@ -0,0 +1,171 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"nofx/decision"
+	"nofx/market"
+	"nofx/signal"
+)
+
+// protectiveDefaultKey 默认止损/止盈配置中适用于所有未单独配置币种的键
+const protectiveDefaultKey = "default"
+
+// maxProtectiveTakeProfitPct 默认止盈百分比上限（相对开仓价）
+const maxProtectiveTakeProfitPct = 1000.0
+
+// ProtectiveDefault 单个币种的默认止损/止盈百分比（相对开仓价的价格变动，0=不推导该价位）
+type ProtectiveDefault struct {
+	StopLossPct   float64 `json:"sl"`
+	TakeProfitPct float64 `json:"tp"`
+}
+
+// ParseProtectiveDefaults 解析数据库中保存的默认止损/止盈（JSON: 币种或 default -> {sl, tp}），未配置或格式错误时返回 nil
+func ParseProtectiveDefaults(raw string) map[string]ProtectiveDefault {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	var defaults map[string]ProtectiveDefault
+	if err := json.Unmarshal([]byte(raw), &defaults); err != nil {
+		log.Printf("⚠️ 解析默认止损/止盈失败: %v", err)
+		return nil
+	}
+	return NormalizeProtectiveDefaults(defaults)
+}
+
+// NormalizeProtectiveDefaults 统一币种格式（如 btc → BTCUSDT），default 键保持不变，为空时返回 nil
+func NormalizeProtectiveDefaults(defaults map[string]ProtectiveDefault) map[string]ProtectiveDefault {
+	if len(defaults) == 0 {
+		return nil
+	}
+	normalized := make(map[string]ProtectiveDefault, len(defaults))
+	for key, value := range defaults {
+		if strings.EqualFold(strings.TrimSpace(key), protectiveDefaultKey) {
+			normalized[protectiveDefaultKey] = value
+			continue
+		}
+		normalized[market.Normalize(key)] = value
+	}
+	return normalized
+}
+
+// FormatProtectiveDefaults 将默认止损/止盈序列化为数据库保存格式（JSON），未配置时返回空字符串
+func FormatProtectiveDefaults(defaults map[string]ProtectiveDefault) string {
+	if len(defaults) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(defaults)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// ValidateProtectiveDefaults 校验默认止损/止盈：止损百分比在 [0,100) 之间，止盈百分比在 [0,1000] 之间
+func ValidateProtectiveDefaults(defaults map[string]ProtectiveDefault) error {
+	for key, value := range defaults {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("币种不能为空")
+		}
+		if value.StopLossPct < 0 || value.StopLossPct >= 100 {
+			return fmt.Errorf("%s 的默认止损百分比必须在 0 到 100 之间", key)
+		}
+		if value.TakeProfitPct < 0 || value.TakeProfitPct > maxProtectiveTakeProfitPct {
+			return fmt.Errorf("%s 的默认止盈百分比必须在 0 到 %.0f 之间", key, maxProtectiveTakeProfitPct)
+		}
+	}
+	return nil
+}
+
+// SetProtectiveDefaults 【功能】运行时更新默认止损/止盈配置，之后的开仓生效
+func (at *AutoTrader) SetProtectiveDefaults(defaults map[string]ProtectiveDefault) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.ProtectiveDefaults = defaults
+}
+
+// protectiveDefaultFor 币种适用的默认止损/止盈（优先币种配置，其次 default），未配置时返回 false
+func (at *AutoTrader) protectiveDefaultFor(symbol string) (ProtectiveDefault, bool) {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	if value, ok := at.config.ProtectiveDefaults[market.Normalize(symbol)]; ok {
+		return value, true
+	}
+	value, ok := at.config.ProtectiveDefaults[protectiveDefaultKey]
+	return value, ok
+}
+
+// deriveProtectivePrices 按默认百分比从开仓价推导止损/止盈价（多仓止损在下、止盈在上，空仓相反），百分比为 0 时返回 0
+func deriveProtectivePrices(defaults ProtectiveDefault, entryPrice float64, isShort bool) (float64, float64) {
+	direction := 1.0
+	if isShort {
+		direction = -1.0
+	}
+	var stopLoss, takeProfit float64
+	if defaults.StopLossPct > 0 {
+		stopLoss = entryPrice * (1 - direction*defaults.StopLossPct/100)
+	}
+	if defaults.TakeProfitPct > 0 {
+		takeProfit = entryPrice * (1 + direction*defaults.TakeProfitPct/100)
+	}
+	return stopLoss, takeProfit
+}
+
+// applyProtectiveDefaults 【功能】开仓决策缺少止损/止盈时按默认百分比从开仓价推导，返回执行备注（未推导时为空）
+func (at *AutoTrader) applyProtectiveDefaults(d *decision.Decision, entryPrice float64) string {
+	if entryPrice <= 0 || (d.StopLoss > 0 && d.TakeProfit > 0) {
+		return ""
+	}
+	defaults, ok := at.protectiveDefaultFor(d.Symbol)
+	if !ok {
+		return ""
+	}
+	stopLoss, takeProfit := deriveProtectivePrices(defaults, entryPrice, d.Action == "open_short")
+
+	var notes []string
+	if d.StopLoss <= 0 && stopLoss > 0 {
+		d.StopLoss = stopLoss
+		notes = append(notes, fmt.Sprintf("未提供止损，按默认 %.2f%% 设置止损 %.4f", defaults.StopLossPct, stopLoss))
+	}
+	if d.TakeProfit <= 0 && takeProfit > 0 {
+		d.TakeProfit = takeProfit
+		notes = append(notes, fmt.Sprintf("未提供止盈，按默认 %.2f%% 设置止盈 %.4f", defaults.TakeProfitPct, takeProfit))
+	}
+	note := strings.Join(notes, "；")
+	if note != "" {
+		log.Printf("  🛡️ %s %s", d.Symbol, note)
+	}
+	return note
+}
+
+// signalProtectiveLevels 信号模式开仓/加仓后使用的止损/止盈价：信号未给出时按默认百分比从当前价推导
+func (at *AutoTrader) signalProtectiveLevels(strat *signal.SignalDecision, entryPrice float64) (float64, float64) {
+	stopLoss := strat.StopLoss.Price
+	var takeProfit float64
+	if len(strat.TakeProfits) > 0 {
+		takeProfit = strat.TakeProfits[0].Price
+	}
+	if (stopLoss > 0 && takeProfit > 0) || entryPrice <= 0 {
+		return stopLoss, takeProfit
+	}
+	defaults, ok := at.protectiveDefaultFor(strat.Symbol)
+	if !ok {
+		return stopLoss, takeProfit
+	}
+	defaultSL, defaultTP := deriveProtectivePrices(defaults, entryPrice, strings.EqualFold(strat.Direction, "SHORT"))
+	if stopLoss <= 0 && defaultSL > 0 {
+		stopLoss = defaultSL
+		log.Printf("  🛡️ %s 信号未提供止损，按默认 %.2f%% 设置止损 %.4f", strat.Symbol, defaults.StopLossPct, stopLoss)
+	}
+	if takeProfit <= 0 && defaultTP > 0 {
+		takeProfit = defaultTP
+		log.Printf("  🛡️ %s 信号未提供止盈，按默认 %.2f%% 设置止盈 %.4f", strat.Symbol, defaults.TakeProfitPct, takeProfit)
+	}
+	return stopLoss, takeProfit
+}
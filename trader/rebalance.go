@@ -0,0 +1,285 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"nofx/logger"
+	"nofx/market"
+)
+
+// 目标权重再平衡模式默认值
+const (
+	defaultRebalanceTolerancePct    = 2.0 // 偏离目标权重在该百分点以内时不调整
+	defaultRebalanceIntervalMinutes = 60  // 再平衡检查间隔
+	maxRebalanceTolerancePct        = 50.0
+	minRebalanceIntervalMinutes     = 5
+	maxRebalanceIntervalMinutes     = 7 * 24 * 60
+)
+
+// ParseRebalanceTargets 解析数据库中保存的目标权重（JSON: symbol -> 权重百分比），未配置或格式错误时返回 nil
+func ParseRebalanceTargets(raw string) map[string]float64 {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	var targets map[string]float64
+	if err := json.Unmarshal([]byte(raw), &targets); err != nil {
+		log.Printf("⚠️ 解析目标权重失败: %v", err)
+		return nil
+	}
+	return NormalizeRebalanceTargets(targets)
+}
+
+// NormalizeRebalanceTargets 统一目标权重的币种格式（如 btc → BTCUSDT），为空时返回 nil
+func NormalizeRebalanceTargets(targets map[string]float64) map[string]float64 {
+	if len(targets) == 0 {
+		return nil
+	}
+	normalized := make(map[string]float64, len(targets))
+	for symbol, weight := range targets {
+		normalized[market.Normalize(symbol)] += weight
+	}
+	return normalized
+}
+
+// FormatRebalanceTargets 将目标权重序列化为数据库保存格式（JSON），未配置时返回空字符串
+func FormatRebalanceTargets(targets map[string]float64) string {
+	if len(targets) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(targets)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// ValidateRebalanceConfig 校验目标权重再平衡配置：每个权重在 (0,100] 之间且合计不超过 100（占仓位计算基数的百分比，
+// 按名义价值计算，与信号模式总仓位不超过分配资金 100% 的约定一致）；容忍带和检查间隔为 0 时使用默认值
+func ValidateRebalanceConfig(targets map[string]float64, tolerancePct float64, intervalMinutes int) error {
+	total := 0.0
+	for symbol, weight := range targets {
+		if weight <= 0 || weight > 100 {
+			return fmt.Errorf("%s 的目标权重必须在 0 到 100 之间", symbol)
+		}
+		total += weight
+	}
+	if total > 100 {
+		return fmt.Errorf("目标权重合计 %.1f%% 超过 100%%", total)
+	}
+	if tolerancePct < 0 || tolerancePct > maxRebalanceTolerancePct {
+		return fmt.Errorf("再平衡容忍带必须在 0 到 %.0f 之间", maxRebalanceTolerancePct)
+	}
+	if intervalMinutes != 0 && (intervalMinutes < minRebalanceIntervalMinutes || intervalMinutes > maxRebalanceIntervalMinutes) {
+		return fmt.Errorf("再平衡间隔必须在 %d 到 %d 分钟之间", minRebalanceIntervalMinutes, maxRebalanceIntervalMinutes)
+	}
+	return nil
+}
+
+// SetRebalanceConfig 【功能】运行时更新目标权重再平衡配置（目标权重、容忍带、检查间隔），下一次检查生效。
+// 启用/关闭该模式（目标权重从无到有或从有到无）需要重启交易员
+func (at *AutoTrader) SetRebalanceConfig(targets map[string]float64, tolerancePct float64, intervalMinutes int) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.RebalanceTargets = targets
+	at.config.RebalanceTolerancePct = tolerancePct
+	at.config.RebalanceIntervalMinutes = intervalMinutes
+}
+
+// isRebalanceMode 是否运行在目标权重再平衡模式（配置了目标权重）
+func (at *AutoTrader) isRebalanceMode() bool {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return len(at.config.RebalanceTargets) > 0
+}
+
+// rebalanceSettings 当前的目标权重、容忍带和检查间隔（未配置时使用默认值）
+func (at *AutoTrader) rebalanceSettings() (map[string]float64, float64, time.Duration) {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	tolerance := at.config.RebalanceTolerancePct
+	if tolerance <= 0 {
+		tolerance = defaultRebalanceTolerancePct
+	}
+	interval := at.config.RebalanceIntervalMinutes
+	if interval <= 0 {
+		interval = defaultRebalanceIntervalMinutes
+	}
+	targets := make(map[string]float64, len(at.config.RebalanceTargets))
+	for symbol, weight := range at.config.RebalanceTargets {
+		targets[symbol] = weight
+	}
+	return targets, tolerance, time.Duration(interval) * time.Minute
+}
+
+// RunRebalanceMode 目标权重再平衡模式主循环：启动时及每个检查间隔执行一次再平衡（与其他决策周期互斥）
+func (at *AutoTrader) RunRebalanceMode() error {
+	_, _, interval := at.rebalanceSettings()
+	log.Printf("⚖️ 模式: 目标权重再平衡（检查间隔 %v）", interval)
+
+	for at.isRunning {
+		if err := at.runExclusiveCycle(at.runRebalanceCycle); err != nil {
+			log.Printf("⏭ [%s] %v", at.name, err)
+		}
+
+		// 间隔在运行时可修改，每轮重新读取
+		_, _, interval = at.rebalanceSettings()
+		select {
+		case <-time.After(interval):
+		case <-at.stopMonitorCh:
+			log.Println("⏹ 退出目标权重再平衡模式")
+			return nil
+		}
+	}
+	return nil
+}
+
+// runRebalanceCycle 执行一次再平衡并写入决策日志（无需调整时不写入）
+func (at *AutoTrader) runRebalanceCycle() {
+	actions, err := at.rebalancePortfolio()
+	if err != nil {
+		log.Printf("❌ [%s] 再平衡失败: %v", at.name, err)
+	}
+	if len(actions) == 0 && err == nil {
+		return
+	}
+	if at.decisionLogger == nil {
+		return
+	}
+	record := &logger.DecisionRecord{Success: err == nil, Decisions: actions}
+	if err != nil {
+		record.ErrorMessage = fmt.Sprintf("再平衡失败: %v", err)
+	}
+	for _, action := range actions {
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⚖️ %s %s %.4f: %s", action.Action, action.Symbol, action.Quantity, action.Reasoning))
+	}
+	if err := at.decisionLogger.LogDecision(record); err != nil {
+		log.Printf("⚠ 保存决策记录失败: %v", err)
+	}
+}
+
+// rebalancePortfolio 按目标权重调整持仓（只做多）：当前权重 = 多仓名义价值 / 仓位计算基数，
+// 偏离目标超过容忍带时，超配的部分平仓、低配的加仓，调整回目标权重。未在目标权重中的币种不处理。
+// 交易时段外、平台维护中不调整；回撤硬止损/净值止损暂停期间只减仓不加仓；仅分析模式下只记录不下单
+func (at *AutoTrader) rebalancePortfolio() ([]logger.DecisionAction, error) {
+	targets, tolerance, _ := at.rebalanceSettings()
+	if len(targets) == 0 {
+		return nil, nil
+	}
+	now := time.Now()
+	if !at.inTradingWindow(now) || MaintenanceMode() {
+		return nil, nil
+	}
+	addBlocked := at.drawdownStopHalted() || at.equityBracketPaused() != ""
+	analysisOnly := at.IsAnalysisOnly()
+
+	rawPositions, err := at.trader.GetPositions()
+	if err != nil {
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+	longs := make(map[string]Position)
+	for _, pos := range NormalizePositions(rawPositions) {
+		if pos.Side == "long" && pos.Quantity > 0 {
+			longs[pos.Symbol] = pos
+		}
+	}
+	base := at.sizingBase()
+
+	symbols := make([]string, 0, len(targets))
+	for symbol := range targets {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	var actions []logger.DecisionAction
+	for _, symbol := range symbols {
+		target := targets[symbol]
+		pos, held := longs[symbol]
+		price := pos.MarkPrice
+		if !held || price <= 0 {
+			if price, err = at.trader.GetMarketPrice(symbol); err != nil || price <= 0 {
+				log.Printf("⚠️ [%s] 再平衡获取 %s 价格失败: %v", at.name, symbol, err)
+				continue
+			}
+		}
+
+		current := pos.Quantity * price / base * 100
+		drift := current - target
+		if math.Abs(drift) <= tolerance {
+			continue
+		}
+
+		quantity := math.Abs(drift) / 100 * base / price
+		action := logger.DecisionAction{
+			Symbol:    symbol,
+			Quantity:  quantity,
+			Price:     price,
+			Timestamp: now,
+			Reasoning: fmt.Sprintf("目标权重再平衡: 当前 %.2f%% → 目标 %.2f%%（容忍带 ±%.2f%%）", current, target, tolerance),
+		}
+		switch {
+		case drift > 0:
+			action.Action = "partial_close"
+			if held && quantity > pos.Quantity {
+				action.Quantity, quantity = pos.Quantity, pos.Quantity
+			}
+		case addBlocked:
+			action.Action = "open_long"
+			action.Status = "rebalance_add_blocked"
+			action.Note = "回撤硬止损或净值止损暂停期间不加仓"
+			actions = append(actions, action)
+			continue
+		default:
+			action.Action = "open_long"
+		}
+
+		if analysisOnly {
+			action.Status = "not_executed"
+			actions = append(actions, action)
+			continue
+		}
+
+		log.Printf("⚖️ [%s] %s %s %.4f（%s）", at.name, action.Action, symbol, quantity, action.Reasoning)
+		var order map[string]interface{}
+		if action.Action == "partial_close" {
+			order, err = at.closePositionQuantity(symbol, "long", quantity)
+		} else {
+			leverage := at.rebalanceLeverage(symbol)
+			action.Leverage = leverage
+			order, err = at.trader.OpenLong(symbol, quantity, leverage)
+		}
+		if err != nil {
+			action.Error = err.Error()
+		} else {
+			action.Success = true
+			if orderID, ok := order["orderId"].(int64); ok {
+				action.OrderID = orderID
+			}
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+// rebalanceLeverage 再平衡加仓使用的杠杆：按币种取交易员配置的杠杆，并限制在系统上限以内
+func (at *AutoTrader) rebalanceLeverage(symbol string) int {
+	at.mu.RLock()
+	leverage := at.config.AltcoinLeverage
+	if symbol == "BTCUSDT" || symbol == "ETHUSDT" {
+		leverage = at.config.BTCETHLeverage
+	}
+	at.mu.RUnlock()
+	if leverage <= 0 {
+		leverage = 5
+	}
+	leverage, _ = ClampLeverageToCeiling(symbol, leverage)
+	return leverage
+}
@@ -0,0 +1,59 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/logger"
+)
+
+const (
+	// DefaultRecentTradesCount 决策上下文默认附带的最近平仓交易笔数
+	DefaultRecentTradesCount = 5
+	// MaxRecentTradesCount 最近平仓交易笔数上限（历史表现分析最多保留10笔，同时控制 prompt 长度）
+	MaxRecentTradesCount = 10
+)
+
+// SetRecentClosedTrades 【功能】更新决策上下文是否附带最近平仓交易及笔数（count<=0 时使用默认值）
+func (at *AutoTrader) SetRecentClosedTrades(include bool, count int) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.IncludeRecentTrades = include
+	at.config.RecentTradesCount = count
+}
+
+// recentClosedTrades 从历史表现分析中截取最近N笔平仓交易（最新在前），未开启时返回 nil
+func (at *AutoTrader) recentClosedTrades(performance *logger.PerformanceAnalysis) []decision.ClosedTradeInfo {
+	at.mu.RLock()
+	include, count := at.config.IncludeRecentTrades, at.config.RecentTradesCount
+	at.mu.RUnlock()
+
+	if !include || performance == nil || len(performance.RecentTrades) == 0 {
+		return nil
+	}
+	if count <= 0 {
+		count = DefaultRecentTradesCount
+	}
+	if count > MaxRecentTradesCount {
+		count = MaxRecentTradesCount
+	}
+	if count > len(performance.RecentTrades) {
+		count = len(performance.RecentTrades)
+	}
+
+	trades := make([]decision.ClosedTradeInfo, 0, count)
+	for _, t := range performance.RecentTrades[:count] {
+		trades = append(trades, decision.ClosedTradeInfo{
+			Symbol:      t.Symbol,
+			Side:        t.Side,
+			EntryPrice:  t.OpenPrice,
+			ExitPrice:   t.ClosePrice,
+			PnL:         t.PnL,
+			PnLPct:      t.PnLPct,
+			HoldingTime: t.Duration,
+			Reasoning:   t.OpenReasoning,
+		})
+	}
+	return trades
+}
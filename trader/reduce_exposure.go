@@ -0,0 +1,166 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// ReduceResult 单个持仓的整体减仓结果
+type ReduceResult struct {
+	Symbol            string  `json:"symbol"`
+	Side              string  `json:"side"`
+	ClosedQuantity    float64 `json:"closed_quantity"`
+	RemainingQuantity float64 `json:"remaining_quantity"`
+	Success           bool    `json:"success"`
+	Reprotected       bool    `json:"reprotected"` // 是否已按剩余数量重设止盈止损
+	Error             string  `json:"error,omitempty"`
+	Note              string  `json:"note,omitempty"`
+}
+
+// closePositionQuantity 按数量平掉 side("long"/"short") 方向的部分持仓
+// 交易器支持 PartialCloser 时使用只减仓市价单，否则回退到 CloseLong/CloseShort
+func (at *AutoTrader) closePositionQuantity(symbol, side string, quantity float64) (map[string]interface{}, error) {
+	if closer, ok := at.trader.(PartialCloser); ok {
+		return closer.ReducePosition(symbol, side, quantity)
+	}
+	if side == "short" {
+		return at.trader.CloseShort(symbol, quantity)
+	}
+	return at.trader.CloseLong(symbol, quantity)
+}
+
+// partialCloseSupported 交易器是否支持不撤销其余挂单的只减仓（备用密钥包装时按当前使用的交易器判断）
+func (at *AutoTrader) partialCloseSupported() bool {
+	t := at.trader
+	if f, ok := t.(*failoverTrader); ok {
+		t, _ = f.current()
+	}
+	_, ok := t.(PartialCloser)
+	return ok
+}
+
+// protectiveLevels 获取持仓当前的止损/止盈价：优先使用最近决策记录的价位，缺失时从交易所挂单中读取
+func (at *AutoTrader) protectiveLevels(pos Position) (stopLoss, takeProfit float64) {
+	at.positionPyramidMu.Lock()
+	state := at.positionPyramid[pos.Key()]
+	at.positionPyramidMu.Unlock()
+	stopLoss, takeProfit = state.stopLoss, state.takeProfit
+	if stopLoss > 0 && takeProfit > 0 {
+		return stopLoss, takeProfit
+	}
+
+	openOrders, err := at.trader.GetOpenOrders(pos.Symbol)
+	if err != nil {
+		return stopLoss, takeProfit
+	}
+	for _, order := range openOrders {
+		price, _ := order["price"].(float64)
+		if price <= 0 {
+			continue
+		}
+		slOnly, tpOnly := hasProtectiveOrders([]map[string]interface{}{order})
+		if slOnly && stopLoss <= 0 {
+			stopLoss = price
+		}
+		if tpOnly && takeProfit <= 0 {
+			takeProfit = price
+		}
+	}
+	return stopLoss, takeProfit
+}
+
+// ReduceAllPositions 将所有持仓按同一比例（0-100）减仓，用于整体降低风险敞口。
+// 每个持仓按比例只减仓，剩余仓位的止盈止损按剩余数量重设；返回每个持仓的执行结果
+func (at *AutoTrader) ReduceAllPositions(pct float64) ([]ReduceResult, error) {
+	if pct > 100 {
+		pct = 100
+	}
+	if pct <= 0 {
+		return nil, fmt.Errorf("减仓比例必须大于0")
+	}
+	if at.IsAnalysisOnly() {
+		return nil, fmt.Errorf("仅分析模式下不执行减仓")
+	}
+
+	var results []ReduceResult
+	var runErr error
+	err := at.runExclusiveCycle(func() {
+		positions, err := at.trader.GetPositions()
+		if err != nil {
+			runErr = fmt.Errorf("获取持仓失败: %w", err)
+			return
+		}
+
+		log.Printf("📉 [%s] 整体减仓 %.1f%%", at.name, pct)
+		for _, pos := range NormalizePositions(positions) {
+			if pos.Quantity == 0 {
+				continue
+			}
+			results = append(results, at.reducePosition(pos, pct))
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, runErr
+}
+
+// reducePosition 按比例减仓单个持仓，并按剩余数量重设止盈止损
+func (at *AutoTrader) reducePosition(pos Position, pct float64) ReduceResult {
+	result := ReduceResult{Symbol: pos.Symbol, Side: pos.Side, RemainingQuantity: pos.Quantity}
+	stopLoss, takeProfit := at.protectiveLevels(pos)
+
+	// 不支持只减仓的交易所回退到 CloseLong/CloseShort，会撤销该币种所有挂单；
+	// 此时若不知道止盈止损价位，剩余仓位将失去保护，拒绝部分减仓
+	if pct < 100 && stopLoss <= 0 && takeProfit <= 0 && !at.partialCloseSupported() {
+		result.Error = "交易所平仓会撤销所有挂单且未知止盈止损价位，拒绝部分减仓（避免剩余仓位失去保护）"
+		log.Printf("  ⚠ %s %s", pos.Key(), result.Error)
+		return result
+	}
+
+	closeQty := pos.Quantity * pct / 100
+	if _, err := at.closePositionQuantity(pos.Symbol, pos.Side, closeQty); err != nil {
+		log.Printf("  ❌ %s 减仓失败: %v", pos.Key(), err)
+		result.Error = err.Error()
+		return result
+	}
+	result.Success = true
+	result.ClosedQuantity = closeQty
+	result.RemainingQuantity = pos.Quantity - closeQty
+	log.Printf("  ✓ %s 减仓 %.4f（%.1f%%），剩余 %.4f", pos.Key(), closeQty, pct, result.RemainingQuantity)
+
+	if pct >= 100 {
+		at.ClearPeakPnLCache(pos.Symbol, pos.Side)
+		at.forgetPositionFirstSeen(pos.Key())
+		return result
+	}
+	if stopLoss <= 0 && takeProfit <= 0 {
+		result.Note = "未知止盈止损价位，未重设保护单"
+		return result
+	}
+
+	// 原保护单按减仓前数量设置（部分交易所平仓时会一并撤销），按剩余数量重设
+	if err := at.trader.CancelStopOrders(pos.Symbol); err != nil {
+		log.Printf("  ⚠ 取消旧止盈止损单失败: %v", err)
+	}
+	positionSide := strings.ToUpper(pos.Side)
+	var errs []string
+	if stopLoss > 0 {
+		if err := at.setStopLossWithRetry(pos.Symbol, positionSide, result.RemainingQuantity, stopLoss); err != nil {
+			errs = append(errs, fmt.Sprintf("重设止损失败: %v", err))
+		}
+	}
+	if takeProfit > 0 {
+		if err := at.setTakeProfitWithRetry(pos.Symbol, positionSide, result.RemainingQuantity, takeProfit); err != nil {
+			errs = append(errs, fmt.Sprintf("重设止盈失败: %v", err))
+		}
+	}
+	if len(errs) > 0 {
+		result.Note = strings.Join(errs, "; ")
+		log.Printf("  ⚠ %s %s", pos.Key(), result.Note)
+	} else {
+		result.Reprotected = true
+	}
+	return result
+}
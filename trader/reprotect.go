@@ -0,0 +1,138 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"nofx/logger"
+)
+
+// hasProtectiveOrders 判断挂单中是否包含止损单/止盈单（兼容各交易所的计划单类型）
+func hasProtectiveOrders(openOrders []map[string]interface{}) (hasStopLoss, hasTakeProfit bool) {
+	for _, order := range openOrders {
+		orderType, _ := order["type"].(string)
+		switch orderType {
+		case "stop_loss", "loss_plan", "pos_loss":
+			hasStopLoss = true
+		case "take_profit", "profit_plan", "pos_profit":
+			hasTakeProfit = true
+		}
+	}
+	return hasStopLoss, hasTakeProfit
+}
+
+// SetAutoReprotect 【功能】运行时切换决策周期后的保护单校验（无需重启）
+func (at *AutoTrader) SetAutoReprotect(enabled bool) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	at.config.AutoReprotect = enabled
+	at.mu.Unlock()
+	at.warnReprotectUnsupported()
+}
+
+// warnReprotectUnsupported 开启了保护单校验但交易所不支持查询当前委托时告警（该功能不会生效）
+func (at *AutoTrader) warnReprotectUnsupported() {
+	at.mu.RLock()
+	enabled := at.config.AutoReprotect
+	at.mu.RUnlock()
+	if !enabled {
+		return
+	}
+	if _, err := at.trader.GetOpenOrders(""); errors.Is(err, ErrOpenOrdersNotSupported) {
+		log.Printf("⚠️ [%s] 已开启保护单自动补设（auto_reprotect），但 %s 不支持查询当前委托，该功能不会生效", at.name, at.exchange)
+	}
+}
+
+// reprotectPositions 决策周期结束后校验每个持仓的止损/止盈单是否仍在交易所
+// （可能因部分成交等被交易所撤销），缺失时按最近一次决策的价位补设，并将补设动作写入决策记录。
+// 仅在开启 AutoReprotect 时执行；不知道价位的持仓（如重启前开的仓）只记录日志不补设。
+// 模拟 OCO 的联动先于补设处理（不受 AutoReprotect 开关影响），避免已平仓持仓遗留的保护单
+func (at *AutoTrader) reprotectPositions(record *logger.DecisionRecord) {
+	at.reconcileOCOOrders()
+
+	at.mu.RLock()
+	enabled := at.config.AutoReprotect
+	at.mu.RUnlock()
+	if !enabled {
+		return
+	}
+
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		log.Printf("⚠️ [保护单校验] 获取持仓失败: %v", err)
+		return
+	}
+
+	for _, pos := range NormalizePositions(positions) {
+		if pos.Quantity == 0 {
+			continue
+		}
+
+		at.positionPyramidMu.Lock()
+		state := at.positionPyramid[pos.Key()]
+		at.positionPyramidMu.Unlock()
+		if state.stopLoss <= 0 && state.takeProfit <= 0 {
+			log.Printf("  ℹ️  [保护单校验] %s 没有最近决策的止损/止盈价位，跳过", pos.Key())
+			continue
+		}
+
+		openOrders, err := at.trader.GetOpenOrders(pos.Symbol)
+		if errors.Is(err, ErrOpenOrdersNotSupported) {
+			// 无法确认保护单是否存在，不能盲目补设（会重复下单）
+			log.Printf("  ℹ️  [保护单校验] 交易所不支持查询当前委托，跳过保护单补设")
+			return
+		}
+		if err != nil {
+			log.Printf("⚠️ [保护单校验] 获取 %s 委托失败: %v", pos.Symbol, err)
+			continue
+		}
+		hasStopLoss, hasTakeProfit := hasProtectiveOrders(openOrders)
+
+		quantity := pos.Available
+		if quantity <= 0 {
+			quantity = pos.Quantity
+		}
+		positionSide := strings.ToUpper(pos.Side)
+
+		if state.stopLoss > 0 && !hasStopLoss {
+			log.Printf("  🛡️ [保护单校验] %s 止损单缺失，按 %.4f 补设", pos.Key(), state.stopLoss)
+			err := at.setStopLossWithRetry(pos.Symbol, positionSide, quantity, state.stopLoss)
+			at.recordReprotect(record, "reprotect_stop_loss", pos, quantity, state.stopLoss, err)
+		}
+		if state.takeProfit > 0 && !hasTakeProfit {
+			log.Printf("  💰 [保护单校验] %s 止盈单缺失，按 %.4f 补设", pos.Key(), state.takeProfit)
+			err := at.setTakeProfitWithRetry(pos.Symbol, positionSide, quantity, state.takeProfit)
+			at.recordReprotect(record, "reprotect_take_profit", pos, quantity, state.takeProfit, err)
+		}
+	}
+}
+
+// recordReprotect 将一次保护单补设写入决策记录
+func (at *AutoTrader) recordReprotect(record *logger.DecisionRecord, action string, pos Position, quantity, price float64, err error) {
+	label := "止损"
+	if action == "reprotect_take_profit" {
+		label = "止盈"
+	}
+	actionRecord := logger.DecisionAction{
+		Action:    action,
+		Symbol:    pos.Symbol,
+		Quantity:  quantity,
+		Price:     price,
+		Timestamp: time.Now(),
+		Success:   err == nil,
+		Note:      fmt.Sprintf("%s 持仓的%s单在交易所缺失，按最近决策价位 %.4f 补设", pos.Side, label, price),
+	}
+	if err != nil {
+		log.Printf("  ❌ [保护单校验] %s 补设%s失败: %v", pos.Key(), label, err)
+		actionRecord.Error = err.Error()
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s 补设%s失败: %v", pos.Symbol, label, err))
+	} else {
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🛡️ %s 补设%s %.4f", pos.Symbol, label, price))
+	}
+	record.Decisions = append(record.Decisions, actionRecord)
+}
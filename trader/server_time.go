@@ -0,0 +1,138 @@
+package trader
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// 签名请求的 recvWindow（毫秒，币安/Aster：请求时间戳与服务器时间相差超过该值时拒绝请求）
+const (
+	DefaultRecvWindowMillis = 50000 // 默认值（与此前 Aster 写死的值一致）
+	MaxRecvWindowMillis     = 60000 // 交易所允许的最大值
+)
+
+// recvWindowMillis 签名请求的 recvWindow（system_config recv_window_ms 的内存缓存）
+var recvWindowMillis atomic.Int64
+
+// SetRecvWindow 更新签名请求的 recvWindow（<=0 时使用默认值，超过交易所上限时取上限）
+func SetRecvWindow(ms int64) {
+	if ms <= 0 {
+		ms = DefaultRecvWindowMillis
+	}
+	if ms > MaxRecvWindowMillis {
+		ms = MaxRecvWindowMillis
+	}
+	recvWindowMillis.Store(ms)
+}
+
+// RecvWindowMillis 当前签名请求使用的 recvWindow（毫秒）
+func RecvWindowMillis() int64 {
+	if ms := recvWindowMillis.Load(); ms > 0 {
+		return ms
+	}
+	return DefaultRecvWindowMillis
+}
+
+// serverTimeSyncInterval 定期重新校准交易所服务器时间的间隔（本地时钟可能持续漂移）
+const serverTimeSyncInterval = 30 * time.Minute
+
+// clockSkewWarnThreshold 本地时钟与交易所相差超过该值时告警
+const clockSkewWarnThreshold = time.Second
+
+// ClockSyncer 支持按交易所服务器时间修正签名时间戳的交易器（可选能力，使用时间戳签名的中心化交易所实现）
+type ClockSyncer interface {
+	// SyncServerTime 查询交易所服务器时间并更新时钟偏移，返回偏移量（服务器时间 - 本地时间）
+	SyncServerTime() (time.Duration, error)
+}
+
+// serverClock 交易所服务器时钟偏移（服务器时间 - 本地时间，毫秒），签名请求的时间戳按此修正
+type serverClock struct {
+	offsetMs atomic.Int64
+}
+
+// nowMillis 按偏移修正后的当前时间戳（毫秒）
+func (c *serverClock) nowMillis() int64 {
+	return time.Now().UnixMilli() + c.offsetMs.Load()
+}
+
+// offset 当前的时钟偏移
+func (c *serverClock) offset() time.Duration {
+	return time.Duration(c.offsetMs.Load()) * time.Millisecond
+}
+
+// sync 查询服务器时间并更新偏移（本地时间取请求往返的中点，抵消网络延迟）
+func (c *serverClock) sync(getServerTime func() (int64, error)) (time.Duration, error) {
+	start := time.Now().UnixMilli()
+	serverTime, err := getServerTime()
+	if err != nil {
+		return 0, err
+	}
+	end := time.Now().UnixMilli()
+	c.offsetMs.Store(serverTime - (start+end)/2)
+	return c.offset(), nil
+}
+
+// syncServerClock 校准交易所服务器时间并记录偏移（交易器不支持时忽略）
+func (at *AutoTrader) syncServerClock() {
+	syncer, ok := at.trader.(ClockSyncer)
+	if !ok {
+		return
+	}
+	offset, err := syncer.SyncServerTime()
+	if err != nil {
+		log.Printf("⚠️ [%s] 同步交易所 %s 服务器时间失败，沿用上次的时钟偏移: %v", at.name, at.exchange, err)
+		return
+	}
+
+	at.mu.Lock()
+	at.clockOffset = offset
+	at.clockSyncedAt = time.Now()
+	at.mu.Unlock()
+
+	if offset >= clockSkewWarnThreshold || offset <= -clockSkewWarnThreshold {
+		log.Printf("⚠️ [%s] 本地时钟与交易所 %s 相差 %dms，已按服务器时间修正签名时间戳（建议开启 NTP 同步）",
+			at.name, at.exchange, offset.Milliseconds())
+		return
+	}
+	log.Printf("⏱ [%s] 已同步交易所 %s 服务器时间，时钟偏移 %dms", at.name, at.exchange, offset.Milliseconds())
+}
+
+// startServerClockSync 启动时校准一次交易所服务器时间，之后定期重新校准
+func (at *AutoTrader) startServerClockSync() {
+	if _, ok := at.trader.(ClockSyncer); !ok {
+		return
+	}
+	at.syncServerClock()
+
+	at.monitorWg.Add(1)
+	go func() {
+		defer at.monitorWg.Done()
+
+		ticker := time.NewTicker(serverTimeSyncInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				at.syncServerClock()
+			case <-at.stopMonitorCh:
+				return
+			}
+		}
+	}()
+}
+
+// clockSkewStatus 时钟偏移状态（用于状态接口，未校准时为 nil）
+func (at *AutoTrader) clockSkewStatus() map[string]interface{} {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	if at.clockSyncedAt.IsZero() {
+		return nil
+	}
+	return map[string]interface{}{
+		"offset_ms":      at.clockOffset.Milliseconds(),
+		"synced_at":      at.clockSyncedAt.Format(time.RFC3339),
+		"recv_window_ms": RecvWindowMillis(),
+	}
+}
@@ -0,0 +1,107 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"nofx/decision"
+	"nofx/signal"
+)
+
+// SignalPreview 信号模式执行计划预览：对账差异 + AI生成的计划动作（不下单）
+type SignalPreview struct {
+	Symbol            string                `json:"symbol"`
+	DiffDetected      bool                  `json:"diff_detected"`         // 交易所挂单/成交与策略是否存在差异
+	DiffReport        string                `json:"diff_report,omitempty"` // 发送给AI的差异报告
+	MissingPoints     []SignalMissingPoint  `json:"missing_points"`        // 缺失的入场/补仓点位
+	MissingStopLoss   bool                  `json:"missing_stop_loss"`     // 有持仓但缺少止损单
+	MissingTakeProfit bool                  `json:"missing_take_profit"`   // 有持仓但缺少止盈单
+	Actions           []SignalPlannedAction `json:"actions"`               // 计划执行的动作（入场、补仓、止盈止损等）
+	AIResponse        string                `json:"ai_response,omitempty"` // AI原始回复
+	Error             string                `json:"error,omitempty"`       // 生成计划失败的原因
+}
+
+// SignalMissingPoint 缺失的入场/补仓点位
+type SignalMissingPoint struct {
+	Kind    string  `json:"kind"` // entry / add_1 / add_2 ...
+	Price   float64 `json:"price"`
+	Percent float64 `json:"percent"`
+}
+
+// SignalPlannedAction 计划执行的动作
+type SignalPlannedAction struct {
+	decision.Decision
+	Source string `json:"source"`         // ai=AI决策, fallback=AI只返回wait时按缺失点位兜底补单
+	Kind   string `json:"kind,omitempty"` // 兜底补单对应的点位
+}
+
+// fail 记录生成计划失败的原因（preview 为 nil 时忽略，便于执行路径直接调用）
+func (p *SignalPreview) fail(err error) {
+	if p == nil || err == nil {
+		return
+	}
+	p.Error = err.Error()
+}
+
+// planFallback AI只返回wait时，按缺失点位记录兜底补单计划（与 placeMissingLimitOrdersFallback 下单内容一致）
+func (p *SignalPreview) planFallback(strat *signal.SignalDecision, missing []expectedPoint, totalInvestmentUSD float64) {
+	for _, m := range missing {
+		if d := fallbackLimitOrderDecision(strat, m, totalInvestmentUSD); d != nil {
+			p.Actions = append(p.Actions, SignalPlannedAction{Decision: *d, Source: "fallback", Kind: m.kind})
+		}
+	}
+}
+
+// fallbackLimitOrderDecision 缺失点位的兜底限价单（保证金 = 资金基数 × 点位比例，按策略杠杆放大为名义价值）
+func fallbackLimitOrderDecision(strat *signal.SignalDecision, m expectedPoint, totalInvestmentUSD float64) *decision.Decision {
+	if m.price <= 0 || m.percent <= 0 {
+		return nil
+	}
+	leverage := strat.LeverageRecommend
+	if leverage <= 0 {
+		leverage = 5
+	}
+	action := "place_long_order"
+	if strings.ToUpper(strings.TrimSpace(strat.Direction)) == "SHORT" {
+		action = "place_short_order"
+	}
+	return &decision.Decision{
+		Symbol:          strat.Symbol,
+		Action:          action,
+		Leverage:        leverage,
+		PositionSizeUSD: totalInvestmentUSD * m.percent * float64(leverage),
+		Price:           m.price,
+		Reasoning:       "Fallback placement due to missing order detected by diff audit.",
+	}
+}
+
+// PreviewSignalStrategy 【功能】预览信号模式对该策略的执行计划：只读对账 + AI决策，在下单前截断，不撤单也不下单
+func (at *AutoTrader) PreviewSignalStrategy(strat *signal.SignalDecision) (*SignalPreview, error) {
+	if strat == nil || strings.TrimSpace(strat.Symbol) == "" {
+		return nil, errors.New("策略缺少交易对")
+	}
+	if strat.Entry.PriceTarget <= 0 && len(strat.Adds) == 0 && strat.StopLoss.Price <= 0 && len(strat.TakeProfits) == 0 {
+		return nil, errors.New("策略缺少入场/补仓/止盈止损点位")
+	}
+
+	diff, report, missing, missingSL, missingTP := at.diffStrategyAgainstExchange(strat, at.getStrategyReceivedAt(strat.SignalID))
+	preview := &SignalPreview{
+		Symbol:            strat.Symbol,
+		DiffDetected:      diff,
+		DiffReport:        report,
+		MissingPoints:     make([]SignalMissingPoint, 0, len(missing)),
+		MissingStopLoss:   missingSL,
+		MissingTakeProfit: missingTP,
+		Actions:           []SignalPlannedAction{},
+	}
+	for _, m := range missing {
+		preview.MissingPoints = append(preview.MissingPoints, SignalMissingPoint{Kind: m.kind, Price: m.price, Percent: m.percent})
+	}
+
+	at.checkAndExecuteStrategyWithAI(strat, report, missing, missingSL, missingTP, preview)
+	if preview.Error != "" {
+		return preview, fmt.Errorf("生成执行计划失败: %s", preview.Error)
+	}
+	return preview, nil
+}
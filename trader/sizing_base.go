@@ -0,0 +1,41 @@
+package trader
+
+// 仓位计算基数
+const (
+	SizingBaseFixed  = "fixed"  // 固定使用初始余额（默认）
+	SizingBaseEquity = "equity" // 使用账户实时净值（盈利后仓位随之放大，亏损后随之缩小）
+)
+
+// defaultSizingBalance 初始余额未设置时的仓位计算兜底金额
+const defaultSizingBalance = 1000.0
+
+// ValidSizingBase 校验仓位计算基数（空值表示使用默认 fixed）
+func ValidSizingBase(base string) bool {
+	return base == "" || base == SizingBaseFixed || base == SizingBaseEquity
+}
+
+// SetSizingBase 【功能】运行时更新仓位计算基数
+func (at *AutoTrader) SetSizingBase(base string) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.SizingBase = base
+}
+
+// sizingBase 仓位百分比对应的资金基数：fixed 使用初始余额，equity 使用账户实时净值（获取失败时回退到初始余额）
+func (at *AutoTrader) sizingBase() float64 {
+	at.mu.RLock()
+	mode := at.config.SizingBase
+	at.mu.RUnlock()
+
+	base := at.initialBalance
+	if mode == SizingBaseEquity {
+		base = at.accountEquity()
+	}
+	if base <= 0 {
+		base = defaultSizingBalance
+	}
+	return base
+}
@@ -0,0 +1,98 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+
+	"nofx/logger"
+)
+
+// ValidStopApproachAlertPct 校验止损接近提醒阈值（0 表示关闭）
+func ValidStopApproachAlertPct(pct float64) bool {
+	return pct >= 0 && pct < 100
+}
+
+// SetStopApproachAlertPct 【功能】运行时更新止损接近提醒阈值（0=关闭）
+func (at *AutoTrader) SetStopApproachAlertPct(pct float64) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.StopApproachAlertPct = pct
+}
+
+// stopDistancePct 标记价格距止损价的距离（占标记价格的百分比），已越过止损时为负数
+func stopDistancePct(pos Position, stopLoss float64) float64 {
+	if pos.Side == "short" {
+		return (stopLoss - pos.MarkPrice) / pos.MarkPrice * 100
+	}
+	return (pos.MarkPrice - stopLoss) / pos.MarkPrice * 100
+}
+
+// checkStopApproach 标记价格距止损价在 StopApproachAlertPct 以内时推送一次提醒（由回撤监控每分钟调用）。
+// 每个持仓按止损价只提醒一次，止损价调整后可再次提醒，持仓消失后清除记录。返回本次推送的提醒数
+func (at *AutoTrader) checkStopApproach(positions []Position) int {
+	at.mu.RLock()
+	alertPct := at.config.StopApproachAlertPct
+	at.mu.RUnlock()
+
+	at.stopApproachMu.Lock()
+	current := make(map[string]bool, len(positions))
+	for _, pos := range positions {
+		current[pos.Key()] = true
+	}
+	for key := range at.stopApproachAlerted {
+		if !current[key] {
+			delete(at.stopApproachAlerted, key)
+		}
+	}
+	at.stopApproachMu.Unlock()
+	if alertPct <= 0 {
+		return 0
+	}
+
+	alerts := 0
+	for _, pos := range positions {
+		if pos.MarkPrice <= 0 {
+			continue
+		}
+		stopLoss, _ := at.protectiveLevels(pos)
+		if stopLoss <= 0 {
+			continue
+		}
+		distancePct := stopDistancePct(pos, stopLoss)
+		if distancePct > alertPct {
+			continue
+		}
+
+		posKey := pos.Key()
+		at.stopApproachMu.Lock()
+		alertedStop, alerted := at.stopApproachAlerted[posKey]
+		if alerted && alertedStop == stopLoss {
+			at.stopApproachMu.Unlock()
+			continue
+		}
+		if at.stopApproachAlerted == nil {
+			at.stopApproachAlerted = make(map[string]float64)
+		}
+		at.stopApproachAlerted[posKey] = stopLoss
+		at.stopApproachMu.Unlock()
+
+		log.Printf("⚠️ [%s] %s 接近止损: 标记价格 %.4f，止损 %.4f（距离 %.2f%%，提醒阈值 %.2f%%）",
+			at.name, posKey, pos.MarkPrice, stopLoss, distancePct, alertPct)
+		at.emitEvent(logger.EventStopApproach,
+			fmt.Sprintf("⚠️ [%s] %s %s 接近止损: 标记价格 %.4f，止损 %.4f（距离 %.2f%%）",
+				at.name, pos.Symbol, pos.Side, pos.MarkPrice, stopLoss, distancePct),
+			map[string]interface{}{
+				"symbol":       pos.Symbol,
+				"side":         pos.Side,
+				"mark_price":   pos.MarkPrice,
+				"stop_loss":    stopLoss,
+				"distance_pct": distancePct,
+				"alert_pct":    alertPct,
+			})
+		alerts++
+	}
+	return alerts
+}
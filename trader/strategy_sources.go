@@ -0,0 +1,22 @@
+package trader
+
+import (
+	"nofx/signal"
+)
+
+// SetStrategySources 【功能】运行时更新信号模式订阅的策略来源（为空表示跟随所有来源），新到的策略立即按新订阅路由
+func (at *AutoTrader) SetStrategySources(sources []string) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.StrategySources = sources
+}
+
+// subscribesToStrategySource 交易员是否订阅了该来源的策略（作为 signal.SourceFilter 交给全局策略管理器路由）
+func (at *AutoTrader) subscribesToStrategySource(source string) bool {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return signal.MatchSource(at.config.StrategySources, source)
+}
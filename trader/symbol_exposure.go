@@ -0,0 +1,82 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"nofx/decision"
+)
+
+// ErrSymbolExposureCap 单币种敞口已达到上限
+var ErrSymbolExposureCap = errors.New("单币种敞口达到上限")
+
+// SetMaxPerSymbolExposurePct 【功能】更新单币种最大敞口（占账户净值百分比，0表示不限制）
+func (at *AutoTrader) SetMaxPerSymbolExposurePct(pct float64) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.MaxPerSymbolExposurePct = pct
+}
+
+// accountEquity 账户净值（钱包余额+未实现盈亏），获取失败时使用初始余额
+func (at *AutoTrader) accountEquity() float64 {
+	equity := at.initialBalance
+	if balance, err := at.trader.GetBalance(); err == nil {
+		wallet, _ := balance["totalWalletBalance"].(float64)
+		unrealized, _ := balance["totalUnrealizedProfit"].(float64)
+		if wallet+unrealized > 0 {
+			equity = wallet + unrealized
+		}
+	}
+	return equity
+}
+
+// clampToSymbolExposure 按单币种敞口上限校验开仓/加仓：该币种现有持仓名义价值（多空合计）加本次开仓
+// 超过 净值×上限 时将 PositionSizeUSD 下调到剩余额度并返回备注，已无剩余额度时返回 ErrSymbolExposureCap
+func (at *AutoTrader) clampToSymbolExposure(d *decision.Decision, price float64) (string, error) {
+	at.mu.RLock()
+	pct := at.config.MaxPerSymbolExposurePct
+	at.mu.RUnlock()
+	if pct <= 0 || d.PositionSizeUSD <= 0 {
+		return "", nil
+	}
+
+	current := 0.0
+	if raw, err := at.trader.GetPositions(); err == nil {
+		for _, pos := range NormalizePositions(raw) {
+			if pos.Symbol == d.Symbol {
+				current += pos.Quantity * price
+			}
+		}
+	}
+	limit := at.accountEquity() * pct / 100
+	room := limit - current
+	if room <= 0 {
+		return "", fmt.Errorf("%w: %s 现有仓位价值 %.2f USDT 已达到单币种上限 %.2f USDT（净值的 %.1f%%），拒绝开仓",
+			ErrSymbolExposureCap, d.Symbol, current, limit, pct)
+	}
+	if d.PositionSizeUSD <= room {
+		return "", nil
+	}
+
+	note := fmt.Sprintf("开仓金额 %.2f USDT 超过单币种上限 %.2f USDT（净值的 %.1f%%，现有 %.2f USDT），已下调为 %.2f USDT",
+		d.PositionSizeUSD, limit, pct, current, room)
+	log.Printf("  ⚠️ %s %s", d.Symbol, note)
+	d.PositionSizeUSD = room
+	return note, nil
+}
+
+// joinNotes 合并执行备注（忽略空备注）
+func joinNotes(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case b == "":
+		return a
+	default:
+		return a + "；" + b
+	}
+}
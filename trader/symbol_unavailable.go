@@ -0,0 +1,156 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"nofx/decision"
+	"nofx/logger"
+)
+
+// symbolUnavailableRecheck 非持仓币种被标记为下架/暂停后，超过该时长重新作为候选币种试探（持仓币种每个周期都会检查）
+const symbolUnavailableRecheck = 6 * time.Hour
+
+// symbolUnavailablePatterns 币种已下架/暂停交易时交易所或行情接口返回的错误特征（小写匹配），
+// 包括行情为空/无效（已下架币种的K线不再更新）
+var symbolUnavailablePatterns = []string{
+	"invalid symbol", // Binance -1121
+	"-1121",
+	"symbol is closed",
+	"symbol not found",
+	"symbol does not exist",
+	"symbol not exist",
+	"delist",
+	"suspend",
+	"not trading",
+	"k线数据为空",
+	"行情无效",
+}
+
+// unavailableSymbol 已标记为下架/暂停交易的币种
+type unavailableSymbol struct {
+	Reason string
+	Since  time.Time
+	Held   bool
+}
+
+// isSymbolUnavailableError 判断错误是否表示币种已下架/暂停交易（与网络超时等临时错误区分）
+func isSymbolUnavailableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, pattern := range symbolUnavailablePatterns {
+		if strings.Contains(msg, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkMarketData 【功能】决策上下文获取行情后的回调（decision.Context.MarketDataCheck）：
+// 下架/暂停错误时标记币种为 symbol_unavailable（持仓币种首次标记时通知用户手动处理），获取成功时解除标记，
+// 临时错误保持原状态。返回币种当前是否不可用
+func (at *AutoTrader) checkMarketData(symbol string, held bool, err error) bool {
+	if err == nil {
+		if _, ok := at.unavailableSymbols.LoadAndDelete(symbol); ok {
+			log.Printf("✅ [%s] %s 行情已恢复，解除 symbol_unavailable 标记", at.name, symbol)
+		}
+		return false
+	}
+	if !isSymbolUnavailableError(err) {
+		return at.symbolUnavailable(symbol)
+	}
+	at.flagSymbolUnavailable(symbol, held, err)
+	return true
+}
+
+// flagSymbolUnavailable 标记币种为下架/暂停交易；持仓币种首次标记时发送通知（候选币种只记录日志）
+func (at *AutoTrader) flagSymbolUnavailable(symbol string, held bool, cause error) {
+	entry := unavailableSymbol{Reason: cause.Error(), Since: time.Now(), Held: held}
+	if previous, loaded := at.unavailableSymbols.LoadOrStore(symbol, entry); loaded {
+		prev := previous.(unavailableSymbol)
+		if !held || prev.Held {
+			return
+		}
+		// 之前作为候选币种标记过，现在确认为持仓币种：更新并通知
+		entry.Since = prev.Since
+		at.unavailableSymbols.Store(symbol, entry)
+	}
+
+	if !held {
+		log.Printf("⚠️ [%s] %s 疑似下架/暂停交易，暂不作为候选币种: %v", at.name, symbol, cause)
+		return
+	}
+	log.Printf("🚫 [%s] 持仓币种 %s 已下架或暂停交易，标记为 symbol_unavailable，需要手动处理: %v", at.name, symbol, cause)
+	at.emitEvent(logger.EventSymbolUnavailable,
+		fmt.Sprintf("🚫 [%s] 持仓币种 %s 已下架或暂停交易，无法获取行情，系统不再自动管理该持仓，请到交易所手动处理", at.name, symbol),
+		map[string]interface{}{
+			"status": "symbol_unavailable",
+			"symbol": symbol,
+			"error":  cause.Error(),
+		})
+}
+
+// symbolUnavailable 币种是否已标记为下架/暂停交易
+func (at *AutoTrader) symbolUnavailable(symbol string) bool {
+	_, ok := at.unavailableSymbols.Load(symbol)
+	return ok
+}
+
+// excludeUnavailableCandidates 从候选币种中剔除已标记为下架/暂停的币种；
+// 当前无持仓的币种标记超过 symbolUnavailableRecheck 后清除，重新作为候选试探
+func (at *AutoTrader) excludeUnavailableCandidates(coins []decision.CandidateCoin, positions []decision.PositionInfo) []decision.CandidateCoin {
+	held := make(map[string]bool, len(positions))
+	for _, pos := range positions {
+		held[pos.Symbol] = true
+	}
+	filtered := make([]decision.CandidateCoin, 0, len(coins))
+	for _, coin := range coins {
+		value, ok := at.unavailableSymbols.Load(coin.Symbol)
+		if ok && !held[coin.Symbol] && time.Since(value.(unavailableSymbol).Since) > symbolUnavailableRecheck {
+			at.unavailableSymbols.Delete(coin.Symbol)
+			ok = false
+		}
+		if !ok {
+			filtered = append(filtered, coin)
+		}
+	}
+	return filtered
+}
+
+// recordUnavailablePositions 在决策记录中标记已下架/暂停交易的持仓，并写入执行日志提醒手动处理
+func (at *AutoTrader) recordUnavailablePositions(record *logger.DecisionRecord, positions []decision.PositionInfo) {
+	for _, pos := range positions {
+		if !pos.SymbolUnavailable {
+			continue
+		}
+		for i := range record.Positions {
+			if record.Positions[i].Symbol == pos.Symbol && record.Positions[i].Side == pos.Side {
+				record.Positions[i].SymbolUnavailable = true
+			}
+		}
+		record.ExecutionLog = append(record.ExecutionLog,
+			fmt.Sprintf("🚫 %s %s 已下架或暂停交易（symbol_unavailable），系统不再自动管理，请手动处理", pos.Symbol, pos.Side))
+	}
+}
+
+// symbolUnavailableStatus 已标记为下架/暂停交易的币种（用于状态接口）
+func (at *AutoTrader) symbolUnavailableStatus() []map[string]interface{} {
+	status := []map[string]interface{}{}
+	at.unavailableSymbols.Range(func(key, value interface{}) bool {
+		entry := value.(unavailableSymbol)
+		status = append(status, map[string]interface{}{
+			"symbol": key.(string),
+			"held":   entry.Held,
+			"since":  entry.Since.Format(time.RFC3339),
+			"reason": entry.Reason,
+		})
+		return true
+	})
+	sort.Slice(status, func(i, j int) bool { return status[i]["symbol"].(string) < status[j]["symbol"].(string) })
+	return status
+}
@@ -0,0 +1,60 @@
+package trader
+
+import (
+	"strings"
+)
+
+// ParseSymbolList 解析逗号分隔的币种列表（去除空白和空项，未配置时返回空列表）
+func ParseSymbolList(raw string) []string {
+	symbols := []string{}
+	for _, symbol := range strings.Split(raw, ",") {
+		symbol = strings.TrimSpace(symbol)
+		if symbol != "" {
+			symbols = append(symbols, symbol)
+		}
+	}
+	return symbols
+}
+
+// SetSymbolUniverse 【功能】运行时更新候选币种池（candidate_symbols）和开仓白名单（trading_symbols），下一个决策周期生效。
+// 未配置候选币种池时沿用 trading_symbols 作为候选池（兼容旧配置），两者都未配置时使用默认币种
+func (at *AutoTrader) SetSymbolUniverse(candidateSymbols, tradingSymbols []string) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.CandidateCoins = candidateSymbols
+	at.config.AllowedSymbols = tradingSymbols
+	if len(tradingSymbols) > 0 {
+		at.tradingCoins = tradingSymbols
+	} else {
+		at.tradingCoins = at.defaultCoins
+	}
+}
+
+// candidateUniverse AI选币的候选池：优先使用 candidate_symbols，否则为交易币种（trading_symbols 或默认币种）
+func (at *AutoTrader) candidateUniverse() []string {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	if len(at.config.CandidateCoins) > 0 {
+		return at.config.CandidateCoins
+	}
+	return at.tradingCoins
+}
+
+// symbolAllowed 币种是否在开仓白名单（显式配置的 trading_symbols）内，未配置白名单时不限制
+func (at *AutoTrader) symbolAllowed(symbol string) bool {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	if len(at.config.AllowedSymbols) == 0 {
+		return true
+	}
+	symbol = normalizeSymbol(symbol)
+	for _, allowed := range at.config.AllowedSymbols {
+		if normalizeSymbol(allowed) == symbol {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	sysconfig "nofx/config"
+	"nofx/signal"
+)
+
+// strategyStatusTP1Hit 第一止盈已分批止盈的策略状态（仍处于持仓阶段）
+const strategyStatusTP1Hit = "TP1_HIT"
+
+// strategyDecisionStore 策略决策历史的持久化操作（*sysconfig.Database 实现）
+type strategyDecisionStore interface {
+	SaveStrategyDecision(history *sysconfig.StrategyDecisionHistory) error
+}
+
+// ValidTP1ClosePct 校验到达第一止盈时的分批止盈比例（0 表示关闭）
+func ValidTP1ClosePct(pct float64) bool {
+	return pct >= 0 && pct <= 100
+}
+
+// SetTP1ScaleOut 【功能】运行时更新信号模式第一止盈分批止盈：平仓比例（0=关闭）与是否将止损移到开仓价
+func (at *AutoTrader) SetTP1ScaleOut(closePct float64, breakevenStop bool) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.TP1ClosePct = closePct
+	at.config.TP1BreakevenStop = breakevenStop
+}
+
+// markTP1ScaledOut 记录策略已执行第一止盈分批止盈，同一策略只执行一次
+func (at *AutoTrader) markTP1ScaledOut(strategyID string) {
+	at.tp1ScaledOut.Store(strategyID, true)
+}
+
+// tp1ScaledOutDone 策略是否已执行过第一止盈分批止盈
+func (at *AutoTrader) tp1ScaledOutDone(strategyID string) bool {
+	_, ok := at.tp1ScaledOut.Load(strategyID)
+	return ok
+}
+
+// tp1Reached 标记价格是否已到达策略第一止盈价
+func tp1Reached(pos Position, tp1 float64) bool {
+	if pos.Side == "short" {
+		return pos.MarkPrice <= tp1
+	}
+	return pos.MarkPrice >= tp1
+}
+
+// checkTP1ScaleOut 【功能】信号模式第一止盈分批止盈（由信号模式补单自检循环调用）：
+// 持仓标记价格到达 TakeProfits[0] 时按配置比例部分平仓，并可将剩余仓位止损移到开仓价（保本），
+// 两个动作都写入策略决策历史。每个策略只执行一次（策略状态标记为 TP1_HIT，重启后不会重复执行）。返回本次执行的策略数
+func (at *AutoTrader) checkTP1ScaleOut(strategies []*signal.SignalDecision) int {
+	at.mu.RLock()
+	closePct, breakevenStop, analysisOnly := at.config.TP1ClosePct, at.config.TP1BreakevenStop, at.config.AnalysisOnly
+	at.mu.RUnlock()
+	if closePct <= 0 || analysisOnly || len(strategies) == 0 {
+		return 0
+	}
+
+	rawPositions, err := at.trader.GetPositions()
+	if err != nil {
+		log.Printf("⚠️ [%s] 第一止盈检查获取持仓失败: %v", at.name, err)
+		return 0
+	}
+	positions := NormalizePositions(rawPositions)
+
+	triggered := 0
+	for _, strat := range strategies {
+		if strat == nil || len(strat.TakeProfits) == 0 || strat.TakeProfits[0].Price <= 0 {
+			continue
+		}
+		if at.isStrategyClosed(strat.SignalID) || at.tp1ScaledOutDone(strat.SignalID) {
+			continue
+		}
+		side := strings.ToLower(strings.TrimSpace(strat.Direction))
+		var pos *Position
+		for i := range positions {
+			if positions[i].Symbol == strat.Symbol && positions[i].Side == side && positions[i].Quantity > 0 {
+				pos = &positions[i]
+				break
+			}
+		}
+		if pos == nil || !tp1Reached(*pos, strat.TakeProfits[0].Price) {
+			continue
+		}
+
+		// 先标记再执行：下单失败时不反复重试部分平仓，避免同一止盈位被多次减仓
+		at.markTP1ScaledOut(strat.SignalID)
+		triggered++
+		at.executeTP1ScaleOut(strat, *pos, closePct, breakevenStop)
+	}
+	return triggered
+}
+
+// executeTP1ScaleOut 执行第一止盈部分平仓与保本止损，并记录两个动作
+func (at *AutoTrader) executeTP1ScaleOut(strat *signal.SignalDecision, pos Position, closePct float64, breakevenStop bool) {
+	tp1 := strat.TakeProfits[0].Price
+	closeQty := pos.Quantity * closePct / 100
+	log.Printf("🎯 [%s] %s %s 到达第一止盈 %.4f（标记价 %.4f），部分平仓 %.0f%%（%.4f）",
+		at.name, pos.Symbol, pos.Side, tp1, pos.MarkPrice, closePct, closeQty)
+
+	_, closeErr := at.closePositionQuantity(pos.Symbol, pos.Side, closeQty)
+	if closeErr != nil {
+		log.Printf("❌ [%s] 第一止盈部分平仓失败: %v", at.name, closeErr)
+	}
+	at.recordTP1Action(strat, pos, "partial_close", closePct/100,
+		fmt.Sprintf("到达第一止盈 %.4f，分批止盈 %.0f%%", tp1, closePct), closeErr)
+
+	remaining := pos.Quantity - closeQty
+	if closeErr == nil && breakevenStop && remaining > 0 && pos.EntryPrice > 0 {
+		if err := at.trader.CancelStopLossOrders(pos.Symbol); err != nil {
+			log.Printf("  ⚠ 取消旧止损单失败: %v", err)
+		}
+		stopErr := at.setStopLossWithRetry(pos.Symbol, strings.ToUpper(pos.Side), remaining, pos.EntryPrice)
+		if stopErr != nil {
+			log.Printf("❌ [%s] 保本止损设置失败: %v", at.name, stopErr)
+		} else {
+			at.updatePyramidStopLoss(pos.Key(), pos.EntryPrice)
+			log.Printf("🛡️ [%s] %s %s 剩余仓位止损已移到开仓价 %.4f", at.name, pos.Symbol, pos.Side, pos.EntryPrice)
+		}
+		at.recordTP1Action(strat, Position{Symbol: pos.Symbol, Side: pos.Side, MarkPrice: pos.MarkPrice, Quantity: remaining}, "update_stop_loss", 0,
+			fmt.Sprintf("第一止盈后止损移到开仓价 %.4f（保本）", pos.EntryPrice), stopErr)
+	}
+
+	if store, ok := at.database.(strategyStatusStore); ok {
+		hadPosition := true
+		status := &sysconfig.TraderStrategyStatus{
+			TraderID:    at.id,
+			StrategyID:  strat.SignalID,
+			Symbol:      strat.Symbol,
+			HadPosition: &hadPosition,
+			Status:      strategyStatusTP1Hit,
+			EntryPrice:  pos.EntryPrice,
+			Quantity:    remaining,
+		}
+		if err := store.UpdateTraderStrategyStatus(status); err != nil {
+			log.Printf("⚠️ 更新策略状态失败: %v", err)
+		}
+	}
+}
+
+// recordTP1Action 将第一止盈自动化动作写入策略决策历史（前端与AI动作一起逐条展示）
+func (at *AutoTrader) recordTP1Action(strat *signal.SignalDecision, pos Position, action string, amountPercent float64, reason string, execErr error) {
+	store, ok := at.database.(strategyDecisionStore)
+	if !ok {
+		return
+	}
+	history := &sysconfig.StrategyDecisionHistory{
+		TraderID:         at.id,
+		StrategyID:       strat.SignalID,
+		DecisionTime:     time.Now(),
+		Action:           action,
+		Symbol:           strat.Symbol,
+		CurrentPrice:     pos.MarkPrice,
+		TargetPrice:      strat.TakeProfits[0].Price,
+		PositionSide:     strings.ToUpper(pos.Side),
+		PositionQty:      pos.Quantity,
+		AmountPercent:    amountPercent,
+		Reason:           reason,
+		ExecutionSuccess: execErr == nil,
+	}
+	if execErr != nil {
+		history.ExecutionError = execErr.Error()
+	}
+	if err := store.SaveStrategyDecision(history); err != nil {
+		log.Printf("⚠️ 保存决策历史失败: %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/tracing"
+)
+
+// spanAttributes 所有决策周期 span 共有的属性
+func (at *AutoTrader) spanAttributes() map[string]interface{} {
+	return map[string]interface{}{"trader_id": at.id}
+}
+
+// startCycleSpan 开始决策周期的根 span（未开启链路追踪时返回 nil）
+func (at *AutoTrader) startCycleSpan() *tracing.Span {
+	attrs := at.spanAttributes()
+	attrs["trader_name"] = at.name
+	attrs["exchange"] = at.exchange
+	return tracing.StartSpan("decision_cycle", attrs)
+}
+
+// endCycleSpan 结束决策周期 span：result 为 ok / skipped（风控暂停、非交易时段等未请求AI）/ error
+func (at *AutoTrader) endCycleSpan(span *tracing.Span, record *logger.DecisionRecord, err error) {
+	if span == nil {
+		return
+	}
+	span.SetAttribute("cycle", at.callCount)
+	span.SetError(err)
+	if record != nil {
+		span.SetAttribute("action_count", len(record.Decisions))
+		if err == nil && !record.Success {
+			span.SetAttribute("result", "skipped")
+			span.SetAttribute("reason", record.ErrorMessage)
+		}
+	}
+	span.End()
+}
+
+// endAICallSpan 结束AI调用 span，记录实际响应的提供商/模型和决策数
+func endAICallSpan(span *tracing.Span, fullDecision *decision.FullDecision, err error) {
+	if span == nil {
+		return
+	}
+	if fullDecision != nil {
+		span.SetAttribute("ai_provider", string(fullDecision.Served.Provider))
+		span.SetAttribute("ai_model", fullDecision.Served.Model)
+		span.SetAttribute("was_fallback", fullDecision.Served.WasFallback)
+		span.SetAttribute("decision_count", len(fullDecision.Decisions))
+	}
+	span.SetError(err)
+	span.End()
+}
+
+// endActionSpan 结束单个动作执行 span，记录币种、动作和执行结果
+func endActionSpan(span *tracing.Span, actionRecord *logger.DecisionAction, err error) {
+	if span == nil {
+		return
+	}
+	span.SetAttribute("symbol", actionRecord.Symbol)
+	span.SetAttribute("action", actionRecord.Action)
+	if actionRecord.Quantity > 0 {
+		span.SetAttribute("quantity", actionRecord.Quantity)
+	}
+	if actionRecord.OrderID != 0 {
+		span.SetAttribute("order_id", actionRecord.OrderID)
+	}
+	if actionRecord.Status != "" {
+		span.SetAttribute("status", actionRecord.Status)
+	}
+	span.SetError(err)
+	span.End()
+}
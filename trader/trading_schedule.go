@@ -0,0 +1,158 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TradingWindow 允许开新仓的时间段（UTC）
+// Start > End 表示跨零点（如 22:00-02:00），Days 按开始时间所在的星期判断
+type TradingWindow struct {
+	Days  []int  `json:"days,omitempty"` // 星期几（0=周日 ... 6=周六），为空表示每天
+	Start string `json:"start"`          // 开始时间 HH:MM（UTC）
+	End   string `json:"end"`            // 结束时间 HH:MM（UTC），24:00 表示当天结束
+}
+
+// parseClock 解析 HH:MM 为当天的分钟数（允许 24:00）
+func parseClock(s string) (int, error) {
+	var h, m int
+	if _, err := fmt.Sscanf(strings.TrimSpace(s), "%d:%d", &h, &m); err != nil {
+		return 0, fmt.Errorf("时间格式无效: %q（应为 HH:MM）", s)
+	}
+	if h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("时间超出范围: %q", s)
+	}
+	return h*60 + m, nil
+}
+
+// ValidateTradingSchedule 校验交易时段配置
+func ValidateTradingSchedule(windows []TradingWindow) error {
+	for i, w := range windows {
+		start, err := parseClock(w.Start)
+		if err != nil {
+			return fmt.Errorf("第%d个时段: %w", i+1, err)
+		}
+		end, err := parseClock(w.End)
+		if err != nil {
+			return fmt.Errorf("第%d个时段: %w", i+1, err)
+		}
+		if start == end {
+			return fmt.Errorf("第%d个时段: 开始与结束时间不能相同", i+1)
+		}
+		for _, d := range w.Days {
+			if d < 0 || d > 6 {
+				return fmt.Errorf("第%d个时段: 星期取值必须为0-6", i+1)
+			}
+		}
+	}
+	return nil
+}
+
+// ParseTradingSchedule 解析数据库中保存的交易时段（JSON），空字符串表示全天可交易
+func ParseTradingSchedule(raw string) ([]TradingWindow, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var windows []TradingWindow
+	if err := json.Unmarshal([]byte(raw), &windows); err != nil {
+		return nil, fmt.Errorf("交易时段解析失败: %w", err)
+	}
+	if err := ValidateTradingSchedule(windows); err != nil {
+		return nil, err
+	}
+	return windows, nil
+}
+
+// FormatTradingSchedule 将交易时段序列化为JSON用于存储，未配置时返回空字符串
+func FormatTradingSchedule(windows []TradingWindow) string {
+	if len(windows) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(windows)
+	return string(data)
+}
+
+// contains 判断 now（UTC）是否落在该时段内
+func (w TradingWindow) contains(now time.Time) bool {
+	now = now.UTC()
+	start, _ := parseClock(w.Start)
+	end, _ := parseClock(w.End)
+	minute := now.Hour()*60 + now.Minute()
+
+	dayAllowed := func(d time.Weekday) bool {
+		if len(w.Days) == 0 {
+			return true
+		}
+		for _, allowed := range w.Days {
+			if allowed == int(d) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if start < end {
+		return dayAllowed(now.Weekday()) && minute >= start && minute < end
+	}
+	// 跨零点：开始当天的 [start, 24:00) 或次日的 [00:00, end)
+	if minute >= start {
+		return dayAllowed(now.Weekday())
+	}
+	return minute < end && dayAllowed(now.AddDate(0, 0, -1).Weekday())
+}
+
+// tradingScheduleActive 判断当前是否处于允许交易的时段，未配置时段时始终为 true
+func tradingScheduleActive(windows []TradingWindow, now time.Time) bool {
+	if len(windows) == 0 {
+		return true
+	}
+	for _, w := range windows {
+		if w.contains(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetTradingSchedule 【功能】更新交易时段（无需重启，nil 表示全天可交易）
+func (at *AutoTrader) SetTradingSchedule(windows []TradingWindow) {
+	if at == nil {
+		return
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.config.TradingSchedule = windows
+}
+
+// inTradingWindow 当前是否处于交易时段
+func (at *AutoTrader) inTradingWindow(now time.Time) bool {
+	at.mu.RLock()
+	windows := at.config.TradingSchedule
+	at.mu.RUnlock()
+	return tradingScheduleActive(windows, now)
+}
+
+// isOpeningAction 是否为开新仓的动作（非交易时段内禁止）
+func isOpeningAction(action string) bool {
+	switch action {
+	case "open_long", "open_short", "place_long_order", "place_short_order", "flip_long", "flip_short":
+		return true
+	}
+	return false
+}
+
+// tradingScheduleStatus 交易时段状态（用于状态接口）
+func (at *AutoTrader) tradingScheduleStatus() map[string]interface{} {
+	at.mu.RLock()
+	windows := at.config.TradingSchedule
+	at.mu.RUnlock()
+	if windows == nil {
+		windows = []TradingWindow{}
+	}
+	return map[string]interface{}{
+		"active":  tradingScheduleActive(windows, time.Now()),
+		"windows": windows,
+	}
+}
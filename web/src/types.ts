@@ -109,6 +109,8 @@ export interface AIModel {
   apiKey?: string
   customApiUrl?: string
   customModelName?: string
+  customHeaders?: Record<string, string>
+  customAuthScheme?: string
 }
 
 export interface Exchange {
@@ -156,6 +158,8 @@ export interface UpdateModelConfigRequest {
       api_key: string
       custom_api_url?: string
       custom_model_name?: string
+      custom_headers?: Record<string, string>
+      custom_auth_scheme?: string
     }
   }
 }